@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// archiveCmd represents the archive command
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Move old audit payloads out of the database",
+	Long: `Archive moves the summaries, commands, notes and follow-ups of audit logs older
+than archive.after_days (30 by default) into gzip files under archive/ next to
+baton.db, then vacuums the database. Payloads smaller than archive.min_bytes stay
+in the database. Each row keeps the file's path and SHA-256, and the audit views
+read archived payloads back transparently.`,
+	RunE: runArchive,
+}
+
+func init() {
+	rootCmd.AddCommand(archiveCmd)
+
+	archiveCmd.Flags().Int("older-than", 0, "archive audit logs older than this many days (default archive.after_days)")
+	archiveCmd.Flags().Int("min-bytes", -1, "keep payloads smaller than this in the database (default archive.min_bytes)")
+	archiveCmd.Flags().Bool("no-vacuum", false, "skip vacuuming the database afterwards")
+}
+
+func runArchive(cmd *cobra.Command, args []string) error {
+	days, _ := cmd.Flags().GetInt("older-than")
+	if days <= 0 {
+		days = globalConfig.Archive.AfterDays
+	}
+	minBytes, _ := cmd.Flags().GetInt("min-bytes")
+	if minBytes < 0 {
+		minBytes = globalConfig.Archive.MinBytes
+	}
+	noVacuum, _ := cmd.Flags().GetBool("no-vacuum")
+	dryRun := globalConfig.Development.DryRunDefault
+
+	if !dryRun {
+		workspaceLock, err := acquireWorkspaceLock("archive")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	result, err := store.ArchiveAuditPayloads(cutoff, minBytes, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to archive audit payloads: %w", err)
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run: would archive %d audit payloads (%d bytes) older than %d days\n", result.Archived, result.Bytes, days)
+		return nil
+	}
+	fmt.Printf("✅ Archived %d audit payloads (%d bytes) older than %d days\n", result.Archived, result.Bytes, days)
+
+	if result.Archived > 0 && !noVacuum {
+		if err := store.Vacuum(); err != nil {
+			return fmt.Errorf("failed to vacuum database: %w", err)
+		}
+	}
+	return nil
+}
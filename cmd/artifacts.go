@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/artifactfs"
+)
+
+// artifactsCmd represents the artifacts command
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Artifact commands",
+	Long:  `Artifact commands for working with task handover artifacts outside the database.`,
+}
+
+// artifactsMaterializeCmd represents the artifacts materialize command
+var artifactsMaterializeCmd = &cobra.Command{
+	Use:   "materialize",
+	Short: "Write the latest artifacts to workspace files",
+	Long: `Materialize writes the latest version of each task's artifacts to
+<artifacts.dir>/<short-task-id>/<name>.md (claudedocs/tasks by default), so they can be
+grepped, opened in an editor and reviewed in pull requests. Set artifacts.materialize
+to keep the files in sync on every upsert.`,
+	RunE: runArtifactsMaterialize,
+}
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+	artifactsCmd.AddCommand(artifactsMaterializeCmd)
+
+	artifactsMaterializeCmd.Flags().String("task", "", "only materialize this task's artifacts")
+}
+
+func runArtifactsMaterialize(cmd *cobra.Command, args []string) error {
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	mirror := artifactfs.NewMirrorFromConfig(store, globalConfig)
+
+	var paths []string
+	if taskID, _ := cmd.Flags().GetString("task"); taskID != "" {
+		if _, err := store.GetTask(taskID); err != nil {
+			return fmt.Errorf("task not found: %s", taskID)
+		}
+		paths, err = mirror.MaterializeTask(taskID)
+	} else {
+		paths, err = mirror.MaterializeAll()
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		fmt.Printf("  %s\n", path)
+	}
+	fmt.Printf("✅ Materialized %d artifacts\n", len(paths))
+	return nil
+}
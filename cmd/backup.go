@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/backup"
+	"baton/internal/storage"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Checkpoint the workspace into a single archive",
+	Long: `Backup writes a gzip tar archive of the workspace: a consistent snapshot of
+baton.db taken with VACUUM INTO, its archived audit payloads and artifact blobs,
+the plan file, the config file and the context files (CLAUDE.md, STYLE_GUIDE.md,
+.claudeignore and .claude/). A manifest records the SHA-256 of every file so
+'baton restore' can verify the archive. Take one before a risky autonomous run.`,
+	RunE: runBackup,
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Restore the workspace from a backup archive",
+	Long: `Restore verifies a backup archive, checking every file against the manifest and
+the database with SQLite's integrity check, and then puts the files back, replacing
+the database and the workspace files it holds. The workspace as it was is backed up
+first unless --no-backup is given. With --dry-run the archive is only verified.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+
+	backupCmd.Flags().StringP("output", "o", "", "archive path (default .baton/backups/<timestamp>.tar.gz)")
+
+	restoreCmd.Flags().Bool("no-backup", false, "do not back up the current workspace before restoring")
+}
+
+// backupWorkspace names the configured workspace's files for backup and restore
+func backupWorkspace() (backup.Workspace, error) {
+	key, err := globalConfig.DatabaseKey()
+	if err != nil {
+		return backup.Workspace{}, err
+	}
+	return backup.Workspace{
+		Dir:         globalConfig.Workspace,
+		Database:    globalConfig.Database,
+		DatabaseKey: key,
+		PlanFile:    globalConfig.PlanFile,
+		ConfigFile:  globalConfig.ConfigFile,
+	}, nil
+}
+
+// writeBackup backs the workspace up to output, or to a new file under
+// .baton/backups when output is ""
+func writeBackup(store *storage.Store, output string) (string, *backup.Result, error) {
+	if output == "" {
+		name := time.Now().Format("20060102-150405") + ".tar.gz"
+		output = filepath.Join(globalConfig.Workspace, ".baton", "backups", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	ws, err := backupWorkspace()
+	if err != nil {
+		return "", nil, err
+	}
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+	result, err := backup.Create(store, ws, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return "", nil, fmt.Errorf("failed to back up workspace: %w", err)
+	}
+	return output, result, nil
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	output, result, err := writeBackup(store, output)
+	if err != nil {
+		return err
+	}
+
+	for _, skipped := range result.Skipped {
+		fmt.Printf("⚠️  Skipped %s\n", skipped)
+	}
+	fmt.Printf("✅ Backed up %d files to %s\n", len(result.Manifest.Files), output)
+	return nil
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	archive := args[0]
+	noBackup, _ := cmd.Flags().GetBool("no-backup")
+	dryRun := globalConfig.Development.DryRunDefault
+
+	ws, err := backupWorkspace()
+	if err != nil {
+		return err
+	}
+	manifest, err := backup.Verify(archive, ws.DatabaseKey)
+	if err != nil {
+		return fmt.Errorf("backup verification failed: %w", err)
+	}
+	if dryRun {
+		fmt.Printf("Dry run: %s is intact, %d files from %s:\n", archive, len(manifest.Files), manifest.CreatedAt.Format("2006-01-02 15:04"))
+		for _, file := range manifest.Files {
+			fmt.Printf("  %s (%d bytes)\n", file.Name, file.Size)
+		}
+		return nil
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("restore")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	if !noBackup {
+		// Initialize database
+		store, err := openStore(globalConfig)
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		output, _, err := writeBackup(store, "")
+		store.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Backed up the current workspace to %s\n", output)
+	}
+
+	if _, err := backup.Restore(archive, ws); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", archive, err)
+	}
+	fmt.Printf("✅ Restored %d files from %s (taken %s)\n", len(manifest.Files), archive, manifest.CreatedAt.Format("2006-01-02 15:04"))
+	return nil
+}
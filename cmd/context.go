@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	batoncontext "baton/internal/context"
+	"baton/internal/llm"
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Context file commands",
+	Long:  `Context file commands for keeping CLAUDE.md, STYLE_GUIDE.md, .claudeignore and subagents current.`,
+}
+
+var contextRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Update context files after the tech stack changes",
+	Long: `Refresh detects the workspace's tech stack from its manifest files (go.mod,
+package.json, pyproject.toml, Cargo.toml, ...) and compares it with the stack
+recorded in .claude/stack.json. For each newly detected technology it proposes
+.claudeignore entries, a STYLE_GUIDE.md section and regenerated developer and
+tester subagents, and applies them after confirmation.
+
+The first run only records the current stack. baton ingest runs the same check.`,
+	RunE: runContextRefresh,
+}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextRefreshCmd)
+
+	contextRefreshCmd.Flags().BoolP("yes", "y", false, "apply the proposed updates without asking")
+}
+
+func runContextRefresh(cmd *cobra.Command, args []string) error {
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	workspaceLock, err := acquireWorkspaceLock("context")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	changed, err := refreshContext(yes)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Println("✅ Tech stack unchanged, context files are current")
+	}
+	return nil
+}
+
+// refreshContext checks the workspace for tech stack drift and offers the
+// context file updates it calls for. It reports whether drift was found.
+func refreshContext(yes bool) (bool, error) {
+	workspace := globalConfig.Workspace
+
+	drift, err := batoncontext.CheckDrift(workspace)
+	if err != nil {
+		return false, err
+	}
+	if !drift.HasChanges() {
+		return false, nil
+	}
+
+	fmt.Println("\n🧭 Tech stack changed:")
+	if len(drift.Added) > 0 {
+		fmt.Printf("  Added:   %s\n", strings.Join(drift.Added, ", "))
+	}
+	if len(drift.Removed) > 0 {
+		fmt.Printf("  Removed: %s\n", strings.Join(drift.Removed, ", "))
+	}
+
+	// Without an LLM only .claudeignore can be proposed
+	var llmClient llm.Client
+	if len(drift.Added) > 0 {
+		if client, err := createLLMClient(); err == nil {
+			llmClient = client
+			fmt.Println("  Preparing context updates...")
+		} else if verbose {
+			fmt.Printf("  LLM client unavailable: %v\n", err)
+		}
+	}
+
+	manager := batoncontext.New(llmClient, workspace)
+	proposal, err := manager.ProposeRefresh(drift)
+	if err != nil {
+		return true, fmt.Errorf("failed to prepare context updates: %w", err)
+	}
+
+	if len(proposal.Updates) > 0 {
+		fmt.Println("\nProposed updates:")
+		for _, update := range proposal.Updates {
+			fmt.Printf("  • %s: %s\n", update.Path, update.Reason)
+		}
+	}
+	for _, skipped := range proposal.Skipped {
+		fmt.Printf("  ⚠️  Skipped %s\n", skipped)
+	}
+
+	if globalConfig.Development.DryRunDefault {
+		fmt.Println("\nDry run: no files changed")
+		return true, nil
+	}
+
+	if len(proposal.Updates) > 0 && !yes {
+		if !stdinIsTerminal() {
+			fmt.Println("\nRun 'baton context refresh --yes' to apply these updates")
+			return true, nil
+		}
+		fmt.Print("\nApply these updates? [y/N]: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("No files changed")
+			return true, nil
+		}
+	}
+
+	if err := manager.Apply(proposal); err != nil {
+		return true, fmt.Errorf("failed to apply context updates: %w", err)
+	}
+	if len(proposal.Updates) > 0 {
+		fmt.Printf("✅ Updated %d context files\n", len(proposal.Updates))
+	}
+	if len(proposal.Skipped) > 0 {
+		fmt.Println("Skipped updates will be proposed again on the next check")
+	} else if len(proposal.Updates) == 0 {
+		fmt.Println("✅ Recorded the new tech stack")
+	}
+	return true, nil
+}
+
+// stdinIsTerminal reports whether stdin is interactive, so prompts can be answered
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/briefing"
+	"baton/internal/llm"
+)
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain <task-id>",
+	Short: "Explain a task's current status",
+	Long: `Explain assembles everything known about a task (description, linked requirements,
+artifacts, audit history and dependencies) and asks the LLM for a concise status
+briefing with a suggested next action.
+
+Briefings are cached per task version; use --refresh to regenerate one anyway.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+	explainCmd.Flags().Bool("refresh", false, "regenerate the briefing even if a cached one is current")
+	explainCmd.Flags().Bool("json", false, "output in JSON format")
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	llmClient, err := llm.NewClient(globalConfig.LLM)
+	if err != nil {
+		return fmt.Errorf("failed to create LLM client: %w", err)
+	}
+
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	result, err := briefing.NewBriefer(store, llmClient).Explain(taskID, refresh)
+	if err != nil {
+		return err
+	}
+
+	// Check for JSON output
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	if jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	source := "generated"
+	if result.Cached {
+		source = "cached"
+	}
+	fmt.Printf("📖 Briefing for %s (%s %s, version %s)\n\n", taskID, source,
+		result.GeneratedAt.Format("2006-01-02 15:04"), result.Version)
+	fmt.Println(result.Content)
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/storage"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the workspace's data as JSON",
+	Long: `Export writes every task (archived and deleted ones included), requirement,
+artifact version, audit log, agent and task note as a single JSON document. Records are
+sorted so two exports of the same workspace are identical, which makes exports good
+snapshots to keep in git, and 'baton import' loads one into another machine or backend.`,
+	RunE: runExport,
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a workspace export",
+	Long: `Import loads a file written by 'baton export' into the database, keeping every
+record's ID and timestamps. The database must not have any tasks or requirements yet,
+and the import happens in one transaction, so it either loads everything or nothing.
+With --dry-run the file is only read and its contents counted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+
+	exportCmd.Flags().String("format", "json", "export format (json)")
+	exportCmd.Flags().StringP("output", "o", "", "write the export to a file instead of stdout")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "json" {
+		return fmt.Errorf("unsupported export format %q (supported: json)", format)
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	export, err := store.ExportAll()
+	if err != nil {
+		return fmt.Errorf("failed to export workspace: %w", err)
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		fmt.Printf("✅ Exported %s to %s\n", exportSummary(export), output)
+		return nil
+	}
+
+	fmt.Print(string(data))
+	return nil
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+
+	export := &storage.WorkspaceExport{}
+	if err := json.Unmarshal(data, export); err != nil {
+		return fmt.Errorf("failed to parse export %s: %w", args[0], err)
+	}
+	if export.FormatVersion != storage.ExportFormatVersion {
+		return fmt.Errorf("unsupported export format version %d (expected %d)", export.FormatVersion, storage.ExportFormatVersion)
+	}
+
+	if globalConfig.Development.DryRunDefault {
+		fmt.Printf("Dry run: %s has %s\n", args[0], exportSummary(export))
+		return nil
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("import")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if err := store.ImportAll(export); err != nil {
+		return fmt.Errorf("failed to import %s: %w", args[0], err)
+	}
+
+	fmt.Printf("✅ Imported %s\n", exportSummary(export))
+	return nil
+}
+
+// exportSummary counts the records in an export
+func exportSummary(export *storage.WorkspaceExport) string {
+	return fmt.Sprintf("%d tasks, %d requirements, %d artifact versions, %d audit logs, %d agents and %d notes",
+		len(export.Tasks), len(export.Requirements), len(export.Artifacts), len(export.AuditLogs), len(export.Agents), len(export.Notes))
+}
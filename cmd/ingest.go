@@ -17,8 +17,10 @@ var ingestCmd = &cobra.Command{
 
 This command will:
 1. Parse the plan file for requirements (FR-*, NFR-*, etc.)
-2. Create or update requirements in the database
+2. Create or update requirements in the database, skipping keys used twice and
+   keys of deprecated or renumbered requirements
 3. Report any parsing errors or validation issues
+4. Check for tech stack changes and offer context file updates (see context refresh)
 
 The command is idempotent - running it multiple times will update existing requirements.`,
 	Args: cobra.MaximumNArgs(1),
@@ -38,8 +40,14 @@ func runIngest(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("📄 Ingesting plan file: %s\n", planFile)
 
+	workspaceLock, err := acquireWorkspaceLock("ingest")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
 	// Initialize database
-	store, err := storage.NewStore(globalConfig.Database)
+	store, err := openStore(globalConfig)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
@@ -66,18 +74,31 @@ func runIngest(cmd *cobra.Command, args []string) error {
 	}
 
 	// Ingest requirements
-	var created, updated int
+	var created, updated, skipped int
+	seen := make(map[string]bool)
 	for _, req := range requirements {
+		// A key used twice in the plan would overwrite the first requirement
+		if seen[req.Key] {
+			skipped++
+			fmt.Printf("⛔ Skipped duplicate: %s (%s)\n", req.Key, req.Title)
+			continue
+		}
+		seen[req.Key] = true
+
 		// Check if requirement already exists
 		existing, err := store.GetRequirement(req.Key)
 		if err != nil {
-			// Doesn't exist, create new
+			// Doesn't exist, create new unless the key was retired by renumbering
 			if err := store.CreateRequirement(req); err != nil {
 				fmt.Printf("❌ Failed to create requirement %s: %v\n", req.Key, err)
 				continue
 			}
 			created++
 			fmt.Printf("✅ Created: %s\n", req.Key)
+		} else if existing.Status == storage.RequirementDeprecated {
+			// The plan still lists it; give it a new key if it is wanted again
+			skipped++
+			fmt.Printf("⛔ Skipped deprecated: %s (use a new key to revive it)\n", req.Key)
 		} else {
 			// Exists, update if different
 			if existing.Title != req.Title || existing.Text != req.Text || existing.Type != req.Type {
@@ -101,6 +122,9 @@ func runIngest(cmd *cobra.Command, args []string) error {
 	fmt.Printf("\n📈 Ingestion Summary:\n")
 	fmt.Printf("  Created: %d requirements\n", created)
 	fmt.Printf("  Updated: %d requirements\n", updated)
+	if skipped > 0 {
+		fmt.Printf("  Skipped: %d requirements\n", skipped)
+	}
 	fmt.Printf("  Total: %d requirements\n", len(requirements))
 
 	if len(issues) == 0 {
@@ -109,5 +133,10 @@ func runIngest(cmd *cobra.Command, args []string) error {
 		fmt.Printf("⚠️ Plan ingestion completed with %d validation issues\n", len(issues))
 	}
 
+	// Offer context file updates when the code base picked up a new technology
+	if _, err := refreshContext(false); err != nil {
+		fmt.Printf("⚠️ Context refresh check failed: %v\n", err)
+	}
+
 	return nil
 }
\ No newline at end of file
@@ -2,9 +2,10 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -12,6 +13,8 @@ import (
 	"baton/internal/config"
 	"baton/internal/context"
 	"baton/internal/llm"
+	"baton/internal/plan"
+	"baton/internal/storage"
 	"baton/internal/wizard"
 )
 
@@ -36,6 +39,7 @@ var (
 	nonInteractive bool
 	basicMode      bool
 	templatePath   string
+	initLLM        string
 )
 
 func init() {
@@ -45,6 +49,93 @@ func init() {
 	initCmd.Flags().BoolVar(&basicMode, "basic", false, "Use basic template initialization (no AI)")
 	initCmd.Flags().BoolVar(&nonInteractive, "non-interactive", false, "Use defaults without prompting")
 	initCmd.Flags().StringVar(&templatePath, "template", "", "Path to template plan.md file")
+	initCmd.Flags().StringVar(&initLLM, "llm", "", "LLM provider for the wizard and the new workspace (claude, openai, openai_compatible, gemini, ollama); defaults to llm.primary")
+}
+
+// wizardLLMConfig is the LLM configuration the wizard runs with: the loaded
+// config (e.g. ~/.baton/config.yaml) with --llm overriding the provider
+func wizardLLMConfig() config.LLMConfig {
+	cfg := globalConfig.LLM
+	if initLLM != "" {
+		cfg.Primary = initLLM
+	}
+	return cfg
+}
+
+// mcpConfigSection renders the mcp section of a new baton.yaml with a freshly
+// generated token, so the MCP server only answers clients that know it
+func mcpConfigSection() (string, error) {
+	token, err := config.NewMCPToken()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`mcp:
+  token: %q
+  token_env: "BATON_MCP_TOKEN"
+  allow_remote: false
+`, token), nil
+}
+
+// llmConfigSection renders the llm section of a new baton.yaml for the
+// provider the wizard ran with
+func llmConfigSection() string {
+	cfg := wizardLLMConfig()
+	switch cfg.Primary {
+	case "openai":
+		return fmt.Sprintf(`llm:
+  primary: "openai"
+  openai:
+    model: %q
+    base_url: %q
+    api_key_env: %q
+`, cfg.OpenAI.Model, cfg.OpenAI.BaseURL, cfg.OpenAI.APIKeyEnv)
+	case "openai_compatible":
+		section := fmt.Sprintf(`llm:
+  primary: "openai_compatible"
+  openai_compatible:
+    model: %q
+    base_url: %q
+    api_key_env: %q
+`, cfg.OpenAICompatible.Model, cfg.OpenAICompatible.BaseURL, cfg.OpenAICompatible.APIKeyEnv)
+		if len(cfg.OpenAICompatible.Headers) > 0 {
+			names := make([]string, 0, len(cfg.OpenAICompatible.Headers))
+			for name := range cfg.OpenAICompatible.Headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			section += "    headers:\n"
+			for _, name := range names {
+				section += fmt.Sprintf("      %q: %q\n", name, cfg.OpenAICompatible.Headers[name])
+			}
+		}
+		return section
+	case "gemini":
+		return fmt.Sprintf(`llm:
+  primary: "gemini"
+  gemini:
+    model: %q
+    base_url: %q
+    api_key_env: %q
+    command: %q
+`, cfg.Gemini.Model, cfg.Gemini.BaseURL, cfg.Gemini.APIKeyEnv, cfg.Gemini.Command)
+	case "ollama":
+		return fmt.Sprintf(`llm:
+  primary: "ollama"
+  ollama:
+    host: %q
+    model: %q
+    mcp_tools: %t
+`, cfg.Ollama.Host, cfg.Ollama.Model, cfg.Ollama.MCPTools)
+	default:
+		return `llm:
+  primary: "claude"
+  claude:
+    command: "claude"
+    headless_args: ["-p"]
+    output_format: "stream-json"
+    mcp_connect: true
+`
+	}
 }
 
 func runInitWizard(cmd *cobra.Command, args []string) error {
@@ -53,7 +144,7 @@ func runInitWizard(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("baton workspace already exists in current directory")
 	}
 
-	fmt.Println(`
+	fmt.Print(`
 ╔══════════════════════════════════════════════════════════════╗
 ║                                                              ║
 ║   🎯 Welcome to Baton - AI-Powered Project Orchestrator     ║
@@ -79,16 +170,7 @@ func runAIWizard() error {
 	reader := bufio.NewReader(os.Stdin)
 
 	// Initialize LLM client
-	cfg := &config.LLMConfig{
-		Primary: "claude",
-		Claude: config.ClaudeConfig{
-			Command:      "claude",
-			HeadlessArgs: []string{"-p"},
-			OutputFormat: "stream-json",
-		},
-	}
-
-	llmClient, err := llm.NewClient(*cfg)
+	llmClient, err := llm.NewClient(wizardLLMConfig())
 	if err != nil {
 		fmt.Printf("⚠️  LLM client not available. Falling back to basic setup.\n")
 		return createBasicWorkspace()
@@ -180,10 +262,11 @@ func runAIWizard() error {
 	fmt.Println()
 	fmt.Println("🚀 Next steps:")
 	fmt.Println("   1. Review the generated plan.md file")
-	fmt.Println("   2. Run 'baton ingest plan.md' to load requirements")
-	fmt.Println("   3. Run 'baton status' to see task overview")
-	fmt.Println("   4. Run 'baton start' to begin first cycle")
-	fmt.Println("   5. Run 'baton web' to access the web UI")
+	fmt.Println("   2. Run 'baton ingest plan.md' after editing it to update requirements")
+	fmt.Println("   3. Run 'baton requirements coverage' to find requirements without tasks")
+	fmt.Println("   4. Run 'baton status' to see task overview")
+	fmt.Println("   5. Run 'baton start' to begin first cycle")
+	fmt.Println("   6. Run 'baton web' to access the web UI")
 	fmt.Println()
 	fmt.Println(strings.Repeat("═", 60))
 
@@ -203,16 +286,7 @@ func createWorkspaceWithPlan(plan *wizard.ProjectPlan, tasks []wizard.Task) erro
 	fmt.Println("   ✓ Created plan.md")
 
 	// Initialize context management system
-	cfg := &config.LLMConfig{
-		Primary: "claude",
-		Claude: config.ClaudeConfig{
-			Command:      "claude",
-			HeadlessArgs: []string{"-p"},
-			OutputFormat: "stream-json",
-		},
-	}
-
-	llmClient, err := llm.NewClient(*cfg)
+	llmClient, err := llm.NewClient(wizardLLMConfig())
 	if err == nil {
 		contextManager := context.New(llmClient, "./")
 
@@ -250,6 +324,13 @@ func createWorkspaceWithPlan(plan *wizard.ProjectPlan, tasks []wizard.Task) erro
 		}
 	}
 
+	// Record the stack the context files describe, for later drift checks
+	if stack, err := context.DetectStack("./"); err == nil {
+		if err := context.SaveStackRecord("./", stack); err != nil {
+			fmt.Printf("   ⚠️  Warning: Failed to record tech stack: %v\n", err)
+		}
+	}
+
 	// Create database with initial tasks
 	if err := createDatabaseWithTasks(tasks); err != nil {
 		return err
@@ -267,6 +348,7 @@ func createWorkspaceWithPlan(plan *wizard.ProjectPlan, tasks []wizard.Task) erro
 baton.db
 baton.db-*
 baton.log
+.baton/baton.lock
 *.tmp
 
 # Development
@@ -289,23 +371,23 @@ web/*.log
 }
 
 func createConfigFile() error {
+	mcpSection, err := mcpConfigSection()
+	if err != nil {
+		return err
+	}
+
 	config := `# Baton Configuration
 # Generated by AI Wizard
 
 plan_file: "./plan.md"
 workspace: "./"
-database: "./baton.db"
-mcp_port: 8080
+` + databaseConfigSection() + `mcp_port: 8080
 
-# LLM Configuration
-llm:
-  primary: "claude"
-  claude:
-    command: "claude"
-    headless_args: ["-p"]
-    output_format: "stream-json"
-    mcp_connect: true
+# MCP server access: clients send "Authorization: Bearer <token>"
+` + mcpSection + `
 
+# LLM Configuration
+` + llmConfigSection() + `
 # Task Selection
 selection:
   algorithm: "priority_dependency"
@@ -345,19 +427,102 @@ development:
   cycle_timebox_seconds: 3600
 `
 
-	if err := os.WriteFile("baton.yaml", []byte(config), 0644); err != nil {
+	// The file holds the MCP token, so only its owner may read it
+	if err := os.WriteFile("baton.yaml", []byte(config), 0600); err != nil {
 		return fmt.Errorf("failed to create baton.yaml: %w", err)
 	}
 	fmt.Println("   ✓ Created baton.yaml")
 	return nil
 }
 
+// databaseConfigSection returns the database settings of the generated
+// baton.yaml, keeping database_encryption on when it was on for baton init
+func databaseConfigSection() string {
+	section := "database: \"./baton.db\"\n"
+	if globalConfig.DatabaseEncryption {
+		section += fmt.Sprintf("database_encryption: true\ndatabase_key_env: %q\n", globalConfig.DatabaseKeyEnv)
+	}
+	return section
+}
+
 func createDatabaseWithTasks(tasks []wizard.Task) error {
-	// This would normally create the SQLite database and insert tasks
-	// For now, we'll just create an empty database file
-	// The actual implementation would use the storage package
+	// The database is created encrypted when database_encryption is on, as
+	// the baton.yaml written alongside it says
+	cfg := *globalConfig
+	cfg.Database = "baton.db"
+	store, err := openDatabase(&cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create baton.db: %w", err)
+	}
+	defer store.Close()
 
-	fmt.Println("   ✓ Created baton.db with initial tasks")
+	// Load the plan's requirements first so the tasks can be linked to them
+	_, requirements, err := plan.NewParser().Parse("plan.md")
+	if err != nil {
+		return fmt.Errorf("failed to parse plan.md: %w", err)
+	}
+	known := make(map[string]bool)
+	for _, req := range requirements {
+		if known[req.Key] {
+			continue
+		}
+		if err := store.CreateRequirement(req); err != nil {
+			return fmt.Errorf("failed to create requirement %s: %w", req.Key, err)
+		}
+		known[req.Key] = true
+	}
+
+	// Dependencies are given by title; keep the ones naming another task
+	idsByTitle := make(map[string]string, len(tasks))
+	for _, t := range tasks {
+		idsByTitle[strings.ToLower(t.Title)] = t.ID
+	}
+
+	storeTasks := make([]*storage.Task, 0, len(tasks))
+	links := make(map[string][]string, len(tasks))
+	var unknown []string
+	for _, t := range tasks {
+		tags := append([]string{}, t.Tags...)
+		if t.MVP != "" {
+			tags = append(tags, storage.MilestoneTagPrefix+t.MVP)
+		}
+		var deps []string
+		for _, dep := range t.Dependencies {
+			if id, ok := idsByTitle[strings.ToLower(dep)]; ok && id != t.ID {
+				deps = append(deps, id)
+			}
+		}
+		tagsJSON, _ := json.Marshal(tags)
+		depsJSON, _ := json.Marshal(deps)
+
+		storeTasks = append(storeTasks, &storage.Task{
+			ID:             t.ID,
+			Title:          t.Title,
+			Description:    t.Description,
+			State:          t.State,
+			Priority:       t.Priority,
+			Owner:          t.Owner,
+			Tags:           tagsJSON,
+			Dependencies:   depsJSON,
+			EstimatedHours: float64(t.EstimatedHours),
+		})
+		for _, key := range t.Requirements {
+			if known[key] {
+				links[t.ID] = append(links[t.ID], key)
+			} else {
+				unknown = append(unknown, key)
+			}
+		}
+	}
+
+	if err := store.CreateTasksWithRequirements(storeTasks, links); err != nil {
+		return fmt.Errorf("failed to create tasks: %w", err)
+	}
+
+	fmt.Printf("   ✓ Created baton.db with %d requirements and %d initial tasks\n", len(known), len(storeTasks))
+	if len(unknown) > 0 {
+		fmt.Printf("   ⚠️  Warning: Tasks referenced requirements not in plan.md: %s\n", strings.Join(unknown, ", "))
+	}
 	return nil
 }
 
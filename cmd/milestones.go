@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/report"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// milestonesCmd represents the milestones command
+var milestonesCmd = &cobra.Command{
+	Use:   "milestones",
+	Short: "Milestone commands",
+	Long: `Milestone commands for following milestones and signing them off.
+
+Milestones are named by "milestone:<name>" task tags and delivered in the order of
+selection.milestone_order, then by name. When the last task of a milestone reaches
+DONE, a milestone_summary artifact (scope delivered, requirements covered,
+outstanding risks) is recorded on it. With selection.milestone_signoff on, tasks of
+later milestones are not selected until the milestone is signed off.`,
+}
+
+// milestonesListCmd represents the milestones list command
+var milestonesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List milestones with their progress and sign-off",
+	RunE:  runMilestonesList,
+}
+
+// milestonesSignoffCmd represents the milestones signoff command
+var milestonesSignoffCmd = &cobra.Command{
+	Use:   "signoff <name>",
+	Short: "Sign off a finished milestone, releasing the next one",
+	Long: `Signoff approves a milestone whose tasks are all finished, after reviewing its
+milestone_summary artifact, so tasks of the next milestone become selectable.
+Signing off again replaces the earlier sign-off.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMilestonesSignoff,
+}
+
+func init() {
+	rootCmd.AddCommand(milestonesCmd)
+	milestonesCmd.AddCommand(milestonesListCmd)
+	milestonesCmd.AddCommand(milestonesSignoffCmd)
+
+	milestonesListCmd.Flags().Bool("json", false, "output in JSON format")
+
+	milestonesSignoffCmd.Flags().String("by", "", "who signs off (default $USER)")
+	milestonesSignoffCmd.Flags().String("note", "", "note to record with the sign-off")
+}
+
+func runMilestonesList(cmd *cobra.Command, args []string) error {
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	milestones, err := statemachine.NewTaskSelector(store, &globalConfig.Selection).ListMilestones()
+	if err != nil {
+		return fmt.Errorf("failed to list milestones: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(milestones, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(milestones) == 0 {
+		fmt.Printf("No milestones found: milestones are named by %s<name> task tags\n", storage.MilestoneTagPrefix)
+		return nil
+	}
+	for _, m := range milestones {
+		status := ""
+		switch {
+		case m.SignedOff != nil:
+			status = fmt.Sprintf("signed off by %s on %s", m.SignedOff.SignedOffBy, m.SignedOff.CreatedAt.Format("2006-01-02 15:04"))
+		case m.RemainingTasks == 0:
+			status = "awaiting sign-off"
+		case m.WaitingFor != "":
+			status = "waiting for " + m.WaitingFor
+		}
+		fmt.Printf("%-16s %3d/%-3d tasks done (%3.0f%%)  %s\n", m.Name, m.DoneTasks, m.TotalTasks, m.PercentDone, status)
+	}
+	return nil
+}
+
+func runMilestonesSignoff(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	note, _ := cmd.Flags().GetString("note")
+	signedOffBy, _ := cmd.Flags().GetString("by")
+	if signedOffBy == "" {
+		signedOffBy = os.Getenv("USER")
+	}
+	if signedOffBy == "" {
+		signedOffBy = "local"
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("milestones signoff")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if _, err := report.SignOffMilestone(store, name, signedOffBy, note, globalConfig.Acceptance.MandatoryTypes); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Milestone %s signed off by %s\n", name, signedOffBy)
+	if !globalConfig.Selection.MilestoneSignoff {
+		fmt.Println("Note: selection.milestone_signoff is off, so sign-off does not gate task selection")
+	}
+	return nil
+}
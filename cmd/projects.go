@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/storage"
+)
+
+// projectsCmd represents the projects command
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "Project commands",
+	Long: `Project commands for keeping several projects in one database.
+
+Tasks, requirements and artifacts belong to one project. Every command works in
+the current project: the one given with --project, else the one chosen with
+'baton projects switch', else "default". The web UI and MCP server serve the
+project they were started in.
+
+Requirement keys are unique within a project, so two projects can both have an
+FR-1; retired and deprecated keys are only reserved in their own project.`,
+}
+
+// projectsCreateCmd represents the projects create command
+var projectsCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a project",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProjectsCreate,
+}
+
+// projectsListCmd represents the projects list command
+var projectsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List projects with their task counts",
+	RunE:  runProjectsList,
+}
+
+// projectsSwitchCmd represents the projects switch command
+var projectsSwitchCmd = &cobra.Command{
+	Use:   "switch <name>",
+	Short: "Make a project the current one for this workspace",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProjectsSwitch,
+}
+
+func init() {
+	rootCmd.AddCommand(projectsCmd)
+	projectsCmd.AddCommand(projectsCreateCmd)
+	projectsCmd.AddCommand(projectsListCmd)
+	projectsCmd.AddCommand(projectsSwitchCmd)
+
+	projectsCreateCmd.Flags().String("description", "", "what the project is")
+	projectsCreateCmd.Flags().Bool("switch", false, "make the new project the current one")
+
+	projectsListCmd.Flags().Bool("json", false, "output in JSON format")
+}
+
+// projectFile holds the workspace's current project, as set by projects switch
+func projectFile() string {
+	return filepath.Join(globalConfig.Workspace, ".baton", "project")
+}
+
+// currentProject returns the project chosen with projects switch, "" when
+// none was
+func currentProject() string {
+	data, err := os.ReadFile(projectFile())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func runProjectsCreate(cmd *cobra.Command, args []string) error {
+	description, _ := cmd.Flags().GetString("description")
+	switchTo, _ := cmd.Flags().GetBool("switch")
+
+	workspaceLock, err := acquireWorkspaceLock("projects create")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openDatabase(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if err := store.CreateProject(&storage.Project{ID: args[0], Description: description}); err != nil {
+		return fmt.Errorf("failed to create project: %w", err)
+	}
+	fmt.Printf("✅ Created project %s\n", args[0])
+
+	if switchTo {
+		return switchProject(args[0])
+	}
+	return nil
+}
+
+func runProjectsList(cmd *cobra.Command, args []string) error {
+	// Initialize database
+	store, err := openDatabase(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	projects, err := store.ListProjects()
+	if err != nil {
+		return fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(projects, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	current := globalConfig.Project
+	if current == "" {
+		current = storage.DefaultProject
+	}
+	for _, project := range projects {
+		marker := " "
+		if project.ID == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %-20s %4d tasks  %s\n", marker, project.ID, project.Tasks, project.Description)
+	}
+	return nil
+}
+
+func runProjectsSwitch(cmd *cobra.Command, args []string) error {
+	// Initialize database
+	store, err := openDatabase(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if err := store.SetProject(args[0]); err != nil {
+		return err
+	}
+	return switchProject(args[0])
+}
+
+// switchProject makes name the workspace's current project
+func switchProject(name string) error {
+	if err := os.MkdirAll(filepath.Dir(projectFile()), 0755); err != nil {
+		return fmt.Errorf("failed to create .baton directory: %w", err)
+	}
+	if err := os.WriteFile(projectFile(), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to switch project: %w", err)
+	}
+	fmt.Printf("🔀 Switched to project %s\n", name)
+	return nil
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/cycle"
+	"baton/internal/replay"
+)
+
+// recordCmd represents the record command
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Execute one cycle and record it to a replay bundle",
+	Long: `Record executes one cycle like 'baton start' and captures everything that went
+into and came out of it: the workspace snapshot, the task selection, the prompt,
+the LLM response stream and every MCP call the agent made.
+
+The bundle can be re-executed with 'baton replay' to check engine and handshake
+changes against real traffic.`,
+	RunE: runRecord,
+}
+
+func init() {
+	rootCmd.AddCommand(recordCmd)
+	recordCmd.Flags().StringP("output", "o", "", "bundle path (default .baton/recordings/<timestamp>.json)")
+	recordCmd.Flags().Duration("timeout", 0, "overall timeout for cycle execution (default: timebox from config)")
+}
+
+func runRecord(cmd *cobra.Command, args []string) error {
+	if globalConfig.Development.DryRunDefault {
+		return fmt.Errorf("cannot record a dry-run cycle: the LLM is not invoked")
+	}
+
+	// Without --timeout the engine timeboxes the cycle from config
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	ctx, stopInterrupt := interruptContext()
+	defer stopInterrupt()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		name := time.Now().Format("20060102-150405") + ".json"
+		output = filepath.Join(globalConfig.Workspace, ".baton", "recordings", name)
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("record")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	llmClient, err := createLLMClient()
+	if err != nil {
+		return fmt.Errorf("failed to create LLM client: %w", err)
+	}
+
+	snapshot, err := replay.TakeSnapshot(store)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot workspace: %w", err)
+	}
+
+	recorder := replay.NewRecorder(globalConfig, snapshot)
+	engine := cycle.NewCycleEngine(store, globalConfig, llmClient)
+	engine.SetRecorder(recorder)
+
+	fmt.Println("⏺ Recording cycle execution")
+
+	result, cycleErr := engine.ExecuteCycle(ctx, false)
+	if cycleErr != nil {
+		recorder.RecordError(cycleErr)
+	}
+
+	// Save the bundle even for failed cycles; those are often the most useful to replay
+	if err := recorder.Bundle().Save(output); err != nil {
+		return fmt.Errorf("failed to save bundle: %w", err)
+	}
+	fmt.Printf("💾 Bundle saved to %s\n", output)
+	if globalConfig.DatabaseEncryption {
+		fmt.Println("⚠️  The bundle holds a snapshot of the encrypted database in plaintext; keep it as safe as the key")
+	}
+
+	if cycleErr != nil {
+		return fmt.Errorf("cycle execution failed: %w", cycleErr)
+	}
+
+	printCycleResult(result)
+	return nil
+}
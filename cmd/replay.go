@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/replay"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <bundle>",
+	Short: "Re-execute a recorded cycle against the mock LLM client",
+	Long: `Replay restores the workspace snapshot from a bundle written by 'baton record'
+into a scratch database, re-executes the cycle with the mock LLM client returning
+the recorded response and re-issuing the recorded MCP calls, and reports any
+difference from the recording. The real workspace is never touched.
+
+Exits non-zero when the replay diverges from the recording.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplay,
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().Bool("json", false, "output in JSON format")
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	bundle, err := replay.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	report, err := replay.Replay(context.Background(), bundle)
+	if err != nil {
+		return fmt.Errorf("replay failed: %w", err)
+	}
+
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("⏵ Replayed bundle recorded %s (baton %s)\n",
+			bundle.RecordedAt.Local().Format("2006-01-02 15:04:05"), bundle.BatonVersion)
+		if report.Replayed != nil {
+			fmt.Printf("Task ID: %s\n", report.Replayed.TaskID)
+			fmt.Printf("State Transition: %s → %s\n", report.Replayed.PrevState, report.Replayed.NextState)
+		}
+		if report.Matches() {
+			fmt.Println("✅ Replay matches the recording")
+		} else {
+			fmt.Printf("❌ Replay diverged in %d places:\n", len(report.Mismatches))
+			for _, mismatch := range report.Mismatches {
+				fmt.Printf("  - %s\n", mismatch)
+			}
+		}
+	}
+
+	if !report.Matches() {
+		return fmt.Errorf("replay diverged from recording")
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/llm"
+	"baton/internal/report"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate reports from workspace history",
+	Long:  `Report commands summarize completed work for releases and reviews.`,
+}
+
+// reportChangelogCmd represents the report changelog command
+var reportChangelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Generate a CHANGELOG section from completed tasks",
+	Long: `Changelog collects the tasks completed since a date or git tag, groups them by
+milestone (a "milestone:<name>" tag) or first tag, and renders a CHANGELOG section
+using each task's commit_summary artifact. Use --polish to have the LLM rewrite the
+section as release notes.`,
+	RunE: runReportChangelog,
+}
+
+// reportAcceptanceCmd represents the report acceptance command
+var reportAcceptanceCmd = &cobra.Command{
+	Use:   "acceptance",
+	Short: "Show which requirements have acceptance tests",
+	Long: `Acceptance maps the acceptance_tests artifacts attached to tasks onto the active
+requirements and shows how many tests each has and the last result reported. The
+results are read from the artifact's "## Results" section, one "- <KEY>: pass|fail|pending"
+line per requirement; the most recently updated artifact wins.
+
+Requirements of the types in acceptance.mandatory_types are flagged when no test
+covers them.`,
+	RunE: runReportAcceptance,
+}
+
+// reportMilestoneCmd represents the report milestone command
+var reportMilestoneCmd = &cobra.Command{
+	Use:   "milestone <name>",
+	Short: "Generate a milestone completion report",
+	Long: `Milestone reports a milestone's progress together with the acceptance test results
+of the requirements linked to its tasks. The report is refused while any mandatory
+requirement (see acceptance.mandatory_types) lacks an acceptance test.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReportMilestone,
+}
+
+// reportCostsCmd represents the report costs command
+var reportCostsCmd = &cobra.Command{
+	Use:   "costs",
+	Short: "Show what cycles cost, by day, agent, provider, outcome or task",
+	Long: `Costs adds up the LLM tokens, cost and duration of the cycles that started an agent
+since a date or git tag (today by default), grouped by day, agent, provider, outcome
+(success, failure, timeout or error) or task.`,
+	RunE: runReportCosts,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportChangelogCmd)
+	reportCmd.AddCommand(reportAcceptanceCmd)
+	reportCmd.AddCommand(reportMilestoneCmd)
+	reportCmd.AddCommand(reportCostsCmd)
+
+	reportChangelogCmd.Flags().String("since", "", "date (YYYY-MM-DD) or git tag to start from (required)")
+	reportChangelogCmd.Flags().String("heading", "Unreleased", "section heading, e.g. the release version")
+	reportChangelogCmd.Flags().Bool("polish", false, "rewrite the section with the LLM")
+	reportChangelogCmd.Flags().StringP("output", "o", "", "write the section to a file instead of stdout")
+	reportChangelogCmd.Flags().Bool("json", false, "output the collected tasks in JSON format")
+	reportChangelogCmd.MarkFlagRequired("since")
+
+	reportAcceptanceCmd.Flags().String("milestone", "", "only requirements linked to this milestone's tasks")
+	reportAcceptanceCmd.Flags().Bool("json", false, "output in JSON format")
+
+	reportMilestoneCmd.Flags().Bool("json", false, "output in JSON format")
+
+	reportCostsCmd.Flags().String("since", "", "date (YYYY-MM-DD) or git tag to start from (default today)")
+	reportCostsCmd.Flags().String("until", "", "date (YYYY-MM-DD) or git tag to stop before (default now)")
+	reportCostsCmd.Flags().String("by", "day", "group cycles by day, agent, provider, outcome or task")
+	reportCostsCmd.Flags().Bool("json", false, "output in JSON format")
+}
+
+func runReportChangelog(cmd *cobra.Command, args []string) error {
+	sinceValue, _ := cmd.Flags().GetString("since")
+	since, err := report.ResolveSince(globalConfig.Workspace, sinceValue)
+	if err != nil {
+		return err
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	changelog, err := report.BuildChangelog(store, since)
+	if err != nil {
+		return err
+	}
+
+	// Check for JSON output
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	if jsonOutput {
+		data, err := json.MarshalIndent(changelog, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	heading, _ := cmd.Flags().GetString("heading")
+	section := changelog.Markdown(heading)
+
+	polish, _ := cmd.Flags().GetBool("polish")
+	if polish && len(changelog.Groups) > 0 {
+		llmClient, err := llm.NewClient(globalConfig.LLM)
+		if err != nil {
+			return fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		section, err = report.Polish(llmClient, changelog, section)
+		if err != nil {
+			return err
+		}
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output != "" {
+		if err := os.WriteFile(output, []byte(section), 0644); err != nil {
+			return fmt.Errorf("failed to write changelog: %w", err)
+		}
+		fmt.Printf("📝 Wrote changelog section to %s\n", output)
+		return nil
+	}
+
+	fmt.Print(section)
+	return nil
+}
+
+func runReportAcceptance(cmd *cobra.Command, args []string) error {
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	milestone, _ := cmd.Flags().GetString("milestone")
+	acceptance, err := report.BuildAcceptanceReport(store, milestone, globalConfig.Acceptance.MandatoryTypes)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(acceptance, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printAcceptance(acceptance)
+	return nil
+}
+
+func runReportMilestone(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	selector := statemachine.NewTaskSelector(store, &globalConfig.Selection)
+	progress, err := selector.GetMilestoneProgress(name)
+	if err != nil {
+		return fmt.Errorf("failed to get milestone progress: %w", err)
+	}
+	if progress == nil {
+		return fmt.Errorf("milestone %s not found: milestones are named by %s<name> task tags", name, storage.MilestoneTagPrefix)
+	}
+
+	acceptance, err := report.BuildAcceptanceReport(store, name, globalConfig.Acceptance.MandatoryTypes)
+	if err != nil {
+		return err
+	}
+	if !acceptance.Complete() {
+		return fmt.Errorf("milestone %s can't be reported complete: mandatory requirements without acceptance tests: %s",
+			name, strings.Join(acceptance.Untested, ", "))
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"milestone":  progress,
+			"acceptance": acceptance,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("🏁 Milestone %s: %d/%d tasks done (%.0f%%)\n", name, progress.DoneTasks, progress.TotalTasks, progress.PercentDone)
+	for _, task := range progress.Remaining {
+		fmt.Printf("  ⏳ %s %s (%s)\n", task.ID[:8], task.Title, task.State)
+	}
+	fmt.Println()
+	printAcceptance(acceptance)
+	return nil
+}
+
+func runReportCosts(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if value, _ := cmd.Flags().GetString("since"); value != "" {
+		var err error
+		if since, err = report.ResolveSince(globalConfig.Workspace, value); err != nil {
+			return err
+		}
+	}
+	var until time.Time
+	if value, _ := cmd.Flags().GetString("until"); value != "" {
+		var err error
+		if until, err = report.ResolveSince(globalConfig.Workspace, value); err != nil {
+			return err
+		}
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	by, _ := cmd.Flags().GetString("by")
+	costs, err := report.BuildCostReport(store, since, until, by)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(costs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	period := "since " + since.Format("2006-01-02 15:04")
+	if !until.IsZero() {
+		period += ", until " + until.Format("2006-01-02 15:04")
+	}
+	fmt.Printf("💰 Cycle costs %s, by %s\n", period, by)
+	if costs.Total.Cycles == 0 {
+		fmt.Println("No cycles")
+		return nil
+	}
+	for _, group := range costs.Groups {
+		fmt.Printf("  %-20s %s\n", group.Key, formatCycleTotals(group))
+	}
+	fmt.Printf("  %-20s %s\n", "Total", formatCycleTotals(costs.Total))
+	return nil
+}
+
+// printAcceptance prints requirement acceptance test coverage as a table
+func printAcceptance(acceptance *report.AcceptanceReport) {
+	if len(acceptance.Requirements) == 0 {
+		fmt.Println("No requirements found")
+		return
+	}
+
+	for _, row := range acceptance.Requirements {
+		status := "untested"
+		if row.Tests > 0 {
+			status = fmt.Sprintf("%s (%d tests)", row.LastStatus, row.Tests)
+		}
+		marker := "  "
+		switch {
+		case row.Mandatory && row.Tests == 0:
+			marker = "❌"
+		case row.LastStatus == report.TestFail:
+			marker = "⚠️"
+		case row.LastStatus == report.TestPass:
+			marker = "✅"
+		}
+		fmt.Printf("%s %-8s %-20s %s\n", marker, row.Key, status, row.Title)
+	}
+
+	if len(acceptance.Untested) > 0 {
+		fmt.Printf("\n%d mandatory requirements lack acceptance tests: %s\n", len(acceptance.Untested), strings.Join(acceptance.Untested, ", "))
+	}
+	if len(acceptance.Failing) > 0 {
+		fmt.Printf("%d requirements are failing their last acceptance test: %s\n", len(acceptance.Failing), strings.Join(acceptance.Failing, ", "))
+	}
+}
@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/storage"
+)
+
+// requirementsCmd represents the requirements command
+var requirementsCmd = &cobra.Command{
+	Use:   "requirements",
+	Short: "Requirement commands",
+	Long: `Requirement commands for listing, adding and deprecating requirements and
+keeping their keys in order.
+
+Keys are allocated per series (FR-, NFR-, FR-P, ...) as the next number after the
+highest ever used. Keys of deprecated requirements and keys given up by renumbering
+are never issued again.`,
+}
+
+// requirementsListCmd represents the requirements list command
+var requirementsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List requirements",
+	RunE:  runRequirementsList,
+}
+
+// requirementsAddCmd represents the requirements add command
+var requirementsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a requirement under the next free key",
+	RunE:  runRequirementsAdd,
+}
+
+// requirementsDeprecateCmd represents the requirements deprecate command
+var requirementsDeprecateCmd = &cobra.Command{
+	Use:   "deprecate <key>",
+	Short: "Deprecate a requirement, retiring its key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRequirementsDeprecate,
+}
+
+// requirementsRenumberCmd represents the requirements renumber command
+var requirementsRenumberCmd = &cobra.Command{
+	Use:   "renumber",
+	Short: "Close gaps in requirement key series",
+	Long: `Renumber moves active requirements down to the lowest numbers in their series
+that no requirement has ever held, keeping their order. Deprecated and previously
+retired keys are skipped, and each old key is retired so it is never reissued.
+
+Task links follow the requirement, and mentions of old keys in task titles,
+descriptions and the plan file are rewritten. Use --dry-run to list the changes.`,
+	RunE: runRequirementsRenumber,
+}
+
+// requirementsCoverageCmd represents the requirements coverage command
+var requirementsCoverageCmd = &cobra.Command{
+	Use:   "coverage [key]",
+	Short: "Show which requirements have tasks linked to them",
+	Long: `Coverage lists the active requirements with the number of tasks linked to each,
+then the requirements no task is linked to and the tasks linked to no requirement.
+Tasks are linked when they are created with requirements, e.g. by the init wizard.
+
+Given a requirement key, it lists the tasks linked to that requirement instead.
+Deleted tasks and deprecated requirements are left out.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRequirementsCoverage,
+}
+
+func init() {
+	rootCmd.AddCommand(requirementsCmd)
+	requirementsCmd.AddCommand(requirementsListCmd)
+	requirementsCmd.AddCommand(requirementsAddCmd)
+	requirementsCmd.AddCommand(requirementsDeprecateCmd)
+	requirementsCmd.AddCommand(requirementsRenumberCmd)
+	requirementsCmd.AddCommand(requirementsCoverageCmd)
+
+	requirementsListCmd.Flags().String("type", "", "filter by type (functional, nonfunctional, constraint, risk, acceptance)")
+	requirementsListCmd.Flags().Bool("json", false, "output in JSON format")
+
+	requirementsAddCmd.Flags().String("title", "", "requirement title (required)")
+	requirementsAddCmd.Flags().String("text", "", "requirement text (default: the title)")
+	requirementsAddCmd.Flags().String("type", "functional", "requirement type")
+	requirementsAddCmd.Flags().String("prefix", "", "key series, e.g. FR or FR-P (default: from the type)")
+	requirementsAddCmd.MarkFlagRequired("title")
+
+	requirementsRenumberCmd.Flags().String("prefix", "", "only renumber this key series")
+
+	requirementsCoverageCmd.Flags().Bool("uncovered", false, "only list requirements no task is linked to")
+	requirementsCoverageCmd.Flags().Bool("json", false, "output in JSON format")
+}
+
+func runRequirementsList(cmd *cobra.Command, args []string) error {
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	reqType, _ := cmd.Flags().GetString("type")
+	requirements, err := store.ListRequirements(reqType)
+	if err != nil {
+		return fmt.Errorf("failed to list requirements: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(requirements, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(requirements) == 0 {
+		fmt.Println("No requirements found")
+		return nil
+	}
+	for _, req := range requirements {
+		status := ""
+		if req.Status == storage.RequirementDeprecated {
+			status = " (deprecated)"
+		}
+		fmt.Printf("%-8s %-13s %s%s\n", req.Key, req.Type, req.Title, status)
+	}
+	return nil
+}
+
+func runRequirementsCoverage(cmd *cobra.Command, args []string) error {
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	uncoveredOnly, _ := cmd.Flags().GetBool("uncovered")
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if len(args) == 1 {
+		tasks, err := store.ListRequirementTasks(args[0])
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			if tasks == nil {
+				tasks = []*storage.Task{}
+			}
+			return printRequirementsJSON(tasks)
+		}
+		if len(tasks) == 0 {
+			fmt.Printf("No tasks are linked to %s\n", args[0])
+			return nil
+		}
+		for _, task := range tasks {
+			fmt.Printf("%s  %-22s %s\n", task.ID[:8], task.State, task.Title)
+		}
+		return nil
+	}
+
+	coverage, err := store.GetLinkCoverage()
+	if err != nil {
+		return fmt.Errorf("failed to get requirement coverage: %w", err)
+	}
+	uncovered := coverage.Uncovered()
+
+	if jsonOutput {
+		if uncoveredOnly {
+			if uncovered == nil {
+				uncovered = []*storage.Requirement{}
+			}
+			return printRequirementsJSON(uncovered)
+		}
+		return printRequirementsJSON(coverage)
+	}
+
+	if len(coverage.Requirements) == 0 {
+		fmt.Println("No active requirements found")
+		return nil
+	}
+
+	if !uncoveredOnly {
+		fmt.Println("📋 Requirement Coverage:")
+		for _, links := range coverage.Requirements {
+			fmt.Printf("  %-8s %3d tasks  %s\n", links.Requirement.Key, len(links.Tasks), links.Requirement.Title)
+		}
+		fmt.Println()
+	}
+
+	if len(uncovered) == 0 {
+		fmt.Println("✅ Every active requirement has linked tasks")
+	} else {
+		fmt.Printf("⚠️ %d of %d requirements have no linked tasks:\n", len(uncovered), len(coverage.Requirements))
+		for _, req := range uncovered {
+			fmt.Printf("  %-8s %s\n", req.Key, req.Title)
+		}
+	}
+
+	if !uncoveredOnly && len(coverage.UnlinkedTasks) > 0 {
+		fmt.Printf("\n%d tasks are linked to no requirement:\n", len(coverage.UnlinkedTasks))
+		for _, task := range coverage.UnlinkedTasks {
+			fmt.Printf("  %s  %s\n", task.ID[:8], task.Title)
+		}
+	}
+	return nil
+}
+
+// printRequirementsJSON prints v as indented JSON
+func printRequirementsJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func runRequirementsAdd(cmd *cobra.Command, args []string) error {
+	title, _ := cmd.Flags().GetString("title")
+	text, _ := cmd.Flags().GetString("text")
+	reqType, _ := cmd.Flags().GetString("type")
+	prefix, _ := cmd.Flags().GetString("prefix")
+	if text == "" {
+		text = title
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("requirements add")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	req := &storage.Requirement{Title: title, Text: text, Type: reqType}
+	if err := store.AllocateRequirement(req, prefix); err != nil {
+		return fmt.Errorf("failed to add requirement: %w", err)
+	}
+
+	fmt.Printf("✅ Added %s: %s\n", req.Key, req.Title)
+	return nil
+}
+
+func runRequirementsDeprecate(cmd *cobra.Command, args []string) error {
+	workspaceLock, err := acquireWorkspaceLock("requirements deprecate")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if err := store.DeprecateRequirement(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Deprecated %s; the key will not be reused\n", args[0])
+	return nil
+}
+
+func runRequirementsRenumber(cmd *cobra.Command, args []string) error {
+	prefix, _ := cmd.Flags().GetString("prefix")
+	dryRun := globalConfig.Development.DryRunDefault
+
+	if !dryRun {
+		workspaceLock, err := acquireWorkspaceLock("requirements renumber")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	renames, err := store.PlanRequirementRenumber(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to plan renumbering: %w", err)
+	}
+	if len(renames) == 0 {
+		fmt.Println("✅ Requirement keys have no gaps to close")
+		return nil
+	}
+
+	keys := make(map[string]string, len(renames))
+	for _, rename := range renames {
+		keys[rename.OldKey] = rename.NewKey
+		fmt.Printf("  %s → %s  %s (%d task links)\n", rename.OldKey, rename.NewKey, rename.Title, rename.TaskLinks)
+	}
+
+	// Rewrite the plan's references too, or the next ingest would recreate the old keys
+	var planContent, newPlanContent string
+	if globalConfig.PlanFile != "" {
+		if content, err := os.ReadFile(globalConfig.PlanFile); err == nil {
+			planContent = string(content)
+			newPlanContent = storage.ReplaceRequirementKeys(planContent, keys)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read plan file: %w", err)
+		}
+	}
+	planChanged := newPlanContent != planContent
+
+	if dryRun {
+		fmt.Printf("Dry run: would renumber %d requirements", len(renames))
+		if planChanged {
+			fmt.Printf(" and update references in %s", globalConfig.PlanFile)
+		}
+		fmt.Println()
+		return nil
+	}
+
+	rewritten, err := store.RenumberRequirements(renames)
+	if err != nil {
+		return fmt.Errorf("failed to renumber requirements: %w", err)
+	}
+	if planChanged {
+		if err := os.WriteFile(globalConfig.PlanFile, []byte(newPlanContent), 0644); err != nil {
+			return fmt.Errorf("renumbered requirements but failed to update %s: %w", globalConfig.PlanFile, err)
+		}
+	}
+
+	fmt.Printf("✅ Renumbered %d requirements and updated %d tasks", len(renames), rewritten)
+	if planChanged {
+		fmt.Printf(" and %s", globalConfig.PlanFile)
+	}
+	fmt.Println()
+	return nil
+}
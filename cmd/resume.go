@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/cycle"
+	"baton/internal/notify"
+	"baton/internal/storage"
+)
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Finish or roll back the cycles of a run that died",
+	Long: `Every cycle checkpoints how far it got: the task it claimed and that task's state,
+the agent, the worktree it works in and its phase (claimed, executing, handshake,
+merging or recording). When baton or the machine dies mid-cycle, the checkpoint is
+left unfinished, the task stays locked and its worktree is left behind.
+
+Resume settles each such cycle. If its task already moved, e.g. the agent updated
+the state before the run died, the cycle is completed: its worktree is merged and
+its audit entry recorded. Otherwise the cycle is rolled back: its worktree is
+discarded (or its branch kept, with isolation.keep_failed) and a failed audit entry
+recorded. --rollback rolls back every cycle, moving tasks back to the state they
+had when the cycle claimed them. Either way the cycle's task and area locks are
+released and a note on the task says what happened.
+
+With --dry-run the plan is only described.`,
+	RunE: runResume,
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+	resumeCmd.Flags().Bool("rollback", false, "roll back interrupted cycles even when their task moved")
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	rollback, _ := cmd.Flags().GetBool("rollback")
+	dryRun := globalConfig.Development.DryRunDefault
+
+	// Holding the lock guarantees no run is in progress, so every unfinished
+	// checkpoint belongs to one that died
+	if !dryRun {
+		workspaceLock, err := acquireWorkspaceLock("resume")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+
+	cycles, err := cycle.FindInterrupted(store, rollback)
+	if err != nil {
+		return err
+	}
+	if len(cycles) == 0 {
+		fmt.Println("No interrupted cycles")
+		return nil
+	}
+
+	for _, ic := range cycles {
+		cp := ic.Checkpoint
+		action := "Completing"
+		switch {
+		case dryRun && ic.Action == storage.CheckpointCompleted:
+			action = "Dry run: would complete"
+		case dryRun:
+			action = "Dry run: would roll back"
+		case ic.Action == storage.CheckpointRolledBack:
+			action = "Rolling back"
+		}
+		fmt.Printf("%s cycle %s on task %s (interrupted in its %s phase, started %s): %s\n",
+			action, cp.CycleID, cp.TaskID, cp.Phase, cp.StartedAt.Format("2006-01-02 15:04:05"), ic.Reason)
+		if dryRun {
+			continue
+		}
+
+		done, err := cycle.Resume(store, globalConfig, ic)
+		for _, step := range done {
+			fmt.Printf("  - %s\n", step)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resume cycle %s: %w", cp.CycleID, err)
+		}
+	}
+	if dryRun {
+		fmt.Println("(a run in progress would also show its cycles here)")
+	}
+	return nil
+}
+
+// warnInterruptedCycles points at 'baton resume' when a run died mid-cycle.
+// Call it while holding the workspace lock.
+func warnInterruptedCycles(store *storage.Store) {
+	checkpoints, err := store.ListUnfinishedCheckpoints()
+	if err != nil || len(checkpoints) == 0 {
+		return
+	}
+	fmt.Printf("⚠️  %d cycle(s) were interrupted, leaving their tasks locked; run 'baton resume' to settle them\n", len(checkpoints))
+}
@@ -3,11 +3,15 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"baton/internal/config"
+	"baton/internal/lock"
+	"baton/internal/plan"
+	"baton/internal/storage"
 	"baton/pkg/version"
 )
 
@@ -16,6 +20,8 @@ var (
 	workspace  string
 	dryRun     bool
 	verbose    bool
+	forceLock  bool
+	project    string
 	globalConfig *config.Config
 )
 
@@ -44,6 +50,8 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&workspace, "workspace", "./", "workspace directory")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "show what would be done without making changes")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVar(&forceLock, "force", false, "take over the workspace lock even if another baton process holds it")
+	rootCmd.PersistentFlags().StringVar(&project, "project", "", "project to work in (default: the one chosen with 'baton projects switch')")
 
 	// Bind flags to viper
 	viper.BindPFlag("workspace", rootCmd.PersistentFlags().Lookup("workspace"))
@@ -67,4 +75,55 @@ func initConfig() {
 	if dryRun {
 		globalConfig.Development.DryRunDefault = true
 	}
-}
\ No newline at end of file
+
+	globalConfig.Project = project
+	if globalConfig.Project == "" {
+		globalConfig.Project = currentProject()
+	}
+
+	// Timestamps are stored in UTC; everything shown to people, from CLI
+	// output to the web API, uses the configured timezone
+	time.Local = globalConfig.Location()
+}
+
+// acquireWorkspaceLock takes the single-writer workspace lock for commands
+// that mutate the database or run an MCP server
+func acquireWorkspaceLock(command string) (*lock.Lock, error) {
+	workspaceLock, err := lock.Acquire(globalConfig.Workspace, command, forceLock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire workspace lock: %w", err)
+	}
+	return workspaceLock, nil
+}
+
+// openDatabase opens cfg's database, decrypting it when database_encryption
+// is on
+func openDatabase(cfg *config.Config) (*storage.Store, error) {
+	key, err := cfg.DatabaseKey()
+	if err != nil {
+		return nil, err
+	}
+	return storage.OpenStore(cfg.Database, key)
+}
+
+// openStore opens cfg's database scoped to cfg's project
+func openStore(cfg *config.Config) (*storage.Store, error) {
+	store, err := openDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.SetProject(cfg.Project); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// checkPlanFile reports whether agents can read the configured plan file,
+// nil when no plan file is configured
+func checkPlanFile() error {
+	if globalConfig.PlanFile == "" {
+		return nil
+	}
+	return plan.Check(globalConfig.PlanFile)
+}
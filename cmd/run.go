@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/cycle"
+	"baton/internal/llm"
+	"baton/internal/notify"
+	"baton/internal/plan"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Execute cycles until no tasks remain",
+	Long: `Run executes cycles one after another, as repeated 'baton start' calls would,
+until no selectable task is left or a limit is reached:
+
+  --max-cycles     stop after this many cycles
+  --max-duration   start no new cycle once this much time has passed
+  --until-state    only work tasks that have not reached this state in the workflow,
+                   and stop once none are left (e.g. ready_for_commit)
+  --stop-on-error  stop after the first failed cycle instead of moving on
+
+--parallel runs that many cycles at once, each on its own task: a cycle locks
+its task so no other worker selects it, and all agents share one MCP server.
+Turn on selection.area_locks to also keep workers out of the same code area.
+
+--cooldown pauses between cycles. On SIGINT or SIGTERM the cycles in flight are
+allowed to finish and no new one starts; a second signal aborts them. A summary
+of every cycle is printed at the end. A spent daily LLM budget or an
+unavailable plan (with plan_unavailable: pause) always ends the run.`,
+	RunE: runRun,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().Int("max-cycles", 0, "stop after this many cycles (0 for no limit; 1 in dry-run mode)")
+	runCmd.Flags().Duration("max-duration", 0, "start no new cycle after this long (0 for no limit)")
+	runCmd.Flags().String("until-state", "", "work tasks up to this state, then stop")
+	runCmd.Flags().Bool("stop-on-error", false, "stop after the first failed cycle")
+	runCmd.Flags().Duration("cooldown", 2*time.Second, "pause between cycles")
+	runCmd.Flags().Int("parallel", 1, "number of cycles to run at once")
+	runCmd.Flags().Bool("quiet", false, "don't print the agents' output while cycles run")
+}
+
+// runSummary tallies the cycles of a run
+type runSummary struct {
+	started    time.Time
+	cycles     []*storage.CycleResult
+	failures   []string // errors of cycles that did not produce a result
+	stopReason string
+}
+
+// runOptions are the limits and settings of a run
+type runOptions struct {
+	maxCycles   int
+	maxDuration time.Duration
+	until       storage.State
+	stopOnError bool
+	cooldown    time.Duration
+	parallel    int
+	quiet       bool
+	dryRun      bool
+	command     string // named in the workspace lock
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	opts := runOptions{dryRun: globalConfig.Development.DryRunDefault, command: "run"}
+	opts.maxCycles, _ = cmd.Flags().GetInt("max-cycles")
+	opts.maxDuration, _ = cmd.Flags().GetDuration("max-duration")
+	untilStr, _ := cmd.Flags().GetString("until-state")
+	opts.stopOnError, _ = cmd.Flags().GetBool("stop-on-error")
+	opts.cooldown, _ = cmd.Flags().GetDuration("cooldown")
+	opts.parallel, _ = cmd.Flags().GetInt("parallel")
+	opts.quiet, _ = cmd.Flags().GetBool("quiet")
+
+	if opts.maxCycles < 0 || opts.maxDuration < 0 || opts.cooldown < 0 {
+		return fmt.Errorf("--max-cycles, --max-duration and --cooldown must not be negative")
+	}
+	if opts.parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+	if untilStr != "" {
+		opts.until = storage.NormalizeState(untilStr)
+		if _, err := statemachine.GetAllowedTransitions(opts.until); err != nil {
+			return fmt.Errorf("invalid --until-state: %w", err)
+		}
+	}
+	// Dry runs move nothing, so the same task would be selected forever
+	if opts.dryRun && opts.maxCycles == 0 {
+		opts.maxCycles = 1
+	}
+
+	ctx, cycleCtx, stop := runSignals()
+	defer stop()
+
+	summary, err := executeRun(ctx, cycleCtx, opts)
+	if err != nil {
+		return err
+	}
+	summary.print()
+	return nil
+}
+
+// runSignals returns the contexts of a run that signals end: the first
+// SIGINT or SIGTERM cancels ctx, to start no new cycles, and a second one
+// cycleCtx, to abort the cycles in flight. stop releases the signals.
+func runSignals() (ctx, cycleCtx context.Context, stop func()) {
+	ctx, stopNew := context.WithCancel(context.Background())
+	cycleCtx, cancelCycles := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			fmt.Println("\n⏸  Stopping after the current cycles (interrupt again to abort them)")
+			stopNew()
+		case <-cycleCtx.Done():
+			return
+		}
+		select {
+		case <-signals:
+			fmt.Println("\n⏹  Aborting the current cycles")
+			cancelCycles()
+		case <-cycleCtx.Done():
+		}
+	}()
+
+	return ctx, cycleCtx, func() {
+		signal.Stop(signals)
+		cancelCycles()
+		stopNew()
+	}
+}
+
+// executeRun executes cycles as opts say until no selectable task is left or
+// a limit is reached. Cancelling ctx starts no new cycle; cancelling cycleCtx
+// aborts the cycles in flight.
+func executeRun(ctx, cycleCtx context.Context, opts runOptions) (*runSummary, error) {
+	dryRun := opts.dryRun
+	fmt.Printf("⏱ Starting run (dry-run: %v, parallel: %d)\n", dryRun, opts.parallel)
+
+	// Fail before taking the lock when a missing plan would pause every cycle
+	if err := checkPlanFile(); err != nil {
+		if globalConfig.PlanUnavailable == "pause" && !dryRun {
+			return nil, fmt.Errorf("run paused: %w (fix the plan or set plan_unavailable: continue)", err)
+		}
+		fmt.Printf("⚠️  %v; agents will work without the plan\n", err)
+	}
+
+	// Dry runs never write, so they can run alongside another writer
+	if !dryRun {
+		workspaceLock, err := acquireWorkspaceLock(opts.command)
+		if err != nil {
+			return nil, err
+		}
+		defer workspaceLock.Release()
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+	store.SetBlobThreshold(globalConfig.Artifacts.BlobThresholdBytes)
+	if !dryRun {
+		warnInterruptedCycles(store)
+	}
+
+	// Initialize LLM client
+	llmClient, err := createLLMClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+
+	pool := cycle.NewPool(store, globalConfig, llmClient, opts.parallel)
+	if opts.until != "" {
+		pool.SetUntilState(opts.until)
+	}
+	if !opts.quiet {
+		if opts.parallel > 1 {
+			pool.SetOutputHandler(printTaskOutput)
+		} else {
+			pool.SetOutputHandler(printCycleOutput)
+		}
+	}
+
+	summary := &runSummary{started: time.Now()}
+	noTasksLeft, err := pool.Run(cycleCtx, cycle.PoolRun{
+		DryRun:   dryRun,
+		Cooldown: opts.cooldown,
+		Next: func(worker, running int) bool {
+			if summary.stopReason != "" {
+				return false
+			}
+			if reason := summary.limitReached(ctx, opts.maxCycles, opts.maxDuration, running); reason != "" {
+				summary.stopReason = reason
+				return false
+			}
+			if opts.parallel == 1 {
+				fmt.Printf("\n━━ Cycle %d ━━\n", summary.count()+1)
+			}
+			return true
+		},
+		Report: func(c cycle.PoolCycle) bool {
+			if opts.parallel > 1 {
+				fmt.Printf("\n━━ Cycle %d (worker %d) ━━\n", summary.count()+1, c.Worker)
+			}
+			if c.Err != nil {
+				fmt.Printf("❌ Cycle failed: %v\n", c.Err)
+				summary.failures = append(summary.failures, c.Err.Error())
+				if reason := runStopError(c.Err); reason != "" {
+					summary.stopReason = reason
+					return false
+				}
+				if opts.stopOnError || cycleCtx.Err() != nil {
+					summary.stopReason = "stopped on error: " + c.Err.Error()
+					return false
+				}
+				return true
+			}
+			printCycleResult(c.Result)
+			summary.cycles = append(summary.cycles, c.Result)
+			if !c.Result.Success && opts.stopOnError {
+				summary.stopReason = "stopped on a failed cycle"
+				return false
+			}
+			return true
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if noTasksLeft && summary.stopReason == "" {
+		// Nothing left to do is how a run normally ends
+		summary.stopReason = "no selectable tasks left"
+		if opts.until != "" {
+			summary.stopReason = fmt.Sprintf("no selectable tasks left before %s", opts.until)
+		}
+		fmt.Println("No selectable tasks left")
+	}
+	return summary, nil
+}
+
+// count returns the number of cycles the run has finished
+func (s *runSummary) count() int {
+	return len(s.cycles) + len(s.failures)
+}
+
+// limitReached returns why the run must not start another cycle while
+// running others are in flight, or ""
+func (s *runSummary) limitReached(ctx context.Context, maxCycles int, maxDuration time.Duration, running int) string {
+	switch {
+	case ctx.Err() != nil:
+		return "interrupted"
+	case maxCycles > 0 && s.count()+running >= maxCycles:
+		return fmt.Sprintf("reached --max-cycles %d", maxCycles)
+	case maxDuration > 0 && time.Since(s.started) >= maxDuration:
+		return fmt.Sprintf("reached --max-duration %s", maxDuration)
+	default:
+		return ""
+	}
+}
+
+// runStopError returns why a cycle error ends the run regardless of
+// --stop-on-error, or "" when the next cycle may fare better
+func runStopError(err error) string {
+	var planErr *plan.UnavailableError
+	if errors.As(err, &planErr) {
+		return "the plan is unavailable"
+	}
+	var budgetErr *llm.BudgetExceededError
+	if errors.As(err, &budgetErr) && budgetErr.Scope == "day" {
+		return "the daily LLM budget is spent"
+	}
+	return ""
+}
+
+// print writes the final report of the run
+// succeeded returns how many cycles succeeded
+func (s *runSummary) succeeded() int {
+	succeeded := 0
+	for _, result := range s.cycles {
+		if result.Success {
+			succeeded++
+		}
+	}
+	return succeeded
+}
+
+func (s *runSummary) print() {
+	succeeded := s.succeeded()
+	var promptTokens, completionTokens int
+	var cost float64
+	moves := make(map[string][]string) // state transitions by task
+	for _, result := range s.cycles {
+		promptTokens += result.PromptTokens
+		completionTokens += result.CompletionTokens
+		cost += result.CostUSD
+		moves[result.TaskID] = append(moves[result.TaskID], fmt.Sprintf("%s → %s", result.PrevState, result.NextState))
+	}
+
+	fmt.Println()
+	fmt.Println("📋 Run Summary")
+	fmt.Printf("Stopped: %s\n", s.stopReason)
+	fmt.Printf("Cycles: %d (%d succeeded, %d failed)\n",
+		s.count(), succeeded, len(s.cycles)-succeeded+len(s.failures))
+	fmt.Printf("Duration: %v\n", time.Since(s.started).Round(time.Second))
+	if promptTokens > 0 || cost > 0 {
+		fmt.Printf("LLM Usage: %d prompt + %d completion tokens, $%.4f\n", promptTokens, completionTokens, cost)
+	}
+
+	if len(moves) > 0 {
+		taskIDs := make([]string, 0, len(moves))
+		for taskID := range moves {
+			taskIDs = append(taskIDs, taskID)
+		}
+		sort.Strings(taskIDs)
+		fmt.Println("Tasks:")
+		for _, taskID := range taskIDs {
+			fmt.Printf("  %s: %s\n", taskID, strings.Join(moves[taskID], ", "))
+		}
+	}
+	if len(s.failures) > 0 {
+		fmt.Println("Errors:")
+		for _, failure := range s.failures {
+			fmt.Printf("  - %s\n", failure)
+		}
+	}
+}
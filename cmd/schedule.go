@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/config"
+	"baton/internal/schedule"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// detachedEnv marks the background process 'baton schedule --detach' starts
+const detachedEnv = "BATON_SCHEDULE_DETACHED"
+
+// scheduleCmd represents the schedule command
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run cycles unattended on a schedule",
+	Long: `Schedule keeps running and starts a run, as 'baton run' would, whenever the cron
+expression of one of the configured schedules matches:
+
+  schedules:
+    - name: nightly
+      cron: "0 22 * * 1-5"    # 22:00 on weeknights, in the configured timezone
+      max_cycles: 5
+      max_duration_minutes: 120
+      until_state: ready_for_commit
+      parallel: 1
+      stop_on_error: false
+
+Expressions have the five cron fields (minute hour day-of-month month day-of-week)
+or are one of @hourly, @daily, @weekly, @monthly and @yearly. A run takes the
+workspace lock for its duration only; a run that finds the lock held is skipped and
+recorded as such. A time that comes while another run is still going is skipped.
+
+The scheduler writes its PID, the next run of each schedule and the outcome of its
+last runs to .baton/schedule.json, which 'baton schedule status' shows. --detach
+starts it in the background, logging to .baton/schedule.log. SIGINT, SIGTERM or
+'baton schedule stop' stop it once the current run's cycles finish; a second
+signal aborts them.`,
+	RunE: runSchedule,
+}
+
+// scheduleStatusCmd represents the schedule status command
+var scheduleStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the scheduler runs, when each schedule runs next and the last runs",
+	RunE:  runScheduleStatus,
+}
+
+// scheduleStopCmd represents the schedule stop command
+var scheduleStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the scheduler once its current cycles finish",
+	Long: `Stop asks the running scheduler to exit: it starts no new run or cycle, and exits
+once the cycles in flight finish. With --abort those cycles are aborted too.`,
+	RunE: runScheduleStop,
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleStatusCmd)
+	scheduleCmd.AddCommand(scheduleStopCmd)
+
+	scheduleCmd.Flags().Bool("detach", false, "run the scheduler in the background")
+	scheduleCmd.Flags().Duration("cooldown", 2*time.Second, "pause between cycles")
+	scheduleCmd.Flags().Bool("quiet", false, "don't print the agents' output while cycles run")
+
+	scheduleStatusCmd.Flags().Bool("json", false, "output in JSON format")
+
+	scheduleStopCmd.Flags().Bool("abort", false, "abort the cycles in flight instead of letting them finish")
+	scheduleStopCmd.Flags().Duration("wait", 10*time.Second, "how long to wait for the scheduler to exit")
+}
+
+// scheduled is a configured schedule with its parsed cron expression
+type scheduled struct {
+	config.Schedule
+	cron *schedule.Cron
+}
+
+// loadSchedules parses the configured schedules and checks their states
+func loadSchedules() ([]*scheduled, error) {
+	if len(globalConfig.Schedules) == 0 {
+		return nil, fmt.Errorf("no schedules configured; add them under schedules: in the config file")
+	}
+
+	var schedules []*scheduled
+	for _, sched := range globalConfig.Schedules {
+		cron, err := schedule.ParseCron(sched.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedules.%s: %w", sched.Name, err)
+		}
+		if sched.UntilState != "" {
+			if _, err := statemachine.GetAllowedTransitions(storage.NormalizeState(sched.UntilState)); err != nil {
+				return nil, fmt.Errorf("schedules.%s: invalid until_state: %w", sched.Name, err)
+			}
+		}
+		schedules = append(schedules, &scheduled{Schedule: sched, cron: cron})
+	}
+	return schedules, nil
+}
+
+// nextRuns returns when each schedule runs next after t, soonest first
+func nextRuns(schedules []*scheduled, t time.Time) []schedule.NextRun {
+	var next []schedule.NextRun
+	for _, sched := range schedules {
+		if at := sched.cron.Next(t); !at.IsZero() {
+			next = append(next, schedule.NextRun{Schedule: sched.Name, At: at})
+		}
+	}
+	for i := 1; i < len(next); i++ {
+		for j := i; j > 0 && next[j].At.Before(next[j-1].At); j-- {
+			next[j], next[j-1] = next[j-1], next[j]
+		}
+	}
+	return next
+}
+
+func runSchedule(cmd *cobra.Command, args []string) error {
+	schedules, err := loadSchedules()
+	if err != nil {
+		return err
+	}
+
+	workspace := globalConfig.Workspace
+	previous, err := schedule.ReadStatus(workspace)
+	if err != nil {
+		return err
+	}
+	if previous != nil && previous.PID != os.Getpid() && previous.Alive() {
+		return fmt.Errorf("the scheduler is already running as pid %d on %s", previous.PID, previous.Hostname)
+	}
+
+	if detach, _ := cmd.Flags().GetBool("detach"); detach {
+		return detachSchedule(workspace)
+	}
+	if os.Getenv(detachedEnv) != "" {
+		// Outlive the terminal that started it
+		signal.Ignore(syscall.SIGHUP)
+	}
+
+	cooldown, _ := cmd.Flags().GetDuration("cooldown")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+
+	hostname, _ := os.Hostname()
+	status := &schedule.Status{PID: os.Getpid(), Hostname: hostname, StartedAt: time.Now()}
+	if previous != nil {
+		status.Runs = previous.Runs
+	}
+	// The last runs stay on record after the scheduler exits
+	defer func() {
+		status.Running = ""
+		status.Next = nil
+		schedule.WriteStatus(workspace, status)
+	}()
+
+	ctx, cycleCtx, stop := runSignals()
+	defer stop()
+
+	fmt.Printf("🗓  Scheduler started (pid %d, %d schedules)\n", status.PID, len(schedules))
+	for {
+		status.Running = ""
+		status.Next = nextRuns(schedules, time.Now())
+		if err := schedule.WriteStatus(workspace, status); err != nil {
+			return err
+		}
+		if len(status.Next) == 0 {
+			return fmt.Errorf("no schedule ever runs again")
+		}
+
+		due := status.Next[0].At
+		fmt.Printf("Next run: %s at %s\n", status.Next[0].Schedule, due.Format("2006-01-02 15:04"))
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			fmt.Println("Scheduler stopped")
+			return nil
+		}
+
+		// Every schedule due at this minute runs, one after another
+		for _, next := range status.Next {
+			if !next.At.Equal(due) || ctx.Err() != nil {
+				continue
+			}
+			for _, sched := range schedules {
+				if sched.Name == next.Schedule {
+					status.Running = sched.Name
+					if err := schedule.WriteStatus(workspace, status); err != nil {
+						return err
+					}
+					status.AddRun(runScheduled(ctx, cycleCtx, sched, cooldown, quiet))
+				}
+			}
+		}
+	}
+}
+
+// runScheduled executes the run of a schedule and reports its outcome
+func runScheduled(ctx, cycleCtx context.Context, sched *scheduled, cooldown time.Duration, quiet bool) *schedule.RunInfo {
+	fmt.Printf("\n🗓  Schedule %s (%s)\n", sched.Name, sched.Cron)
+	opts := runOptions{
+		maxCycles:   sched.MaxCycles,
+		maxDuration: time.Duration(sched.MaxDurationMinutes) * time.Minute,
+		stopOnError: sched.StopOnError,
+		cooldown:    cooldown,
+		parallel:    sched.Parallel,
+		quiet:       quiet,
+		dryRun:      globalConfig.Development.DryRunDefault,
+		command:     "schedule " + sched.Name,
+	}
+	if opts.parallel == 0 {
+		opts.parallel = 1
+	}
+	if sched.UntilState != "" {
+		opts.until = storage.NormalizeState(sched.UntilState)
+	}
+	// Dry runs move nothing, so the same task would be selected forever
+	if opts.dryRun && opts.maxCycles == 0 {
+		opts.maxCycles = 1
+	}
+
+	run := &schedule.RunInfo{Schedule: sched.Name, StartedAt: time.Now()}
+	summary, err := executeRun(ctx, cycleCtx, opts)
+	run.FinishedAt = time.Now()
+	if err != nil {
+		fmt.Printf("❌ Scheduled run failed: %v\n", err)
+		run.Error = err.Error()
+		return run
+	}
+	summary.print()
+	run.Cycles = summary.count()
+	run.Succeeded = summary.succeeded()
+	run.StopReason = summary.stopReason
+	return run
+}
+
+// detachSchedule starts the scheduler again in the background
+func detachSchedule(workspace string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	logPath := filepath.Join(workspace, ".baton", "schedule.log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open schedule log: %w", err)
+	}
+	defer logFile.Close()
+
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg != "--detach" && arg != "--detach=true" {
+			args = append(args, arg)
+		}
+	}
+	child := exec.Command(executable, args...)
+	child.Stdout = logFile
+	child.Stderr = logFile
+	child.Env = append(os.Environ(), detachedEnv+"=1")
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to start the scheduler: %w", err)
+	}
+	fmt.Printf("🗓  Scheduler started in the background (pid %d), logging to %s\n", child.Process.Pid, logPath)
+	return child.Process.Release()
+}
+
+func runScheduleStatus(cmd *cobra.Command, args []string) error {
+	status, err := schedule.ReadStatus(globalConfig.Workspace)
+	if err != nil {
+		return err
+	}
+	running := status != nil && status.Alive()
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(map[string]interface{}{"running": running, "status": status}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if running {
+		fmt.Printf("🗓  Scheduler running as pid %d on %s since %s\n", status.PID, status.Hostname, status.StartedAt.Format("2006-01-02 15:04"))
+		if status.Running != "" {
+			fmt.Printf("Running now: %s\n", status.Running)
+		}
+	} else {
+		fmt.Println("🗓  Scheduler not running")
+	}
+
+	// Without a scheduler the next runs come from the config
+	next := []schedule.NextRun(nil)
+	if running {
+		next = status.Next
+	} else if schedules, err := loadSchedules(); err == nil {
+		next = nextRuns(schedules, time.Now())
+	}
+	if len(next) > 0 {
+		fmt.Println("Next runs:")
+		for _, run := range next {
+			fmt.Printf("  %-20s %s\n", run.Schedule, run.At.Format("Mon 2006-01-02 15:04"))
+		}
+	}
+
+	if status != nil && len(status.Runs) > 0 {
+		fmt.Println("Last runs:")
+		for _, run := range status.Runs {
+			outcome := fmt.Sprintf("%d cycles, %d succeeded; %s", run.Cycles, run.Succeeded, run.StopReason)
+			if run.Error != "" {
+				outcome = "failed: " + run.Error
+			}
+			fmt.Printf("  %-20s %s (%s): %s\n", run.Schedule, run.StartedAt.Format("2006-01-02 15:04"),
+				run.FinishedAt.Sub(run.StartedAt).Round(time.Second), outcome)
+		}
+	}
+	return nil
+}
+
+func runScheduleStop(cmd *cobra.Command, args []string) error {
+	status, err := schedule.ReadStatus(globalConfig.Workspace)
+	if err != nil {
+		return err
+	}
+	if status == nil || !status.Alive() {
+		fmt.Println("Scheduler not running")
+		return nil
+	}
+	hostname, _ := os.Hostname()
+	if status.Hostname != hostname {
+		return fmt.Errorf("the scheduler runs on %s; stop it there", status.Hostname)
+	}
+
+	process, err := os.FindProcess(status.PID)
+	if err != nil {
+		return err
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to stop the scheduler: %w", err)
+	}
+	if abort, _ := cmd.Flags().GetBool("abort"); abort {
+		// The second signal aborts the cycles in flight
+		time.Sleep(100 * time.Millisecond)
+		process.Signal(syscall.SIGTERM)
+	}
+
+	wait, _ := cmd.Flags().GetDuration("wait")
+	for deadline := time.Now().Add(wait); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+		if !status.Alive() {
+			fmt.Printf("⏹  Scheduler (pid %d) stopped\n", status.PID)
+			return nil
+		}
+	}
+	fmt.Printf("⏸  Scheduler (pid %d) is stopping once its current cycles finish\n", status.PID)
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/search"
+	"baton/internal/storage"
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search tasks, artifacts, requirements and audit notes",
+	Long: `Search runs a full-text search over task titles and descriptions, the latest
+version of each artifact, requirements and the notes of audit entries, best matches
+first. Matched words are marked with [brackets] in each snippet.
+
+--mode semantic ranks by embedding similarity instead (see the search config).
+--state and --owner filter by the task a hit belongs to, so they leave out
+requirements.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().String("kind", "", "only hits of this kind (task, artifact, requirement, audit)")
+	searchCmd.Flags().String("state", "", "only hits on tasks in this state")
+	searchCmd.Flags().String("owner", "", "only hits on tasks with this owner")
+	searchCmd.Flags().String("mode", search.ModeKeyword, "keyword or semantic")
+	searchCmd.Flags().Int("limit", search.DefaultLimit, "maximum number of hits")
+	searchCmd.Flags().Bool("json", false, "output in JSON format")
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	query := strings.Join(args, " ")
+	mode, _ := cmd.Flags().GetString("mode")
+	filters := storage.SearchFilters{}
+	filters.Kind, _ = cmd.Flags().GetString("kind")
+	filters.Owner, _ = cmd.Flags().GetString("owner")
+	filters.Limit, _ = cmd.Flags().GetInt("limit")
+	if stateStr, _ := cmd.Flags().GetString("state"); stateStr != "" {
+		state := storage.NormalizeState(stateStr)
+		filters.State = &state
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	var embedder search.Embedder
+	if mode == search.ModeSemantic {
+		if embedder, err = search.NewEmbedder(globalConfig.Search); err != nil {
+			return fmt.Errorf("semantic search is not available: %w", err)
+		}
+	}
+
+	hits, err := search.NewSearcher(store, embedder).Search(query, mode, filters)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		if hits == nil {
+			hits = []*storage.SearchHit{}
+		}
+		data, err := json.MarshalIndent(hits, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(hits) == 0 {
+		fmt.Printf("No matches for %q\n", query)
+		return nil
+	}
+	for _, hit := range hits {
+		fmt.Printf("%-11s %s\n", hit.Kind, hit.Title)
+		if hit.TaskID != "" && hit.Kind != storage.SearchKindTask {
+			fmt.Printf("            task %s %s (%s)\n", hit.TaskID[:8], hit.TaskTitle, hit.TaskState)
+		}
+		if snippet := strings.Join(strings.Fields(hit.Snippet), " "); snippet != "" {
+			fmt.Printf("            %s\n", snippet)
+		}
+	}
+	return nil
+}
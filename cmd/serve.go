@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/cycle"
+	"baton/internal/mcp"
+	"baton/internal/notify"
+	"baton/internal/plan"
+	"baton/internal/tenant"
+	"baton/internal/web"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the web UI, MCP server and cycle worker in one process",
+	Long: `Serve runs everything a long-lived workspace needs against one database:
+
+- the web UI and REST API (with /healthz and /readyz health endpoints)
+- the MCP server over HTTP, including the HTTP+SSE transport at /sse
+- optionally a cycle worker that executes one cycle every --worker-interval;
+  POST /api/cycles/start runs one right away, optionally on a given task_id
+
+Agents started by the worker connect to the shared MCP server. On SIGINT or
+SIGTERM the worker stops taking new cycles, an in-flight cycle is given
+--shutdown-timeout to finish, and then both servers shut down.
+
+When the config has a projects section, serve instead hosts each listed
+project under /p/{name}/ on --port, with its MCP server at /p/{name}/mcp and
+its own token. The worker is not available in this mode.`,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().IntP("port", "p", 3001, "port for the web UI and health endpoints")
+	serveCmd.Flags().Bool("worker", false, "run a cycle worker")
+	serveCmd.Flags().Duration("worker-interval", 30*time.Second, "pause between worker cycles")
+	serveCmd.Flags().Bool("read-only", false, "disable the web UI's mutating endpoints and LLM calls")
+	serveCmd.Flags().Duration("shutdown-timeout", 30*time.Second, "how long to wait for an in-flight cycle on shutdown")
+}
+
+// cycleWorker runs cycles one after another until stopped. A single worker
+// is supported because selection does not claim tasks, so two workers would
+// pick the same one.
+type cycleWorker struct {
+	engine   *cycle.CycleEngine
+	interval time.Duration
+	trigger  chan string // cycles requested through the web UI, by task ID
+
+	mu         sync.RWMutex
+	running    bool
+	lastCycle  time.Time
+	lastError  string
+	cyclesDone int
+	paused     bool // the last cycle was paused because the plan is unavailable
+}
+
+// run executes cycles until ctx is cancelled; cycleCtx bounds in-flight cycles
+func (w *cycleWorker) run(ctx, cycleCtx context.Context) {
+	w.setRunning(true)
+	defer w.setRunning(false)
+
+	taskID := ""
+	for {
+		w.runOnce(cycleCtx, taskID)
+
+		select {
+		case <-ctx.Done():
+			return
+		case taskID = <-w.trigger:
+		case <-time.After(w.interval):
+			taskID = ""
+		}
+	}
+}
+
+// StartCycle implements web.CycleStarter: the requested cycle runs as soon as
+// the worker is idle, instead of after the interval
+func (w *cycleWorker) StartCycle(taskID string) error {
+	if taskID != "" {
+		// Reject a task that cannot be started now rather than failing later
+		if _, err := w.engine.SelectTask(taskID); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case w.trigger <- taskID:
+		return nil
+	default:
+		return fmt.Errorf("a cycle is already queued")
+	}
+}
+
+// runOnce executes a single cycle, on taskID when set, and publishes the
+// result to web clients
+func (w *cycleWorker) runOnce(ctx context.Context, taskID string) {
+	result, err := w.engine.ExecuteCycleForTask(ctx, taskID, false)
+
+	var planErr *plan.UnavailableError
+	paused := err != nil && errors.As(err, &planErr)
+
+	w.mu.Lock()
+	w.lastCycle = time.Now()
+	if err != nil {
+		w.lastError = err.Error()
+	} else {
+		w.lastError = ""
+		w.cyclesDone++
+	}
+	wasPaused := w.paused
+	w.paused = paused
+	w.mu.Unlock()
+
+	// Log pausing and resuming once rather than on every tick
+	if paused {
+		if !wasPaused {
+			log.Printf("Cycles paused until the plan is available: %v", err)
+		}
+		return
+	}
+	if wasPaused {
+		log.Printf("Plan available again, resuming cycles")
+	}
+
+	if err != nil {
+		// Nothing to do is the normal idle state, not a failure
+		if cycle.IsNoTaskError(err) {
+			if verbose {
+				log.Printf("Cycle worker idle: %v", err)
+			}
+			return
+		}
+		log.Printf("Cycle failed: %v", err)
+		return
+	}
+
+	log.Printf("Cycle %s: task %s %s → %s", result.CycleID, result.TaskID, result.PrevState, result.NextState)
+}
+
+func (w *cycleWorker) setRunning(running bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = running
+}
+
+// status reports the worker state for the readiness endpoint
+func (w *cycleWorker) status() map[string]interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"running":     w.running,
+		"cycles_done": w.cyclesDone,
+		"paused":      w.paused,
+	}
+	if !w.lastCycle.IsZero() {
+		status["last_cycle"] = w.lastCycle
+	}
+	if w.lastError != "" {
+		status["last_error"] = w.lastError
+	}
+	return status
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	cfg := globalConfig
+	port, _ := cmd.Flags().GetInt("port")
+	runWorker, _ := cmd.Flags().GetBool("worker")
+	workerInterval, _ := cmd.Flags().GetDuration("worker-interval")
+	shutdownTimeout, _ := cmd.Flags().GetDuration("shutdown-timeout")
+	readOnly, _ := cmd.Flags().GetBool("read-only")
+
+	if len(cfg.Projects) > 0 {
+		if runWorker {
+			return fmt.Errorf("--worker is not supported when serving multiple projects")
+		}
+		return runServeProjects(port, readOnly)
+	}
+	readOnly = readOnly || cfg.Web.ReadOnly
+
+	if err := checkPlanFile(); err != nil {
+		if cfg.PlanUnavailable == "pause" {
+			log.Printf("Warning: %v; the worker will pause cycles until it is fixed", err)
+		} else {
+			log.Printf("Warning: %v; agents will work without the plan", err)
+		}
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("serve")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, cfg).Close()
+	store.SetBlobThreshold(cfg.Artifacts.BlobThresholdBytes)
+	warnInterruptedCycles(store)
+
+	// The web UI can run without an LLM; the worker cannot
+	llmClient, err := createLLMClient()
+	if err != nil {
+		if runWorker {
+			return fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		log.Printf("LLM client unavailable, prompt-based features are disabled: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// MCP server shared by all agents
+	mcpServer := mcp.NewServer(store, cfg)
+	cycle.ServePrompts(mcpServer, store, cfg)
+	if err := mcpServer.StartHTTP(); err != nil {
+		return fmt.Errorf("failed to start MCP server: %w", err)
+	}
+	defer mcpServer.Stop()
+
+	// The worker keeps its LLM client; read-only only restricts the web UI
+	webLLMClient := llmClient
+	if readOnly {
+		webLLMClient = nil
+	}
+	webServer := web.NewServer(store, cfg, webLLMClient)
+	webServer.SetReadOnly(readOnly)
+
+	var worker *cycleWorker
+	if runWorker {
+		engine := cycle.NewCycleEngine(store, cfg, llmClient)
+		engine.UseMCPServer(mcpServer)
+		worker = &cycleWorker{engine: engine, interval: workerInterval, trigger: make(chan string, 1)}
+		webServer.SetCycleReporter(engine)
+		webServer.SetCycleStarter(worker)
+		engine.SetOutputHandler(webServer.BroadcastCycleOutput)
+	}
+
+	webServer.Handle("/healthz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeServeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+	}))
+	webServer.Handle("/readyz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		components := map[string]interface{}{
+			"web": "ok",
+			"mcp": "ok",
+		}
+		ready := true
+
+		if err := store.Ping(); err != nil {
+			components["database"] = err.Error()
+			ready = false
+		} else {
+			components["database"] = "ok"
+		}
+
+		if !mcpServer.IsRunning() {
+			components["mcp"] = "stopped"
+			ready = false
+		}
+
+		// A missing plan degrades agents but the server can still serve
+		if err := checkPlanFile(); err != nil {
+			components["plan"] = err.Error()
+		} else if cfg.PlanFile != "" {
+			components["plan"] = "ok"
+		}
+
+		if worker != nil {
+			components["worker"] = worker.status()
+		}
+
+		status, code := "ready", http.StatusOK
+		if !ready {
+			status, code = "not_ready", http.StatusServiceUnavailable
+		}
+		writeServeJSON(w, code, map[string]interface{}{"status": status, "components": components})
+	}))
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- webServer.Start(port)
+	}()
+
+	// In-flight cycles outlive the signal by up to shutdownTimeout
+	cycleCtx, cancelCycles := context.WithCancel(context.Background())
+	defer cancelCycles()
+
+	var workerDone sync.WaitGroup
+	if worker != nil {
+		workerDone.Add(1)
+		go func() {
+			defer workerDone.Done()
+			worker.run(ctx, cycleCtx)
+		}()
+		log.Printf("Cycle worker started (interval %s)", workerInterval)
+	}
+
+	log.Printf("Serving project %s: web UI on port %d and MCP on port %d", store.Project(), port, cfg.MCPPort)
+
+	var serveErr error
+	select {
+	case err := <-errChan:
+		if err != nil {
+			serveErr = fmt.Errorf("web server error: %w", err)
+		}
+		stop()
+	case <-ctx.Done():
+		log.Println("Shutting down gracefully...")
+	}
+
+	// Let an in-flight cycle finish, then cancel it
+	waited := make(chan struct{})
+	go func() {
+		workerDone.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(shutdownTimeout):
+		log.Printf("Cycle still running after %s, cancelling it", shutdownTimeout)
+		cancelCycles()
+		<-waited
+	}
+
+	if err := webServer.Stop(); err != nil {
+		log.Printf("Error stopping web server: %v", err)
+	}
+	if err := mcpServer.Stop(); err != nil {
+		log.Printf("Error stopping MCP server: %v", err)
+	}
+
+	log.Println("Server stopped")
+	return serveErr
+}
+
+// runServeProjects hosts every project in the config's projects section on
+// one port until interrupted
+func runServeProjects(port int, readOnly bool) error {
+	host, err := tenant.NewHost(globalConfig, tenant.Options{ReadOnly: readOnly, ForceLock: forceLock})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- host.Start(port)
+	}()
+
+	var serveErr error
+	select {
+	case err := <-errChan:
+		if err != nil {
+			serveErr = fmt.Errorf("server error: %w", err)
+		}
+	case <-ctx.Done():
+		log.Println("Shutting down gracefully...")
+	}
+
+	if err := host.Stop(); err != nil {
+		log.Printf("Error stopping server: %v", err)
+	}
+
+	log.Println("Server stopped")
+	return serveErr
+}
+
+// writeServeJSON writes a JSON health response
+func writeServeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
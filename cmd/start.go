@@ -1,14 +1,22 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"baton/internal/cycle"
+	"baton/internal/hooks"
 	"baton/internal/llm"
+	"baton/internal/notify"
 	"baton/internal/storage"
 )
 
@@ -18,38 +26,97 @@ var startCmd = &cobra.Command{
 	Short: "Execute one cycle",
 	Long: `Start executes one cycle: select → transition → analyze/execute → handover → completion handshake → audit → stop.
 
-Each cycle advances exactly one task by one valid state transition.`,
+Each cycle advances exactly one task by one valid state transition.
+
+With --task the cycle works on the given task instead of selecting one. The
+task must still be selectable: not in a terminal state, not blocked by
+dependencies, handled by an agent and outside any locked area.
+
+With --review, once the agent moved its task and the after_success hooks passed,
+the proposed state change, the artifacts it wrote and a diff of its file changes
+are shown for approval. Rejecting undoes the move and records your feedback in a
+note on the task for the next cycle's agent; with worktree isolation the agent's
+file changes are discarded too.
+
+With --dry-run nothing runs and nothing changes; instead the cycle is previewed:
+the task that would be selected and why, the agent, model tier and timebox,
+the rendered prompt, the transitions the agent could make with the handovers
+each requires and the hooks it would run, the cycle hooks, and an estimate of
+the cost from the agent's recent cycles. --json prints the preview as JSON.
+
+With --pipeline the agents of a pipeline from the config's pipelines run one
+cycle each on the same task, one after the other: the first stage's agent takes
+the task (selected among those it handles, or given with --task) and each later
+stage runs once the stage before it moved the task to a state its agent handles.
+Every stage gets its own audit entry; the combined result is printed at the end.
+A dry run previews the first stage.`,
 	RunE: runStart,
 }
 
 func init() {
 	rootCmd.AddCommand(startCmd)
-	startCmd.Flags().Duration("timeout", 0, "timeout for cycle execution")
+	startCmd.Flags().Duration("timeout", 0, "overall timeout for cycle execution (default: timebox from config)")
+	startCmd.Flags().Bool("quiet", false, "don't print the agent's output while the cycle runs")
+	startCmd.Flags().String("task", "", "run the cycle on this task instead of selecting one")
+	startCmd.Flags().Bool("review", false, "ask for approval of the agent's transition before it stands")
+	startCmd.Flags().Bool("json", false, "with --dry-run, output the preview in JSON format")
+	startCmd.Flags().String("pipeline", "", "run the cycles of the named pipeline's agents in turn")
 }
 
 func runStart(cmd *cobra.Command, args []string) error {
-	// Get timeout from flags
+	// Get timeout from flags; without one the engine timeboxes the cycle from config
 	timeout, _ := cmd.Flags().GetDuration("timeout")
-	if timeout == 0 {
-		timeout = time.Duration(globalConfig.Development.CycleTimeboxSeconds) * time.Second
-	}
 
-	// Create context with timeout
-	ctx := context.Background()
+	// Create context with timeout, cancelled by an interrupt
+	ctx, stopInterrupt := interruptContext()
+	defer stopInterrupt()
 	if timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 
+	// Reviews are answered at the terminal; dry runs have no transition to review
+	review, _ := cmd.Flags().GetBool("review")
+	if review && !globalConfig.Development.DryRunDefault && !stdinIsTerminal() {
+		return fmt.Errorf("--review asks for approval on the terminal, but stdin is not one")
+	}
+
+	// A dry run previews the cycle instead
+	if globalConfig.Development.DryRunDefault {
+		return runStartPreview(cmd)
+	}
+
 	fmt.Printf("⏱ Starting cycle execution (dry-run: %v)\n", globalConfig.Development.DryRunDefault)
 
+	// Fail before taking the lock when a missing plan would pause the cycle anyway
+	if err := checkPlanFile(); err != nil {
+		if globalConfig.PlanUnavailable == "pause" && !globalConfig.Development.DryRunDefault {
+			return fmt.Errorf("cycle paused: %w (fix the plan or set plan_unavailable: continue)", err)
+		}
+		fmt.Printf("⚠️  %v; agents will work without the plan\n", err)
+	}
+
+	// Dry runs never write, so they can run alongside another writer
+	if !globalConfig.Development.DryRunDefault {
+		workspaceLock, err := acquireWorkspaceLock("start")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
 	// Initialize database
-	store, err := storage.NewStore(globalConfig.Database)
+	store, err := openStore(globalConfig)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+	store.SetBlobThreshold(globalConfig.Artifacts.BlobThresholdBytes)
+	if !globalConfig.Development.DryRunDefault {
+		warnInterruptedCycles(store)
+	}
 
 	// Initialize LLM client
 	llmClient, err := createLLMClient()
@@ -60,8 +127,26 @@ func runStart(cmd *cobra.Command, args []string) error {
 	// Create cycle engine
 	engine := cycle.NewCycleEngine(store, globalConfig, llmClient)
 
-	// Execute the cycle
-	result, err := engine.ExecuteCycle(ctx, globalConfig.Development.DryRunDefault)
+	// Print the agent's output as it streams in
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+		engine.SetOutputHandler(printCycleOutput)
+	}
+
+	if review {
+		engine.SetReviewer(reviewInTerminal())
+	}
+
+	// Execute the pipeline's cycles, or the cycle
+	taskID, _ := cmd.Flags().GetString("task")
+	if pipeline, _ := cmd.Flags().GetString("pipeline"); pipeline != "" {
+		result, err := engine.ExecutePipeline(ctx, pipeline, taskID, globalConfig.Development.DryRunDefault)
+		if err != nil {
+			return fmt.Errorf("pipeline execution failed: %w", err)
+		}
+		printPipelineResult(result)
+		return nil
+	}
+	result, err := engine.ExecuteCycleForTask(ctx, taskID, globalConfig.Development.DryRunDefault)
 	if err != nil {
 		return fmt.Errorf("cycle execution failed: %w", err)
 	}
@@ -72,25 +157,154 @@ func runStart(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func createLLMClient() (llm.Client, error) {
-	// Create client factory
-	factory := llm.NewClientFactory()
+// runStartPreview previews the cycle 'baton start' would run, without
+// running it or changing anything
+func runStartPreview(cmd *cobra.Command) error {
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	llmClient, err := createLLMClient()
+	if err != nil {
+		return fmt.Errorf("failed to create LLM client: %w", err)
+	}
+
+	engine := cycle.NewCycleEngine(store, globalConfig, llmClient)
+	taskID, _ := cmd.Flags().GetString("task")
+	var preview *cycle.Preview
+	if pipeline, _ := cmd.Flags().GetString("pipeline"); pipeline != "" {
+		preview, err = engine.PreviewPipeline(pipeline, taskID)
+	} else {
+		preview, err = engine.Preview(taskID)
+	}
+	if err != nil {
+		return fmt.Errorf("cycle preview failed: %w", err)
+	}
 
-	// Register Claude client
-	claudeClient := llm.NewClaudeClient(&globalConfig.LLM.Claude, globalConfig.MCPPort)
-	factory.Register("claude", claudeClient)
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(preview, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	printCyclePreview(preview)
+	return nil
+}
 
-	// Get primary client
-	client, exists := factory.Get(globalConfig.LLM.Primary)
-	if !exists {
-		return nil, fmt.Errorf("primary LLM client '%s' not found", globalConfig.LLM.Primary)
+// printCyclePreview prints what a cycle would do
+func printCyclePreview(preview *cycle.Preview) {
+	task := preview.Task
+	fmt.Printf("🔎 Dry run: nothing is executed or changed\n\n")
+	fmt.Printf("Task: %s (%s)\n", task.Title, task.ID)
+	fmt.Printf("State: %s, priority %d\n", task.State, task.Priority)
+	fmt.Printf("Selected because: %s\n", preview.SelectionReason)
+	if len(preview.Pipeline) > 0 {
+		fmt.Printf("Pipeline: %s (previewing the first stage)\n", strings.Join(preview.Pipeline, " → "))
 	}
 
-	if !client.IsAvailable() {
-		return nil, fmt.Errorf("primary LLM client '%s' is not available", globalConfig.LLM.Primary)
+	fmt.Printf("\n🤖 Agent: %s", preview.Agent)
+	if preview.AgentRole != "" {
+		fmt.Printf(" (%s)", preview.AgentRole)
+	}
+	fmt.Println()
+	fmt.Printf("Provider: %s\n", preview.Provider)
+	if preview.ModelTier != "" {
+		fmt.Printf("Model tier: %s", preview.ModelTier)
+		if len(preview.EscalationPath) > 1 {
+			fmt.Printf(" (may escalate: %s)", strings.Join(preview.EscalationPath, " → "))
+		}
+		fmt.Println()
+	}
+	if preview.TimeboxSeconds > 0 {
+		fmt.Printf("Timebox: %s\n", time.Duration(preview.TimeboxSeconds)*time.Second)
+	} else {
+		fmt.Printf("Timebox: none\n")
+	}
+	if preview.Isolation != "" {
+		fmt.Printf("Isolation: %s\n", preview.Isolation)
+	}
+	if len(preview.AllowedCommands) > 0 {
+		fmt.Printf("Commands: %s\n", strings.Join(preview.AllowedCommands, ", "))
+	}
+	if preview.PlanUnavailable != "" {
+		fmt.Printf("⚠️  Plan unavailable: %s\n", preview.PlanUnavailable)
 	}
 
-	return client, nil
+	fmt.Printf("\n🔀 Transitions from %s:\n", task.State)
+	for _, transition := range preview.Transitions {
+		status := "✅"
+		if !transition.IsValid {
+			status = "⛔"
+		}
+		fmt.Printf("  %s %s\n", status, transition.To)
+		if len(transition.Handovers) > 0 {
+			fmt.Printf("     Handovers: %s\n", strings.Join(transition.Handovers, ", "))
+		}
+		if transition.Reason != "" {
+			fmt.Printf("     %s\n", transition.Reason)
+		}
+		for _, hook := range transition.Hooks {
+			fmt.Printf("     Hook: %s\n", formatPlannedHook(hook))
+		}
+	}
+
+	if len(preview.BeforeSelection) > 0 || len(preview.AfterSuccess) > 0 {
+		fmt.Printf("\n🪝 Cycle hooks:\n")
+		for _, hook := range preview.BeforeSelection {
+			fmt.Printf("  before_selection: %s\n", formatPlannedHook(hook))
+		}
+		for _, hook := range preview.AfterSuccess {
+			fmt.Printf("  after_success: %s\n", formatPlannedHook(hook))
+		}
+	}
+
+	estimate := preview.Estimate
+	fmt.Printf("\n💰 Estimated cost: $%.4f (%s)\n", estimate.CostUSD, estimate.Basis)
+	fmt.Printf("Prompt: ~%d tokens", estimate.PromptTokens)
+	if estimate.PromptCostUSD > 0 {
+		fmt.Printf(", $%.4f", estimate.PromptCostUSD)
+	}
+	fmt.Println()
+	if estimate.PastCycles > 0 {
+		fmt.Printf("Average cycle: %s\n", (time.Duration(estimate.AvgDurationSeconds) * time.Second).String())
+	}
+
+	fmt.Printf("\n📝 Prompt:\n")
+	for _, line := range strings.Split(strings.TrimRight(preview.Prompt, "\n"), "\n") {
+		fmt.Printf("  │ %s\n", line)
+	}
+}
+
+// formatPlannedHook describes a hook that would run
+func formatPlannedHook(hook hooks.Planned) string {
+	if hook.Blocking {
+		return fmt.Sprintf("%s (%s, blocking)", hook.Hook, hook.Kind)
+	}
+	return fmt.Sprintf("%s (%s)", hook.Hook, hook.Kind)
+}
+
+func createLLMClient() (llm.Client, error) {
+	// Primary client, chained to llm.fallback when one is configured
+	return llm.NewClientForMCP(globalConfig.LLM, globalConfig.MCPPort)
+}
+
+// printCycleOutput prints streamed agent output, indented under the cycle header
+func printCycleOutput(chunk *cycle.OutputChunk) {
+	for _, line := range strings.Split(strings.TrimRight(chunk.Content, "\n"), "\n") {
+		fmt.Printf("  │ %s\n", line)
+	}
+}
+
+// printTaskOutput prints agent output labelled with its task, for cycles
+// running side by side
+func printTaskOutput(chunk *cycle.OutputChunk) {
+	for _, line := range strings.Split(strings.TrimRight(chunk.Content, "\n"), "\n") {
+		fmt.Printf("  │ [%s] %s\n", chunk.TaskID, line)
+	}
 }
 
 func printCycleResult(result *storage.CycleResult) {
@@ -102,7 +316,14 @@ func printCycleResult(result *storage.CycleResult) {
 
 	fmt.Printf("Task ID: %s\n", result.TaskID)
 	fmt.Printf("State Transition: %s → %s\n", result.PrevState, result.NextState)
+	if result.AutoRetries > 0 {
+		fmt.Printf("Auto-retries: %d (after failed completion handshakes)\n", result.AutoRetries)
+	}
 	fmt.Printf("Duration: %v\n", result.Duration.Round(time.Millisecond))
+	if result.PromptTokens > 0 || result.CostUSD > 0 {
+		fmt.Printf("LLM Usage: %d prompt + %d completion tokens, $%.4f\n",
+			result.PromptTokens, result.CompletionTokens, result.CostUSD)
+	}
 
 	if len(result.ArtifactsCreated) > 0 {
 		fmt.Printf("Artifacts Created: %v\n", result.ArtifactsCreated)
@@ -111,4 +332,101 @@ func printCycleResult(result *storage.CycleResult) {
 	if result.Error != nil {
 		fmt.Printf("Error: %v\n", result.Error)
 	}
-}
\ No newline at end of file
+}
+
+// printPipelineResult prints each stage's cycle and the pipeline's totals
+func printPipelineResult(result *cycle.PipelineResult) {
+	for i, stage := range result.Stages {
+		fmt.Printf("\n── Stage %d: %s\n", i+1, stage.Agent)
+		if stage.Result != nil {
+			printCycleResult(stage.Result)
+		} else if stage.Error != "" {
+			fmt.Printf("❌ Cycle failed: %s\n", stage.Error)
+		}
+	}
+
+	fmt.Println()
+	if result.Completed {
+		fmt.Printf("✅ Pipeline %s completed\n", result.Pipeline)
+	} else {
+		fmt.Printf("⚠️  Pipeline %s stopped: %s\n", result.Pipeline, result.Stopped)
+	}
+	fmt.Printf("Task ID: %s\n", result.TaskID)
+	fmt.Printf("State Transition: %s → %s\n", result.PrevState, result.NextState)
+	fmt.Printf("Duration: %v\n", result.Duration.Round(time.Millisecond))
+	if result.PromptTokens > 0 || result.CostUSD > 0 {
+		fmt.Printf("LLM Usage: %d prompt + %d completion tokens, $%.4f\n",
+			result.PromptTokens, result.CompletionTokens, result.CostUSD)
+	}
+}
+
+// maxReviewDiffLines is how much of a diff a review shows
+const maxReviewDiffLines = 300
+
+// reviewInTerminal returns a reviewer that shows each proposed transition and
+// asks whether to apply it
+func reviewInTerminal() cycle.Reviewer {
+	reviewer := os.Getenv("USER")
+	if reviewer == "" {
+		reviewer = "cli"
+	}
+	reader := bufio.NewReader(os.Stdin)
+
+	return func(proposal *cycle.Proposal) *cycle.ReviewDecision {
+		fmt.Println()
+		fmt.Printf("🔍 Review: %s proposes %s → %s\n", proposal.Agent, proposal.From, proposal.To)
+		fmt.Printf("Task: %s (%s)\n", proposal.TaskTitle, proposal.TaskID)
+		if len(proposal.Artifacts) > 0 {
+			fmt.Printf("Artifacts: %s\n", strings.Join(proposal.Artifacts, ", "))
+		}
+		if response := strings.TrimSpace(proposal.Response); response != "" {
+			if len(response) > 1000 {
+				response = response[:1000] + " ..."
+			}
+			fmt.Printf("Agent's reply:\n%s\n", response)
+		}
+
+		switch {
+		case proposal.DiffError != "":
+			fmt.Printf("No diff: %s\n", proposal.DiffError)
+		case proposal.Diff == "":
+			fmt.Println("No file changes")
+		default:
+			lines := strings.Split(proposal.Diff, "\n")
+			fmt.Printf("Changes in %s:\n", proposal.WorkDir)
+			if len(lines) > maxReviewDiffLines {
+				fmt.Println(strings.Join(lines[:maxReviewDiffLines], "\n"))
+				fmt.Printf("... %d more lines (git diff in %s)\n", len(lines)-maxReviewDiffLines, proposal.WorkDir)
+			} else {
+				fmt.Println(proposal.Diff)
+			}
+			if !proposal.Isolated {
+				fmt.Println("Rejecting undoes the transition only: these changes stay in the workspace")
+			}
+		}
+
+		decision := &cycle.ReviewDecision{Reviewer: reviewer}
+		fmt.Printf("\nApply the move to %s? [y/N]: ", proposal.To)
+		answer, _ := reader.ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer == "y" || answer == "yes" {
+			decision.Approved = true
+			return decision
+		}
+		fmt.Print("Feedback for the next cycle's agent (optional): ")
+		feedback, _ := reader.ReadString('\n')
+		decision.Feedback = strings.TrimSpace(feedback)
+		return decision
+	}
+}
+
+// interruptContext returns a context the first SIGINT or SIGTERM cancels,
+// which stops the cycle's agent and whatever it started; a second one ends
+// baton as usual. stop releases the signals.
+func interruptContext() (ctx context.Context, stop func()) {
+	ctx, stop = signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
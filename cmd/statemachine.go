@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/workflow"
+)
+
+// statemachineCmd represents the statemachine command
+var statemachineCmd = &cobra.Command{
+	Use:   "statemachine",
+	Short: "Inspect the task state machine",
+	Long:  `Statemachine commands describe the states tasks move through and the transitions between them.`,
+}
+
+// statemachineGraphCmd represents the statemachine graph command
+var statemachineGraphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Render the state machine as a diagram",
+	Long: `Graph renders the state transitions as a Graphviz dot or Mermaid state diagram,
+with the handover artifacts each transition requires on its edge, so the workflow can
+be documented and reviewed.
+
+The active workflow is rendered by default; --file renders a definition written by
+'baton workflow export' instead.
+
+  baton statemachine graph --format dot | dot -Tsvg > workflow.svg
+  baton statemachine graph --format mermaid -o docs/workflow.mmd`,
+	RunE: runStatemachineGraph,
+}
+
+func init() {
+	rootCmd.AddCommand(statemachineCmd)
+	statemachineCmd.AddCommand(statemachineGraphCmd)
+
+	statemachineGraphCmd.Flags().String("format", workflow.FormatMermaid, "diagram format (dot or mermaid)")
+	statemachineGraphCmd.Flags().String("file", "", "render a workflow definition file instead of the active workflow")
+	statemachineGraphCmd.Flags().StringP("output", "o", "", "write the diagram to a file instead of stdout")
+}
+
+func runStatemachineGraph(cmd *cobra.Command, args []string) error {
+	def := workflow.Active(globalConfig)
+	if file, _ := cmd.Flags().GetString("file"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read workflow: %w", err)
+		}
+		if def, err = workflow.Parse(data); err != nil {
+			return err
+		}
+		if problems := def.Validate(); len(problems) > 0 {
+			return fmt.Errorf("workflow %s is invalid: %s", file, problems[0])
+		}
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	graph, err := def.Graph(format)
+	if err != nil {
+		return err
+	}
+
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		if err := os.WriteFile(output, []byte(graph), 0644); err != nil {
+			return fmt.Errorf("failed to write diagram: %w", err)
+		}
+		fmt.Printf("✅ State machine diagram written to %s\n", output)
+		return nil
+	}
+
+	fmt.Print(graph)
+	return nil
+}
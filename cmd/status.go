@@ -3,9 +3,12 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"baton/internal/lock"
+	"baton/internal/plan"
 	"baton/internal/statemachine"
 	"baton/internal/storage"
 )
@@ -21,11 +24,13 @@ var statusCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(statusCmd)
 	statusCmd.Flags().Bool("json", false, "output in JSON format")
+	statusCmd.Flags().Bool("by-owner", false, "show open tasks, in-flight work and throughput per owner/agent")
+	statusCmd.Flags().Duration("window", 7*24*time.Hour, "throughput window for --by-owner")
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
 	// Initialize database
-	store, err := storage.NewStore(globalConfig.Database)
+	store, err := openStore(globalConfig)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
@@ -33,11 +38,58 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 	// Get task selector for status information
 	selector := statemachine.NewTaskSelector(store, &globalConfig.Selection)
+	selector.SetAgentCoverage(statemachine.AgentCoverage(globalConfig))
+
+	if byOwner, _ := cmd.Flags().GetBool("by-owner"); byOwner {
+		return runOwnerStatus(cmd, selector)
+	}
+
 	status, err := selector.GetTaskStatus()
 	if err != nil {
 		return fmt.Errorf("failed to get status: %w", err)
 	}
 
+	// Report which process, if any, holds the workspace lock
+	owner, stale, err := lock.Inspect(globalConfig.Workspace)
+	if err != nil {
+		return fmt.Errorf("failed to inspect workspace lock: %w", err)
+	}
+	if owner != nil {
+		status["workspace_lock"] = map[string]interface{}{
+			"pid":        owner.PID,
+			"command":    owner.Command,
+			"hostname":   owner.Hostname,
+			"started_at": owner.StartedAt.Local(),
+			"stale":      stale,
+		}
+	}
+
+	// Report tasks stuck in a work state
+	staleTasks, err := statemachine.FindStaleTasks(store, globalConfig.Staleness, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to find stale tasks: %w", err)
+	}
+	status["stale_tasks"] = staleTasks
+
+	// Report open tasks past their due date
+	overdueTasks, err := statemachine.FindOverdueTasks(store, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to find overdue tasks: %w", err)
+	}
+	status["overdue_tasks"] = overdueTasks
+
+	// Report what recent cycles cost
+	cycleCosts, err := store.GetRecentCycleCosts(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to get cycle costs: %w", err)
+	}
+	status["cycle_costs"] = cycleCosts
+
+	// Report whether agents can read the plan
+	if globalConfig.PlanFile != "" {
+		status["plan"] = plan.CheckStatus(globalConfig.PlanFile)
+	}
+
 	// Check for JSON output
 	jsonOutput, _ := cmd.Flags().GetBool("json")
 	if jsonOutput {
@@ -59,6 +111,17 @@ func printStatus(status map[string]interface{}) {
 	fmt.Println("📈 Baton Workspace Status")
 	fmt.Println("========================")
 
+	// Degraded mode banner, first so it isn't missed
+	if planStatus, ok := status["plan"].(*plan.Status); ok && !planStatus.Available {
+		fmt.Printf("⚠️  DEGRADED: plan file %s is unavailable (%s)\n", planStatus.Path, planStatus.Error)
+		if globalConfig.PlanUnavailable == "pause" {
+			fmt.Println("   Cycles are paused until the plan is fixed")
+		} else {
+			fmt.Println("   Cycles run with agents working without the plan")
+		}
+		fmt.Println()
+	}
+
 	// Total tasks
 	totalTasks := status["total_tasks"].(int)
 	fmt.Printf("Total Tasks: %d\n", totalTasks)
@@ -69,6 +132,34 @@ func printStatus(status map[string]interface{}) {
 		completionRate := float64(completedTasks) / float64(totalTasks) * 100
 		fmt.Printf("Completion Rate: %.1f%% (%d/%d)\n", completionRate, completedTasks, totalTasks)
 	}
+	if cancelled := status["cancelled_tasks"].(int); cancelled > 0 {
+		fmt.Printf("Cancelled: %d (left out of the completion rate)\n", cancelled)
+	}
+
+	// Workspace lock
+	if lockInfo, ok := status["workspace_lock"].(map[string]interface{}); ok {
+		startedAt := lockInfo["started_at"].(time.Time)
+		if lockInfo["stale"].(bool) {
+			fmt.Printf("🔓 Stale Lock: pid %d (baton %s) on %s is no longer running\n",
+				lockInfo["pid"], lockInfo["command"], lockInfo["hostname"])
+		} else {
+			fmt.Printf("🔒 Locked By: pid %d (baton %s) on %s since %s\n",
+				lockInfo["pid"], lockInfo["command"], lockInfo["hostname"], startedAt.Format(time.RFC3339))
+		}
+	} else {
+		fmt.Println("🔓 Workspace Lock: free")
+	}
+
+	// Area locks, shown only when enabled
+	if areaLocks, ok := status["area_locks"].([]*storage.AreaLock); ok {
+		if len(areaLocks) == 0 {
+			fmt.Println("🔓 Area Locks: none held")
+		}
+		for _, lock := range areaLocks {
+			fmt.Printf("🔒 Area %s: task %s (cycle %s, %s) until %s\n",
+				lock.Area, lock.TaskID, lock.CycleID, lock.Holder, lock.ExpiresAt.Format(time.RFC3339))
+		}
+	}
 
 	fmt.Println()
 
@@ -122,4 +213,85 @@ func printStatus(status map[string]interface{}) {
 	} else {
 		fmt.Println("⚠️ No blocked tasks")
 	}
-}
\ No newline at end of file
+
+	// Stale tasks, when detection is on
+	if staleTasks, _ := status["stale_tasks"].([]*statemachine.StaleTask); len(staleTasks) > 0 {
+		fmt.Println()
+		fmt.Printf("🐢 Stale Tasks (%d):\n", len(staleTasks))
+		for i, task := range staleTasks {
+			if i >= 5 { // Limit display to first 5
+				fmt.Printf("  ... and %d more\n", len(staleTasks)-5)
+				break
+			}
+			fmt.Printf("  %s: %s\n    %s for %.1fh (threshold %gh)\n",
+				task.ID, task.Title, task.State, task.HoursInState, task.ThresholdHours)
+		}
+	}
+
+	// Overdue tasks
+	if overdueTasks, _ := status["overdue_tasks"].([]*statemachine.OverdueTask); len(overdueTasks) > 0 {
+		fmt.Println()
+		fmt.Printf("⏰ Overdue Tasks (%d):\n", len(overdueTasks))
+		for i, task := range overdueTasks {
+			if i >= 5 { // Limit display to first 5
+				fmt.Printf("  ... and %d more\n", len(overdueTasks)-5)
+				break
+			}
+			fmt.Printf("  %s: %s\n    %s, due %s (%.1fh overdue)\n",
+				task.ID, task.Title, task.State, task.DueDate.Format("2006-01-02 15:04"), task.HoursOverdue)
+		}
+	}
+
+	// Cycle costs
+	if cycleCosts, ok := status["cycle_costs"].(*storage.RecentCycleCosts); ok && cycleCosts.LastWeek.Cycles > 0 {
+		fmt.Println()
+		fmt.Println("💰 Cycle Costs:")
+		fmt.Printf("  Today: %s\n", formatCycleTotals(cycleCosts.Today))
+		fmt.Printf("  Last 7 days: %s\n", formatCycleTotals(cycleCosts.LastWeek))
+	}
+}
+
+// formatCycleTotals describes what a set of cycles cost on one line
+func formatCycleTotals(totals *storage.CycleTotals) string {
+	return fmt.Sprintf("%d cycles (%d succeeded), %d prompt + %d completion tokens, $%.4f, %s agent time",
+		totals.Cycles, totals.Succeeded, totals.PromptTokens, totals.CompletionTokens, totals.CostUSD,
+		(time.Duration(totals.DurationSeconds) * time.Second).String())
+}
+
+func runOwnerStatus(cmd *cobra.Command, selector *statemachine.TaskSelector) error {
+	window, _ := cmd.Flags().GetDuration("window")
+	report, err := selector.GetOwnerWorkload(globalConfig, time.Now().Add(-window))
+	if err != nil {
+		return fmt.Errorf("failed to get owner workload: %w", err)
+	}
+
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Println("👥 Workload by Owner")
+	fmt.Println("====================")
+	fmt.Printf("Throughput since %s\n\n", report.Since.Format("2006-01-02 15:04"))
+
+	if len(report.Owners) == 0 {
+		fmt.Println("No tasks or recent activity")
+		return nil
+	}
+
+	fmt.Printf("%-20s %6s %9s %8s %12s %10s\n", "OWNER", "OPEN", "IN-FLIGHT", "BLOCKED", "TRANSITIONS", "COMPLETED")
+	for _, w := range report.Owners {
+		owner := w.Owner
+		if w.IsAgent {
+			owner += " (agent)"
+		}
+		fmt.Printf("%-20s %6d %9d %8d %12d %10d\n", owner, w.OpenTasks, w.InFlight, w.Blocked, w.Transitions, w.Completed)
+	}
+
+	return nil
+}
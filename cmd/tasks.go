@@ -2,12 +2,27 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"baton/internal/artifactfs"
+	"baton/internal/cycle"
+	"baton/internal/decompose"
+	"baton/internal/hooks"
+	"baton/internal/llm"
+	"baton/internal/notify"
+	"baton/internal/report"
 	"baton/internal/statemachine"
 	"baton/internal/storage"
+	"baton/internal/taskcsv"
 )
 
 // tasksCmd represents the tasks command
@@ -37,8 +52,293 @@ var tasksNextCmd = &cobra.Command{
 var tasksUpdateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update task state",
-	Long:  `Manually update a task's state with validation and audit logging.`,
-	RunE:  runTasksUpdate,
+	Long: `Manually update a task's state with validation and audit logging.
+
+Instead of --id, one or more --filter name=value flags move every matching task,
+where name is state, owner, priority, tag or a custom field. Every task's
+transition is validated first and all of them are saved in one transaction, so
+either every matching task moves or none does; with --dry-run they are only listed.
+
+A move into a state at its selection.wip_limits limit is refused unless
+--override-wip is given.`,
+	RunE: runTasksUpdate,
+}
+
+// tasksEstimateCmd represents the tasks estimate command
+var tasksEstimateCmd = &cobra.Command{
+	Use:   "estimate",
+	Short: "Set a task's estimated hours",
+	Long:  `Set how many hours of work a task is expected to take; with the timebox formula enabled this sizes each cycle's timeout.`,
+	RunE:  runTasksEstimate,
+}
+
+// tasksDueCmd represents the tasks due command
+var tasksDueCmd = &cobra.Command{
+	Use:   "due",
+	Short: "Set a task's due date",
+	Long: `Set the date a task must be done by, as YYYY-MM-DD (the end of that day) or an
+RFC 3339 timestamp; "none" clears it. Weighted selection favors tasks as their due
+date nears, and open tasks past it are reported as overdue by 'baton status'.`,
+	RunE: runTasksDue,
+}
+
+// tasksDecomposeCmd represents the tasks decompose command
+var tasksDecomposeCmd = &cobra.Command{
+	Use:   "decompose",
+	Short: "Split a task into smaller dependent subtasks",
+	Long: `Ask the LLM to split a task into smaller subtasks that are worked in order.
+The original task becomes their parent and waits for them; the rationale is saved
+as its decomposition artifact. Cycles offer this once a task reaches
+decomposition.failure_threshold unsuccessful cycles in a row, or run it
+themselves with decomposition.mode: auto.`,
+	RunE: runTasksDecompose,
+}
+
+// tasksWatchCmd represents the tasks watch command
+var tasksWatchCmd = &cobra.Command{
+	Use:   "watch <task-id>",
+	Short: "Get notified when a task changes",
+	Long: `Subscribe to a task: whenever it transitions, gains an artifact or fails a
+cycle, a notification is sent on the chosen channel from notifications.channels
+(notifications.default_channel when none is given). Watching again changes the channel.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTasksWatch,
+}
+
+// tasksUnwatchCmd represents the tasks unwatch command
+var tasksUnwatchCmd = &cobra.Command{
+	Use:   "unwatch <task-id>",
+	Short: "Stop notifications for a task",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksUnwatch,
+}
+
+// tasksWatchersCmd represents the tasks watchers command
+var tasksWatchersCmd = &cobra.Command{
+	Use:   "watchers [task-id]",
+	Short: "List task watches",
+	Long:  `List who is watching a task, or every watch in the workspace when no task is given.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runTasksWatchers,
+}
+
+// tasksSetFieldCmd represents the tasks set-field command
+var tasksSetFieldCmd = &cobra.Command{
+	Use:   "set-field",
+	Short: "Set a task's custom field",
+	Long: `Set one of the custom fields defined under custom_fields in the configuration,
+such as story points or a customer, on a task. The value is checked against the
+field's type; --unset clears it.`,
+	RunE: runTasksSetField,
+}
+
+// tasksHistoryCmd represents the tasks history command
+var tasksHistoryCmd = &cobra.Command{
+	Use:   "history <task-id>",
+	Short: "Show a task's title and description revisions",
+	Long: `List every recorded revision of a task's title and description with who made
+it: import, llm, web, cli or renumber. --diff shows what each revision changed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTasksHistory,
+}
+
+// tasksRevertCmd represents the tasks revert command
+var tasksRevertCmd = &cobra.Command{
+	Use:   "revert <task-id> <revision>",
+	Short: "Restore a task's title and description from a revision",
+	Long: `Restore the title and description a task had at one of its revisions, as
+listed by 'baton tasks history'. The restore is recorded as a new revision.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runTasksRevert,
+}
+
+// tasksRollbackCmd represents the tasks rollback command
+var tasksRollbackCmd = &cobra.Command{
+	Use:   "rollback <task-id>",
+	Short: "Undo a task's last recorded state transition",
+	Long: `Return a task to the state it had before its last transition in the audit log,
+e.g. when an agent wrongly marked it ready_for_commit. Artifacts changed since the
+transition's work began get the content of their earlier version back, saved as a
+new version; artifacts the transition first created are kept. A compensating audit
+entry records the rollback, and running rollback again undoes the transition before.
+With --dry-run the rollback is only described.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTasksRollback,
+}
+
+// tasksReviewCmd represents the tasks review command
+var tasksReviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "List low-confidence transitions awaiting human review",
+	Long: `List the tasks whose last transition the agent rated below
+completion.low_confidence_threshold in its self-assessment, least confident
+first, with the questions it left open. A task drops off the list once it
+changes state again.`,
+	RunE: runTasksReview,
+}
+
+// tasksExportCmd represents the tasks export command
+var tasksExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export tasks to CSV or JSON",
+	Long: `Write tasks as CSV, with dependencies as short IDs, or as JSON. The CSV can be
+edited in a spreadsheet and read back with baton tasks import.`,
+	RunE: runTasksExport,
+}
+
+// tasksImportCmd represents the tasks import command
+var tasksImportCmd = &cobra.Command{
+	Use:   "import <file.csv|file.json>",
+	Short: "Create and update tasks from CSV or JSON",
+	Long: `Create and update tasks from a CSV file with a header row naming any of the
+columns id, title, description, state, priority, owner, milestone, tags,
+dependencies and estimated_hours.
+
+A row whose id is the short ID of an existing task updates it, leaving fields
+with empty cells unchanged; any other row creates a task. An id that matches no
+task is a label other rows can list in their dependencies. Tags and dependencies
+are separated by ";". Every row is validated first and nothing is imported when
+any row is invalid; with --dry-run the changes are only listed.
+
+A .json file holds tasks as written by 'baton tasks export --format json'; each
+task is imported as the CSV row it would export as.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTasksImport,
+}
+
+// tasksSetParentCmd represents the tasks set-parent command
+var tasksSetParentCmd = &cobra.Command{
+	Use:   "set-parent <task-id> [parent-id]",
+	Short: "Group a task under a parent task",
+	Long: `Group a task under a parent task, such as an epic or MVP, or make it a top-level
+task again with --unset. A parent task is not selected, and can't move into a work
+state, until every subtask grouped under it is DONE. 'baton tasks list --tree'
+shows the hierarchy.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runTasksSetParent,
+}
+
+// tasksNoteCmd represents the tasks note command
+var tasksNoteCmd = &cobra.Command{
+	Use:   "note <task-id> <text>",
+	Short: "Add a note to a task",
+	Long:  `Add a note to a task without changing its state. Notes are listed by 'baton tasks notes'.`,
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runTasksNote,
+}
+
+// tasksNotesCmd represents the tasks notes command
+var tasksNotesCmd = &cobra.Command{
+	Use:   "notes <task-id>",
+	Short: "List a task's notes",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksNotes,
+}
+
+// tasksArchiveCmd represents the tasks archive command
+var tasksArchiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Archive finished tasks last updated before a date",
+	Long: `Archive tasks in a finished state (DONE by default) that were last updated
+before --before, so task lists, selection and the board leave them out. Archived
+tasks keep their history and still count in reports, milestones and replay
+snapshots; list them with 'baton tasks list --archived' and bring them back with
+'baton tasks unarchive'.`,
+	RunE: runTasksArchive,
+}
+
+// tasksUnarchiveCmd represents the tasks unarchive command
+var tasksUnarchiveCmd = &cobra.Command{
+	Use:   "unarchive <task-id>...",
+	Short: "Return archived tasks to the task lists",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runTasksUnarchive,
+}
+
+// tasksDeleteCmd represents the tasks delete command
+var tasksDeleteCmd = &cobra.Command{
+	Use:   "delete <task-id>...",
+	Short: "Move tasks to the trash",
+	Long: `Move tasks to the trash. Deleted tasks drop out of task lists, counts, search,
+selection and the board, but keep their artifacts and history until they are purged
+with 'baton tasks trash --purge'; 'baton tasks restore' brings them back. A task
+with subtasks can't be deleted until they are.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runTasksDelete,
+}
+
+// tasksRestoreCmd represents the tasks restore command
+var tasksRestoreCmd = &cobra.Command{
+	Use:   "restore <task-id>...",
+	Short: "Take deleted tasks out of the trash",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runTasksRestore,
+}
+
+// tasksTrashCmd represents the tasks trash command
+var tasksTrashCmd = &cobra.Command{
+	Use:   "trash [task-id...]",
+	Short: "List or purge deleted tasks",
+	Long: `List the tasks in the trash, most recently deleted first. With --purge the given
+tasks, or every task in the trash when none are given, are removed for good along with
+their artifacts, audit logs, notes and other history; with --dry-run they are only listed.`,
+	RunE: runTasksTrash,
+}
+
+// tasksHoldCmd represents the tasks hold command
+var tasksHoldCmd = &cobra.Command{
+	Use:   "hold <task-id>",
+	Short: "Put a task on hold as blocked or paused",
+	Long: `Put a task waiting on something outside it on hold: blocked by default, or paused
+with --paused. A held task keeps its place in the workflow but is not selected,
+and the board shows it apart from the work columns, until 'baton tasks resume'
+returns it to the state it was held from. --reason is recorded as a note on the task.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTasksHold,
+}
+
+// tasksResumeCmd represents the tasks resume command
+var tasksResumeCmd = &cobra.Command{
+	Use:   "resume <task-id>",
+	Short: "Return a blocked or paused task to the state it was held from",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksResume,
+}
+
+// tasksCancelCmd represents the tasks cancel command
+var tasksCancelCmd = &cobra.Command{
+	Use:   "cancel <task-id>",
+	Short: "Cancel a task that won't be done",
+	Long: `Move a task to the cancelled terminal state, from any state but DONE. --reason
+is required and recorded as a note on the task. Cancelled tasks are left out of
+the completion rate, as archived tasks are.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTasksCancel,
+}
+
+// tasksTimeCmd represents the tasks time command
+var tasksTimeCmd = &cobra.Command{
+	Use:   "time <task-id>",
+	Short: "Show how long a task spent in each state",
+	Long: `Show every state a task has moved into, when, and how long it stayed there,
+with totals per state. The current state counts up to now. Tasks created before
+state history was kept start with their current state, dated from their last update.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTasksTime,
+}
+
+// tasksSimulateCmd represents the tasks simulate command
+var tasksSimulateCmd = &cobra.Command{
+	Use:   "simulate <task-id>",
+	Short: "Check what moving a task to a state needs, without moving it",
+	Long: `Check a state change the way 'baton tasks update' would, without making it:
+unfinished dependencies and subtasks, missing or invalid handover artifacts, unmet
+transition rules, a full WIP limit, and the hooks the move would run. Blocking hooks
+are listed but not run, so they may still refuse the move.
+
+  baton tasks simulate task-123 --to ready_for_commit`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTasksSimulate,
 }
 
 func init() {
@@ -46,24 +346,152 @@ func init() {
 	tasksCmd.AddCommand(tasksListCmd)
 	tasksCmd.AddCommand(tasksNextCmd)
 	tasksCmd.AddCommand(tasksUpdateCmd)
+	tasksCmd.AddCommand(tasksEstimateCmd)
+	tasksCmd.AddCommand(tasksDueCmd)
+	tasksCmd.AddCommand(tasksDecomposeCmd)
+	tasksCmd.AddCommand(tasksWatchCmd)
+	tasksCmd.AddCommand(tasksUnwatchCmd)
+	tasksCmd.AddCommand(tasksWatchersCmd)
+	tasksCmd.AddCommand(tasksSetFieldCmd)
+	tasksCmd.AddCommand(tasksHistoryCmd)
+	tasksCmd.AddCommand(tasksRevertCmd)
+	tasksCmd.AddCommand(tasksRollbackCmd)
+	tasksCmd.AddCommand(tasksReviewCmd)
+	tasksCmd.AddCommand(tasksExportCmd)
+	tasksCmd.AddCommand(tasksImportCmd)
+	tasksCmd.AddCommand(tasksSetParentCmd)
+	tasksCmd.AddCommand(tasksNoteCmd)
+	tasksCmd.AddCommand(tasksNotesCmd)
+	tasksCmd.AddCommand(tasksArchiveCmd)
+	tasksCmd.AddCommand(tasksUnarchiveCmd)
+	tasksCmd.AddCommand(tasksDeleteCmd)
+	tasksCmd.AddCommand(tasksRestoreCmd)
+	tasksCmd.AddCommand(tasksTrashCmd)
+	tasksCmd.AddCommand(tasksHoldCmd)
+	tasksCmd.AddCommand(tasksResumeCmd)
+	tasksCmd.AddCommand(tasksCancelCmd)
+	tasksCmd.AddCommand(tasksTimeCmd)
+	tasksCmd.AddCommand(tasksSimulateCmd)
 
 	// List command flags
 	tasksListCmd.Flags().String("state", "", "filter by state")
 	tasksListCmd.Flags().Int("priority", -1, "filter by priority")
 	tasksListCmd.Flags().String("owner", "", "filter by owner")
+	tasksListCmd.Flags().StringArray("tag", nil, "only tasks with this tag (repeatable; tasks must have every tag)")
+	tasksListCmd.Flags().StringArray("field", nil, "filter by custom field, as name=value (repeatable)")
+	tasksListCmd.Flags().Bool("archived", false, "list archived tasks instead")
+	tasksListCmd.Flags().Bool("tree", false, "show subtasks grouped under their parent tasks")
+	tasksListCmd.Flags().String("sort", storage.SortByPriority, "sort by "+strings.Join(storage.TaskSorts, ", "))
+	tasksListCmd.Flags().Bool("reverse", false, "reverse the sort order")
+	tasksListCmd.Flags().Int("limit", 0, "list at most this many tasks (0 lists all)")
+	tasksListCmd.Flags().Int("offset", 0, "skip this many tasks")
+	tasksListCmd.Flags().String("cursor", "", "continue after the page that printed this cursor")
 	tasksListCmd.Flags().Bool("json", false, "output in JSON format")
 
+	// Next command flags
+	tasksNextCmd.Flags().Bool("json", false, "output in JSON format")
+
 	// Update command flags
-	tasksUpdateCmd.Flags().String("id", "", "task ID (required)")
+	tasksUpdateCmd.Flags().String("id", "", "task ID (required unless --filter is given)")
 	tasksUpdateCmd.Flags().String("state", "", "new state (required)")
 	tasksUpdateCmd.Flags().String("note", "", "optional note")
-	tasksUpdateCmd.MarkFlagRequired("id")
+	tasksUpdateCmd.Flags().Bool("override-wip", false, "move the task even if its new state is at its WIP limit")
+	tasksUpdateCmd.Flags().StringArray("filter", nil, "update every task matching name=value, where name is state, owner, priority, tag or a custom field (repeatable)")
 	tasksUpdateCmd.MarkFlagRequired("state")
+	tasksUpdateCmd.MarkFlagsOneRequired("id", "filter")
+	tasksUpdateCmd.MarkFlagsMutuallyExclusive("id", "filter")
+
+	// Estimate command flags
+	tasksEstimateCmd.Flags().String("id", "", "task ID (required)")
+	tasksEstimateCmd.Flags().Float64("hours", 0, "estimated hours of work; 0 clears the estimate (required)")
+	tasksEstimateCmd.MarkFlagRequired("id")
+	tasksEstimateCmd.MarkFlagRequired("hours")
+
+	// Due command flags
+	tasksDueCmd.Flags().String("id", "", "task ID (required)")
+	tasksDueCmd.Flags().String("date", "", `due date (YYYY-MM-DD or RFC 3339), or "none" to clear it (required)`)
+	tasksDueCmd.MarkFlagRequired("id")
+	tasksDueCmd.MarkFlagRequired("date")
+
+	// Set-field command flags
+	tasksSetFieldCmd.Flags().String("id", "", "task ID (required)")
+	tasksSetFieldCmd.Flags().String("name", "", "custom field name (required)")
+	tasksSetFieldCmd.Flags().String("value", "", "new value")
+	tasksSetFieldCmd.Flags().Bool("unset", false, "clear the field")
+	tasksSetFieldCmd.MarkFlagRequired("id")
+	tasksSetFieldCmd.MarkFlagRequired("name")
+
+	// Decompose command flags
+	tasksDecomposeCmd.Flags().String("id", "", "task ID (required)")
+	tasksDecomposeCmd.Flags().Bool("json", false, "output in JSON format")
+	tasksDecomposeCmd.MarkFlagRequired("id")
+
+	// Watch command flags
+	tasksWatchCmd.Flags().String("channel", "", "notification channel (default notifications.default_channel)")
+	tasksWatchCmd.Flags().String("watcher", "", "who is watching (default $USER)")
+	tasksUnwatchCmd.Flags().String("watcher", "", "who is watching (default $USER)")
+	tasksWatchersCmd.Flags().Bool("json", false, "output in JSON format")
+
+	// History command flags
+	tasksHistoryCmd.Flags().Bool("diff", false, "show what each revision changed")
+	tasksHistoryCmd.Flags().Bool("json", false, "output in JSON format")
+	tasksRevertCmd.Flags().String("actor", "cli", "who the revert is attributed to")
+	tasksRollbackCmd.Flags().String("actor", "cli", "who the rollback is attributed to")
+	tasksRollbackCmd.Flags().String("reason", "", "why the transition is undone, recorded in the audit entry")
+
+	// Review command flags
+	tasksReviewCmd.Flags().Float64("threshold", -1, "confidence below which a transition needs review (default completion.low_confidence_threshold)")
+	tasksReviewCmd.Flags().Bool("json", false, "output in JSON format")
+
+	// Export command flags
+	tasksExportCmd.Flags().String("format", "csv", "output format: csv or json")
+	tasksExportCmd.Flags().StringP("output", "o", "", "file to write (default stdout)")
+	tasksExportCmd.Flags().String("state", "", "only export tasks in this state")
+	tasksExportCmd.Flags().String("milestone", "", "only export tasks in this milestone")
+	tasksExportCmd.Flags().StringArray("field", nil, "only export tasks with this custom field value, as name=value (repeatable)")
+
+	// Import command flags
+	tasksImportCmd.Flags().Bool("json", false, "output the planned changes in JSON format")
+
+	// Set-parent command flags
+	tasksSetParentCmd.Flags().Bool("unset", false, "make the task a top-level task")
+
+	// Note command flags
+	tasksNoteCmd.Flags().String("author", "", "who wrote the note (default $USER)")
+	tasksNotesCmd.Flags().Bool("json", false, "output in JSON format")
+
+	// Archive command flags
+	tasksArchiveCmd.Flags().String("before", "", "archive tasks last updated before this date, as YYYY-MM-DD (required)")
+	tasksArchiveCmd.Flags().String("state", string(storage.Done), "archive tasks in this finished state")
+	tasksArchiveCmd.Flags().Bool("json", false, "output the archived tasks in JSON format")
+	tasksArchiveCmd.MarkFlagRequired("before")
+
+	// Trash command flags
+	tasksTrashCmd.Flags().Bool("purge", false, "permanently remove the given tasks, or every task in the trash")
+	tasksTrashCmd.Flags().Bool("json", false, "output in JSON format")
+
+	// Hold and resume command flags
+	tasksHoldCmd.Flags().Bool("paused", false, "pause the task instead of marking it blocked")
+	tasksHoldCmd.Flags().String("reason", "", "why the task is on hold, recorded as a note")
+	tasksResumeCmd.Flags().String("note", "", "optional note")
+
+	// Cancel command flags
+	tasksCancelCmd.Flags().String("reason", "", "why the task won't be done (required)")
+	tasksCancelCmd.MarkFlagRequired("reason")
+
+	// Time command flags
+	tasksTimeCmd.Flags().Bool("json", false, "output in JSON format")
+
+	// Simulate command flags
+	tasksSimulateCmd.Flags().String("to", "", "state to check the move to (required)")
+	tasksSimulateCmd.Flags().Bool("override-wip", false, "ignore selection.wip_limits, as 'tasks update --override-wip' does")
+	tasksSimulateCmd.Flags().Bool("json", false, "output in JSON format")
+	tasksSimulateCmd.MarkFlagRequired("to")
 }
 
 func runTasksList(cmd *cobra.Command, args []string) error {
 	// Initialize database
-	store, err := storage.NewStore(globalConfig.Database)
+	store, err := openStore(globalConfig)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
@@ -85,15 +513,38 @@ func runTasksList(cmd *cobra.Command, args []string) error {
 		filters.Owner = &owner
 	}
 
+	filters.Tags, _ = cmd.Flags().GetStringArray("tag")
+	if filters.CustomFields, err = customFieldFilters(cmd); err != nil {
+		return err
+	}
+	filters.ArchivedOnly, _ = cmd.Flags().GetBool("archived")
+	filters.Sort, _ = cmd.Flags().GetString("sort")
+	filters.Reverse, _ = cmd.Flags().GetBool("reverse")
+	filters.Limit, _ = cmd.Flags().GetInt("limit")
+	filters.Offset, _ = cmd.Flags().GetInt("offset")
+	filters.Cursor, _ = cmd.Flags().GetString("cursor")
+	if filters.Limit < 0 || filters.Offset < 0 {
+		return fmt.Errorf("--limit and --offset must not be negative")
+	}
+
 	// Get tasks
-	tasks, err := store.ListTasks(filters)
+	page, err := store.ListTaskPage(filters)
 	if err != nil {
 		return fmt.Errorf("failed to list tasks: %w", err)
 	}
+	tasks := page.Tasks
+
+	tree, _ := cmd.Flags().GetBool("tree")
 
-	// Check for JSON output
+	// Check for JSON output; a limited listing prints the page with its total and next cursor
 	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
-		data, err := json.MarshalIndent(tasks, "", "  ")
+		var v interface{} = tasks
+		if tree {
+			v = storage.BuildTaskTree(tasks)
+		} else if filters.Limit > 0 {
+			v = page
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
 		if err != nil {
 			return fmt.Errorf("failed to marshal JSON: %w", err)
 		}
@@ -107,7 +558,21 @@ func runTasksList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Printf("Found %d tasks:\n\n", len(tasks))
+	if len(tasks) < page.Total {
+		fmt.Printf("Showing %d of %d tasks:\n\n", len(tasks), page.Total)
+	} else {
+		fmt.Printf("Found %d tasks:\n\n", len(tasks))
+	}
+	// The next page's hint is printed last, after the listing
+	if page.NextCursor != "" {
+		defer fmt.Printf("More tasks follow; repeat the command with --cursor %s\n", page.NextCursor)
+	}
+
+	if tree {
+		printTaskTree(storage.BuildTaskTree(tasks))
+		return nil
+	}
+
 	for _, task := range tasks {
 		fmt.Printf("📝 %s\n", task.ID)
 		fmt.Printf("  Title: %s\n", task.Title)
@@ -116,6 +581,25 @@ func runTasksList(cmd *cobra.Command, args []string) error {
 		if task.Owner != "" {
 			fmt.Printf("  Owner: %s\n", task.Owner)
 		}
+		if task.EstimatedHours > 0 {
+			fmt.Printf("  Estimate: %gh\n", task.EstimatedHours)
+		}
+		if task.DueDate != nil {
+			if task.IsOverdue(time.Now()) {
+				fmt.Printf("  Due: %s (overdue)\n", task.DueDate.Format("2006-01-02 15:04"))
+			} else {
+				fmt.Printf("  Due: %s\n", task.DueDate.Format("2006-01-02 15:04"))
+			}
+		}
+		if task.ParentID != "" {
+			fmt.Printf("  Parent: %s\n", task.ParentID)
+		}
+		values := task.CustomFieldMap()
+		for _, name := range globalConfig.CustomFields.Names() {
+			if value, ok := values[name]; ok {
+				fmt.Printf("  %s: %v\n", name, value)
+			}
+		}
 		if task.Description != "" {
 			fmt.Printf("  Description: %s\n", task.Description)
 		}
@@ -125,9 +609,48 @@ func runTasksList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// printTaskTree prints one line per task, with subtasks indented under
+// their parent
+func printTaskTree(nodes []*storage.TaskNode) {
+	for _, node := range nodes {
+		fmt.Println(taskTreeLine(node))
+		printSubtaskTree(node.Children, "  ")
+	}
+}
+
+// printSubtaskTree prints the subtasks of a task as tree branches
+func printSubtaskTree(nodes []*storage.TaskNode, indent string) {
+	for i, node := range nodes {
+		branch, next := "├─ ", "│  "
+		if i == len(nodes)-1 {
+			branch, next = "└─ ", "   "
+		}
+		fmt.Println(indent + branch + taskTreeLine(node))
+		printSubtaskTree(node.Children, indent+next)
+	}
+}
+
+// taskTreeLine is a task's line in the tree, with subtask progress for parents
+func taskTreeLine(node *storage.TaskNode) string {
+	line := fmt.Sprintf("%s %s [%s]", node.ID[:8], node.Title, node.State)
+	if len(node.Children) > 0 {
+		done, counted := 0, 0
+		for _, child := range node.Children {
+			if child.State == storage.Done {
+				done++
+			}
+			if child.State != storage.Cancelled {
+				counted++
+			}
+		}
+		line += fmt.Sprintf(" (%d/%d subtasks done)", done, counted)
+	}
+	return line
+}
+
 func runTasksNext(cmd *cobra.Command, args []string) error {
 	// Initialize database
-	store, err := storage.NewStore(globalConfig.Database)
+	store, err := openStore(globalConfig)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
@@ -135,6 +658,7 @@ func runTasksNext(cmd *cobra.Command, args []string) error {
 
 	// Create task selector
 	selector := statemachine.NewTaskSelector(store, &globalConfig.Selection)
+	selector.SetAgentCoverage(statemachine.AgentCoverage(globalConfig))
 
 	// Get next task
 	result, err := selector.SelectNext()
@@ -142,6 +666,15 @@ func runTasksNext(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to select next task: %w", err)
 	}
 
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
 	// Display result
 	fmt.Println("🎯 Next Task Selection")
 	fmt.Println("=====================")
@@ -149,6 +682,9 @@ func runTasksNext(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Title: %s\n", result.Task.Title)
 	fmt.Printf("State: %s\n", result.Task.State)
 	fmt.Printf("Priority: %d\n", result.Task.Priority)
+	if result.Score != nil {
+		fmt.Printf("Score: %.2f\n", result.Score.Total)
+	}
 	fmt.Printf("\n🧐 Selection Reasoning:\n%s\n", result.Reason)
 
 	return nil
@@ -158,19 +694,37 @@ func runTasksUpdate(cmd *cobra.Command, args []string) error {
 	taskID, _ := cmd.Flags().GetString("id")
 	stateStr, _ := cmd.Flags().GetString("state")
 	note, _ := cmd.Flags().GetString("note")
+	overrideWIP, _ := cmd.Flags().GetBool("override-wip")
+	if filters, _ := cmd.Flags().GetStringArray("filter"); len(filters) > 0 {
+		return runTasksBulkUpdate(filters, stateStr, note, overrideWIP)
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("tasks update")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
 
 	// Initialize database
-	store, err := storage.NewStore(globalConfig.Database)
+	store, err := openStore(globalConfig)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
 
 	// Normalize state
 	newState := storage.NormalizeState(stateStr)
 
 	// Create validator
 	validator := statemachine.NewTransitionValidator(store)
+	validator.SetArtifactSchemas(globalConfig.ArtifactSchemas)
+	validator.SetTransitionRules(globalConfig.TransitionRules)
+	validator.SetHooks(hooks.NewRunner(globalConfig.Hooks, globalConfig.Workspace))
+	if !overrideWIP {
+		validator.SetWIPLimits(globalConfig.Selection.WIPLimits)
+	}
+	validator.SetReviewEscalation(globalConfig.ReviewEscalation)
 
 	// Perform the update
 	if err := validator.ValidateAndTransition(taskID, newState, note); err != nil {
@@ -181,6 +735,1395 @@ func runTasksUpdate(cmd *cobra.Command, args []string) error {
 	if note != "" {
 		fmt.Printf("Note: %s\n", note)
 	}
+	if task, err := store.GetTask(taskID); err == nil && task.State != newState && storage.IsHeld(task.State) {
+		printEscalation(taskID, task.State)
+	}
+
+	milestone, err := report.RecordMilestoneSummary(store, taskID, globalConfig.Acceptance.MandatoryTypes)
+	if err != nil {
+		return err
+	}
+	if milestone != "" {
+		fmt.Printf("🏁 Milestone %s is finished; review its summary and sign it off with: baton milestones signoff %s\n", milestone, milestone)
+	}
+
+	return nil
+}
+
+// runTasksBulkUpdate moves every task matching the --filter flags to a state
+func runTasksBulkUpdate(filterFlags []string, stateStr, note string, overrideWIP bool) error {
+	filters, err := bulkTaskFilters(filterFlags)
+	if err != nil {
+		return err
+	}
+	dryRun := globalConfig.Development.DryRunDefault
+
+	if !dryRun {
+		workspaceLock, err := acquireWorkspaceLock("tasks update")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+
+	tasks, err := store.ListTasks(filters)
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if len(tasks) == 0 {
+		fmt.Println("No tasks match the filters")
+		return nil
+	}
+	newState := storage.NormalizeState(stateStr)
+	if err := statemachine.ValidateReason(newState, note); err != nil {
+		return err
+	}
+
+	// Tasks already in the state are left alone; every other task must be able to move
+	validator := statemachine.NewTransitionValidator(store)
+	validator.SetArtifactSchemas(globalConfig.ArtifactSchemas)
+	validator.SetTransitionRules(globalConfig.TransitionRules)
+	var moving []*storage.Task
+	var problems []string
+	for _, task := range tasks {
+		if task.State == newState {
+			continue
+		}
+		if err := validator.Validate(task, newState); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %q: %v", artifactfs.ShortID(task.ID), task.Title, err))
+			continue
+		}
+		moving = append(moving, task)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d of %d matching tasks can't move to %s, so none were updated:\n  %s",
+			len(problems), len(tasks), newState, strings.Join(problems, "\n  "))
+	}
+	if len(moving) == 0 {
+		fmt.Printf("No tasks to update: %d matching tasks are already %s\n", len(tasks), newState)
+		return nil
+	}
+	// Together the moving tasks must fit within the new state's WIP limit
+	if !overrideWIP {
+		validator.SetWIPLimits(globalConfig.Selection.WIPLimits)
+		if err := validator.CheckWIPLimit(newState, len(moving)); err != nil {
+			return fmt.Errorf("can't move %d tasks to %s: %w", len(moving), newState, err)
+		}
+	}
+
+	for _, task := range moving {
+		fmt.Printf("  %s %q: %s -> %s\n", artifactfs.ShortID(task.ID), task.Title, task.State, newState)
+	}
+	if dryRun {
+		fmt.Printf("Dry run: would update %d tasks to state: %s\n", len(moving), newState)
+		return nil
+	}
+
+	for _, task := range moving {
+		task.State = newState
+	}
+	if err := store.UpdateTasksBatch(moving, "cli", note); err != nil {
+		return fmt.Errorf("failed to update tasks: %w", err)
+	}
+	if newState == storage.Cancelled {
+		for _, task := range moving {
+			if err := statemachine.RecordCancelReason(store, task.ID, note); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("✅ Updated %d tasks to state: %s\n", len(moving), newState)
+	if note != "" {
+		fmt.Printf("Note: %s\n", note)
+	}
+
+	validator.SetReviewEscalation(globalConfig.ReviewEscalation)
+	for _, task := range moving {
+		hold, err := validator.Escalate(task.ID)
+		if err != nil {
+			return err
+		}
+		if hold != "" {
+			printEscalation(task.ID, hold)
+		}
+	}
+
+	for _, task := range moving {
+		milestone, err := report.RecordMilestoneSummary(store, task.ID, globalConfig.Acceptance.MandatoryTypes)
+		if err != nil {
+			return err
+		}
+		if milestone != "" {
+			fmt.Printf("🏁 Milestone %s is finished; review its summary and sign it off with: baton milestones signoff %s\n", milestone, milestone)
+		}
+	}
+
+	return nil
+}
+
+// printEscalation tells the user a task was put on hold for failing review too often
+func printEscalation(taskID string, hold storage.State) {
+	fmt.Printf("⚠️  Task %s failed review %d times and was escalated to %s; look into it, then resume it with: baton tasks resume %s\n",
+		taskID, globalConfig.ReviewEscalation.MaxFailures, hold, taskID)
+}
+
+// bulkTaskFilters parses --filter name=value flags
+func bulkTaskFilters(flags []string) (storage.TaskFilters, error) {
+	filters := storage.TaskFilters{}
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || name == "" {
+			return filters, fmt.Errorf("invalid --filter %q: use name=value", flag)
+		}
+		switch name {
+		case "state":
+			state := storage.NormalizeState(value)
+			filters.State = &state
+		case "owner":
+			owner := value
+			filters.Owner = &owner
+		case "priority":
+			priority, err := strconv.Atoi(value)
+			if err != nil {
+				return filters, fmt.Errorf("invalid --filter %q: priority must be a number", flag)
+			}
+			filters.Priority = &priority
+		case "tag":
+			filters.Tags = append(filters.Tags, value)
+		default:
+			fieldValue, err := globalConfig.CustomFields.Value(name, value)
+			if err != nil {
+				return filters, fmt.Errorf("invalid --filter %q: %w", flag, err)
+			}
+			if filters.CustomFields == nil {
+				filters.CustomFields = make(map[string]interface{})
+			}
+			filters.CustomFields[name] = fieldValue
+		}
+	}
+	return filters, nil
+}
+
+func runTasksEstimate(cmd *cobra.Command, args []string) error {
+	taskID, _ := cmd.Flags().GetString("id")
+	hours, _ := cmd.Flags().GetFloat64("hours")
+	if hours < 0 {
+		return fmt.Errorf("hours must not be negative")
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("tasks estimate")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	task, err := store.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	task.EstimatedHours = hours
+	if err := store.UpdateTask(task); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Task %s estimated at %gh (cycle timebox: %s)\n", taskID, hours, cycle.CycleTimeout(globalConfig, task))
+	return nil
+}
+
+func runTasksDue(cmd *cobra.Command, args []string) error {
+	taskID, _ := cmd.Flags().GetString("id")
+	dateStr, _ := cmd.Flags().GetString("date")
+
+	var due *time.Time
+	if dateStr != "none" {
+		parsed, err := storage.ParseDueDate(dateStr)
+		if err != nil {
+			return err
+		}
+		due = &parsed
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("tasks due")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	task, err := store.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	task.DueDate = due
+	if err := store.UpdateTask(task); err != nil {
+		return err
+	}
 
+	if due == nil {
+		fmt.Printf("✅ Task %s due date cleared\n", taskID)
+		return nil
+	}
+	fmt.Printf("✅ Task %s due %s\n", taskID, due.Format("2006-01-02 15:04"))
 	return nil
-}
\ No newline at end of file
+}
+
+func runTasksDecompose(cmd *cobra.Command, args []string) error {
+	taskID, _ := cmd.Flags().GetString("id")
+
+	workspaceLock, err := acquireWorkspaceLock("tasks decompose")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+
+	llmClient, err := llm.NewClient(globalConfig.LLM)
+	if err != nil {
+		return fmt.Errorf("failed to create LLM client: %w", err)
+	}
+
+	result, err := decompose.NewDecomposer(store, llmClient, globalConfig.Decomposition).Decompose(taskID)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("✂️  Task %s split into %d subtasks:\n", taskID, len(result.Subtasks))
+	for i, subtask := range result.Subtasks {
+		fmt.Printf("  %d. %s (%s)\n", i+1, subtask.Title, subtask.ID)
+	}
+	if result.Rationale != "" {
+		fmt.Printf("\nRationale: %s\n", result.Rationale)
+	}
+	return nil
+}
+
+// watcherName returns the --watcher flag, falling back to the current user
+func watcherName(cmd *cobra.Command) string {
+	if watcher, _ := cmd.Flags().GetString("watcher"); watcher != "" {
+		return watcher
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "local"
+}
+
+func runTasksWatch(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	channel, _ := cmd.Flags().GetString("channel")
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	task, err := store.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("task %s not found: %w", taskID, err)
+	}
+	if _, err := notify.NewNotifier(store, globalConfig.Notifications).Channel(channel); err != nil {
+		return err
+	}
+
+	watch := &storage.TaskWatch{TaskID: task.ID, Watcher: watcherName(cmd), Channel: channel}
+	if err := store.WatchTask(watch); err != nil {
+		return fmt.Errorf("failed to watch task: %w", err)
+	}
+
+	if channel == "" {
+		channel = globalConfig.Notifications.DefaultChannel
+	}
+	fmt.Printf("👀 %s is watching %q (%s) on channel %s\n", watch.Watcher, task.Title, task.ID, channel)
+	return nil
+}
+
+func runTasksUnwatch(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	watcher := watcherName(cmd)
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	removed, err := store.UnwatchTask(taskID, watcher)
+	if err != nil {
+		return fmt.Errorf("failed to unwatch task: %w", err)
+	}
+	if !removed {
+		return fmt.Errorf("%s is not watching task %s", watcher, taskID)
+	}
+
+	fmt.Printf("✅ %s stopped watching task %s\n", watcher, taskID)
+	return nil
+}
+
+func runTasksWatchers(cmd *cobra.Command, args []string) error {
+	taskID := ""
+	if len(args) > 0 {
+		taskID = args[0]
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	watches, err := store.ListTaskWatches(taskID)
+	if err != nil {
+		return fmt.Errorf("failed to list watches: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		if watches == nil {
+			watches = []*storage.TaskWatch{}
+		}
+		data, err := json.MarshalIndent(watches, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(watches) == 0 {
+		fmt.Println("No watches found.")
+		return nil
+	}
+
+	fmt.Printf("%-36s %-20s %-12s\n", "Task", "Watcher", "Channel")
+	for _, watch := range watches {
+		channel := watch.Channel
+		if channel == "" {
+			channel = "(default)"
+		}
+		fmt.Printf("%-36s %-20s %-12s\n", watch.TaskID, watch.Watcher, channel)
+	}
+	return nil
+}
+
+// customFieldFilters parses the --field name=value flags
+func customFieldFilters(cmd *cobra.Command) (map[string]interface{}, error) {
+	flags, _ := cmd.Flags().GetStringArray("field")
+	if len(flags) == 0 {
+		return nil, nil
+	}
+
+	filters := make(map[string]interface{}, len(flags))
+	for _, flag := range flags {
+		name, text, ok := strings.Cut(flag, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --field %q: use name=value", flag)
+		}
+		value, err := globalConfig.CustomFields.Value(name, text)
+		if err != nil {
+			return nil, err
+		}
+		filters[name] = value
+	}
+	return filters, nil
+}
+
+func runTasksSetField(cmd *cobra.Command, args []string) error {
+	taskID, _ := cmd.Flags().GetString("id")
+	name, _ := cmd.Flags().GetString("name")
+	unset, _ := cmd.Flags().GetBool("unset")
+	if !unset && !cmd.Flags().Changed("value") {
+		return fmt.Errorf("--value or --unset is required")
+	}
+
+	var value interface{}
+	if !unset {
+		value, _ = cmd.Flags().GetString("value")
+	}
+	value, err := globalConfig.CustomFields.Value(name, value)
+	if err != nil {
+		return err
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("tasks set-field")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	task, err := store.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	task.SetCustomField(name, value)
+	if err := store.UpdateTask(task); err != nil {
+		return err
+	}
+
+	if value == nil {
+		fmt.Printf("✅ Cleared %s on task %s\n", name, taskID)
+	} else {
+		fmt.Printf("✅ Set %s = %v on task %s\n", name, value, taskID)
+	}
+	return nil
+}
+
+func runTasksHistory(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if _, err := store.GetTask(taskID); err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	revisions, err := store.ListTaskRevisions(taskID)
+	if err != nil {
+		return fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		if revisions == nil {
+			revisions = []*storage.TaskRevision{}
+		}
+		data, err := json.MarshalIndent(revisions, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(revisions) == 0 {
+		fmt.Println("No revisions recorded; the title and description never changed.")
+		return nil
+	}
+
+	showDiff, _ := cmd.Flags().GetBool("diff")
+	for i, rev := range revisions {
+		actor := rev.Actor
+		if actor == "" {
+			actor = "(original)"
+		}
+		fmt.Printf("Revision %d  %s  %s", rev.Revision, rev.CreatedAt.Format("2006-01-02 15:04"), actor)
+		if rev.RevertedFrom > 0 {
+			fmt.Printf("  (reverted to %d)", rev.RevertedFrom)
+		}
+		fmt.Println()
+
+		if !showDiff || i == 0 {
+			fmt.Printf("  Title: %s\n", rev.Title)
+			continue
+		}
+		prev := revisions[i-1]
+		if prev.Title != rev.Title {
+			fmt.Printf("  Title: %s -> %s\n", prev.Title, rev.Title)
+		}
+		if prev.Description != rev.Description {
+			fmt.Print(indentLines(storage.DiffText(prev.Description, rev.Description), "    "))
+		}
+	}
+	return nil
+}
+
+// indentLines prefixes every line of text with indent
+func indentLines(text, indent string) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = indent + line
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func runTasksRollback(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	actor, _ := cmd.Flags().GetString("actor")
+	reason, _ := cmd.Flags().GetString("reason")
+	dryRun := globalConfig.Development.DryRunDefault
+
+	if !dryRun {
+		workspaceLock, err := acquireWorkspaceLock("tasks rollback")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+
+	if _, err := store.GetTask(taskID); err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	var rollback *storage.Rollback
+	if dryRun {
+		rollback, err = store.PlanRollback(taskID)
+	} else {
+		rollback, err = store.RollbackTask(taskID, actor, reason)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to roll back task: %w", err)
+	}
+
+	undone := rollback.Undone
+	if dryRun {
+		fmt.Printf("Dry run: would roll task %s back from %s to %s\n", taskID, rollback.From, rollback.To)
+	} else {
+		fmt.Printf("↩️  Rolled task %s back from %s to %s\n", taskID, rollback.From, rollback.To)
+	}
+	fmt.Printf("  Undoing: cycle %s by %s at %s\n", undone.CycleID, undone.Actor, undone.CreatedAt.Format(time.RFC3339))
+	for _, restore := range rollback.Artifacts {
+		if dryRun {
+			fmt.Printf("  Artifact %s: v%d would get the content of v%d back\n", restore.Name, restore.FromVersion, restore.ToVersion)
+		} else {
+			fmt.Printf("  Artifact %s: v%d has the content of v%d back, as v%d\n", restore.Name, restore.FromVersion, restore.ToVersion, restore.Version)
+		}
+	}
+	for _, name := range rollback.Kept {
+		fmt.Printf("  Artifact %s: kept, it has no version from before the transition\n", name)
+	}
+	return nil
+}
+
+func runTasksRevert(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	revision, err := strconv.Atoi(args[1])
+	if err != nil || revision < 1 {
+		return fmt.Errorf("invalid revision %q: must be a positive number", args[1])
+	}
+	actor, _ := cmd.Flags().GetString("actor")
+
+	workspaceLock, err := acquireWorkspaceLock("tasks revert")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if _, err := store.GetTask(taskID); err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	task, err := store.RevertTask(taskID, revision, actor)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Reverted task %s to revision %d: %s\n", taskID, revision, task.Title)
+	return nil
+}
+
+func runTasksReview(cmd *cobra.Command, args []string) error {
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	if threshold < 0 {
+		threshold = globalConfig.Completion.LowConfidenceThreshold
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	assessments, err := store.ListLowConfidenceAssessments(threshold)
+	if err != nil {
+		return fmt.Errorf("failed to list assessments: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		if assessments == nil {
+			assessments = []*storage.TaskAssessment{}
+		}
+		data, err := json.MarshalIndent(assessments, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(assessments) == 0 {
+		fmt.Printf("No transitions below confidence %.2f.\n", threshold)
+		return nil
+	}
+
+	fmt.Printf("Found %d transitions below confidence %.2f:\n\n", len(assessments), threshold)
+	for _, assessment := range assessments {
+		title := ""
+		if task, err := store.GetTask(assessment.TaskID); err == nil {
+			title = task.Title
+		}
+		fmt.Printf("⚠️  %s  %s\n", assessment.TaskID, title)
+		fmt.Printf("  %s → %s by %s, confidence %.2f (%s)\n", assessment.PrevState, assessment.NextState,
+			assessment.Actor, *assessment.Confidence, assessment.CreatedAt.Format("2006-01-02 15:04"))
+		for _, question := range assessment.OpenQuestions {
+			fmt.Printf("  ? %s\n", question)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func runTasksExport(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown format %q: use csv or json", format)
+	}
+	milestone, _ := cmd.Flags().GetString("milestone")
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	filters := storage.TaskFilters{}
+	if state, _ := cmd.Flags().GetString("state"); state != "" {
+		normalizedState := storage.NormalizeState(state)
+		filters.State = &normalizedState
+	}
+	if filters.CustomFields, err = customFieldFilters(cmd); err != nil {
+		return err
+	}
+	tasks, err := store.ListTasks(filters)
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if milestone != "" {
+		var inMilestone []*storage.Task
+		for _, task := range tasks {
+			if task.Milestone() == milestone {
+				inMilestone = append(inMilestone, task)
+			}
+		}
+		tasks = inMilestone
+	}
+
+	out := os.Stdout
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if format == "json" {
+		if tasks == nil {
+			tasks = []*storage.Task{}
+		}
+		data, err := json.MarshalIndent(tasks, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	return taskcsv.Export(out, tasks, globalConfig.CustomFields)
+}
+
+func runTasksImport(cmd *cobra.Command, args []string) error {
+	dryRun := globalConfig.Development.DryRunDefault
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", args[0], err)
+	}
+	defer file.Close()
+
+	if !dryRun {
+		workspaceLock, err := acquireWorkspaceLock("tasks import")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	existing, err := store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	plan := taskcsv.Plan
+	if strings.EqualFold(filepath.Ext(args[0]), ".json") {
+		plan = taskcsv.PlanJSON
+	}
+	changes, err := plan(file, existing, globalConfig.CustomFields)
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", args[0], err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput && dryRun {
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	created, updated := 0, 0
+	for _, change := range changes {
+		switch {
+		case change.Create:
+			created++
+			fmt.Printf("  + line %d: create %q (%s, priority %d)\n", change.Line, change.Task.Title, change.Task.State, change.Task.Priority)
+		case len(change.Changed) > 0:
+			updated++
+			fmt.Printf("  ~ line %d: update %s %q: %s\n", change.Line, artifactfs.ShortID(change.Task.ID), change.Task.Title, strings.Join(change.Changed, ", "))
+		}
+	}
+	unchanged := len(changes) - created - updated
+
+	if dryRun {
+		fmt.Printf("Dry run: would create %d tasks and update %d (%d unchanged)\n", created, updated, unchanged)
+		return nil
+	}
+
+	if err := taskcsv.Apply(store, changes); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Created %d tasks and updated %d (%d unchanged)\n", created, updated, unchanged)
+	return nil
+}
+
+func runTasksArchive(cmd *cobra.Command, args []string) error {
+	beforeStr, _ := cmd.Flags().GetString("before")
+	before, err := time.ParseInLocation("2006-01-02", beforeStr, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid --before %q: must be a date as YYYY-MM-DD", beforeStr)
+	}
+	stateStr, _ := cmd.Flags().GetString("state")
+	state := storage.NormalizeState(stateStr)
+	if !statemachine.IsTerminalState(state) {
+		return fmt.Errorf("invalid --state %q: only tasks in a finished state can be archived", stateStr)
+	}
+	dryRun := globalConfig.Development.DryRunDefault
+
+	if !dryRun {
+		workspaceLock, err := acquireWorkspaceLock("tasks archive")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	tasks, err := store.ArchiveTasks(state, before, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to archive tasks: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		if tasks == nil {
+			tasks = []*storage.Task{}
+		}
+		data, err := json.MarshalIndent(tasks, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, task := range tasks {
+		fmt.Printf("  %s %s (updated %s)\n", task.ID[:8], task.Title, task.UpdatedAt.Format("2006-01-02"))
+	}
+	if dryRun {
+		fmt.Printf("Dry run: would archive %d %s tasks updated before %s\n", len(tasks), state, beforeStr)
+		return nil
+	}
+	fmt.Printf("✅ Archived %d %s tasks updated before %s\n", len(tasks), state, beforeStr)
+	return nil
+}
+
+func runTasksUnarchive(cmd *cobra.Command, args []string) error {
+	workspaceLock, err := acquireWorkspaceLock("tasks unarchive")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	for _, taskID := range args {
+		if _, err := store.GetTask(taskID); err != nil {
+			return fmt.Errorf("task not found: %s", taskID)
+		}
+	}
+
+	n, err := store.UnarchiveTasks(args)
+	if err != nil {
+		return fmt.Errorf("failed to unarchive tasks: %w", err)
+	}
+	fmt.Printf("✅ Unarchived %d of %d tasks\n", n, len(args))
+	return nil
+}
+
+func runTasksDelete(cmd *cobra.Command, args []string) error {
+	workspaceLock, err := acquireWorkspaceLock("tasks delete")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	for _, taskID := range args {
+		if err := store.DeleteTask(taskID); err != nil {
+			if errors.Is(err, storage.ErrTaskNotFound) {
+				return fmt.Errorf("task not found: %s", taskID)
+			}
+			return fmt.Errorf("failed to delete task: %w", err)
+		}
+		fmt.Printf("🗑️  Moved task %s to the trash\n", taskID)
+	}
+	fmt.Println("Restore with: baton tasks restore <task-id>")
+	return nil
+}
+
+func runTasksRestore(cmd *cobra.Command, args []string) error {
+	workspaceLock, err := acquireWorkspaceLock("tasks restore")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	for _, taskID := range args {
+		if err := store.RestoreTask(taskID); err != nil {
+			if errors.Is(err, storage.ErrTaskNotFound) {
+				return fmt.Errorf("task not in the trash: %s", taskID)
+			}
+			return fmt.Errorf("failed to restore task: %w", err)
+		}
+		fmt.Printf("✅ Restored task %s\n", taskID)
+	}
+	return nil
+}
+
+func runTasksHold(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	reason, _ := cmd.Flags().GetString("reason")
+	holdState := storage.Blocked
+	if paused, _ := cmd.Flags().GetBool("paused"); paused {
+		holdState = storage.Paused
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("tasks hold")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+
+	validator := statemachine.NewTransitionValidator(store)
+	validator.SetArtifactSchemas(globalConfig.ArtifactSchemas)
+	validator.SetTransitionRules(globalConfig.TransitionRules)
+	validator.SetHooks(hooks.NewRunner(globalConfig.Hooks, globalConfig.Workspace))
+	if err := validator.ValidateAndTransition(taskID, holdState, reason); err != nil {
+		return fmt.Errorf("failed to put task on hold: %w", err)
+	}
+
+	if reason != "" {
+		author := os.Getenv("USER")
+		if author == "" {
+			author = "cli"
+		}
+		if err := store.AddTaskNote(&storage.TaskNote{TaskID: taskID, Author: author, Body: string(holdState) + ": " + reason}); err != nil {
+			return fmt.Errorf("failed to record the reason: %w", err)
+		}
+	}
+
+	task, err := store.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	fmt.Printf("⏸️  Task %s is %s; resume it to %s with: baton tasks resume %s\n", taskID, holdState, task.HeldFrom, taskID)
+	return nil
+}
+
+func runTasksResume(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	note, _ := cmd.Flags().GetString("note")
+
+	workspaceLock, err := acquireWorkspaceLock("tasks resume")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+
+	if _, err := store.GetTask(taskID); err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+	state, err := store.ResumeTask(taskID, note)
+	if err != nil {
+		return fmt.Errorf("failed to resume task: %w", err)
+	}
+	fmt.Printf("▶️  Resumed task %s to state: %s\n", taskID, state)
+	return nil
+}
+
+func runTasksCancel(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	reason, _ := cmd.Flags().GetString("reason")
+
+	workspaceLock, err := acquireWorkspaceLock("tasks cancel")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+	defer notify.Attach(store, globalConfig).Close()
+
+	validator := statemachine.NewTransitionValidator(store)
+	validator.SetArtifactSchemas(globalConfig.ArtifactSchemas)
+	validator.SetTransitionRules(globalConfig.TransitionRules)
+	validator.SetHooks(hooks.NewRunner(globalConfig.Hooks, globalConfig.Workspace))
+	if err := validator.ValidateAndTransition(taskID, storage.Cancelled, reason); err != nil {
+		return fmt.Errorf("failed to cancel task: %w", err)
+	}
+
+	fmt.Printf("🚫 Cancelled task %s: %s\n", taskID, reason)
+	return nil
+}
+
+func runTasksTime(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if _, err := store.GetTask(taskID); err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	timeInState, err := store.GetTimeInState(taskID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to get state history: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(timeInState, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, span := range timeInState.Spans {
+		current := ""
+		if span.LeftAt == nil {
+			current = "  (current)"
+		}
+		fmt.Printf("%s  %-26s %s%s\n", span.EnteredAt.Format("2006-01-02 15:04"), span.State,
+			formatSeconds(span.Seconds), current)
+	}
+
+	states := make([]string, 0, len(timeInState.Totals))
+	for state := range timeInState.Totals {
+		states = append(states, string(state))
+	}
+	sort.Strings(states)
+	fmt.Println("\nTotals:")
+	for _, state := range states {
+		fmt.Printf("  %-26s %s\n", state, formatSeconds(timeInState.Totals[storage.State(state)]))
+	}
+	return nil
+}
+
+func runTasksSimulate(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	toStr, _ := cmd.Flags().GetString("to")
+	overrideWIP, _ := cmd.Flags().GetBool("override-wip")
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	validator := statemachine.NewTransitionValidator(store)
+	validator.SetArtifactSchemas(globalConfig.ArtifactSchemas)
+	validator.SetTransitionRules(globalConfig.TransitionRules)
+	validator.SetHooks(hooks.NewRunner(globalConfig.Hooks, globalConfig.Workspace))
+	if !overrideWIP {
+		validator.SetWIPLimits(globalConfig.Selection.WIPLimits)
+	}
+
+	req, err := validator.GetTransitionRequirements(taskID, storage.NormalizeState(toStr))
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		data, err := json.MarshalIndent(req, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if req.IsValid {
+		fmt.Printf("✅ %s -> %s would be allowed\n", req.From, req.To)
+	} else {
+		fmt.Printf("❌ %s -> %s would be refused: %s\n", req.From, req.To, req.Reason)
+	}
+	printSimulateList("Blocked by", req.DependenciesBlocked)
+	printSimulateList("Missing handovers", req.MissingHandovers)
+	printSimulateList("Schema violations", req.SchemaViolations)
+	printSimulateList("Missing citations", req.MissingCitations)
+	printSimulateList("Transition rules not met", req.RuleViolations)
+	if req.WIPLimit != "" {
+		fmt.Printf("  %s\n", req.WIPLimit)
+	}
+	if req.ReasonRequired {
+		fmt.Println("  A reason note is required")
+	}
+	if len(req.Hooks) > 0 {
+		fmt.Println("  Hooks that would run:")
+		for _, hook := range req.Hooks {
+			blocking := ""
+			if hook.Blocking {
+				blocking = ", blocking"
+			}
+			fmt.Printf("    - %s (%s%s)\n", hook.Hook, hook.Kind, blocking)
+		}
+	}
+	return nil
+}
+
+// printSimulateList prints one kind of problem found by tasks simulate
+func printSimulateList(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Printf("  %s:\n", title)
+	for _, item := range items {
+		fmt.Printf("    - %s\n", item)
+	}
+}
+
+// formatSeconds renders a duration in seconds to the minute
+func formatSeconds(seconds float64) string {
+	return (time.Duration(seconds) * time.Second).Round(time.Minute).String()
+}
+
+func runTasksTrash(cmd *cobra.Command, args []string) error {
+	purge, _ := cmd.Flags().GetBool("purge")
+	if len(args) > 0 && !purge {
+		return fmt.Errorf("task IDs are only given with --purge")
+	}
+	dryRun := globalConfig.Development.DryRunDefault
+
+	if purge && !dryRun {
+		workspaceLock, err := acquireWorkspaceLock("tasks trash")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	tasks, err := store.ListDeletedTasks()
+	if err != nil {
+		return fmt.Errorf("failed to list deleted tasks: %w", err)
+	}
+	if len(args) > 0 {
+		deleted := make(map[string]*storage.Task, len(tasks))
+		for _, task := range tasks {
+			deleted[task.ID] = task
+		}
+		tasks = tasks[:0]
+		for _, taskID := range args {
+			task, ok := deleted[taskID]
+			if !ok {
+				return fmt.Errorf("task not in the trash: %s", taskID)
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput && (!purge || dryRun) {
+		if tasks == nil {
+			tasks = []*storage.Task{}
+		}
+		data, err := json.MarshalIndent(tasks, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(tasks) == 0 {
+		fmt.Println("The trash is empty")
+		return nil
+	}
+	for _, task := range tasks {
+		fmt.Printf("  %s %s (deleted %s)\n", task.ID[:8], task.Title, task.DeletedAt.Format("2006-01-02 15:04"))
+	}
+	if !purge {
+		fmt.Printf("%d tasks in the trash\n", len(tasks))
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("Dry run: would purge %d tasks\n", len(tasks))
+		return nil
+	}
+
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	n, err := store.PurgeTasks(ids)
+	if err != nil {
+		return fmt.Errorf("failed to purge tasks: %w", err)
+	}
+	fmt.Printf("✅ Purged %d tasks\n", n)
+	return nil
+}
+
+func runTasksSetParent(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	unset, _ := cmd.Flags().GetBool("unset")
+	parentID := ""
+	switch {
+	case len(args) == 2 && unset:
+		return fmt.Errorf("give either a parent task ID or --unset, not both")
+	case len(args) == 2:
+		parentID = args[1]
+	case !unset:
+		return fmt.Errorf("a parent task ID or --unset is required")
+	}
+
+	workspaceLock, err := acquireWorkspaceLock("tasks set-parent")
+	if err != nil {
+		return err
+	}
+	defer workspaceLock.Release()
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if err := store.SetTaskParent(taskID, parentID); err != nil {
+		return fmt.Errorf("failed to set the parent of task %s: %w", taskID, err)
+	}
+
+	if parentID == "" {
+		fmt.Printf("✅ Task %s is now a top-level task\n", taskID)
+		return nil
+	}
+	fmt.Printf("✅ Task %s is now grouped under %s\n", taskID, parentID)
+	return nil
+}
+
+func runTasksNote(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+	body := strings.Join(args[1:], " ")
+	author, _ := cmd.Flags().GetString("author")
+	if author == "" {
+		author = os.Getenv("USER")
+	}
+	if author == "" {
+		author = "cli"
+	}
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if _, err := store.GetTask(taskID); err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	note := &storage.TaskNote{TaskID: taskID, Author: author, Body: body}
+	if err := store.AddTaskNote(note); err != nil {
+		return fmt.Errorf("failed to add note: %w", err)
+	}
+
+	fmt.Printf("✅ Added note %s to task %s\n", note.ID[:8], taskID)
+	return nil
+}
+
+func runTasksNotes(cmd *cobra.Command, args []string) error {
+	taskID := args[0]
+
+	// Initialize database
+	store, err := openStore(globalConfig)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer store.Close()
+
+	if _, err := store.GetTask(taskID); err != nil {
+		return fmt.Errorf("task not found: %s", taskID)
+	}
+
+	notes, err := store.ListTaskNotes(taskID)
+	if err != nil {
+		return fmt.Errorf("failed to list notes: %w", err)
+	}
+
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		if notes == nil {
+			notes = []*storage.TaskNote{}
+		}
+		data, err := json.MarshalIndent(notes, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(notes) == 0 {
+		fmt.Printf("No notes on task %s\n", taskID)
+		return nil
+	}
+	for _, note := range notes {
+		fmt.Printf("%s  %s\n", note.CreatedAt.Format("2006-01-02 15:04"), note.Author)
+		fmt.Println(indentLines(note.Body, "  "))
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/config"
+	"baton/internal/cycle"
+	"baton/internal/plan"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+	"baton/internal/worktree"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the workspace configuration",
+	Long: `Validate checks the configuration for problems that would only surface mid-run,
+such as workflow states that no agent handles or agents listing unknown states.
+
+Warnings are reported but do not fail; errors exit non-zero.`,
+	RunE: runValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().Bool("json", false, "output in JSON format")
+}
+
+// validationReport lists the problems found in the configuration
+type validationReport struct {
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	report := validateConfig(globalConfig)
+
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, msg := range report.Errors {
+			fmt.Printf("❌ %s\n", msg)
+		}
+		for _, msg := range report.Warnings {
+			fmt.Printf("⚠️  %s\n", msg)
+		}
+		if len(report.Errors) == 0 && len(report.Warnings) == 0 {
+			fmt.Println("✅ Configuration is valid")
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("configuration has %d errors", len(report.Errors))
+	}
+	return nil
+}
+
+// validateConfig checks agent coverage of the workflow states, the agents'
+// prompt templates, the states transition hooks and WIP limits name and that
+// the plan file is readable
+func validateConfig(cfg *config.Config) *validationReport {
+	report := &validationReport{Errors: []string{}, Warnings: []string{}}
+
+	// Only states a task can leave need an agent, and held tasks wait for a resume
+	var workStates []string
+	known := make(map[string]bool)
+	for _, state := range statemachine.GetAllStates() {
+		known[string(state)] = true
+		if !statemachine.IsTerminalState(state) && !storage.IsHeld(state) {
+			workStates = append(workStates, string(state))
+		}
+	}
+	sort.Strings(workStates)
+
+	agentIDs := make([]string, 0, len(cfg.Agents))
+	for agentID := range cfg.Agents {
+		agentIDs = append(agentIDs, agentID)
+	}
+	sort.Strings(agentIDs)
+
+	for _, agentID := range agentIDs {
+		agent := cfg.Agents[agentID]
+		for _, state := range agent.AllowedStates {
+			if !known[state] {
+				report.Errors = append(report.Errors, fmt.Sprintf("agent %q lists unknown state %q", agentID, state))
+			}
+		}
+
+		// A template that doesn't parse fails every cycle of the agent; a missing
+		// one only matters once the prompts directory is in use
+		if path := cfg.PromptTemplatePath(&agent); path != "" {
+			if tmpl, err := cycle.LoadPromptTemplate(path); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("agent %q: %v", agentID, err))
+			} else if tmpl == nil && dirExists(filepath.Dir(path)) {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("agent %q prompt template %s does not exist; the built-in prompt is used", agentID, path))
+			}
+		}
+	}
+
+	for i, hook := range cfg.Hooks {
+		for _, state := range []string{hook.From, hook.To} {
+			if state != "*" && !known[state] {
+				report.Errors = append(report.Errors, fmt.Sprintf("hooks[%d] (%s) lists unknown state %q", i, hook.Label(), state))
+			}
+		}
+	}
+
+	for _, sched := range cfg.Schedules {
+		if sched.UntilState != "" && !known[sched.UntilState] {
+			report.Errors = append(report.Errors, fmt.Sprintf("schedules.%s lists unknown until_state %q", sched.Name, sched.UntilState))
+		}
+	}
+
+	for i, rule := range cfg.TransitionRules {
+		for _, state := range []string{rule.From, rule.To} {
+			if state != "*" && !known[state] {
+				report.Errors = append(report.Errors, fmt.Sprintf("transition_rules[%d] (%s) lists unknown state %q", i, rule.Condition(), state))
+			}
+		}
+	}
+
+	limited := make([]string, 0, len(cfg.Selection.WIPLimits))
+	for state := range cfg.Selection.WIPLimits {
+		limited = append(limited, state)
+	}
+	sort.Strings(limited)
+	for _, state := range limited {
+		switch {
+		case !known[state]:
+			report.Errors = append(report.Errors, fmt.Sprintf("selection.wip_limits lists unknown state %q", state))
+		case statemachine.IsTerminalState(storage.State(state)) || storage.IsHeld(storage.State(state)):
+			report.Warnings = append(report.Warnings, fmt.Sprintf("selection.wip_limits.%s has no effect: tasks are not worked in %s", state, state))
+		}
+	}
+
+	if cfg.DefaultAgent != "" {
+		if _, exists := cfg.Agents[cfg.DefaultAgent]; !exists {
+			report.Errors = append(report.Errors, fmt.Sprintf("default_agent %q is not a configured agent", cfg.DefaultAgent))
+		}
+	}
+
+	uncovered := cfg.UncoveredStates(workStates)
+	switch {
+	case len(uncovered) == 0:
+	case cfg.DefaultAgent != "":
+		report.Warnings = append(report.Warnings, fmt.Sprintf("no agent lists states %v; default agent %q will handle them",
+			uncovered, cfg.DefaultAgent))
+	case cfg.Selection.SkipUnassignedStates:
+		report.Warnings = append(report.Warnings, fmt.Sprintf("no agent handles states %v; tasks in them will be skipped by selection",
+			uncovered))
+	default:
+		report.Warnings = append(report.Warnings, fmt.Sprintf("no agent handles states %v; a cycle selecting such a task will fail "+
+			"(set default_agent or selection.skip_unassigned_states)", uncovered))
+	}
+
+	if cfg.Isolation.Mode == "worktree" {
+		if err := worktree.Check(cfg.Workspace); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("isolation.mode worktree: %v; every cycle will fail", err))
+		}
+	}
+
+	// A plan agents can't read pauses cycles or leaves agents without it
+	if cfg.PlanFile != "" {
+		if err := plan.Check(cfg.PlanFile); err != nil {
+			if cfg.PlanUnavailable == "pause" {
+				report.Errors = append(report.Errors, fmt.Sprintf("%v; cycles will pause until it is fixed", err))
+			} else {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%v; agents will work without the plan", err))
+			}
+		}
+	}
+
+	return report
+}
+
+// dirExists reports whether path is an existing directory
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
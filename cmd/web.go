@@ -9,9 +9,8 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"baton/internal/config"
 	"baton/internal/llm"
-	"baton/internal/storage"
+	"baton/internal/notify"
 	"baton/internal/web"
 )
 
@@ -28,7 +27,11 @@ The web server provides:
 - Responsive design for desktop and mobile
 
 The server will start on the specified port (default: 3001) and serve both
-the API endpoints and the static frontend files.`,
+the API endpoints and the static frontend files.
+
+With --read-only (or web.read_only in config) the server is a dashboard for
+observers: every mutating endpoint is rejected, no LLM calls are made and no
+workspace lock is taken, while task views and WebSocket updates keep working.`,
 	RunE: runWebServer,
 }
 
@@ -36,6 +39,7 @@ var (
 	webPort     int
 	webDevMode  bool
 	webStaticDir string
+	webReadOnly bool
 )
 
 func init() {
@@ -44,30 +48,44 @@ func init() {
 	webCmd.Flags().IntVarP(&webPort, "port", "p", 3001, "Port to run the web server on")
 	webCmd.Flags().BoolVar(&webDevMode, "dev", false, "Enable development mode with CORS and verbose logging")
 	webCmd.Flags().StringVar(&webStaticDir, "static-dir", "./web/dist", "Directory containing static web files")
+	webCmd.Flags().BoolVar(&webReadOnly, "read-only", false, "Serve a read-only dashboard with all mutating endpoints disabled")
 }
 
 func runWebServer(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	cfg := globalConfig
+
+	readOnly := webReadOnly || cfg.Web.ReadOnly
+
+	// A read-only server never writes, so it can run alongside another writer
+	if !readOnly {
+		workspaceLock, err := acquireWorkspaceLock("web")
+		if err != nil {
+			return err
+		}
+		defer workspaceLock.Release()
 	}
 
 	// Initialize database
-	store, err := storage.NewStore(cfg.Database)
+	store, err := openStore(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create store: %w", err)
 	}
 	defer store.Close()
+	defer notify.Attach(store, cfg).Close()
+	store.SetBlobThreshold(cfg.Artifacts.BlobThresholdBytes)
 
-	// Initialize LLM client
-	llmClient, err := llm.NewClient(cfg.LLM)
-	if err != nil {
-		return fmt.Errorf("failed to create LLM client: %w", err)
+	// Initialize LLM client; observers must not be able to trigger LLM spend
+	var llmClient llm.Client
+	if !readOnly {
+		llmClient, err = llm.NewClient(cfg.LLM)
+		if err != nil {
+			return fmt.Errorf("failed to create LLM client: %w", err)
+		}
 	}
 
 	// Create web server
 	webServer := web.NewServer(store, cfg, llmClient)
+	webServer.SetReadOnly(readOnly)
 
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -76,7 +94,10 @@ func runWebServer(cmd *cobra.Command, args []string) error {
 	// Start server in goroutine
 	errChan := make(chan error, 1)
 	go func() {
-		log.Printf("Starting web UI server on port %d", webPort)
+		log.Printf("Starting web UI server for project %s on port %d", store.Project(), webPort)
+		if readOnly {
+			log.Println("Read-only mode enabled - mutating endpoints are disabled")
+		}
 		if webDevMode {
 			log.Println("Development mode enabled - CORS allowed from localhost:3000")
 		}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"baton/internal/workflow"
+)
+
+// workflowCmd represents the workflow command
+var workflowCmd = &cobra.Command{
+	Use:   "workflow",
+	Short: "Share workflow definitions between projects",
+	Long: `Workflow commands export the active state machine, handover requirements,
+artifact schemas and agent permissions as one YAML file, and import such a file
+into another project.`,
+}
+
+// workflowExportCmd represents the workflow export command
+var workflowExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the active workflow",
+	Long:  `Export writes the active workflow definition as YAML.`,
+	RunE:  runWorkflowExport,
+}
+
+// workflowImportCmd represents the workflow import command
+var workflowImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a workflow definition",
+	Long: `Import validates a workflow definition, shows how it differs from the active
+workflow and writes its agent permissions and artifact schemas into the config file.
+
+States, transitions and handovers are built in, so definitions that change them are
+shown in the diff but rejected.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorkflowImport,
+}
+
+func init() {
+	rootCmd.AddCommand(workflowCmd)
+	workflowCmd.AddCommand(workflowExportCmd)
+	workflowCmd.AddCommand(workflowImportCmd)
+
+	workflowExportCmd.Flags().StringP("output", "o", "", "write the definition to a file instead of stdout")
+	workflowImportCmd.Flags().Bool("dry-run", false, "validate and show the diff without changing the config file")
+}
+
+func runWorkflowExport(cmd *cobra.Command, args []string) error {
+	data, err := workflow.Active(globalConfig).Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to render workflow: %w", err)
+	}
+
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write workflow: %w", err)
+		}
+		fmt.Printf("✅ Workflow exported to %s\n", output)
+		return nil
+	}
+
+	fmt.Print(string(data))
+	return nil
+}
+
+func runWorkflowImport(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read workflow: %w", err)
+	}
+
+	incoming, err := workflow.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	if problems := incoming.Validate(); len(problems) > 0 {
+		fmt.Println("❌ Invalid workflow:")
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		return fmt.Errorf("workflow %s has %d problems", args[0], len(problems))
+	}
+
+	active := workflow.Active(globalConfig)
+	changes := active.Diff(incoming)
+	if len(changes) == 0 {
+		fmt.Println("✅ Workflow matches the active workflow; nothing to import")
+		return nil
+	}
+
+	fmt.Println("📋 Changes against the active workflow:")
+	for _, change := range changes {
+		fmt.Printf("  %s\n", change)
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return nil
+	}
+
+	if globalConfig.ConfigFile == "" {
+		return fmt.Errorf("no config file to import into; run baton init or pass --config")
+	}
+	if err := workflow.Apply(globalConfig.ConfigFile, active, incoming); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Workflow imported into %s\n", globalConfig.ConfigFile)
+	return nil
+}
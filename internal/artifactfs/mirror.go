@@ -0,0 +1,126 @@
+package artifactfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+// DefaultDir is where artifacts are mirrored when artifacts.dir is not set
+const DefaultDir = "claudedocs/tasks"
+
+// shortIDLength is how many characters of a task ID name its directory
+const shortIDLength = 8
+
+// unsafeFileChars matches characters not allowed in artifact file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Mirror writes the latest version of each artifact to a file under
+// <dir>/<short-task-id>/, so handovers can be read in editors and reviewed in PRs
+type Mirror struct {
+	store *storage.Store
+	root  string
+}
+
+// NewMirror creates a mirror rooted at dir
+func NewMirror(store *storage.Store, dir string) *Mirror {
+	return &Mirror{store: store, root: dir}
+}
+
+// NewMirrorFromConfig creates a mirror rooted at artifacts.dir, resolved against the workspace
+func NewMirrorFromConfig(store *storage.Store, cfg *config.Config) *Mirror {
+	dir := cfg.Artifacts.Dir
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if !filepath.IsAbs(dir) && cfg.Workspace != "" {
+		dir = filepath.Join(cfg.Workspace, dir)
+	}
+	return NewMirror(store, dir)
+}
+
+// ShortID returns the directory name used for a task
+func ShortID(taskID string) string {
+	if len(taskID) > shortIDLength {
+		return taskID[:shortIDLength]
+	}
+	return taskID
+}
+
+// Path returns the file an artifact is mirrored to
+func (m *Mirror) Path(artifact *storage.Artifact) string {
+	name := unsafeFileChars.ReplaceAllString(artifact.Name, "_")
+	ext := ".md"
+	trimmed := strings.TrimSpace(artifact.Content)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		ext = ".json"
+	}
+	return filepath.Join(m.root, ShortID(artifact.TaskID), name+ext)
+}
+
+// Write mirrors one artifact version, replacing the file atomically
+func (m *Mirror) Write(artifact *storage.Artifact) (string, error) {
+	path := m.Path(artifact)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(artifact.Content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write artifact file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write artifact file: %w", err)
+	}
+	return path, nil
+}
+
+// MaterializeTask mirrors the latest version of each of the task's artifacts
+func (m *Mirror) MaterializeTask(taskID string) ([]string, error) {
+	artifacts, err := m.store.ListArtifacts(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts for task %s: %w", taskID, err)
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, artifact := range artifacts {
+		// ListArtifacts orders by name, version DESC so the first of each name is the latest
+		if seen[artifact.Name] {
+			continue
+		}
+		seen[artifact.Name] = true
+
+		path, err := m.Write(artifact)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// MaterializeAll mirrors the latest artifacts of every task
+func (m *Mirror) MaterializeAll() ([]string, error) {
+	tasks, err := m.store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	var paths []string
+	for _, task := range tasks {
+		taskPaths, err := m.MaterializeTask(task.ID)
+		paths = append(paths, taskPaths...)
+		if err != nil {
+			return paths, err
+		}
+	}
+	return paths, nil
+}
@@ -85,16 +85,21 @@ func (al *Logger) GenerateReport() (map[string]interface{}, error) {
 	stateCount := make(map[string]int)
 	completedCount := 0
 
+	// Cancelled tasks are left out of the completion rate, as archived ones are
+	countedTasks := 0
 	for _, task := range tasks {
 		stateCount[string(task.State)]++
 		if task.State == storage.Done {
 			completedCount++
 		}
+		if task.State != storage.Cancelled {
+			countedTasks++
+		}
 	}
 
 	report["by_state"] = stateCount
-	if len(tasks) > 0 {
-		report["completion_rate"] = float64(completedCount) / float64(len(tasks)) * 100.0
+	if countedTasks > 0 {
+		report["completion_rate"] = float64(completedCount) / float64(countedTasks) * 100.0
 	}
 
 	return report, nil
@@ -0,0 +1,402 @@
+// Package backup checkpoints a workspace into a single archive and restores it
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"baton/internal/storage"
+	"baton/pkg/version"
+)
+
+// FormatVersion is bumped whenever the archive layout changes incompatibly
+const FormatVersion = 1
+
+// manifestName is the archive entry listing every other entry with its checksum
+const manifestName = "manifest.json"
+
+// Archive entries live under these prefixes: the database, its archived
+// audit payloads and artifact blobs, and files by their path in the workspace
+const (
+	databaseEntry    = "database/baton.db"
+	auditArchiveDir  = "database/archive/"
+	blobDir          = "database/blobs/"
+	workspacePrefix  = "workspace/"
+	auditArchiveName = "archive"      // directory of archived audit payloads next to the database
+	blobDirName      = ".baton/blobs" // directory of artifact blobs, relative to the database's
+)
+
+// contextFiles are the workspace's context files and directories, relative to it
+var contextFiles = []string{"CLAUDE.md", "STYLE_GUIDE.md", ".claudeignore", ".claude"}
+
+// Manifest describes an archive's contents
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	BatonVersion  string    `json:"baton_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Files         []File    `json:"files"`
+}
+
+// File is one archive entry with its size and SHA-256
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Workspace names the files a backup covers and where a restore puts them
+type Workspace struct {
+	Dir         string // workspace directory
+	Database    string
+	DatabaseKey string // key of an encrypted database, "" for plaintext
+	PlanFile    string
+	ConfigFile  string // "" when no config file was read
+}
+
+// Result is what a backup or restore covered
+type Result struct {
+	Manifest *Manifest `json:"manifest"`
+	Skipped  []string  `json:"skipped,omitempty"` // files left out, with the reason
+}
+
+// Create writes an archive of the workspace to out: a snapshot of the
+// database taken with VACUUM INTO, its archived audit payloads, the plan
+// file, the config file and the context files. Files outside the workspace
+// directory are skipped and reported.
+func Create(store *storage.Store, ws Workspace, out io.Writer) (*Result, error) {
+	tmpDir, err := os.MkdirTemp("", "baton-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshot := filepath.Join(tmpDir, "baton.db")
+	if err := store.BackupTo(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	result := &Result{Manifest: &Manifest{FormatVersion: FormatVersion, BatonVersion: version.Version, CreatedAt: time.Now()}}
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	add := func(name, src string) error {
+		file, err := addFile(tw, name, src)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", src, err)
+		}
+		result.Manifest.Files = append(result.Manifest.Files, *file)
+		return nil
+	}
+
+	if err := add(databaseEntry, snapshot); err != nil {
+		return nil, err
+	}
+	err = walkFiles(filepath.Join(filepath.Dir(ws.Database), auditArchiveName), func(src, rel string) error {
+		return add(auditArchiveDir+rel, src)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = walkFiles(filepath.Join(filepath.Dir(ws.Database), filepath.FromSlash(blobDirName)), func(src, rel string) error {
+		return add(blobDir+rel, src)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var workspaceFiles []string
+	for _, file := range []string{ws.PlanFile, ws.ConfigFile} {
+		if file == "" {
+			continue
+		}
+		rel, err := relPath(ws.Dir, file)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: outside the workspace", file))
+			continue
+		}
+		workspaceFiles = append(workspaceFiles, rel)
+	}
+	workspaceFiles = append(workspaceFiles, contextFiles...)
+
+	for _, rel := range workspaceFiles {
+		err := walkFiles(filepath.Join(ws.Dir, rel), func(src, sub string) error {
+			name := path.Join(filepath.ToSlash(rel), sub)
+			return add(workspacePrefix+name, src)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, err := json.MarshalIndent(result.Manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	header := &tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(manifest)), ModTime: result.Manifest.CreatedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Verify checks an archive without restoring it: every entry must match
+// the manifest's checksums and the database must pass SQLite's integrity
+// check. key decrypts an encrypted database.
+func Verify(archive, key string) (*Manifest, error) {
+	tmpDir, err := os.MkdirTemp("", "baton-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	return extract(archive, tmpDir, key)
+}
+
+// Restore verifies an archive and then puts its files back: the database at
+// ws.Database, replacing it, and the other files at their workspace paths.
+// Nothing is written when verification fails.
+func Restore(archive string, ws Workspace) (*Manifest, error) {
+	tmpDir, err := os.MkdirTemp("", "baton-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	manifest, err := extract(archive, tmpDir, ws.DatabaseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range manifest.Files {
+		src := filepath.Join(tmpDir, filepath.FromSlash(file.Name))
+		var dst string
+		switch {
+		case file.Name == databaseEntry:
+			dst = ws.Database
+		case strings.HasPrefix(file.Name, auditArchiveDir):
+			dst = filepath.Join(filepath.Dir(ws.Database), auditArchiveName, filepath.FromSlash(strings.TrimPrefix(file.Name, auditArchiveDir)))
+		case strings.HasPrefix(file.Name, blobDir):
+			dst = filepath.Join(filepath.Dir(ws.Database), filepath.FromSlash(blobDirName), filepath.FromSlash(strings.TrimPrefix(file.Name, blobDir)))
+		default:
+			dst = filepath.Join(ws.Dir, filepath.FromSlash(strings.TrimPrefix(file.Name, workspacePrefix)))
+		}
+		if err := replaceFile(src, dst); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", dst, err)
+		}
+	}
+
+	// The old database's write-ahead log must not be replayed onto the restored one
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(ws.Database + suffix); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// extract unpacks an archive into dir and verifies it against its manifest
+func extract(archive, dir, key string) (*Manifest, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a baton backup: %w", archive, err)
+	}
+	tr := tar.NewReader(gz)
+
+	sums := make(map[string]File)
+	var manifest *Manifest
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if header.Name == manifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse manifest: %w", err)
+			}
+			continue
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		name := path.Clean(header.Name)
+		if header.Typeflag != tar.TypeReg || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("unexpected archive entry %q", header.Name)
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(out, hash), tr)
+		out.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		sums[name] = File{Name: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%s is not a baton backup: it has no %s", archive, manifestName)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d (expected %d)", manifest.FormatVersion, FormatVersion)
+	}
+	hasDatabase := false
+	for _, file := range manifest.Files {
+		hasDatabase = hasDatabase || file.Name == databaseEntry
+		got, ok := sums[file.Name]
+		if !ok {
+			return nil, fmt.Errorf("backup is missing %s", file.Name)
+		}
+		if got != file {
+			return nil, fmt.Errorf("backup is corrupt: %s does not match its checksum", file.Name)
+		}
+		delete(sums, file.Name)
+	}
+	for name := range sums {
+		return nil, fmt.Errorf("backup has %s, which its manifest does not list", name)
+	}
+
+	if !hasDatabase {
+		return nil, fmt.Errorf("backup is missing %s", databaseEntry)
+	}
+	if err := storage.CheckIntegrity(filepath.Join(dir, filepath.FromSlash(databaseEntry)), key); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// relPath is file's path relative to dir, either of which may be relative
+// to the working directory
+func relPath(dir, file string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absDir, absFile)
+}
+
+// addFile writes src to the archive as name, returning its manifest entry
+func addFile(tw *tar.Writer, name, src string) (*File, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	header := &tar.Header{Name: name, Mode: int64(info.Mode().Perm()), Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(header); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, hash), f); err != nil {
+		return nil, err
+	}
+	return &File{Name: name, Size: info.Size(), SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// walkFiles calls fn for root when it is a file, or for every regular file
+// under it, with its slash-separated path relative to root. A missing root is
+// not an error.
+func walkFiles(root string, fn func(src, rel string) error) error {
+	info, err := os.Stat(root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fn(root, "")
+	}
+
+	return filepath.WalkDir(root, func(src string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(root, src)
+		if err != nil {
+			return err
+		}
+		return fn(src, filepath.ToSlash(rel))
+	})
+}
+
+// replaceFile copies src over dst through a temporary file, so dst is never
+// left half-written
+func replaceFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".restoring"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
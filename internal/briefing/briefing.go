@@ -0,0 +1,294 @@
+package briefing
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"baton/internal/llm"
+	"baton/internal/storage"
+	"baton/internal/textutil"
+)
+
+// maxArtifactChars bounds how much of each artifact is included in the prompt
+const maxArtifactChars = 1500
+
+// maxAuditEntries bounds how much audit history is included in the prompt
+const maxAuditEntries = 10
+
+// Briefer assembles task context and asks the LLM for a status briefing
+type Briefer struct {
+	store     *storage.Store
+	llmClient llm.Client
+}
+
+// Briefing is the result of explaining a task
+type Briefing struct {
+	TaskID      string    `json:"task_id"`
+	Version     string    `json:"version"`
+	Content     string    `json:"content"`
+	Cached      bool      `json:"cached"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// NewBriefer creates a new task briefer
+func NewBriefer(store *storage.Store, llmClient llm.Client) *Briefer {
+	return &Briefer{
+		store:     store,
+		llmClient: llmClient,
+	}
+}
+
+// taskContext is everything known about a task at one point in time
+type taskContext struct {
+	task         *storage.Task
+	requirements []*storage.Requirement
+	artifacts    []*storage.Artifact // latest version of each artifact
+	auditLogs    []*storage.AuditLog
+	dependencies []*storage.Task
+	dependents   []*storage.Task
+}
+
+// ErrNotCached is returned by Cached when the task has no briefing for its current version
+var ErrNotCached = errors.New("no current briefing for this task")
+
+// Cached returns the task's briefing only if one is cached for its current
+// version, never calling the LLM
+func (b *Briefer) Cached(taskID string) (*Briefing, error) {
+	tc, err := b.gather(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	version := tc.version()
+	cached, err := b.store.GetTaskBriefing(taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotCached
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached briefing: %w", err)
+	}
+	if cached.Version != version {
+		return nil, ErrNotCached
+	}
+
+	return &Briefing{
+		TaskID:      taskID,
+		Version:     version,
+		Content:     cached.Content,
+		Cached:      true,
+		GeneratedAt: cached.CreatedAt,
+	}, nil
+}
+
+// Explain returns a briefing for the task, reusing the cached one while the
+// task has not changed unless refresh is set
+func (b *Briefer) Explain(taskID string, refresh bool) (*Briefing, error) {
+	tc, err := b.gather(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	version := tc.version()
+
+	if !refresh {
+		cached, err := b.store.GetTaskBriefing(taskID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to read cached briefing: %w", err)
+		}
+		if cached != nil && cached.Version == version {
+			return &Briefing{
+				TaskID:      taskID,
+				Version:     version,
+				Content:     cached.Content,
+				Cached:      true,
+				GeneratedAt: cached.CreatedAt,
+			}, nil
+		}
+	}
+
+	if b.llmClient == nil {
+		return nil, fmt.Errorf("no LLM client available to generate a briefing")
+	}
+
+	content, err := b.llmClient.GenerateText(context.Background(), tc.prompt())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate briefing: %w", err)
+	}
+
+	cached := &storage.TaskBriefing{
+		TaskID:  taskID,
+		Version: version,
+		Content: strings.TrimSpace(content),
+	}
+	if err := b.store.SaveTaskBriefing(cached); err != nil {
+		return nil, fmt.Errorf("failed to cache briefing: %w", err)
+	}
+
+	return &Briefing{
+		TaskID:      taskID,
+		Version:     version,
+		Content:     cached.Content,
+		GeneratedAt: cached.CreatedAt,
+	}, nil
+}
+
+// gather collects the task, its requirements, artifacts, audit history and dependency neighbours
+func (b *Briefer) gather(taskID string) (*taskContext, error) {
+	task, err := b.store.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task %s: %w", taskID, err)
+	}
+
+	tc := &taskContext{task: task}
+
+	if tc.requirements, err = b.store.ListTaskRequirements(taskID); err != nil {
+		return nil, fmt.Errorf("failed to get linked requirements: %w", err)
+	}
+
+	artifacts, err := b.store.ListArtifacts(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifacts: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, artifact := range artifacts {
+		// ListArtifacts orders by name, version DESC so the first of each name is the latest
+		if !seen[artifact.Name] {
+			seen[artifact.Name] = true
+			tc.artifacts = append(tc.artifacts, artifact)
+		}
+	}
+
+	if tc.auditLogs, err = b.store.GetAuditLogs(taskID); err != nil {
+		return nil, fmt.Errorf("failed to get audit history: %w", err)
+	}
+
+	var depIDs []string
+	if len(task.Dependencies) > 0 {
+		json.Unmarshal(task.Dependencies, &depIDs)
+	}
+	for _, depID := range depIDs {
+		if dep, err := b.store.GetTask(depID); err == nil {
+			tc.dependencies = append(tc.dependencies, dep)
+		}
+	}
+
+	allTasks, err := b.store.ListTasks(storage.TaskFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	for _, other := range allTasks {
+		var otherDeps []string
+		if len(other.Dependencies) > 0 {
+			json.Unmarshal(other.Dependencies, &otherDeps)
+		}
+		for _, depID := range otherDeps {
+			if depID == taskID {
+				tc.dependents = append(tc.dependents, other)
+				break
+			}
+		}
+	}
+
+	return tc, nil
+}
+
+// version hashes everything the briefing depends on so a cached briefing is
+// reused only while the task, its artifacts, history and dependencies are unchanged
+func (tc *taskContext) version() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "task:%s:%s:%s\n", tc.task.ID, tc.task.State, tc.task.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	for _, req := range tc.requirements {
+		fmt.Fprintf(h, "req:%s:%s\n", req.Key, req.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	for _, artifact := range tc.artifacts {
+		fmt.Fprintf(h, "artifact:%s:%d\n", artifact.Name, artifact.Version)
+	}
+	fmt.Fprintf(h, "audit:%d\n", len(tc.auditLogs))
+	if len(tc.auditLogs) > 0 {
+		fmt.Fprintf(h, "audit-latest:%s\n", tc.auditLogs[0].ID)
+	}
+	for _, dep := range tc.dependencies {
+		fmt.Fprintf(h, "dep:%s:%s\n", dep.ID, dep.State)
+	}
+	for _, dep := range tc.dependents {
+		fmt.Fprintf(h, "dependent:%s:%s\n", dep.ID, dep.State)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// prompt renders the briefing request for the LLM
+func (tc *taskContext) prompt() string {
+	var b strings.Builder
+
+	b.WriteString(`You are briefing a human who is joining this project mid-way.
+Using only the information below, write a concise briefing for the task with two sections:
+
+## Current Status
+3-6 sentences: what the task is, how far it has progressed, what was decided, and any problems or blockers.
+
+## Suggested Next Action
+One or two concrete next steps and who (which agent role or a human) should take them.
+
+`)
+
+	task := tc.task
+	fmt.Fprintf(&b, "# Task: %s\n", task.Title)
+	fmt.Fprintf(&b, "- ID: %s\n- State: %s\n- Priority: %d\n", task.ID, task.State, task.Priority)
+	if task.Owner != "" {
+		fmt.Fprintf(&b, "- Owner: %s\n", task.Owner)
+	}
+	fmt.Fprintf(&b, "\n## Description\n%s\n", task.Description)
+
+	if len(tc.requirements) > 0 {
+		b.WriteString("\n## Linked Requirements\n")
+		for _, req := range tc.requirements {
+			fmt.Fprintf(&b, "- %s (%s): %s\n", req.Key, req.Title, req.Text)
+		}
+	}
+
+	if len(tc.dependencies) > 0 {
+		b.WriteString("\n## Depends On\n")
+		for _, dep := range tc.dependencies {
+			fmt.Fprintf(&b, "- %s [%s]\n", dep.Title, dep.State)
+		}
+	}
+
+	if len(tc.dependents) > 0 {
+		b.WriteString("\n## Blocks\n")
+		for _, dep := range tc.dependents {
+			fmt.Fprintf(&b, "- %s [%s]\n", dep.Title, dep.State)
+		}
+	}
+
+	if len(tc.artifacts) > 0 {
+		b.WriteString("\n## Artifacts (latest versions)\n")
+		for _, artifact := range tc.artifacts {
+			content := artifact.Content
+			if len(content) > maxArtifactChars {
+				content = textutil.Truncate(content, maxArtifactChars) + "\n[truncated]"
+			}
+			fmt.Fprintf(&b, "\n### %s (v%d)\n%s\n", artifact.Name, artifact.Version, content)
+		}
+	}
+
+	if len(tc.auditLogs) > 0 {
+		b.WriteString("\n## Recent History (newest first)\n")
+		for i, entry := range tc.auditLogs {
+			if i >= maxAuditEntries {
+				break
+			}
+			fmt.Fprintf(&b, "- %s: %s → %s by %s (%s) %s\n",
+				entry.CreatedAt.Format("2006-01-02 15:04"), entry.PrevState, entry.NextState,
+				entry.Actor, entry.Result, entry.Note)
+		}
+	}
+
+	return b.String()
+}
@@ -1,27 +1,74 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
+
+	"baton/internal/schedule"
 )
 
+// validProjectName matches names that can appear in a project's URL path
+var validProjectName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 // Config represents the application configuration
 type Config struct {
 	PlanFile  string    `yaml:"plan_file" mapstructure:"plan_file"`
+	PlanUnavailable string `yaml:"plan_unavailable" mapstructure:"plan_unavailable"` // continue or pause when plan_file can't be read
 	Workspace string    `yaml:"workspace" mapstructure:"workspace"`
 	Database  string    `yaml:"database" mapstructure:"database"`
+	DatabaseEncryption bool `yaml:"database_encryption" mapstructure:"database_encryption"` // encrypt the database at rest; see DatabaseKey
+	DatabaseKeyEnv string   `yaml:"database_key_env" mapstructure:"database_key_env"` // environment variable holding the encryption key
+	PromptsDir string   `yaml:"prompts_dir" mapstructure:"prompts_dir"` // agents' prompt_template files, relative to the workspace
 	MCPPort   int       `yaml:"mcp_port" mapstructure:"mcp_port"`
+	MCP       MCPConfig `yaml:"mcp" mapstructure:"mcp"` // who may reach the HTTP MCP server
+	Timezone  string    `yaml:"timezone" mapstructure:"timezone"` // IANA name timestamps are displayed in, or Local / UTC
 	LLM       LLMConfig `yaml:"llm" mapstructure:"llm"`
 	Agents    map[string]Agent `yaml:"agents" mapstructure:"agents"`
+	DefaultAgent string        `yaml:"default_agent" mapstructure:"default_agent"` // agent ID used for states no agent covers
 	Selection SelectionConfig `yaml:"selection" mapstructure:"selection"`
 	Completion CompletionConfig `yaml:"completion" mapstructure:"completion"`
+	ArtifactSchemas map[string]ArtifactSchema `yaml:"artifact_schemas" mapstructure:"artifact_schemas"`
+	Artifacts ArtifactsConfig `yaml:"artifacts" mapstructure:"artifacts"`
+	Archive   ArchiveConfig `yaml:"archive" mapstructure:"archive"`
+	CustomFields CustomFields `yaml:"custom_fields" mapstructure:"custom_fields"` // extra task metadata, by field name
+	Search    SearchConfig `yaml:"search" mapstructure:"search"`
+	Timebox   TimeboxConfig `yaml:"timebox" mapstructure:"timebox"`
+	Isolation IsolationConfig `yaml:"isolation" mapstructure:"isolation"`
+	Staleness StalenessConfig `yaml:"staleness" mapstructure:"staleness"`
+	Decomposition DecompositionConfig `yaml:"decomposition" mapstructure:"decomposition"`
+	ReviewEscalation ReviewEscalationConfig `yaml:"review_escalation" mapstructure:"review_escalation"`
+	Acceptance AcceptanceConfig `yaml:"acceptance" mapstructure:"acceptance"`
+	Notifications NotificationsConfig `yaml:"notifications" mapstructure:"notifications"`
+	Hooks     []TransitionHook `yaml:"hooks" mapstructure:"hooks"` // commands and webhooks run on state changes
+	CycleHooks CycleHooksConfig `yaml:"cycle_hooks" mapstructure:"cycle_hooks"` // commands run around each cycle
+	Schedules []Schedule `yaml:"schedules" mapstructure:"schedules"` // unattended runs of 'baton schedule'
+	Pipelines []Pipeline `yaml:"pipelines" mapstructure:"pipelines"` // agents chained in one 'baton start --pipeline'
+	TransitionRules []TransitionRule `yaml:"transition_rules" mapstructure:"transition_rules"` // conditions on handover artifact metadata
+	Web       WebConfig `yaml:"web" mapstructure:"web"`
 	Security  SecurityConfig `yaml:"security" mapstructure:"security"`
 	Logging   LoggingConfig `yaml:"logging" mapstructure:"logging"`
 	Development DevelopmentConfig `yaml:"development" mapstructure:"development"`
+	Projects  map[string]ProjectConfig `yaml:"projects" mapstructure:"projects"` // hosted by one baton serve; see LoadProject
+
+	// Project is the project the database is scoped to: --project or the one
+	// chosen with 'baton projects switch'; "" means storage.DefaultProject
+	Project string `yaml:"-" mapstructure:"-"`
+
+	// ConfigFile is the file the configuration was read from, "" when defaults only
+	ConfigFile string `yaml:"-" mapstructure:"-"`
+
+	location *time.Location // parsed Timezone
 }
 
 // LLMConfig represents LLM configuration
@@ -29,9 +76,48 @@ type LLMConfig struct {
 	Primary        string      `yaml:"primary" mapstructure:"primary"`
 	Fallback       *string     `yaml:"fallback" mapstructure:"fallback"`
 	TimeoutSeconds int         `yaml:"timeout_seconds" mapstructure:"timeout_seconds"`
-	MaxRetries     int         `yaml:"max_retries" mapstructure:"max_retries"`
+	MaxRetries     int         `yaml:"max_retries" mapstructure:"max_retries"` // retries of transient failures per call
+	RetryBackoffSeconds    float64 `yaml:"retry_backoff_seconds" mapstructure:"retry_backoff_seconds"`         // before the first retry, doubled for each one after
+	RetryMaxBackoffSeconds float64 `yaml:"retry_max_backoff_seconds" mapstructure:"retry_max_backoff_seconds"` // cap on the delay between retries
+	RateLimit      RateLimitConfig `yaml:"rate_limit" mapstructure:"rate_limit"` // shared by every LLM call in the process
 	Claude         ClaudeConfig `yaml:"claude" mapstructure:"claude"`
 	OpenAI         OpenAIConfig `yaml:"openai" mapstructure:"openai"`
+	OpenAICompatible OpenAICompatibleConfig `yaml:"openai_compatible" mapstructure:"openai_compatible"`
+	Ollama         OllamaConfig `yaml:"ollama" mapstructure:"ollama"`
+	Gemini         GeminiConfig `yaml:"gemini" mapstructure:"gemini"`
+	Tiers          map[string]ModelTier `yaml:"tiers" mapstructure:"tiers"`           // named model choices, e.g. cheap and premium
+	TierOrder      []string          `yaml:"tier_order" mapstructure:"tier_order"`   // cheapest first; escalation climbs this list
+	DefaultTier    string            `yaml:"default_tier" mapstructure:"default_tier"`
+	StateTiers     map[string]string `yaml:"state_tiers" mapstructure:"state_tiers"` // starting tier per state, overrides the agent's
+	Escalation     EscalationPolicy  `yaml:"escalation" mapstructure:"escalation"`
+	BudgetUSDPerCycle float64             `yaml:"budget_usd_per_cycle" mapstructure:"budget_usd_per_cycle"` // 0 means no limit
+	BudgetUSDPerDay   float64             `yaml:"budget_usd_per_day" mapstructure:"budget_usd_per_day"`     // 0 means no limit
+	Pricing           map[string]ModelPrice `yaml:"pricing" mapstructure:"pricing"`                         // by model, for providers that do not report cost
+}
+
+// RateLimitConfig caps the LLM calls a baton process makes, web UI prompts and
+// cycles alike; 0 means no limit
+type RateLimitConfig struct {
+	RequestsPerMinute int `yaml:"requests_per_minute" mapstructure:"requests_per_minute"` // calls started in any 60 seconds
+	MaxConcurrent     int `yaml:"max_concurrent" mapstructure:"max_concurrent"`           // calls in flight at once
+}
+
+// ModelPrice is what a model costs, in USD per million tokens
+type ModelPrice struct {
+	InputPerMTok  float64 `yaml:"input_per_mtok" mapstructure:"input_per_mtok"`
+	OutputPerMTok float64 `yaml:"output_per_mtok" mapstructure:"output_per_mtok"`
+}
+
+// ModelTier is a provider and model a cycle can be served by
+type ModelTier struct {
+	Provider string `yaml:"provider" mapstructure:"provider"` // defaults to llm.primary
+	Model    string `yaml:"model" mapstructure:"model"`       // empty uses the provider's default model
+}
+
+// EscalationPolicy decides when a cycle is retried on the next tier up
+type EscalationPolicy struct {
+	OnFailure       bool `yaml:"on_failure" mapstructure:"on_failure"`               // the LLM call fails
+	OnLowConfidence bool `yaml:"on_low_confidence" mapstructure:"on_low_confidence"` // the agent asks to escalate
 }
 
 // ClaudeConfig represents Claude Code configuration
@@ -40,12 +126,47 @@ type ClaudeConfig struct {
 	HeadlessArgs  []string `yaml:"headless_args" mapstructure:"headless_args"`
 	OutputFormat  string   `yaml:"output_format" mapstructure:"output_format"`
 	MCPConnect    bool     `yaml:"mcp_connect" mapstructure:"mcp_connect"`
+	Model         string   `yaml:"model" mapstructure:"model"` // empty uses the CLI's default model
 }
 
-// OpenAIConfig represents OpenAI CLI configuration
+// OpenAIConfig represents OpenAI Chat Completions API configuration. Any
+// OpenAI-compatible endpoint can be used by changing base_url.
 type OpenAIConfig struct {
-	Command       string   `yaml:"command" mapstructure:"command"`
-	HeadlessArgs  []string `yaml:"headless_args" mapstructure:"headless_args"`
+	Model     string `yaml:"model" mapstructure:"model"`
+	BaseURL   string `yaml:"base_url" mapstructure:"base_url"`       // e.g. https://api.openai.com/v1
+	APIKeyEnv string `yaml:"api_key_env" mapstructure:"api_key_env"` // environment variable holding the API key
+	MaxTokens int    `yaml:"max_tokens" mapstructure:"max_tokens"`   // 0 leaves the limit to the API
+}
+
+// OpenAICompatibleConfig represents a generic OpenAI-compatible Chat
+// Completions endpoint, such as a LiteLLM or OpenRouter proxy or an internal
+// gateway, served alongside the OpenAI client itself
+type OpenAICompatibleConfig struct {
+	Model     string            `yaml:"model" mapstructure:"model"`
+	BaseURL   string            `yaml:"base_url" mapstructure:"base_url"`       // e.g. http://localhost:4000/v1
+	APIKeyEnv string            `yaml:"api_key_env" mapstructure:"api_key_env"` // optional; sent as a bearer token when set
+	Headers   map[string]string `yaml:"headers" mapstructure:"headers"`         // extra request headers; $VAR references are expanded
+	MaxTokens int               `yaml:"max_tokens" mapstructure:"max_tokens"`   // 0 leaves the limit to the API
+}
+
+// OllamaConfig represents a local Ollama server. With mcp_tools the client
+// offers the baton MCP methods to the model as tools and runs its calls.
+type OllamaConfig struct {
+	Host         string `yaml:"host" mapstructure:"host"`                     // e.g. http://localhost:11434
+	Model        string `yaml:"model" mapstructure:"model"`
+	NumCtx       int    `yaml:"num_ctx" mapstructure:"num_ctx"`               // context window; 0 uses the model's default
+	MCPTools     bool   `yaml:"mcp_tools" mapstructure:"mcp_tools"`
+	MaxToolCalls int    `yaml:"max_tool_calls" mapstructure:"max_tool_calls"` // tool round trips per prompt
+}
+
+// GeminiConfig represents Google Gemini configuration: the generateContent
+// API by default, or the Gemini CLI when command is set
+type GeminiConfig struct {
+	Model     string `yaml:"model" mapstructure:"model"`
+	BaseURL   string `yaml:"base_url" mapstructure:"base_url"`       // e.g. https://generativelanguage.googleapis.com/v1beta
+	APIKeyEnv string `yaml:"api_key_env" mapstructure:"api_key_env"` // environment variable holding the API key
+	MaxTokens int    `yaml:"max_tokens" mapstructure:"max_tokens"`   // 0 leaves the limit to the API
+	Command   string `yaml:"command" mapstructure:"command"`         // e.g. gemini; empty calls the API
 }
 
 // Agent represents an agent configuration
@@ -61,6 +182,7 @@ type Agent struct {
 type RoutingPolicy struct {
 	LLMPreference   string `yaml:"llm_preference" mapstructure:"llm_preference"`
 	PromptTemplate  string `yaml:"prompt_template" mapstructure:"prompt_template"`
+	ModelTier       string `yaml:"model_tier" mapstructure:"model_tier"` // starting tier for this agent's cycles
 }
 
 // AgentPermissions represents what an agent can do
@@ -69,16 +191,65 @@ type AgentPermissions struct {
 	CanExecuteCommands  bool     `yaml:"can_execute_commands" mapstructure:"can_execute_commands"`
 	CanUpdateArtifacts  bool     `yaml:"can_update_artifacts" mapstructure:"can_update_artifacts"`
 	CanReadArtifacts    bool     `yaml:"can_read_artifacts" mapstructure:"can_read_artifacts"`
+	CanCreateTasks      bool     `yaml:"can_create_tasks" mapstructure:"can_create_tasks"` // through baton.tasks.create and baton.tasks.update
 	CanTransitionTo     []string `yaml:"can_transition_to" mapstructure:"can_transition_to"`
 }
 
-// SelectionConfig represents task selection policy
+// SelectionConfig represents task selection policy. The weights only apply to
+// the weighted algorithm, which ranks tasks by the weighted sum of per-factor
+// scores that each lie between 0 and 1.
 type SelectionConfig struct {
-	Algorithm       string  `yaml:"algorithm" mapstructure:"algorithm"`
+	Algorithm       string  `yaml:"algorithm" mapstructure:"algorithm"` // priority_dependency or weighted
 	PriorityWeight  float64 `yaml:"priority_weight" mapstructure:"priority_weight"`
+	AgeWeight       float64 `yaml:"age_weight" mapstructure:"age_weight"`             // favors tasks left untouched longest
+	EstimateWeight  float64 `yaml:"estimate_weight" mapstructure:"estimate_weight"`   // favors small estimated tasks
+	FanOutWeight    float64 `yaml:"fanout_weight" mapstructure:"fanout_weight"`       // favors tasks that unblock many others
+	MilestoneWeight float64 `yaml:"milestone_weight" mapstructure:"milestone_weight"` // favors tasks in nearly finished milestones
+	DeadlineWeight  float64 `yaml:"deadline_weight" mapstructure:"deadline_weight"`   // favors tasks whose due date is near or past
 	DependencyStrict bool   `yaml:"dependency_strict" mapstructure:"dependency_strict"`
 	PreferLeafTasks bool    `yaml:"prefer_leaf_tasks" mapstructure:"prefer_leaf_tasks"`
 	TieBreaker      string  `yaml:"tie_breaker" mapstructure:"tie_breaker"`
+	SkipUnassignedStates bool `yaml:"skip_unassigned_states" mapstructure:"skip_unassigned_states"` // skip tasks whose state has no agent
+	AreaLocks       AreaLocksConfig `yaml:"area_locks" mapstructure:"area_locks"`
+	MilestoneSignoff bool     `yaml:"milestone_signoff" mapstructure:"milestone_signoff"` // hold a milestone's tasks until every earlier milestone is signed off
+	MilestoneOrder   []string `yaml:"milestone_order" mapstructure:"milestone_order"`     // delivery order; milestones not listed follow by name
+	WIPLimits        map[string]int `yaml:"wip_limits" mapstructure:"wip_limits"`       // most tasks a state may hold at once, e.g. implementing: 1
+}
+
+// AreaLocksConfig keeps concurrent workers out of the same code area. A task's
+// areas are its tags (only those listed in areas, if any); while a cycle in
+// one of states runs, no other cycle may work on a task sharing an area.
+type AreaLocksConfig struct {
+	Enabled    bool     `yaml:"enabled" mapstructure:"enabled"`
+	Areas      []string `yaml:"areas" mapstructure:"areas"`             // tags that name areas; empty treats every tag as one
+	States     []string `yaml:"states" mapstructure:"states"`           // states whose cycles lock their areas
+	TTLMinutes int      `yaml:"ttl_minutes" mapstructure:"ttl_minutes"` // locks left by crashed workers expire after this
+}
+
+// AreasFor returns the areas a cycle on a task with tags in state locks, or
+// nil when area locks do not apply
+func (c *AreaLocksConfig) AreasFor(tags []string, state string) []string {
+	if !c.Enabled || !contains(c.States, state) {
+		return nil
+	}
+
+	var areas []string
+	for _, tag := range tags {
+		if (len(c.Areas) == 0 || contains(c.Areas, tag)) && !contains(areas, tag) {
+			areas = append(areas, tag)
+		}
+	}
+	return areas
+}
+
+// contains reports whether list holds item
+func contains(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
 }
 
 // CompletionConfig represents completion handshake settings
@@ -88,6 +259,142 @@ type CompletionConfig struct {
 	TimeoutSeconds              int    `yaml:"timeout_seconds" mapstructure:"timeout_seconds"`
 	RequireExplicitStateUpdate  bool   `yaml:"require_explicit_state_update" mapstructure:"require_explicit_state_update"`
 	FollowUpTemplate            string `yaml:"follow_up_template" mapstructure:"follow_up_template"`
+	// Transitions the agent rates below this confidence (0 to 1) are queued for human review
+	LowConfidenceThreshold      float64 `yaml:"low_confidence_threshold" mapstructure:"low_confidence_threshold"`
+	// Cycles whose handshake failed, leaving the task in needs_fixes, are retried
+	// up to this many times with the failure in the prompt; 0 turns retries off
+	MaxAutoRetries              int     `yaml:"max_auto_retries" mapstructure:"max_auto_retries"`
+}
+
+// ArtifactSchema describes what a handover artifact must contain
+type ArtifactSchema struct {
+	Format           string   `yaml:"format" mapstructure:"format" json:"format,omitempty"`                                  // markdown (default) or json
+	RequiredSections []string `yaml:"required_sections" mapstructure:"required_sections" json:"required_sections,omitempty"` // markdown headings, e.g. "## Steps"
+	RequiredFields   []string `yaml:"required_fields" mapstructure:"required_fields" json:"required_fields,omitempty"`       // top-level keys of a json artifact
+	MinLength        int      `yaml:"min_length" mapstructure:"min_length" json:"min_length,omitempty"`
+}
+
+// SearchConfig represents task search settings
+type SearchConfig struct {
+	EmbeddingProvider string `yaml:"embedding_provider" mapstructure:"embedding_provider"` // local or api
+	EmbeddingEndpoint string `yaml:"embedding_endpoint" mapstructure:"embedding_endpoint"` // OpenAI-compatible /v1/embeddings URL
+	EmbeddingModel    string `yaml:"embedding_model" mapstructure:"embedding_model"`
+	APIKeyEnv         string `yaml:"api_key_env" mapstructure:"api_key_env"` // environment variable holding the API key
+}
+
+// TimeboxConfig derives each cycle's timeout from the task's estimate and state:
+// (base_seconds + estimated_hours * seconds_per_hour) * state_factors[state],
+// clamped to [min_seconds, max_seconds]. When disabled, development.cycle_timebox_seconds applies.
+type TimeboxConfig struct {
+	Enabled              bool               `yaml:"enabled" mapstructure:"enabled"`
+	BaseSeconds          int                `yaml:"base_seconds" mapstructure:"base_seconds"`
+	SecondsPerHour       int                `yaml:"seconds_per_hour" mapstructure:"seconds_per_hour"`             // per estimated hour of work
+	DefaultEstimateHours float64            `yaml:"default_estimate_hours" mapstructure:"default_estimate_hours"` // used for unestimated tasks
+	StateFactors         map[string]float64 `yaml:"state_factors" mapstructure:"state_factors"`                   // states not listed use 1.0
+	MinSeconds           int                `yaml:"min_seconds" mapstructure:"min_seconds"`
+	MaxSeconds           int                `yaml:"max_seconds" mapstructure:"max_seconds"`
+}
+
+// StalenessConfig flags tasks stuck in a work state: a task in one of states
+// for longer than after_hours (or its state_hours override) is stale.
+type StalenessConfig struct {
+	AfterHours float64            `yaml:"after_hours" mapstructure:"after_hours"` // 0 turns stale-task detection off
+	StateHours map[string]float64 `yaml:"state_hours" mapstructure:"state_hours"` // per-state overrides of after_hours
+	States     []string           `yaml:"states" mapstructure:"states"`           // work states watched
+}
+
+// Threshold returns how long a task may stay in state before it is stale, or
+// 0 when tasks in state are not watched
+func (c StalenessConfig) Threshold(state string) time.Duration {
+	if c.AfterHours <= 0 {
+		return 0
+	}
+	for _, watched := range c.States {
+		if watched != state {
+			continue
+		}
+		hours := c.AfterHours
+		if override, ok := c.StateHours[state]; ok {
+			hours = override
+		}
+		return time.Duration(hours * float64(time.Hour))
+	}
+	return 0
+}
+
+// ArtifactsConfig controls mirroring artifacts into the workspace as files
+// and where large artifact content is kept
+type ArtifactsConfig struct {
+	Materialize        bool   `yaml:"materialize" mapstructure:"materialize"`                   // keep files in sync on every upsert
+	Dir                string `yaml:"dir" mapstructure:"dir"`                                   // relative to the workspace
+	BlobThresholdBytes int    `yaml:"blob_threshold_bytes" mapstructure:"blob_threshold_bytes"` // larger content goes to .baton/blobs; 0 keeps it all in the database
+}
+
+// ArchiveConfig decides which audit payloads baton archive moves out of the
+// database into compressed files
+type ArchiveConfig struct {
+	AfterDays int `yaml:"after_days" mapstructure:"after_days"` // archive audit logs older than this
+	MinBytes  int `yaml:"min_bytes" mapstructure:"min_bytes"`   // smaller payloads stay in the database
+}
+
+// IsolationConfig keeps an agent's file changes out of the working tree until
+// its cycle succeeds. In worktree mode each cycle's agent works in a git
+// worktree on a branch of its own, which is merged into the workspace's branch
+// when the completion handshake succeeds and discarded when the cycle fails.
+type IsolationConfig struct {
+	Mode         string `yaml:"mode" mapstructure:"mode"`                   // none or worktree
+	Dir          string `yaml:"dir" mapstructure:"dir"`                     // where worktrees are created; "" means inside the repository's .git directory
+	BranchPrefix string `yaml:"branch_prefix" mapstructure:"branch_prefix"` // of each cycle's branch
+	KeepFailed   bool   `yaml:"keep_failed" mapstructure:"keep_failed"`     // keep the branch of a failed cycle for inspection
+}
+
+// DecompositionConfig decides when a task that keeps failing is split into
+// smaller dependent subtasks
+type DecompositionConfig struct {
+	FailureThreshold int    `yaml:"failure_threshold" mapstructure:"failure_threshold"` // unsuccessful cycles in a row; 0 disables
+	Mode             string `yaml:"mode" mapstructure:"mode"`                           // offer (suggest the command) or auto
+	MaxSubtasks      int    `yaml:"max_subtasks" mapstructure:"max_subtasks"`
+}
+
+// ReviewEscalationConfig stops a task that keeps failing review: once it has
+// moved into needs_fixes max_failures times it is put on hold in state, for a
+// person to look at, and its watchers and channel are notified
+type ReviewEscalationConfig struct {
+	MaxFailures int    `yaml:"max_failures" mapstructure:"max_failures"` // 0 disables
+	State       string `yaml:"state" mapstructure:"state"`               // blocked or paused
+	Channel     string `yaml:"channel" mapstructure:"channel"`           // also notified of every escalation; "" notifies watchers only
+}
+
+// AcceptanceConfig decides which requirements need acceptance tests before
+// a milestone can be reported complete
+type AcceptanceConfig struct {
+	MandatoryTypes []string `yaml:"mandatory_types" mapstructure:"mandatory_types"` // requirement types that must have tests
+}
+
+// NotificationsConfig names the channels task watchers can be notified on
+type NotificationsConfig struct {
+	DefaultChannel string                         `yaml:"default_channel" mapstructure:"default_channel"` // used by watches that name no channel
+	Channels       map[string]NotificationChannel `yaml:"channels" mapstructure:"channels"`
+}
+
+// NotificationChannel is one delivery target: a generic JSON webhook, a Slack
+// incoming webhook, or a desktop notification on the machine running baton
+type NotificationChannel struct {
+	Type string `yaml:"type" mapstructure:"type"` // webhook, slack or desktop
+	URL  string `yaml:"url" mapstructure:"url"`   // required for webhook and slack
+}
+
+// MCPConfig secures the HTTP MCP server 'baton serve' starts on mcp_port
+type MCPConfig struct {
+	Token       string `yaml:"token" mapstructure:"token"`               // bearer token every request must carry; "" uses a token generated into the workspace
+	TokenEnv    string `yaml:"token_env" mapstructure:"token_env"`       // environment variable that, when set, overrides token
+	AllowRemote bool   `yaml:"allow_remote" mapstructure:"allow_remote"` // listen on every interface instead of localhost only; needs a token
+	InsecureNoToken bool `yaml:"insecure_no_token" mapstructure:"insecure_no_token"` // serve without any token, letting every local client in
+}
+
+// WebConfig represents web UI server settings
+type WebConfig struct {
+	ReadOnly bool `yaml:"read_only" mapstructure:"read_only"` // disable every mutating endpoint and LLM call
 }
 
 // SecurityConfig represents security and safety settings
@@ -106,11 +413,107 @@ type LoggingConfig struct {
 	AuditRetentionDays int    `yaml:"audit_retention_days" mapstructure:"audit_retention_days"`
 }
 
+// ProjectConfig is one project hosted by a multi-project baton serve
+type ProjectConfig struct {
+	Config   string `yaml:"config" mapstructure:"config"`       // project config file, relative to this file
+	TokenEnv string `yaml:"token_env" mapstructure:"token_env"` // environment variable holding the project's API token
+}
+
+// TransitionHook runs a command or posts to a webhook when a task moves from
+// one state to another. A blocking hook that fails stops the transition.
+type TransitionHook struct {
+	Name           string `yaml:"name" mapstructure:"name"`                       // shown in the audit log; defaults to the command or webhook
+	From           string `yaml:"from" mapstructure:"from"`                       // state left, or * for any
+	To             string `yaml:"to" mapstructure:"to"`                           // state entered, or * for any
+	Command        string `yaml:"command" mapstructure:"command"`                 // run with sh -c in the workspace
+	Webhook        string `yaml:"webhook" mapstructure:"webhook"`                 // URL the transition is POSTed to as JSON
+	TimeoutSeconds int    `yaml:"timeout_seconds" mapstructure:"timeout_seconds"` // 0 means 60
+	Blocking       bool   `yaml:"blocking" mapstructure:"blocking"`               // a failure refuses the transition
+}
+
+// Matches reports whether the hook runs when a task moves from one state to another
+func (h TransitionHook) Matches(from, to string) bool {
+	return (h.From == "*" || h.From == from) && (h.To == "*" || h.To == to)
+}
+
+// Label names the hook in audit entries and errors
+func (h TransitionHook) Label() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.Command != "":
+		return h.Command
+	default:
+		return h.Webhook
+	}
+}
+
+// CycleHooksConfig holds commands run around each cycle, in the workspace
+// (or, with worktree isolation, after selection in the cycle's worktree)
+type CycleHooksConfig struct {
+	BeforeSelection []CycleHook `yaml:"before_selection" mapstructure:"before_selection"` // run before a cycle selects its task, e.g. git pull
+	AfterSuccess    []CycleHook `yaml:"after_success" mapstructure:"after_success"`       // run once the agent moved the task, e.g. make lint && make test
+}
+
+// CycleHook is a command run around a cycle. A failing hook either blocks,
+// which stops the cycle before selection or undoes the agent's transition
+// after it, or only annotates the cycle's audit entry.
+type CycleHook struct {
+	Name           string `yaml:"name" mapstructure:"name"`                       // shown in the audit log; defaults to the command
+	Command        string `yaml:"command" mapstructure:"command"`                 // run with sh -c
+	TimeoutSeconds int    `yaml:"timeout_seconds" mapstructure:"timeout_seconds"` // 0 means 60
+	OnFailure      string `yaml:"on_failure" mapstructure:"on_failure"`           // block (default) or annotate
+}
+
+// Blocking reports whether the hook's failure blocks the cycle
+func (h CycleHook) Blocking() bool {
+	return h.OnFailure != "annotate"
+}
+
+// Label names the hook in audit entries and errors
+func (h CycleHook) Label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.Command
+}
+
+// Schedule is a run 'baton schedule' starts whenever its cron expression
+// matches, with the limits 'baton run' takes as flags
+type Schedule struct {
+	Name               string `yaml:"name" mapstructure:"name"`                                 // shown in status and logs
+	Cron               string `yaml:"cron" mapstructure:"cron"`                                 // e.g. "0 22 * * 1-5", in the configured timezone
+	MaxCycles          int    `yaml:"max_cycles" mapstructure:"max_cycles"`                     // 0 for no limit
+	MaxDurationMinutes int    `yaml:"max_duration_minutes" mapstructure:"max_duration_minutes"` // start no new cycle after this long; 0 for no limit
+	UntilState         string `yaml:"until_state" mapstructure:"until_state"`                   // only work tasks that have not reached this state
+	Parallel           int    `yaml:"parallel" mapstructure:"parallel"`                         // cycles at once; 0 means 1
+	StopOnError        bool   `yaml:"stop_on_error" mapstructure:"stop_on_error"`
+}
+
+// Pipeline chains the cycles of several agents on one task in a single
+// 'baton start --pipeline', such as an architect planning and a developer
+// implementing right after
+type Pipeline struct {
+	Name   string   `yaml:"name" mapstructure:"name"`
+	Stages []string `yaml:"stages" mapstructure:"stages"` // agent IDs, in order; each runs one cycle
+}
+
+// PipelineByName returns the pipeline with the given name
+func (c *Config) PipelineByName(name string) (*Pipeline, bool) {
+	for i := range c.Pipelines {
+		if c.Pipelines[i].Name == name {
+			return &c.Pipelines[i], true
+		}
+	}
+	return nil, false
+}
+
 // DevelopmentConfig represents development settings
 type DevelopmentConfig struct {
 	DryRunDefault         bool `yaml:"dry_run_default" mapstructure:"dry_run_default"`
 	DebugMCP              bool `yaml:"debug_mcp" mapstructure:"debug_mcp"`
 	CycleTimeboxSeconds   int  `yaml:"cycle_timebox_seconds" mapstructure:"cycle_timebox_seconds"`
+	RecordTranscripts     bool `yaml:"record_transcripts" mapstructure:"record_transcripts"` // write each cycle's LLM exchanges to claudedocs/transcripts
 }
 
 // Load loads configuration from file and environment
@@ -142,10 +545,48 @@ func Load(configPath string) (*Config, error) {
 		}
 	}
 
+	return decode(v, "")
+}
+
+// LoadProject loads one project's configuration for a multi-project server.
+// The project file is layered over the server's, so it only needs the
+// settings that differ, such as workspace and database. A relative workspace
+// is resolved against the project file's directory. Environment overrides are
+// not applied, since they would apply to every project alike.
+func LoadProject(serverConfigFile, projectConfigFile string) (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	if serverConfigFile != "" {
+		v.SetConfigFile(serverConfigFile)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+	}
+	v.SetConfigFile(projectConfigFile)
+	if err := v.MergeInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading project config file: %w", err)
+	}
+
+	config, err := decode(v, filepath.Dir(projectConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	config.Projects = nil
+	return config, nil
+}
+
+// decode unmarshals, validates and resolves the configuration read into v;
+// a relative workspace is resolved against baseDir when one is given
+func decode(v *viper.Viper, baseDir string) (*Config, error) {
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
+	config.ConfigFile = v.ConfigFileUsed()
+	if baseDir != "" && !filepath.IsAbs(config.Workspace) {
+		config.Workspace = filepath.Join(baseDir, config.Workspace)
+	}
 
 	// Validate and resolve paths
 	if err := config.validate(); err != nil {
@@ -166,6 +607,10 @@ func (c *Config) validate() error {
 		c.PlanFile = filepath.Join(c.Workspace, c.PlanFile)
 	}
 
+	if c.PromptsDir != "" && !filepath.IsAbs(c.PromptsDir) {
+		c.PromptsDir = filepath.Join(c.Workspace, c.PromptsDir)
+	}
+
 	// Validate workspace exists or can be created
 	if err := os.MkdirAll(c.Workspace, 0755); err != nil {
 		return fmt.Errorf("cannot create workspace directory %s: %w", c.Workspace, err)
@@ -176,9 +621,524 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid MCP port %d: must be between 1024-65535", c.MCPPort)
 	}
 
+	// Validate the display timezone
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: must be an IANA name such as Europe/Amsterdam, UTC or Local", c.Timezone)
+	}
+	c.location = location
+
+	if c.Completion.LowConfidenceThreshold < 0 || c.Completion.LowConfidenceThreshold > 1 {
+		return fmt.Errorf("invalid completion.low_confidence_threshold %v: must be between 0 and 1", c.Completion.LowConfidenceThreshold)
+	}
+	if c.Completion.MaxAutoRetries < 0 {
+		return fmt.Errorf("invalid completion.max_auto_retries %d: must not be negative", c.Completion.MaxAutoRetries)
+	}
+
+	// Validate default agent refers to a configured agent
+	if c.DefaultAgent != "" {
+		if _, exists := c.Agents[c.DefaultAgent]; !exists {
+			return fmt.Errorf("default_agent %q is not a configured agent", c.DefaultAgent)
+		}
+	}
+
+	// Validate selection policy
+	switch c.Selection.Algorithm {
+	case "priority_dependency", "weighted":
+	default:
+		return fmt.Errorf("invalid selection.algorithm %q: must be priority_dependency or weighted", c.Selection.Algorithm)
+	}
+	weights := []struct {
+		name  string
+		value float64
+	}{
+		{"priority_weight", c.Selection.PriorityWeight},
+		{"age_weight", c.Selection.AgeWeight},
+		{"estimate_weight", c.Selection.EstimateWeight},
+		{"fanout_weight", c.Selection.FanOutWeight},
+		{"milestone_weight", c.Selection.MilestoneWeight},
+		{"deadline_weight", c.Selection.DeadlineWeight},
+	}
+	var totalWeight float64
+	for _, weight := range weights {
+		if weight.value < 0 {
+			return fmt.Errorf("selection.%s must not be negative", weight.name)
+		}
+		totalWeight += weight.value
+	}
+	if c.Selection.Algorithm == "weighted" && totalWeight == 0 {
+		return fmt.Errorf("selection.algorithm weighted needs at least one positive weight")
+	}
+	if c.Selection.AreaLocks.Enabled && c.Selection.AreaLocks.TTLMinutes <= 0 {
+		return fmt.Errorf("selection.area_locks.ttl_minutes must be positive")
+	}
+	for state, limit := range c.Selection.WIPLimits {
+		if limit <= 0 {
+			return fmt.Errorf("selection.wip_limits.%s must be positive", state)
+		}
+	}
+
+	// Validate LLM retries
+	if c.LLM.MaxRetries < 0 || c.LLM.RetryBackoffSeconds < 0 || c.LLM.RetryMaxBackoffSeconds < 0 {
+		return fmt.Errorf("llm.max_retries, llm.retry_backoff_seconds and llm.retry_max_backoff_seconds must not be negative")
+	}
+
+	if baseURL := c.LLM.OpenAICompatible.BaseURL; baseURL != "" {
+		if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid llm.openai_compatible.base_url %q: must be an http or https URL", baseURL)
+		}
+	}
+
+	if c.LLM.RateLimit.RequestsPerMinute < 0 || c.LLM.RateLimit.MaxConcurrent < 0 {
+		return fmt.Errorf("llm.rate_limit.requests_per_minute and llm.rate_limit.max_concurrent must not be negative")
+	}
+
+	// Validate LLM budgets
+	if c.LLM.BudgetUSDPerCycle < 0 || c.LLM.BudgetUSDPerDay < 0 {
+		return fmt.Errorf("llm.budget_usd_per_cycle and llm.budget_usd_per_day must not be negative")
+	}
+	for model, price := range c.LLM.Pricing {
+		if price.InputPerMTok < 0 || price.OutputPerMTok < 0 {
+			return fmt.Errorf("llm.pricing.%s prices must not be negative", model)
+		}
+	}
+
+	// Validate model tiers
+	for _, tier := range c.LLM.TierOrder {
+		if _, exists := c.LLM.Tiers[tier]; !exists {
+			return fmt.Errorf("llm.tier_order lists unknown tier %q", tier)
+		}
+	}
+	if c.LLM.DefaultTier != "" {
+		if _, exists := c.LLM.Tiers[c.LLM.DefaultTier]; !exists {
+			return fmt.Errorf("llm.default_tier %q is not a configured tier", c.LLM.DefaultTier)
+		}
+	}
+	for state, tier := range c.LLM.StateTiers {
+		if _, exists := c.LLM.Tiers[tier]; !exists {
+			return fmt.Errorf("llm.state_tiers.%s refers to unknown tier %q", state, tier)
+		}
+	}
+	for agentID, agent := range c.Agents {
+		if tier := agent.RoutingPolicy.ModelTier; tier != "" {
+			if _, exists := c.LLM.Tiers[tier]; !exists {
+				return fmt.Errorf("agent %s routing_policy.model_tier refers to unknown tier %q", agentID, tier)
+			}
+		}
+	}
+
+	// Validate timebox formula
+	if c.Timebox.Enabled {
+		if c.Timebox.MinSeconds < 0 || c.Timebox.MaxSeconds < 0 || c.Timebox.BaseSeconds < 0 || c.Timebox.SecondsPerHour < 0 {
+			return fmt.Errorf("timebox seconds must not be negative")
+		}
+		if c.Timebox.MaxSeconds > 0 && c.Timebox.MinSeconds > c.Timebox.MaxSeconds {
+			return fmt.Errorf("timebox.min_seconds %d exceeds timebox.max_seconds %d", c.Timebox.MinSeconds, c.Timebox.MaxSeconds)
+		}
+		for state, factor := range c.Timebox.StateFactors {
+			if factor <= 0 {
+				return fmt.Errorf("timebox.state_factors.%s must be positive", state)
+			}
+		}
+	}
+
+	// Validate staleness thresholds
+	if c.Staleness.AfterHours < 0 {
+		return fmt.Errorf("staleness.after_hours must not be negative")
+	}
+	for state, hours := range c.Staleness.StateHours {
+		if hours <= 0 {
+			return fmt.Errorf("staleness.state_hours.%s must be positive", state)
+		}
+	}
+
+	if c.Artifacts.BlobThresholdBytes < 0 {
+		return fmt.Errorf("artifacts.blob_threshold_bytes must not be negative")
+	}
+
+	// Validate archive
+	if c.Archive.AfterDays < 0 || c.Archive.MinBytes < 0 {
+		return fmt.Errorf("archive.after_days and archive.min_bytes must not be negative")
+	}
+
+	// Validate plan handling
+	switch c.PlanUnavailable {
+	case "", "continue", "pause":
+	default:
+		return fmt.Errorf("invalid plan_unavailable %q: must be continue or pause", c.PlanUnavailable)
+	}
+
+	// Validate custom fields
+	for name, field := range c.CustomFields {
+		if err := field.validate(name); err != nil {
+			return err
+		}
+	}
+
+	// Validate isolation
+	switch c.Isolation.Mode {
+	case "", "none":
+	case "worktree":
+		if c.Isolation.BranchPrefix == "" {
+			return fmt.Errorf("isolation.branch_prefix must not be empty in worktree mode")
+		}
+	default:
+		return fmt.Errorf("invalid isolation.mode %q: must be none or worktree", c.Isolation.Mode)
+	}
+
+	// Validate decomposition
+	switch c.Decomposition.Mode {
+	case "", "offer", "auto":
+	default:
+		return fmt.Errorf("invalid decomposition.mode %q: must be offer or auto", c.Decomposition.Mode)
+	}
+	if c.Decomposition.FailureThreshold < 0 || c.Decomposition.MaxSubtasks < 0 {
+		return fmt.Errorf("decomposition.failure_threshold and decomposition.max_subtasks must not be negative")
+	}
+
+	// Validate escalation
+	if c.ReviewEscalation.MaxFailures < 0 {
+		return fmt.Errorf("review_escalation.max_failures must not be negative")
+	}
+	switch c.ReviewEscalation.State {
+	case "", "blocked", "paused":
+	default:
+		return fmt.Errorf("invalid review_escalation.state %q: must be blocked or paused", c.ReviewEscalation.State)
+	}
+	if channel := c.ReviewEscalation.Channel; channel != "" && channel != "desktop" {
+		if _, ok := c.Notifications.Channels[channel]; !ok {
+			return fmt.Errorf("review_escalation.channel %q is not a configured notification channel", channel)
+		}
+	}
+
+	for _, reqType := range c.Acceptance.MandatoryTypes {
+		switch reqType {
+		case "functional", "nonfunctional", "constraint", "risk", "acceptance":
+		default:
+			return fmt.Errorf("invalid acceptance.mandatory_types entry %q: must be functional, nonfunctional, constraint, risk or acceptance", reqType)
+		}
+	}
+
+	// Validate notification channels
+	for name, channel := range c.Notifications.Channels {
+		switch channel.Type {
+		case "webhook", "slack":
+			if channel.URL == "" {
+				return fmt.Errorf("notifications.channels.%s: %s channel requires a url", name, channel.Type)
+			}
+		case "desktop":
+		default:
+			return fmt.Errorf("notifications.channels.%s: invalid type %q: must be webhook, slack or desktop", name, channel.Type)
+		}
+	}
+
+	// Validate transition hooks
+	for i, hook := range c.Hooks {
+		if hook.From == "" || hook.To == "" {
+			return fmt.Errorf("hooks[%d]: from and to are required (use * for any state)", i)
+		}
+		if (hook.Command == "") == (hook.Webhook == "") {
+			return fmt.Errorf("hooks[%d]: set exactly one of command and webhook", i)
+		}
+		if hook.TimeoutSeconds < 0 {
+			return fmt.Errorf("hooks[%d]: timeout_seconds must not be negative", i)
+		}
+	}
+
+	// Validate cycle hooks
+	for _, list := range []struct {
+		event string
+		hooks []CycleHook
+	}{
+		{"before_selection", c.CycleHooks.BeforeSelection},
+		{"after_success", c.CycleHooks.AfterSuccess},
+	} {
+		event := list.event
+		for i, hook := range list.hooks {
+			if hook.Command == "" {
+				return fmt.Errorf("cycle_hooks.%s[%d]: command is required", event, i)
+			}
+			if hook.TimeoutSeconds < 0 {
+				return fmt.Errorf("cycle_hooks.%s[%d]: timeout_seconds must not be negative", event, i)
+			}
+			switch hook.OnFailure {
+			case "", "block", "annotate":
+			default:
+				return fmt.Errorf("cycle_hooks.%s[%d]: invalid on_failure %q: must be block or annotate", event, i, hook.OnFailure)
+			}
+		}
+	}
+
+	// Validate schedules
+	names := make(map[string]bool)
+	for i, sched := range c.Schedules {
+		if sched.Name == "" {
+			return fmt.Errorf("schedules[%d]: name is required", i)
+		}
+		if names[sched.Name] {
+			return fmt.Errorf("schedules[%d]: duplicate name %q", i, sched.Name)
+		}
+		names[sched.Name] = true
+		if _, err := schedule.ParseCron(sched.Cron); err != nil {
+			return fmt.Errorf("schedules.%s: %w", sched.Name, err)
+		}
+		if sched.MaxCycles < 0 || sched.MaxDurationMinutes < 0 || sched.Parallel < 0 {
+			return fmt.Errorf("schedules.%s: max_cycles, max_duration_minutes and parallel must not be negative", sched.Name)
+		}
+	}
+
+	// Validate pipelines
+	names = make(map[string]bool)
+	for i, pipeline := range c.Pipelines {
+		if pipeline.Name == "" {
+			return fmt.Errorf("pipelines[%d]: name is required", i)
+		}
+		if names[pipeline.Name] {
+			return fmt.Errorf("pipelines[%d]: duplicate name %q", i, pipeline.Name)
+		}
+		names[pipeline.Name] = true
+		if len(pipeline.Stages) < 2 {
+			return fmt.Errorf("pipelines.%s: stages must list at least two agents", pipeline.Name)
+		}
+		for _, agentID := range pipeline.Stages {
+			if _, exists := c.Agents[agentID]; !exists {
+				return fmt.Errorf("pipelines.%s: stage refers to unknown agent %q", pipeline.Name, agentID)
+			}
+		}
+	}
+
+	// Validate transition rules
+	for i, rule := range c.TransitionRules {
+		if rule.From == "" || rule.To == "" {
+			return fmt.Errorf("transition_rules[%d]: from and to are required (use * for any state)", i)
+		}
+		if rule.Artifact == "" || rule.Field == "" {
+			return fmt.Errorf("transition_rules[%d]: artifact and field are required", i)
+		}
+		if err := rule.validateCondition(); err != nil {
+			return fmt.Errorf("transition_rules[%d]: %w", i, err)
+		}
+	}
+
+	// Validate hosted projects
+	for name, project := range c.Projects {
+		if !validProjectName.MatchString(name) {
+			return fmt.Errorf("invalid project name %q: use letters, digits, '-' and '_'", name)
+		}
+		if project.Config == "" || project.TokenEnv == "" {
+			return fmt.Errorf("projects.%s needs both config and token_env", name)
+		}
+	}
+
+	// Validate search embedding provider
+	switch c.Search.EmbeddingProvider {
+	case "", "local", "api":
+	default:
+		return fmt.Errorf("invalid search.embedding_provider %q: must be local or api", c.Search.EmbeddingProvider)
+	}
+
 	return nil
 }
 
+// MCPToken returns the bearer token the HTTP MCP server requires: the
+// mcp.token_env variable when it is set, otherwise mcp.token, otherwise the
+// token EnsureMCPToken generated into the workspace. "" means none.
+func (c *Config) MCPToken() string {
+	if c.MCP.InsecureNoToken {
+		return ""
+	}
+	if c.MCP.TokenEnv != "" {
+		if token := os.Getenv(c.MCP.TokenEnv); token != "" {
+			return token
+		}
+	}
+	if c.MCP.Token != "" {
+		return c.MCP.Token
+	}
+	if data, err := os.ReadFile(c.MCPTokenFile()); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+// EnsureMCPToken returns MCPToken, first generating a token into
+// MCPTokenFile when none is configured, so the MCP server never runs open by
+// accident. It returns "" only with mcp.insecure_no_token set.
+func (c *Config) EnsureMCPToken() (string, error) {
+	if c.MCP.InsecureNoToken {
+		return "", nil
+	}
+	if token := c.MCPToken(); token != "" {
+		return token, nil
+	}
+
+	token, err := NewMCPToken()
+	if err != nil {
+		return "", err
+	}
+	path := c.MCPTokenFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	// Readable by its owner only, like the token baton init writes to baton.yaml
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write MCP token: %w", err)
+	}
+	return token, nil
+}
+
+// MCPTokenFile is where EnsureMCPToken keeps a generated token
+func (c *Config) MCPTokenFile() string {
+	return filepath.Join(c.Workspace, ".baton", "mcp-token")
+}
+
+// NewMCPToken generates a random MCP bearer token
+func NewMCPToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate MCP token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// DatabaseKey returns the key the database is encrypted with: the
+// database_key_env variable when it is set, otherwise the system keychain's
+// entry for the database file. "" means database_encryption is off.
+func (c *Config) DatabaseKey() (string, error) {
+	if !c.DatabaseEncryption {
+		return "", nil
+	}
+	if c.DatabaseKeyEnv != "" {
+		if key := os.Getenv(c.DatabaseKeyEnv); key != "" {
+			return key, nil
+		}
+	}
+	key, err := keychainKey(c.Database)
+	if err != nil {
+		return "", fmt.Errorf("database_encryption is on but there is no key: set $%s or add one to the keychain under service %q, account %q (%v)",
+			c.DatabaseKeyEnv, keychainService, c.Database, err)
+	}
+	return key, nil
+}
+
+// Location returns the timezone timestamps are displayed in, falling back to
+// the system's when the configuration was not validated
+func (c *Config) Location() *time.Location {
+	if c.location != nil {
+		return c.location
+	}
+	if location, err := time.LoadLocation(c.Timezone); err == nil {
+		return location
+	}
+	return time.Local
+}
+
+// PromptTemplatePath returns the file of an agent's prompt template, or ""
+// when the agent has none. Relative names are looked up in prompts_dir.
+func (c *Config) PromptTemplatePath(agent *Agent) string {
+	name := agent.RoutingPolicy.PromptTemplate
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(c.PromptsDir, name)
+}
+
+// AgentForState returns the agent that handles a state. Agents are checked
+// in ID order so overlapping allowed_states resolve deterministically; when
+// none covers the state the default agent, if configured, is returned.
+func (c *Config) AgentForState(state string) (*Agent, bool) {
+	agentID, ok := c.AgentIDForState(state)
+	if !ok {
+		return nil, false
+	}
+	agent := c.Agents[agentID]
+	return &agent, true
+}
+
+// AgentIDForState returns the ID of the agent AgentForState would return
+func (c *Config) AgentIDForState(state string) (string, bool) {
+	agentIDs := make([]string, 0, len(c.Agents))
+	for agentID := range c.Agents {
+		agentIDs = append(agentIDs, agentID)
+	}
+	sort.Strings(agentIDs)
+
+	for _, agentID := range agentIDs {
+		for _, allowedState := range c.Agents[agentID].AllowedStates {
+			if allowedState == state {
+				return agentID, true
+			}
+		}
+	}
+
+	if _, exists := c.Agents[c.DefaultAgent]; exists && c.DefaultAgent != "" {
+		return c.DefaultAgent, true
+	}
+
+	return "", false
+}
+
+// StartingTier returns the model tier a cycle in the given state starts on:
+// the state's tier, else the agent's, else the default; "" means no tiering
+func (c *Config) StartingTier(state string, agent *Agent) string {
+	if tier, exists := c.LLM.StateTiers[state]; exists {
+		return tier
+	}
+	if agent != nil && agent.RoutingPolicy.ModelTier != "" {
+		return agent.RoutingPolicy.ModelTier
+	}
+	return c.LLM.DefaultTier
+}
+
+// EscalationPath returns the tiers a cycle may be served by, starting with
+// the given tier and climbing tier_order
+func (c *Config) EscalationPath(start string) []string {
+	if start == "" {
+		return nil
+	}
+	for i, tier := range c.LLM.TierOrder {
+		if tier == start {
+			return append([]string(nil), c.LLM.TierOrder[i:]...)
+		}
+	}
+	return []string{start}
+}
+
+// UncoveredStates returns the states that no agent lists in allowed_states,
+// whether or not a default agent will pick them up
+func (c *Config) UncoveredStates(states []string) []string {
+	covered := make(map[string]bool)
+	for _, agent := range c.Agents {
+		for _, allowedState := range agent.AllowedStates {
+			covered[allowedState] = true
+		}
+	}
+
+	var uncovered []string
+	for _, state := range states {
+		if !covered[state] {
+			uncovered = append(uncovered, state)
+		}
+	}
+	return uncovered
+}
+
+// DefaultTimeboxStateFactors gives planning and review shorter cycles than implementation
+func DefaultTimeboxStateFactors() map[string]float64 {
+	return map[string]float64{
+		"planning":     0.5,
+		"implementing": 1.5,
+		"reviewing":    0.5,
+		"fixing":       1.0,
+		"committing":   0.25,
+	}
+}
+
+// DefaultStalenessStates are the work states watched for stuck tasks
+func DefaultStalenessStates() []string {
+	return []string{"planning", "implementing", "reviewing", "committing", "fixing"}
+}
+
 // CreateDefaultConfig creates a default configuration file
 func CreateDefaultConfig(path string) error {
 	config := getDefaultConfig()
@@ -198,25 +1158,67 @@ func CreateDefaultConfig(path string) error {
 // setDefaults sets default configuration values
 func setDefaults(v *viper.Viper) {
 	v.SetDefault("plan_file", "./plan.md")
+	v.SetDefault("plan_unavailable", "continue")
 	v.SetDefault("workspace", "./")
 	v.SetDefault("database", "./baton.db")
+	v.SetDefault("database_encryption", false)
+	v.SetDefault("database_key_env", "BATON_DATABASE_KEY")
+	v.SetDefault("prompts_dir", "./prompts")
 	v.SetDefault("mcp_port", 8080)
+	v.SetDefault("mcp.token", "")
+	v.SetDefault("mcp.token_env", "BATON_MCP_TOKEN")
+	v.SetDefault("mcp.allow_remote", false)
+	v.SetDefault("mcp.insecure_no_token", false)
+	v.SetDefault("timezone", "Local")
+	v.SetDefault("default_agent", "")
 
 	// LLM defaults
 	v.SetDefault("llm.primary", "claude")
 	v.SetDefault("llm.timeout_seconds", 300)
 	v.SetDefault("llm.max_retries", 1)
+	v.SetDefault("llm.retry_backoff_seconds", 2)
+	v.SetDefault("llm.retry_max_backoff_seconds", 30)
+	v.SetDefault("llm.rate_limit.requests_per_minute", 0)
+	v.SetDefault("llm.rate_limit.max_concurrent", 0)
 	v.SetDefault("llm.claude.command", "claude")
 	v.SetDefault("llm.claude.headless_args", []string{"-p"})
 	v.SetDefault("llm.claude.output_format", "stream-json")
 	v.SetDefault("llm.claude.mcp_connect", true)
+	v.SetDefault("llm.openai.model", "gpt-4o")
+	v.SetDefault("llm.openai.base_url", "https://api.openai.com/v1")
+	v.SetDefault("llm.openai.api_key_env", "OPENAI_API_KEY")
+	v.SetDefault("llm.openai_compatible.base_url", "")
+	v.SetDefault("llm.openai_compatible.headers", map[string]string{})
+	v.SetDefault("llm.gemini.model", "gemini-2.5-pro")
+	v.SetDefault("llm.gemini.base_url", "https://generativelanguage.googleapis.com/v1beta")
+	v.SetDefault("llm.gemini.api_key_env", "GEMINI_API_KEY")
+	v.SetDefault("llm.ollama.host", "http://localhost:11434")
+	v.SetDefault("llm.ollama.model", "llama3.1")
+	v.SetDefault("llm.ollama.mcp_tools", true)
+	v.SetDefault("llm.ollama.max_tool_calls", 20)
+	v.SetDefault("llm.escalation.on_failure", true)
+	v.SetDefault("llm.escalation.on_low_confidence", true)
+	v.SetDefault("llm.budget_usd_per_cycle", 0)
+	v.SetDefault("llm.budget_usd_per_day", 0)
 
 	// Selection defaults
 	v.SetDefault("selection.algorithm", "priority_dependency")
 	v.SetDefault("selection.priority_weight", 1.0)
+	v.SetDefault("selection.age_weight", 0.3)
+	v.SetDefault("selection.estimate_weight", 0.2)
+	v.SetDefault("selection.fanout_weight", 0.3)
+	v.SetDefault("selection.milestone_weight", 0.2)
+	v.SetDefault("selection.deadline_weight", 0.5)
 	v.SetDefault("selection.dependency_strict", true)
 	v.SetDefault("selection.prefer_leaf_tasks", true)
 	v.SetDefault("selection.tie_breaker", "oldest_updated")
+	v.SetDefault("selection.skip_unassigned_states", false)
+	v.SetDefault("selection.area_locks.enabled", false)
+	v.SetDefault("selection.area_locks.states", []string{"implementing", "fixing"})
+	v.SetDefault("selection.area_locks.ttl_minutes", 120)
+	v.SetDefault("selection.milestone_signoff", true)
+	v.SetDefault("selection.milestone_order", []string{})
+	v.SetDefault("selection.wip_limits", map[string]int{})
 
 	// Completion defaults
 	v.SetDefault("completion.max_retries", 2)
@@ -224,6 +1226,62 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("completion.timeout_seconds", 600)
 	v.SetDefault("completion.require_explicit_state_update", true)
 	v.SetDefault("completion.follow_up_template", "Are you finished? The state is not updated. Please either update the task state or provide a structured outcome with reason and next state.")
+	v.SetDefault("completion.low_confidence_threshold", 0.5)
+	v.SetDefault("completion.max_auto_retries", 0)
+
+	// Search defaults
+	v.SetDefault("search.embedding_provider", "local")
+	v.SetDefault("search.embedding_endpoint", "https://api.openai.com/v1/embeddings")
+	v.SetDefault("search.embedding_model", "text-embedding-3-small")
+	v.SetDefault("search.api_key_env", "OPENAI_API_KEY")
+
+	// Timebox defaults
+	v.SetDefault("timebox.enabled", false)
+	v.SetDefault("timebox.base_seconds", 300)
+	v.SetDefault("timebox.seconds_per_hour", 600)
+	v.SetDefault("timebox.default_estimate_hours", 2)
+	v.SetDefault("timebox.state_factors", DefaultTimeboxStateFactors())
+	v.SetDefault("timebox.min_seconds", 300)
+	v.SetDefault("timebox.max_seconds", 7200)
+
+	// Staleness defaults
+	v.SetDefault("staleness.after_hours", 24)
+	v.SetDefault("staleness.state_hours", map[string]float64{})
+	v.SetDefault("staleness.states", DefaultStalenessStates())
+
+	// Artifact file defaults
+	v.SetDefault("artifacts.materialize", false)
+	v.SetDefault("artifacts.dir", "claudedocs/tasks")
+	v.SetDefault("artifacts.blob_threshold_bytes", 262144)
+
+	// Archive defaults
+	v.SetDefault("archive.after_days", 30)
+	v.SetDefault("archive.min_bytes", 2048)
+
+	// Isolation defaults
+	v.SetDefault("isolation.mode", "none")
+	v.SetDefault("isolation.dir", "")
+	v.SetDefault("isolation.branch_prefix", "baton/")
+	v.SetDefault("isolation.keep_failed", false)
+
+	// Decomposition defaults
+	v.SetDefault("decomposition.failure_threshold", 3)
+	v.SetDefault("decomposition.mode", "offer")
+	v.SetDefault("decomposition.max_subtasks", 5)
+
+	// Escalation defaults
+	v.SetDefault("review_escalation.max_failures", 0)
+	v.SetDefault("review_escalation.state", "blocked")
+	v.SetDefault("review_escalation.channel", "")
+
+	// Acceptance defaults
+	v.SetDefault("acceptance.mandatory_types", []string{"functional"})
+
+	// Notification defaults
+	v.SetDefault("notifications.default_channel", "desktop")
+
+	// Web defaults
+	v.SetDefault("web.read_only", false)
 
 	// Security defaults
 	v.SetDefault("security.allowed_commands", []string{"git", "npm", "go", "python", "pytest", "cargo", "make"})
@@ -241,4 +1299,5 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("development.dry_run_default", false)
 	v.SetDefault("development.debug_mcp", false)
 	v.SetDefault("development.cycle_timebox_seconds", 3600)
+	v.SetDefault("development.record_transcripts", false)
 }
\ No newline at end of file
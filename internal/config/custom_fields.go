@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validCustomFieldName matches custom field names; config keys are lowercased
+var validCustomFieldName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// reservedCustomFieldNames are built-in task fields and CSV columns
+var reservedCustomFieldNames = map[string]bool{
+	"id": true, "title": true, "description": true, "state": true, "priority": true,
+	"owner": true, "milestone": true, "tags": true, "dependencies": true, "estimated_hours": true,
+	"due_date": true,
+}
+
+// CustomFields are the configured custom fields, by name
+type CustomFields map[string]CustomField
+
+// CustomField defines an extra piece of task metadata, such as story points
+// or a customer
+type CustomField struct {
+	Type        string   `yaml:"type" mapstructure:"type" json:"type"`                 // string, number, boolean or enum
+	Values      []string `yaml:"values" mapstructure:"values" json:"values,omitempty"` // allowed values of an enum
+	Description string   `yaml:"description" mapstructure:"description" json:"description,omitempty"`
+}
+
+// validate checks a field definition
+func (f CustomField) validate(name string) error {
+	if !validCustomFieldName.MatchString(name) {
+		return fmt.Errorf("invalid custom field name %q: use lowercase letters, digits and '_'", name)
+	}
+	if reservedCustomFieldNames[name] {
+		return fmt.Errorf("custom field %q clashes with a built-in task field", name)
+	}
+	switch f.Type {
+	case "string", "number", "boolean":
+	case "enum":
+		if len(f.Values) == 0 {
+			return fmt.Errorf("custom_fields.%s: enum field requires values", name)
+		}
+	default:
+		return fmt.Errorf("custom_fields.%s: invalid type %q: must be string, number, boolean or enum", name, f.Type)
+	}
+	return nil
+}
+
+// ParseValue converts text, e.g. from a command line or a CSV cell, to a
+// value of the field's type
+func (f CustomField) ParseValue(text string) (interface{}, error) {
+	switch f.Type {
+	case "number":
+		n, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", text)
+		}
+		return b, nil
+	case "enum":
+		for _, allowed := range f.Values {
+			if text == allowed {
+				return text, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of %s", text, strings.Join(f.Values, ", "))
+	}
+	return text, nil
+}
+
+// CheckValue checks a decoded JSON value against the field's type. Strings
+// are parsed as ParseValue does, so "5" is accepted for a number field.
+func (f CustomField) CheckValue(value interface{}) (interface{}, error) {
+	if text, ok := value.(string); ok {
+		return f.ParseValue(text)
+	}
+	switch value := value.(type) {
+	case float64:
+		if f.Type == "number" {
+			return value, nil
+		}
+	case int:
+		if f.Type == "number" {
+			return float64(value), nil
+		}
+	case bool:
+		if f.Type == "boolean" {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("%v is not a valid %s value", value, f.Type)
+}
+
+// Value checks a value for the named field and returns it in the field's
+// type. A nil value, which clears the field, is returned as is.
+func (fields CustomFields) Value(name string, value interface{}) (interface{}, error) {
+	field, ok := fields[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown custom field %q", name)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	value, err := field.CheckValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("custom field %s: %w", name, err)
+	}
+	return value, nil
+}
+
+// Names returns the field names in order
+func (fields CustomFields) Names() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -4,13 +4,19 @@ package config
 func getDefaultConfig() *Config {
 	return &Config{
 		PlanFile:  "./plan.md",
+		PlanUnavailable: "continue",
 		Workspace: "./",
 		Database:  "./baton.db",
+		DatabaseKeyEnv: "BATON_DATABASE_KEY",
+		PromptsDir: "./prompts",
 		MCPPort:   8080,
+		Timezone:  "Local",
 		LLM: LLMConfig{
 			Primary:        "claude",
 			TimeoutSeconds: 300,
 			MaxRetries:     1,
+			RetryBackoffSeconds:    2,
+			RetryMaxBackoffSeconds: 30,
 			Claude: ClaudeConfig{
 				Command:      "claude",
 				HeadlessArgs: []string{"-p"},
@@ -18,8 +24,24 @@ func getDefaultConfig() *Config {
 				MCPConnect:   true,
 			},
 			OpenAI: OpenAIConfig{
-				Command:      "openai",
-				HeadlessArgs: []string{"--non-interactive"},
+				Model:     "gpt-4o",
+				BaseURL:   "https://api.openai.com/v1",
+				APIKeyEnv: "OPENAI_API_KEY",
+			},
+			Ollama: OllamaConfig{
+				Host:         "http://localhost:11434",
+				Model:        "llama3.1",
+				MCPTools:     true,
+				MaxToolCalls: 20,
+			},
+			Gemini: GeminiConfig{
+				Model:     "gemini-2.5-pro",
+				BaseURL:   "https://generativelanguage.googleapis.com/v1beta",
+				APIKeyEnv: "GEMINI_API_KEY",
+			},
+			Escalation: EscalationPolicy{
+				OnFailure:       true,
+				OnLowConfidence: true,
 			},
 		},
 		Agents: map[string]Agent{
@@ -34,6 +56,7 @@ func getDefaultConfig() *Config {
 				Permissions: AgentPermissions{
 					CanReadPlan:        true,
 					CanUpdateArtifacts: true,
+					CanCreateTasks:     true,
 					CanTransitionTo:    []string{"planning", "ready_for_implementation"},
 				},
 			},
@@ -49,6 +72,7 @@ func getDefaultConfig() *Config {
 					CanReadPlan:         true,
 					CanExecuteCommands:  true,
 					CanUpdateArtifacts:  true,
+					CanCreateTasks:      true,
 					CanTransitionTo:     []string{"implementing", "ready_for_code_review", "needs_fixes"},
 				},
 			},
@@ -70,9 +94,19 @@ func getDefaultConfig() *Config {
 		Selection: SelectionConfig{
 			Algorithm:        "priority_dependency",
 			PriorityWeight:   1.0,
+			AgeWeight:        0.3,
+			EstimateWeight:   0.2,
+			FanOutWeight:     0.3,
+			MilestoneWeight:  0.2,
+			DeadlineWeight:   0.5,
 			DependencyStrict: true,
 			PreferLeafTasks:  true,
 			TieBreaker:       "oldest_updated",
+			AreaLocks: AreaLocksConfig{
+				States:     []string{"implementing", "fixing"},
+				TTLMinutes: 120,
+			},
+			MilestoneSignoff: true,
 		},
 		Completion: CompletionConfig{
 			MaxRetries:                  2,
@@ -80,6 +114,7 @@ func getDefaultConfig() *Config {
 			TimeoutSeconds:             600,
 			RequireExplicitStateUpdate: true,
 			FollowUpTemplate:           "Are you finished? The state is not updated. Please either update the task state or provide a structured outcome with reason and next state.",
+			LowConfidenceThreshold:     0.5,
 		},
 		Security: SecurityConfig{
 			AllowedCommands:      []string{"git", "npm", "go", "python", "pytest", "cargo", "make"},
@@ -93,10 +128,62 @@ func getDefaultConfig() *Config {
 			File:               "baton.log",
 			AuditRetentionDays: 90,
 		},
+		Search: SearchConfig{
+			EmbeddingProvider: "local",
+			EmbeddingEndpoint: "https://api.openai.com/v1/embeddings",
+			EmbeddingModel:    "text-embedding-3-small",
+			APIKeyEnv:         "OPENAI_API_KEY",
+		},
+		Timebox: TimeboxConfig{
+			Enabled:              false,
+			BaseSeconds:          300,
+			SecondsPerHour:       600,
+			DefaultEstimateHours: 2,
+			StateFactors:         DefaultTimeboxStateFactors(),
+			MinSeconds:           300,
+			MaxSeconds:           7200,
+		},
+		Staleness: StalenessConfig{
+			AfterHours: 24,
+			StateHours: map[string]float64{},
+			States:     DefaultStalenessStates(),
+		},
+		Artifacts: ArtifactsConfig{
+			Materialize:        false,
+			Dir:                "claudedocs/tasks",
+			BlobThresholdBytes: 262144,
+		},
+		Archive: ArchiveConfig{
+			AfterDays: 30,
+			MinBytes:  2048,
+		},
+		Isolation: IsolationConfig{
+			Mode:         "none",
+			BranchPrefix: "baton/",
+		},
+		Decomposition: DecompositionConfig{
+			FailureThreshold: 3,
+			Mode:             "offer",
+			MaxSubtasks:      5,
+		},
+		ReviewEscalation: ReviewEscalationConfig{
+			MaxFailures: 0,
+			State:       "blocked",
+		},
+		Acceptance: AcceptanceConfig{
+			MandatoryTypes: []string{"functional"},
+		},
+		Notifications: NotificationsConfig{
+			DefaultChannel: "desktop",
+		},
+		Web: WebConfig{
+			ReadOnly: false,
+		},
 		Development: DevelopmentConfig{
 			DryRunDefault:       false,
 			DebugMCP:            false,
 			CycleTimeboxSeconds: 3600,
+			RecordTranscripts:   false,
 		},
 	}
 }
\ No newline at end of file
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keychainService is the keychain service database keys are stored under,
+// one entry per database file
+const keychainService = "baton"
+
+// keychainKey reads the key stored for account from the macOS keychain or,
+// on Linux, the Secret Service (secret-tool)
+func keychainKey(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", account, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", account)
+	default:
+		return "", fmt.Errorf("no keychain support on %s", runtime.GOOS)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keychain lookup failed: %w", err)
+	}
+	key := strings.TrimRight(string(out), "\r\n")
+	if key == "" {
+		return "", fmt.Errorf("the keychain entry is empty")
+	}
+	return key, nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TransitionRule allows a transition only when a field of a handover
+// artifact's JSON metadata satisfies a condition, e.g. reviewing ->
+// ready_for_commit only when review_findings has approved: true. The artifact
+// becomes required for the transition.
+type TransitionRule struct {
+	From     string      `yaml:"from" mapstructure:"from"`         // state left, or * for any
+	To       string      `yaml:"to" mapstructure:"to"`             // state entered, or * for any
+	Artifact string      `yaml:"artifact" mapstructure:"artifact"` // handover artifact whose metadata is checked
+	Field    string      `yaml:"field" mapstructure:"field"`       // metadata field, with dots for nested objects
+	Op       string      `yaml:"op" mapstructure:"op"`             // eq (default), ne, gt, gte, lt, lte or exists
+	Value    interface{} `yaml:"value" mapstructure:"value"`       // compared with the field; unused by exists
+	Message  string      `yaml:"message" mapstructure:"message"`   // shown when the rule refuses a transition
+}
+
+// TransitionRuleOps lists the valid transition rule operators
+var TransitionRuleOps = []string{"eq", "ne", "gt", "gte", "lt", "lte", "exists"}
+
+// Matches reports whether the rule applies when a task moves from one state to another
+func (r TransitionRule) Matches(from, to string) bool {
+	return (r.From == "*" || r.From == from) && (r.To == "*" || r.To == to)
+}
+
+// Operator is the rule's operator, eq when none is set
+func (r TransitionRule) Operator() string {
+	if r.Op == "" {
+		return "eq"
+	}
+	return r.Op
+}
+
+// Condition describes what the rule requires, e.g. "review_findings meta.approved eq true"
+func (r TransitionRule) Condition() string {
+	if r.Operator() == "exists" {
+		return fmt.Sprintf("%s meta.%s exists", r.Artifact, r.Field)
+	}
+	return fmt.Sprintf("%s meta.%s %s %v", r.Artifact, r.Field, r.Operator(), r.Value)
+}
+
+// Satisfied reports whether a metadata field value meets the rule; present
+// is false when the metadata has no such field. Numbers compare by value,
+// other values only equal values of the same type.
+func (r TransitionRule) Satisfied(value interface{}, present bool) bool {
+	op := r.Operator()
+	if op == "exists" {
+		return present
+	}
+	if !present {
+		return op == "ne"
+	}
+
+	got, gotNumber := ruleNumber(value)
+	want, wantNumber := ruleNumber(r.Value)
+	switch op {
+	case "eq", "ne":
+		var equal bool
+		switch value.(type) {
+		case string, bool:
+			equal = value == r.Value
+		default: // objects and lists never equal a configured value
+			equal = gotNumber && wantNumber && got == want
+		}
+		return equal == (op == "eq")
+	}
+	if !gotNumber || !wantNumber {
+		return false
+	}
+	switch op {
+	case "gt":
+		return got > want
+	case "gte":
+		return got >= want
+	case "lt":
+		return got < want
+	default: // lte
+		return got <= want
+	}
+}
+
+// validateCondition checks the rule's operator and value
+func (r TransitionRule) validateCondition() error {
+	op := r.Operator()
+	if !contains(TransitionRuleOps, op) {
+		return fmt.Errorf("invalid op %q: must be one of %s", r.Op, strings.Join(TransitionRuleOps, ", "))
+	}
+	if op == "exists" {
+		return nil
+	}
+	switch r.Value.(type) {
+	case nil:
+		return fmt.Errorf("op %s needs a value", op)
+	case string, bool:
+	default:
+		if _, ok := ruleNumber(r.Value); !ok {
+			return fmt.Errorf("value must be a string, number or boolean")
+		}
+	}
+	if _, ok := ruleNumber(r.Value); !ok && op != "eq" && op != "ne" {
+		return fmt.Errorf("op %s needs a numeric value", op)
+	}
+	return nil
+}
+
+// ruleNumber converts the numbers YAML and JSON decode to float64
+func ruleNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
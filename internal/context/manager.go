@@ -1,6 +1,7 @@
 package context
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -118,7 +119,7 @@ Focus on being specific and actionable for an AI assistant working on this codeb
 		strings.Join(projectContext.Requirements, ", "),
 		strings.Join(projectContext.Constraints, ", "))
 
-	content, err := m.llmClient.GenerateText(prompt)
+	content, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return err
 	}
@@ -152,7 +153,7 @@ Include specific, measurable, and testable requirements that developers can impl
 		projectContext.Vision,
 		strings.Join(projectContext.Requirements, ", "))
 
-	content, err := m.llmClient.GenerateText(prompt)
+	content, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return err
 	}
@@ -187,7 +188,7 @@ Include diagrams in ASCII art or mermaid format where helpful.`,
 		projectContext.Architecture,
 		strings.Join(projectContext.TechStack, ", "))
 
-	content, err := m.llmClient.GenerateText(prompt)
+	content, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return err
 	}
@@ -220,7 +221,7 @@ Focus on patterns that should be consistently followed across the codebase.`,
 		projectContext.Name,
 		strings.Join(projectContext.TechStack, ", "))
 
-	content, err := m.llmClient.GenerateText(prompt)
+	content, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return err
 	}
@@ -250,7 +251,7 @@ Format as a gitignore-style file with comments explaining each section.
 Be specific to the tech stack being used.`,
 		strings.Join(projectContext.TechStack, ", "))
 
-	content, err := m.llmClient.GenerateText(prompt)
+	content, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return err
 	}
@@ -283,7 +284,7 @@ Focus on practical guidance developers can immediately use.`,
 		projectContext.Name,
 		strings.Join(projectContext.TechStack, ", "))
 
-	content, err := m.llmClient.GenerateText(prompt)
+	content, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return err
 	}
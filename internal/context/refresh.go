@@ -0,0 +1,202 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileUpdate is a proposed new content for one context file
+type FileUpdate struct {
+	Path    string // relative to the workspace
+	Reason  string
+	Content string
+}
+
+// RefreshProposal is the set of context file updates for a tech stack change
+type RefreshProposal struct {
+	Drift   *StackDrift
+	Updates []FileUpdate
+	Skipped []string // updates that could not be prepared, with the reason
+}
+
+// ProposeRefresh prepares context file updates for the technologies drift
+// added: new .claudeignore entries, a STYLE_GUIDE.md section per technology
+// and regenerated developer and tester subagents. Nothing is written until
+// the proposal is applied. Without an LLM client only .claudeignore is
+// proposed.
+func (m *Manager) ProposeRefresh(drift *StackDrift) (*RefreshProposal, error) {
+	proposal := &RefreshProposal{Drift: drift}
+	if len(drift.Added) == 0 {
+		return proposal, nil
+	}
+
+	ignore, ok, err := m.proposeClaudeIgnore(drift.Added)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		proposal.Updates = append(proposal.Updates, ignore)
+	}
+
+	if m.llmClient == nil {
+		proposal.Skipped = append(proposal.Skipped,
+			"STYLE_GUIDE.md: no LLM client available",
+			"developer and tester subagents: no LLM client available")
+		return proposal, nil
+	}
+
+	style, ok, err := m.proposeStyleGuide(drift.Added, drift.Detected)
+	if err != nil {
+		proposal.Skipped = append(proposal.Skipped, fmt.Sprintf("STYLE_GUIDE.md: %v", err))
+	} else if ok {
+		proposal.Updates = append(proposal.Updates, style)
+	}
+
+	projectContext := &ProjectContext{
+		Name:      m.projectName(),
+		TechStack: drift.Detected,
+	}
+	for _, agentType := range []SubagentType{DeveloperAgent, TesterAgent} {
+		spec, err := m.generateSubagentSpec(agentType, projectContext)
+		if err != nil {
+			proposal.Skipped = append(proposal.Skipped, fmt.Sprintf("%s subagent: %v", agentType, err))
+			continue
+		}
+		proposal.Updates = append(proposal.Updates, FileUpdate{
+			Path:    filepath.Join(".claude", "subagents", spec.Name+".md"),
+			Reason:  "regenerated for " + strings.Join(drift.Detected, ", "),
+			Content: subagentFileContent(spec),
+		})
+	}
+
+	return proposal, nil
+}
+
+// Apply writes the proposed files and records the detected stack, so the
+// same drift is not proposed again. When some updates were skipped the stack
+// is left unrecorded and the next check proposes them again.
+func (m *Manager) Apply(proposal *RefreshProposal) error {
+	for _, update := range proposal.Updates {
+		path := filepath.Join(m.workspaceDir, update.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(update.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", update.Path, err)
+		}
+	}
+	if len(proposal.Skipped) > 0 {
+		return nil
+	}
+	return SaveStackRecord(m.workspaceDir, proposal.Drift.Detected)
+}
+
+// proposeClaudeIgnore appends the ignore patterns of added technologies that
+// .claudeignore lacks
+func (m *Manager) proposeClaudeIgnore(added []string) (FileUpdate, bool, error) {
+	current, err := readOptional(filepath.Join(m.workspaceDir, ".claudeignore"))
+	if err != nil {
+		return FileUpdate{}, false, err
+	}
+
+	present := make(map[string]bool)
+	for _, line := range strings.Split(current, "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+
+	var section strings.Builder
+	var techs []string
+	for _, tech := range added {
+		var missing []string
+		for _, pattern := range ignorePatterns[tech] {
+			if !present[pattern] {
+				missing = append(missing, pattern)
+				present[pattern] = true
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		techs = append(techs, tech)
+		fmt.Fprintf(&section, "\n# %s\n%s\n", tech, strings.Join(missing, "\n"))
+	}
+	if len(techs) == 0 {
+		return FileUpdate{}, false, nil
+	}
+
+	content := current
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return FileUpdate{
+		Path:    ".claudeignore",
+		Reason:  "ignore build output of " + strings.Join(techs, ", "),
+		Content: content + section.String(),
+	}, true, nil
+}
+
+// proposeStyleGuide appends a conventions section for each added technology
+// STYLE_GUIDE.md has no section for yet
+func (m *Manager) proposeStyleGuide(added, stack []string) (FileUpdate, bool, error) {
+	current, err := readOptional(filepath.Join(m.workspaceDir, "STYLE_GUIDE.md"))
+	if err != nil {
+		return FileUpdate{}, false, err
+	}
+
+	content := current
+	var techs []string
+	for _, tech := range added {
+		heading := fmt.Sprintf("## %s Conventions", tech)
+		if strings.Contains(strings.ToLower(current), strings.ToLower(heading)) {
+			continue
+		}
+
+		prompt := fmt.Sprintf(`The project %s now also uses %s. Its current stack is: %s.
+
+Write a STYLE_GUIDE.md section for %s code in this project covering naming,
+file organization, error handling and testing conventions, with short examples.
+Start with the heading "%s" and use only level-3 headings below it.
+Output only the markdown section.`,
+			m.projectName(), tech, strings.Join(stack, ", "), tech, heading)
+
+		section, err := m.llmClient.GenerateText(context.Background(), prompt)
+		if err != nil {
+			return FileUpdate{}, false, err
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += "\n" + strings.TrimSpace(section) + "\n"
+		techs = append(techs, tech)
+	}
+	if len(techs) == 0 {
+		return FileUpdate{}, false, nil
+	}
+
+	return FileUpdate{
+		Path:    "STYLE_GUIDE.md",
+		Reason:  "add conventions for " + strings.Join(techs, ", "),
+		Content: content,
+	}, true, nil
+}
+
+// projectName names the project after its workspace directory
+func (m *Manager) projectName() string {
+	abs, err := filepath.Abs(m.workspaceDir)
+	if err != nil {
+		return m.workspaceDir
+	}
+	return filepath.Base(abs)
+}
+
+// readOptional reads a file, treating a missing file as empty
+func readOptional(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
@@ -0,0 +1,233 @@
+package context
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stackFile records the tech stack the context files were last generated for
+const stackFile = ".claude/stack.json"
+
+// maxStackDepth bounds how deep DetectStack looks for manifest files
+const maxStackDepth = 3
+
+// skippedDirs are never searched for manifests
+var skippedDirs = map[string]bool{
+	".git":         true,
+	".baton":       true,
+	".claude":      true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	".next":        true,
+	"venv":         true,
+	".venv":        true,
+}
+
+// manifestStacks maps manifest file names to the technology they signal
+var manifestStacks = map[string]string{
+	"go.mod":           "go",
+	"package.json":     "node.js",
+	"tsconfig.json":    "typescript",
+	"requirements.txt": "python",
+	"pyproject.toml":   "python",
+	"Pipfile":          "python",
+	"Cargo.toml":       "rust",
+	"pom.xml":          "java",
+	"build.gradle":     "java",
+	"build.gradle.kts": "kotlin",
+	"Gemfile":          "ruby",
+	"composer.json":    "php",
+	"Dockerfile":       "docker",
+}
+
+// frameworkMarkers maps a dependency name, as it appears in a manifest, to the
+// framework it signals. Markers are looked up only in the manifests listed.
+var frameworkMarkers = map[string]map[string]string{
+	"package.json": {
+		`"react"`:         "react",
+		`"next"`:          "next.js",
+		`"vue"`:           "vue",
+		`"@angular/core"`: "angular",
+		`"svelte"`:        "svelte",
+		`"express"`:       "express",
+		`"typescript"`:    "typescript",
+	},
+	"requirements.txt": {"django": "django", "flask": "flask", "fastapi": "fastapi"},
+	"pyproject.toml":   {"django": "django", "flask": "flask", "fastapi": "fastapi"},
+	"go.mod": {
+		"github.com/gin-gonic/gin": "gin",
+		"github.com/gofiber/fiber": "fiber",
+	},
+	"Cargo.toml": {"actix-web": "actix", "axum": "axum"},
+}
+
+// ignorePatterns are .claudeignore entries for each technology
+var ignorePatterns = map[string][]string{
+	"go":         {"vendor/", "*.test", "coverage.out"},
+	"node.js":    {"node_modules/", "npm-debug.log*", "coverage/"},
+	"typescript": {"*.tsbuildinfo"},
+	"next.js":    {".next/", "out/"},
+	"react":      {"build/"},
+	"vue":        {"dist/"},
+	"angular":    {".angular/", "dist/"},
+	"svelte":     {".svelte-kit/"},
+	"python":     {"__pycache__/", "*.pyc", ".venv/", "venv/", ".pytest_cache/"},
+	"rust":       {"target/"},
+	"java":       {"target/", "build/", "*.class"},
+	"kotlin":     {"build/", ".gradle/"},
+	"ruby":       {".bundle/", "vendor/bundle/"},
+	"php":        {"vendor/"},
+	"docker":     {".docker/"},
+}
+
+// StackRecord is the tech stack saved alongside the context files
+type StackRecord struct {
+	TechStack []string  `json:"tech_stack"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StackDrift is the difference between the recorded and detected tech stacks
+type StackDrift struct {
+	Recorded []string
+	Detected []string
+	Added    []string
+	Removed  []string
+}
+
+// HasChanges reports whether any technology was added or removed
+func (d *StackDrift) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0
+}
+
+// DetectStack finds the technologies used under dir from its manifest files
+func DetectStack(dir string) ([]string, error) {
+	found := make(map[string]bool)
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if entry.IsDir() {
+			if rel != "." && (skippedDirs[entry.Name()] || strings.Count(rel, string(filepath.Separator)) >= maxStackDepth-1) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		name := entry.Name()
+		tech, ok := manifestStacks[name]
+		if !ok {
+			return nil
+		}
+		found[tech] = true
+
+		if markers, ok := frameworkMarkers[name]; ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			content := strings.ToLower(string(data))
+			for marker, framework := range markers {
+				if strings.Contains(content, strings.ToLower(marker)) {
+					found[framework] = true
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stack := make([]string, 0, len(found))
+	for tech := range found {
+		stack = append(stack, tech)
+	}
+	sort.Strings(stack)
+	return stack, nil
+}
+
+// LoadStackRecord reads the recorded tech stack; ok is false when none exists
+func LoadStackRecord(dir string) (*StackRecord, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, stackFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var record StackRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, false, fmt.Errorf("invalid %s: %w", stackFile, err)
+	}
+	return &record, true, nil
+}
+
+// SaveStackRecord records stack as the one the context files describe
+func SaveStackRecord(dir string, stack []string) error {
+	data, err := json.MarshalIndent(StackRecord{TechStack: stack, UpdatedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, stackFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// CheckDrift compares the detected tech stack with the recorded one. When
+// nothing is recorded yet the detected stack becomes the baseline and no
+// drift is reported.
+func CheckDrift(dir string) (*StackDrift, error) {
+	detected, err := DetectStack(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect tech stack: %w", err)
+	}
+
+	record, ok, err := LoadStackRecord(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := SaveStackRecord(dir, detected); err != nil {
+			return nil, fmt.Errorf("failed to record tech stack: %w", err)
+		}
+		return &StackDrift{Recorded: detected, Detected: detected}, nil
+	}
+
+	return &StackDrift{
+		Recorded: record.TechStack,
+		Detected: detected,
+		Added:    difference(detected, record.TechStack),
+		Removed:  difference(record.TechStack, detected),
+	}, nil
+}
+
+// difference returns the items of a missing from b
+func difference(a, b []string) []string {
+	seen := make(map[string]bool, len(b))
+	for _, item := range b {
+		seen[item] = true
+	}
+	var out []string
+	for _, item := range a {
+		if !seen[item] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -1,6 +1,7 @@
 package context
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -97,7 +98,7 @@ Format as a complete system prompt that will be used in a Claude Code subagent.`
 		strings.Join(projectContext.TechStack, ", "),
 		projectContext.Architecture)
 
-	systemPrompt, err := m.llmClient.GenerateText(prompt)
+	systemPrompt, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +135,7 @@ Format as a complete system prompt that will be used in a Claude Code subagent.`
 		strings.Join(projectContext.Requirements, ", "),
 		strings.Join(projectContext.TechStack, ", "))
 
-	systemPrompt, err := m.llmClient.GenerateText(prompt)
+	systemPrompt, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +171,7 @@ Format as a complete system prompt that will be used in a Claude Code subagent.`
 		strings.Join(projectContext.TechStack, ", "),
 		strings.Join(projectContext.TechStack, ", "))
 
-	systemPrompt, err := m.llmClient.GenerateText(prompt)
+	systemPrompt, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +207,7 @@ Format as a complete system prompt that will be used in a Claude Code subagent.`
 		strings.Join(projectContext.TechStack, ", "),
 		strings.Join(projectContext.TechStack, ", "))
 
-	systemPrompt, err := m.llmClient.GenerateText(prompt)
+	systemPrompt, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -242,7 +243,7 @@ Format as a complete system prompt that will be used in a Claude Code subagent.`
 		strings.Join(projectContext.TechStack, ", "),
 		strings.Join(projectContext.TechStack, ", "))
 
-	systemPrompt, err := m.llmClient.GenerateText(prompt)
+	systemPrompt, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -275,7 +276,7 @@ Focus on clear, comprehensive, and maintainable documentation.
 Format as a complete system prompt that will be used in a Claude Code subagent.`,
 		projectContext.Name)
 
-	systemPrompt, err := m.llmClient.GenerateText(prompt)
+	systemPrompt, err := m.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -290,7 +291,17 @@ Format as a complete system prompt that will be used in a Claude Code subagent.`
 
 // writeSubagentFile creates the markdown file for a subagent
 func (m *Manager) writeSubagentFile(spec *SubagentSpec) error {
-	content := fmt.Sprintf(`---
+	content := subagentFileContent(spec)
+
+	filename := fmt.Sprintf("%s.md", spec.Name)
+	filePath := filepath.Join(m.workspaceDir, ".claude", "subagents", filename)
+
+	return os.WriteFile(filePath, []byte(content), 0644)
+}
+
+// subagentFileContent renders a subagent as markdown with front matter
+func subagentFileContent(spec *SubagentSpec) string {
+	return fmt.Sprintf(`---
 name: %s
 description: %s
 tools: %s
@@ -298,11 +309,6 @@ tools: %s
 
 %s
 `, spec.Name, spec.Description, strings.Join(spec.Tools, ", "), spec.Prompt)
-
-	filename := fmt.Sprintf("%s.md", spec.Name)
-	filePath := filepath.Join(m.workspaceDir, ".claude", "subagents", filename)
-
-	return os.WriteFile(filePath, []byte(content), 0644)
 }
 
 // GetSubagentForTask determines which subagent should handle a specific task
@@ -0,0 +1,68 @@
+package cycle
+
+import (
+	"log"
+	"time"
+
+	"baton/internal/llm"
+	"baton/internal/storage"
+)
+
+// cycleAccount records what a cycle cost and how it ended in the cycles
+// table, whichever way the cycle returns. A nil *cycleAccount, as dry runs
+// have, records nothing.
+type cycleAccount struct {
+	store   *storage.Store
+	tracker *llm.CostTracker
+	record  *storage.CycleRecord
+	tiers   *tierOutcome
+}
+
+// account starts accounting for a cycle whose agent is about to start
+func (ce *CycleEngine) account(task *storage.Task, agent, cycleID string, start time.Time, tracker *llm.CostTracker, dryRun bool) *cycleAccount {
+	if dryRun {
+		return nil
+	}
+	return &cycleAccount{
+		store:   ce.store,
+		tracker: tracker,
+		record:  &storage.CycleRecord{CycleID: cycleID, TaskID: task.ID, Agent: agent, StartedAt: start},
+	}
+}
+
+// served records the model tier and provider that served the cycle
+func (ca *cycleAccount) served(tiers *tierOutcome) {
+	if ca != nil {
+		ca.tiers = tiers
+	}
+}
+
+// ended records the cycle's outcome, one of the storage.Cycle* outcomes
+func (ca *cycleAccount) ended(outcome string) {
+	if ca != nil {
+		ca.record.Outcome = outcome
+	}
+}
+
+// finish saves the record; a cycle that returned err without an outcome
+// recorded was aborted
+func (ca *cycleAccount) finish(err error) {
+	if ca == nil {
+		return
+	}
+	record := ca.record
+	if record.Outcome == "" || (err != nil && record.Outcome == storage.CycleSuccess) {
+		record.Outcome = storage.CycleError
+	}
+	if ca.tiers != nil {
+		record.ModelTier, record.Provider = ca.tiers.Tier, ca.tiers.Provider
+	}
+	usage := ca.tracker.Usage()
+	record.PromptTokens, record.CompletionTokens, record.CostUSD = usage.PromptTokens, usage.CompletionTokens, usage.CostUSD
+	record.FinishedAt = time.Now()
+	record.DurationSeconds = record.FinishedAt.Sub(record.StartedAt).Seconds()
+
+	if err := ca.store.RecordCycle(record); err != nil {
+		log.Printf("Failed to record the cost of cycle %s: %v", record.CycleID, err)
+	}
+}
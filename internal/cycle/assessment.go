@@ -0,0 +1,109 @@
+package cycle
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+// Assessment is an agent's self-assessment at the end of a cycle, reported in
+// its structured outcome: how confident it is in the transition and what it
+// could not settle
+type Assessment struct {
+	Confidence    *float64 `json:"confidence,omitempty"` // 0 to 1
+	OpenQuestions []string `json:"open_questions,omitempty"`
+}
+
+// assessmentFrom returns the self-assessment in a structured outcome, or nil
+// when it has none. A confidence outside 0 to 1 is dropped.
+func assessmentFrom(outcome *structuredOutcome) *Assessment {
+	assessment := &Assessment{}
+	if outcome.Confidence != nil && *outcome.Confidence >= 0 && *outcome.Confidence <= 1 {
+		assessment.Confidence = outcome.Confidence
+	}
+	for _, question := range outcome.OpenQuestions {
+		if question = strings.TrimSpace(question); question != "" {
+			assessment.OpenQuestions = append(assessment.OpenQuestions, question)
+		}
+	}
+	if assessment.Confidence == nil && len(assessment.OpenQuestions) == 0 {
+		return nil
+	}
+	return assessment
+}
+
+// parseAssessment finds the self-assessment in an agent reply
+func parseAssessment(content string) *Assessment {
+	outcome, ok := parseStructuredOutcome(content)
+	if !ok {
+		return nil
+	}
+	return assessmentFrom(outcome)
+}
+
+// lowConfidence reports whether the assessment rates the transition below the
+// review threshold
+func (a *Assessment) lowConfidence(cfg *config.CompletionConfig) bool {
+	return a != nil && a.Confidence != nil && *a.Confidence < cfg.LowConfidenceThreshold
+}
+
+// recordAssessment adds the assessment to the cycle's audit entry and stores
+// it as the task's latest, flagging a low-confidence transition for review
+func (ce *CycleEngine) recordAssessment(entry *storage.AuditLog, assessment *Assessment) {
+	if assessment == nil {
+		return
+	}
+
+	entry.Confidence = assessment.Confidence
+	if len(assessment.OpenQuestions) > 0 {
+		entry.OpenQuestions, _ = json.Marshal(assessment.OpenQuestions)
+	}
+	if assessment.lowConfidence(&ce.config.Completion) {
+		entry.Note = fmt.Sprintf("Low confidence (%.2f), queued for human review\n%s", *assessment.Confidence, entry.Note)
+	}
+
+	err := ce.store.SaveTaskAssessment(&storage.TaskAssessment{
+		TaskID:        entry.TaskID,
+		CycleID:       entry.CycleID,
+		Actor:         entry.Actor,
+		PrevState:     storage.State(entry.PrevState),
+		NextState:     storage.State(entry.NextState),
+		Confidence:    assessment.Confidence,
+		OpenQuestions: assessment.OpenQuestions,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to save the self-assessment of task %s: %v", entry.TaskID, err)
+	}
+}
+
+// buildSelfAssessment asks the agent for its self-assessment and carries over
+// the questions the previous cycle on the task left open
+func (ce *CycleEngine) buildSelfAssessment(task *storage.Task) (string, error) {
+	var b strings.Builder
+
+	previous, err := ce.store.GetTaskAssessment(task.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to get the task's last self-assessment: %w", err)
+	}
+	if previous != nil && len(previous.OpenQuestions) > 0 {
+		fmt.Fprintf(&b, "\n## Open Questions\nThe last cycle on this task (%s, %s → %s) left these questions open. Settle them or carry them over:\n",
+			previous.Actor, previous.PrevState, previous.NextState)
+		for _, question := range previous.OpenQuestions {
+			fmt.Fprintf(&b, "- %s\n", question)
+		}
+	}
+
+	b.WriteString("\n## Self-Assessment\n")
+	b.WriteString("End your reply with a JSON object rating this cycle's work, such as " +
+		`{"confidence": 0.8, "open_questions": ["..."]}` +
+		". confidence is 0 to 1: how sure you are that the task is ready for its next state. " +
+		"open_questions lists what you could not settle; the next cycle on the task sees them. " +
+		"Low-confidence transitions are queued for human review.\n")
+	return b.String(), nil
+}
@@ -0,0 +1,134 @@
+package cycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"baton/internal/storage"
+	"baton/internal/textutil"
+)
+
+// maxRetryContext bounds how much of the failed cycle's note and of the
+// review findings a retry's prompt carries
+const maxRetryContext = 2000
+
+// retryAttempt is what a cycle retried after a failed handshake learns of
+// the attempt before it
+type retryAttempt struct {
+	Attempt   int
+	Max       int
+	Handshake string // how the failed cycle's handshake ended
+	Failure   string // the failed cycle's audit note
+	Findings  string // the task's latest review_findings, "" when there are none
+}
+
+type autoRetryKey struct{}
+
+// withAutoRetry marks the cycle run with ctx as a retry
+func withAutoRetry(ctx context.Context, retry *retryAttempt) context.Context {
+	return context.WithValue(ctx, autoRetryKey{}, retry)
+}
+
+// autoRetryFrom returns the retry a cycle is, or nil
+func autoRetryFrom(ctx context.Context) *retryAttempt {
+	retry, _ := ctx.Value(autoRetryKey{}).(*retryAttempt)
+	return retry
+}
+
+// label names the retry in the audit entry
+func (r *retryAttempt) label() string {
+	return fmt.Sprintf("Auto-retry %d of %d after the completion handshake %s", r.Attempt, r.Max, r.Handshake)
+}
+
+// promptNote tells the retrying agent why the last attempt failed, "" when
+// the cycle is no retry
+func (r *retryAttempt) promptNote() string {
+	if r == nil {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n## Previous Attempt Failed\n")
+	fmt.Fprintf(&b, "This is automatic retry %d of %d. The last cycle on this task ended without a state update "+
+		"(completion handshake %s), so the task was put back in its state. What it left behind:\n\n%s\n",
+		r.Attempt, r.Max, r.Handshake, truncate(r.Failure, maxRetryContext))
+	if r.Findings != "" {
+		fmt.Fprintf(&b, "\nThe latest review findings:\n\n%s\n", truncate(r.Findings, maxRetryContext))
+	}
+	b.WriteString("\nAddress the failure, and update the task state with baton.tasks.update_state when you are done.")
+	return b.String()
+}
+
+// autoRetry runs the cycle after result again, up to
+// completion.max_auto_retries times, as long as its handshake failed and
+// left the task in needs_fixes. Each retry puts the task back in the state
+// the failed cycle started in and is a cycle of its own, with its own audit
+// entry. The result covers every attempt.
+func (ce *CycleEngine) autoRetry(ctx context.Context, result *storage.CycleResult) (*storage.CycleResult, error) {
+	max := ce.config.Completion.MaxAutoRetries
+	for attempt := 1; attempt <= max && ctx.Err() == nil; attempt++ {
+		retry := ce.retryAttemptFor(result, attempt, max)
+		if retry == nil {
+			break
+		}
+		if err := ce.store.UpdateTaskState(result.TaskID, result.PrevState, retry.label()); err != nil {
+			return nil, fmt.Errorf("failed to put task back in %s for a retry: %w", result.PrevState, err)
+		}
+
+		retried, err := ce.executeCycle(withAutoRetry(ctx, retry), result.TaskID, false)
+		if err != nil {
+			return nil, fmt.Errorf("auto-retry %d of %d: %w", attempt, max, err)
+		}
+		retried.PrevState = result.PrevState
+		retried.AutoRetries = attempt
+		retried.PromptTokens += result.PromptTokens
+		retried.CompletionTokens += result.CompletionTokens
+		retried.CostUSD += result.CostUSD
+		retried.Duration += result.Duration
+		result = retried
+	}
+	return result, nil
+}
+
+// retryAttemptFor returns the retry of the cycle after result, nil when it
+// isn't one to retry: it must have failed its handshake and left the task in
+// needs_fixes, without the task being escalated since
+func (ce *CycleEngine) retryAttemptFor(result *storage.CycleResult, attempt, max int) *retryAttempt {
+	if result.Outcome != "failure" || result.NextState != storage.NeedsFixes || result.PrevState == storage.NeedsFixes {
+		return nil
+	}
+	task, err := ce.store.GetTask(result.TaskID)
+	if err != nil || task.State != storage.NeedsFixes {
+		return nil
+	}
+
+	logs, err := ce.store.GetAuditLogs(result.TaskID)
+	if err != nil {
+		log.Printf("Failed to read the audit log of task %s: %v", result.TaskID, err)
+		return nil
+	}
+	for _, entry := range logs {
+		if entry.CycleID != result.CycleID {
+			continue
+		}
+		if entry.Handshake != HandshakeExhausted && entry.Handshake != HandshakeTimeout {
+			return nil
+		}
+		retry := &retryAttempt{Attempt: attempt, Max: max, Handshake: entry.Handshake, Failure: entry.Note}
+		if findings, err := ce.store.GetArtifact(result.TaskID, "review_findings", 0); err == nil {
+			retry.Findings = findings.Content
+		}
+		return retry
+	}
+	return nil
+}
+
+// truncate shortens text to at most its first max bytes, cut on a rune boundary
+func truncate(text string, max int) string {
+	text = strings.TrimSpace(text)
+	if len(text) > max {
+		text = textutil.Truncate(text, max) + "…"
+	}
+	return text
+}
@@ -0,0 +1,74 @@
+package cycle
+
+import (
+	"log"
+
+	"baton/internal/storage"
+)
+
+// PhaseClaimed is the checkpoint phase of a cycle that claimed its task but
+// has not started its agent yet
+const PhaseClaimed = "claimed"
+
+// cycleCheckpoint persists how far a cycle got, so that 'baton resume' can
+// finish or undo it if the run dies. A nil *cycleCheckpoint, as dry runs
+// have, records nothing. Failing to record is logged, not fatal: the cycle
+// itself can still succeed.
+type cycleCheckpoint struct {
+	store *storage.Store
+	cp    *storage.CycleCheckpoint
+}
+
+// checkpoint records that a cycle claimed task, holding its lock as holder
+func (ce *CycleEngine) checkpoint(task *storage.Task, cycleID, holder string, dryRun bool) *cycleCheckpoint {
+	if dryRun {
+		return nil
+	}
+	cc := &cycleCheckpoint{
+		store: ce.store,
+		cp:    &storage.CycleCheckpoint{CycleID: cycleID, TaskID: task.ID, PrevState: task.State, Phase: PhaseClaimed, Holder: holder},
+	}
+	cc.save()
+	return cc
+}
+
+// started records the agent that works the cycle and the worktree it works in
+func (cc *cycleCheckpoint) started(agent string, isolation *isolatedCycle) {
+	if cc == nil {
+		return
+	}
+	cc.cp.Agent = agent
+	cc.cp.Phase = PhaseExecuting
+	if isolation != nil {
+		cc.cp.WorktreePath = isolation.tree.Path
+		cc.cp.WorktreeBranch = isolation.tree.Branch
+	}
+	cc.save()
+}
+
+// phase records the phase the cycle entered
+func (cc *cycleCheckpoint) phase(phase string) {
+	if cc == nil {
+		return
+	}
+	cc.cp.Phase = phase
+	if err := cc.store.SetCheckpointPhase(cc.cp.CycleID, phase); err != nil {
+		log.Printf("Failed to checkpoint cycle %s: %v", cc.cp.CycleID, err)
+	}
+}
+
+// finish records that the cycle ended, whether or not it succeeded
+func (cc *cycleCheckpoint) finish() {
+	if cc == nil {
+		return
+	}
+	if err := cc.store.FinishCheckpoint(cc.cp.CycleID, storage.CheckpointFinished); err != nil {
+		log.Printf("Failed to checkpoint cycle %s: %v", cc.cp.CycleID, err)
+	}
+}
+
+func (cc *cycleCheckpoint) save() {
+	if err := cc.store.SaveCheckpoint(cc.cp); err != nil {
+		log.Printf("Failed to checkpoint cycle %s: %v", cc.cp.CycleID, err)
+	}
+}
@@ -0,0 +1,63 @@
+package cycle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"baton/internal/hooks"
+)
+
+// confineCommands lets the cycle's agent, if permitted, run commands through
+// baton.commands.run in dir, where it works. It returns the prompt's note on
+// how to run them, "" when the agent may not.
+func (ce *CycleEngine) confineCommands(cycleID, dir string, allowed, dryRun bool) string {
+	if dryRun {
+		return ""
+	}
+	ce.mcpServer.ConfineCommands(cycleID, dir, allowed)
+	return ce.commandsNote(allowed)
+}
+
+// commandsNote tells an agent that may run commands how to, "" when it may
+// run none
+func (ce *CycleEngine) commandsNote(allowed bool) string {
+	if !allowed || len(ce.config.Security.AllowedCommands) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\nRun shell commands through baton.commands.run (params: command, args, dir, timeout_seconds), "+
+		"not a shell of your own. Commands run without a shell in your working directory, and may not reach outside it. "+
+		"Allowed commands: %s.", strings.Join(ce.config.Security.AllowedCommands, ", "))
+}
+
+// auditCommands lists the cycle hooks that ran and the commands the cycle's
+// agent ran or was refused, for the audit entry; nil when there are none
+func (ce *CycleEngine) auditCommands(cycleID string, cycleHooks []*hooks.Result) json.RawMessage {
+	var commands []interface{}
+	for _, result := range cycleHooks {
+		commands = append(commands, result)
+	}
+	for _, result := range ce.mcpServer.CycleCommands(cycleID) {
+		commands = append(commands, result)
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	raw, _ := json.Marshal(commands)
+	return raw
+}
+
+// refusedCommandsNote summarizes the commands the broker refused, for the
+// audit entry
+func (ce *CycleEngine) refusedCommandsNote(cycleID string) string {
+	var refused []string
+	for _, result := range ce.mcpServer.CycleCommands(cycleID) {
+		if !result.Allowed {
+			refused = append(refused, fmt.Sprintf("%s (%s)", result.Command, result.Error))
+		}
+	}
+	if len(refused) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Commands refused: %s", strings.Join(refused, ", "))
+}
@@ -0,0 +1,83 @@
+package cycle
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"baton/internal/hooks"
+	"baton/internal/storage"
+)
+
+// runBeforeSelection runs the before_selection cycle hooks in the workspace.
+// The engines of a pool run them one at a time, since hooks such as git pull
+// change the workspace they share. A blocking hook that fails stops the cycle.
+func (ce *CycleEngine) runBeforeSelection(cycleID string) ([]*hooks.Result, error) {
+	if len(ce.config.CycleHooks.BeforeSelection) == 0 {
+		return nil, nil
+	}
+	if ce.claimMu != nil {
+		ce.claimMu.Lock()
+		defer ce.claimMu.Unlock()
+	}
+
+	results := hooks.RunCycle(ce.config.CycleHooks.BeforeSelection, ce.config.Workspace,
+		"BATON_CYCLE_ID="+cycleID,
+		"BATON_HOOK_EVENT=before_selection",
+	)
+	if failed := hooks.FirstBlockingFailure(results); failed != nil {
+		return results, fmt.Errorf("cycle not started: before_selection hook %q failed: %s", failed.Hook, failed.Error)
+	}
+	return results, nil
+}
+
+// runAfterSuccess runs the after_success cycle hooks once the agent moved task
+// to state to, in dir, where the agent worked. It returns the failed blocking
+// hook, if any, whose failure undoes the move.
+func (ce *CycleEngine) runAfterSuccess(task *storage.Task, to storage.State, cycleID, dir string) ([]*hooks.Result, *hooks.Result) {
+	if len(ce.config.CycleHooks.AfterSuccess) == 0 {
+		return nil, nil
+	}
+	results := hooks.RunCycle(ce.config.CycleHooks.AfterSuccess, dir,
+		"BATON_CYCLE_ID="+cycleID,
+		"BATON_HOOK_EVENT=after_success",
+		"BATON_TASK_ID="+task.ID,
+		"BATON_TASK_TITLE="+task.Title,
+		"BATON_FROM_STATE="+string(task.State),
+		"BATON_TO_STATE="+string(to),
+	)
+	return results, hooks.FirstBlockingFailure(results)
+}
+
+// undoTransition moves task back to the state it had when the cycle started,
+// since a blocking after_success hook failed after the agent's move to to. A
+// note on the task tells the next cycle's agent what to fix.
+func (ce *CycleEngine) undoTransition(task *storage.Task, to storage.State, failed *hooks.Result) error {
+	reason := fmt.Sprintf("after_success hook %q failed", failed.Hook)
+	if err := ce.store.UpdateTaskState(task.ID, task.State, reason); err != nil {
+		return fmt.Errorf("failed to undo the move to %s: %w", to, err)
+	}
+
+	body := fmt.Sprintf("The move to %s was undone: %s: %s", to, reason, failed.Error)
+	if failed.Output != "" {
+		body += "\n\n" + failed.Output
+	}
+	if err := ce.store.AddTaskNote(&storage.TaskNote{TaskID: task.ID, Author: "baton", Body: body}); err != nil {
+		log.Printf("Failed to note on task %s: %v", task.ID, err)
+	}
+	return nil
+}
+
+// cycleHooksNote summarizes the cycle hooks that failed, for the audit entry
+func cycleHooksNote(results []*hooks.Result) string {
+	var failed []string
+	for _, result := range results {
+		if !result.Success {
+			failed = append(failed, fmt.Sprintf("%s (%s)", result.Hook, result.Error))
+		}
+	}
+	if len(failed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Cycle hooks failed: %s", strings.Join(failed, ", "))
+}
@@ -2,14 +2,22 @@ package cycle
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
 	"baton/internal/config"
+	"baton/internal/decompose"
+	"baton/internal/hooks"
 	"baton/internal/llm"
 	"baton/internal/mcp"
+	"baton/internal/plan"
+	"baton/internal/report"
 	"baton/internal/statemachine"
 	"baton/internal/storage"
 	"baton/internal/audit"
@@ -25,17 +33,44 @@ type CycleEngine struct {
 	validator *statemachine.TransitionValidator
 	auditor   *audit.Logger
 	handshake *CompletionHandshake
+	recorder  Recorder
+	live      liveTracker
+	onOutput  func(chunk *OutputChunk)
+	reviewer  Reviewer
+
+	// mcpTransportDisabled skips starting the per-cycle MCP server
+	mcpTransportDisabled bool
+
+	// claimMu is shared by the engines of a pool, so they claim tasks one at a time
+	claimMu *sync.Mutex
+}
+
+// Recorder captures the inputs and outputs of a cycle as it executes
+type Recorder interface {
+	RecordSelection(selection *statemachine.SelectionResult)
+	RecordPrompt(agent string, prompt string)
+	RecordLLMResponse(response *llm.Response, err error)
+	RecordMCPCall(req *mcp.JSONRPCRequest, resp *mcp.JSONRPCResponse)
+	RecordResult(result *storage.CycleResult)
 }
 
 // NewCycleEngine creates a new cycle engine
 func NewCycleEngine(store *storage.Store, config *config.Config, llmClient llm.Client) *CycleEngine {
 	selector := statemachine.NewTaskSelector(store, &config.Selection)
+	selector.SetAgentCoverage(statemachine.AgentCoverage(config))
 	validator := statemachine.NewTransitionValidator(store)
+	validator.SetArtifactSchemas(config.ArtifactSchemas)
+	validator.SetTransitionRules(config.TransitionRules)
+	validator.SetHooks(hooks.NewRunner(config.Hooks, config.Workspace))
+	validator.SetWIPLimits(config.Selection.WIPLimits)
+	validator.SetReviewEscalation(config.ReviewEscalation)
 	auditor := audit.NewLogger(store)
 	mcpServer := mcp.NewServer(store, config)
 	handshake := NewCompletionHandshake(store, &config.Completion)
+	handshake.SetArtifactSchemas(config.ArtifactSchemas)
+	handshake.SetTransitionRules(config.TransitionRules)
 
-	return &CycleEngine{
+	engine := &CycleEngine{
 		store:     store,
 		config:    config,
 		mcpServer: mcpServer,
@@ -45,39 +80,149 @@ func NewCycleEngine(store *storage.Store, config *config.Config, llmClient llm.C
 		auditor:   auditor,
 		handshake: handshake,
 	}
+	handshake.SetAttemptObserver(func(attempt int) {
+		engine.live.update(func(cycle *LiveCycle) {
+			cycle.HandshakeAttempts = attempt
+		})
+	})
+	mcpServer.SetPromptRenderer(engine.RenderPrompt)
+	return engine
+}
+
+// SetRecorder makes the engine report the cycle's selection, prompt, LLM
+// response, MCP traffic and result to the recorder
+func (ce *CycleEngine) SetRecorder(recorder Recorder) {
+	ce.recorder = recorder
+	ce.mcpServer.SetCallObserver(recorder.RecordMCPCall)
+}
+
+// SetOutputHandler makes the engine pass agent output to fn as the LLM client
+// streams it, for live progress; fn must not block for long
+func (ce *CycleEngine) SetOutputHandler(fn func(chunk *OutputChunk)) {
+	ce.onOutput = fn
+}
+
+// DisableMCPTransport stops the engine from starting its own MCP server each
+// cycle, for callers that dispatch calls directly through MCPServer (replay)
+func (ce *CycleEngine) DisableMCPTransport() {
+	ce.mcpTransportDisabled = true
+}
+
+// UseMCPServer makes the engine run its cycles against a persistent MCP server
+// that the caller starts and stops, scoping it to each cycle's task in turn.
+// The caller offers prompts on it, see ServePrompts.
+func (ce *CycleEngine) UseMCPServer(server *mcp.Server) {
+	ce.mcpServer = server
+	ce.mcpTransportDisabled = true
+}
+
+// MCPServer returns the MCP server the engine exposes to agents
+func (ce *CycleEngine) MCPServer() *mcp.Server {
+	return ce.mcpServer
 }
 
 // ExecuteCycle executes a complete cycle
 func (ce *CycleEngine) ExecuteCycle(ctx context.Context, dryRun bool) (*storage.CycleResult, error) {
+	return ce.ExecuteCycleForTask(ctx, "", dryRun)
+}
+
+// SetUntilState makes cycles only select tasks that have not yet reached
+// state in the workflow
+func (ce *CycleEngine) SetUntilState(state storage.State) {
+	ce.selector.SetUntilState(state)
+}
+
+// SelectTask checks that a cycle could start on the task, as ExecuteCycleForTask does
+func (ce *CycleEngine) SelectTask(taskID string) (*statemachine.SelectionResult, error) {
+	return ce.selector.SelectTask(taskID)
+}
+
+// ExecuteCycleForTask executes a complete cycle on the given task, bypassing
+// selection but not its checks; an empty taskID selects the next task. With
+// completion.max_auto_retries, a cycle whose handshake failed is retried.
+func (ce *CycleEngine) ExecuteCycleForTask(ctx context.Context, taskID string, dryRun bool) (*storage.CycleResult, error) {
+	result, err := ce.executeCycle(ctx, taskID, dryRun)
+	if err != nil || dryRun {
+		return result, err
+	}
+	return ce.autoRetry(ctx, result)
+}
+
+// executeCycle executes one cycle, as ExecuteCycleForTask does
+func (ce *CycleEngine) executeCycle(ctx context.Context, taskID string, dryRun bool) (_ *storage.CycleResult, err error) {
 	cycleID := uuid.New().String()
 	start := time.Now()
 
 	result := &storage.CycleResult{
+		CycleID: cycleID,
 		Success: false,
 	}
 
-	// Add timeout context if configured
-	if ce.config.Development.CycleTimeboxSeconds > 0 {
-		timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(ce.config.Development.CycleTimeboxSeconds)*time.Second)
-		defer cancel()
-		ctx = timeoutCtx
-	}
-
 	// Step 1: Context reset (conceptual - new cycle starts fresh)
 	// Step 2: Rehydrate context from stored sources (handled by task selection)
 
-	// Step 3: Select next task
-	selectionResult, err := ce.selector.SelectNext()
+	// Run the before_selection hooks, e.g. to pull the latest changes
+	var cycleHooks []*hooks.Result
+	if !dryRun {
+		results, err := ce.runBeforeSelection(cycleID)
+		if err != nil {
+			return nil, err
+		}
+		cycleHooks = results
+	}
+
+	// Step 3: Select next task and claim it from parallel workers
+	selectionResult, release, err := ce.claimTask(taskID, cycleID, dryRun)
 	if err != nil {
-		return nil, fmt.Errorf("task selection failed: %w", err)
+		return nil, err
 	}
+	defer release()
 
 	task := selectionResult.Task
 	result.TaskID = task.ID
 	result.PrevState = task.State
 
-	// Step 4: Start MCP server
+	// Record the cycle's progress for 'baton resume', in case the run dies
+	checkpoint := ce.checkpoint(task, cycleID, taskLockHolder(cycleID), dryRun)
+	defer checkpoint.finish()
+
+	if ce.recorder != nil {
+		ce.recorder.RecordSelection(selectionResult)
+	}
+
+	// Timebox the cycle according to the task's estimate and state
+	timeout := CycleTimeout(ce.config, task)
+	if timeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		ctx = timeoutCtx
+	}
+
+	// Don't spend LLM calls on agents that can't read the plan, if configured
+	planErr := ce.checkPlan()
+	if planErr != nil {
+		if ce.config.PlanUnavailable == "pause" && !dryRun {
+			return nil, fmt.Errorf("cycle paused: %w", planErr)
+		}
+		log.Printf("Warning: %v; agents will work without the plan", planErr)
+	}
+
+	// Never start a cycle once the day's LLM budget is spent
+	var tracker *llm.CostTracker
 	if !dryRun {
+		tracker, err = ce.newCostTracker()
+		if err != nil {
+			return nil, err
+		}
+		if err := tracker.Check(); err != nil {
+			return nil, fmt.Errorf("cycle not started: %w", err)
+		}
+	}
+
+	// Step 4: Start MCP server, scoped to this cycle's task until in-flight requests drain
+	ce.mcpServer.BeginCycle(cycleID, task.ID)
+	defer ce.mcpServer.EndCycle(cycleID)
+	if !dryRun && !ce.mcpTransportDisabled {
 		if err := ce.mcpServer.Start(); err != nil {
 			return nil, fmt.Errorf("failed to start MCP server: %w", err)
 		}
@@ -89,16 +234,83 @@ func (ce *CycleEngine) ExecuteCycle(ctx context.Context, dryRun bool) (*storage.
 	if err != nil {
 		return nil, fmt.Errorf("failed to get agent for task: %w", err)
 	}
+	if agentID, ok := ce.config.AgentIDForState(string(task.State)); ok {
+		ce.mcpServer.SetCycleAgent(cycleID, agentID)
+	}
+
+	// Keep the agent's file changes out of the working tree until the cycle succeeds
+	isolation, err := ce.isolate(task, cycleID, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer isolation.discard()
 
 	prompt, err := ce.buildPrompt(task, agent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build prompt: %w", err)
 	}
+	prompt += isolation.promptNote()
+	prompt += ce.confineCommands(cycleID, isolation.workDir(ce.config.Workspace), agent.Permissions.CanExecuteCommands, dryRun)
+	prompt += createTasksNote(agent.Permissions.CanCreateTasks)
+	prompt += planUnavailableNote(planErr)
+	prompt += autoRetryFrom(ctx).promptNote()
+
+	if ce.recorder != nil {
+		ce.recorder.RecordPrompt(agent.Name, prompt)
+	}
+
+	ce.live.begin(&LiveCycle{
+		CycleID:        cycleID,
+		TaskID:         task.ID,
+		TaskTitle:      task.Title,
+		TaskState:      task.State,
+		Agent:          agent.Name,
+		Phase:          PhaseExecuting,
+		StartedAt:      start,
+		TimeboxSeconds: int(timeout / time.Second),
+		DryRun:         dryRun,
+	})
+	defer ce.live.end(cycleID)
+	checkpoint.started(agent.Name, isolation)
+
+	// Record what the cycle costs, however it ends
+	account := ce.account(task, agent.Name, cycleID, start, tracker, dryRun)
+	defer func() { account.finish(err) }()
 
 	var llmResponse *llm.Response
+	tiers := &tierOutcome{}
+	llmCtx := isolation.context(llm.WithMCPToken(llm.WithMCPCycle(ctx, cycleID), ce.config.MCPToken()))
 	if !dryRun {
-		llmResponse, err = ce.llmClient.Execute(ctx, prompt, agent.Name)
+		if ce.onOutput != nil {
+			llmCtx = llm.WithStream(llmCtx, func(content string) {
+				ce.onOutput(&OutputChunk{CycleID: cycleID, TaskID: task.ID, Content: content})
+			})
+		}
+		if ce.config.Development.RecordTranscripts {
+			llmCtx = withTranscript(llmCtx, NewTranscript(ce.config, cycleID, task, agent))
+		}
+		llmResponse, tiers, err = ce.executeTiered(llmCtx, task, agent, prompt, tracker)
+		account.served(tiers)
+		result.ModelTier = tiers.Tier
+		result.Provider = tiers.Provider
+		usage := tracker.Usage()
+		result.PromptTokens, result.CompletionTokens, result.CostUSD = usage.PromptTokens, usage.CompletionTokens, usage.CostUSD
+		if ce.recorder != nil {
+			ce.recorder.RecordLLMResponse(llmResponse, err)
+		}
 		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				account.ended(storage.CycleTimeout)
+				ce.logUnsuccessfulCycle(cycleID, task, agent, storage.CycleTimeout,
+					tiers.annotate(timedOutNote(timeout, llmResponse)), usage, timeout, time.Since(start))
+				ce.noteTimedOut(task, agent.Name, timeout, llmResponse, isolation != nil)
+				ce.checkDecomposition(task)
+				return nil, fmt.Errorf("cycle exceeded its %s timebox: %w", timeout, err)
+			}
+			account.ended(storage.CycleFailure)
+			ce.logUnsuccessfulCycle(cycleID, task, agent, "failure",
+				tiers.annotate(fmt.Sprintf("LLM execution failed: %v", err)), usage, timeout, time.Since(start))
+			ce.checkDecomposition(task)
 			return nil, fmt.Errorf("LLM execution failed: %w", err)
 		}
 	} else {
@@ -110,13 +322,55 @@ func (ce *CycleEngine) ExecuteCycle(ctx context.Context, dryRun bool) (*storage.
 	}
 
 	// Step 6: Enforce completion handshake
+	cycleResult := "success"
+	var handshakeResult *HandshakeResult
+	var reviewDecision *ReviewDecision
 	if !dryRun {
-		handshakeResult, err := ce.handshake.Enforce(ctx, task.ID, llmResponse)
+		ce.live.update(func(cycle *LiveCycle) { cycle.Phase = PhaseHandshake })
+		checkpoint.phase(PhaseHandshake)
+		handshakeResult, err = ce.handshake.Enforce(llmCtx, task.ID, task.State, llmResponse, ce.followUp(agent, tiers.Tier, tracker, prompt, llmResponse))
 		if err != nil {
 			return nil, fmt.Errorf("completion handshake failed: %w", err)
 		}
+		if !handshakeResult.Success {
+			cycleResult = "failure"
+		}
 		result.NextState = handshakeResult.FinalState
+		if handshakeResult.Success {
+			ce.live.update(func(cycle *LiveCycle) { cycle.Phase = PhaseHooks })
+			afterHooks, failed := ce.runAfterSuccess(task, handshakeResult.FinalState, cycleID, isolation.workDir(ce.config.Workspace))
+			cycleHooks = append(cycleHooks, afterHooks...)
+			if failed != nil {
+				// The checks failed, so the agent's move and changes don't stand
+				if err := ce.undoTransition(task, handshakeResult.FinalState, failed); err != nil {
+					return nil, err
+				}
+				cycleResult = "failure"
+				result.NextState = task.State
+			} else {
+				// A human may approve the move, or reject and undo it
+				if ce.reviewer != nil {
+					ce.live.update(func(cycle *LiveCycle) { cycle.Phase = PhaseReview })
+					checkpoint.phase(PhaseReview)
+					reviewDecision, err = ce.review(task, handshakeResult.FinalState, cycleID, agent.Name,
+						llmResponse.Content, handshakeResult.ArtifactsCreated, isolation)
+					if err != nil {
+						return nil, err
+					}
+				}
+				if reviewDecision != nil && !reviewDecision.Approved {
+					cycleResult = "failure"
+					result.NextState = task.State
+				} else {
+					ce.live.update(func(cycle *LiveCycle) { cycle.Phase = PhaseMerging })
+					checkpoint.phase(PhaseMerging)
+					result.Error = isolation.merge(result)
+				}
+			}
+		}
 		result.ArtifactsCreated = handshakeResult.ArtifactsCreated
+		usage := tracker.Usage()
+		result.PromptTokens, result.CompletionTokens, result.CostUSD = usage.PromptTokens, usage.CompletionTokens, usage.CostUSD
 	} else {
 		// Dry run - predict next state
 		allowedStates, _ := statemachine.GetAllowedTransitions(task.State)
@@ -127,9 +381,13 @@ func (ce *CycleEngine) ExecuteCycle(ctx context.Context, dryRun bool) (*storage.
 		}
 	}
 
+	account.ended(cycleResult)
+
 	// Step 7: Create/update handover artifacts (handled by completion handshake)
 
 	// Step 8: Record audit entry
+	ce.live.update(func(cycle *LiveCycle) { cycle.Phase = PhaseRecording })
+	checkpoint.phase(PhaseRecording)
 	auditEntry := &storage.AuditLog{
 		TaskID:          task.ID,
 		CycleID:         cycleID,
@@ -139,47 +397,164 @@ func (ce *CycleEngine) ExecuteCycle(ctx context.Context, dryRun bool) (*storage.
 		SelectionReason: selectionResult.Reason,
 		InputsSummary:   ce.buildInputsSummary(task),
 		OutputsSummary:  ce.buildOutputsSummary(result.ArtifactsCreated),
-		Result:          "success",
+		Result:          cycleResult,
+		TimeboxSeconds:  int(timeout / time.Second),
+		DurationSeconds: time.Since(start).Seconds(),
+		ModelTier:       tiers.Tier,
+		Provider:        tiers.Provider,
+		PromptTokens:     result.PromptTokens,
+		CompletionTokens: result.CompletionTokens,
+		CostUSD:          result.CostUSD,
 	}
 
 	if llmResponse != nil {
 		auditEntry.Note = fmt.Sprintf("LLM Response: %s", llmResponse.Content[:min(len(llmResponse.Content), 200)])
 	}
+	if handshakeResult != nil {
+		auditEntry.Handshake = handshakeResult.Outcome
+		if handshakeResult.Outcome != HandshakeUpdated {
+			auditEntry.Note = fmt.Sprintf("Handshake %s: %s\n%s", handshakeResult.Outcome, handshakeResult.Note, auditEntry.Note)
+		}
+		if len(handshakeResult.FollowUps) > 0 {
+			auditEntry.FollowUps, _ = json.Marshal(handshakeResult.FollowUps)
+		}
+	}
+	auditEntry.Commands = ce.auditCommands(cycleID, cycleHooks)
+	if note := cycleHooksNote(cycleHooks); note != "" {
+		auditEntry.Note = note + "\n" + auditEntry.Note
+	}
+	if note := ce.refusedCommandsNote(cycleID); note != "" {
+		auditEntry.Note = note + "\n" + auditEntry.Note
+	}
+	if note := reviewNote(reviewDecision); note != "" {
+		auditEntry.Note = note + "\n" + auditEntry.Note
+	}
+	if stage := pipelineStageFrom(ctx); stage != "" {
+		auditEntry.Note = stage + "\n" + auditEntry.Note
+	}
+	if retry := autoRetryFrom(ctx); retry != nil {
+		auditEntry.Note = retry.label() + "\n" + auditEntry.Note
+	}
+	auditEntry.Note = tiers.annotate(auditEntry.Note)
 
 	if !dryRun {
+		// The self-assessment comes with the structured outcome of a follow-up,
+		// else at the end of the agent's reply
+		var assessment *Assessment
+		if handshakeResult != nil {
+			assessment = handshakeResult.Assessment
+		}
+		if assessment == nil && llmResponse != nil {
+			assessment = parseAssessment(llmResponse.Content)
+		}
+		ce.recordAssessment(auditEntry, assessment)
+
 		if err := ce.auditor.LogCycle(auditEntry); err != nil {
 			return nil, fmt.Errorf("failed to log audit entry: %w", err)
 		}
+		if cycleResult != "success" {
+			ce.checkDecomposition(task)
+		}
+		ce.checkEscalation(task)
+		ce.checkMilestone(task)
 	}
 
 	// Step 9: Stop MCP server (handled by defer)
 
 	// Step 10: Return cycle result
+	result.Outcome = cycleResult
 	result.Success = true
 	result.Duration = time.Since(start)
 
+	if ce.recorder != nil {
+		ce.recorder.RecordResult(result)
+	}
+
 	return result, nil
 }
 
 // getAgentForTask determines which agent should handle a task
 func (ce *CycleEngine) getAgentForTask(task *storage.Task) (*config.Agent, error) {
-	for agentID, agent := range ce.config.Agents {
-		// Check if agent can handle this state
-		for _, allowedState := range agent.AllowedStates {
-			if allowedState == string(task.State) {
-				return &agent, nil
-			}
-		}
-		_ = agentID // Use the variable to avoid unused warning
+	agent, ok := ce.config.AgentForState(string(task.State))
+	if !ok {
+		return nil, fmt.Errorf("no agent configured for state %s (set default_agent or selection.skip_unassigned_states)", task.State)
 	}
+	return agent, nil
+}
 
-	return nil, fmt.Errorf("no agent configured for state %s", task.State)
+// checkPlan reports whether agents can read the plan file, nil when no plan
+// file is configured
+func (ce *CycleEngine) checkPlan() error {
+	if ce.config.PlanFile == "" {
+		return nil
+	}
+	return plan.Check(ce.config.PlanFile)
 }
 
-// buildPrompt constructs the prompt for the LLM
+// buildPrompt constructs the prompt for the LLM from the agent's prompt
+// template, or the built-in prompt when it has none
 func (ce *CycleEngine) buildPrompt(task *storage.Task, agent *config.Agent) (string, error) {
-	// Base prompt structure
-	prompt := fmt.Sprintf(`# %s Role
+	prompt, err := ce.renderPromptTemplate(task, agent, defaultPrompt(task, agent))
+	if err != nil {
+		return "", err
+	}
+
+	grounding, err := ce.buildRequirementGrounding(task)
+	if err != nil {
+		return "", err
+	}
+
+	assessment, err := ce.buildSelfAssessment(task)
+	if err != nil {
+		return "", err
+	}
+
+	return prompt + grounding + ce.buildHandoverSchemas(task) + ce.buildTransitionRules(task) + assessment, nil
+}
+
+// ServePrompts offers the agents' role prompts on an MCP server the engines
+// don't create, before it starts
+func ServePrompts(server *mcp.Server, store *storage.Store, config *config.Config) {
+	server.SetPromptRenderer(NewCycleEngine(store, config, nil).RenderPrompt)
+}
+
+// RenderPrompt renders the prompt a cycle of the agent on the task would
+// start with, but for the notes on where and how that cycle runs
+func (ce *CycleEngine) RenderPrompt(agentID, taskID string) (string, error) {
+	agent, ok := ce.config.Agents[agentID]
+	if !ok {
+		return "", fmt.Errorf("unknown agent %q", agentID)
+	}
+	task, err := ce.store.GetTask(taskID)
+	if err != nil {
+		return "", fmt.Errorf("task %s not found: %w", taskID, err)
+	}
+	return ce.buildPrompt(task, &agent)
+}
+
+// createTasksNote tells an agent that may create tasks to file the follow-up
+// work it finds, "" when it may not
+func createTasksNote(allowed bool) string {
+	if !allowed {
+		return ""
+	}
+	return "\n\nWhen you find work outside this task, such as missing tests or a bug elsewhere, file it as a new task " +
+		"with baton.tasks.create (params: title, description, priority, dependencies, ...) instead of doing it now. " +
+		"baton.tasks.update changes the fields of a task, and baton.tasks.add_dependency, baton.tasks.remove_dependency " +
+		"and baton.tasks.graph restructure the task dependencies."
+}
+
+// planUnavailableNote tells the agent the plan can't be read, "" when it can
+func planUnavailableNote(planErr error) string {
+	if planErr == nil {
+		return ""
+	}
+	return "\n\nNote: the project plan is unavailable this cycle (" + planErr.Error() + "). Do not rely on baton.plan.read; record any assumptions in a task note."
+}
+
+// defaultPrompt is the built-in prompt for agents without a prompt template
+func defaultPrompt(task *storage.Task, agent *config.Agent) string {
+	return fmt.Sprintf(`# %s Role
 
 You are the %s for this project. %s
 
@@ -201,11 +576,16 @@ Handle the current task state (%s) according to your role.
 ## Available MCP Methods
 - baton.tasks.get - Get task details
 - baton.tasks.update_state - Update task state
+- baton.tasks.check_transition - Check what a state change needs, without making it
 - baton.tasks.append_note - Add notes to task
 - baton.artifacts.upsert - Create/update artifacts
 - baton.artifacts.get - Get existing artifacts
 - baton.plan.read - Read the project plan
 - baton.requirements.list - List requirements
+- baton.requirements.create - Add a requirement; its key is allocated for you
+- baton.search - Search tasks, artifacts, requirements and audit notes
+- baton.milestones.list - Progress of every milestone
+- baton.milestones.progress - Remaining work in a milestone
 
 Please proceed with handling this task.`,
 		agent.Name,
@@ -217,8 +597,123 @@ Please proceed with handling this task.`,
 		task.Priority,
 		task.State,
 	)
+}
+
+// buildHandoverSchemas tells the agent what the handovers it may need to
+// produce from the current state must contain
+func (ce *CycleEngine) buildHandoverSchemas(task *storage.Task) string {
+	allowedStates, err := statemachine.GetAllowedTransitions(task.State)
+	if err != nil || len(ce.config.ArtifactSchemas) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for _, next := range allowedStates {
+		for _, name := range getRequiredHandovers(task.State, next) {
+			schema, exists := ce.config.ArtifactSchemas[name]
+			if !exists || seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			fmt.Fprintf(&b, "- **%s**", name)
+			if schema.Format == "json" {
+				fmt.Fprintf(&b, ": JSON object with fields %s", strings.Join(schema.RequiredFields, ", "))
+			} else if len(schema.RequiredSections) > 0 {
+				fmt.Fprintf(&b, ": sections %s", strings.Join(schema.RequiredSections, ", "))
+			}
+			if schema.MinLength > 0 {
+				fmt.Fprintf(&b, " (at least %d characters)", schema.MinLength)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if b.Len() == 0 {
+		return ""
+	}
+	return "\n## Handover Artifact Requirements\nArtifacts that do not match are rejected on upsert:\n" + b.String()
+}
+
+// buildTransitionRules tells the agent which artifact metadata the
+// transitions out of the current state depend on
+func (ce *CycleEngine) buildTransitionRules(task *storage.Task) string {
+	allowedStates, err := statemachine.GetAllowedTransitions(task.State)
+	if err != nil || len(ce.config.TransitionRules) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, next := range allowedStates {
+		for _, rule := range ce.config.TransitionRules {
+			if rule.Matches(string(task.State), string(next)) {
+				fmt.Fprintf(&b, "- to %s: %s\n", next, rule.Condition())
+			}
+		}
+	}
+
+	if b.Len() == 0 {
+		return ""
+	}
+	return "\n## Transition Conditions\nThese transitions are refused unless the artifact's meta matches:\n" + b.String()
+}
+
+// buildRequirementGrounding lists the task's linked requirements with their
+// stable keys and, in the review stage, flags work that does not cite them
+func (ce *CycleEngine) buildRequirementGrounding(task *storage.Task) (string, error) {
+	requirements, err := ce.store.ListTaskRequirements(task.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get linked requirements: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n## Linked Requirements\n")
+	if len(requirements) == 0 {
+		b.WriteString("No requirements are linked to this task. Use baton.requirements.list to find the ones your work satisfies.\n")
+	}
+
+	keys := make([]string, 0, len(requirements))
+	for _, req := range requirements {
+		keys = append(keys, req.Key)
+		fmt.Fprintf(&b, "- **%s** (%s): %s\n", req.Key, req.Title, req.Text)
+	}
+
+	b.WriteString("\n## Requirement Citations\n")
+	b.WriteString("Cite requirement keys (e.g. FR-1) in the implementation_plan and change_summary artifacts next to the work that satisfies them. ")
+	b.WriteString("Transitions are rejected while a linked requirement is not cited.\n")
+	if len(requirements) > 0 {
+		b.WriteString("\n## Acceptance Tests\n")
+		b.WriteString("If you write or run acceptance tests, record them in the acceptance_tests artifact with a \"## Results\" section ")
+		b.WriteString("listing one \"- <KEY>: pass|fail|pending\" line per requirement the tests cover.\n")
+	}
+
+	if task.State != storage.Reviewing {
+		return b.String(), nil
+	}
+
+	// Review stage: surface citation gaps in the change summary for the reviewer
+	summary, err := ce.store.GetArtifact(task.ID, "change_summary", 0)
+	if err != nil {
+		return b.String(), nil
+	}
+
+	missing := plan.MissingCitations(summary.Content, keys)
+	uncited := plan.UncitedSections(summary.Content)
+	if len(missing) == 0 && len(uncited) == 0 {
+		return b.String(), nil
+	}
 
-	return prompt, nil
+	b.WriteString("\n## Citation Check\n")
+	if len(missing) > 0 {
+		fmt.Fprintf(&b, "- change_summary does not cite: %s\n", strings.Join(missing, ", "))
+	}
+	for _, section := range uncited {
+		fmt.Fprintf(&b, "- Uncited work in change_summary section \"%s\"\n", section)
+	}
+	b.WriteString("Flag uncited work in review_findings and request fixes if it is out of scope.\n")
+
+	return b.String(), nil
 }
 
 // buildInputsSummary creates a summary of cycle inputs
@@ -226,6 +721,132 @@ func (ce *CycleEngine) buildInputsSummary(task *storage.Task) string {
 	return fmt.Sprintf("Task: %s (State: %s, Priority: %d)", task.Title, task.State, task.Priority)
 }
 
+// logUnsuccessfulCycle records a cycle that failed or ran out of time, so
+// timebox settings can be tuned against how long cycles really take and
+// repeated failures can trigger decomposition
+// newCostTracker creates the tracker that holds a cycle to the LLM budgets,
+// loading what earlier cycles spent today when a daily budget is set
+func (ce *CycleEngine) newCostTracker() (*llm.CostTracker, error) {
+	var spentToday float64
+	if ce.config.LLM.BudgetUSDPerDay > 0 {
+		now := time.Now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		var err error
+		if spentToday, err = ce.store.LLMSpendSince(midnight); err != nil {
+			return nil, fmt.Errorf("failed to read today's LLM spending: %w", err)
+		}
+	}
+	return llm.NewCostTracker(&ce.config.LLM, spentToday), nil
+}
+
+// acquireAreaLocks locks the areas a cycle on task changes, as configured
+// under selection.area_locks, and returns the function that releases them.
+// Locks expire after the TTL, or the timebox if longer, in case the process
+// dies before releasing them.
+func (ce *CycleEngine) acquireAreaLocks(task *storage.Task, cycleID string, timeout time.Duration) (func(), error) {
+	locks := &ce.config.Selection.AreaLocks
+	if !locks.Enabled {
+		return func() {}, nil
+	}
+
+	areas := locks.AreasFor(task.TagList(), string(task.State))
+	if len(areas) == 0 {
+		return func() {}, nil
+	}
+
+	ttl := time.Duration(locks.TTLMinutes) * time.Minute
+	if timeout > ttl {
+		ttl = timeout
+	}
+
+	if err := ce.store.AcquireAreaLocks(areas, task.ID, cycleID, lockHolder(), ttl); err != nil {
+		return nil, fmt.Errorf("failed to lock areas for task %s: %w", task.ID, err)
+	}
+
+	return func() {
+		if err := ce.store.ReleaseAreaLocks(cycleID); err != nil {
+			log.Printf("Failed to release area locks for cycle %s: %v", cycleID, err)
+		}
+	}, nil
+}
+
+func (ce *CycleEngine) logUnsuccessfulCycle(cycleID string, task *storage.Task, agent *config.Agent, result, note string, usage llm.Usage, timeout, elapsed time.Duration) {
+	entry := &storage.AuditLog{
+		TaskID:          task.ID,
+		CycleID:         cycleID,
+		PrevState:       string(task.State),
+		NextState:       string(task.State),
+		Actor:           agent.Name,
+		InputsSummary:   ce.buildInputsSummary(task),
+		Result:          result,
+		Note:            note,
+		TimeboxSeconds:  int(timeout / time.Second),
+		DurationSeconds: elapsed.Seconds(),
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          usage.CostUSD,
+		Commands:         ce.auditCommands(cycleID, nil),
+	}
+
+	if err := ce.auditor.LogCycle(entry); err != nil {
+		log.Printf("Failed to log unsuccessful cycle: %v", err)
+	}
+}
+
+// checkDecomposition offers or runs a decomposition once a task has failed
+// decomposition.failure_threshold cycles in a row
+func (ce *CycleEngine) checkDecomposition(task *storage.Task) {
+	decomposer := decompose.NewDecomposer(ce.store, ce.llmClient, ce.config.Decomposition)
+	due, failed, err := decomposer.ShouldDecompose(task.ID)
+	if err != nil {
+		log.Printf("Failed to check task %s for decomposition: %v", task.ID, err)
+		return
+	}
+	if !due {
+		return
+	}
+
+	if ce.config.Decomposition.Mode != "auto" {
+		log.Printf("Task %s has had %d unsuccessful cycles in a row; split it with: baton tasks decompose --id %s",
+			task.ID, failed, task.ID)
+		return
+	}
+
+	result, err := decomposer.Decompose(task.ID)
+	if err != nil {
+		log.Printf("Failed to decompose task %s: %v", task.ID, err)
+		return
+	}
+	log.Printf("Task %s split into %d subtasks after %d unsuccessful cycles", task.ID, len(result.Subtasks), failed)
+}
+
+// checkEscalation puts the task on hold when the cycle sent it back to
+// needs_fixes once too often, as configured under review_escalation
+func (ce *CycleEngine) checkEscalation(task *storage.Task) {
+	hold, err := ce.validator.Escalate(task.ID)
+	if err != nil {
+		log.Printf("Failed to check task %s for escalation: %v", task.ID, err)
+		return
+	}
+	if hold != "" {
+		log.Printf("Task %s failed review %d times and was escalated to %s; resume it with: baton tasks resume %s",
+			task.ID, ce.config.ReviewEscalation.MaxFailures, hold, task.ID)
+	}
+}
+
+// checkMilestone records the milestone summary when the cycle finished the
+// last task of the task's milestone
+func (ce *CycleEngine) checkMilestone(task *storage.Task) {
+	milestone, err := report.RecordMilestoneSummary(ce.store, task.ID, ce.config.Acceptance.MandatoryTypes)
+	if err != nil {
+		log.Printf("Failed to summarize the milestone of task %s: %v", task.ID, err)
+		return
+	}
+	if milestone != "" {
+		log.Printf("Milestone %s is finished; review its summary and sign it off with: baton milestones signoff %s", milestone, milestone)
+	}
+}
+
 // buildOutputsSummary creates a summary of cycle outputs
 func (ce *CycleEngine) buildOutputsSummary(artifactsCreated []string) string {
 	if len(artifactsCreated) == 0 {
@@ -2,27 +2,60 @@ package cycle
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"baton/internal/config"
 	"baton/internal/llm"
+	"baton/internal/statemachine"
 	"baton/internal/storage"
 )
 
+// Handshake outcomes recorded in the audit log
+const (
+	HandshakeUpdated   = "updated"   // the task state changed, by the agent or its structured outcome
+	HandshakeRefused   = "refused"   // the agent explicitly declined to change the state, giving a reason
+	HandshakeTimeout   = "timeout"   // completion.timeout_seconds passed before the state changed
+	HandshakeExhausted = "exhausted" // every follow-up was sent without the state changing
+)
+
+// FollowUpFunc sends a follow-up prompt to the cycle's agent
+type FollowUpFunc func(ctx context.Context, prompt string) (*llm.Response, error)
+
 // CompletionHandshake enforces completion handshake after cycle execution
 type CompletionHandshake struct {
-	store  *storage.Store
-	config *config.CompletionConfig
+	store   *storage.Store
+	config  *config.CompletionConfig
+	rules   []config.TransitionRule
+	schemas map[string]config.ArtifactSchema // checked against the artifacts of structured outcomes
+
+	// onAttempt is told the number of each follow-up check as it starts
+	onAttempt func(attempt int)
 }
 
 // HandshakeResult represents the result of a completion handshake
 type HandshakeResult struct {
 	Success          bool     `json:"success"`
+	Outcome          string   `json:"outcome"` // one of the Handshake* outcomes
 	FinalState       storage.State `json:"final_state"`
 	ArtifactsCreated []string `json:"artifacts_created"`
 	FollowUps        []string `json:"follow_ups"`
 	Note             string   `json:"note"`
+	Assessment       *Assessment `json:"assessment,omitempty"` // the self-assessment in the agent's last structured outcome
+}
+
+// structuredOutcome is the JSON an agent may reply with instead of updating
+// the task state itself
+type structuredOutcome struct {
+	Reason        string            `json:"reason"`
+	NextState     string            `json:"next_state"`
+	Summary       string            `json:"summary"`   // what the agent did, recorded with the transition
+	Artifacts     map[string]string `json:"artifacts"` // handover artifacts by name, saved before the transition
+	Confidence    *float64 `json:"confidence"`     // the agent's self-assessment, 0 to 1
+	OpenQuestions []string `json:"open_questions"` // what the agent could not settle
 }
 
 // NewCompletionHandshake creates a new completion handshake enforcer
@@ -33,20 +66,21 @@ func NewCompletionHandshake(store *storage.Store, config *config.CompletionConfi
 	}
 }
 
-// Enforce enforces the completion handshake
-func (ch *CompletionHandshake) Enforce(ctx context.Context, taskID string, llmResponse *llm.Response) (*HandshakeResult, error) {
+// SetAttemptObserver makes the handshake report each follow-up attempt to fn
+func (ch *CompletionHandshake) SetAttemptObserver(fn func(attempt int)) {
+	ch.onAttempt = fn
+}
+
+// Enforce enforces the completion handshake. initialState is the state the
+// task was in when the cycle started, so that a state the agent set while it
+// worked counts. The whole handshake, follow-up prompts included, must finish
+// within completion.timeout_seconds; followUp sends each follow-up to the
+// agent and may be nil to only re-check the state.
+func (ch *CompletionHandshake) Enforce(ctx context.Context, taskID string, initialState storage.State, llmResponse *llm.Response, followUp FollowUpFunc) (*HandshakeResult, error) {
 	result := &HandshakeResult{
 		Success: false,
 	}
 
-	// Get the task to check its current state
-	task, err := ch.store.GetTask(taskID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get task: %w", err)
-	}
-
-	initialState := task.State
-
 	// Check if the task state was updated (the primary success condition)
 	updatedTask, err := ch.store.GetTask(taskID)
 	if err != nil {
@@ -56,90 +90,311 @@ func (ch *CompletionHandshake) Enforce(ctx context.Context, taskID string, llmRe
 	// If state changed, handshake is successful
 	if updatedTask.State != initialState {
 		result.Success = true
+		result.Outcome = HandshakeUpdated
 		result.FinalState = updatedTask.State
 		result.Note = "Task state successfully updated"
-
-		// Check for artifacts created during this cycle
-		artifacts, err := ch.store.ListArtifacts(taskID)
-		if err == nil {
-			for _, artifact := range artifacts {
-				// Consider artifacts created in the last few seconds as "new"
-				if time.Since(artifact.CreatedAt) < 30*time.Second {
-					result.ArtifactsCreated = append(result.ArtifactsCreated, artifact.Name)
-				}
-			}
-		}
-
+		result.ArtifactsCreated = ch.recentArtifacts(taskID)
 		return result, nil
 	}
 
 	// State not updated - need to enforce completion handshake
-	return ch.enforceHandshake(ctx, taskID, initialState, llmResponse)
+	hsCtx := ctx
+	if ch.config.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		hsCtx, cancel = context.WithTimeout(ctx, time.Duration(ch.config.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+	return ch.enforceHandshake(ctx, hsCtx, taskID, initialState, llmResponse, followUp)
 }
 
-// enforceHandshake performs the completion handshake enforcement
-func (ch *CompletionHandshake) enforceHandshake(ctx context.Context, taskID string, initialState storage.State, llmResponse *llm.Response) (*HandshakeResult, error) {
+// enforceHandshake performs the completion handshake enforcement. ctx is the
+// cycle's context and hsCtx the handshake's, so a handshake timeout can be
+// told apart from the cycle being cancelled or running out of time.
+func (ch *CompletionHandshake) enforceHandshake(ctx, hsCtx context.Context, taskID string, initialState storage.State, llmResponse *llm.Response, followUp FollowUpFunc) (*HandshakeResult, error) {
 	result := &HandshakeResult{
 		Success:    false,
 		FinalState: initialState,
 	}
 
+	// The agent may have answered with a structured outcome instead of
+	// updating the state, which makes a follow-up unnecessary
+	if llmResponse != nil {
+		if done, err := ch.applyOutcome(result, taskID, initialState, llmResponse.Content); done || err != nil {
+			return result, err
+		}
+	}
+
 	// Attempt follow-up prompts with bounded retries
 	for retry := 0; retry < ch.config.MaxRetries; retry++ {
+		if ch.onAttempt != nil {
+			ch.onAttempt(retry + 1)
+		}
+
 		// Add delay between retries
 		if retry > 0 {
 			select {
 			case <-time.After(time.Duration(ch.config.RetryDelaySeconds) * time.Second):
-			case <-ctx.Done():
-				return nil, ctx.Err()
+			case <-hsCtx.Done():
 			}
 		}
+		if hsCtx.Err() != nil {
+			break
+		}
 
 		// Check if state was updated in the meantime
-		task, err := ch.store.GetTask(taskID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get task during handshake: %w", err)
+		note := "Task state successfully updated"
+		if retry > 0 {
+			note = fmt.Sprintf("Task state updated after follow-up %d", retry)
 		}
-
-		if task.State != initialState {
-			// Success - state was updated
-			result.Success = true
-			result.FinalState = task.State
-			result.Note = fmt.Sprintf("Task state updated after follow-up %d", retry+1)
-			return result, nil
+		if done, err := ch.checkUpdated(result, taskID, initialState, note); done || err != nil {
+			return result, err
 		}
 
 		// Add follow-up to record
 		followUpMsg := ch.config.FollowUpTemplate
 		result.FollowUps = append(result.FollowUps, followUpMsg)
+		if followUp == nil {
+			continue
+		}
+
+		response, err := followUp(hsCtx, ch.followUpPrompt(taskID, initialState))
+		if hsCtx.Err() != nil {
+			break
+		}
+		if err != nil {
+			result.FollowUps = append(result.FollowUps, fmt.Sprintf("follow-up %d failed: %v", retry+1, err))
+			continue
+		}
+		if done, err := ch.applyOutcome(result, taskID, initialState, response.Content); done || err != nil {
+			return result, err
+		}
+	}
+
+	// The cycle itself was cancelled or ran out of time
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// A follow-up may have landed the state change just before the end
+	if done, err := ch.checkUpdated(result, taskID, initialState, "Task state updated after the last follow-up"); done || err != nil {
+		return result, err
+	}
 
-		// In a real implementation, we would send the follow-up to the LLM
-		// For now, we'll simulate waiting and checking again
-		// TODO: Implement actual follow-up mechanism with LLM
+	var note string
+	if hsCtx.Err() != nil {
+		result.Outcome = HandshakeTimeout
+		note = fmt.Sprintf("Completion handshake timed out after %ds. Agent did not update task state.", ch.config.TimeoutSeconds)
+	} else {
+		result.Outcome = HandshakeExhausted
+		note = fmt.Sprintf("Completion handshake failed after %d retries. Agent did not update task state.", ch.config.MaxRetries)
 	}
+	result.Note = note
 
 	// All retries exhausted - set task to needs_fixes
 	if ch.config.RequireExplicitStateUpdate {
-		note := fmt.Sprintf("Completion handshake failed after %d retries. Agent did not update task state.", ch.config.MaxRetries)
-		
 		if err := ch.store.UpdateTaskState(taskID, storage.NeedsFixes, note); err != nil {
 			return nil, fmt.Errorf("failed to set task to needs_fixes: %w", err)
 		}
 
-			result.FinalState = storage.NeedsFixes
-		result.Note = note
+		result.FinalState = storage.NeedsFixes
 	}
 
 	return result, nil
 }
 
+// checkUpdated reports whether the task has left its initial state, recording
+// the success in result
+func (ch *CompletionHandshake) checkUpdated(result *HandshakeResult, taskID string, initialState storage.State, note string) (bool, error) {
+	task, err := ch.store.GetTask(taskID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get task during handshake: %w", err)
+	}
+	if task.State == initialState {
+		return false, nil
+	}
+
+	result.Success = true
+	result.Outcome = HandshakeUpdated
+	result.FinalState = task.State
+	result.ArtifactsCreated = ch.recentArtifacts(taskID)
+	result.Note = note
+	return true, nil
+}
+
+// applyOutcome acts on a structured outcome in an agent reply. An outcome with
+// a valid next state is applied; one without is an explicit refusal, which
+// leaves the task where it is. It reports whether the handshake is over.
+func (ch *CompletionHandshake) applyOutcome(result *HandshakeResult, taskID string, initialState storage.State, content string) (bool, error) {
+	outcome, ok := parseStructuredOutcome(content)
+	if !ok {
+		return false, nil
+	}
+	if assessment := assessmentFrom(outcome); assessment != nil {
+		result.Assessment = assessment
+	}
+	// A self-assessment alone neither changes nor declines the state
+	if outcome.Reason == "" && outcome.NextState == "" && outcome.Summary == "" {
+		return false, nil
+	}
+	reason := outcome.Reason
+	if reason == "" {
+		reason = outcome.Summary
+	}
+
+	// The agent may have updated the state itself as well
+	if done, err := ch.checkUpdated(result, taskID, initialState, "Task state successfully updated"); done || err != nil {
+		return done, err
+	}
+
+	if outcome.NextState == "" || storage.State(outcome.NextState) == initialState {
+		result.Outcome = HandshakeRefused
+		result.Note = "Agent declined to update the task state"
+		if reason != "" {
+			result.Note += ": " + reason
+		}
+		return true, nil
+	}
+
+	// The outcome is checked in full, its artifacts as if saved, before any
+	// of it is written; a rejected outcome leaves the task as it was
+	nextState := storage.State(outcome.NextState)
+	if err := statemachine.ValidateTransition(initialState, nextState); err != nil {
+		result.FollowUps = append(result.FollowUps, fmt.Sprintf("rejected structured outcome: %v", err))
+		return false, nil
+	}
+	artifacts, err := ch.outcomeArtifacts(taskID, outcome.Artifacts)
+	if err != nil {
+		result.FollowUps = append(result.FollowUps, fmt.Sprintf("rejected structured outcome: %v", err))
+		return false, nil
+	}
+	pending := pendingArtifacts{store: ch.store, artifacts: artifacts}
+	if err := ch.validateCompletion(pending, taskID, initialState, nextState); err != nil {
+		result.FollowUps = append(result.FollowUps, fmt.Sprintf("rejected structured outcome: %v", err))
+		return false, nil
+	}
+	if err := ch.store.UpdateTaskStateWithArtifacts(taskID, nextState, reason, artifacts); err != nil {
+		return false, fmt.Errorf("failed to apply structured outcome: %w", err)
+	}
+
+	result.Success = true
+	result.Outcome = HandshakeUpdated
+	result.FinalState = nextState
+	result.ArtifactsCreated = ch.recentArtifacts(taskID)
+	result.Note = fmt.Sprintf("Task state updated from the agent's structured outcome: %s", reason)
+	return true, nil
+}
+
+// outcomeArtifacts returns the artifacts of a structured outcome, in name
+// order, to be saved as the agent would have through MCP. Every artifact is
+// checked against its schema.
+func (ch *CompletionHandshake) outcomeArtifacts(taskID string, contents map[string]string) ([]*storage.Artifact, error) {
+	names := make([]string, 0, len(contents))
+	for name, content := range contents {
+		if err := statemachine.ValidateArtifact(ch.schemas, name, content); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	artifacts := make([]*storage.Artifact, len(names))
+	for i, name := range names {
+		artifacts[i] = &storage.Artifact{TaskID: taskID, Name: name, Content: contents[name]}
+	}
+	return artifacts, nil
+}
+
+// pendingArtifacts reads a task's artifacts as they will be once artifacts,
+// not yet saved, are
+type pendingArtifacts struct {
+	store     *storage.Store
+	artifacts []*storage.Artifact
+}
+
+func (p pendingArtifacts) GetArtifact(taskID, name string, version int) (*storage.Artifact, error) {
+	if version == 0 {
+		for _, artifact := range p.artifacts {
+			if artifact.TaskID == taskID && artifact.Name == name {
+				return artifact, nil
+			}
+		}
+	}
+	return p.store.GetArtifact(taskID, name, version)
+}
+
+// followUpPrompt builds the follow-up sent to the agent
+func (ch *CompletionHandshake) followUpPrompt(taskID string, state storage.State) string {
+	var b strings.Builder
+	b.WriteString(ch.config.FollowUpTemplate)
+	fmt.Fprintf(&b, "\n\nTask %s is still in state %s.", taskID, state)
+	if allowed, err := statemachine.GetAllowedTransitions(state); err == nil && len(allowed) > 0 {
+		names := make([]string, len(allowed))
+		for i, s := range allowed {
+			names[i] = string(s)
+		}
+		fmt.Fprintf(&b, " Allowed next states: %s.", strings.Join(names, ", "))
+	}
+	b.WriteString("\nTo answer with a structured outcome, reply with a JSON object such as " +
+		`{"reason": "...", "next_state": "...", "summary": "...", "artifacts": {"change_summary": "..."}, "confidence": 0.8, "open_questions": []}` +
+		". artifacts holds the handover artifacts the transition needs, if you have not saved them yet. " +
+		"Leave next_state empty to decline changing the state, explaining why in reason.\n")
+	return b.String()
+}
+
+// recentArtifacts lists artifacts created during this cycle
+func (ch *CompletionHandshake) recentArtifacts(taskID string) []string {
+	var names []string
+	artifacts, err := ch.store.ListArtifacts(taskID)
+	if err == nil {
+		for _, artifact := range artifacts {
+			// Consider artifacts created in the last few seconds as "new"
+			if time.Since(artifact.CreatedAt) < 30*time.Second {
+				names = append(names, artifact.Name)
+			}
+		}
+	}
+	return names
+}
+
+// parseStructuredOutcome finds the last JSON object in content that carries a
+// reason, next state, summary or self-assessment
+func parseStructuredOutcome(content string) (*structuredOutcome, bool) {
+	for i := strings.LastIndex(content, "{"); i >= 0; i = strings.LastIndex(content[:i], "{") {
+		var outcome structuredOutcome
+		if err := json.NewDecoder(strings.NewReader(content[i:])).Decode(&outcome); err != nil {
+			continue
+		}
+		if outcome.Reason != "" || outcome.NextState != "" || outcome.Summary != "" || outcome.Confidence != nil || len(outcome.OpenQuestions) > 0 {
+			return &outcome, true
+		}
+	}
+	return nil, false
+}
+
+// SetArtifactSchemas makes the artifacts of structured outcomes check
+// against their configured schemas
+func (ch *CompletionHandshake) SetArtifactSchemas(schemas map[string]config.ArtifactSchema) {
+	ch.schemas = schemas
+}
+
+// SetTransitionRules makes structured outcomes check the configured
+// conditions on handover artifact metadata
+func (ch *CompletionHandshake) SetTransitionRules(rules []config.TransitionRule) {
+	ch.rules = rules
+}
+
 // ValidateCompletion validates that completion requirements are met
 func (ch *CompletionHandshake) ValidateCompletion(taskID string, fromState, toState storage.State) error {
-	// Check required handover artifacts
-	requiredArtifacts := getRequiredHandovers(fromState, toState)
+	return ch.validateCompletion(ch.store, taskID, fromState, toState)
+}
+
+// validateCompletion is ValidateCompletion reading artifacts from artifacts
+func (ch *CompletionHandshake) validateCompletion(artifacts statemachine.ArtifactGetter, taskID string, fromState, toState storage.State) error {
+	// Check required handover artifacts, and those transition rules check
+	requiredArtifacts := append(getRequiredHandovers(fromState, toState), statemachine.RuleArtifacts(ch.rules, fromState, toState)...)
 
 	for _, artifactName := range requiredArtifacts {
-		artifact, err := ch.store.GetArtifact(taskID, artifactName, 0) // Get latest version
+		artifact, err := artifacts.GetArtifact(taskID, artifactName, 0) // Get latest version
 		if err != nil {
 			return fmt.Errorf("required handover artifact '%s' not found for transition %s->%s", artifactName, fromState, toState)
 		}
@@ -149,7 +404,14 @@ func (ch *CompletionHandshake) ValidateCompletion(taskID string, fromState, toSt
 		}
 	}
 
-	return nil
+	if len(ch.rules) == 0 {
+		return nil
+	}
+	task, err := ch.store.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	return statemachine.CheckTransitionRules(artifacts, ch.rules, task, toState)
 }
 
 // getRequiredHandovers returns required handover artifacts for a transition
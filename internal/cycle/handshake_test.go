@@ -0,0 +1,78 @@
+package cycle
+
+import (
+	"path/filepath"
+	"testing"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+func TestApplyOutcome(t *testing.T) {
+	store, err := storage.NewStore(filepath.Join(t.TempDir(), "handshake.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &storage.Task{Title: "Implemented task", State: storage.Implementing, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	summary := &storage.Artifact{TaskID: task.ID, Name: "change_summary", Content: "first draft",
+		Meta: []byte(`{"tests_passed": false}`)}
+	if err := store.UpsertArtifact(summary); err != nil {
+		t.Fatalf("Failed to create artifact: %v", err)
+	}
+
+	ch := NewCompletionHandshake(store, &config.CompletionConfig{})
+	ch.SetTransitionRules([]config.TransitionRule{{
+		From: string(storage.Implementing), To: string(storage.ReadyForCodeReview),
+		Artifact: "change_summary", Field: "tests_passed", Value: true,
+	}})
+
+	// The outcome's change_summary carries no metadata, so the rule refuses
+	// it: nothing of the outcome may be saved
+	outcome := `{"reason": "done", "next_state": "ready_for_code_review",
+		"artifacts": {"change_summary": "final", "notes": "extra"}}`
+	result := &HandshakeResult{}
+	done, err := ch.applyOutcome(result, task.ID, storage.Implementing, outcome)
+	if err != nil {
+		t.Fatalf("Failed to apply outcome: %v", err)
+	}
+	if done || len(result.FollowUps) != 1 {
+		t.Fatalf("Expected the outcome to be rejected with a follow-up, got done %v and %v", done, result.FollowUps)
+	}
+
+	if got, _ := store.GetTask(task.ID); got.State != storage.Implementing {
+		t.Errorf("Expected the task to stay %s, got %s", storage.Implementing, got.State)
+	}
+	latest, err := store.GetArtifact(task.ID, "change_summary", 0)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	if latest.Version != 1 || latest.Content != "first draft" {
+		t.Errorf("Expected change_summary to stay at v1, got v%d %q", latest.Version, latest.Content)
+	}
+	if _, err := store.GetArtifact(task.ID, "notes", 0); err == nil {
+		t.Error("Expected the rejected outcome's notes not to be saved")
+	}
+
+	// Without the rule the same outcome is applied, artifacts and all
+	ch.SetTransitionRules(nil)
+	result = &HandshakeResult{}
+	done, err = ch.applyOutcome(result, task.ID, storage.Implementing, outcome)
+	if err != nil {
+		t.Fatalf("Failed to apply outcome: %v", err)
+	}
+	if !done || result.Outcome != HandshakeUpdated || result.FinalState != storage.ReadyForCodeReview {
+		t.Fatalf("Expected the outcome to be applied, got %+v", result)
+	}
+	latest, err = store.GetArtifact(task.ID, "change_summary", 0)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	if latest.Version != 2 || latest.Content != "final" {
+		t.Errorf("Expected change_summary v2 from the outcome, got v%d %q", latest.Version, latest.Content)
+	}
+}
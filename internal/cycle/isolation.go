@@ -0,0 +1,110 @@
+package cycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"baton/internal/llm"
+	"baton/internal/storage"
+	"baton/internal/worktree"
+)
+
+// isolatedCycle is the git worktree a cycle's agent works in when
+// isolation.mode is worktree. A nil *isolatedCycle means no isolation, so
+// its methods can be called either way.
+type isolatedCycle struct {
+	store      *storage.Store
+	tree       *worktree.Worktree
+	taskID     string
+	keepFailed bool
+	finished   bool
+}
+
+// isolate creates the worktree of a cycle, or returns nil when isolation is
+// off or the cycle is a dry run
+func (ce *CycleEngine) isolate(task *storage.Task, cycleID string, dryRun bool) (*isolatedCycle, error) {
+	if dryRun || ce.config.Isolation.Mode != "worktree" {
+		return nil, nil
+	}
+
+	branch := fmt.Sprintf("%s%s-%s", ce.config.Isolation.BranchPrefix, task.ID, shortID(cycleID))
+	tree, err := worktree.Create(ce.config.Workspace, ce.config.Isolation.Dir, branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to isolate cycle: %w", err)
+	}
+	return &isolatedCycle{store: ce.store, tree: tree, taskID: task.ID, keepFailed: ce.config.Isolation.KeepFailed}, nil
+}
+
+// context makes agents run in the worktree
+func (ic *isolatedCycle) context(ctx context.Context) context.Context {
+	if ic == nil {
+		return ctx
+	}
+	return llm.WithWorkDir(ctx, ic.tree.Dir)
+}
+
+// workDir returns where the agent works: the worktree, or workspace
+func (ic *isolatedCycle) workDir(workspace string) string {
+	if ic == nil {
+		return workspace
+	}
+	return ic.tree.Dir
+}
+
+// promptNote tells the agent where it works
+func (ic *isolatedCycle) promptNote() string {
+	if ic == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n\nNote: you are working in a git worktree of your own on branch %s (%s). "+
+		"Your changes are merged into the main branch only if this cycle completes successfully.", ic.tree.Branch, ic.tree.Dir)
+}
+
+// merge merges the changes of a successful cycle. A failed merge keeps the
+// branch and is noted on the task, since the cycle's work is otherwise done.
+func (ic *isolatedCycle) merge(result *storage.CycleResult) error {
+	if ic == nil || ic.finished {
+		return nil
+	}
+	ic.finished = true
+
+	message := fmt.Sprintf("Task %s: %s → %s\n\nCycle %s", result.TaskID, result.PrevState, result.NextState, result.CycleID)
+	if err := ic.tree.Merge(message); err != nil {
+		ic.note(fmt.Sprintf("Merging the changes of cycle %s failed: %v", result.CycleID, err))
+		return fmt.Errorf("failed to merge cycle changes: %w", err)
+	}
+	return nil
+}
+
+// discard throws away the changes of a cycle that did not succeed, or with
+// isolation.keep_failed keeps its branch; it does nothing after merge
+func (ic *isolatedCycle) discard() {
+	if ic == nil || ic.finished {
+		return
+	}
+	ic.finished = true
+
+	if err := ic.tree.Discard(ic.keepFailed); err != nil {
+		log.Printf("Failed to discard worktree %s: %v", ic.tree.Path, err)
+		return
+	}
+	if ic.keepFailed {
+		ic.note(fmt.Sprintf("The changes of a failed cycle are kept on branch %s", ic.tree.Branch))
+	}
+}
+
+// note records a note on the cycle's task
+func (ic *isolatedCycle) note(body string) {
+	if err := ic.store.AddTaskNote(&storage.TaskNote{TaskID: ic.taskID, Author: "baton", Body: body}); err != nil {
+		log.Printf("Failed to note on task %s: %v", ic.taskID, err)
+	}
+}
+
+// shortID returns the first eight characters of an ID, for branch names
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
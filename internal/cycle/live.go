@@ -0,0 +1,113 @@
+package cycle
+
+import (
+	"sync"
+	"time"
+
+	"baton/internal/storage"
+)
+
+// Phases of a cycle reported by CurrentCycle
+const (
+	PhaseExecuting = "executing" // the agent is working through the LLM
+	PhaseHandshake = "handshake" // waiting for the agent to update the task state
+	PhaseHooks     = "hooks"     // running the after_success cycle hooks
+	PhaseReview    = "review"    // waiting for a human to approve the transition
+	PhaseMerging   = "merging"   // merging the changes of an isolated cycle
+	PhaseRecording = "recording" // writing the audit entry
+)
+
+// LLMEvent is the most recent thing that happened between the engine and the LLM
+type LLMEvent struct {
+	Type   string    `json:"type"` // request, response, error, retry, fallback, escalation or budget
+	Tier   string    `json:"tier,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// LiveCycle is a snapshot of the cycle an engine is executing
+type LiveCycle struct {
+	CycleID           string        `json:"cycle_id"`
+	TaskID            string        `json:"task_id"`
+	TaskTitle         string        `json:"task_title"`
+	TaskState         storage.State `json:"task_state"`
+	Agent             string        `json:"agent"`
+	Phase             string        `json:"phase"`
+	ModelTier         string        `json:"model_tier,omitempty"`
+	StartedAt         time.Time     `json:"started_at"`
+	ElapsedSeconds    float64       `json:"elapsed_seconds"`
+	TimeboxSeconds    int           `json:"timebox_seconds,omitempty"`
+	LastLLMEvent      *LLMEvent     `json:"last_llm_event,omitempty"`
+	HandshakeAttempts int           `json:"handshake_attempts"`
+	DryRun            bool          `json:"dry_run"`
+}
+
+// OutputChunk is a piece of agent output streamed while a cycle executes
+type OutputChunk struct {
+	CycleID string `json:"cycle_id"`
+	TaskID  string `json:"task_id"`
+	Content string `json:"content"`
+}
+
+// liveTracker holds the in-flight cycle, if any, for concurrent readers
+type liveTracker struct {
+	mu      sync.RWMutex
+	current *LiveCycle
+}
+
+// begin records that a cycle started working on task
+func (lt *liveTracker) begin(cycle *LiveCycle) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.current = cycle
+}
+
+// end clears the in-flight cycle if it is still the given one
+func (lt *liveTracker) end(cycleID string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	if lt.current != nil && lt.current.CycleID == cycleID {
+		lt.current = nil
+	}
+}
+
+// update applies fn to the in-flight cycle, if any
+func (lt *liveTracker) update(fn func(cycle *LiveCycle)) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	if lt.current != nil {
+		fn(lt.current)
+	}
+}
+
+// llmEvent records the latest LLM event
+func (lt *liveTracker) llmEvent(eventType, tier, detail string) {
+	lt.update(func(cycle *LiveCycle) {
+		cycle.LastLLMEvent = &LLMEvent{Type: eventType, Tier: tier, Detail: detail, At: time.Now()}
+		if tier != "" {
+			cycle.ModelTier = tier
+		}
+	})
+}
+
+// snapshot returns a copy of the in-flight cycle with its elapsed time filled in
+func (lt *liveTracker) snapshot() (*LiveCycle, bool) {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	if lt.current == nil {
+		return nil, false
+	}
+
+	cycle := *lt.current
+	if cycle.LastLLMEvent != nil {
+		event := *cycle.LastLLMEvent
+		cycle.LastLLMEvent = &event
+	}
+	cycle.ElapsedSeconds = time.Since(cycle.StartedAt).Seconds()
+	return &cycle, true
+}
+
+// CurrentCycle returns the cycle the engine is executing, if any
+func (ce *CycleEngine) CurrentCycle() (*LiveCycle, bool) {
+	return ce.live.snapshot()
+}
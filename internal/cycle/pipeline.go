@@ -0,0 +1,162 @@
+package cycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"baton/internal/storage"
+)
+
+// PipelineStage is the cycle one agent of a pipeline ran
+type PipelineStage struct {
+	Agent  string               `json:"agent"` // agent ID
+	Result *storage.CycleResult `json:"result,omitempty"`
+	Error  string               `json:"error,omitempty"` // why the cycle did not complete
+}
+
+// PipelineResult is the combined outcome of a pipeline's cycles on one task
+type PipelineResult struct {
+	Pipeline         string           `json:"pipeline"`
+	TaskID           string           `json:"task_id"`
+	PrevState        storage.State    `json:"prev_state"`        // before the first stage
+	NextState        storage.State    `json:"next_state"`        // after the last stage that ran
+	Stages           []*PipelineStage `json:"stages"`            // the stages that ran, in order
+	Completed        bool             `json:"completed"`         // every stage ran, succeeded and moved the task on
+	Stopped          string           `json:"stopped,omitempty"` // why the later stages did not run
+	Duration         time.Duration    `json:"duration"`
+	PromptTokens     int              `json:"prompt_tokens,omitempty"`
+	CompletionTokens int              `json:"completion_tokens,omitempty"`
+	CostUSD          float64          `json:"cost_usd,omitempty"`
+}
+
+type pipelineStageKey struct{}
+
+// withPipelineStage labels the cycle run with ctx as a stage of a pipeline
+func withPipelineStage(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, pipelineStageKey{}, label)
+}
+
+// pipelineStageFrom returns the pipeline stage label of a cycle, or ""
+func pipelineStageFrom(ctx context.Context) string {
+	label, _ := ctx.Value(pipelineStageKey{}).(string)
+	return label
+}
+
+// ExecutePipeline runs the cycles of the named pipeline's agents one after
+// the other on the given task, or on the next task the first stage's agent
+// handles when taskID is "". Each stage is a cycle of its own, with its own
+// audit entry, and works on the artifacts and merged changes of the stages
+// before it. A stage runs only once the one before it moved the task to a
+// state the stage's agent handles.
+func (ce *CycleEngine) ExecutePipeline(ctx context.Context, name, taskID string, dryRun bool) (*PipelineResult, error) {
+	pipeline, ok := ce.config.PipelineByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown pipeline %q", name)
+	}
+
+	// The first stage takes a task its agent handles
+	first := pipeline.Stages[0]
+	if taskID == "" {
+		ce.selector.SetStateFilter(func(state storage.State) bool { return ce.handles(first, state) })
+		defer ce.selector.SetStateFilter(nil)
+	} else if err := ce.checkStage(first, taskID); err != nil {
+		return nil, fmt.Errorf("pipeline %s cannot start: %w", name, err)
+	}
+
+	start := time.Now()
+	result := &PipelineResult{Pipeline: name}
+	for i, agentID := range pipeline.Stages {
+		if i > 0 {
+			if err := ce.checkStage(agentID, taskID); err != nil {
+				result.Stopped = fmt.Sprintf("stage %d (%s) did not run: %v", i+1, agentID, err)
+				break
+			}
+		}
+
+		label := fmt.Sprintf("Pipeline %s, stage %d of %d (%s)", name, i+1, len(pipeline.Stages), agentID)
+		cycleResult, err := ce.ExecuteCycleForTask(withPipelineStage(ctx, label), taskID, dryRun)
+		if err != nil && i == 0 {
+			return nil, err
+		}
+
+		stage := &PipelineStage{Agent: agentID, Result: cycleResult}
+		result.Stages = append(result.Stages, stage)
+		if err != nil {
+			stage.Error = err.Error()
+			result.Stopped = fmt.Sprintf("stage %d (%s) failed: %v", i+1, agentID, err)
+			break
+		}
+
+		if i == 0 {
+			taskID = cycleResult.TaskID
+			result.TaskID = cycleResult.TaskID
+			result.PrevState = cycleResult.PrevState
+		}
+		result.NextState = cycleResult.NextState
+		result.PromptTokens += cycleResult.PromptTokens
+		result.CompletionTokens += cycleResult.CompletionTokens
+		result.CostUSD += cycleResult.CostUSD
+
+		if cycleResult.Error != nil {
+			stage.Error = cycleResult.Error.Error()
+			result.Stopped = fmt.Sprintf("stage %d (%s) failed: %v", i+1, agentID, cycleResult.Error)
+			break
+		}
+		if cycleResult.Outcome != "success" {
+			result.Stopped = fmt.Sprintf("stage %d (%s) ended in %s, leaving the task in %s", i+1, agentID, cycleResult.Outcome, cycleResult.NextState)
+			break
+		}
+		if cycleResult.NextState == cycleResult.PrevState && !dryRun {
+			result.Stopped = fmt.Sprintf("stage %d (%s) left the task in %s", i+1, agentID, cycleResult.NextState)
+			break
+		}
+	}
+
+	result.Completed = result.Stopped == ""
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// checkStage checks that the agent of a pipeline stage handles the state
+// the task is in
+func (ce *CycleEngine) checkStage(agentID, taskID string) error {
+	task, err := ce.store.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("task %s not found: %w", taskID, err)
+	}
+	if !ce.handles(agentID, task.State) {
+		return fmt.Errorf("task %s is %s, which agent %s does not handle", task.ID, task.State, agentID)
+	}
+	return nil
+}
+
+// handles reports whether a cycle on a task in state would be the agent's
+func (ce *CycleEngine) handles(agentID string, state storage.State) bool {
+	handler, ok := ce.config.AgentIDForState(string(state))
+	return ok && handler == agentID
+}
+
+// PreviewPipeline previews the first stage of the named pipeline on the
+// given task, or on the next task the stage's agent handles when taskID is ""
+func (ce *CycleEngine) PreviewPipeline(name, taskID string) (*Preview, error) {
+	pipeline, ok := ce.config.PipelineByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown pipeline %q", name)
+	}
+
+	first := pipeline.Stages[0]
+	if taskID == "" {
+		ce.selector.SetStateFilter(func(state storage.State) bool { return ce.handles(first, state) })
+		defer ce.selector.SetStateFilter(nil)
+	} else if err := ce.checkStage(first, taskID); err != nil {
+		return nil, fmt.Errorf("pipeline %s cannot start: %w", name, err)
+	}
+
+	preview, err := ce.Preview(taskID)
+	if err != nil {
+		return nil, err
+	}
+	preview.Pipeline = pipeline.Stages
+	return preview, nil
+}
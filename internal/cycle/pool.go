@@ -0,0 +1,246 @@
+package cycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/llm"
+	"baton/internal/mcp"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// maxClaimAttempts bounds how often a cycle selects again after another
+// process locked the task it selected
+const maxClaimAttempts = 5
+
+// lockHolder identifies this process in task and area locks
+func lockHolder() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// taskLockHolder identifies a cycle in the lock on its task
+func taskLockHolder(cycleID string) string {
+	return fmt.Sprintf("cycle %s (%s)", cycleID, lockHolder())
+}
+
+// IsNoTaskError reports whether a cycle failed because no task could be selected
+func IsNoTaskError(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "no selectable tasks") || strings.Contains(err.Error(), "no unblocked tasks"))
+}
+
+// claimTask selects the cycle's task and, unless dry-running, locks it and
+// the areas the cycle changes, so that parallel workers never work the same
+// task or area. The engines of a pool claim one at a time. release gives up
+// the locks once the cycle is over.
+func (ce *CycleEngine) claimTask(taskID, cycleID string, dryRun bool) (*statemachine.SelectionResult, func(), error) {
+	if ce.claimMu != nil {
+		ce.claimMu.Lock()
+		defer ce.claimMu.Unlock()
+	}
+
+	for attempt := 1; ; attempt++ {
+		var selection *statemachine.SelectionResult
+		var err error
+		if taskID != "" {
+			selection, err = ce.selector.SelectTask(taskID)
+		} else {
+			selection, err = ce.selector.SelectNext()
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("task selection failed: %w", err)
+		}
+		if dryRun {
+			return selection, func() {}, nil
+		}
+
+		task := selection.Task
+		holder := taskLockHolder(cycleID)
+		if err := ce.store.LockTask(task.ID, holder); err != nil {
+			// Another process locked the task since it was selected; selecting
+			// again passes it over
+			var lockErr *storage.TaskLockedError
+			if errors.As(err, &lockErr) && taskID == "" && attempt < maxClaimAttempts {
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to lock task %s: %w", task.ID, err)
+		}
+		unlock := func() {
+			if err := ce.store.UnlockTask(task.ID, holder); err != nil {
+				log.Printf("Failed to unlock task %s: %v", task.ID, err)
+			}
+		}
+
+		releaseAreas, err := ce.acquireAreaLocks(task, cycleID, CycleTimeout(ce.config, task))
+		if err != nil {
+			unlock()
+			return nil, nil, err
+		}
+
+		stopRenewal := ce.renewTaskLock(task.ID, holder)
+		return selection, func() {
+			stopRenewal()
+			releaseAreas()
+			unlock()
+		}, nil
+	}
+}
+
+// renewTaskLock renews a task lock until the returned function is called, so
+// that only the locks of runs that died expire
+func (ce *CycleEngine) renewTaskLock(taskID, holder string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(storage.TaskLockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := ce.store.LockTask(taskID, holder); err != nil {
+					log.Printf("Failed to renew the lock on task %s: %v", taskID, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Pool runs cycles on several tasks at once. Each worker has its own engine;
+// all of them share one MCP server, which tells the agents' requests apart by
+// the cycle endpoint they connect to.
+type Pool struct {
+	engines []*CycleEngine
+	server  *mcp.Server
+	claimMu sync.Mutex
+}
+
+// PoolCycle is the outcome of one cycle of a pool worker
+type PoolCycle struct {
+	Worker int // 1-based
+	Result *storage.CycleResult
+	Err    error
+}
+
+// PoolRun configures Pool.Run
+type PoolRun struct {
+	DryRun   bool
+	Cooldown time.Duration // pause of each worker between its cycles
+
+	// Next is asked before each cycle whether a worker may start another,
+	// given how many cycles are running
+	Next func(worker, running int) bool
+
+	// Report receives the outcome of each cycle that got as far as selecting
+	// a task, and returns false to start no more cycles
+	Report func(cycle PoolCycle) bool
+}
+
+// NewPool creates a pool of size workers
+func NewPool(store *storage.Store, config *config.Config, llmClient llm.Client, size int) *Pool {
+	pool := &Pool{server: mcp.NewServer(store, config)}
+	ServePrompts(pool.server, store, config)
+	for i := 0; i < size; i++ {
+		engine := NewCycleEngine(store, config, llmClient)
+		engine.UseMCPServer(pool.server)
+		engine.claimMu = &pool.claimMu
+		pool.engines = append(pool.engines, engine)
+	}
+	return pool
+}
+
+// Size returns the number of workers
+func (p *Pool) Size() int {
+	return len(p.engines)
+}
+
+// SetOutputHandler passes the agent output of every worker to fn, which is
+// called from several goroutines at once
+func (p *Pool) SetOutputHandler(fn func(chunk *OutputChunk)) {
+	for _, engine := range p.engines {
+		engine.SetOutputHandler(fn)
+	}
+}
+
+// SetUntilState makes every worker only select tasks that have not yet
+// reached state in the workflow
+func (p *Pool) SetUntilState(state storage.State) {
+	for _, engine := range p.engines {
+		engine.SetUntilState(state)
+	}
+}
+
+// Run keeps every worker executing cycles until Next or Report stops them or
+// no task is left for any of them; ctx cancels the cycles in flight. Calls to
+// Next and Report are serialized. A worker that finds no task to select waits
+// while other workers' cycles run, since finishing them may unblock tasks.
+// noTasksLeft reports whether the run ended for lack of tasks.
+func (p *Pool) Run(ctx context.Context, run PoolRun) (noTasksLeft bool, err error) {
+	if !run.DryRun {
+		if err := p.server.StartHTTP(); err != nil {
+			return false, fmt.Errorf("failed to start MCP server: %w", err)
+		}
+		defer p.server.Stop()
+	}
+
+	var mu sync.Mutex
+	finished := sync.NewCond(&mu)
+	running := 0
+	stopped := false
+
+	var wg sync.WaitGroup
+	for i, engine := range p.engines {
+		wg.Add(1)
+		go func(worker int, engine *CycleEngine) {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			for !stopped {
+				if !run.Next(worker, running) {
+					stopped = true
+					break
+				}
+
+				running++
+				mu.Unlock()
+				result, err := engine.ExecuteCycleForTask(ctx, "", run.DryRun)
+				mu.Lock()
+				running--
+				finished.Broadcast()
+
+				if IsNoTaskError(err) {
+					if running == 0 {
+						noTasksLeft = true
+						stopped = true
+						break
+					}
+					finished.Wait()
+					continue
+				}
+				if !run.Report(PoolCycle{Worker: worker, Result: result, Err: err}) {
+					stopped = true
+					break
+				}
+
+				if run.Cooldown > 0 {
+					mu.Unlock()
+					time.Sleep(run.Cooldown)
+					mu.Lock()
+				}
+			}
+			finished.Broadcast()
+		}(i+1, engine)
+	}
+	wg.Wait()
+
+	return noTasksLeft, nil
+}
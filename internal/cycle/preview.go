@@ -0,0 +1,165 @@
+package cycle
+
+import (
+	"fmt"
+	"time"
+
+	"baton/internal/hooks"
+	"baton/internal/llm"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// previewHistory is how far back the cost estimate of a preview looks
+const previewHistory = 30 * 24 * time.Hour
+
+// Preview is what a cycle would do, worked out without running its agent,
+// its hooks or changing anything
+type Preview struct {
+	Task            *storage.Task `json:"task"`
+	SelectionReason string        `json:"selection_reason"`
+	Agent           string        `json:"agent"`
+	AgentRole       string        `json:"agent_role,omitempty"`
+	Provider        string        `json:"provider"`
+	ModelTier       string        `json:"model_tier,omitempty"`       // "" without model tiers
+	EscalationPath  []string      `json:"escalation_path,omitempty"`  // the tiers the cycle may climb, from ModelTier
+	TimeboxSeconds  int           `json:"timebox_seconds"`            // 0 means no limit
+	Isolation       string        `json:"isolation,omitempty"`        // worktree, when the agent works in one
+	PlanUnavailable string        `json:"plan_unavailable,omitempty"` // why the agent can't read the plan
+	Prompt          string        `json:"prompt"`
+
+	// The moves the agent may make from the task's state, with the handovers
+	// each requires, what stands in its way today and the hooks it would run
+	Transitions []*statemachine.TransitionRequirement `json:"transitions"`
+
+	BeforeSelection []hooks.Planned `json:"before_selection,omitempty"` // cycle hooks run before the task is selected
+	AfterSuccess    []hooks.Planned `json:"after_success,omitempty"`    // cycle hooks run once the agent moved the task
+	AllowedCommands []string        `json:"allowed_commands,omitempty"` // what the agent may run through baton.commands.run
+
+	Estimate *CostEstimate `json:"estimate"`
+
+	// The pipeline's agents, when the cycle is the first stage of one; the
+	// later stages depend on where this one leaves the task
+	Pipeline []string `json:"pipeline,omitempty"`
+}
+
+// CostEstimate is what a cycle is expected to cost
+type CostEstimate struct {
+	PromptTokens       int     `json:"prompt_tokens"`             // of the prompt, at four characters a token
+	PromptCostUSD      float64 `json:"prompt_cost_usd,omitempty"` // the prompt priced at the starting tier's model, when llm.pricing lists it
+	PastCycles         int     `json:"past_cycles"`               // the agent's cycles of the last 30 days the averages come from
+	AvgCostUSD         float64 `json:"avg_cost_usd,omitempty"`
+	AvgDurationSeconds float64 `json:"avg_duration_seconds,omitempty"`
+	CostUSD            float64 `json:"cost_usd"` // the best estimate: the average cost, else the prompt's
+	Basis              string  `json:"basis"`    // where CostUSD comes from
+}
+
+// Preview works out what a cycle on the task, or on the next selected one
+// when taskID is "", would do. Unlike a dry run of ExecuteCycleForTask, it
+// renders the prompt, checks every transition the agent could make and
+// estimates the cost.
+func (ce *CycleEngine) Preview(taskID string) (*Preview, error) {
+	var selection *statemachine.SelectionResult
+	var err error
+	if taskID != "" {
+		selection, err = ce.selector.SelectTask(taskID)
+	} else {
+		selection, err = ce.selector.SelectNext()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("task selection failed: %w", err)
+	}
+	task := selection.Task
+
+	agent, err := ce.getAgentForTask(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent for task: %w", err)
+	}
+
+	preview := &Preview{
+		Task:            task,
+		SelectionReason: selection.Reason,
+		Agent:           agent.Name,
+		AgentRole:       agent.Role,
+		Provider:        ce.llmClient.GetName(),
+		ModelTier:       ce.config.StartingTier(string(task.State), agent),
+		TimeboxSeconds:  int(CycleTimeout(ce.config, task) / time.Second),
+		BeforeSelection: hooks.PlanCycle(ce.config.CycleHooks.BeforeSelection),
+		AfterSuccess:    hooks.PlanCycle(ce.config.CycleHooks.AfterSuccess),
+	}
+	preview.EscalationPath = ce.config.EscalationPath(preview.ModelTier)
+	if tier, ok := ce.config.LLM.Tiers[preview.ModelTier]; ok && tier.Provider != "" {
+		preview.Provider = tier.Provider
+	}
+	if ce.config.Isolation.Mode == "worktree" {
+		preview.Isolation = "worktree"
+	}
+	if agent.Permissions.CanExecuteCommands {
+		preview.AllowedCommands = ce.config.Security.AllowedCommands
+	}
+
+	// The prompt as the agent would get it, but for where an isolated cycle's worktree would be
+	planErr := ce.checkPlan()
+	if planErr != nil {
+		preview.PlanUnavailable = planErr.Error()
+	}
+	prompt, err := ce.buildPrompt(task, agent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build prompt: %w", err)
+	}
+	preview.Prompt = prompt + ce.commandsNote(agent.Permissions.CanExecuteCommands) +
+		createTasksNote(agent.Permissions.CanCreateTasks) + planUnavailableNote(planErr)
+
+	next, err := statemachine.GetAllowedTransitions(task.State)
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range next {
+		requirement, err := ce.validator.GetTransitionRequirements(task.ID, state)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the move to %s: %w", state, err)
+		}
+		preview.Transitions = append(preview.Transitions, requirement)
+	}
+
+	preview.Estimate, err = ce.estimateCost(agent.Name, preview.ModelTier, preview.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// estimateCost estimates what a cycle of agent costs from the agent's recent
+// cycles, else from the prompt alone
+func (ce *CycleEngine) estimateCost(agent, tier, prompt string) (*CostEstimate, error) {
+	estimate := &CostEstimate{PromptTokens: llm.CountTokens(prompt)}
+	if model := ce.config.LLM.Tiers[tier].Model; model != "" {
+		estimate.PromptCostUSD, _ = llm.PromptCost(ce.config.LLM.Pricing, model, estimate.PromptTokens)
+	}
+
+	records, err := ce.store.ListCycles(time.Now().Add(-previewHistory), time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list past cycles: %w", err)
+	}
+	totals := &storage.CycleTotals{}
+	for _, record := range records {
+		if record.Agent == agent {
+			totals.Add(record)
+		}
+	}
+
+	estimate.PastCycles = totals.Cycles
+	switch {
+	case totals.Cycles > 0:
+		estimate.AvgCostUSD = totals.CostUSD / float64(totals.Cycles)
+		estimate.AvgDurationSeconds = totals.DurationSeconds / float64(totals.Cycles)
+		estimate.CostUSD = estimate.AvgCostUSD
+		estimate.Basis = fmt.Sprintf("average of the %s's %d cycles in the last 30 days", agent, totals.Cycles)
+	case estimate.PromptCostUSD > 0:
+		estimate.CostUSD = estimate.PromptCostUSD
+		estimate.Basis = "the prompt alone, priced from llm.pricing; the agent's work costs more"
+	default:
+		estimate.Basis = "unknown: the agent has no recent cycles and llm.pricing does not price its model"
+	}
+	return estimate, nil
+}
@@ -0,0 +1,156 @@
+package cycle
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"baton/internal/config"
+	"baton/internal/plan"
+	"baton/internal/storage"
+)
+
+// planExcerptLimit caps the plan text handed to a prompt template when no
+// section of the plan cites the task's requirements
+const planExcerptLimit = 4000
+
+// PromptData is what an agent's prompt template is rendered with
+type PromptData struct {
+	Agent        *config.Agent
+	Task         *storage.Task
+	Artifacts    map[string]string      // latest content of each of the task's artifacts, by name
+	Requirements []*storage.Requirement // requirements linked to the task
+	PlanExcerpt  string                 // plan sections citing those requirements, or the start of the plan
+	Default      string                 // the built-in prompt, for templates that extend it
+}
+
+// promptFuncs are the helpers available to prompt templates
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"truncate": func(n int, s string) string {
+		if len(s) <= n {
+			return s
+		}
+		return s[:n] + "..."
+	},
+}
+
+// LoadPromptTemplate parses a prompt template file. It returns nil without an
+// error when the file does not exist.
+func LoadPromptTemplate(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+
+	tmpl, err := template.New(path).Funcs(promptFuncs).Option("missingkey=zero").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// renderPromptTemplate renders the agent's prompt template, re-read every
+// cycle so edits apply without a restart. Agents without a template, or
+// whose template file does not exist, get the built-in prompt.
+func (ce *CycleEngine) renderPromptTemplate(task *storage.Task, agent *config.Agent, builtin string) (string, error) {
+	path := ce.config.PromptTemplatePath(agent)
+	if path == "" {
+		return builtin, nil
+	}
+	tmpl, err := LoadPromptTemplate(path)
+	if err != nil || tmpl == nil {
+		return builtin, err
+	}
+
+	data, err := ce.promptData(task, agent, builtin)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", path, err)
+	}
+	return b.String(), nil
+}
+
+// promptData gathers the variables a prompt template can use
+func (ce *CycleEngine) promptData(task *storage.Task, agent *config.Agent, builtin string) (*PromptData, error) {
+	data := &PromptData{
+		Agent:     agent,
+		Task:      task,
+		Artifacts: make(map[string]string),
+		Default:   builtin,
+	}
+
+	artifacts, err := ce.store.ListArtifacts(task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	// Artifacts are listed newest version first within each name
+	for _, artifact := range artifacts {
+		if _, seen := data.Artifacts[artifact.Name]; !seen {
+			data.Artifacts[artifact.Name] = artifact.Content
+		}
+	}
+
+	data.Requirements, err = ce.store.ListTaskRequirements(task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked requirements: %w", err)
+	}
+
+	data.PlanExcerpt = planExcerpt(ce.config.PlanFile, data.Requirements)
+	return data, nil
+}
+
+// planExcerpt returns the plan sections that cite any of the requirements, in
+// plan order, or the start of the plan when none do. An unreadable plan gives "".
+func planExcerpt(path string, requirements []*storage.Requirement) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	keys := make(map[string]bool, len(requirements))
+	for _, req := range requirements {
+		keys[req.Key] = true
+	}
+
+	var excerpt []string
+	var section []string
+	cites := false
+	flush := func() {
+		if cites {
+			excerpt = append(excerpt, strings.TrimSpace(strings.Join(section, "\n")))
+		}
+		section, cites = nil, false
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			flush()
+		}
+		section = append(section, line)
+		for _, key := range plan.CitedKeys(line) {
+			cites = cites || keys[key]
+		}
+	}
+	flush()
+
+	if len(excerpt) > 0 {
+		return strings.Join(excerpt, "\n\n")
+	}
+	text := strings.TrimSpace(string(content))
+	if len(text) > planExcerptLimit {
+		text = text[:planExcerptLimit] + "\n..."
+	}
+	return text
+}
@@ -0,0 +1,165 @@
+package cycle
+
+import (
+	"fmt"
+
+	"baton/internal/audit"
+	"baton/internal/config"
+	"baton/internal/storage"
+	"baton/internal/worktree"
+)
+
+// InterruptedCycle is a cycle whose run died before it finished, and what
+// resuming it does
+type InterruptedCycle struct {
+	Checkpoint *storage.CycleCheckpoint
+	Task       *storage.Task // nil when the task no longer exists
+	Audited    bool          // whether the cycle's audit entry was recorded
+	Action     string        // storage.CheckpointCompleted or storage.CheckpointRolledBack
+	Reason     string
+}
+
+// FindInterrupted lists the cycles of runs that died and plans how to resume
+// them. A cycle whose task already moved is completed, unless rollback is
+// set or the move awaited review; any other is rolled back. Checkpoints can't tell the cycles of a dead
+// run from those of a running one, so call it only while holding the
+// workspace lock.
+func FindInterrupted(store *storage.Store, rollback bool) ([]*InterruptedCycle, error) {
+	checkpoints, err := store.ListUnfinishedCheckpoints()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cycle checkpoints: %w", err)
+	}
+
+	var cycles []*InterruptedCycle
+	for _, cp := range checkpoints {
+		ic := &InterruptedCycle{Checkpoint: cp, Action: storage.CheckpointRolledBack}
+		if ic.Audited, err = store.CycleAudited(cp.CycleID); err != nil {
+			return nil, err
+		}
+		ic.Task, err = store.GetTask(cp.TaskID)
+		switch {
+		case err != nil:
+			ic.Task = nil
+			ic.Reason = "the task no longer exists"
+		case ic.Task.State == cp.PrevState:
+			ic.Reason = fmt.Sprintf("the task had not moved from %s", cp.PrevState)
+		case rollback:
+			ic.Reason = fmt.Sprintf("the task had moved from %s to %s, and --rollback undoes that", cp.PrevState, ic.Task.State)
+		case cp.Phase == PhaseReview:
+			ic.Reason = fmt.Sprintf("the task had moved from %s to %s, but the move was never approved in review", cp.PrevState, ic.Task.State)
+		default:
+			ic.Action = storage.CheckpointCompleted
+			ic.Reason = fmt.Sprintf("the task had moved from %s to %s", cp.PrevState, ic.Task.State)
+		}
+		cycles = append(cycles, ic)
+	}
+	return cycles, nil
+}
+
+// Resume completes or rolls back an interrupted cycle as FindInterrupted
+// planned: the cycle's worktree is merged or discarded, its audit entry
+// recorded if it was not, and its task and area locks released. It returns
+// what it did, step by step.
+func Resume(store *storage.Store, cfg *config.Config, ic *InterruptedCycle) ([]string, error) {
+	cp := ic.Checkpoint
+	var done []string
+
+	if ic.Action == storage.CheckpointCompleted {
+		if cp.WorktreePath != "" {
+			done = append(done, resumeWorktree(cfg, cp, func(tree *worktree.Worktree) error {
+				return tree.Merge(fmt.Sprintf("Task %s: %s → %s\n\nCycle %s", cp.TaskID, cp.PrevState, ic.Task.State, cp.CycleID))
+			}, "merged the worktree's changes"))
+		}
+	} else {
+		if ic.Task != nil && ic.Task.State != cp.PrevState {
+			if ic.Audited {
+				// The cycle's move is the last one recorded
+				if _, err := store.RollbackTask(cp.TaskID, "baton", "the cycle was interrupted"); err != nil {
+					return done, fmt.Errorf("failed to roll back task %s: %w", cp.TaskID, err)
+				}
+			} else if err := store.UpdateTaskState(cp.TaskID, cp.PrevState, "rolled back: the cycle was interrupted"); err != nil {
+				return done, fmt.Errorf("failed to roll back task %s: %w", cp.TaskID, err)
+			}
+			done = append(done, fmt.Sprintf("moved the task back from %s to %s", ic.Task.State, cp.PrevState))
+		}
+		if cp.WorktreePath != "" {
+			keep := cfg.Isolation.KeepFailed
+			message := "discarded the worktree"
+			if keep {
+				message = "removed the worktree, keeping branch " + cp.WorktreeBranch
+			}
+			done = append(done, resumeWorktree(cfg, cp, func(tree *worktree.Worktree) error {
+				return tree.Discard(keep)
+			}, message))
+		}
+	}
+
+	if ic.Task != nil {
+		if !ic.Audited {
+			if err := audit.NewLogger(store).LogCycle(resumeAuditEntry(ic)); err != nil {
+				return done, fmt.Errorf("failed to log audit entry: %w", err)
+			}
+			done = append(done, "recorded the cycle's audit entry")
+		}
+		if err := store.UnlockTask(cp.TaskID, cp.Holder); err != nil {
+			return done, fmt.Errorf("failed to unlock task %s: %w", cp.TaskID, err)
+		}
+		note := fmt.Sprintf("Cycle %s was interrupted in its %s phase; baton resume %s: %s", cp.CycleID, cp.Phase, resumeVerb(ic.Action), ic.Reason)
+		if err := store.AddTaskNote(&storage.TaskNote{TaskID: cp.TaskID, Author: "baton", Body: note}); err != nil {
+			return done, fmt.Errorf("failed to note on task %s: %w", cp.TaskID, err)
+		}
+	}
+	if err := store.ReleaseAreaLocks(cp.CycleID); err != nil {
+		return done, fmt.Errorf("failed to release area locks: %w", err)
+	}
+	done = append(done, "released the cycle's locks")
+
+	if err := store.FinishCheckpoint(cp.CycleID, ic.Action); err != nil {
+		return done, fmt.Errorf("failed to finish checkpoint: %w", err)
+	}
+	return done, nil
+}
+
+// resumeWorktree applies fn to an interrupted cycle's worktree. A worktree
+// that is gone or fails is reported, not fatal: the task's state is what
+// resuming settles.
+func resumeWorktree(cfg *config.Config, cp *storage.CycleCheckpoint, fn func(tree *worktree.Worktree) error, message string) string {
+	tree, err := worktree.Reopen(cfg.Workspace, cp.WorktreePath, cp.WorktreeBranch)
+	if err == nil {
+		err = fn(tree)
+	}
+	if err != nil {
+		return fmt.Sprintf("left the worktree alone: %v", err)
+	}
+	return message
+}
+
+// resumeAuditEntry is the audit entry of an interrupted cycle that never got
+// to record its own
+func resumeAuditEntry(ic *InterruptedCycle) *storage.AuditLog {
+	cp := ic.Checkpoint
+	entry := &storage.AuditLog{
+		TaskID:    cp.TaskID,
+		CycleID:   cp.CycleID,
+		PrevState: string(cp.PrevState),
+		NextState: string(cp.PrevState),
+		Actor:     cp.Agent,
+		Result:    "failure",
+		Note:      fmt.Sprintf("Rolled back by baton resume after the cycle was interrupted in its %s phase: %s", cp.Phase, ic.Reason),
+	}
+	if ic.Action == storage.CheckpointCompleted {
+		entry.NextState = string(ic.Task.State)
+		entry.Result = "success"
+		entry.Handshake = HandshakeUpdated
+		entry.Note = fmt.Sprintf("Completed by baton resume after the cycle was interrupted in its %s phase: %s", cp.Phase, ic.Reason)
+	}
+	return entry
+}
+
+// resumeVerb describes what resuming did with a cycle, in a sentence
+func resumeVerb(action string) string {
+	if action == storage.CheckpointCompleted {
+		return "completed it"
+	}
+	return "rolled it back"
+}
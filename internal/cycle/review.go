@@ -0,0 +1,104 @@
+package cycle
+
+import (
+	"fmt"
+	"log"
+
+	"baton/internal/storage"
+	"baton/internal/worktree"
+)
+
+// Proposal is a transition an agent made, awaiting a human's approval
+type Proposal struct {
+	CycleID   string
+	TaskID    string
+	TaskTitle string
+	Agent     string
+	From      storage.State
+	To        storage.State
+	Artifacts []string // the artifacts the agent created or updated
+	Response  string   // the agent's final reply
+	WorkDir   string   // where the agent changed files
+	Diff      string   // the agent's changes to the files, "" when there are none
+	DiffError string   // why there is no diff, e.g. no git repository
+	Isolated  bool     // whether rejecting discards the changes, as they are in a worktree
+}
+
+// ReviewDecision is a human's verdict on a Proposal
+type ReviewDecision struct {
+	Approved bool
+	Reviewer string
+	Feedback string // why the transition was rejected, for the next cycle's agent
+}
+
+// Reviewer asks a human to approve a proposed transition. It blocks until
+// they decide.
+type Reviewer func(proposal *Proposal) *ReviewDecision
+
+// SetReviewer makes the engine ask reviewer to approve each transition an
+// agent makes before it stands: before the cycle's changes are merged, and
+// after the after_success hooks passed. A rejected transition is undone.
+func (ce *CycleEngine) SetReviewer(reviewer Reviewer) {
+	ce.reviewer = reviewer
+}
+
+// review asks the reviewer, if one is set, to approve the agent's move of
+// task to state to. A rejection undoes the move and leaves the reviewer's
+// feedback in a note on the task. It returns the decision, nil without a
+// reviewer.
+func (ce *CycleEngine) review(task *storage.Task, to storage.State, cycleID, agent, response string, artifacts []string, isolation *isolatedCycle) (*ReviewDecision, error) {
+	if ce.reviewer == nil {
+		return nil, nil
+	}
+
+	proposal := &Proposal{
+		CycleID:   cycleID,
+		TaskID:    task.ID,
+		TaskTitle: task.Title,
+		Agent:     agent,
+		From:      task.State,
+		To:        to,
+		Artifacts: artifacts,
+		Response:  response,
+		WorkDir:   isolation.workDir(ce.config.Workspace),
+		Isolated:  isolation != nil,
+	}
+	if diff, err := worktree.Diff(proposal.WorkDir); err != nil {
+		proposal.DiffError = err.Error()
+	} else {
+		proposal.Diff = diff
+	}
+
+	decision := ce.reviewer(proposal)
+	if decision.Approved {
+		return decision, nil
+	}
+
+	reason := fmt.Sprintf("rejected in review by %s", decision.Reviewer)
+	if err := ce.store.UpdateTaskState(task.ID, task.State, reason); err != nil {
+		return nil, fmt.Errorf("failed to undo the move to %s: %w", to, err)
+	}
+
+	body := fmt.Sprintf("The move to %s was rejected in review.", to)
+	if decision.Feedback != "" {
+		body += "\n\n" + decision.Feedback
+	}
+	if err := ce.store.AddTaskNote(&storage.TaskNote{TaskID: task.ID, Author: decision.Reviewer, Body: body}); err != nil {
+		log.Printf("Failed to note on task %s: %v", task.ID, err)
+	}
+	return decision, nil
+}
+
+// reviewNote summarizes a review decision, for the audit entry
+func reviewNote(decision *ReviewDecision) string {
+	switch {
+	case decision == nil:
+		return ""
+	case decision.Approved:
+		return fmt.Sprintf("Approved in review by %s", decision.Reviewer)
+	case decision.Feedback != "":
+		return fmt.Sprintf("Rejected in review by %s: %s", decision.Reviewer, decision.Feedback)
+	default:
+		return fmt.Sprintf("Rejected in review by %s", decision.Reviewer)
+	}
+}
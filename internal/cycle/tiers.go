@@ -0,0 +1,210 @@
+package cycle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/llm"
+	"baton/internal/storage"
+)
+
+// LowConfidenceMarker is what an agent replies with to hand a cycle to a stronger model
+const LowConfidenceMarker = "BATON_ESCALATE"
+
+// tierOutcome describes which model tier and provider served a cycle
+type tierOutcome struct {
+	Tier        string   // tier that produced the final response; "" without tiering
+	Escalations []string // why each lower tier was passed over, in order
+	Provider    string   // provider that produced the final response
+	Fallbacks   []string // providers that failed before it, with their errors
+	Retries     []string // transient failures retried before the final response
+	Budget      string   // the LLM budget overage that stopped the cycle's LLM calls
+}
+
+// served records the provider behind a tier's response
+func (o *tierOutcome) served(client llm.Client, response *llm.Response) {
+	o.Provider = llm.Provider(response, client.GetName())
+	o.Fallbacks = llm.FallbackFailures(response)
+	o.Retries = llm.Retries(response)
+}
+
+// executeTiered runs the prompt on the cycle's starting model tier and climbs
+// the tier order while the escalation policy calls for it. Escalation only
+// happens while the task is still in its starting state, so work an agent has
+// already handed over is never redone. Once a call takes the cycle past its
+// LLM budget no further tier is tried.
+func (ce *CycleEngine) executeTiered(ctx context.Context, task *storage.Task, agent *config.Agent, prompt string, tracker *llm.CostTracker) (*llm.Response, *tierOutcome, error) {
+	outcome := &tierOutcome{}
+	path := ce.config.EscalationPath(ce.config.StartingTier(string(task.State), agent))
+	if len(path) == 0 {
+		response, err := ce.executeLogged(ctx, ce.llmClient, "", prompt, agent, tracker)
+		outcome.served(ce.llmClient, response)
+		outcome.checkBudget(tracker)
+		return response, outcome, err
+	}
+
+	policy := ce.config.LLM.Escalation
+	for i, tierName := range path {
+		outcome.Tier = tierName
+		last := i == len(path)-1
+
+		client, err := llm.ClientForTier(ce.llmClient, ce.config.LLM, ce.config.LLM.Tiers[tierName])
+		if err != nil {
+			return nil, outcome, fmt.Errorf("model tier %s is unavailable: %w", tierName, err)
+		}
+
+		tierPrompt := prompt
+		if !last && policy.OnLowConfidence {
+			tierPrompt += fmt.Sprintf("\n\n## Escalation\nIf you are not confident you can handle this task correctly, "+
+				"reply with %s and stop without updating the task state or artifacts; a stronger model will take over.\n",
+				LowConfidenceMarker)
+		}
+
+		response, err := ce.executeLogged(ctx, client, tierName, tierPrompt, agent, tracker)
+		outcome.served(client, response)
+		if outcome.checkBudget(tracker) || last || ctx.Err() != nil {
+			return response, outcome, err
+		}
+
+		reason := escalationReason(policy, response, err)
+		if reason == "" {
+			return response, outcome, err
+		}
+
+		// Never redo a cycle whose state change already landed
+		current, getErr := ce.store.GetTask(task.ID)
+		if getErr != nil || current.State != task.State {
+			return response, outcome, err
+		}
+
+		outcome.Escalations = append(outcome.Escalations, fmt.Sprintf("%s: %s", tierName, reason))
+		ce.live.llmEvent("escalation", tierName, reason)
+	}
+
+	// Unreachable: the last tier always returns
+	return nil, outcome, fmt.Errorf("no model tier served the cycle")
+}
+
+// checkBudget records whether the cycle has spent its LLM budget
+func (o *tierOutcome) checkBudget(tracker *llm.CostTracker) bool {
+	if err := tracker.Check(); err != nil {
+		o.Budget = err.Error()
+		return true
+	}
+	return false
+}
+
+// executeLogged runs one LLM call, reporting it as the in-flight cycle's
+// latest LLM event, adding its usage to the tracker and recording it to the
+// cycle's transcript, if any
+func (ce *CycleEngine) executeLogged(ctx context.Context, client llm.Client, tier, prompt string, agent *config.Agent, tracker *llm.CostTracker) (*llm.Response, error) {
+	ce.live.llmEvent("request", tier, "")
+	started := time.Now()
+	response, err := client.Execute(ctx, prompt, agent.Name)
+	usage := tracker.Record(prompt, response)
+	transcriptFrom(ctx).record(tier, prompt, response, err, usage, time.Since(started))
+	switch {
+	case err != nil:
+		ce.live.llmEvent("error", tier, err.Error())
+	case response != nil && !response.Success:
+		ce.live.llmEvent("response", tier, "unsuccessful")
+	default:
+		ce.live.llmEvent("response", tier, "success")
+	}
+	if retries := llm.Retries(response); len(retries) > 0 {
+		ce.live.llmEvent("retry", tier, fmt.Sprintf("after %d retries: %s", len(retries), strings.Join(retries, "; ")))
+	}
+	if failures := llm.FallbackFailures(response); len(failures) > 0 {
+		ce.live.llmEvent("fallback", tier, fmt.Sprintf("served by %s after %s", llm.Provider(response, ""), strings.Join(failures, "; ")))
+	}
+	if budgetErr := tracker.Check(); budgetErr != nil {
+		ce.live.llmEvent("budget", tier, fmt.Sprintf("%v (this call: $%.4f)", budgetErr, usage.CostUSD))
+	}
+	return response, err
+}
+
+// followUp sends the completion handshake's follow-ups to the model tier that
+// served the cycle, charging them to the cycle's LLM budget. A follow-up
+// continues the cycle's session when the client can resume it; otherwise the
+// cycle's prompt is sent again with the follow-up appended.
+func (ce *CycleEngine) followUp(agent *config.Agent, tierName string, tracker *llm.CostTracker, prompt string, response *llm.Response) FollowUpFunc {
+	sessionID := llm.ResumableSession(response)
+	return func(ctx context.Context, message string) (*llm.Response, error) {
+		if err := tracker.Check(); err != nil {
+			return nil, err
+		}
+		client := ce.llmClient
+		if tierName != "" {
+			tierClient, err := llm.ClientForTier(ce.llmClient, ce.config.LLM, ce.config.LLM.Tiers[tierName])
+			if err != nil {
+				return nil, fmt.Errorf("model tier %s is unavailable: %w", tierName, err)
+			}
+			client = tierClient
+		}
+
+		followUpPrompt := message
+		if sessionID != "" {
+			ctx = llm.WithSession(ctx, sessionID)
+		} else {
+			followUpPrompt = prompt + "\n\n## Follow-up\n" + message
+		}
+
+		response, err := ce.executeLogged(ctx, client, tierName, followUpPrompt, agent, tracker)
+		if resumed := llm.ResumableSession(response); resumed != "" {
+			sessionID = resumed
+		}
+		if err == nil && response != nil && !response.Success && response.Error != nil {
+			err = response.Error
+		}
+		return response, err
+	}
+}
+
+// escalationReason returns why a response warrants a stronger model, or "" if it does not
+func escalationReason(policy config.EscalationPolicy, response *llm.Response, err error) string {
+	if policy.OnFailure {
+		if err != nil {
+			return "failed: " + err.Error()
+		}
+		if response != nil && !response.Success {
+			return "unsuccessful response"
+		}
+	}
+	if policy.OnLowConfidence && err == nil && response != nil && strings.Contains(response.Content, LowConfidenceMarker) {
+		return "low confidence"
+	}
+	return ""
+}
+
+// summary describes the tier and provider outcome for the audit note
+func (o *tierOutcome) summary() string {
+	var lines []string
+	switch {
+	case o.Tier == "":
+	case len(o.Escalations) == 0:
+		lines = append(lines, fmt.Sprintf("Served by tier %s", o.Tier))
+	default:
+		lines = append(lines, fmt.Sprintf("Served by tier %s after escalating (%s)", o.Tier, strings.Join(o.Escalations, "; ")))
+	}
+	if len(o.Retries) > 0 {
+		lines = append(lines, fmt.Sprintf("Retried %d times after transient LLM failures (%s)", len(o.Retries), strings.Join(o.Retries, "; ")))
+	}
+	if len(o.Fallbacks) > 0 {
+		lines = append(lines, fmt.Sprintf("Served by fallback provider %s (%s)", o.Provider, strings.Join(o.Fallbacks, "; ")))
+	}
+	if o.Budget != "" {
+		lines = append(lines, o.Budget)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// annotate prefixes an audit note with the summary, if there is one
+func (o *tierOutcome) annotate(note string) string {
+	if summary := o.summary(); summary != "" {
+		return summary + "\n" + note
+	}
+	return note
+}
@@ -0,0 +1,89 @@
+package cycle
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/llm"
+	"baton/internal/storage"
+)
+
+// maxPartialOutput is how much of the reply of an agent stopped at its
+// timebox is kept, from its end
+const maxPartialOutput = 2000
+
+// CycleTimeout returns how long a cycle on the task may run; zero means no limit.
+// With the timebox formula enabled the timeout follows the task's estimate and
+// state, otherwise development.cycle_timebox_seconds applies to every cycle.
+func CycleTimeout(cfg *config.Config, task *storage.Task) time.Duration {
+	tb := cfg.Timebox
+	if !tb.Enabled {
+		return time.Duration(cfg.Development.CycleTimeboxSeconds) * time.Second
+	}
+
+	hours := task.EstimatedHours
+	if hours <= 0 {
+		hours = tb.DefaultEstimateHours
+	}
+
+	factor := 1.0
+	if f, ok := tb.StateFactors[string(task.State)]; ok {
+		factor = f
+	}
+
+	seconds := (float64(tb.BaseSeconds) + hours*float64(tb.SecondsPerHour)) * factor
+	if seconds < float64(tb.MinSeconds) {
+		seconds = float64(tb.MinSeconds)
+	}
+	if tb.MaxSeconds > 0 && seconds > float64(tb.MaxSeconds) {
+		seconds = float64(tb.MaxSeconds)
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// partialOutput returns the end of what an agent said before it was stopped,
+// "" when it said nothing
+func partialOutput(response *llm.Response) string {
+	if response == nil {
+		return ""
+	}
+	output := strings.TrimSpace(response.Content)
+	if len(output) > maxPartialOutput {
+		output = "…" + output[len(output)-maxPartialOutput:]
+	}
+	return output
+}
+
+// timedOutNote describes a cycle stopped at its timebox, for the audit entry
+func timedOutNote(timeout time.Duration, response *llm.Response) string {
+	note := fmt.Sprintf("Cycle exceeded its %s timebox; the agent was stopped", timeout)
+	if output := partialOutput(response); output != "" {
+		note += fmt.Sprintf("\nPartial output: %s", output)
+	}
+	return note
+}
+
+// noteTimedOut tells the next cycle on task that the last one was stopped at
+// its timebox of timeout, in which state it left the task and what the agent
+// said until then
+func (ce *CycleEngine) noteTimedOut(task *storage.Task, agent string, timeout time.Duration, response *llm.Response, isolated bool) {
+	body := fmt.Sprintf("The %s's cycle ran out of its %s timebox and was stopped before it finished.", agent, timeout)
+	if current, err := ce.store.GetTask(task.ID); err == nil && current.State != task.State {
+		body += fmt.Sprintf(" It had moved the task from %s to %s; check that work is complete.", task.State, current.State)
+	}
+	if isolated {
+		body += " Its file changes were not merged."
+	} else {
+		body += " Its file changes, if any, are left in the workspace as they were."
+	}
+	if output := partialOutput(response); output != "" {
+		body += "\n\nIts last output:\n" + output
+	}
+	if err := ce.store.AddTaskNote(&storage.TaskNote{TaskID: task.ID, Author: "baton", Body: body}); err != nil {
+		log.Printf("Failed to note on task %s: %v", task.ID, err)
+	}
+}
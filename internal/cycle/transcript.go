@@ -0,0 +1,144 @@
+package cycle
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/llm"
+	"baton/internal/redact"
+	"baton/internal/storage"
+)
+
+// TranscriptDir is where cycle transcripts are written, relative to the workspace
+const TranscriptDir = "claudedocs/transcripts"
+
+// TranscriptEntry is one LLM exchange of a cycle: a line of its transcript
+type TranscriptEntry struct {
+	Time             time.Time `json:"time"`
+	CycleID          string    `json:"cycle_id"`
+	TaskID           string    `json:"task_id"`
+	Agent            string    `json:"agent"`
+	Sequence         int       `json:"sequence"` // 1 for the cycle's first call, then escalations and handshake follow-ups
+	Tier             string    `json:"tier,omitempty"`
+	Provider         string    `json:"provider,omitempty"`
+	Model            string    `json:"model,omitempty"`
+	SessionID        string    `json:"session_id,omitempty"`
+	Prompt           string    `json:"prompt"`
+	Content          string    `json:"content"`
+	Raw              []string  `json:"raw,omitempty"` // stream-json lines, for clients that stream
+	Success          bool      `json:"success"`
+	Error            string    `json:"error,omitempty"`
+	DurationMS       int64     `json:"duration_ms"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+}
+
+// Transcript appends a cycle's LLM exchanges to <TranscriptDir>/<cycle_id>.jsonl,
+// masking secrets per security.secret_patterns
+type Transcript struct {
+	mu       sync.Mutex
+	path     string
+	redactor *redact.Redactor
+	cycleID  string
+	taskID   string
+	agent    string
+	sequence int
+}
+
+// NewTranscript creates the transcript of a cycle; the file is created with
+// the first exchange
+func NewTranscript(cfg *config.Config, cycleID string, task *storage.Task, agent *config.Agent) *Transcript {
+	return &Transcript{
+		path:     filepath.Join(cfg.Workspace, TranscriptDir, cycleID+".jsonl"),
+		redactor: redact.New(cfg.Security.SecretPatterns),
+		cycleID:  cycleID,
+		taskID:   task.ID,
+		agent:    agent.Name,
+	}
+}
+
+// record appends one exchange. Failures to write are logged, never fatal to
+// the cycle; a nil transcript records nothing.
+func (t *Transcript) record(tier, prompt string, response *llm.Response, err error, usage llm.Usage, elapsed time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sequence++
+	entry := TranscriptEntry{
+		Time:             time.Now(),
+		CycleID:          t.cycleID,
+		TaskID:           t.taskID,
+		Agent:            t.agent,
+		Sequence:         t.sequence,
+		Tier:             tier,
+		Prompt:           t.redactor.String(prompt),
+		DurationMS:       elapsed.Milliseconds(),
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          usage.CostUSD,
+	}
+	if response != nil {
+		entry.Provider = llm.Provider(response, "")
+		entry.Model, _ = response.Metadata["model"].(string)
+		entry.SessionID = response.SessionID
+		entry.Content = t.redactor.String(response.Content)
+		entry.Raw = t.redactor.Strings(response.Stream)
+		entry.Success = response.Success
+		if response.Error != nil {
+			entry.Error = t.redactor.String(response.Error.Error())
+		}
+	}
+	if err != nil {
+		entry.Success = false
+		entry.Error = t.redactor.String(err.Error())
+	}
+
+	if writeErr := t.append(&entry); writeErr != nil {
+		log.Printf("Warning: failed to write transcript %s: %v", t.path, writeErr)
+	}
+}
+
+// append writes an entry as one JSON line
+func (t *Transcript) append(entry *TranscriptEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// transcriptKey carries a cycle's transcript in the context of its LLM calls
+type transcriptKey struct{}
+
+// withTranscript returns a context whose LLM calls are recorded to t
+func withTranscript(ctx context.Context, t *Transcript) context.Context {
+	return context.WithValue(ctx, transcriptKey{}, t)
+}
+
+// transcriptFrom returns the context's transcript, or nil when the cycle
+// records none
+func transcriptFrom(ctx context.Context) *Transcript {
+	t, _ := ctx.Value(transcriptKey{}).(*Transcript)
+	return t
+}
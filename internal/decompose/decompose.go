@@ -0,0 +1,267 @@
+package decompose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"baton/internal/config"
+	"baton/internal/llm"
+	"baton/internal/storage"
+)
+
+// ArtifactName is the artifact on the original task recording why and how it was split
+const ArtifactName = "decomposition"
+
+// ResultDecomposed marks the audit entry of a decomposition; failures before it
+// no longer count against the task
+const ResultDecomposed = "decomposed"
+
+// maxFailureNotes bounds how many failed cycle notes are included in the prompt
+const maxFailureNotes = 5
+
+const decompositionPrompt = `A task keeps failing to make progress. Split it into smaller tasks that can each be
+completed in a single focused cycle.
+
+## Task
+Title: %s
+State: %s
+Description:
+%s
+
+## Recent unsuccessful cycles
+%s
+Return ONLY a JSON object of this form, with at most %d subtasks in the order they must be done:
+{
+  "rationale": "why the task kept failing and how the split addresses it",
+  "subtasks": [
+    {"title": "...", "description": "...", "estimated_hours": 2}
+  ]
+}`
+
+// Decomposer splits tasks that keep failing into smaller dependent subtasks
+type Decomposer struct {
+	store     *storage.Store
+	llmClient llm.Client
+	config    config.DecompositionConfig
+}
+
+// Subtask is one piece of work proposed by the LLM
+type Subtask struct {
+	Title          string  `json:"title"`
+	Description    string  `json:"description"`
+	EstimatedHours float64 `json:"estimated_hours"`
+}
+
+// Result describes a completed decomposition
+type Result struct {
+	Task         *storage.Task   `json:"task"`
+	Subtasks     []*storage.Task `json:"subtasks"`
+	Rationale    string          `json:"rationale"`
+	FailedCycles int             `json:"failed_cycles"`
+}
+
+// NewDecomposer creates a new task decomposer
+func NewDecomposer(store *storage.Store, llmClient llm.Client, cfg config.DecompositionConfig) *Decomposer {
+	return &Decomposer{
+		store:     store,
+		llmClient: llmClient,
+		config:    cfg,
+	}
+}
+
+// FailedCycles returns how many cycles in a row have ended without success for
+// the task, counting back from the most recent one to the last success or
+// decomposition
+func FailedCycles(store *storage.Store, taskID string) (int, error) {
+	logs, err := store.GetAuditLogs(taskID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read audit history: %w", err)
+	}
+
+	failed := 0
+	for _, entry := range logs {
+		if entry.Result == "success" || entry.Result == ResultDecomposed {
+			break
+		}
+		failed++
+	}
+	return failed, nil
+}
+
+// ShouldDecompose reports whether the task has reached the failure threshold
+func (d *Decomposer) ShouldDecompose(taskID string) (bool, int, error) {
+	if d.config.FailureThreshold <= 0 {
+		return false, 0, nil
+	}
+	failed, err := FailedCycles(d.store, taskID)
+	if err != nil {
+		return false, 0, err
+	}
+	return failed >= d.config.FailureThreshold, failed, nil
+}
+
+// Decompose asks the LLM to split the task, creates the subtasks with the
+// original as their parent and records the rationale as an artifact
+func (d *Decomposer) Decompose(taskID string) (*Result, error) {
+	task, err := d.store.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	if task.State == storage.Done || task.State == storage.Cancelled {
+		return nil, fmt.Errorf("task %s is already %s", taskID, task.State)
+	}
+
+	failed, err := FailedCycles(d.store, taskID)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := d.failureNotes(taskID, failed)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSubtasks := d.config.MaxSubtasks
+	if maxSubtasks <= 0 {
+		maxSubtasks = 5
+	}
+
+	prompt := fmt.Sprintf(decompositionPrompt, task.Title, task.State, task.Description, notes, maxSubtasks)
+	plan := &decompositionPlan{}
+	if err := llm.GenerateJSON(context.Background(), d.llmClient, prompt, decompositionSchema, plan); err != nil {
+		return nil, fmt.Errorf("LLM call failed: %w", err)
+	}
+	if len(plan.Subtasks) < 2 {
+		return nil, fmt.Errorf("LLM proposed %d subtasks; a split needs at least 2", len(plan.Subtasks))
+	}
+	if len(plan.Subtasks) > maxSubtasks {
+		plan.Subtasks = plan.Subtasks[:maxSubtasks]
+	}
+
+	// Each subtask depends on the one before it, so they are worked in order
+	children := make([]*storage.Task, 0, len(plan.Subtasks))
+	previous := ""
+	for _, st := range plan.Subtasks {
+		if strings.TrimSpace(st.Title) == "" {
+			return nil, fmt.Errorf("LLM proposed a subtask without a title")
+		}
+		deps := []string{}
+		if previous != "" {
+			deps = []string{previous}
+		}
+		depsJSON, _ := json.Marshal(deps)
+
+		child := &storage.Task{
+			ID:             uuid.New().String(),
+			Title:          st.Title,
+			Description:    st.Description,
+			State:          storage.ReadyForPlan,
+			Priority:       task.Priority,
+			Owner:          task.Owner,
+			Tags:           task.Tags,
+			Dependencies:   depsJSON,
+			BlockedBy:      json.RawMessage("[]"),
+			EstimatedHours: st.EstimatedHours,
+		}
+		children = append(children, child)
+		previous = child.ID
+	}
+
+	if err := d.store.SplitTask(task, children); err != nil {
+		return nil, fmt.Errorf("failed to split task: %w", err)
+	}
+
+	result := &Result{
+		Task:         task,
+		Subtasks:     children,
+		Rationale:    plan.Rationale,
+		FailedCycles: failed,
+	}
+
+	if err := d.store.UpsertArtifact(&storage.Artifact{
+		TaskID:  task.ID,
+		Name:    ArtifactName,
+		Content: result.Markdown(),
+		Meta:    json.RawMessage(`{"source":"decomposition"}`),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to save decomposition artifact: %w", err)
+	}
+
+	if err := d.store.CreateAuditLog(&storage.AuditLog{
+		TaskID:    task.ID,
+		CycleID:   uuid.New().String(),
+		PrevState: string(task.State),
+		NextState: string(task.State),
+		Actor:     "baton",
+		Result:    ResultDecomposed,
+		Note:      fmt.Sprintf("Split into %d subtasks after %d unsuccessful cycles", len(children), failed),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to log decomposition: %w", err)
+	}
+
+	return result, nil
+}
+
+// Markdown renders the decomposition artifact
+func (r *Result) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## Rationale\n\n")
+	if r.Rationale != "" {
+		b.WriteString(r.Rationale)
+	} else {
+		b.WriteString("No rationale given.")
+	}
+	fmt.Fprintf(&b, "\n\nSplit after %d unsuccessful cycles in a row.\n\n## Subtasks\n\n", r.FailedCycles)
+	for i, child := range r.Subtasks {
+		fmt.Fprintf(&b, "%d. %s (`%s`)\n", i+1, child.Title, child.ID)
+	}
+	return b.String()
+}
+
+// failureNotes lists the notes of the task's most recent unsuccessful cycles
+func (d *Decomposer) failureNotes(taskID string, failed int) (string, error) {
+	logs, err := d.store.GetAuditLogs(taskID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read audit history: %w", err)
+	}
+
+	var b strings.Builder
+	for i := 0; i < failed && i < len(logs) && i < maxFailureNotes; i++ {
+		note := logs[i].Note
+		if len(note) > 300 {
+			note = note[:300] + "..."
+		}
+		fmt.Fprintf(&b, "- %s (%s, %s): %s\n", logs[i].CreatedAt.Format("2006-01-02 15:04"), logs[i].PrevState, logs[i].Result, note)
+	}
+	if b.Len() == 0 {
+		b.WriteString("- none recorded\n")
+	}
+	return b.String(), nil
+}
+
+// decompositionPlan is the JSON the LLM is asked to return
+type decompositionPlan struct {
+	Rationale string    `json:"rationale"`
+	Subtasks  []Subtask `json:"subtasks"`
+}
+
+// decompositionSchema is the JSON schema of decompositionPlan
+var decompositionSchema = llm.Schema{
+	"type":     "object",
+	"required": []string{"subtasks"},
+	"properties": map[string]interface{}{
+		"rationale": llm.Schema{"type": "string"},
+		"subtasks": llm.Schema{"type": "array", "items": llm.Schema{
+			"type":     "object",
+			"required": []string{"title", "description"},
+			"properties": map[string]interface{}{
+				"title":           llm.Schema{"type": "string"},
+				"description":     llm.Schema{"type": "string"},
+				"estimated_hours": llm.Schema{"type": "number"},
+			},
+		}},
+	},
+}
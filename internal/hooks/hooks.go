@@ -0,0 +1,256 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+// Hook kinds
+const (
+	KindCommand = "command"
+	KindWebhook = "webhook"
+)
+
+// defaultTimeout bounds a hook with no timeout_seconds
+const defaultTimeout = 60 * time.Second
+
+// maxOutput is how much of a command's output is kept, from the end
+const maxOutput = 4000
+
+// Result is the outcome of one hook run
+type Result struct {
+	Hook            string  `json:"hook"`
+	Kind            string  `json:"kind"` // command or webhook
+	Blocking        bool    `json:"blocking"`
+	Success         bool    `json:"success"`
+	Output          string  `json:"output,omitempty"` // tail of a command's combined output
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// Payload is the JSON body a webhook hook is POSTed
+type Payload struct {
+	Event  string        `json:"event"` // always "transition"
+	TaskID string        `json:"task_id"`
+	Title  string        `json:"title"`
+	From   storage.State `json:"from"`
+	To     storage.State `json:"to"`
+	Note   string        `json:"note,omitempty"`
+	At     time.Time     `json:"at"`
+}
+
+// Runner runs the hooks configured for transitions
+type Runner struct {
+	hooks     []config.TransitionHook
+	workspace string
+	client    *http.Client
+}
+
+// NewRunner creates a runner for hooks, running commands in workspace
+func NewRunner(hooks []config.TransitionHook, workspace string) *Runner {
+	return &Runner{
+		hooks:     hooks,
+		workspace: workspace,
+		client:    &http.Client{},
+	}
+}
+
+// Run runs, in configured order, the blocking or the non-blocking hooks for
+// task moving to state to
+func (r *Runner) Run(task *storage.Task, to storage.State, note string, blocking bool) []*Result {
+	var results []*Result
+	for _, hook := range r.hooks {
+		if hook.Blocking != blocking || !hook.Matches(string(task.State), string(to)) {
+			continue
+		}
+		results = append(results, r.run(hook, task, to, note))
+	}
+	return results
+}
+
+// Planned is a hook a transition would run
+type Planned struct {
+	Hook     string `json:"hook"`
+	Kind     string `json:"kind"` // command or webhook
+	Blocking bool   `json:"blocking"`
+}
+
+// Plan lists the hooks task moving to state to would run, without running
+// them: the blocking hooks first, then the others, each in configured order
+func (r *Runner) Plan(task *storage.Task, to storage.State) []Planned {
+	var planned []Planned
+	for _, blocking := range []bool{true, false} {
+		for _, hook := range r.hooks {
+			if hook.Blocking != blocking || !hook.Matches(string(task.State), string(to)) {
+				continue
+			}
+			kind := KindWebhook
+			if hook.Command != "" {
+				kind = KindCommand
+			}
+			planned = append(planned, Planned{Hook: hook.Label(), Kind: kind, Blocking: hook.Blocking})
+		}
+	}
+	return planned
+}
+
+// FirstFailure returns the first failed result, or nil when all succeeded
+func FirstFailure(results []*Result) *Result {
+	for _, result := range results {
+		if !result.Success {
+			return result
+		}
+	}
+	return nil
+}
+
+func (r *Runner) run(hook config.TransitionHook, task *storage.Task, to storage.State, note string) *Result {
+	timeout := defaultTimeout
+	if hook.TimeoutSeconds > 0 {
+		timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := &Result{Hook: hook.Label(), Blocking: hook.Blocking}
+	start := time.Now()
+	var err error
+	if hook.Command != "" {
+		result.Kind = KindCommand
+		result.Output, err = r.command(ctx, hook.Command, task, to)
+	} else {
+		result.Kind = KindWebhook
+		err = r.webhook(ctx, hook.Webhook, &Payload{
+			Event:  "transition",
+			TaskID: task.ID,
+			Title:  task.Title,
+			From:   task.State,
+			To:     to,
+			Note:   note,
+			At:     time.Now().UTC(),
+		})
+	}
+	result.DurationSeconds = time.Since(start).Seconds()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// command runs a command hook with sh -c, telling it about the transition in
+// BATON_* environment variables
+func (r *Runner) command(ctx context.Context, command string, task *storage.Task, to storage.State) (string, error) {
+	return runCommand(ctx, r.workspace, command,
+		"BATON_TASK_ID="+task.ID,
+		"BATON_TASK_TITLE="+task.Title,
+		"BATON_FROM_STATE="+string(task.State),
+		"BATON_TO_STATE="+string(to),
+	)
+}
+
+// runCommand runs command with sh -c in dir, adding env to the environment,
+// and returns the tail of its combined output
+func runCommand(ctx context.Context, dir, command string, env ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+
+	output := strings.TrimSpace(string(out))
+	if len(output) > maxOutput {
+		output = "..." + output[len(output)-maxOutput:]
+	}
+	return output, err
+}
+
+// RunCycle runs cycle hooks in order with sh -c in dir, adding env to their
+// environment. It stops after the first blocking hook that fails, since the
+// cycle stops there too.
+func RunCycle(cycleHooks []config.CycleHook, dir string, env ...string) []*Result {
+	var results []*Result
+	for _, hook := range cycleHooks {
+		timeout := defaultTimeout
+		if hook.TimeoutSeconds > 0 {
+			timeout = time.Duration(hook.TimeoutSeconds) * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+		result := &Result{Hook: hook.Label(), Kind: KindCommand, Blocking: hook.Blocking()}
+		start := time.Now()
+		var err error
+		result.Output, err = runCommand(ctx, dir, hook.Command, env...)
+		result.DurationSeconds = time.Since(start).Seconds()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		cancel()
+
+		result.Success = err == nil
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+		if !result.Success && result.Blocking {
+			break
+		}
+	}
+	return results
+}
+
+// PlanCycle lists the cycle hooks RunCycle would run, without running them
+func PlanCycle(cycleHooks []config.CycleHook) []Planned {
+	var planned []Planned
+	for _, hook := range cycleHooks {
+		planned = append(planned, Planned{Hook: hook.Label(), Kind: KindCommand, Blocking: hook.Blocking()})
+	}
+	return planned
+}
+
+// FirstBlockingFailure returns the first failed result of a blocking hook, or nil
+func FirstBlockingFailure(results []*Result) *Result {
+	for _, result := range results {
+		if !result.Success && result.Blocking {
+			return result
+		}
+	}
+	return nil
+}
+
+// webhook POSTs payload to url
+func (r *Runner) webhook(ctx context.Context, url string, payload *Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
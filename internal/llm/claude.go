@@ -39,19 +39,40 @@ func (c *ClaudeClient) Execute(ctx context.Context, prompt string, agentID strin
 	// Add prompt
 	args = append(args, prompt)
 
+	// Add model override
+	if c.config.Model != "" {
+		args = append(args, "--model", c.config.Model)
+	}
+
 	// Add output format
 	if c.config.OutputFormat != "" {
 		args = append(args, "--output-format", c.config.OutputFormat)
 	}
 
-	// Add MCP connection if enabled
+	// Continue an earlier session, such as the cycle a handshake follow-up belongs to
+	if sessionID := sessionFrom(ctx); sessionID != "" {
+		args = append(args, "--resume", sessionID)
+	}
+
+	// Add MCP connection if enabled; the token reaches the CLI through the
+	// environment, which other users can't list the way they can arguments
+	env := os.Environ()
 	if c.config.MCPConnect && c.mcpPort > 0 {
-		args = append(args, "--mcp", fmt.Sprintf("http://localhost:%d", c.mcpPort))
+		mcpConfig, err := claudeMCPConfig(ctx, c.mcpPort)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--mcp-config", mcpConfig)
+		if token := mcpToken(ctx); token != "" {
+			env = append(env, mcpTokenEnv+"="+token)
+		}
 	}
 
-	// Create command
-	cmd := exec.CommandContext(ctx, c.config.Command, args...)
-	cmd.Env = os.Environ()
+	// Create command, stopped with the processes it starts once ctx is done
+	cmd := exec.Command(c.config.Command, args...)
+	cmd.Env = env
+	cmd.Dir = workDirFrom(ctx)
+	prepareProcess(cmd)
 
 	// Get pipes
 	stdout, err := cmd.StdoutPipe()
@@ -68,36 +89,78 @@ func (c *ClaudeClient) Execute(ctx context.Context, prompt string, agentID strin
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start claude command: %w", err)
 	}
+	stopped := stopOnDone(ctx, cmd, stdout, stderr)
 
 	// Read output based on format
 	var response *Response
 	if c.config.OutputFormat == "stream-json" {
-		response, err = c.parseStreamingJSON(stdout, stderr)
+		response, err = c.parseStreamingJSON(stdout, stderr, streamFrom(ctx))
 	} else {
 		response, err = c.parseStandardOutput(stdout, stderr)
 	}
 
-	if err != nil {
-		_ = cmd.Process.Kill()
+	if err != nil && ctx.Err() == nil {
+		terminateProcessGroup(cmd, true)
+		_ = cmd.Wait()
+		stopped()
 		return nil, err
 	}
 
 	// Wait for command to complete
-	if err := cmd.Wait(); err != nil {
+	waitErr := cmd.Wait()
+	stopped()
+
+	// Stopped as ctx is done: keep what the agent said until then
+	if ctx.Err() != nil {
+		if response == nil {
+			response = &Response{Metadata: make(map[string]interface{})}
+		}
+		response.Success = false
+		response.Duration = time.Since(start)
+		response.Error = fmt.Errorf("claude command stopped: %w", ctx.Err())
+		return response, response.Error
+	}
+
+	if err := waitErr; err != nil {
 		if response == nil {
 			return nil, fmt.Errorf("claude command failed: %w", err)
 		}
-		// Command failed but we got some output
+		// Command failed but we got some output; keep any error it reported
 		response.Success = false
-		response.Error = err
+		if response.Error != nil {
+			response.Error = fmt.Errorf("%w (%v)", response.Error, err)
+		} else {
+			response.Error = err
+		}
 	}
 
 	response.Duration = time.Since(start)
+	if response.SessionID != "" {
+		if response.Metadata == nil {
+			response.Metadata = make(map[string]interface{})
+		}
+		response.Metadata["resumable"] = true
+	}
 	return response, nil
 }
 
-// parseStreamingJSON parses streaming JSON output from Claude Code
-func (c *ClaudeClient) parseStreamingJSON(stdout, stderr io.Reader) (*Response, error) {
+// GenerateText executes a one-shot prompt and returns the response content
+func (c *ClaudeClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	response, err := c.Execute(ctx, prompt, "")
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Success && response.Error != nil {
+		return "", response.Error
+	}
+
+	return response.Content, nil
+}
+
+// parseStreamingJSON parses streaming JSON output from Claude Code, passing
+// content to onChunk as it arrives
+func (c *ClaudeClient) parseStreamingJSON(stdout, stderr io.Reader, onChunk StreamFunc) (*Response, error) {
 	response := &Response{
 		Success:  true,
 		Metadata: make(map[string]interface{}),
@@ -121,15 +184,22 @@ func (c *ClaudeClient) parseStreamingJSON(stdout, stderr io.Reader) (*Response,
 		if line == "" {
 			continue
 		}
+		response.Stream = append(response.Stream, line)
 
 		// Parse JSON line
 		var msg map[string]interface{}
 		if err := json.Unmarshal([]byte(line), &msg); err != nil {
 			// Not JSON, treat as plain text
 			contentParts = append(contentParts, line)
+			onChunk(line)
 			continue
 		}
 
+		// Every message carries the session; the init message arrives first
+		if sessionID, ok := msg["session_id"].(string); ok && sessionID != "" {
+			response.SessionID = sessionID
+		}
+
 		// Handle different message types
 		msgType, ok := msg["type"].(string)
 		if !ok {
@@ -140,18 +210,29 @@ func (c *ClaudeClient) parseStreamingJSON(stdout, stderr io.Reader) (*Response,
 		case "content":
 			if content, ok := msg["content"].(string); ok {
 				contentParts = append(contentParts, content)
+				onChunk(content)
 			}
 		case "result":
 			// Final result message
 			if cost, ok := msg["total_cost_usd"].(float64); ok {
 				response.Cost = cost
 			}
-			if sessionID, ok := msg["session_id"].(string); ok {
-				response.SessionID = sessionID
-			}
 			if metadata, ok := msg["metadata"].(map[string]interface{}); ok {
 				response.Metadata = metadata
 			}
+			if usage, ok := msg["usage"].(map[string]interface{}); ok {
+				// Cached prompt tokens are billed too, so they count as prompt tokens
+				var promptTokens float64
+				for _, key := range []string{"input_tokens", "cache_creation_input_tokens", "cache_read_input_tokens"} {
+					if n, ok := usage[key].(float64); ok {
+						promptTokens += n
+					}
+				}
+				response.Metadata["prompt_tokens"] = int(promptTokens)
+				if n, ok := usage["output_tokens"].(float64); ok {
+					response.Metadata["completion_tokens"] = int(n)
+				}
+			}
 		case "error":
 			response.Success = false
 			if errMsg, ok := msg["message"].(string); ok {
@@ -197,6 +278,16 @@ func (c *ClaudeClient) parseStandardOutput(stdout, stderr io.Reader) (*Response,
 	return response, nil
 }
 
+// WithModel returns a copy of the client that runs the given model
+func (c *ClaudeClient) WithModel(model string) Client {
+	cfg := *c.config
+	cfg.Model = model
+	return &ClaudeClient{
+		config:  &cfg,
+		mcpPort: c.mcpPort,
+	}
+}
+
 // GetName returns the client name
 func (c *ClaudeClient) GetName() string {
 	return "claude"
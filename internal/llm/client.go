@@ -2,20 +2,32 @@ package llm
 
 import (
 	"context"
+	"fmt"
 	"time"
+
+	"baton/internal/config"
 )
 
-// Client represents an LLM client interface
+// Client represents an LLM client interface. Execute runs an agent prompt
+// during a cycle; GenerateText runs a one-shot prompt and returns the reply
+// text, and is what GenerateJSON builds on.
 type Client interface {
 	Execute(ctx context.Context, prompt string, agentID string) (*Response, error)
+	GenerateText(ctx context.Context, prompt string) (string, error)
 	GetName() string
 	IsAvailable() bool
 }
 
+// ModelSelector is implemented by clients that can run a specific model
+type ModelSelector interface {
+	WithModel(model string) Client
+}
+
 // Response represents an LLM response
 type Response struct {
 	Success    bool            `json:"success"`
 	Content    string          `json:"content"`
+	Stream     []string        `json:"stream,omitempty"` // raw stream-json lines, in order
 	Cost       float64         `json:"total_cost_usd"`
 	Duration   time.Duration   `json:"duration"`
 	SessionID  string          `json:"session_id"`
@@ -46,6 +58,101 @@ func (f *ClientFactory) Get(name string) (Client, bool) {
 	return client, exists
 }
 
+// NewConfiguredFactory creates a factory holding every built-in client,
+// configured from cfg, retrying transient failures per llm.max_retries and
+// limited by llm.rate_limit; mcpPort is passed to clients that connect to the
+// MCP server
+func NewConfiguredFactory(cfg config.LLMConfig, mcpPort int) *ClientFactory {
+	// Each provider retries its own transient failures before any fallback
+	policy := RetryPolicy{
+		MaxRetries: cfg.MaxRetries,
+		Backoff:    time.Duration(cfg.RetryBackoffSeconds * float64(time.Second)),
+		MaxBackoff: time.Duration(cfg.RetryMaxBackoffSeconds * float64(time.Second)),
+	}
+	// Every attempt, retries included, waits for the process-wide rate limit
+	limiter := SharedRateLimiter(cfg.RateLimit)
+	wrap := func(client Client) Client {
+		if limiter.Limited() {
+			client = NewRateLimitedClient(client, limiter)
+		}
+		return NewRetryClient(client, policy)
+	}
+
+	factory := NewClientFactory()
+	factory.Register("claude", wrap(NewClaudeClient(&cfg.Claude, mcpPort)))
+	factory.Register("openai", wrap(NewOpenAIClient(&cfg.OpenAI, time.Duration(cfg.TimeoutSeconds)*time.Second)))
+	factory.Register("openai_compatible", wrap(NewOpenAICompatibleClient(&cfg.OpenAICompatible, time.Duration(cfg.TimeoutSeconds)*time.Second)))
+	factory.Register("gemini", wrap(NewGeminiClient(&cfg.Gemini, time.Duration(cfg.TimeoutSeconds)*time.Second)))
+	factory.Register("ollama", wrap(NewOllamaClient(&cfg.Ollama, time.Duration(cfg.TimeoutSeconds)*time.Second, mcpPort)))
+	return factory
+}
+
+// NewClient creates the primary LLM client described by the LLM configuration
+func NewClient(cfg config.LLMConfig) (Client, error) {
+	return NewClientForMCP(cfg, 0)
+}
+
+// NewClientForMCP creates the primary LLM client, passing mcpPort to clients
+// that connect to the MCP server. With llm.fallback set the client falls back
+// to that provider when the primary fails; an unavailable primary is skipped
+// as long as the fallback is available.
+func NewClientForMCP(cfg config.LLMConfig, mcpPort int) (Client, error) {
+	factory := NewConfiguredFactory(cfg, mcpPort)
+
+	client, exists := factory.Get(cfg.Primary)
+	if !exists {
+		return nil, fmt.Errorf("primary LLM client '%s' not found", cfg.Primary)
+	}
+
+	if cfg.Fallback == nil || *cfg.Fallback == "" || *cfg.Fallback == cfg.Primary {
+		if !client.IsAvailable() {
+			return nil, fmt.Errorf("primary LLM client '%s' is not available", cfg.Primary)
+		}
+		return client, nil
+	}
+
+	fallback, exists := factory.Get(*cfg.Fallback)
+	if !exists {
+		return nil, fmt.Errorf("fallback LLM client '%s' not found", *cfg.Fallback)
+	}
+
+	primaryAvailable, fallbackAvailable := client.IsAvailable(), fallback.IsAvailable()
+	switch {
+	case primaryAvailable && fallbackAvailable:
+		return NewCompositeClient(client, fallback), nil
+	case primaryAvailable:
+		return client, nil
+	case fallbackAvailable:
+		return fallback, nil
+	default:
+		return nil, fmt.Errorf("neither primary LLM client '%s' nor fallback '%s' is available", cfg.Primary, *cfg.Fallback)
+	}
+}
+
+// ClientForTier returns a client serving the model tier. The base client is
+// reused when the tier names its provider (or none), so injected clients such
+// as the replay mock keep serving cycles; other providers are created from cfg.
+func ClientForTier(base Client, cfg config.LLMConfig, tier config.ModelTier) (Client, error) {
+	client := base
+	if tier.Provider != "" && tier.Provider != base.GetName() {
+		providerCfg := cfg
+		providerCfg.Primary = tier.Provider
+		var err error
+		client, err = NewClient(providerCfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tier.Model != "" {
+		if selector, ok := client.(ModelSelector); ok {
+			client = selector.WithModel(tier.Model)
+		}
+	}
+
+	return client, nil
+}
+
 // GetAvailable returns all available clients
 func (f *ClientFactory) GetAvailable() []Client {
 	var available []Client
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CompositeClient tries its clients in order, falling back to the next one
+// when a client returns an error, e.g. a missing CLI, an API outage or a
+// request timeout. It answers to the first client's name, so model tiers
+// naming that provider keep using the chain.
+type CompositeClient struct {
+	clients []Client
+}
+
+// NewCompositeClient creates a client that tries clients in the given order
+func NewCompositeClient(clients ...Client) *CompositeClient {
+	return &CompositeClient{clients: clients}
+}
+
+// Execute runs the prompt on the first client that does not fail. The
+// response's metadata records the provider that served it under "provider"
+// and the failures that led there under "fallback_from".
+func (c *CompositeClient) Execute(ctx context.Context, prompt string, agentID string) (*Response, error) {
+	var failures []string
+	for _, client := range c.clients {
+		response, err := client.Execute(ctx, prompt, agentID)
+		if err == nil {
+			if response.Metadata == nil {
+				response.Metadata = make(map[string]interface{})
+			}
+			response.Metadata["provider"] = client.GetName()
+			if len(failures) > 0 {
+				response.Metadata["fallback_from"] = failures
+			}
+			return response, nil
+		}
+
+		failures = append(failures, fmt.Sprintf("%s: %v", client.GetName(), err))
+
+		// The caller's deadline covers the whole chain; a fallback would only
+		// fail too, so return what the provider said before it was stopped
+		if ctx.Err() != nil {
+			if response != nil {
+				if response.Metadata == nil {
+					response.Metadata = make(map[string]interface{})
+				}
+				response.Metadata["provider"] = client.GetName()
+			}
+			return response, fmt.Errorf("all LLM providers failed: %s", strings.Join(failures, "; "))
+		}
+	}
+
+	return nil, fmt.Errorf("all LLM providers failed: %s", strings.Join(failures, "; "))
+}
+
+// GenerateText executes a one-shot prompt and returns the response content
+func (c *CompositeClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	response, err := c.Execute(ctx, prompt, "")
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Success && response.Error != nil {
+		return "", response.Error
+	}
+
+	return response.Content, nil
+}
+
+// GenerateStructured runs a one-shot JSON prompt on the first client that
+// does not fail, in that client's structured output mode if it has one
+func (c *CompositeClient) GenerateStructured(ctx context.Context, prompt string, schema Schema) (string, error) {
+	var failures []string
+	for _, client := range c.clients {
+		content, err := generateStructured(ctx, client, prompt, schema)
+		if err == nil {
+			return content, nil
+		}
+
+		failures = append(failures, fmt.Sprintf("%s: %v", client.GetName(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return "", fmt.Errorf("all LLM providers failed: %s", strings.Join(failures, "; "))
+}
+
+// WithModel returns a copy of the chain whose first client runs the given
+// model; model names are provider specific, so fallbacks keep their own
+func (c *CompositeClient) WithModel(model string) Client {
+	clients := append([]Client(nil), c.clients...)
+	if selector, ok := clients[0].(ModelSelector); ok {
+		clients[0] = selector.WithModel(model)
+	}
+	return &CompositeClient{clients: clients}
+}
+
+// GetName returns the name of the first client in the chain
+func (c *CompositeClient) GetName() string {
+	return c.clients[0].GetName()
+}
+
+// IsAvailable reports whether any client in the chain is available
+func (c *CompositeClient) IsAvailable() bool {
+	for _, client := range c.clients {
+		if client.IsAvailable() {
+			return true
+		}
+	}
+	return false
+}
+
+// Provider returns the provider that served a response: the "provider"
+// metadata a CompositeClient records, or fallback when there is none
+func Provider(response *Response, fallback string) string {
+	if response != nil {
+		if provider, ok := response.Metadata["provider"].(string); ok {
+			return provider
+		}
+	}
+	return fallback
+}
+
+// FallbackFailures returns the provider failures a CompositeClient fell back past
+func FallbackFailures(response *Response) []string {
+	if response == nil {
+		return nil
+	}
+	failures, _ := response.Metadata["fallback_from"].([]string)
+	return failures
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"baton/internal/config"
+)
+
+// Usage is the tokens and cost of one or more LLM calls
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Add adds other's tokens and cost to u
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CostUSD += other.CostUSD
+}
+
+// CountTokens estimates the tokens in text at four characters per token, for
+// providers that do not report usage
+func CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// ResponseUsage returns the tokens and cost of one call. Token counts come
+// from the response's prompt_tokens and completion_tokens metadata, or are
+// estimated from the text. The cost is the one the provider reported, or is
+// priced from the response's model in pricing.
+func ResponseUsage(prompt string, response *Response, pricing map[string]config.ModelPrice) Usage {
+	if response == nil {
+		return Usage{}
+	}
+
+	usage := Usage{CostUSD: response.Cost}
+	var ok bool
+	if usage.PromptTokens, ok = metadataInt(response.Metadata, "prompt_tokens"); !ok {
+		usage.PromptTokens = CountTokens(prompt)
+	}
+	if usage.CompletionTokens, ok = metadataInt(response.Metadata, "completion_tokens"); !ok {
+		usage.CompletionTokens = CountTokens(response.Content)
+	}
+
+	if usage.CostUSD == 0 {
+		model, _ := response.Metadata["model"].(string)
+		if price, ok := priceFor(pricing, model); ok {
+			usage.CostUSD = (float64(usage.PromptTokens)*price.InputPerMTok +
+				float64(usage.CompletionTokens)*price.OutputPerMTok) / 1e6
+		}
+	}
+	return usage
+}
+
+// PromptCost prices a prompt of the given tokens at model's input price,
+// when pricing lists the model
+func PromptCost(pricing map[string]config.ModelPrice, model string, tokens int) (float64, bool) {
+	price, ok := priceFor(pricing, model)
+	if !ok {
+		return 0, false
+	}
+	return float64(tokens) * price.InputPerMTok / 1e6, true
+}
+
+// priceFor finds a model's price. APIs report dated snapshots such as
+// gpt-4o-2024-08-06, so the longest configured name the model starts with
+// wins; names are compared case-insensitively, as config keys are lowercased.
+func priceFor(pricing map[string]config.ModelPrice, model string) (config.ModelPrice, bool) {
+	model = strings.ToLower(model)
+	var best string
+	for name := range pricing {
+		if strings.HasPrefix(model, strings.ToLower(name)) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return config.ModelPrice{}, false
+	}
+	return pricing[best], true
+}
+
+// metadataInt reads a token count, which is an int when set by a client and
+// a float64 when decoded from JSON
+func metadataInt(metadata map[string]interface{}, key string) (int, bool) {
+	switch n := metadata[key].(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// BudgetExceededError reports LLM spending past a configured budget
+type BudgetExceededError struct {
+	Scope string  // "cycle" or "day"
+	Limit float64 // USD
+	Spent float64 // USD
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("LLM budget exceeded: $%.4f spent against the %s budget of $%.2f", e.Spent, e.Scope, e.Limit)
+}
+
+// CostTracker adds up a cycle's LLM usage and checks it against
+// llm.budget_usd_per_cycle and, with what was spent earlier in the day,
+// llm.budget_usd_per_day
+type CostTracker struct {
+	config     *config.LLMConfig
+	spentToday float64
+	usage      Usage
+}
+
+// NewCostTracker creates a tracker for one cycle; spentToday is what earlier
+// cycles spent today
+func NewCostTracker(config *config.LLMConfig, spentToday float64) *CostTracker {
+	return &CostTracker{
+		config:     config,
+		spentToday: spentToday,
+	}
+}
+
+// Record adds one call's usage to the cycle and returns it
+func (t *CostTracker) Record(prompt string, response *Response) Usage {
+	usage := ResponseUsage(prompt, response, t.config.Pricing)
+	t.usage.Add(usage)
+	return usage
+}
+
+// Usage returns the cycle's usage so far
+func (t *CostTracker) Usage() Usage {
+	return t.usage
+}
+
+// Check returns a *BudgetExceededError once the cycle or the day has spent
+// its budget
+func (t *CostTracker) Check() error {
+	if limit := t.config.BudgetUSDPerCycle; limit > 0 && t.usage.CostUSD >= limit {
+		return &BudgetExceededError{Scope: "cycle", Limit: limit, Spent: t.usage.CostUSD}
+	}
+	if limit := t.config.BudgetUSDPerDay; limit > 0 && t.spentToday+t.usage.CostUSD >= limit {
+		return &BudgetExceededError{Scope: "day", Limit: limit, Spent: t.spentToday + t.usage.CostUSD}
+	}
+	return nil
+}
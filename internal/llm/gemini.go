@@ -0,0 +1,307 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"baton/internal/config"
+)
+
+// GeminiClient implements the LLM client against Google's Gemini models,
+// through the generateContent API or, with command set, the Gemini CLI
+type GeminiClient struct {
+	config *config.GeminiConfig
+	client *http.Client
+}
+
+// NewGeminiClient creates a new Gemini client; timeout bounds each API request
+func NewGeminiClient(config *config.GeminiConfig, timeout time.Duration) *GeminiClient {
+	return &GeminiClient{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// geminiPart is one piece of a message's content
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiContent is one message of a generateContent request or response
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiGenerationConfig limits and shapes the reply
+type geminiGenerationConfig struct {
+	MaxOutputTokens    int    `json:"maxOutputTokens,omitempty"`
+	ResponseMimeType   string `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema Schema `json:"responseJsonSchema,omitempty"`
+}
+
+// geminiRequest is the body of POST /models/{model}:generateContent
+type geminiRequest struct {
+	Contents         []geminiContent         `json:"contents"`
+	GenerationConfig *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// geminiResponse is the part of a generateContent response the client uses
+type geminiResponse struct {
+	ResponseID   string `json:"responseId"`
+	ModelVersion string `json:"modelVersion"`
+	Candidates   []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// Execute sends the prompt as a single user message
+func (c *GeminiClient) Execute(ctx context.Context, prompt string, agentID string) (*Response, error) {
+	if c.config.Command != "" {
+		return c.executeCLI(ctx, prompt)
+	}
+	return c.generate(ctx, prompt, nil)
+}
+
+// GenerateStructured runs a one-shot prompt in JSON schema mode. The CLI has
+// no such mode and relies on the schema GenerateJSON puts in the prompt.
+func (c *GeminiClient) GenerateStructured(ctx context.Context, prompt string, schema Schema) (string, error) {
+	if c.config.Command != "" {
+		return c.GenerateText(ctx, prompt)
+	}
+
+	response, err := c.generate(ctx, prompt, &geminiGenerationConfig{
+		ResponseMimeType:   "application/json",
+		ResponseJSONSchema: schema,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Success && response.Error != nil {
+		return "", response.Error
+	}
+
+	return response.Content, nil
+}
+
+// generate sends a generateContent request
+func (c *GeminiClient) generate(ctx context.Context, prompt string, generation *geminiGenerationConfig) (*Response, error) {
+	start := time.Now()
+
+	apiKey := os.Getenv(c.config.APIKeyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("Gemini API key not set: export %s", c.config.APIKeyEnv)
+	}
+
+	if c.config.MaxTokens > 0 {
+		if generation == nil {
+			generation = &geminiGenerationConfig{}
+		}
+		generation.MaxOutputTokens = c.config.MaxTokens
+	}
+	body, err := json.Marshal(geminiRequest{
+		Contents:         []geminiContent{{Role: "user", Parts: []geminiPart{{Text: prompt}}}},
+		GenerationConfig: generation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/models/%s:generateContent", strings.TrimRight(c.config.BaseURL, "/"), c.config.Model)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Gemini request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Gemini response: %w", err)
+	}
+
+	var generated geminiResponse
+	if err := json.Unmarshal(data, &generated); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Gemini request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if generated.Error != nil {
+			return nil, fmt.Errorf("Gemini request failed with status %d: %s", resp.StatusCode, generated.Error.Message)
+		}
+		return nil, fmt.Errorf("Gemini request failed with status %d", resp.StatusCode)
+	}
+	if len(generated.Candidates) == 0 {
+		if generated.PromptFeedback != nil && generated.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("Gemini blocked the prompt: %s", generated.PromptFeedback.BlockReason)
+		}
+		return nil, fmt.Errorf("Gemini response has no candidates")
+	}
+
+	candidate := generated.Candidates[0]
+	var text []string
+	for _, part := range candidate.Content.Parts {
+		text = append(text, part.Text)
+	}
+
+	model := generated.ModelVersion
+	if model == "" {
+		model = c.config.Model
+	}
+	response := &Response{
+		Success:   true,
+		Content:   strings.Join(text, ""),
+		Duration:  time.Since(start),
+		SessionID: generated.ResponseID,
+		Metadata: map[string]interface{}{
+			"model":             model,
+			"finish_reason":     candidate.FinishReason,
+			"prompt_tokens":     generated.UsageMetadata.PromptTokenCount,
+			"completion_tokens": generated.UsageMetadata.CandidatesTokenCount,
+			"total_tokens":      generated.UsageMetadata.TotalTokenCount,
+		},
+	}
+
+	// A reply cut off by the token limit or a safety filter is incomplete
+	switch candidate.FinishReason {
+	case "MAX_TOKENS":
+		response.Success = false
+		response.Error = fmt.Errorf("Gemini response truncated at max_tokens")
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT":
+		response.Success = false
+		response.Error = fmt.Errorf("Gemini stopped the response: %s", candidate.FinishReason)
+	}
+
+	return response, nil
+}
+
+// executeCLI runs the prompt through the Gemini CLI in non-interactive mode
+func (c *GeminiClient) executeCLI(ctx context.Context, prompt string) (*Response, error) {
+	start := time.Now()
+
+	args := []string{"-p", prompt}
+	if c.config.Model != "" {
+		args = append(args, "-m", c.config.Model)
+	}
+
+	cmd := exec.Command(c.config.Command, args...)
+	cmd.Env = os.Environ()
+	cmd.Dir = workDirFrom(ctx)
+	prepareProcess(cmd)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("gemini command failed: %w", err)
+	}
+	stopped := stopOnDone(ctx, cmd)
+	err := cmd.Wait()
+	stopped()
+
+	// Stopped as ctx is done: keep what the agent said until then
+	if ctx.Err() != nil {
+		stopErr := fmt.Errorf("gemini command stopped: %w", ctx.Err())
+		return &Response{
+			Success:  false,
+			Content:  stdout.String(),
+			Duration: time.Since(start),
+			Metadata: map[string]interface{}{"model": c.config.Model},
+			Error:    stopErr,
+		}, stopErr
+	}
+
+	if err != nil {
+		if stdout.Len() == 0 {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("gemini command failed: %w: %s", err, msg)
+			}
+			return nil, fmt.Errorf("gemini command failed: %w", err)
+		}
+		// Command failed but we got some output
+		return &Response{
+			Success:  false,
+			Content:  stdout.String(),
+			Duration: time.Since(start),
+			Metadata: map[string]interface{}{"model": c.config.Model},
+			Error:    err,
+		}, nil
+	}
+
+	return &Response{
+		Success:  true,
+		Content:  stdout.String(),
+		Duration: time.Since(start),
+		Metadata: map[string]interface{}{"model": c.config.Model},
+	}, nil
+}
+
+// GenerateText executes a one-shot prompt and returns the response content
+func (c *GeminiClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	response, err := c.Execute(ctx, prompt, "")
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Success && response.Error != nil {
+		return "", response.Error
+	}
+
+	return response.Content, nil
+}
+
+// WithModel returns a copy of the client that runs the given model
+func (c *GeminiClient) WithModel(model string) Client {
+	cfg := *c.config
+	cfg.Model = model
+	return &GeminiClient{
+		config: &cfg,
+		client: c.client,
+	}
+}
+
+// GetName returns the client name
+func (c *GeminiClient) GetName() string {
+	return "gemini"
+}
+
+// IsAvailable checks that the CLI is installed or, for the API, that an API
+// key is configured
+func (c *GeminiClient) IsAvailable() bool {
+	if c.config.Command != "" {
+		_, err := exec.LookPath(c.config.Command)
+		return err == nil
+	}
+	return c.config.APIKeyEnv != "" && os.Getenv(c.config.APIKeyEnv) != ""
+}
@@ -0,0 +1,250 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Schema is a JSON schema. GenerateJSON checks the keywords type, properties,
+// required, items and enum; providers with a structured output mode receive
+// the whole schema.
+type Schema map[string]interface{}
+
+// StructuredGenerator is implemented by clients whose provider can constrain
+// a reply to a JSON schema
+type StructuredGenerator interface {
+	GenerateStructured(ctx context.Context, prompt string, schema Schema) (string, error)
+}
+
+// codeFence matches a fenced code block, optionally tagged json
+var codeFence = regexp.MustCompile("(?s)```(?:json)?\\s*\\n(.*?)```")
+
+// trailingComma matches a comma closing an object or array, which JSON forbids
+var trailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+
+// GenerateJSON runs prompt and decodes the reply into v after checking it
+// against schema. Replies wrapped in prose or code fences are unwrapped; a
+// reply that still does not match is sent back to the model once to repair.
+func GenerateJSON(ctx context.Context, client Client, prompt string, schema Schema, v interface{}) error {
+	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	prompt = fmt.Sprintf("%s\n\nRespond with ONLY a JSON value matching this JSON schema:\n%s", prompt, schemaJSON)
+
+	reply, err := generateStructured(ctx, client, prompt, schema)
+	if err != nil {
+		return err
+	}
+
+	value, problems := checkJSON(reply, schema)
+	if len(problems) > 0 {
+		repairPrompt := fmt.Sprintf(`Your previous response did not match the required JSON schema:
+- %s
+
+JSON schema:
+%s
+
+Previous response:
+%s
+
+Respond with ONLY the corrected JSON value.`, strings.Join(problems, "\n- "), schemaJSON, reply)
+
+		reply, err = generateStructured(ctx, client, repairPrompt, schema)
+		if err != nil {
+			return err
+		}
+		if value, problems = checkJSON(reply, schema); len(problems) > 0 {
+			return fmt.Errorf("LLM response does not match the schema: %s", strings.Join(problems, "; "))
+		}
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode LLM response: %w", err)
+	}
+	return nil
+}
+
+// generateStructured uses the provider's structured output mode when it has one
+func generateStructured(ctx context.Context, client Client, prompt string, schema Schema) (string, error) {
+	if generator, ok := client.(StructuredGenerator); ok {
+		return generator.GenerateStructured(ctx, prompt, schema)
+	}
+	return client.GenerateText(ctx, prompt)
+}
+
+// checkJSON extracts the JSON value from reply and validates it, returning
+// the problems found
+func checkJSON(reply string, schema Schema) (interface{}, []string) {
+	value, err := ExtractJSON(reply)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	return value, ValidateJSON(value, schema)
+}
+
+// ExtractJSON decodes the JSON value in an LLM reply, tolerating surrounding
+// prose, markdown code fences and trailing commas
+func ExtractJSON(reply string) (interface{}, error) {
+	candidates := []string{strings.TrimSpace(reply)}
+	if match := codeFence.FindStringSubmatch(reply); match != nil {
+		candidates = append(candidates, strings.TrimSpace(match[1]))
+	}
+	for _, delims := range []string{"{}", "[]"} {
+		start := strings.IndexByte(reply, delims[0])
+		end := strings.LastIndexByte(reply, delims[1])
+		if start >= 0 && end > start {
+			candidates = append(candidates, reply[start:end+1])
+		}
+	}
+
+	for _, candidate := range candidates {
+		for _, text := range []string{candidate, trailingComma.ReplaceAllString(candidate, "$1")} {
+			var value interface{}
+			if err := json.Unmarshal([]byte(text), &value); err == nil {
+				return value, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no JSON value found in response")
+}
+
+// ValidateJSON checks a decoded JSON value against schema and returns the
+// problems found, each prefixed with the path of the offending value
+func ValidateJSON(value interface{}, schema Schema) []string {
+	var problems []string
+	validate("$", value, schema, &problems)
+	return problems
+}
+
+// validate appends the problems of value at path to problems
+func validate(path string, value interface{}, schema Schema, problems *[]string) {
+	if types := stringList(schema["type"]); len(types) > 0 && !matchesType(value, types) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonType(value)))
+		return
+	}
+
+	if enum, ok := schema["enum"]; ok && !inEnum(value, enum) {
+		*problems = append(*problems, fmt.Sprintf("%s: %v is not one of %v", path, value, enum))
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := value[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		properties := asSchema(schema["properties"])
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := properties[name]; ok {
+				validate(path+"."+name, value[name], asSchema(property), problems)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"]; ok {
+			for i, item := range value {
+				validate(fmt.Sprintf("%s[%d]", path, i), item, asSchema(items), problems)
+			}
+		}
+	}
+}
+
+// matchesType reports whether value has one of the JSON schema types
+func matchesType(value interface{}, types []string) bool {
+	for _, t := range types {
+		actual := jsonType(value)
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON schema type of a decoded value
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// inEnum reports whether value equals one of the enum's values
+func inEnum(value interface{}, enum interface{}) bool {
+	for _, allowed := range list(enum) {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// asSchema converts a nested schema, which may be written as Schema or as a
+// plain map, to Schema
+func asSchema(v interface{}) Schema {
+	switch v := v.(type) {
+	case Schema:
+		return v
+	case map[string]interface{}:
+		return v
+	}
+	return nil
+}
+
+// stringList converts a string or list of strings to a slice
+func stringList(v interface{}) []string {
+	if s, ok := v.(string); ok {
+		return []string{s}
+	}
+	var out []string
+	for _, item := range list(v) {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// list converts a []string or []interface{} to []interface{}
+func list(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, s := range v {
+			out[i] = s
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type mcpCycleKey struct{}
+
+type mcpTokenKey struct{}
+
+// mcpTokenEnv carries the MCP token to agent CLIs, whose MCP configuration
+// refers to it rather than holding the token on the command line
+const mcpTokenEnv = "BATON_MCP_TOKEN"
+
+// WithMCPCycle returns a context that makes clients which connect to the
+// baton MCP server use the endpoint of the given cycle, so that a server
+// shared by concurrent cycles knows which task each agent works on
+func WithMCPCycle(ctx context.Context, cycleID string) context.Context {
+	return context.WithValue(ctx, mcpCycleKey{}, cycleID)
+}
+
+// WithMCPToken returns a context that makes clients which connect to the
+// baton MCP server send the token it requires; "" sends none
+func WithMCPToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, mcpTokenKey{}, token)
+}
+
+// mcpURL returns the MCP server endpoint on port for the context's cycle; the
+// server serves each cycle under /cycles/<cycle ID>, on localhost
+func mcpURL(ctx context.Context, port int) string {
+	endpoint := fmt.Sprintf("http://127.0.0.1:%d", port)
+	if cycleID, _ := ctx.Value(mcpCycleKey{}).(string); cycleID != "" {
+		endpoint += "/cycles/" + cycleID
+	}
+	return endpoint
+}
+
+// mcpToken returns the token the context's clients send to the MCP server,
+// in an Authorization header and never in the URL
+func mcpToken(ctx context.Context) string {
+	token, _ := ctx.Value(mcpTokenKey{}).(string)
+	return token
+}
+
+// claudeMCPConfig returns the --mcp-config JSON connecting the claude CLI to
+// the MCP server on port. The Authorization header refers to the token in
+// mcpTokenEnv, which the CLI expands, so the token never appears in argv.
+func claudeMCPConfig(ctx context.Context, port int) (string, error) {
+	server := map[string]interface{}{
+		"type": "http",
+		"url":  mcpURL(ctx, port),
+	}
+	if mcpToken(ctx) != "" {
+		server["headers"] = map[string]string{
+			"Authorization": "Bearer ${" + mcpTokenEnv + "}",
+		}
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"mcpServers": map[string]interface{}{"baton": server},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode MCP config: %w", err)
+	}
+	return string(data), nil
+}
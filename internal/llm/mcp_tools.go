@@ -0,0 +1,179 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// ollamaTool is a function the model may call, in Ollama's tool format
+type ollamaTool struct {
+	Type     string             `json:"type"`
+	Function ollamaToolFunction `json:"function"`
+}
+
+// ollamaToolFunction describes a tool's name and JSON schema parameters
+type ollamaToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// toolMethods maps tool names to the MCP methods they call. Tool names avoid
+// dots, which many models handle poorly.
+var toolMethods = map[string]string{
+	"baton_tasks_get":              "baton.tasks.get",
+	"baton_tasks_update_state":     "baton.tasks.update_state",
+	"baton_tasks_check_transition": "baton.tasks.check_transition",
+	"baton_tasks_append_note":      "baton.tasks.append_note",
+	"baton_artifacts_get":          "baton.artifacts.get",
+	"baton_artifacts_list":         "baton.artifacts.list",
+	"baton_artifacts_upsert":       "baton.artifacts.upsert",
+	"baton_cycle_current":          "baton.cycle.current",
+}
+
+// ollamaTools are the baton methods offered to models
+var ollamaTools = []ollamaTool{
+	tool("baton_tasks_get", "Get a task with its state, description and artifacts",
+		params(map[string]string{"task_id": "Task ID"}, "task_id")),
+	tool("baton_tasks_update_state", "Move a task to its next state when the work for the current state is done",
+		params(map[string]string{"task_id": "Task ID", "state": "Next state", "note": "What was done"}, "task_id", "state")),
+	tool("baton_tasks_check_transition", "Check what moving a task to a state needs, without moving it",
+		params(map[string]string{"task_id": "Task ID", "state": "State to check"}, "task_id", "state")),
+	tool("baton_tasks_append_note", "Append a note to a task without changing its state",
+		params(map[string]string{"task_id": "Task ID", "note": "Note text"}, "task_id", "note")),
+	tool("baton_artifacts_get", "Get the latest version of a task artifact",
+		params(map[string]string{"task_id": "Task ID", "name": "Artifact name, e.g. plan or review"}, "task_id", "name")),
+	tool("baton_artifacts_list", "List a task's artifacts",
+		params(map[string]string{"task_id": "Task ID"}, "task_id")),
+	tool("baton_artifacts_upsert", "Create or update a task artifact such as a plan, implementation notes or a review",
+		params(map[string]string{"task_id": "Task ID", "name": "Artifact name", "content": "Artifact content"}, "task_id", "name", "content")),
+	tool("baton_cycle_current", "Get the cycle and task currently being worked on",
+		params(nil)),
+}
+
+// tool builds a tool definition
+func tool(name, description string, parameters map[string]interface{}) ollamaTool {
+	return ollamaTool{
+		Type:     "function",
+		Function: ollamaToolFunction{Name: name, Description: description, Parameters: parameters},
+	}
+}
+
+// params builds an object schema of string properties
+func params(properties map[string]string, required ...string) map[string]interface{} {
+	props := make(map[string]interface{}, len(properties))
+	for name, description := range properties {
+		props[name] = map[string]interface{}{"type": "string", "description": description}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// mcpToolBridge runs tool calls against the baton MCP server over HTTP
+type mcpToolBridge struct {
+	url     string
+	token   string
+	session string // Mcp-Session-Id the server gave out on initialize
+	client  *http.Client
+	nextID  int64
+}
+
+// newMCPToolBridge creates a bridge to the MCP server at url, sending token
+// as a bearer token unless it is ""
+func newMCPToolBridge(url, token string, client *http.Client) *mcpToolBridge {
+	return &mcpToolBridge{
+		url:    url + "/",
+		token:  token,
+		client: client,
+	}
+}
+
+// initialize performs the MCP initialize handshake
+func (b *mcpToolBridge) initialize(ctx context.Context) error {
+	_, err := b.rpc(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": "2024-11-05",
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "baton-ollama"},
+	})
+	return err
+}
+
+// call runs one tool call and returns its result, or the error, as the text
+// handed back to the model
+func (b *mcpToolBridge) call(ctx context.Context, name string, arguments map[string]interface{}) string {
+	method, ok := toolMethods[name]
+	if !ok {
+		return fmt.Sprintf("error: unknown tool %q", name)
+	}
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+
+	result, err := b.rpc(ctx, method, arguments)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return string(result)
+}
+
+// rpc sends a JSON-RPC request and returns its result
+func (b *mcpToolBridge) rpc(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      atomic.AddInt64(&b.nextID, 1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Taking event streams gets the bridge a session of its own, so its
+	// handshake is not shared with other clients without one
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	if b.session != "" {
+		req.Header.Set("Mcp-Session-Id", b.session)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if session := resp.Header.Get("Mcp-Session-Id"); session != "" {
+		b.session = session
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string          `json:"message"`
+			Data    json.RawMessage `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid MCP response: %w", err)
+	}
+	if response.Error != nil {
+		if len(response.Error.Data) > 0 && string(response.Error.Data) != "null" {
+			return nil, fmt.Errorf("%s: %s", response.Error.Message, response.Error.Data)
+		}
+		return nil, fmt.Errorf("%s", response.Error.Message)
+	}
+	return response.Result, nil
+}
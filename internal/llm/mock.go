@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MockClient is a scripted LLM client that returns canned responses in order.
+// It is used to replay recorded cycles without invoking a real model.
+type MockClient struct {
+	mu        sync.Mutex
+	responses []*Response
+	calls     []MockCall
+
+	// OnExecute, if set, runs before each scripted response is returned
+	OnExecute func(ctx context.Context, prompt string, agentID string) error
+}
+
+// MockCall records one prompt sent to the mock client
+type MockCall struct {
+	Prompt  string `json:"prompt"`
+	AgentID string `json:"agent_id"`
+}
+
+// NewMockClient creates a mock client that returns the given responses in order
+func NewMockClient(responses ...*Response) *MockClient {
+	return &MockClient{
+		responses: responses,
+	}
+}
+
+// Execute returns the next scripted response
+func (m *MockClient) Execute(ctx context.Context, prompt string, agentID string) (*Response, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, MockCall{Prompt: prompt, AgentID: agentID})
+	if len(m.responses) == 0 {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("mock client has no scripted response left")
+	}
+	response := m.responses[0]
+	m.responses = m.responses[1:]
+	m.mu.Unlock()
+
+	if m.OnExecute != nil {
+		if err := m.OnExecute(ctx, prompt, agentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// GenerateText returns the content of the next scripted response
+func (m *MockClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	response, err := m.Execute(ctx, prompt, "")
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// Calls returns the prompts the mock client has received
+func (m *MockClient) Calls() []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCall(nil), m.calls...)
+}
+
+// GetName returns the client name
+func (m *MockClient) GetName() string {
+	return "mock"
+}
+
+// IsAvailable always reports the mock client as available
+func (m *MockClient) IsAvailable() bool {
+	return true
+}
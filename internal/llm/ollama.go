@@ -0,0 +1,250 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"baton/internal/config"
+)
+
+// OllamaClient implements the LLM client against a local Ollama server. When
+// the MCP server is reachable it exposes baton's methods as tools, so models
+// with tool support can update tasks and artifacts during a cycle.
+type OllamaClient struct {
+	config  *config.OllamaConfig
+	client  *http.Client
+	mcpPort int
+}
+
+// NewOllamaClient creates a new Ollama client; timeout bounds each request
+func NewOllamaClient(config *config.OllamaConfig, timeout time.Duration, mcpPort int) *OllamaClient {
+	return &OllamaClient{
+		config:  config,
+		client:  &http.Client{Timeout: timeout},
+		mcpPort: mcpPort,
+	}
+}
+
+// ollamaMessage is one message of an Ollama chat
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+// ollamaToolCall is a function call requested by the model
+type ollamaToolCall struct {
+	Function struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaChatRequest is the body of POST /api/chat
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Tools    []ollamaTool           `json:"tools,omitempty"`
+	Format   Schema                 `json:"format,omitempty"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaChatResponse is the part of a chat response the client uses
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// Execute sends the prompt and runs any tool calls the model makes until it
+// answers without one or the tool call budget is spent
+func (c *OllamaClient) Execute(ctx context.Context, prompt string, agentID string) (*Response, error) {
+	start := time.Now()
+
+	messages := []ollamaMessage{{Role: "user", Content: prompt}}
+
+	var tools *mcpToolBridge
+	if c.config.MCPTools && c.mcpPort > 0 {
+		tools = newMCPToolBridge(mcpURL(ctx, c.mcpPort), mcpToken(ctx), c.client)
+		if err := tools.initialize(ctx); err != nil {
+			// Without the MCP server the model still answers, it just cannot act
+			tools = nil
+		}
+	}
+
+	var promptTokens, completionTokens, toolCalls int
+	for {
+		request := ollamaChatRequest{
+			Model:    c.config.Model,
+			Messages: messages,
+			Stream:   false,
+		}
+		if c.config.NumCtx > 0 {
+			request.Options = map[string]interface{}{"num_ctx": c.config.NumCtx}
+		}
+		if tools != nil && toolCalls < c.config.MaxToolCalls {
+			request.Tools = ollamaTools
+		}
+
+		chat, err := c.chat(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		promptTokens += chat.PromptEvalCount
+		completionTokens += chat.EvalCount
+
+		if len(chat.Message.ToolCalls) == 0 || request.Tools == nil {
+			response := &Response{
+				Success:  true,
+				Content:  chat.Message.Content,
+				Duration: time.Since(start),
+				Metadata: map[string]interface{}{
+					"model":             chat.Model,
+					"done_reason":       chat.DoneReason,
+					"prompt_tokens":     promptTokens,
+					"completion_tokens": completionTokens,
+					"tool_calls":        toolCalls,
+				},
+			}
+
+			// A reply cut off by the context or token limit is incomplete
+			if chat.DoneReason == "length" {
+				response.Success = false
+				response.Error = fmt.Errorf("Ollama response truncated at the model's limit")
+			}
+			return response, nil
+		}
+
+		messages = append(messages, chat.Message)
+		for _, call := range chat.Message.ToolCalls {
+			toolCalls++
+			messages = append(messages, ollamaMessage{
+				Role:     "tool",
+				ToolName: call.Function.Name,
+				Content:  tools.call(ctx, call.Function.Name, call.Function.Arguments),
+			})
+		}
+	}
+}
+
+// chat sends one non-streaming chat request
+func (c *OllamaClient) chat(ctx context.Context, request ollamaChatRequest) (*ollamaChatResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/api/chat"), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Ollama request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Ollama response: %w", err)
+	}
+
+	var chat ollamaChatResponse
+	if err := json.Unmarshal(data, &chat); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Ollama request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return nil, fmt.Errorf("failed to parse Ollama response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if chat.Error != "" {
+			return nil, fmt.Errorf("Ollama request failed with status %d: %s", resp.StatusCode, chat.Error)
+		}
+		return nil, fmt.Errorf("Ollama request failed with status %d", resp.StatusCode)
+	}
+
+	return &chat, nil
+}
+
+// GenerateText executes a one-shot prompt and returns the response content
+func (c *OllamaClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	response, err := c.Execute(ctx, prompt, "")
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Success && response.Error != nil {
+		return "", response.Error
+	}
+
+	return response.Content, nil
+}
+
+// GenerateStructured runs a one-shot prompt constrained to the JSON schema,
+// without tools
+func (c *OllamaClient) GenerateStructured(ctx context.Context, prompt string, schema Schema) (string, error) {
+	request := ollamaChatRequest{
+		Model:    c.config.Model,
+		Messages: []ollamaMessage{{Role: "user", Content: prompt}},
+		Format:   schema,
+		Stream:   false,
+	}
+	if c.config.NumCtx > 0 {
+		request.Options = map[string]interface{}{"num_ctx": c.config.NumCtx}
+	}
+
+	chat, err := c.chat(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	if chat.DoneReason == "length" {
+		return "", fmt.Errorf("Ollama response truncated at the model's limit")
+	}
+
+	return chat.Message.Content, nil
+}
+
+// WithModel returns a copy of the client that runs the given model
+func (c *OllamaClient) WithModel(model string) Client {
+	cfg := *c.config
+	cfg.Model = model
+	return &OllamaClient{
+		config:  &cfg,
+		client:  c.client,
+		mcpPort: c.mcpPort,
+	}
+}
+
+// GetName returns the client name
+func (c *OllamaClient) GetName() string {
+	return "ollama"
+}
+
+// IsAvailable checks that the Ollama server answers
+func (c *OllamaClient) IsAvailable() bool {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(c.url("/api/version"))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// url joins path to the configured host
+func (c *OllamaClient) url(path string) string {
+	return strings.TrimRight(c.config.Host, "/") + path
+}
@@ -0,0 +1,256 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"baton/internal/config"
+)
+
+// OpenAIClient implements the LLM client against the OpenAI Chat Completions
+// API, or any endpoint compatible with it
+type OpenAIClient struct {
+	config *config.OpenAIConfig
+	client *http.Client
+
+	name        string            // provider name, "openai" or "openai_compatible"
+	label       string            // how errors name the endpoint
+	headers     map[string]string // extra request headers
+	keyOptional bool              // whether requests may go without an API key
+}
+
+// NewOpenAIClient creates a new OpenAI client; timeout bounds each request
+func NewOpenAIClient(config *config.OpenAIConfig, timeout time.Duration) *OpenAIClient {
+	return &OpenAIClient{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+		name:   "openai",
+		label:  "OpenAI",
+	}
+}
+
+// NewOpenAICompatibleClient creates a client for a generic OpenAI-compatible
+// endpoint such as a LiteLLM or OpenRouter proxy. The API key is optional and
+// the configured headers are sent with every request, their $VAR references
+// expanded from the environment.
+func NewOpenAICompatibleClient(cfg *config.OpenAICompatibleConfig, timeout time.Duration) *OpenAIClient {
+	headers := make(map[string]string, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		headers[name] = os.ExpandEnv(value)
+	}
+
+	return &OpenAIClient{
+		config: &config.OpenAIConfig{
+			Model:     cfg.Model,
+			BaseURL:   cfg.BaseURL,
+			APIKeyEnv: cfg.APIKeyEnv,
+			MaxTokens: cfg.MaxTokens,
+		},
+		client:      &http.Client{Timeout: timeout},
+		name:        "openai_compatible",
+		label:       "OpenAI-compatible endpoint",
+		headers:     headers,
+		keyOptional: true,
+	}
+}
+
+// chatMessage is one message of a chat completion request or response
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatCompletionRequest is the body of POST /chat/completions
+type chatCompletionRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// responseFormat asks for a reply matching a JSON schema
+type responseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema struct {
+		Name   string `json:"name"`
+		Schema Schema `json:"schema"`
+	} `json:"json_schema"`
+}
+
+// chatCompletionResponse is the part of a chat completion the client uses
+type chatCompletionResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// Execute sends the prompt as a single user message
+func (c *OpenAIClient) Execute(ctx context.Context, prompt string, agentID string) (*Response, error) {
+	return c.complete(ctx, chatCompletionRequest{
+		Model:     c.config.Model,
+		Messages:  []chatMessage{{Role: "user", Content: prompt}},
+		MaxTokens: c.config.MaxTokens,
+	})
+}
+
+// GenerateStructured runs a one-shot prompt in JSON schema mode
+func (c *OpenAIClient) GenerateStructured(ctx context.Context, prompt string, schema Schema) (string, error) {
+	format := &responseFormat{Type: "json_schema"}
+	format.JSONSchema.Name = "response"
+	format.JSONSchema.Schema = schema
+
+	response, err := c.complete(ctx, chatCompletionRequest{
+		Model:          c.config.Model,
+		Messages:       []chatMessage{{Role: "user", Content: prompt}},
+		MaxTokens:      c.config.MaxTokens,
+		ResponseFormat: format,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Success && response.Error != nil {
+		return "", response.Error
+	}
+
+	return response.Content, nil
+}
+
+// complete sends a chat completion request
+func (c *OpenAIClient) complete(ctx context.Context, request chatCompletionRequest) (*Response, error) {
+	start := time.Now()
+
+	var apiKey string
+	if c.config.APIKeyEnv != "" {
+		apiKey = os.Getenv(c.config.APIKeyEnv)
+	}
+	if apiKey == "" && (!c.keyOptional || c.config.APIKeyEnv != "") {
+		return nil, fmt.Errorf("%s API key not set: export %s", c.label, c.config.APIKeyEnv)
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(c.config.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", c.label, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", c.label, err)
+	}
+
+	var completion chatCompletionResponse
+	if err := json.Unmarshal(data, &completion); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s request failed with status %d: %s", c.label, resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return nil, fmt.Errorf("failed to parse %s response: %w", c.label, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if completion.Error != nil {
+			return nil, fmt.Errorf("%s request failed with status %d: %s", c.label, resp.StatusCode, completion.Error.Message)
+		}
+		return nil, fmt.Errorf("%s request failed with status %d", c.label, resp.StatusCode)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("%s response has no choices", c.label)
+	}
+
+	choice := completion.Choices[0]
+	response := &Response{
+		Success:   true,
+		Content:   choice.Message.Content,
+		Duration:  time.Since(start),
+		SessionID: completion.ID,
+		Metadata: map[string]interface{}{
+			"model":             completion.Model,
+			"finish_reason":     choice.FinishReason,
+			"prompt_tokens":     completion.Usage.PromptTokens,
+			"completion_tokens": completion.Usage.CompletionTokens,
+			"total_tokens":      completion.Usage.TotalTokens,
+		},
+	}
+
+	// A reply cut off by the token limit is incomplete
+	if choice.FinishReason == "length" {
+		response.Success = false
+		response.Error = fmt.Errorf("%s response truncated at max_tokens", c.label)
+	}
+
+	return response, nil
+}
+
+// GenerateText executes a one-shot prompt and returns the response content
+func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	response, err := c.Execute(ctx, prompt, "")
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Success && response.Error != nil {
+		return "", response.Error
+	}
+
+	return response.Content, nil
+}
+
+// WithModel returns a copy of the client that runs the given model
+func (c *OpenAIClient) WithModel(model string) Client {
+	cfg := *c.config
+	cfg.Model = model
+	copied := *c
+	copied.config = &cfg
+	return &copied
+}
+
+// GetName returns the client name
+func (c *OpenAIClient) GetName() string {
+	return c.name
+}
+
+// IsAvailable checks that an API key is configured; an OpenAI-compatible
+// endpoint also needs a base URL and model, and a key only if it names one
+func (c *OpenAIClient) IsAvailable() bool {
+	if c.keyOptional {
+		return c.config.BaseURL != "" && c.config.Model != "" &&
+			(c.config.APIKeyEnv == "" || os.Getenv(c.config.APIKeyEnv) != "")
+	}
+	return c.config.APIKeyEnv != "" && os.Getenv(c.config.APIKeyEnv) != ""
+}
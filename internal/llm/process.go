@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// KillGrace is how long a CLI agent gets to exit once asked to stop, when
+// its cycle's timebox runs out or the run is interrupted, before it and the
+// processes it started are killed
+const KillGrace = 5 * time.Second
+
+// prepareProcess makes cmd start in a process group of its own, so the tools
+// and servers it spawns are stopped with it, and keeps Wait from waiting on
+// processes that still hold its output once it exited
+func prepareProcess(cmd *exec.Cmd) {
+	startProcessGroup(cmd)
+	cmd.WaitDelay = KillGrace
+}
+
+// stopOnDone stops the process group of cmd, started with prepareProcess,
+// once ctx is done: it asks the processes to terminate, and KillGrace later
+// kills them and closes output, so readers of it don't wait on processes
+// that linger. The returned func ends the watch; call it once cmd exited.
+func stopOnDone(ctx context.Context, cmd *exec.Cmd, output ...io.Closer) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+		terminateProcessGroup(cmd, false)
+
+		select {
+		case <-done:
+			return
+		case <-time.After(KillGrace):
+		}
+		terminateProcessGroup(cmd, true)
+		for _, closer := range output {
+			_ = closer.Close()
+		}
+	}()
+	return func() { close(done) }
+}
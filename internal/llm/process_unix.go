@@ -0,0 +1,23 @@
+//go:build !windows
+
+package llm
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startProcessGroup makes cmd the leader of a new process group
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcessGroup sends the process group of cmd SIGTERM, or SIGKILL
+// when forced
+func terminateProcessGroup(cmd *exec.Cmd, force bool) {
+	sig := syscall.SIGTERM
+	if force {
+		sig = syscall.SIGKILL
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, sig)
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package llm
+
+import "os/exec"
+
+// startProcessGroup does nothing on Windows, where processes have no groups
+// to signal
+func startProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup kills cmd, as Windows has no signal to ask it to
+// terminate; its children are left alone
+func terminateProcessGroup(cmd *exec.Cmd, force bool) {
+	_ = cmd.Process.Kill()
+}
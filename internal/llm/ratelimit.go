@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"baton/internal/config"
+)
+
+// RateLimiter caps how many LLM calls start per minute and how many run at
+// once. Waiting callers give up when their context is done.
+type RateLimiter struct {
+	perMinute int
+	slots     chan struct{} // nil when concurrency is not limited
+
+	mu     sync.Mutex
+	starts []time.Time // call starts within the last minute, oldest first
+}
+
+// NewRateLimiter creates a limiter; a zero limit is no limit
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	limiter := &RateLimiter{perMinute: cfg.RequestsPerMinute}
+	if cfg.MaxConcurrent > 0 {
+		limiter.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return limiter
+}
+
+var (
+	sharedLimitersMu sync.Mutex
+	sharedLimiters   = make(map[config.RateLimitConfig]*RateLimiter)
+)
+
+// SharedRateLimiter returns the process-wide limiter for cfg, so the clients
+// of the web UI, cycles and model tiers draw on the same limits
+func SharedRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+
+	limiter, exists := sharedLimiters[cfg]
+	if !exists {
+		limiter = NewRateLimiter(cfg)
+		sharedLimiters[cfg] = limiter
+	}
+	return limiter
+}
+
+// Limited reports whether the limiter limits anything
+func (l *RateLimiter) Limited() bool {
+	return l.perMinute > 0 || l.slots != nil
+}
+
+// Wait blocks until a call may start and returns the function that ends it
+func (l *RateLimiter) Wait(ctx context.Context) (func(), error) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			return release, nil
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserve records a call start when the last minute has room for one, else
+// returns how long until it will
+func (l *RateLimiter) reserve() time.Duration {
+	if l.perMinute <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for len(l.starts) > 0 && now.Sub(l.starts[0]) >= time.Minute {
+		l.starts = l.starts[1:]
+	}
+	if len(l.starts) < l.perMinute {
+		l.starts = append(l.starts, now)
+		return 0
+	}
+	return l.starts[0].Add(time.Minute).Sub(now)
+}
+
+// RateLimitedClient makes every call of a client wait for its rate limiter
+type RateLimitedClient struct {
+	client  Client
+	limiter *RateLimiter
+}
+
+// NewRateLimitedClient wraps client with the limiter
+func NewRateLimitedClient(client Client, limiter *RateLimiter) *RateLimitedClient {
+	return &RateLimitedClient{client: client, limiter: limiter}
+}
+
+// Execute runs the prompt once the limiter lets it
+func (c *RateLimitedClient) Execute(ctx context.Context, prompt string, agentID string) (*Response, error) {
+	release, err := c.limiter.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.client.Execute(ctx, prompt, agentID)
+}
+
+// GenerateText runs a one-shot prompt once the limiter lets it
+func (c *RateLimitedClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	release, err := c.limiter.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return c.client.GenerateText(ctx, prompt)
+}
+
+// GenerateStructured runs a one-shot JSON prompt in the wrapped client's
+// structured output mode, if it has one, once the limiter lets it
+func (c *RateLimitedClient) GenerateStructured(ctx context.Context, prompt string, schema Schema) (string, error) {
+	release, err := c.limiter.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return generateStructured(ctx, c.client, prompt, schema)
+}
+
+// WithModel returns a copy of the wrapped client running the given model,
+// sharing this client's limiter
+func (c *RateLimitedClient) WithModel(model string) Client {
+	selector, ok := c.client.(ModelSelector)
+	if !ok {
+		return c
+	}
+	return &RateLimitedClient{client: selector.WithModel(model), limiter: c.limiter}
+}
+
+// GetName returns the wrapped client's name
+func (c *RateLimitedClient) GetName() string {
+	return c.client.GetName()
+}
+
+// IsAvailable reports whether the wrapped client is available
+func (c *RateLimitedClient) IsAvailable() bool {
+	return c.client.IsAvailable()
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// transientMarkers are error texts of failures worth retrying: rate limits,
+// overloaded or failing servers, timeouts and dropped connections
+var transientMarkers = []string{
+	"rate limit", "rate_limit", "too many requests", "status 429",
+	"overloaded", "status 529", "status 500", "status 502", "status 503", "status 504",
+	"timeout", "timed out", "deadline exceeded",
+	"connection reset", "connection refused", "broken pipe", "unexpected eof",
+	"temporarily unavailable", "text file busy", "signal: killed",
+}
+
+// IsTransient reports whether an LLM failure is likely to succeed on retry
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	text := strings.ToLower(err.Error())
+	for _, marker := range transientMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy is how often and how patiently a RetryClient retries
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt
+	Backoff    time.Duration // delay before the first retry, doubled for each one after
+	MaxBackoff time.Duration // cap on the delay
+}
+
+// delay returns the jittered backoff before the given retry, counting from 1:
+// a random duration between half and all of the exponential delay
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff << (retry - 1)
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// RetryClient retries a client's transient failures with exponential backoff
+// and jitter. Errors and unsuccessful responses count as failures; other
+// failures, such as a missing CLI or a bad API key, are returned at once.
+type RetryClient struct {
+	client Client
+	policy RetryPolicy
+}
+
+// NewRetryClient wraps client with the retry policy
+func NewRetryClient(client Client, policy RetryPolicy) *RetryClient {
+	return &RetryClient{client: client, policy: policy}
+}
+
+// Execute runs the prompt, retrying transient failures. The response's
+// metadata records the failures retried under "retries".
+func (c *RetryClient) Execute(ctx context.Context, prompt string, agentID string) (*Response, error) {
+	var retries []string
+	for retry := 0; ; retry++ {
+		if retry > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%w (after %d retries: %s)", ctx.Err(), len(retries), strings.Join(retries, "; "))
+			case <-time.After(c.policy.delay(retry)):
+			}
+		}
+
+		response, err := c.client.Execute(ctx, prompt, agentID)
+		failure := err
+		if failure == nil && response != nil && !response.Success {
+			failure = response.Error
+		}
+
+		// The caller's deadline or cancellation ends the cycle, not the attempt
+		if failure == nil || !IsTransient(failure) || ctx.Err() != nil || retry >= c.policy.MaxRetries {
+			if err != nil && len(retries) > 0 {
+				return response, fmt.Errorf("%w (after %d retries)", err, len(retries))
+			}
+			if response != nil && len(retries) > 0 {
+				if response.Metadata == nil {
+					response.Metadata = make(map[string]interface{})
+				}
+				response.Metadata["retries"] = retries
+			}
+			return response, err
+		}
+
+		retries = append(retries, failure.Error())
+	}
+}
+
+// GenerateText executes a one-shot prompt and returns the response content
+func (c *RetryClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	response, err := c.Execute(ctx, prompt, "")
+	if err != nil {
+		return "", err
+	}
+
+	if !response.Success && response.Error != nil {
+		return "", response.Error
+	}
+
+	return response.Content, nil
+}
+
+// GenerateStructured runs a one-shot JSON prompt in the wrapped client's
+// structured output mode, if it has one, retrying transient failures
+func (c *RetryClient) GenerateStructured(ctx context.Context, prompt string, schema Schema) (string, error) {
+	var lastErr error
+	for retry := 0; retry <= c.policy.MaxRetries; retry++ {
+		if retry > 0 {
+			select {
+			case <-ctx.Done():
+				return "", lastErr
+			case <-time.After(c.policy.delay(retry)):
+			}
+		}
+		content, err := generateStructured(ctx, c.client, prompt, schema)
+		if err == nil || !IsTransient(err) || ctx.Err() != nil {
+			return content, err
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("%w (after %d retries)", lastErr, c.policy.MaxRetries)
+}
+
+// WithModel returns a retrying copy of the wrapped client running the given model
+func (c *RetryClient) WithModel(model string) Client {
+	selector, ok := c.client.(ModelSelector)
+	if !ok {
+		return c
+	}
+	return &RetryClient{client: selector.WithModel(model), policy: c.policy}
+}
+
+// GetName returns the wrapped client's name
+func (c *RetryClient) GetName() string {
+	return c.client.GetName()
+}
+
+// IsAvailable reports whether the wrapped client is available
+func (c *RetryClient) IsAvailable() bool {
+	return c.client.IsAvailable()
+}
+
+// Retries returns the transient failures a RetryClient retried past
+func Retries(response *Response) []string {
+	if response == nil {
+		return nil
+	}
+	retries, _ := response.Metadata["retries"].([]string)
+	return retries
+}
@@ -0,0 +1,29 @@
+package llm
+
+import "context"
+
+type sessionKey struct{}
+
+// WithSession returns a context that makes clients which keep conversations,
+// such as Claude, continue the given session instead of starting a new one
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionID)
+}
+
+// sessionFrom returns the session the context asks to continue, or ""
+func sessionFrom(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionKey{}).(string)
+	return sessionID
+}
+
+// ResumableSession returns the session a response came from if a later
+// prompt can continue it with WithSession, or ""
+func ResumableSession(response *Response) string {
+	if response == nil {
+		return ""
+	}
+	if resumable, _ := response.Metadata["resumable"].(bool); !resumable {
+		return ""
+	}
+	return response.SessionID
+}
@@ -0,0 +1,24 @@
+package llm
+
+import "context"
+
+// StreamFunc receives response content as a client reads it, before Execute
+// returns
+type StreamFunc func(chunk string)
+
+type streamKey struct{}
+
+// WithStream returns a context that makes clients which read their output
+// incrementally, such as Claude with stream-json output, pass each content
+// chunk to fn
+func WithStream(ctx context.Context, fn StreamFunc) context.Context {
+	return context.WithValue(ctx, streamKey{}, fn)
+}
+
+// streamFrom returns the context's StreamFunc, or one that discards chunks
+func streamFrom(ctx context.Context) StreamFunc {
+	if fn, ok := ctx.Value(streamKey{}).(StreamFunc); ok && fn != nil {
+		return fn
+	}
+	return func(string) {}
+}
@@ -0,0 +1,17 @@
+package llm
+
+import "context"
+
+type workDirKey struct{}
+
+// WithWorkDir returns a context that makes clients which run a local agent,
+// such as Claude or the Gemini CLI, start it in dir instead of the current directory
+func WithWorkDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workDirKey{}, dir)
+}
+
+// workDirFrom returns the directory the context asks agents to run in, or ""
+func workDirFrom(ctx context.Context) string {
+	dir, _ := ctx.Value(workDirKey{}).(string)
+	return dir
+}
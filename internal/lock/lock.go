@@ -0,0 +1,170 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Owner describes the process holding the workspace lock
+type Owner struct {
+	PID       int       `json:"pid"`
+	Command   string    `json:"command"`
+	Hostname  string    `json:"hostname"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Lock is a held single-writer lock on a workspace
+type Lock struct {
+	path  string
+	owner Owner
+}
+
+// LockedError is returned when another live process holds the workspace lock
+type LockedError struct {
+	Owner Owner
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("workspace is locked by pid %d (baton %s) on %s since %s; use --force to override",
+		e.Owner.PID, e.Owner.Command, e.Owner.Hostname, e.Owner.StartedAt.Local().Format(time.RFC3339))
+}
+
+// Path returns the lock file location for a workspace
+func Path(workspace string) string {
+	return filepath.Join(workspace, ".baton", "baton.lock")
+}
+
+// Acquire takes the workspace lock for the given command. Stale locks left
+// behind by dead processes on this host are reclaimed automatically; a lock
+// held by a live process is only taken over when force is set.
+func Acquire(workspace, command string, force bool) (*Lock, error) {
+	path := Path(workspace)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	owner := Owner{
+		PID:       os.Getpid(),
+		Command:   command,
+		Hostname:  hostname,
+		StartedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock owner: %w", err)
+	}
+
+	// The owner is written to a file of our own first and linked into place,
+	// so the lock file never exists without its owner in it; a reader never
+	// sees a half-written lock and takes it for a stale one
+	pending, err := writePending(path, data)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(pending)
+
+	// Two attempts: the second one runs after clearing a stale or forced lock
+	for attempt := 0; attempt < 2; attempt++ {
+		err := os.Link(pending, path)
+		if err == nil {
+			return &Lock{path: path, owner: owner}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		current, stale, err := Inspect(workspace)
+		if err != nil {
+			return nil, err
+		}
+
+		if current != nil && !stale && !force {
+			return nil, &LockedError{Owner: *current}
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove existing lock: %w", err)
+		}
+	}
+
+	return nil, fmt.Errorf("failed to acquire workspace lock at %s", path)
+}
+
+// writePending writes the lock contents to a uniquely named file beside the
+// lock file and returns its path
+func writePending(path string, data []byte) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create lock file: %w", err)
+	}
+	_, writeErr := file.Write(data)
+	if writeErr == nil {
+		writeErr = file.Chmod(0644)
+	}
+	closeErr := file.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write lock file: %w", errors.Join(writeErr, closeErr))
+	}
+	return file.Name(), nil
+}
+
+// Inspect returns the current lock owner, if any, and whether the lock is
+// stale (its owner process on this host is no longer running). A lock file
+// that cannot be parsed is reported as stale: Acquire only ever puts complete
+// ones in place.
+func Inspect(workspace string) (*Owner, bool, error) {
+	data, err := os.ReadFile(Path(workspace))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	var owner Owner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return nil, true, nil
+	}
+
+	hostname, _ := os.Hostname()
+	stale := owner.Hostname == hostname && !processAlive(owner.PID)
+
+	return &owner, stale, nil
+}
+
+// ProcessAlive reports whether a process with the given PID exists on this host
+func ProcessAlive(pid int) bool {
+	return processAlive(pid)
+}
+
+// Owner returns the ownership information recorded for this lock
+func (l *Lock) Owner() Owner {
+	return l.owner
+}
+
+// Release removes the lock file if it is still owned by this process
+func (l *Lock) Release() error {
+	current, _, err := Inspect(filepath.Dir(filepath.Dir(l.path)))
+	if err != nil {
+		return err
+	}
+
+	if current == nil || current.PID != l.owner.PID || !current.StartedAt.Equal(l.owner.StartedAt) {
+		// Someone forced the lock away from us; leave their lock in place
+		return nil
+	}
+
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+
+	return nil
+}
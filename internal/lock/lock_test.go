@@ -0,0 +1,137 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAcquireConcurrently(t *testing.T) {
+	workspace := t.TempDir()
+
+	// Contenders share this process's PID, so none may take the others'
+	// lock for a stale one; each round exactly one of them must win
+	for round := 0; round < 20; round++ {
+		const contenders = 16
+		var (
+			wg      sync.WaitGroup
+			start   = make(chan struct{})
+			mu      sync.Mutex
+			winners []*Lock
+			errs    []error
+		)
+		for i := 0; i < contenders; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				lock, err := Acquire(workspace, "test", false)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+					return
+				}
+				winners = append(winners, lock)
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if len(winners) != 1 {
+			t.Fatalf("Round %d: expected exactly one holder, got %d", round, len(winners))
+		}
+		for _, err := range errs {
+			var locked *LockedError
+			if !errors.As(err, &locked) {
+				t.Fatalf("Round %d: expected LockedError, got %v", round, err)
+			}
+		}
+
+		if err := winners[0].Release(); err != nil {
+			t.Fatalf("Failed to release lock: %v", err)
+		}
+	}
+
+	// Nothing is left behind but the lock directory
+	entries, err := os.ReadDir(filepath.Dir(Path(workspace)))
+	if err != nil {
+		t.Fatalf("Failed to read lock directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected an empty lock directory, found %d entries", len(entries))
+	}
+}
+
+func TestInspectNeverSeesPartialLock(t *testing.T) {
+	workspace := t.TempDir()
+
+	// Readers polling while the lock is taken and released must only ever
+	// see no lock or a complete one held by this live process
+	done := make(chan struct{})
+	var sawStale atomic.Bool
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, isStale, err := Inspect(workspace); err == nil && isStale {
+					sawStale.Store(true)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 2000; i++ {
+		lock, err := Acquire(workspace, "test", false)
+		if err != nil {
+			close(done)
+			wg.Wait()
+			t.Fatalf("Failed to acquire lock: %v", err)
+		}
+		if err := lock.Release(); err != nil {
+			close(done)
+			wg.Wait()
+			t.Fatalf("Failed to release lock: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if sawStale.Load() {
+		t.Error("Inspect saw a lock that was still being written as stale")
+	}
+}
+
+func TestAcquireReclaimsStaleLock(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Dir(Path(workspace)), 0755); err != nil {
+		t.Fatalf("Failed to create lock directory: %v", err)
+	}
+	if err := os.WriteFile(Path(workspace), []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write corrupt lock: %v", err)
+	}
+
+	lock, err := Acquire(workspace, "test", false)
+	if err != nil {
+		t.Fatalf("Failed to reclaim corrupt lock: %v", err)
+	}
+	defer lock.Release()
+
+	owner, stale, err := Inspect(workspace)
+	if err != nil {
+		t.Fatalf("Failed to inspect lock: %v", err)
+	}
+	if owner == nil || stale || owner.PID != os.Getpid() {
+		t.Errorf("Expected a live lock held by this process, got %+v (stale %v)", owner, stale)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package lock
+
+import "os"
+
+// processAlive reports whether a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	// On Windows FindProcess opens a handle and fails for unknown PIDs
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireToken lets through only requests that carry the bearer token in an
+// "Authorization: Bearer" header. The token is never taken from the query,
+// where it would end up in process listings and logs. An empty token lets
+// every request through; the server only passes one when
+// mcp.insecure_no_token is set.
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tokenMatches(requestToken(r), token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="baton"`)
+			http.Error(w, "Missing or invalid MCP token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestToken returns the bearer token a request carries, "" when it
+// carries none
+func requestToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
+
+// tokenMatches compares in constant time so timing does not leak the token
+func tokenMatches(got, want string) bool {
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"baton/internal/config"
+)
+
+func TestRequireToken(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		query  string
+		want   int
+	}{
+		{name: "missing header", token: "secret", want: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", header: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "not a bearer token", token: "secret", header: "secret", want: http.StatusUnauthorized},
+		{name: "token in query", token: "secret", query: "?token=secret", want: http.StatusUnauthorized},
+		{name: "right token", token: "secret", header: "Bearer secret", want: http.StatusOK},
+		{name: "empty bearer against empty token", token: "", header: "Bearer ", want: http.StatusOK},
+		{name: "no token configured", token: "", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			requireToken(tt.token, next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestEnsureMCPToken(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &config.Config{Workspace: workspace}
+	cfg.MCP.TokenEnv = "BATON_TEST_MCP_TOKEN"
+	os.Unsetenv(cfg.MCP.TokenEnv)
+
+	token, err := cfg.EnsureMCPToken()
+	if err != nil {
+		t.Fatalf("Failed to ensure token: %v", err)
+	}
+	if token == "" {
+		t.Fatal("Expected a token to be generated on first start")
+	}
+
+	info, err := os.Stat(filepath.Join(workspace, ".baton", "mcp-token"))
+	if err != nil {
+		t.Fatalf("Failed to stat token file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected token file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	again, err := cfg.EnsureMCPToken()
+	if err != nil {
+		t.Fatalf("Failed to ensure token again: %v", err)
+	}
+	if again != token {
+		t.Error("Expected the generated token to be reused on the next start")
+	}
+
+	// Only the explicit opt-out runs without a token
+	cfg.MCP.InsecureNoToken = true
+	token, err = cfg.EnsureMCPToken()
+	if err != nil {
+		t.Fatalf("Failed to ensure token with opt-out: %v", err)
+	}
+	if token != "" {
+		t.Errorf("Expected no token with mcp.insecure_no_token, got %q", token)
+	}
+}
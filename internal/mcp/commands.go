@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+
+	"baton/internal/sandbox"
+)
+
+// cycleCommands is what a cycle's agent may run through baton.commands.run,
+// and what it ran
+type cycleCommands struct {
+	broker  *sandbox.Broker
+	allowed bool // whether the cycle's agent has can_execute_commands
+	results []*sandbox.Result
+}
+
+// ConfineCommands lets the agent of a cycle run commands through
+// baton.commands.run in workDir, if allowed, as security permits
+func (s *Server) ConfineCommands(cycleID, workDir string, allowed bool) {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	if s.commands == nil {
+		s.commands = make(map[string]*cycleCommands)
+	}
+	s.commands[cycleID] = &cycleCommands{broker: sandbox.NewBroker(s.config.Security, workDir), allowed: allowed}
+}
+
+// CycleCommands returns the commands a cycle's agent ran or was refused, in
+// the order it asked for them
+func (s *Server) CycleCommands(cycleID string) []*sandbox.Result {
+	s.scopeMu.RLock()
+	defer s.scopeMu.RUnlock()
+	commands, ok := s.commands[cycleID]
+	if !ok {
+		return nil
+	}
+	return append([]*sandbox.Result(nil), commands.results...)
+}
+
+// SkipCommands makes baton.commands.run check and record commands without
+// running them, as replays of recorded cycles must
+func (s *Server) SkipCommands() {
+	s.skipCommands = true
+}
+
+// handleRunCommand handles baton.commands.run
+func (s *Server) handleRunCommand(req *JSONRPCRequest) *JSONRPCResponse {
+	var command sandbox.Request
+	if err := req.Bind(&command); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	if command.Command == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing command parameter", nil)
+	}
+	// A whole command line in command, without args, is split on spaces
+	if len(command.Args) == 0 && strings.ContainsAny(command.Command, " \t") {
+		fields := strings.Fields(command.Command)
+		command.Command, command.Args = fields[0], fields[1:]
+	}
+
+	scope, ok := s.scopeFor(req.cycleID)
+	if !ok {
+		return NewJSONRPCError(req.ID, InvalidRequest, "Commands can only be run during a cycle", nil)
+	}
+	s.scopeMu.RLock()
+	commands := s.commands[scope.CycleID]
+	s.scopeMu.RUnlock()
+
+	var result *sandbox.Result
+	switch {
+	case commands == nil:
+		result = sandbox.Refuse(&command, "the cycle runs no commands")
+	case !commands.allowed:
+		result = sandbox.Refuse(&command, "the agent may not execute commands (permissions.can_execute_commands)")
+	case s.skipCommands:
+		if _, err := commands.broker.Check(&command); err != nil {
+			result = sandbox.Refuse(&command, err.Error())
+		} else {
+			result = sandbox.Refuse(&command, "not run: commands are skipped on replay")
+			result.Allowed = true
+		}
+	default:
+		result = commands.broker.Run(context.Background(), &command,
+			"BATON_CYCLE_ID="+scope.CycleID,
+			"BATON_TASK_ID="+scope.TaskID,
+		)
+	}
+
+	if commands != nil {
+		s.scopeMu.Lock()
+		commands.results = append(commands.results, result)
+		s.scopeMu.Unlock()
+	}
+	return NewJSONRPCResponse(req.ID, result)
+}
@@ -0,0 +1,252 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// GraphNode is a task in the dependency graph baton.tasks.graph returns
+type GraphNode struct {
+	ID           string        `json:"id"`
+	Title        string        `json:"title"`
+	State        storage.State `json:"state,omitempty"`
+	Dependencies []string      `json:"dependencies"` // tasks it depends on
+	Dependents   []string      `json:"dependents"`   // tasks that depend on it
+}
+
+// GraphEdge is a dependency of one task on another
+type GraphEdge struct {
+	TaskID    string `json:"task_id"`
+	DependsOn string `json:"depends_on"`
+}
+
+// handleAddDependency handles baton.tasks.add_dependency, making a task
+// depend on another. Edges that would make tasks wait on each other forever
+// are refused, naming the cycle.
+func (s *Server) handleAddDependency(req *JSONRPCRequest) *JSONRPCResponse {
+	return s.editDependency(req, true)
+}
+
+// handleRemoveDependency handles baton.tasks.remove_dependency
+func (s *Server) handleRemoveDependency(req *JSONRPCRequest) *JSONRPCResponse {
+	return s.editDependency(req, false)
+}
+
+// editDependency adds or removes the dependency of task_id on depends_on,
+// keeping blocked_by in step, and records the edit in the task's audit log
+func (s *Server) editDependency(req *JSONRPCRequest, add bool) *JSONRPCResponse {
+	var params DependencyParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID, dependsOn := params.TaskID, params.DependsOn
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
+	}
+	if dependsOn == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing depends_on parameter", nil)
+	}
+
+	editor, denied := s.taskEditorFor(req)
+	if denied != nil {
+		return denied
+	}
+
+	s.tasksMu.Lock()
+	defer s.tasksMu.Unlock()
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return NewJSONRPCError(req.ID, ResourceNotFound, "Task not found", map[string]interface{}{"task_id": taskID})
+	}
+
+	dependencies := task.DependencyList()
+	var blockedBy []string
+	if len(task.BlockedBy) > 0 {
+		json.Unmarshal(task.BlockedBy, &blockedBy)
+	}
+
+	var note string
+	if add {
+		if _, err := s.store.GetTask(dependsOn); err != nil {
+			return NewJSONRPCError(req.ID, ResourceNotFound, "Dependency task not found", map[string]interface{}{"depends_on": dependsOn})
+		}
+		if containsString(dependencies, dependsOn) {
+			return NewJSONRPCError(req.ID, InvalidParams, fmt.Sprintf("Task %s already depends on %s", taskID, dependsOn), nil)
+		}
+		graph, err := statemachine.LoadDependencyGraph(s.store)
+		if err != nil {
+			return NewJSONRPCError(req.ID, InternalError, "Failed to load dependency graph", err.Error())
+		}
+		if err := graph.CheckEdge(taskID, dependsOn); err != nil {
+			var cycle *statemachine.CycleError
+			if errors.As(err, &cycle) {
+				return NewJSONRPCError(req.ID, InvalidParams, err.Error(), map[string]interface{}{"cycle": cycle.Cycle})
+			}
+			return NewJSONRPCError(req.ID, InvalidParams, err.Error(), nil)
+		}
+		dependencies = append(dependencies, dependsOn)
+		if !containsString(blockedBy, dependsOn) {
+			blockedBy = append(blockedBy, dependsOn)
+		}
+		note = "Added dependency on " + dependsOn
+	} else {
+		if !containsString(dependencies, dependsOn) {
+			return NewJSONRPCError(req.ID, InvalidParams, fmt.Sprintf("Task %s does not depend on %s", taskID, dependsOn), nil)
+		}
+		dependencies = withoutString(dependencies, dependsOn)
+		blockedBy = withoutString(blockedBy, dependsOn)
+		note = "Removed dependency on " + dependsOn
+	}
+
+	task.Dependencies, _ = json.Marshal(dependencies)
+	task.BlockedBy, _ = json.Marshal(blockedBy)
+	if err := s.store.UpdateTaskBy(task, editor.actor); err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to update task", err.Error())
+	}
+	editor.audit(s.store, task, task.State, ResultTaskUpdated, note)
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"success":      true,
+		"task_id":      taskID,
+		"depends_on":   dependsOn,
+		"dependencies": dependencies,
+	})
+}
+
+// handleGraph handles baton.tasks.graph, which returns the dependency graph:
+// every task on a dependency edge, or with task_id only the tasks it depends
+// on and that depend on it, directly or not. Nodes come in dependency order,
+// every task after the tasks it depends on.
+func (s *Server) handleGraph(req *JSONRPCRequest) *JSONRPCResponse {
+	var params TaskParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID := params.TaskID
+
+	tasks, err := s.store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to list tasks", err.Error())
+	}
+	byID := make(map[string]*storage.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	graph := statemachine.NewDependencyGraph(tasks)
+
+	var included map[string]bool
+	if taskID != "" {
+		if _, ok := byID[taskID]; !ok {
+			return NewJSONRPCError(req.ID, ResourceNotFound, "Task not found", map[string]interface{}{"task_id": taskID})
+		}
+		included = map[string]bool{taskID: true}
+		reach(taskID, graph.Dependencies, included)
+		reach(taskID, graph.Dependents, included)
+	} else {
+		included = make(map[string]bool)
+		for _, task := range tasks {
+			if len(graph.Dependencies(task.ID)) > 0 || len(graph.Dependents(task.ID)) > 0 {
+				included[task.ID] = true
+			}
+		}
+	}
+
+	nodes := make([]*GraphNode, 0, len(included))
+	edges := []GraphEdge{}
+	for _, id := range dependencyOrder(included, graph) {
+		node := &GraphNode{ID: id, Dependencies: nonNil(graph.Dependencies(id)), Dependents: nonNil(graph.Dependents(id))}
+		// A dependency on a task in the trash has no title or state
+		if task, ok := byID[id]; ok {
+			node.Title, node.State = task.Title, task.State
+		}
+		nodes = append(nodes, node)
+		for _, dep := range node.Dependencies {
+			if included[dep] {
+				edges = append(edges, GraphEdge{TaskID: id, DependsOn: dep})
+			}
+		}
+	}
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"nodes": nodes,
+		"edges": edges,
+		"count": len(nodes),
+	})
+}
+
+// reach adds every task reachable from id through next to seen
+func reach(id string, next func(string) []string, seen map[string]bool) {
+	for _, other := range next(id) {
+		if !seen[other] {
+			seen[other] = true
+			reach(other, next, seen)
+		}
+	}
+}
+
+// dependencyOrder sorts the given tasks so each comes after the tasks it
+// depends on, breaking ties by ID
+func dependencyOrder(ids map[string]bool, graph *statemachine.DependencyGraph) []string {
+	var order []string
+	done := make(map[string]bool, len(ids))
+	var visit func(id string)
+	visit = func(id string) {
+		if done[id] {
+			return
+		}
+		done[id] = true
+		deps := append([]string(nil), graph.Dependencies(id)...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if ids[dep] {
+				visit(dep)
+			}
+		}
+		order = append(order, id)
+	}
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	for _, id := range sorted {
+		visit(id)
+	}
+	return order
+}
+
+// containsString reports whether list includes value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutString returns list without value, never nil
+func withoutString(list []string, value string) []string {
+	result := []string{}
+	for _, item := range list {
+		if item != value {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// nonNil returns list, or an empty list for nil, so it encodes as []
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
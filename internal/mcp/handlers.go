@@ -1,19 +1,31 @@
 package mcp
 
 import (
-	"encoding/json"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"os"
+	"strings"
+	"time"
 
+	"baton/internal/artifactfs"
+	"baton/internal/config"
+	"baton/internal/report"
+	"baton/internal/search"
 	"baton/internal/statemachine"
 	"baton/internal/storage"
 )
 
 // TaskHandler handles task-related MCP operations
 type TaskHandler struct {
-	store     *storage.Store
-	selector  *statemachine.TaskSelector
-	validator *statemachine.TransitionValidator
+	store        *storage.Store
+	selector     *statemachine.TaskSelector
+	validator    *statemachine.TransitionValidator
+	customFields config.CustomFields
+	staleness    config.StalenessConfig
 }
 
 // NewTaskHandler creates a new task handler
@@ -25,6 +37,16 @@ func NewTaskHandler(store *storage.Store, selector *statemachine.TaskSelector, v
 	}
 }
 
+// SetCustomFields sets the custom field definitions task values are checked against
+func (h *TaskHandler) SetCustomFields(fields config.CustomFields) {
+	h.customFields = fields
+}
+
+// SetStaleness sets the thresholds baton.tasks.stale flags tasks by
+func (h *TaskHandler) SetStaleness(staleness config.StalenessConfig) {
+	h.staleness = staleness
+}
+
 // GetNext handles baton.tasks.get_next
 func (h *TaskHandler) GetNext(req *JSONRPCRequest) *JSONRPCResponse {
 	result, err := h.selector.SelectNext()
@@ -40,18 +62,20 @@ func (h *TaskHandler) GetNext(req *JSONRPCRequest) *JSONRPCResponse {
 
 	response := map[string]interface{}{
 		"task": map[string]interface{}{
-			"id":           result.Task.ID,
-			"title":        result.Task.Title,
-			"description":  result.Task.Description,
-			"state":        result.Task.State,
-			"priority":     result.Task.Priority,
-			"owner":        result.Task.Owner,
-			"tags":         result.Task.Tags,
-			"dependencies": result.Task.Dependencies,
-			"blocked_by":   result.Task.BlockedBy,
-			"created_at":   result.Task.CreatedAt,
-			"updated_at":   result.Task.UpdatedAt,
-			"artifacts":    artifacts,
+			"id":            result.Task.ID,
+			"title":         result.Task.Title,
+			"description":   result.Task.Description,
+			"state":         result.Task.State,
+			"priority":      result.Task.Priority,
+			"owner":         result.Task.Owner,
+			"tags":          result.Task.Tags,
+			"dependencies":  result.Task.Dependencies,
+			"blocked_by":    result.Task.BlockedBy,
+			"due_date":      result.Task.DueDate,
+			"custom_fields": result.Task.CustomFieldMap(),
+			"created_at":    result.Task.CreatedAt,
+			"updated_at":    result.Task.UpdatedAt,
+			"artifacts":     artifacts,
 		},
 		"selection_reason": result.Reason,
 	}
@@ -61,8 +85,12 @@ func (h *TaskHandler) GetNext(req *JSONRPCRequest) *JSONRPCResponse {
 
 // Get handles baton.tasks.get
 func (h *TaskHandler) Get(req *JSONRPCRequest) *JSONRPCResponse {
-	taskID, err := req.GetStringParam("task_id")
-	if err != nil {
+	var params TaskParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID := params.TaskID
+	if taskID == "" {
 		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
 	}
 
@@ -76,20 +104,27 @@ func (h *TaskHandler) Get(req *JSONRPCRequest) *JSONRPCResponse {
 	if err != nil {
 		return NewJSONRPCError(req.ID, InternalError, "Failed to get task artifacts", err.Error())
 	}
+	notes, err := h.store.ListTaskNotes(task.ID)
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to get task notes", err.Error())
+	}
 
 	response := map[string]interface{}{
-		"id":           task.ID,
-		"title":        task.Title,
-		"description":  task.Description,
-		"state":        task.State,
-		"priority":     task.Priority,
-		"owner":        task.Owner,
-		"tags":         task.Tags,
-		"dependencies": task.Dependencies,
-		"blocked_by":   task.BlockedBy,
-		"created_at":   task.CreatedAt,
-		"updated_at":   task.UpdatedAt,
-		"artifacts":    artifacts,
+		"id":            task.ID,
+		"title":         task.Title,
+		"description":   task.Description,
+		"state":         task.State,
+		"priority":      task.Priority,
+		"owner":         task.Owner,
+		"tags":          task.Tags,
+		"dependencies":  task.Dependencies,
+		"blocked_by":    task.BlockedBy,
+		"due_date":      task.DueDate,
+		"custom_fields": task.CustomFieldMap(),
+		"created_at":    task.CreatedAt,
+		"updated_at":    task.UpdatedAt,
+		"artifacts":     artifacts,
+		"notes":         notes,
 	}
 
 	return NewJSONRPCResponse(req.ID, response)
@@ -97,23 +132,20 @@ func (h *TaskHandler) Get(req *JSONRPCRequest) *JSONRPCResponse {
 
 // UpdateState handles baton.tasks.update_state
 func (h *TaskHandler) UpdateState(req *JSONRPCRequest) *JSONRPCResponse {
-	taskID, err := req.GetStringParam("task_id")
-	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
+	var params StateParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
 	}
-
-	stateStr, err := req.GetStringParam("state")
-	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Missing state parameter", nil)
+	taskID := params.TaskID
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
 	}
 
-	note, _ := req.GetOptionalStringParam("note")
-
 	// Normalize and validate state
-	newState := storage.NormalizeState(stateStr)
+	newState := storage.NormalizeState(params.State)
 
 	// Perform the transition
-	if err := h.validator.ValidateAndTransition(taskID, newState, note); err != nil {
+	if err := h.validator.ValidateAndTransition(taskID, newState, params.Note); err != nil {
 		return NewJSONRPCError(req.ID, InvalidParams, "State transition failed", err.Error())
 	}
 
@@ -124,27 +156,116 @@ func (h *TaskHandler) UpdateState(req *JSONRPCRequest) *JSONRPCResponse {
 	})
 }
 
-// AppendNote handles baton.tasks.append_note
-func (h *TaskHandler) AppendNote(req *JSONRPCRequest) *JSONRPCResponse {
-	taskID, err := req.GetStringParam("task_id")
-	if err != nil {
+// CheckTransition handles baton.tasks.check_transition. It reports everything
+// update_state would check, and the hooks it would run, without moving the task.
+func (h *TaskHandler) CheckTransition(req *JSONRPCRequest) *JSONRPCResponse {
+	var params TransitionParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	if params.TaskID == "" {
 		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
 	}
 
-	note, err := req.GetStringParam("note")
+	requirement, err := h.validator.GetTransitionRequirements(params.TaskID, storage.NormalizeState(params.State))
 	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Missing note parameter", nil)
+		return NewJSONRPCError(req.ID, ResourceNotFound, "Failed to check transition", err.Error())
+	}
+
+	return NewJSONRPCResponse(req.ID, requirement)
+}
+
+// DefaultNoteAuthor is who wrote a note appended without an author
+const DefaultNoteAuthor = "agent"
+
+// AppendNote handles baton.tasks.append_note, recording a note on the task
+// without changing its state
+func (h *TaskHandler) AppendNote(req *JSONRPCRequest) *JSONRPCResponse {
+	var params NoteParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID, note, author := params.TaskID, params.Note, params.Author
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
+	}
+
+	if author == "" {
+		author = DefaultNoteAuthor
+	}
+
+	if _, err := h.store.GetTask(taskID); err != nil {
+		return NewJSONRPCError(req.ID, ResourceNotFound, "Task not found", map[string]interface{}{"task_id": taskID})
+	}
+
+	taskNote := &storage.TaskNote{TaskID: taskID, Author: author, Body: note}
+	if err := h.store.AddTaskNote(taskNote); err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to append note", err.Error())
+	}
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"success": true,
+		"task_id": taskID,
+		"note_id": taskNote.ID,
+	})
+}
+
+// SetFields handles baton.tasks.set_fields. Fields not named keep their
+// value; a null value clears the field.
+func (h *TaskHandler) SetFields(req *JSONRPCRequest) *JSONRPCResponse {
+	var params FieldsParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID, fields := params.TaskID, params.Fields
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
+	}
+	if fields == nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing fields parameter", nil)
 	}
 
-	// Get current task to maintain state
 	task, err := h.store.GetTask(taskID)
 	if err != nil {
 		return NewJSONRPCError(req.ID, ResourceNotFound, "Task not found", map[string]interface{}{"task_id": taskID})
 	}
 
-	// Update with note (keeps same state)
-	if err := h.store.UpdateTaskState(taskID, task.State, note); err != nil {
-		return NewJSONRPCError(req.ID, InternalError, "Failed to append note", err.Error())
+	for name, value := range fields {
+		checked, err := h.customFields.Value(name, value)
+		if err != nil {
+			return NewJSONRPCError(req.ID, InvalidParams, "Invalid custom field", err.Error())
+		}
+		task.SetCustomField(name, checked)
+	}
+
+	if err := h.store.UpdateTask(task); err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to update task", err.Error())
+	}
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"success":       true,
+		"task_id":       taskID,
+		"custom_fields": task.CustomFieldMap(),
+	})
+}
+
+// Delete handles baton.tasks.delete, which moves a task to the trash. It is
+// not task-scoped: the task to delete is always named.
+func (h *TaskHandler) Delete(req *JSONRPCRequest) *JSONRPCResponse {
+	var params TaskParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID := params.TaskID
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
+	}
+
+	if err := h.store.DeleteTask(taskID); err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			return NewJSONRPCError(req.ID, ResourceNotFound, "Task not found", map[string]interface{}{"task_id": taskID})
+		}
+		return NewJSONRPCError(req.ID, InternalError, "Failed to delete task", err.Error())
 	}
 
 	return NewJSONRPCResponse(req.ID, map[string]interface{}{
@@ -153,73 +274,203 @@ func (h *TaskHandler) AppendNote(req *JSONRPCRequest) *JSONRPCResponse {
 	})
 }
 
-// List handles baton.tasks.list
-func (h *TaskHandler) List(req *JSONRPCRequest) *JSONRPCResponse {
-	params, err := req.GetParams()
+// Stale handles baton.tasks.stale, which lists the tasks stuck in a work state
+// longer than the configured staleness threshold, longest stuck first
+func (h *TaskHandler) Stale(req *JSONRPCRequest) *JSONRPCResponse {
+	stale, err := statemachine.FindStaleTasks(h.store, h.staleness, time.Now())
 	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", nil)
+		return NewJSONRPCError(req.ID, InternalError, "Failed to find stale tasks", err.Error())
 	}
 
-	filters := storage.TaskFilters{}
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"tasks": stale,
+		"count": len(stale),
+	})
+}
 
-	// Parse filters
-	if stateStr, ok := params["state"].(string); ok {
-		state := storage.NormalizeState(stateStr)
+// List handles baton.tasks.list
+func (h *TaskHandler) List(req *JSONRPCRequest) *JSONRPCResponse {
+	var params TaskListParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+
+	filters := storage.TaskFilters{
+		Priority:     params.Priority,
+		Owner:        params.Owner,
+		Tags:         params.Tags,
+		ParentID:     params.ParentID,
+		ArchivedOnly: params.Archived,
+		Sort:         params.Sort,
+		Reverse:      params.Reverse,
+		Cursor:       params.Cursor,
+		Limit:        params.Limit,
+		Offset:       params.Offset,
+	}
+
+	if params.State != nil {
+		state := storage.NormalizeState(*params.State)
 		filters.State = &state
 	}
 
-	if priority, ok := params["priority"].(float64); ok {
-		p := int(priority)
-		filters.Priority = &p
+	if params.CustomFields != nil {
+		filters.CustomFields = make(map[string]interface{}, len(params.CustomFields))
+		for name, value := range params.CustomFields {
+			checked, err := h.customFields.Value(name, value)
+			if err != nil {
+				return NewJSONRPCError(req.ID, InvalidParams, "Invalid custom field filter", err.Error())
+			}
+			filters.CustomFields[name] = checked
+		}
 	}
 
-	if owner, ok := params["owner"].(string); ok {
-		filters.Owner = &owner
+	if params.DueBefore != nil {
+		due, err := storage.ParseDueDate(*params.DueBefore)
+		if err != nil {
+			return NewJSONRPCError(req.ID, InvalidParams, "Invalid due_before", err.Error())
+		}
+		filters.DueBefore = &due
 	}
 
-	tasks, err := h.store.ListTasks(filters)
+	if err := storage.CheckTaskSort(filters.Sort); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid sort", err.Error())
+	}
+
+	page, err := h.store.ListTaskPage(filters)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid cursor", err.Error())
+	}
 	if err != nil {
 		return NewJSONRPCError(req.ID, InternalError, "Failed to list tasks", err.Error())
 	}
 
+	result := map[string]interface{}{
+		"tasks": page.Tasks,
+		"count": len(page.Tasks),
+		"total": page.Total,
+	}
+	if page.NextCursor != "" {
+		result["next_cursor"] = page.NextCursor
+	}
+	return NewJSONRPCResponse(req.ID, result)
+}
+
+// SearchHandler handles baton.search and its older name baton.tasks.search
+type SearchHandler struct {
+	searcher    *search.Searcher
+	embedderErr error // why semantic search is unavailable, if it is
+}
+
+// NewSearchHandler creates a new search handler; semantic search is disabled
+// with the reason reported to callers when the embedder cannot be created
+func NewSearchHandler(store *storage.Store, cfg config.SearchConfig) *SearchHandler {
+	embedder, err := search.NewEmbedder(cfg)
+	return &SearchHandler{
+		searcher:    search.NewSearcher(store, embedder),
+		embedderErr: err,
+	}
+}
+
+// Search handles baton.search
+func (h *SearchHandler) Search(req *JSONRPCRequest) *JSONRPCResponse {
+	var params SearchParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+
+	query := params.Query
+	if strings.TrimSpace(query) == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing query parameter", nil)
+	}
+
+	// The schema restricts mode to keyword and semantic
+	mode := search.ModeKeyword
+	if params.Mode != "" {
+		mode = params.Mode
+	}
+	if mode == search.ModeSemantic && h.embedderErr != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Semantic search is not available", h.embedderErr.Error())
+	}
+
+	filters := storage.SearchFilters{Owner: params.Filters.Owner, Limit: params.Limit}
+	if params.Filters.State != "" {
+		state := storage.NormalizeState(params.Filters.State)
+		filters.State = &state
+	}
+	if kind := params.Filters.Kind; kind != "" {
+		if !search.ValidKind(kind) {
+			return NewJSONRPCError(req.ID, InvalidParams, "Invalid kind filter", map[string]interface{}{
+				"kind":    kind,
+				"allowed": storage.SearchKinds,
+			})
+		}
+		filters.Kind = kind
+	}
+
+	hits, err := h.searcher.Search(query, mode, filters)
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Search failed", err.Error())
+	}
+	if hits == nil {
+		hits = []*storage.SearchHit{}
+	}
+
 	return NewJSONRPCResponse(req.ID, map[string]interface{}{
-		"tasks": tasks,
-		"count": len(tasks),
+		"results": hits,
+		"count":   len(hits),
+		"mode":    mode,
 	})
 }
 
 // ArtifactHandler handles artifact-related MCP operations
 type ArtifactHandler struct {
-	store *storage.Store
+	store   *storage.Store
+	schemas map[string]config.ArtifactSchema
+	mirror  *artifactfs.Mirror
 }
 
-// NewArtifactHandler creates a new artifact handler
-func NewArtifactHandler(store *storage.Store) *ArtifactHandler {
-	return &ArtifactHandler{store: store}
+// NewArtifactHandler creates a new artifact handler that validates artifacts against their schemas
+func NewArtifactHandler(store *storage.Store, schemas map[string]config.ArtifactSchema) *ArtifactHandler {
+	return &ArtifactHandler{store: store, schemas: schemas}
+}
+
+// SetMirror makes every upsert also write the artifact to its workspace file
+func (h *ArtifactHandler) SetMirror(mirror *artifactfs.Mirror) {
+	h.mirror = mirror
 }
 
 // Upsert handles baton.artifacts.upsert
 func (h *ArtifactHandler) Upsert(req *JSONRPCRequest) *JSONRPCResponse {
-	taskID, err := req.GetStringParam("task_id")
-	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
+	var params UpsertParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
 	}
-
-	name, err := req.GetStringParam("name")
-	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Missing name parameter", nil)
+	taskID, name, content := params.TaskID, params.Name, params.Content
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
 	}
 
-	content, err := req.GetStringParam("content")
-	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Missing content parameter", nil)
+	// Reject content that does not match the artifact's schema, listing every problem
+	if err := statemachine.ValidateArtifact(h.schemas, name, content); err != nil {
+		var schemaErr *statemachine.ArtifactSchemaError
+		if errors.As(err, &schemaErr) {
+			return NewJSONRPCError(req.ID, InvalidParams, err.Error(), map[string]interface{}{
+				"artifact": schemaErr.Artifact,
+				"problems": schemaErr.Problems,
+				"schema":   h.schemas[name],
+			})
+		}
+		return NewJSONRPCError(req.ID, InvalidParams, err.Error(), nil)
 	}
 
-	params, _ := req.GetParams()
-	var meta json.RawMessage
-	if metaData, ok := params["meta"]; ok {
-		if metaBytes, err := json.Marshal(metaData); err == nil {
-			meta = metaBytes
+	// Acceptance test results must name requirements that exist
+	if name == report.AcceptanceTestsArtifact {
+		if problems := h.checkAcceptanceTests(content); len(problems) > 0 {
+			return NewJSONRPCError(req.ID, InvalidParams, (&report.AcceptanceTestsError{Problems: problems}).Error(), map[string]interface{}{
+				"artifact": name,
+				"problems": problems,
+				"hint":     "list results under \"## Results\" as \"- <KEY>: pass|fail|pending\"",
+			})
 		}
 	}
 
@@ -227,61 +478,145 @@ func (h *ArtifactHandler) Upsert(req *JSONRPCRequest) *JSONRPCResponse {
 		TaskID:  taskID,
 		Name:    name,
 		Content: content,
-		Meta:    meta,
+		Meta:    params.Meta,
 	}
 
 	if err := h.store.UpsertArtifact(artifact); err != nil {
 		return NewJSONRPCError(req.ID, InternalError, "Failed to upsert artifact", err.Error())
 	}
 
+	result := map[string]interface{}{
+		"id":      artifact.ID,
+		"task_id": artifact.TaskID,
+		"name":    artifact.Name,
+		"version": artifact.Version,
+	}
+
+	// The database stays the source of truth, so a failed file write does not fail the upsert
+	if h.mirror != nil {
+		if path, err := h.mirror.Write(artifact); err != nil {
+			log.Printf("Failed to mirror artifact %s of task %s: %v", name, taskID, err)
+		} else {
+			result["path"] = path
+		}
+	}
+
 	return NewJSONRPCResponse(req.ID, map[string]interface{}{
-		"success": true,
-		"artifact": map[string]interface{}{
-			"id":      artifact.ID,
-			"task_id": artifact.TaskID,
-			"name":    artifact.Name,
-			"version": artifact.Version,
-		},
+		"success":  true,
+		"artifact": result,
 	})
 }
 
+// checkAcceptanceTests lists the problems with an acceptance_tests artifact
+func (h *ArtifactHandler) checkAcceptanceTests(content string) []string {
+	results, err := report.ParseAcceptanceTests(content)
+	var problems []string
+	var parseErr *report.AcceptanceTestsError
+	if errors.As(err, &parseErr) {
+		problems = parseErr.Problems
+	}
+	for _, result := range results {
+		if _, err := h.store.GetRequirement(result.Key); err != nil {
+			problems = append(problems, fmt.Sprintf("unknown requirement %s", result.Key))
+		}
+	}
+	return problems
+}
+
 // Get handles baton.artifacts.get
 func (h *ArtifactHandler) Get(req *JSONRPCRequest) *JSONRPCResponse {
-	taskID, err := req.GetStringParam("task_id")
-	if err != nil {
+	var params ArtifactParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID, name, version := params.TaskID, params.Name, params.Version
+	if taskID == "" {
 		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
 	}
 
-	name, err := req.GetStringParam("name")
+	artifact, err := h.store.GetArtifact(taskID, name, version)
 	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Missing name parameter", nil)
+		return NewJSONRPCError(req.ID, ResourceNotFound, "Artifact not found", map[string]interface{}{
+			"task_id": taskID,
+			"name":    name,
+			"version": version,
+		})
 	}
 
-	version := 0 // Default to latest
-	if v, err := req.GetIntParam("version"); err == nil {
-		version = v
+	return NewJSONRPCResponse(req.ID, artifact)
+}
+
+// Artifact content is read in chunks of at most maxReadChunk bytes,
+// readChunk by default
+const (
+	readChunk    = 64 << 10
+	maxReadChunk = 1 << 20
+)
+
+// Read handles baton.artifacts.read, which streams an artifact's content in
+// base64 chunks so large and binary artifacts need not fit in one response
+func (h *ArtifactHandler) Read(req *JSONRPCRequest) *JSONRPCResponse {
+	var params ReadParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID, name, version, offset := params.TaskID, params.Name, params.Version, params.Offset
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
 	}
 
-	artifact, err := h.store.GetArtifact(taskID, name, version)
-	if err != nil {
+	// The schema keeps offset and limit in range
+	limit := readChunk
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	artifact, content, err := h.store.OpenArtifact(taskID, name, version)
+	if errors.Is(err, sql.ErrNoRows) {
 		return NewJSONRPCError(req.ID, ResourceNotFound, "Artifact not found", map[string]interface{}{
 			"task_id": taskID,
 			"name":    name,
 			"version": version,
 		})
 	}
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to read artifact", err.Error())
+	}
+	defer content.Close()
 
-	return NewJSONRPCResponse(req.ID, artifact)
+	if _, err := content.Seek(int64(offset), io.SeekStart); err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to read artifact", err.Error())
+	}
+	chunk := make([]byte, limit)
+	n, err := io.ReadFull(content, chunk)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to read artifact", err.Error())
+	}
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"id":       artifact.ID,
+		"task_id":  artifact.TaskID,
+		"name":     artifact.Name,
+		"version":  artifact.Version,
+		"size":     artifact.Size,
+		"offset":   offset,
+		"data":     base64.StdEncoding.EncodeToString(chunk[:n]),
+		"encoding": "base64",
+		"eof":      int64(offset+n) >= artifact.Size,
+	})
 }
 
 // List handles baton.artifacts.list
 func (h *ArtifactHandler) List(req *JSONRPCRequest) *JSONRPCResponse {
-	taskID, err := req.GetStringParam("task_id")
-	if err != nil {
+	var params TaskParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	if params.TaskID == "" {
 		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
 	}
 
-	artifacts, err := h.store.ListArtifacts(taskID)
+	artifacts, err := h.store.ListArtifacts(params.TaskID)
 	if err != nil {
 		return NewJSONRPCError(req.ID, InternalError, "Failed to list artifacts", err.Error())
 	}
@@ -304,17 +639,12 @@ func NewRequirementHandler(store *storage.Store) *RequirementHandler {
 
 // List handles baton.requirements.list
 func (h *RequirementHandler) List(req *JSONRPCRequest) *JSONRPCResponse {
-	params, err := req.GetParams()
-	if err != nil {
-		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", nil)
+	var params RequirementListParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
 	}
 
-	reqType := ""
-	if t, ok := params["type"].(string); ok {
-		reqType = t
-	}
-
-	requirements, err := h.store.ListRequirements(reqType)
+	requirements, err := h.store.ListRequirements(params.Type)
 	if err != nil {
 		return NewJSONRPCError(req.ID, InternalError, "Failed to list requirements", err.Error())
 	}
@@ -325,6 +655,81 @@ func (h *RequirementHandler) List(req *JSONRPCRequest) *JSONRPCResponse {
 	})
 }
 
+// Create handles baton.requirements.create. The key is allocated, the next
+// free one in the series for the type or the given prefix, so agents never
+// pick a key that is taken, deprecated or retired.
+func (h *RequirementHandler) Create(req *JSONRPCRequest) *JSONRPCResponse {
+	var params RequirementParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	if params.Title == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing title parameter", nil)
+	}
+
+	requirement := &storage.Requirement{Title: params.Title, Text: params.Title, Type: "functional"}
+	if params.Text != "" {
+		requirement.Text = params.Text
+	}
+	if params.Type != "" {
+		requirement.Type = params.Type
+	}
+
+	if err := h.store.AllocateRequirement(requirement, params.Prefix); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Failed to create requirement", err.Error())
+	}
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"success":     true,
+		"requirement": requirement,
+	})
+}
+
+// MilestoneHandler handles milestone-related MCP operations
+type MilestoneHandler struct {
+	selector *statemachine.TaskSelector
+}
+
+// NewMilestoneHandler creates a new milestone handler
+func NewMilestoneHandler(selector *statemachine.TaskSelector) *MilestoneHandler {
+	return &MilestoneHandler{selector: selector}
+}
+
+// List handles baton.milestones.list
+func (h *MilestoneHandler) List(req *JSONRPCRequest) *JSONRPCResponse {
+	milestones, err := h.selector.ListMilestones()
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to list milestones", err.Error())
+	}
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"milestones": milestones,
+		"count":      len(milestones),
+	})
+}
+
+// Progress handles baton.milestones.progress
+func (h *MilestoneHandler) Progress(req *JSONRPCRequest) *JSONRPCResponse {
+	var params MilestoneParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	name := params.Milestone
+
+	progress, err := h.selector.GetMilestoneProgress(name)
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to get milestone progress", err.Error())
+	}
+	if progress == nil {
+		return NewJSONRPCError(req.ID, ResourceNotFound, "Milestone not found", map[string]interface{}{
+			"milestone": name,
+			"hint":      "milestones are named by " + storage.MilestoneTagPrefix + "<name> task tags",
+		})
+	}
+
+	return NewJSONRPCResponse(req.ID, progress)
+}
+
 // PlanHandler handles plan-related MCP operations
 type PlanHandler struct {
 	planFile string
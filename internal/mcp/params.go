@@ -0,0 +1,138 @@
+package mcp
+
+import "encoding/json"
+
+// Params structs of the baton methods, decoded with JSONRPCRequest.Bind. Each
+// mirrors its method's schema in methodTools: a field per property, pointers
+// for optional params whose zero value means something. TestParamsMatchSchemas
+// fails when the two drift apart, so change both when a method gains a param.
+// baton.commands.run's params are a sandbox.Request.
+
+// TaskParams are the params of the methods that take only a task:
+// baton.tasks.get, baton.tasks.delete, baton.tasks.graph and
+// baton.artifacts.list
+type TaskParams struct {
+	TaskID string `json:"task_id"`
+}
+
+// TransitionParams are the params of baton.tasks.check_transition
+type TransitionParams struct {
+	TaskID string `json:"task_id"`
+	State  string `json:"state"`
+}
+
+// StateParams are the params of baton.tasks.update_state
+type StateParams struct {
+	TransitionParams
+	Note string `json:"note"`
+}
+
+// NoteParams are the params of baton.tasks.append_note
+type NoteParams struct {
+	TaskID string `json:"task_id"`
+	Note   string `json:"note"`
+	Author string `json:"author"`
+}
+
+// FieldsParams are the params of baton.tasks.set_fields
+type FieldsParams struct {
+	TaskID string                 `json:"task_id"`
+	Fields map[string]interface{} `json:"fields"` // a nil value clears the field
+}
+
+// TaskEditParams are the params of baton.tasks.create: the task fields it
+// sets, nil when left out
+type TaskEditParams struct {
+	Title          *string                `json:"title"`
+	Description    *string                `json:"description"`
+	Priority       *int                   `json:"priority"`
+	Owner          *string                `json:"owner"`
+	Tags           []string               `json:"tags"`
+	Dependencies   []string               `json:"dependencies"`
+	ParentID       *string                `json:"parent_id"`
+	EstimatedHours *float64               `json:"estimated_hours"`
+	DueDate        *string                `json:"due_date"`
+	CustomFields   map[string]interface{} `json:"custom_fields"`
+}
+
+// TaskUpdateParams are the params of baton.tasks.update
+type TaskUpdateParams struct {
+	TaskID string `json:"task_id"`
+	TaskEditParams
+}
+
+// DependencyParams are the params of baton.tasks.add_dependency and
+// baton.tasks.remove_dependency
+type DependencyParams struct {
+	TaskID    string `json:"task_id"`
+	DependsOn string `json:"depends_on"`
+}
+
+// TaskListParams are the params of baton.tasks.list
+type TaskListParams struct {
+	State        *string                `json:"state"`
+	Priority     *int                   `json:"priority"`
+	Owner        *string                `json:"owner"`
+	Tags         []string               `json:"tags"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+	ParentID     *string                `json:"parent_id"`
+	Archived     bool                   `json:"archived"`
+	DueBefore    *string                `json:"due_before"`
+	Sort         string                 `json:"sort"`
+	Reverse      bool                   `json:"reverse"`
+	Cursor       string                 `json:"cursor"`
+	Limit        int                    `json:"limit"`
+	Offset       int                    `json:"offset"`
+}
+
+// SearchParams are the params of baton.search and baton.tasks.search
+type SearchParams struct {
+	Query   string `json:"query"`
+	Mode    string `json:"mode"`
+	Filters struct {
+		State string `json:"state"`
+		Owner string `json:"owner"`
+		Kind  string `json:"kind"`
+	} `json:"filters"`
+	Limit int `json:"limit"`
+}
+
+// UpsertParams are the params of baton.artifacts.upsert
+type UpsertParams struct {
+	TaskID  string          `json:"task_id"`
+	Name    string          `json:"name"`
+	Content string          `json:"content"`
+	Meta    json.RawMessage `json:"meta"`
+}
+
+// ArtifactParams are the params of baton.artifacts.get
+type ArtifactParams struct {
+	TaskID  string `json:"task_id"`
+	Name    string `json:"name"`
+	Version int    `json:"version"` // 0 for the latest
+}
+
+// ReadParams are the params of baton.artifacts.read
+type ReadParams struct {
+	ArtifactParams
+	Offset int  `json:"offset"`
+	Limit  *int `json:"limit"`
+}
+
+// MilestoneParams are the params of baton.milestones.progress
+type MilestoneParams struct {
+	Milestone string `json:"milestone"`
+}
+
+// RequirementListParams are the params of baton.requirements.list
+type RequirementListParams struct {
+	Type string `json:"type"`
+}
+
+// RequirementParams are the params of baton.requirements.create
+type RequirementParams struct {
+	Title  string `json:"title"`
+	Text   string `json:"text"`
+	Type   string `json:"type"`
+	Prefix string `json:"prefix"`
+}
@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"baton/internal/sandbox"
+)
+
+// methodParams are the params structs the baton methods bind, by method
+var methodParams = map[string]interface{}{
+	"baton.tasks.get_next":          struct{}{},
+	"baton.tasks.get":               TaskParams{},
+	"baton.tasks.update_state":      StateParams{},
+	"baton.tasks.check_transition":  TransitionParams{},
+	"baton.tasks.append_note":       NoteParams{},
+	"baton.tasks.set_fields":        FieldsParams{},
+	"baton.tasks.list":              TaskListParams{},
+	"baton.tasks.delete":            TaskParams{},
+	"baton.tasks.create":            TaskEditParams{},
+	"baton.tasks.update":            TaskUpdateParams{},
+	"baton.tasks.add_dependency":    DependencyParams{},
+	"baton.tasks.remove_dependency": DependencyParams{},
+	"baton.tasks.graph":             TaskParams{},
+	"baton.tasks.stale":             struct{}{},
+	"baton.tasks.search":            SearchParams{},
+	"baton.search":                  SearchParams{},
+	"baton.artifacts.upsert":        UpsertParams{},
+	"baton.artifacts.get":           ArtifactParams{},
+	"baton.artifacts.list":          TaskParams{},
+	"baton.artifacts.read":          ReadParams{},
+	"baton.milestones.list":         struct{}{},
+	"baton.milestones.progress":     MilestoneParams{},
+	"baton.cycle.current":           struct{}{},
+	"baton.commands.run":            sandbox.Request{},
+	"baton.requirements.list":       RequirementListParams{},
+	"baton.requirements.create":     RequirementParams{},
+	"baton.plan.read":               struct{}{},
+}
+
+func TestParamsMatchSchemas(t *testing.T) {
+	for method := range methodTools {
+		if _, ok := methodParams[method]; !ok {
+			t.Errorf("Expected a params struct for %s", method)
+		}
+	}
+	for method, params := range methodParams {
+		tool, ok := methodTools[method]
+		if !ok {
+			t.Errorf("Expected a schema for %s", method)
+			continue
+		}
+		for _, problem := range compareSchema(tool.schema, reflect.TypeOf(params), "") {
+			t.Errorf("%s: %s", method, problem)
+		}
+	}
+}
+
+// compareSchema lists where an object schema and the struct its value
+// decodes into disagree
+func compareSchema(schema map[string]interface{}, typ reflect.Type, path string) []string {
+	properties, _ := schema["properties"].(map[string]interface{})
+	fields := jsonFields(typ)
+
+	var problems []string
+	for name, field := range fields {
+		property, ok := properties[name].(map[string]interface{})
+		if !ok {
+			problems = append(problems, join(path, name)+" has a field but no property")
+			continue
+		}
+		problems = append(problems, compareProperty(property, field, join(path, name))...)
+	}
+	for name := range properties {
+		if _, ok := fields[name]; !ok {
+			problems = append(problems, join(path, name)+" has a property but no field")
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// compareProperty lists where a property's schema and its field's type
+// disagree
+func compareProperty(property map[string]interface{}, typ reflect.Type, path string) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	kind, _ := property["type"].(string)
+	if got := jsonType(typ); got != kind {
+		return []string{path + " is " + article(kind) + " but its field is " + article(got)}
+	}
+	switch {
+	case kind == "object" && typ.Kind() == reflect.Struct:
+		return compareSchema(property, typ, path)
+	case kind == "array":
+		if items, ok := property["items"].(map[string]interface{}); ok {
+			return compareProperty(items, typ.Elem(), path+"[]")
+		}
+	}
+	return nil
+}
+
+// jsonFields are a struct's fields by JSON name, those of embedded structs
+// included
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			for name, embedded := range jsonFields(field.Type) {
+				fields[name] = embedded
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// jsonType is the JSON Schema type a Go type decodes from
+func jsonType(typ reflect.Type) string {
+	if typ == reflect.TypeOf(json.RawMessage{}) {
+		return "object"
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return typ.Kind().String()
+}
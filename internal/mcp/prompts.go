@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"sort"
+)
+
+// PromptRenderer renders the prompt a cycle of the agent on the task starts
+// with
+type PromptRenderer func(agentID, taskID string) (string, error)
+
+// Prompt is an agent's role prompt, offered through prompts/list
+type Prompt struct {
+	Name        string           `json:"name"` // the agent's ID
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments"`
+}
+
+// PromptArgument is an argument a prompt is rendered with
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// SetPromptRenderer offers each agent's role prompt through prompts/list and
+// prompts/get, rendered by render. It must be set before the server starts.
+func (s *Server) SetPromptRenderer(render PromptRenderer) {
+	s.renderPrompt = render
+	s.handlers["prompts/list"] = s.handlePromptsList
+	s.handlers["prompts/get"] = s.handlePromptsGet
+}
+
+// Prompts returns the role prompts of the configured agents, by agent ID
+func (s *Server) Prompts() []Prompt {
+	agentIDs := make([]string, 0, len(s.config.Agents))
+	for agentID := range s.config.Agents {
+		agentIDs = append(agentIDs, agentID)
+	}
+	sort.Strings(agentIDs)
+
+	prompts := make([]Prompt, 0, len(agentIDs))
+	for _, agentID := range agentIDs {
+		agent := s.config.Agents[agentID]
+		prompts = append(prompts, Prompt{
+			Name:        agentID,
+			Title:       agent.Name,
+			Description: agent.Role,
+			Arguments: []PromptArgument{{
+				Name:        "task_id",
+				Description: "Task to work on; defaults to the current cycle's task",
+				Required:    true,
+			}},
+		})
+	}
+	return prompts
+}
+
+// handlePromptsList handles prompts/list. Every prompt fits on one page, so
+// the cursor is ignored.
+func (s *Server) handlePromptsList(req *JSONRPCRequest) *JSONRPCResponse {
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"prompts": s.Prompts(),
+	})
+}
+
+// handlePromptsGet handles prompts/get, rendering an agent's prompt for a task
+// as a cycle of the agent would start with it
+func (s *Server) handlePromptsGet(req *JSONRPCRequest) *JSONRPCResponse {
+	params, err := req.GetParams()
+	if err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid prompts/get parameters", nil)
+	}
+	name, _ := params["name"].(string)
+	agent, ok := s.config.Agents[name]
+	if !ok {
+		return NewJSONRPCError(req.ID, InvalidParams, "Unknown prompt", map[string]interface{}{"name": name})
+	}
+
+	arguments, _ := params["arguments"].(map[string]interface{})
+	taskID, _ := arguments["task_id"].(string)
+	if taskID == "" {
+		if scope, ok := s.scopeFor(req.cycleID); ok {
+			taskID = scope.TaskID
+		}
+	}
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id argument", nil)
+	}
+	if _, err := s.store.GetTask(taskID); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Task not found", map[string]interface{}{"task_id": taskID})
+	}
+
+	text, err := s.renderPrompt(name, taskID)
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to render prompt", err.Error())
+	}
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"description": agent.Name + ": " + agent.Role,
+		"messages": []map[string]interface{}{{
+			"role":    "user",
+			"content": map[string]interface{}{"type": "text", "text": text},
+		}},
+	})
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 )
 
 // JSONRPCRequest represents a JSON-RPC 2.0 request
@@ -11,6 +12,9 @@ type JSONRPCRequest struct {
 	Method  string      `json:"method"`
 	Params  interface{} `json:"params,omitempty"`
 	ID      interface{} `json:"id"`
+
+	cycleID string // cycle the request was made for, "" when not made for one
+	session string // SSE session or STDIO the request came over, "" for plain HTTP
 }
 
 // JSONRPCResponse represents a JSON-RPC 2.0 response
@@ -48,8 +52,35 @@ const (
 const (
 	ResourceNotFound = -32002
 	ToolNotFound     = -32001
+	NotInitialized   = -32003
 )
 
+// SupportedProtocolVersions lists the MCP protocol revisions this server speaks, newest first
+var SupportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// protocolVersionPattern matches MCP protocol revision identifiers (YYYY-MM-DD)
+var protocolVersionPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// NegotiateProtocolVersion picks the protocol version to use for a client.
+// A supported version is accepted as-is; a newer unknown version is negotiated
+// down to the newest supported version not after it. Malformed versions and
+// versions older than anything supported are rejected.
+func NegotiateProtocolVersion(requested string) (string, error) {
+	if !protocolVersionPattern.MatchString(requested) {
+		return "", fmt.Errorf("invalid protocolVersion %q: expected YYYY-MM-DD", requested)
+	}
+
+	// Revision identifiers are dates, so they order lexically
+	for _, supported := range SupportedProtocolVersions {
+		if supported <= requested {
+			return supported, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported protocolVersion %s: oldest supported is %s",
+		requested, SupportedProtocolVersions[len(SupportedProtocolVersions)-1])
+}
+
 // NewJSONRPCResponse creates a successful JSON-RPC response
 func NewJSONRPCResponse(id interface{}, result interface{}) *JSONRPCResponse {
 	return &JSONRPCResponse{
@@ -97,10 +128,7 @@ func ParseJSONRPCRequest(data []byte) (*JSONRPCRequest, error) {
 		return nil, fmt.Errorf("missing method field")
 	}
 
-	if req.ID == nil {
-		return nil, fmt.Errorf("missing id field")
-	}
-
+	// A request without an id is a notification and gets no response
 	return &req, nil
 }
 
@@ -189,4 +217,20 @@ func (r *JSONRPCRequest) GetParams() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("params is not an object")
 	}
 	return params, nil
-}
\ No newline at end of file
+}
+// Bind decodes the params into v, the params struct of the request's method.
+// Params are checked against the method's schema before its handler runs, so
+// they decode into the struct's fields without type surprises.
+func (r *JSONRPCRequest) Bind(v interface{}) error {
+	if r.Params == nil {
+		return nil
+	}
+	data, err := json.Marshal(r.Params)
+	if err != nil {
+		return fmt.Errorf("params can't be encoded: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("params don't match the method: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"baton/internal/storage"
+)
+
+// Resource URIs. Artifacts are served at their latest version.
+const (
+	planURI         = "baton://plan"
+	requirementsURI = "baton://requirements"
+	requirementURI  = "baton://requirement/" // followed by the requirement's key
+	taskURI         = "baton://task/"        // followed by <task ID>/artifact/<name>
+	artifactSegment = "/artifact/"
+	resourcePage    = 100 // resources per resources/list page
+)
+
+// Resource is something an MCP client can read through resources/read
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// ResourceContents is the content of a resource, as text
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// artifactURI is the URI of a task's artifact
+func artifactURI(taskID, name string) string {
+	return taskURI + taskID + artifactSegment + name
+}
+
+// listResources lists the plan, the requirements and the latest version of
+// every artifact of the tasks that aren't archived or deleted
+func (s *Server) listResources() ([]Resource, error) {
+	var resources []Resource
+	if s.config.PlanFile != "" {
+		resource := Resource{URI: planURI, Name: "plan", Title: "Project plan", Description: s.config.PlanFile, MimeType: "text/markdown"}
+		if info, err := os.Stat(s.config.PlanFile); err == nil {
+			resource.Size = info.Size()
+		}
+		resources = append(resources, resource)
+	}
+
+	requirements, err := s.store.ListRequirements("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list requirements: %w", err)
+	}
+	resources = append(resources, Resource{URI: requirementsURI, Name: "requirements", Title: "Requirements",
+		Description: fmt.Sprintf("All %d requirements", len(requirements)), MimeType: "text/markdown"})
+	for _, requirement := range requirements {
+		resources = append(resources, Resource{URI: requirementURI + requirement.Key, Name: requirement.Key,
+			Title: requirement.Title, Description: fmt.Sprintf("%s requirement (%s)", requirement.Type, requirement.Status),
+			MimeType: "text/markdown"})
+	}
+
+	tasks, err := s.store.ListTasks(storage.TaskFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	for _, task := range tasks {
+		artifacts, err := s.store.ListArtifacts(task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts of task %s: %w", task.ID, err)
+		}
+		seen := map[string]bool{}
+		for _, artifact := range artifacts {
+			// Versions come newest first
+			if seen[artifact.Name] {
+				continue
+			}
+			seen[artifact.Name] = true
+			resources = append(resources, Resource{URI: artifactURI(task.ID, artifact.Name), Name: artifact.Name,
+				Title:       fmt.Sprintf("%s of %s", artifact.Name, task.Title),
+				Description: fmt.Sprintf("Version %d, task in %s", artifact.Version, task.State),
+				MimeType:    s.artifactMimeType(artifact.Name), Size: artifact.Size})
+		}
+	}
+	return resources, nil
+}
+
+// artifactMimeType is the MIME type of an artifact, by its schema's format
+func (s *Server) artifactMimeType(name string) string {
+	if schema, ok := s.config.ArtifactSchemas[name]; ok && schema.Format == "json" {
+		return "application/json"
+	}
+	return "text/markdown"
+}
+
+// readResource returns the content of the resource at uri, or nil when there
+// is none
+func (s *Server) readResource(uri string) (*ResourceContents, error) {
+	switch {
+	case uri == planURI:
+		if s.config.PlanFile == "" {
+			return nil, nil
+		}
+		content, err := os.ReadFile(s.config.PlanFile)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plan file: %w", err)
+		}
+		return &ResourceContents{URI: uri, MimeType: "text/markdown", Text: string(content)}, nil
+
+	case uri == requirementsURI:
+		requirements, err := s.store.ListRequirements("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list requirements: %w", err)
+		}
+		var b strings.Builder
+		b.WriteString("# Requirements\n")
+		for _, requirement := range requirements {
+			b.WriteString("\n" + requirementMarkdown(requirement, "##"))
+		}
+		return &ResourceContents{URI: uri, MimeType: "text/markdown", Text: b.String()}, nil
+
+	case strings.HasPrefix(uri, requirementURI):
+		requirement, err := s.store.GetRequirement(strings.TrimPrefix(uri, requirementURI))
+		if err != nil {
+			return nil, nil
+		}
+		return &ResourceContents{URI: uri, MimeType: "text/markdown", Text: requirementMarkdown(requirement, "#")}, nil
+
+	case strings.HasPrefix(uri, taskURI):
+		taskID, name, ok := strings.Cut(strings.TrimPrefix(uri, taskURI), artifactSegment)
+		if !ok || taskID == "" || name == "" {
+			return nil, nil
+		}
+		artifact, err := s.store.GetArtifact(taskID, name, 0)
+		if err != nil {
+			return nil, nil
+		}
+		return &ResourceContents{URI: uri, MimeType: s.artifactMimeType(name), Text: artifact.Content}, nil
+	}
+	return nil, nil
+}
+
+// requirementMarkdown renders a requirement under a heading of the given level
+func requirementMarkdown(requirement *storage.Requirement, heading string) string {
+	text := fmt.Sprintf("%s %s: %s\n\nType: %s, status: %s\n", heading, requirement.Key, requirement.Title, requirement.Type, requirement.Status)
+	if requirement.Text != "" && requirement.Text != requirement.Title {
+		text += "\n" + requirement.Text + "\n"
+	}
+	return text
+}
+
+// handleResourcesList handles resources/list, a page at a time
+func (s *Server) handleResourcesList(req *JSONRPCRequest) *JSONRPCResponse {
+	offset := 0
+	if cursor, ok := req.GetOptionalStringParam("cursor"); ok && cursor != "" {
+		n, err := strconv.Atoi(cursor)
+		if err != nil || n < 0 {
+			return NewJSONRPCError(req.ID, InvalidParams, "Invalid cursor", nil)
+		}
+		offset = n
+	}
+
+	resources, err := s.listResources()
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to list resources", err.Error())
+	}
+
+	result := map[string]interface{}{}
+	if offset > len(resources) {
+		offset = len(resources)
+	}
+	end := offset + resourcePage
+	if end < len(resources) {
+		result["nextCursor"] = strconv.Itoa(end)
+	} else {
+		end = len(resources)
+	}
+	result["resources"] = resources[offset:end]
+	return NewJSONRPCResponse(req.ID, result)
+}
+
+// handleResourceTemplatesList handles resources/templates/list
+func (s *Server) handleResourceTemplatesList(req *JSONRPCRequest) *JSONRPCResponse {
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"resourceTemplates": []map[string]interface{}{
+			{
+				"uriTemplate": taskURI + "{task_id}" + artifactSegment + "{name}",
+				"name":        "artifact",
+				"title":       "Task artifact",
+				"description": "The latest version of a task's artifact",
+			},
+			{
+				"uriTemplate": requirementURI + "{key}",
+				"name":        "requirement",
+				"title":       "Requirement",
+				"description": "A requirement, by its key",
+				"mimeType":    "text/markdown",
+			},
+		},
+	})
+}
+
+// handleResourcesRead handles resources/read
+func (s *Server) handleResourcesRead(req *JSONRPCRequest) *JSONRPCResponse {
+	uri, err := req.GetStringParam("uri")
+	if err != nil || uri == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing uri parameter", nil)
+	}
+
+	contents, err := s.readResource(uri)
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to read resource", err.Error())
+	}
+	if contents == nil {
+		return NewJSONRPCError(req.ID, ResourceNotFound, "Resource not found", map[string]interface{}{"uri": uri})
+	}
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"contents": []*ResourceContents{contents},
+	})
+}
+
+// handleResourcesSubscribe handles resources/subscribe. Updates are pushed as
+// notifications/resources/updated, so only sessions that can receive them,
+// over SSE or STDIO, may subscribe.
+func (s *Server) handleResourcesSubscribe(req *JSONRPCRequest) *JSONRPCResponse {
+	uri, err := req.GetStringParam("uri")
+	if err != nil || uri == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing uri parameter", nil)
+	}
+	if req.session == "" {
+		return NewJSONRPCError(req.ID, InvalidRequest, "Resource updates are only sent in SSE, streamable HTTP and STDIO sessions", nil)
+	}
+
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	if s.subscriptions == nil {
+		s.subscriptions = make(map[string]map[string]bool)
+	}
+	if s.subscriptions[uri] == nil {
+		s.subscriptions[uri] = make(map[string]bool)
+	}
+	s.subscriptions[uri][req.session] = true
+	if s.unsubscribeStore == nil {
+		s.unsubscribeStore = s.store.Subscribe(s.notifyResourceUpdated)
+	}
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{})
+}
+
+// handleResourcesUnsubscribe handles resources/unsubscribe
+func (s *Server) handleResourcesUnsubscribe(req *JSONRPCRequest) *JSONRPCResponse {
+	uri, err := req.GetStringParam("uri")
+	if err != nil || uri == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing uri parameter", nil)
+	}
+
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	delete(s.subscriptions[uri], req.session)
+	if len(s.subscriptions[uri]) == 0 {
+		delete(s.subscriptions, uri)
+	}
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{})
+}
+
+// notifyResourceUpdated tells the sessions subscribed to an artifact that a
+// new version of it was stored
+func (s *Server) notifyResourceUpdated(event storage.TaskEvent) {
+	if event.Kind != storage.EventArtifact {
+		return
+	}
+	uri := artifactURI(event.TaskID, event.Artifact)
+
+	s.subsMu.Lock()
+	var sessions []string
+	for session := range s.subscriptions[uri] {
+		sessions = append(sessions, session)
+	}
+	s.subsMu.Unlock()
+	if len(sessions) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(NewJSONRPCNotification("notifications/resources/updated", map[string]interface{}{
+		"uri":   uri,
+		"title": event.Artifact,
+	}))
+	if err != nil {
+		log.Printf("Failed to marshal resource update: %v", err)
+		return
+	}
+	for _, session := range sessions {
+		if !s.push(session, data) {
+			// The session is gone
+			s.subsMu.Lock()
+			delete(s.subscriptions[uri], session)
+			s.subsMu.Unlock()
+		}
+	}
+}
+
+// clearSubscriptions drops every resource subscription, as the sessions that
+// made them end with the server
+func (s *Server) clearSubscriptions() {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	s.subscriptions = nil
+	if s.unsubscribeStore != nil {
+		s.unsubscribeStore()
+		s.unsubscribeStore = nil
+	}
+}
@@ -0,0 +1,225 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CycleScope identifies a cycle a server is serving. A server shared across
+// cycles is scoped to each cycle while it runs, to several at once when
+// cycles run in parallel.
+type CycleScope struct {
+	CycleID   string    `json:"cycle_id"`
+	TaskID    string    `json:"task_id"`
+	Agent     string    `json:"agent,omitempty"` // ID of the agent working the cycle, once known
+	StartedAt time.Time `json:"started_at"`
+}
+
+// taskScopedMethods take a task_id that defaults to the current cycle's task
+var taskScopedMethods = map[string]bool{
+	"baton.tasks.get":               true,
+	"baton.tasks.update_state":      true,
+	"baton.tasks.check_transition":  true,
+	"baton.tasks.append_note":       true,
+	"baton.tasks.set_fields":        true,
+	"baton.tasks.add_dependency":    true,
+	"baton.tasks.remove_dependency": true,
+	"baton.artifacts.upsert":        true,
+	"baton.artifacts.get":           true,
+	"baton.artifacts.list":          true,
+	"baton.artifacts.read":          true,
+}
+
+// cyclePathPrefix is the path under which the HTTP transport serves each
+// cycle, as /cycles/<cycle ID>; requests sent there are scoped to that cycle
+const cyclePathPrefix = "/cycles/"
+
+type cycleKey struct{}
+
+// BeginCycle scopes the server to a cycle working on taskID
+func (s *Server) BeginCycle(cycleID, taskID string) {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	if s.scopes == nil {
+		s.scopes = make(map[string]*CycleScope)
+	}
+	s.scopes[cycleID] = &CycleScope{CycleID: cycleID, TaskID: taskID, StartedAt: time.Now()}
+}
+
+// SetCycleAgent records which agent works a cycle, whose permissions the
+// cycle's requests are held to
+func (s *Server) SetCycleAgent(cycleID, agentID string) {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	if scope, ok := s.scopes[cycleID]; ok {
+		scope.Agent = agentID
+	}
+}
+
+// EndCycle clears the scope of the given cycle, and the commands it ran
+func (s *Server) EndCycle(cycleID string) {
+	s.scopeMu.Lock()
+	defer s.scopeMu.Unlock()
+	delete(s.scopes, cycleID)
+	delete(s.commands, cycleID)
+}
+
+// CurrentCycle returns the cycle the server is scoped to, if there is
+// exactly one
+func (s *Server) CurrentCycle() (CycleScope, bool) {
+	s.scopeMu.RLock()
+	defer s.scopeMu.RUnlock()
+	if len(s.scopes) != 1 {
+		return CycleScope{}, false
+	}
+	for _, scope := range s.scopes {
+		return *scope, true
+	}
+	return CycleScope{}, false
+}
+
+// ActiveCycles returns the cycles the server is scoped to, oldest first
+func (s *Server) ActiveCycles() []CycleScope {
+	s.scopeMu.RLock()
+	defer s.scopeMu.RUnlock()
+	cycles := make([]CycleScope, 0, len(s.scopes))
+	for _, scope := range s.scopes {
+		cycles = append(cycles, *scope)
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i].StartedAt.Before(cycles[j].StartedAt) })
+	return cycles
+}
+
+// scopeFor returns the scope of a request made for cycleID, or when cycleID
+// is "" the only active cycle
+func (s *Server) scopeFor(cycleID string) (CycleScope, bool) {
+	if cycleID == "" {
+		return s.CurrentCycle()
+	}
+	s.scopeMu.RLock()
+	defer s.scopeMu.RUnlock()
+	scope, ok := s.scopes[cycleID]
+	if !ok {
+		return CycleScope{}, false
+	}
+	return *scope, true
+}
+
+// withCyclePath takes the cycle of requests sent to /cycles/<cycle ID>/...
+// out of the path and into the request context, then serves them with next
+func withCyclePath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, ok := strings.CutPrefix(r.URL.Path, cyclePathPrefix); ok {
+			cycleID, path, _ := strings.Cut(rest, "/")
+			u := *r.URL
+			u.Path = "/" + path
+			r = r.WithContext(context.WithValue(r.Context(), cycleKey{}, cycleID))
+			r.URL = &u
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cycleFromRequest returns the cycle an HTTP request was sent for, or ""
+func cycleFromRequest(r *http.Request) string {
+	cycleID, _ := r.Context().Value(cycleKey{}).(string)
+	return cycleID
+}
+
+// applyScope records the cycle a request was made for and fills in its task
+// for task-scoped requests that omit task_id
+func (s *Server) applyScope(req *JSONRPCRequest, cycleID string) {
+	req.cycleID = cycleID
+	if !taskScopedMethods[req.Method] {
+		return
+	}
+	scope, ok := s.scopeFor(cycleID)
+	if !ok {
+		return
+	}
+
+	if req.Params == nil {
+		req.Params = map[string]interface{}{}
+	}
+	params, err := req.GetParams()
+	if err != nil {
+		return
+	}
+	if _, exists := params["task_id"]; !exists {
+		params["task_id"] = scope.TaskID
+	}
+}
+
+// handleCurrentCycle handles baton.cycle.current. Unless the request was made
+// for a cycle, it lists the active cycles when there are several.
+func (s *Server) handleCurrentCycle(req *JSONRPCRequest) *JSONRPCResponse {
+	scope, ok := s.scopeFor(req.cycleID)
+	if !ok {
+		result := map[string]interface{}{"active": false}
+		if cycles := s.ActiveCycles(); req.cycleID == "" && len(cycles) > 1 {
+			result["cycles"] = cycles
+		}
+		return NewJSONRPCResponse(req.ID, result)
+	}
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"active":     true,
+		"cycle_id":   scope.CycleID,
+		"task_id":    scope.TaskID,
+		"agent":      scope.Agent,
+		"started_at": scope.StartedAt,
+	})
+}
+
+// beginRequest registers an in-flight request, refusing it while the server drains
+func (s *Server) beginRequest() bool {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	if s.draining {
+		return false
+	}
+	s.inFlight++
+	return true
+}
+
+// endRequest marks an in-flight request as finished
+func (s *Server) endRequest() {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	s.inFlight--
+	if s.inFlight == 0 && s.drained != nil {
+		close(s.drained)
+		s.drained = nil
+	}
+}
+
+// drain stops accepting requests and waits for in-flight ones to finish
+func (s *Server) drain(ctx context.Context) error {
+	s.requestsMu.Lock()
+	s.draining = true
+	if s.inFlight == 0 {
+		s.requestsMu.Unlock()
+		return nil
+	}
+	drained := make(chan struct{})
+	s.drained = drained
+	inFlight := s.inFlight
+	s.requestsMu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d MCP requests still in flight: %w", inFlight, ctx.Err())
+	}
+}
+
+// acceptRequests lets a restarted server take requests again
+func (s *Server) acceptRequests() {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	s.draining = false
+}
@@ -10,13 +10,18 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"baton/internal/artifactfs"
 	"baton/internal/config"
+	"baton/internal/hooks"
 	"baton/internal/statemachine"
 	"baton/internal/storage"
+	"baton/pkg/version"
 )
 
 // Server represents the MCP server
@@ -28,18 +33,65 @@ type Server struct {
 	handlers  map[string]HandlerFunc
 	mu        sync.RWMutex
 	running   bool
+
+	// Protocol version negotiated by each session's initialize handshake, by
+	// session ID; guarded separately from mu which is held while serving
+	sessionMu  sync.RWMutex
+	handshakes map[string]string
+
+	observer CallObserver
+	sse      *sseSessions
+
+	// Path prefix the HTTP transport is mounted under, "" when served on its own port
+	basePath string
+
+	// Cycles the server is scoped to by ID, for servers shared across cycles
+	scopeMu sync.RWMutex
+	scopes  map[string]*CycleScope
+
+	// Commands each cycle's agent may run and ran, guarded by scopeMu
+	commands     map[string]*cycleCommands
+	skipCommands bool
+
+	// Task creates and updates, serialized as they read, check and write
+	// dependencies
+	tasksMu sync.Mutex
+
+	// Sessions subscribed to each resource URI, and the store subscription
+	// feeding them artifact changes while there are any
+	subsMu           sync.Mutex
+	subscriptions    map[string]map[string]bool
+	unsubscribeStore func()
+
+	// Renders the agents' role prompts for prompts/get, nil when not offered
+	renderPrompt PromptRenderer
+
+	// STDIO responses and notifications, written one at a time
+	stdoutMu sync.Mutex
+	stdout   *json.Encoder
+
+	// In-flight request tracking, so Stop can drain before shutting down
+	requestsMu sync.Mutex
+	inFlight   int
+	draining   bool
+	drained    chan struct{}
 }
 
 // HandlerFunc represents a method handler
 type HandlerFunc func(*JSONRPCRequest) *JSONRPCResponse
 
+// CallObserver is notified of each handled request and its response (nil for notifications)
+type CallObserver func(req *JSONRPCRequest, resp *JSONRPCResponse)
+
 // NewServer creates a new MCP server
 func NewServer(store *storage.Store, config *config.Config) *Server {
 	server := &Server{
 		store:    store,
 		config:   config,
 		port:     config.MCPPort,
-		handlers: make(map[string]HandlerFunc),
+		handlers:   make(map[string]HandlerFunc),
+		handshakes: make(map[string]string),
+		sse:        newSSESessions(),
 	}
 
 	// Register handlers
@@ -52,34 +104,92 @@ func NewServer(store *storage.Store, config *config.Config) *Server {
 func (s *Server) registerHandlers() {
 	// Create handler instances
 	selector := statemachine.NewTaskSelector(s.store, &s.config.Selection)
+	selector.SetAgentCoverage(statemachine.AgentCoverage(s.config))
 	validator := statemachine.NewTransitionValidator(s.store)
+	validator.SetArtifactSchemas(s.config.ArtifactSchemas)
+	validator.SetTransitionRules(s.config.TransitionRules)
+	validator.SetHooks(hooks.NewRunner(s.config.Hooks, s.config.Workspace))
+	validator.SetWIPLimits(s.config.Selection.WIPLimits)
+	validator.SetReviewEscalation(s.config.ReviewEscalation)
 
 	taskHandler := NewTaskHandler(s.store, selector, validator)
-	artifactHandler := NewArtifactHandler(s.store)
+	taskHandler.SetCustomFields(s.config.CustomFields)
+	taskHandler.SetStaleness(s.config.Staleness)
+	artifactHandler := NewArtifactHandler(s.store, s.config.ArtifactSchemas)
+	if s.config.Artifacts.Materialize {
+		artifactHandler.SetMirror(artifactfs.NewMirrorFromConfig(s.store, s.config))
+	}
 	requirementHandler := NewRequirementHandler(s.store)
 	planHandler := NewPlanHandler(s.config.PlanFile)
+	searchHandler := NewSearchHandler(s.store, s.config.Search)
+	milestoneHandler := NewMilestoneHandler(selector)
 
 	// Register task methods
 	s.handlers["baton.tasks.get_next"] = taskHandler.GetNext
 	s.handlers["baton.tasks.get"] = taskHandler.Get
 	s.handlers["baton.tasks.update_state"] = taskHandler.UpdateState
+	s.handlers["baton.tasks.check_transition"] = taskHandler.CheckTransition
 	s.handlers["baton.tasks.append_note"] = taskHandler.AppendNote
+	s.handlers["baton.tasks.set_fields"] = taskHandler.SetFields
 	s.handlers["baton.tasks.list"] = taskHandler.List
+	s.handlers["baton.tasks.delete"] = taskHandler.Delete
+	s.handlers["baton.tasks.create"] = s.handleCreateTask
+	s.handlers["baton.tasks.update"] = s.handleUpdateTask
+	s.handlers["baton.tasks.add_dependency"] = s.handleAddDependency
+	s.handlers["baton.tasks.remove_dependency"] = s.handleRemoveDependency
+	s.handlers["baton.tasks.graph"] = s.handleGraph
+	s.handlers["baton.tasks.stale"] = taskHandler.Stale
+	s.handlers["baton.tasks.search"] = searchHandler.Search
+
+	// Register search across tasks, artifacts, requirements and audit notes
+	s.handlers["baton.search"] = searchHandler.Search
 
 	// Register artifact methods
 	s.handlers["baton.artifacts.upsert"] = artifactHandler.Upsert
 	s.handlers["baton.artifacts.get"] = artifactHandler.Get
 	s.handlers["baton.artifacts.list"] = artifactHandler.List
+	s.handlers["baton.artifacts.read"] = artifactHandler.Read
+
+	// Register milestone methods
+	s.handlers["baton.milestones.list"] = milestoneHandler.List
+	s.handlers["baton.milestones.progress"] = milestoneHandler.Progress
+
+	// Register cycle methods
+	s.handlers["baton.cycle.current"] = s.handleCurrentCycle
+
+	// Register command execution, confined to the cycle's workspace
+	s.handlers["baton.commands.run"] = s.handleRunCommand
 
 	// Register requirement methods
 	s.handlers["baton.requirements.list"] = requirementHandler.List
+	s.handlers["baton.requirements.create"] = requirementHandler.Create
 
-	// Register plan methods
-	s.handlers["baton.plan.read"] = planHandler.Read
+	// Register plan methods only when a plan file is configured
+	if s.config.PlanFile != "" {
+		s.handlers["baton.plan.read"] = planHandler.Read
+	}
 
 	// Register standard MCP methods
 	s.handlers["initialize"] = s.handleInitialize
+	s.handlers["notifications/initialized"] = s.handleInitializedNotification
 	s.handlers["ping"] = s.handlePing
+
+	// Offer the baton methods as tools, for clients that discover them
+	s.handlers["tools/list"] = s.handleToolsList
+	s.handlers["tools/call"] = s.handleToolsCall
+
+	// Offer the plan, requirements and artifacts as resources
+	s.handlers["resources/list"] = s.handleResourcesList
+	s.handlers["resources/templates/list"] = s.handleResourceTemplatesList
+	s.handlers["resources/read"] = s.handleResourcesRead
+	s.handlers["resources/subscribe"] = s.handleResourcesSubscribe
+	s.handlers["resources/unsubscribe"] = s.handleResourcesUnsubscribe
+}
+
+// preInitMethods may be called before the initialize handshake has completed
+var preInitMethods = map[string]bool{
+	"initialize": true,
+	"ping":       true,
 }
 
 // Start starts the MCP server
@@ -100,7 +210,21 @@ func (s *Server) Start() error {
 	return s.runHTTPMode()
 }
 
-// Stop stops the MCP server
+// StartHTTP starts the server in HTTP mode regardless of how stdin is connected,
+// for long-running processes such as baton serve
+func (s *Server) StartHTTP() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("server is already running")
+	}
+
+	return s.runHTTPMode()
+}
+
+// Stop stops the MCP server. New requests are refused while requests already
+// in flight are given up to 5 seconds to finish before the transport closes.
 func (s *Server) Stop() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -109,11 +233,18 @@ func (s *Server) Stop() error {
 		return nil
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.drain(ctx); err != nil {
+		log.Printf("Stopping MCP server: %v", err)
+	}
+
 	s.running = false
+	s.sse.closeAll()
+	s.clearSubscriptions()
 
 	if s.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
 		return s.server.Shutdown(ctx)
 	}
 
@@ -133,9 +264,10 @@ func (s *Server) isSTDIOMode() bool {
 // runSTDIOMode runs the server in STDIO mode for Claude Code integration
 func (s *Server) runSTDIOMode() error {
 	s.running = true
+	s.acceptRequests()
 
 	scanner := bufio.NewScanner(os.Stdin)
-	writer := json.NewEncoder(os.Stdout)
+	s.stdout = json.NewEncoder(os.Stdout)
 
 	for scanner.Scan() && s.running {
 		line := strings.TrimSpace(scanner.Text())
@@ -147,18 +279,19 @@ func (s *Server) runSTDIOMode() error {
 		req, err := ParseJSONRPCRequest([]byte(line))
 		if err != nil {
 			response := NewJSONRPCError(nil, ParseError, "Invalid JSON-RPC request", err.Error())
-			if err := writer.Encode(response); err != nil {
+			if err := s.writeSTDIO(response); err != nil {
 				log.Printf("Failed to write error response: %v", err)
 			}
 			continue
 		}
 
 		// Handle request
-		response := s.handleRequest(req)
+		req.session = stdioSession
+		response := s.handleRequest(req, "")
 
 		// Send response (only if not a notification)
 		if !req.IsNotification() && response != nil {
-			if err := writer.Encode(response); err != nil {
+			if err := s.writeSTDIO(response); err != nil {
 				log.Printf("Failed to write response: %v", err)
 			}
 		}
@@ -167,14 +300,75 @@ func (s *Server) runSTDIOMode() error {
 	return scanner.Err()
 }
 
-// runHTTPMode runs the server in HTTP mode
-func (s *Server) runHTTPMode() error {
+// stdioSession is the session of requests read from stdin
+const stdioSession = "stdio"
+
+// writeSTDIO writes a message to stdout
+func (s *Server) writeSTDIO(message interface{}) error {
+	s.stdoutMu.Lock()
+	defer s.stdoutMu.Unlock()
+	return s.stdout.Encode(message)
+}
+
+// push sends a notification to a session, reporting whether the session is
+// still there to get it
+func (s *Server) push(session string, data []byte) bool {
+	if session == stdioSession {
+		if s.stdout == nil {
+			return false
+		}
+		if err := s.writeSTDIO(json.RawMessage(data)); err != nil {
+			log.Printf("Failed to write notification: %v", err)
+		}
+		return true
+	}
+	return s.sse.send(session, data)
+}
+
+// Mount starts the server without a listener of its own and returns its HTTP
+// transport for serving under basePath, which the caller must strip from
+// request paths. Stop still drains and closes SSE sessions.
+func (s *Server) Mount(basePath string) http.Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.basePath = strings.TrimRight(basePath, "/")
+	s.running = true
+	s.acceptRequests()
+	return s.httpHandler()
+}
+
+// httpHandler routes the HTTP and SSE transports
+func (s *Server) httpHandler() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleHTTP)
+	mux.HandleFunc("/", s.handleStreamable)
+	mux.HandleFunc("/sse", s.handleSSE)
+	mux.HandleFunc("/messages", s.handleSSEMessage)
+	return withCyclePath(mux)
+}
+
+// runHTTPMode runs the server in HTTP mode, on localhost unless mcp.allow_remote
+// is set. Requests without the token are refused; without one configured, a
+// token is generated into the workspace first.
+func (s *Server) runHTTPMode() error {
+	token, err := s.config.EnsureMCPToken()
+	if err != nil {
+		return err
+	}
+	host := "127.0.0.1"
+	if s.config.MCP.AllowRemote {
+		if token == "" {
+			return fmt.Errorf("mcp.allow_remote needs a token: unset mcp.insecure_no_token")
+		}
+		host = ""
+	}
+	if token == "" {
+		log.Printf("MCP server runs without a token (mcp.insecure_no_token): any local client can use it")
+	}
 
 	s.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: mux,
+		Addr:    net.JoinHostPort(host, strconv.Itoa(s.port)),
+		Handler: requireToken(token, s.httpHandler()),
 	}
 
 	listener, err := net.Listen("tcp", s.server.Addr)
@@ -183,18 +377,32 @@ func (s *Server) runHTTPMode() error {
 	}
 
 	s.running = true
+	s.acceptRequests()
+
+	// Serve in the background so Start returns and the cycle can proceed
+	go func() {
+		log.Printf("MCP server starting on %s", listener.Addr())
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("MCP server error: %v", err)
+		}
+	}()
 
-	log.Printf("MCP server starting on port %d", s.port)
-	return s.server.Serve(listener)
+	return nil
 }
 
-// handleHTTP handles HTTP requests
+// handleHTTP handles JSON-RPC messages POSTed to the MCP endpoint, in a
+// streamable HTTP session or without one
 func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	session, ok := s.streamableSession(w, r)
+	if !ok {
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
@@ -208,10 +416,24 @@ func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := s.handleRequest(req)
-	if response != nil {
-		s.writeJSONResponse(w, response)
+	if session == nil {
+		session = s.openStreamableSession(w, r, req)
+	}
+	cycleID := cycleFromRequest(r)
+	if session != nil {
+		req.session = session.id
+		if cycleID == "" {
+			cycleID = session.cycleID
+		}
+	}
+
+	response := s.handleRequest(req, cycleID)
+	if response == nil {
+		// Notifications are acknowledged without a body
+		w.WriteHeader(http.StatusAccepted)
+		return
 	}
+	s.writeJSONResponse(w, response)
 }
 
 // writeJSONResponse writes a JSON response
@@ -229,20 +451,139 @@ func (s *Server) writeJSONResponse(w http.ResponseWriter, response *JSONRPCRespo
 	}
 }
 
-// handleRequest processes a JSON-RPC request
-func (s *Server) handleRequest(req *JSONRPCRequest) *JSONRPCResponse {
-	s.mu.RLock()
+// SetCallObserver registers a function that sees every request the server
+// handles along with its response. It must be set before the server starts.
+func (s *Server) SetCallObserver(observer CallObserver) {
+	s.observer = observer
+}
+
+// HandleRequest dispatches a request in-process, bypassing the transport
+func (s *Server) HandleRequest(req *JSONRPCRequest) *JSONRPCResponse {
+	return s.handleRequest(req, "")
+}
+
+// handleRequest processes a JSON-RPC request made for cycleID ("" when not
+// made for a cycle) and reports it to the observer
+func (s *Server) handleRequest(req *JSONRPCRequest, cycleID string) *JSONRPCResponse {
+	if !s.beginRequest() {
+		if req.IsNotification() {
+			return nil
+		}
+		return NewJSONRPCError(req.ID, InternalError, "Server is shutting down", nil)
+	}
+	defer s.endRequest()
+
+	s.applyScope(req, cycleID)
+	response := s.dispatch(req)
+	if s.observer != nil {
+		s.observer(req, response)
+	}
+	return response
+}
+
+// dispatch routes a JSON-RPC request to its handler
+func (s *Server) dispatch(req *JSONRPCRequest) *JSONRPCResponse {
+	// handlers is only written during construction, so it is read without
+	// taking mu, which Start holds for the lifetime of the STDIO loop
 	handler, exists := s.handlers[req.Method]
-	s.mu.RUnlock()
+
+	if req.IsNotification() {
+		// Notifications never get a response, not even an error
+		if exists {
+			handler(req)
+		}
+		return nil
+	}
 
 	if !exists {
 		return NewJSONRPCError(req.ID, MethodNotFound, fmt.Sprintf("Method not found: %s", req.Method), nil)
 	}
 
+	if !preInitMethods[req.Method] && !s.isInitialized(req.session) {
+		return NewJSONRPCError(req.ID, NotInitialized, "Server not initialized",
+			map[string]interface{}{"method": req.Method, "hint": "call initialize first"})
+	}
+
+	if invalid := checkParams(req); invalid != nil {
+		return invalid
+	}
+
 	// Call the handler
 	return handler(req)
 }
 
+// isInitialized reports whether the client of a session has completed the
+// initialize request. Requests without a session, from plain HTTP clients or
+// made in-process, share the "" session.
+func (s *Server) isInitialized(session string) bool {
+	return s.ProtocolVersion(session) != ""
+}
+
+// ProtocolVersion returns the protocol version negotiated in a session, ""
+// when its client has not initialized
+func (s *Server) ProtocolVersion(session string) string {
+	s.sessionMu.RLock()
+	defer s.sessionMu.RUnlock()
+	return s.handshakes[session]
+}
+
+// setProtocolVersion records the handshake of a session, forgetting those of
+// SSE and streamable HTTP sessions that have since ended
+func (s *Server) setProtocolVersion(session, protocolVersion string) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	for id := range s.handshakes {
+		if id == "" || id == stdioSession {
+			continue
+		}
+		if _, open := s.sse.get(id); !open {
+			delete(s.handshakes, id)
+		}
+	}
+	s.handshakes[session] = protocolVersion
+}
+
+// capabilities advertises only the MCP features backed by registered handlers
+func (s *Server) capabilities() map[string]interface{} {
+	capabilities := map[string]interface{}{}
+
+	if _, ok := s.handlers["tools/list"]; ok {
+		capabilities["tools"] = map[string]interface{}{
+			"listChanged": false,
+		}
+	}
+
+	if _, ok := s.handlers["resources/list"]; ok {
+		_, subscribe := s.handlers["resources/subscribe"]
+		capabilities["resources"] = map[string]interface{}{
+			"subscribe":   subscribe,
+			"listChanged": false,
+		}
+	}
+
+	if _, ok := s.handlers["prompts/list"]; ok {
+		capabilities["prompts"] = map[string]interface{}{
+			"listChanged": false,
+		}
+	}
+
+	// Baton's own methods are not part of the MCP spec, so list them as experimental
+	var methods []string
+	for method := range s.handlers {
+		if strings.HasPrefix(method, "baton.") {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	capabilities["experimental"] = map[string]interface{}{
+		"baton": map[string]interface{}{
+			"methods": methods,
+		},
+	}
+
+	return capabilities
+}
+
 // handleInitialize handles the MCP initialize method
 func (s *Server) handleInitialize(req *JSONRPCRequest) *JSONRPCResponse {
 	params, err := req.GetParams()
@@ -250,38 +591,59 @@ func (s *Server) handleInitialize(req *JSONRPCRequest) *JSONRPCResponse {
 		return NewJSONRPCError(req.ID, InvalidParams, "Invalid initialize parameters", nil)
 	}
 
+	requested, ok := params["protocolVersion"].(string)
+	if !ok {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing protocolVersion parameter",
+			map[string]interface{}{"supported": SupportedProtocolVersions})
+	}
+
+	protocolVersion, err := NegotiateProtocolVersion(requested)
+	if err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Unsupported protocol version", map[string]interface{}{
+			"requested": requested,
+			"supported": SupportedProtocolVersions,
+			"error":     err.Error(),
+		})
+	}
+
+	if caps, exists := params["capabilities"]; exists {
+		if _, ok := caps.(map[string]interface{}); !ok {
+			return NewJSONRPCError(req.ID, InvalidParams, "Client capabilities must be an object", nil)
+		}
+	}
+
 	// Extract client info
 	clientInfo := map[string]interface{}{}
 	if info, ok := params["clientInfo"].(map[string]interface{}); ok {
 		clientInfo = info
 	}
 
-	// Build server capabilities
-	capabilities := map[string]interface{}{
-		"tools": map[string]interface{}{
-			"listChanged": false,
-		},
-		"resources": map[string]interface{}{
-			"subscribe":   false,
-			"listChanged": false,
-		},
-	}
+	// Each session (e.g. one per cycle) runs its own handshake
+	s.setProtocolVersion(req.session, protocolVersion)
 
 	result := map[string]interface{}{
-		"protocolVersion": "2024-11-05",
-		"capabilities":    capabilities,
+		"protocolVersion": protocolVersion,
+		"capabilities":    s.capabilities(),
 		"serverInfo": map[string]interface{}{
 			"name":    "baton",
 			"title":   "Baton CLI Orchestrator",
-			"version": "1.0.0",
+			"version": version.Version,
 		},
-		"instructions": "Baton MCP server provides task orchestration capabilities. Use baton.* methods to interact with tasks, artifacts, requirements, and plans.",
+		"instructions": "Baton MCP server provides task orchestration capabilities. Use baton.* methods, or the baton_* tools that call them, to interact with tasks, artifacts, requirements, and plans.",
 	}
 
-	log.Printf("MCP initialized for client: %v", clientInfo)
+	log.Printf("MCP initialized for client %v with protocol %s", clientInfo, protocolVersion)
 	return NewJSONRPCResponse(req.ID, result)
 }
 
+// handleInitializedNotification handles notifications/initialized sent by the client once it is ready
+func (s *Server) handleInitializedNotification(req *JSONRPCRequest) *JSONRPCResponse {
+	if !s.isInitialized(req.session) {
+		log.Printf("Ignoring notifications/initialized received before initialize")
+	}
+	return nil
+}
+
 // handlePing handles the ping method
 func (s *Server) handlePing(req *JSONRPCRequest) *JSONRPCResponse {
 	return NewJSONRPCResponse(req.ID, map[string]interface{}{})
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"baton/internal/config"
+)
+
+// post sends a JSON-RPC request to the MCP endpoint in session ("" for none)
+// and returns the response and the session ID the server handed out
+func post(t *testing.T, handler http.Handler, session, method string, params interface{}) (*JSONRPCResponse, string) {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set(sessionHeader, session)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for %s, got %d: %s", method, rec.Code, rec.Body.String())
+	}
+
+	var response JSONRPCResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response to %s: %v", method, err)
+	}
+	return &response, rec.Header().Get(sessionHeader)
+}
+
+func initialize(t *testing.T, handler http.Handler, protocolVersion string) string {
+	t.Helper()
+	response, session := post(t, handler, "", "initialize", map[string]interface{}{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "test"},
+	})
+	if response.Error != nil {
+		t.Fatalf("Failed to initialize: %v", response.Error.Message)
+	}
+	if session == "" {
+		t.Fatal("Expected a session ID from initialize")
+	}
+	return session
+}
+
+func TestHandshakePerSession(t *testing.T) {
+	server := NewServer(nil, &config.Config{})
+	handler := server.Mount("")
+	defer server.Stop()
+
+	older := initialize(t, handler, "2024-11-05")
+
+	// Another session never initialized, so it must not ride on the first
+	stale := server.sse.open("", true)
+	response, _ := post(t, handler, stale.id, "tools/list", nil)
+	if response.Error == nil || response.Error.Code != NotInitialized {
+		t.Fatalf("Expected NotInitialized for an uninitialized session, got %+v", response.Error)
+	}
+	response, _ = post(t, handler, "", "tools/list", nil)
+	if response.Error == nil || response.Error.Code != NotInitialized {
+		t.Fatalf("Expected NotInitialized without a session, got %+v", response.Error)
+	}
+
+	newer := initialize(t, handler, "2025-06-18")
+	if got := server.ProtocolVersion(older); got != "2024-11-05" {
+		t.Errorf("Expected the first session to keep 2024-11-05, got %q", got)
+	}
+	if got := server.ProtocolVersion(newer); got != "2025-06-18" {
+		t.Errorf("Expected the second session to use 2025-06-18, got %q", got)
+	}
+
+	response, _ = post(t, handler, older, "tools/list", nil)
+	if response.Error != nil {
+		t.Errorf("Expected tools/list to work in an initialized session, got %v", response.Error.Message)
+	}
+}
@@ -0,0 +1,294 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// sseKeepAlive is how often an idle event stream gets a comment, so
+	// clients and proxies don't take it for dead
+	sseKeepAlive = 15 * time.Second
+	// sseReplay is how many sent events a session keeps for clients that
+	// reconnect with Last-Event-ID
+	sseReplay = 64
+	// sessionIdleTimeout ends streamable HTTP sessions that had neither a
+	// stream nor a request for this long
+	sessionIdleTimeout = time.Hour
+)
+
+// sseEvent is a message sent on an event stream, by its event ID
+type sseEvent struct {
+	id   int
+	data []byte
+}
+
+// sseSession is one client connected over the HTTP+SSE transport, or a
+// streamable HTTP session. Responses to requests POSTed to an HTTP+SSE
+// session's message endpoint, and notifications for either, are delivered on
+// its stream.
+type sseSession struct {
+	id         string
+	cycleID    string // cycle the session was opened for, "" when not opened for one
+	streamable bool   // a streamable HTTP session, which outlives its streams
+	messages   chan []byte
+	done       chan struct{}
+
+	mu       sync.Mutex
+	eventID  int           // ID of the last event sent
+	sent     []sseEvent    // the last events sent, replayed to reconnecting clients
+	detach   chan struct{} // closed to end the stream attached to the session, nil when none is
+	lastSeen time.Time
+}
+
+// sseSessions tracks the open SSE and streamable HTTP sessions
+type sseSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*sseSession
+}
+
+func newSSESessions() *sseSessions {
+	return &sseSessions{sessions: make(map[string]*sseSession)}
+}
+
+// open starts a session, ending streamable sessions left idle too long
+func (ss *sseSessions) open(cycleID string, streamable bool) *sseSession {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	for id, session := range ss.sessions {
+		if session.idle() {
+			close(session.done)
+			delete(ss.sessions, id)
+		}
+	}
+
+	session := &sseSession{
+		id:         uuid.New().String(),
+		cycleID:    cycleID,
+		streamable: streamable,
+		messages:   make(chan []byte, 16),
+		done:       make(chan struct{}),
+		lastSeen:   time.Now(),
+	}
+	ss.sessions[session.id] = session
+	return session
+}
+
+func (ss *sseSessions) get(id string) (*sseSession, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	session, exists := ss.sessions[id]
+	return session, exists
+}
+
+func (ss *sseSessions) close(id string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if session, exists := ss.sessions[id]; exists {
+		close(session.done)
+		delete(ss.sessions, id)
+	}
+}
+
+// send queues a message on a session's stream, dropping it when the stream
+// is backed up, and reports whether the session is open
+func (ss *sseSessions) send(id string, message []byte) bool {
+	session, exists := ss.get(id)
+	if !exists {
+		return false
+	}
+	select {
+	case session.messages <- message:
+	case <-session.done:
+		return false
+	default:
+		log.Printf("Dropped a message for SSE session %s: its stream is backed up", id)
+	}
+	return true
+}
+
+// closeAll ends every open stream, used on shutdown
+func (ss *sseSessions) closeAll() {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for id, session := range ss.sessions {
+		close(session.done)
+		delete(ss.sessions, id)
+	}
+}
+
+// touch marks the session as used now
+func (session *sseSession) touch() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.lastSeen = time.Now()
+}
+
+// idle reports whether a streamable session has gone unused too long to keep
+func (session *sseSession) idle() bool {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.streamable && session.detach == nil && time.Since(session.lastSeen) > sessionIdleTimeout
+}
+
+// attach makes a new stream the session's, ending the one before it, and
+// returns the channel closed when the stream is replaced in turn
+func (session *sseSession) attach() chan struct{} {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.detach != nil {
+		close(session.detach)
+	}
+	session.detach = make(chan struct{})
+	return session.detach
+}
+
+// release detaches a stream that ended, unless another replaced it
+func (session *sseSession) release(detach chan struct{}) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.detach == detach {
+		session.detach = nil
+	}
+	session.lastSeen = time.Now()
+}
+
+// record numbers a message about to be sent and keeps it for replay
+func (session *sseSession) record(data []byte) sseEvent {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.eventID++
+	event := sseEvent{id: session.eventID, data: data}
+	session.sent = append(session.sent, event)
+	if len(session.sent) > sseReplay {
+		session.sent = session.sent[len(session.sent)-sseReplay:]
+	}
+	return event
+}
+
+// since returns the kept events sent after the given event ID
+func (session *sseSession) since(eventID int) []sseEvent {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	var events []sseEvent
+	for _, event := range session.sent {
+		if event.id > eventID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// writeEvent writes a message event to a stream
+func writeEvent(w io.Writer, event sseEvent) {
+	fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", event.id, event.data)
+}
+
+// stream sends the session's messages on an event stream until the session
+// ends, the client goes away or detach is closed, with a keep-alive comment
+// whenever the stream has been quiet for sseKeepAlive
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, flusher http.Flusher, session *sseSession, detach chan struct{}) {
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case message := <-session.messages:
+			writeEvent(w, session.record(message))
+			flusher.Flush()
+			keepAlive.Reset(sseKeepAlive)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-session.done:
+			return
+		case <-detach:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleSSE opens an event stream and tells the client where to POST messages
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	session := s.sse.open(cycleFromRequest(r), false)
+	defer s.sse.close(session.id)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	endpoint := s.basePath
+	if session.cycleID != "" {
+		endpoint += cyclePathPrefix + session.cycleID
+	}
+	endpoint += "/messages?session_id=" + session.id
+	fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", endpoint)
+	flusher.Flush()
+
+	s.stream(w, r, flusher, session, nil)
+}
+
+// handleSSEMessage accepts a JSON-RPC message for an SSE session and delivers the response on its stream
+func (s *Server) handleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, exists := s.sse.get(r.URL.Query().Get("session_id"))
+	if !exists {
+		http.Error(w, "Unknown session", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var response *JSONRPCResponse
+	req, err := ParseJSONRPCRequest(body)
+	if err != nil {
+		response = NewJSONRPCError(nil, ParseError, "Invalid JSON-RPC request", err.Error())
+	} else {
+		req.session = session.id
+		response = s.handleRequest(req, session.cycleID)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if response == nil {
+		return
+	}
+
+	data, err := response.Marshal()
+	if err != nil {
+		log.Printf("Failed to marshal SSE response: %v", err)
+		return
+	}
+
+	select {
+	case session.messages <- data:
+	case <-session.done:
+	}
+}
@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// sessionHeader carries the ID of a streamable HTTP session, given out in the
+// response to initialize and sent back with every request after it
+const sessionHeader = "Mcp-Session-Id"
+
+// handleStreamable serves the streamable HTTP transport on the MCP endpoint.
+// POST takes JSON-RPC messages, as plain HTTP clients send them too; GET opens
+// the stream notifications of a session are sent on, and DELETE ends the
+// session.
+func (s *Server) handleStreamable(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		s.handleHTTP(w, r)
+	case "GET":
+		s.handleStreamableGet(w, r)
+	case "DELETE":
+		s.handleStreamableDelete(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// acceptsEventStream reports whether a client takes event streams, as
+// streamable HTTP clients say they do
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// streamableSession returns the session a request names in its
+// Mcp-Session-Id header, nil when it names none. When the session is unknown
+// or has ended it answers 404, which tells the client to initialize again,
+// and returns false.
+func (s *Server) streamableSession(w http.ResponseWriter, r *http.Request) (*sseSession, bool) {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		return nil, true
+	}
+	session, exists := s.sse.get(id)
+	if !exists || !session.streamable {
+		http.Error(w, "Unknown or expired session; initialize again", http.StatusNotFound)
+		return nil, false
+	}
+	session.touch()
+	return session, true
+}
+
+// openStreamableSession starts a session for a client initializing over the
+// streamable HTTP transport and gives the client its ID. Plain HTTP clients,
+// which don't take event streams, get no session.
+func (s *Server) openStreamableSession(w http.ResponseWriter, r *http.Request, req *JSONRPCRequest) *sseSession {
+	if req.Method != "initialize" || !acceptsEventStream(r) {
+		return nil
+	}
+	session := s.sse.open(cycleFromRequest(r), true)
+	w.Header().Set(sessionHeader, session.id)
+	return session
+}
+
+// handleStreamableGet opens the event stream of a session. A client that
+// reconnects with Last-Event-ID first gets the events it missed, as far as
+// the session still keeps them. A session has one stream at a time; a new one
+// replaces the last.
+func (s *Server) handleStreamableGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get(sessionHeader) == "" {
+		http.Error(w, "Missing "+sessionHeader+" header; initialize first", http.StatusBadRequest)
+		return
+	}
+	session, ok := s.streamableSession(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	detach := session.attach()
+	defer session.release(detach)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(sessionHeader, session.id)
+	w.WriteHeader(http.StatusOK)
+
+	if lastEventID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		for _, event := range session.since(lastEventID) {
+			writeEvent(w, event)
+		}
+	}
+	flusher.Flush()
+
+	s.stream(w, r, flusher, session, detach)
+}
+
+// handleStreamableDelete ends a session at the client's request
+func (s *Server) handleStreamableDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(sessionHeader) == "" {
+		http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+	session, ok := s.streamableSession(w, r)
+	if !ok {
+		return
+	}
+	s.sse.close(session.id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,285 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// Audit results of tasks created and edited through the MCP API
+const (
+	ResultTaskCreated = "created"
+	ResultTaskUpdated = "updated"
+)
+
+// taskEditFields are the fields baton.tasks.update can change. The state is
+// changed through baton.tasks.update_state, which runs the transition checks.
+var taskEditFields = []string{"title", "description", "priority", "owner", "tags", "dependencies",
+	"parent_id", "estimated_hours", "due_date", "custom_fields"}
+
+// taskEditor is who creates or edits a task through the MCP API: the agent of
+// the cycle a request was made for, or a client outside any cycle
+type taskEditor struct {
+	cycleID string // "" outside a cycle
+	taskID  string // the cycle's task
+	actor   string
+}
+
+// taskEditorFor returns who made a task-editing request, or the error to
+// answer it with when the cycle's agent lacks can_create_tasks
+func (s *Server) taskEditorFor(req *JSONRPCRequest) (*taskEditor, *JSONRPCResponse) {
+	scope, ok := s.scopeFor(req.cycleID)
+	if !ok {
+		return &taskEditor{actor: "mcp"}, nil
+	}
+	agent, ok := s.config.Agents[scope.Agent]
+	if !ok || !agent.Permissions.CanCreateTasks {
+		return nil, NewJSONRPCError(req.ID, InvalidRequest,
+			"The agent may not create or edit tasks (permissions.can_create_tasks)", map[string]interface{}{"agent": scope.Agent})
+	}
+	return &taskEditor{cycleID: scope.CycleID, taskID: scope.TaskID, actor: scope.Agent}, nil
+}
+
+// audit records a task edit in the task's audit log, as part of the cycle
+// that made it when there is one
+func (e *taskEditor) audit(store *storage.Store, task *storage.Task, prevState storage.State, result, note string) {
+	cycleID := e.cycleID
+	if cycleID == "" {
+		cycleID = "manual"
+	}
+	entry := &storage.AuditLog{
+		TaskID:    task.ID,
+		CycleID:   cycleID,
+		PrevState: string(prevState),
+		NextState: string(task.State),
+		Actor:     e.actor,
+		Result:    result,
+		Note:      note,
+	}
+	if err := store.CreateAuditLog(entry); err != nil {
+		log.Printf("Failed to audit %s task %s: %v", result, task.ID, err)
+	}
+}
+
+// handleCreateTask handles baton.tasks.create, which files a new task in
+// ready_for_plan. During a cycle, the cycle's agent needs can_create_tasks,
+// and the new task's audit entry names the task it was discovered on.
+func (s *Server) handleCreateTask(req *JSONRPCRequest) *JSONRPCResponse {
+	var params TaskEditParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	if params.Title == nil || strings.TrimSpace(*params.Title) == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing title parameter", nil)
+	}
+
+	editor, denied := s.taskEditorFor(req)
+	if denied != nil {
+		return denied
+	}
+
+	s.tasksMu.Lock()
+	defer s.tasksMu.Unlock()
+
+	task := &storage.Task{
+		State:        storage.ReadyForPlan,
+		Priority:     5,
+		Tags:         json.RawMessage("[]"),
+		Dependencies: json.RawMessage("[]"),
+		BlockedBy:    json.RawMessage("[]"),
+	}
+	if _, err := s.applyTaskEdits(task, params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid task", err.Error())
+	}
+	if err := s.store.CreateTask(task); err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to create task", err.Error())
+	}
+
+	note := "Created through the MCP API"
+	if editor.taskID != "" {
+		note = fmt.Sprintf("Created by %s while working on task %s", editor.actor, editor.taskID)
+	}
+	editor.audit(s.store, task, "", ResultTaskCreated, note)
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"success": true,
+		"task":    task,
+	})
+}
+
+// handleUpdateTask handles baton.tasks.update, which changes the given fields
+// of a task and leaves the others as they are. It is not task-scoped: the
+// task to edit is always named.
+func (s *Server) handleUpdateTask(req *JSONRPCRequest) *JSONRPCResponse {
+	var params TaskUpdateParams
+	if err := req.Bind(&params); err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid parameters", err.Error())
+	}
+	taskID := params.TaskID
+	if taskID == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing task_id parameter", nil)
+	}
+
+	editor, denied := s.taskEditorFor(req)
+	if denied != nil {
+		return denied
+	}
+
+	s.tasksMu.Lock()
+	defer s.tasksMu.Unlock()
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		return NewJSONRPCError(req.ID, ResourceNotFound, "Task not found", map[string]interface{}{"task_id": taskID})
+	}
+	changed, err := s.applyTaskEdits(task, params.TaskEditParams)
+	if err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid task", err.Error())
+	}
+	if len(changed) == 0 {
+		return NewJSONRPCError(req.ID, InvalidParams, "Nothing to update",
+			map[string]interface{}{"fields": taskEditFields})
+	}
+	if err := s.store.UpdateTaskBy(task, editor.actor); err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to update task", err.Error())
+	}
+	editor.audit(s.store, task, task.State, ResultTaskUpdated, "Changed "+strings.Join(changed, ", "))
+
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"success": true,
+		"task":    task,
+		"changed": changed,
+	})
+}
+
+// applyTaskEdits sets the task fields given in params, after checking them,
+// and returns the names of those that were given. The task is left as it was
+// when any of them is invalid.
+func (s *Server) applyTaskEdits(task *storage.Task, params TaskEditParams) ([]string, error) {
+	edited := *task
+	var changed []string
+
+	if params.Title != nil {
+		if strings.TrimSpace(*params.Title) == "" {
+			return nil, fmt.Errorf("title must be a non-empty string")
+		}
+		edited.Title = *params.Title
+		changed = append(changed, "title")
+	}
+	if params.Description != nil {
+		edited.Description = *params.Description
+		changed = append(changed, "description")
+	}
+	// The schema keeps priority from 1 to 10
+	if params.Priority != nil {
+		edited.Priority = *params.Priority
+		changed = append(changed, "priority")
+	}
+	if params.Owner != nil {
+		edited.Owner = *params.Owner
+		changed = append(changed, "owner")
+	}
+	if params.Tags != nil {
+		if err := checkList("tags", params.Tags); err != nil {
+			return nil, err
+		}
+		edited.Tags, _ = json.Marshal(params.Tags)
+		changed = append(changed, "tags")
+	}
+	if params.Dependencies != nil {
+		if err := checkList("dependencies", params.Dependencies); err != nil {
+			return nil, err
+		}
+		if err := s.checkDependencies(edited.ID, params.Dependencies); err != nil {
+			return nil, err
+		}
+		edited.Dependencies, _ = json.Marshal(params.Dependencies)
+		// blocked_by is kept in step with dependencies
+		edited.BlockedBy, _ = json.Marshal(params.Dependencies)
+		changed = append(changed, "dependencies")
+	}
+	if params.ParentID != nil {
+		parentID := *params.ParentID
+		if parentID != "" {
+			if parentID == edited.ID {
+				return nil, fmt.Errorf("a task can't be grouped under itself")
+			}
+			if _, err := s.store.GetTask(parentID); err != nil {
+				return nil, fmt.Errorf("parent task %s not found", parentID)
+			}
+		}
+		edited.ParentID = parentID
+		changed = append(changed, "parent_id")
+	}
+	if params.EstimatedHours != nil {
+		if *params.EstimatedHours < 0 {
+			return nil, fmt.Errorf("estimated_hours must be a non-negative number")
+		}
+		edited.EstimatedHours = *params.EstimatedHours
+		changed = append(changed, "estimated_hours")
+	}
+	if params.DueDate != nil {
+		if *params.DueDate == "" {
+			edited.DueDate = nil
+		} else {
+			due, err := storage.ParseDueDate(*params.DueDate)
+			if err != nil {
+				return nil, fmt.Errorf("due_date must be YYYY-MM-DD or RFC 3339, got %q", *params.DueDate)
+			}
+			edited.DueDate = &due
+		}
+		changed = append(changed, "due_date")
+	}
+	if params.CustomFields != nil {
+		for name, value := range params.CustomFields {
+			checked, err := s.config.CustomFields.Value(name, value)
+			if err != nil {
+				return nil, err
+			}
+			edited.SetCustomField(name, checked)
+		}
+		changed = append(changed, "custom_fields")
+	}
+
+	*task = edited
+	return changed, nil
+}
+
+// checkDependencies checks that every dependency exists and that depending on
+// them wouldn't make tasks wait on each other forever. taskID is "" for a task
+// not created yet, which nothing can depend on.
+func (s *Server) checkDependencies(taskID string, deps []string) error {
+	var graph *statemachine.DependencyGraph
+	for _, dep := range deps {
+		if _, err := s.store.GetTask(dep); err != nil {
+			return fmt.Errorf("dependency task %s not found", dep)
+		}
+		if taskID == "" {
+			continue
+		}
+		if graph == nil {
+			var err error
+			if graph, err = statemachine.LoadDependencyGraph(s.store); err != nil {
+				return err
+			}
+		}
+		if err := graph.CheckEdge(taskID, dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkList checks that a list param names no empty strings
+func checkList(field string, list []string) error {
+	for _, item := range list {
+		if item == "" {
+			return fmt.Errorf("%s must be an array of non-empty strings", field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"baton/internal/search"
+)
+
+// Tool is a baton method offered to MCP clients through tools/list
+type Tool struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// methodTool describes the tool a baton method is offered as
+type methodTool struct {
+	title       string
+	description string
+	schema      map[string]interface{} // JSON Schema of the method's params
+}
+
+// taskIDProperty is the task_id of task-scoped methods, which may be left out
+// during a cycle
+var taskIDProperty = property("string", "Task ID; defaults to the current cycle's task")
+
+// methodTools are the tools of the baton methods, by method
+var methodTools = map[string]methodTool{
+	"baton.tasks.get_next": {"Get next task", "Select the next task to work on, with its artifacts",
+		object(nil)},
+	"baton.tasks.get": {"Get task", "Get a task with its state, description, notes and artifacts",
+		object(map[string]interface{}{"task_id": taskIDProperty})},
+	"baton.tasks.update_state": {"Update task state", "Move a task to its next state when the work for the current state is done; handovers the transition requires must exist first",
+		object(map[string]interface{}{
+			"task_id": taskIDProperty,
+			"state":   property("string", "Next state"),
+			"note":    property("string", "What was done"),
+		}, "state")},
+	"baton.tasks.check_transition": {"Check transition", "Check what moving a task to a state needs, without moving it",
+		object(map[string]interface{}{
+			"task_id": taskIDProperty,
+			"state":   property("string", "State to check"),
+		}, "state")},
+	"baton.tasks.append_note": {"Append note", "Append a note to a task without changing its state",
+		object(map[string]interface{}{
+			"task_id": taskIDProperty,
+			"note":    property("string", "Note text"),
+			"author":  property("string", "Who wrote the note"),
+		}, "note")},
+	"baton.tasks.set_fields": {"Set custom fields", "Set a task's custom fields, as configured in custom_fields",
+		object(map[string]interface{}{
+			"task_id": taskIDProperty,
+			"fields":  property("object", "Values by field name; null clears a field"),
+		}, "fields")},
+	"baton.tasks.list": {"List tasks", "List tasks matching filters, a page at a time",
+		object(map[string]interface{}{
+			"state":         property("string", "Only tasks in this state"),
+			"priority":      property("integer", "Only tasks of this priority"),
+			"owner":         property("string", "Only tasks of this owner"),
+			"tags":          arrayProperty("string", "Only tasks with all of these tags"),
+			"custom_fields": property("object", "Only tasks with these custom field values"),
+			"parent_id":     property("string", "Only subtasks of this task"),
+			"archived":      property("boolean", "List archived tasks instead"),
+			"due_before":    property("string", "Only tasks due before this date (YYYY-MM-DD)"),
+			"sort":          property("string", "Field to sort by"),
+			"reverse":       property("boolean", "Reverse the sort order"),
+			"cursor":        property("string", "Cursor of the next page, from the last page"),
+			"limit":         atLeast(property("integer", "Tasks per page"), 0),
+			"offset":        atLeast(property("integer", "Tasks to skip"), 0),
+		})},
+	"baton.tasks.delete": {"Delete task", "Move a task to the trash",
+		object(map[string]interface{}{"task_id": property("string", "Task ID")}, "task_id")},
+	"baton.tasks.create": {"Create task", "File a new task in ready_for_plan, such as follow-up work found while working on another",
+		object(taskEditProperties(nil), "title")},
+	"baton.tasks.update": {"Update task", "Change fields of a task; the state is changed with update_state",
+		object(taskEditProperties(map[string]interface{}{"task_id": property("string", "Task ID")}), "task_id")},
+	"baton.tasks.add_dependency": {"Add dependency", "Make a task depend on another; edges that would form a dependency cycle are refused",
+		object(map[string]interface{}{
+			"task_id":    taskIDProperty,
+			"depends_on": property("string", "ID of the task it must wait for"),
+		}, "depends_on")},
+	"baton.tasks.remove_dependency": {"Remove dependency", "Drop a task's dependency on another",
+		object(map[string]interface{}{
+			"task_id":    taskIDProperty,
+			"depends_on": property("string", "ID of the task it no longer waits for"),
+		}, "depends_on")},
+	"baton.tasks.graph": {"Dependency graph", "The task dependency graph, in dependency order; with task_id only the tasks connected to it",
+		object(map[string]interface{}{"task_id": property("string", "Only the tasks this one depends on or that depend on it")})},
+	"baton.tasks.stale": {"List stale tasks", "List the tasks stuck in a work state longer than the staleness threshold",
+		object(nil)},
+	"baton.tasks.search": {"Search tasks", "Search tasks, artifacts, requirements and audit notes",
+		searchSchema()},
+	"baton.search": {"Search", "Search tasks, artifacts, requirements and audit notes",
+		searchSchema()},
+	"baton.artifacts.upsert": {"Upsert artifact", "Create or update a task artifact, such as a plan, implementation notes or a review, as a new version",
+		object(map[string]interface{}{
+			"task_id": taskIDProperty,
+			"name":    property("string", "Artifact name"),
+			"content": property("string", "Artifact content"),
+			"meta":    property("object", "Structured metadata, checked by transition_rules"),
+		}, "name", "content")},
+	"baton.artifacts.get": {"Get artifact", "Get a version of a task artifact, the latest by default",
+		object(map[string]interface{}{
+			"task_id": taskIDProperty,
+			"name":    property("string", "Artifact name"),
+			"version": atLeast(property("integer", "Version; the latest when left out"), 1),
+		}, "name")},
+	"baton.artifacts.list": {"List artifacts", "List a task's artifacts",
+		object(map[string]interface{}{"task_id": taskIDProperty})},
+	"baton.artifacts.read": {"Read artifact", "Read part of a large artifact's content",
+		object(map[string]interface{}{
+			"task_id": taskIDProperty,
+			"name":    property("string", "Artifact name"),
+			"version": atLeast(property("integer", "Version; the latest when left out"), 1),
+			"offset":  atLeast(property("integer", "Byte to start reading at"), 0),
+			"limit":   between(property("integer", "Bytes to read; 64 KiB when left out"), 1, maxReadChunk),
+		}, "name")},
+	"baton.milestones.list": {"List milestones", "Progress of every milestone",
+		object(nil)},
+	"baton.milestones.progress": {"Milestone progress", "Remaining work in a milestone",
+		object(map[string]interface{}{"milestone": property("string", "Milestone name")}, "milestone")},
+	"baton.cycle.current": {"Current cycle", "Get the cycle and task currently being worked on",
+		object(nil)},
+	"baton.commands.run": {"Run command", "Run an allowed command in the cycle's working directory, without a shell",
+		object(map[string]interface{}{
+			"command":         property("string", "Command to run, as listed in security.allowed_commands"),
+			"args":            arrayProperty("string", "Arguments, passed as they are"),
+			"dir":             property("string", "Directory relative to the working directory"),
+			"timeout_seconds": atLeast(property("integer", "Timeout; 5 minutes when left out"), 1),
+		}, "command")},
+	"baton.requirements.list": {"List requirements", "List requirements",
+		object(map[string]interface{}{"type": property("string", "Only requirements of this type")})},
+	"baton.requirements.create": {"Create requirement", "Add a requirement; its key is allocated for you",
+		object(map[string]interface{}{
+			"title":  property("string", "Requirement title"),
+			"text":   property("string", "Requirement text; the title when left out"),
+			"type":   property("string", "Requirement type, functional by default"),
+			"prefix": property("string", "Key prefix, e.g. REQ-AUTH"),
+		}, "title")},
+	"baton.plan.read": {"Read plan", "Read the project plan",
+		object(nil)},
+}
+
+// taskEditProperties are the task fields baton.tasks.create and
+// baton.tasks.update take, with the extra properties given
+func taskEditProperties(extra map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{
+		"title":           property("string", "Task title"),
+		"description":     property("string", "What the task is about"),
+		"priority":        between(property("integer", "Priority from 1 to 10; 5 for a new task when left out"), 1, 10),
+		"owner":           property("string", "Owner"),
+		"tags":            arrayProperty("string", "Tags, replacing the task's"),
+		"dependencies":    arrayProperty("string", "IDs of the tasks it depends on, replacing the task's"),
+		"parent_id":       property("string", "Task to group it under; \"\" for none"),
+		"estimated_hours": property("number", "Estimated hours of work"),
+		"due_date":        property("string", "Due date (YYYY-MM-DD); \"\" for none"),
+		"custom_fields":   property("object", "Custom field values by field name"),
+	}
+	for name, schema := range extra {
+		properties[name] = schema
+	}
+	return properties
+}
+
+// searchSchema is the schema of the search methods' params
+func searchSchema() map[string]interface{} {
+	return object(map[string]interface{}{
+		"query": property("string", "What to search for"),
+		"mode":  enumProperty("keyword (default) or semantic", search.ModeKeyword, search.ModeSemantic),
+		"filters": object(map[string]interface{}{
+			"state": property("string", "Only tasks in this state"),
+			"owner": property("string", "Only tasks of this owner"),
+			"kind":  property("string", "Only hits of this kind, e.g. task or artifact"),
+		}),
+		"limit": atLeast(property("integer", "Most hits to return"), 0),
+	}, "query")
+}
+
+// object builds the schema of an object that has only the properties given
+func object(properties map[string]interface{}, required ...string) map[string]interface{} {
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openObject lets an object schema's value have properties it doesn't list
+func openObject(schema map[string]interface{}) map[string]interface{} {
+	schema["additionalProperties"] = true
+	return schema
+}
+
+// property builds the schema of a property
+func property(kind, description string) map[string]interface{} {
+	return map[string]interface{}{"type": kind, "description": description}
+}
+
+// arrayProperty builds the schema of an array property
+func arrayProperty(items, description string) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": items}, "description": description}
+}
+
+// enumProperty builds the schema of a string property that takes one of values
+func enumProperty(description string, values ...string) map[string]interface{} {
+	schema := property("string", description)
+	schema["enum"] = values
+	return schema
+}
+
+// atLeast sets the lowest value a number property's schema allows
+func atLeast(schema map[string]interface{}, minimum float64) map[string]interface{} {
+	schema["minimum"] = minimum
+	return schema
+}
+
+// between sets the range of values a number property's schema allows
+func between(schema map[string]interface{}, minimum, maximum float64) map[string]interface{} {
+	schema["maximum"] = maximum
+	return atLeast(schema, minimum)
+}
+
+// toolName is the name a baton method is offered as. Tool names avoid dots,
+// which some clients refuse.
+func toolName(method string) string {
+	return strings.ReplaceAll(method, ".", "_")
+}
+
+// toolMethod returns the baton method a tool calls, by its name or the
+// method's own
+func (s *Server) toolMethod(name string) (string, bool) {
+	for method := range methodTools {
+		if name == toolName(method) || name == method {
+			_, registered := s.handlers[method]
+			return method, registered
+		}
+	}
+	return "", false
+}
+
+// Tools returns the tools of the registered baton methods, by name
+func (s *Server) Tools() []Tool {
+	var tools []Tool
+	for method, def := range methodTools {
+		if _, ok := s.handlers[method]; !ok {
+			continue
+		}
+		tools = append(tools, Tool{Name: toolName(method), Title: def.title, Description: def.description, InputSchema: def.schema})
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// handleToolsList handles tools/list. Every tool fits on one page, so the
+// cursor is ignored.
+func (s *Server) handleToolsList(req *JSONRPCRequest) *JSONRPCResponse {
+	return NewJSONRPCResponse(req.ID, map[string]interface{}{
+		"tools": s.Tools(),
+	})
+}
+
+// handleToolsCall handles tools/call by calling the tool's baton method with
+// its arguments. The method's result comes back as JSON text, and its errors
+// as a result marked isError, so the model sees them.
+func (s *Server) handleToolsCall(req *JSONRPCRequest) *JSONRPCResponse {
+	params, err := req.GetParams()
+	if err != nil {
+		return NewJSONRPCError(req.ID, InvalidParams, "Invalid tools/call parameters", nil)
+	}
+	name, _ := params["name"].(string)
+	if name == "" {
+		return NewJSONRPCError(req.ID, InvalidParams, "Missing name parameter", nil)
+	}
+	method, ok := s.toolMethod(name)
+	if !ok {
+		return NewJSONRPCError(req.ID, ToolNotFound, fmt.Sprintf("Unknown tool: %s", name), nil)
+	}
+
+	arguments := map[string]interface{}{}
+	if raw, exists := params["arguments"]; exists && raw != nil {
+		if arguments, ok = raw.(map[string]interface{}); !ok {
+			return NewJSONRPCError(req.ID, InvalidParams, "Tool arguments must be an object", nil)
+		}
+	}
+
+	call := &JSONRPCRequest{JSONRPC: "2.0", Method: method, Params: arguments, ID: req.ID}
+	s.applyScope(call, req.cycleID)
+	response := checkParams(call)
+	if response == nil {
+		response = s.handlers[method](call)
+	}
+
+	if response.Error != nil {
+		text := response.Error.Message
+		if response.Error.Data != nil {
+			if data, err := json.Marshal(response.Error.Data); err == nil {
+				text += ": " + string(data)
+			}
+		}
+		return NewJSONRPCResponse(req.ID, toolResult(text, true))
+	}
+	data, err := json.Marshal(response.Result)
+	if err != nil {
+		return NewJSONRPCError(req.ID, InternalError, "Failed to encode tool result", err.Error())
+	}
+	return NewJSONRPCResponse(req.ID, toolResult(string(data), false))
+}
+
+// toolResult is a tools/call result of text content
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// FieldError is a param that does not match its method's schema, returned in
+// the data of InvalidParams errors
+type FieldError struct {
+	Field   string `json:"field,omitempty"` // path of the param, e.g. filters.kind or tags[1]
+	Message string `json:"message"`
+}
+
+// protocolSchemas are the JSON Schemas of the params of the MCP methods that
+// take any, by method; the baton methods' schemas are their tools'. They are
+// left open, as later protocol versions add params.
+var protocolSchemas = map[string]map[string]interface{}{
+	"tools/call": openObject(object(map[string]interface{}{
+		"name":      property("string", "Tool name"),
+		"arguments": property("object", "Tool arguments"),
+	}, "name")),
+	"resources/read":        openObject(object(map[string]interface{}{"uri": property("string", "Resource URI")}, "uri")),
+	"resources/subscribe":   openObject(object(map[string]interface{}{"uri": property("string", "Resource URI")}, "uri")),
+	"resources/unsubscribe": openObject(object(map[string]interface{}{"uri": property("string", "Resource URI")}, "uri")),
+	"prompts/get": openObject(object(map[string]interface{}{
+		"name":      property("string", "Prompt name"),
+		"arguments": property("object", "Prompt arguments"),
+	}, "name")),
+}
+
+// metaParam is the param MCP reserves for request metadata, which any
+// method's params may carry
+const metaParam = "_meta"
+
+// methodSchema returns the JSON Schema of a method's params, if it has one
+func methodSchema(method string) (map[string]interface{}, bool) {
+	if tool, ok := methodTools[method]; ok {
+		return tool.schema, true
+	}
+	schema, ok := protocolSchemas[method]
+	return schema, ok
+}
+
+// checkParams validates a request's params against its method's schema and
+// returns the InvalidParams error naming every mismatch, or nil when they
+// match. Params left out count as an empty object.
+func checkParams(req *JSONRPCRequest) *JSONRPCResponse {
+	schema, ok := methodSchema(req.Method)
+	if !ok {
+		return nil
+	}
+	params := req.Params
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	errs := validateValue(schema, params, "")
+	if len(errs) == 0 {
+		return nil
+	}
+	first := errs[0]
+	message := "Invalid params: " + first.Message
+	if first.Field != "" {
+		message = fmt.Sprintf("Invalid params: %s %s", first.Field, first.Message)
+	}
+	return NewJSONRPCError(req.ID, InvalidParams, message, map[string]interface{}{"errors": errs})
+}
+
+// validateValue checks a decoded JSON value against a schema, supporting the
+// keywords the method schemas use: type, properties, required,
+// additionalProperties, items, enum, minimum and maximum. Properties the
+// schema doesn't list are refused when additionalProperties is false.
+func validateValue(schema map[string]interface{}, value interface{}, path string) []FieldError {
+	kind, _ := schema["type"].(string)
+	if kind != "" && !hasType(value, kind) {
+		return []FieldError{{Field: path, Message: "must be " + article(kind)}}
+	}
+
+	var errs []FieldError
+	if values, ok := schema["enum"].([]string); ok && !enumContains(values, value) {
+		errs = append(errs, FieldError{Field: path, Message: "must be one of " + strings.Join(values, ", ")})
+	}
+	if number, ok := value.(float64); ok {
+		if minimum, ok := schema["minimum"].(float64); ok && number < minimum {
+			errs = append(errs, FieldError{Field: path, Message: fmt.Sprintf("must be at least %v", minimum)})
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && number > maximum {
+			errs = append(errs, FieldError{Field: path, Message: fmt.Sprintf("must be at most %v", maximum)})
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, exists := v[name]; !exists {
+				errs = append(errs, FieldError{Field: join(path, name), Message: "is required"})
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		closed := schema["additionalProperties"] == false
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok && closed && name != metaParam {
+				errs = append(errs, FieldError{Field: join(path, name), Message: "is not a known param"})
+				continue
+			}
+			// null stands for a param left out
+			if !ok || v[name] == nil {
+				continue
+			}
+			errs = append(errs, validateValue(property, v[name], join(path, name))...)
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				errs = append(errs, validateValue(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+// hasType reports whether a decoded JSON value is of a JSON Schema type
+func hasType(value interface{}, kind string) bool {
+	switch kind {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	}
+	return true
+}
+
+// enumContains reports whether value is one of the allowed strings
+func enumContains(values []string, value interface{}) bool {
+	s, ok := value.(string)
+	return ok && containsString(values, s)
+}
+
+// article names a JSON Schema type for an error message
+func article(kind string) string {
+	switch kind {
+	case "object", "array", "integer":
+		return "an " + kind
+	}
+	return "a " + kind
+}
+
+// join appends a property name to a param path
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		params interface{}
+		errors []FieldError // nil when the params are valid
+	}{
+		{"valid", "baton.tasks.update_state",
+			map[string]interface{}{"task_id": "T-1", "state": "implementing"}, nil},
+		{"no params", "baton.tasks.get_next", nil, nil},
+		{"missing required", "baton.tasks.update_state",
+			map[string]interface{}{"task_id": "T-1"},
+			[]FieldError{{Field: "state", Message: "is required"}}},
+		{"every missing required", "baton.artifacts.upsert",
+			map[string]interface{}{},
+			[]FieldError{{Field: "name", Message: "is required"}, {Field: "content", Message: "is required"}}},
+		{"wrong type", "baton.tasks.list",
+			map[string]interface{}{"limit": "ten"},
+			[]FieldError{{Field: "limit", Message: "must be an integer"}}},
+		{"fraction for integer", "baton.tasks.list",
+			map[string]interface{}{"priority": 1.5},
+			[]FieldError{{Field: "priority", Message: "must be an integer"}}},
+		{"wrong item type", "baton.tasks.list",
+			map[string]interface{}{"tags": []interface{}{"api", 1.0}},
+			[]FieldError{{Field: "tags[1]", Message: "must be a string"}}},
+		{"enum violation", "baton.search",
+			map[string]interface{}{"query": "login", "mode": "fuzzy"},
+			[]FieldError{{Field: "mode", Message: "must be one of keyword, semantic"}}},
+		{"below minimum", "baton.tasks.list",
+			map[string]interface{}{"offset": -1.0},
+			[]FieldError{{Field: "offset", Message: "must be at least 0"}}},
+		{"above maximum", "baton.tasks.create",
+			map[string]interface{}{"title": "Fix login", "priority": 11.0},
+			[]FieldError{{Field: "priority", Message: "must be at most 10"}}},
+		{"unknown field", "baton.tasks.get",
+			map[string]interface{}{"task_id": "T-1", "taskid": "T-2"},
+			[]FieldError{{Field: "taskid", Message: "is not a known param"}}},
+		{"unknown nested field", "baton.search",
+			map[string]interface{}{"query": "login", "filters": map[string]interface{}{"status": "done"}},
+			[]FieldError{{Field: "filters.status", Message: "is not a known param"}}},
+		{"state on update", "baton.tasks.update",
+			map[string]interface{}{"task_id": "T-1", "state": "done"},
+			[]FieldError{{Field: "state", Message: "is not a known param"}}},
+		{"meta", "baton.tasks.get",
+			map[string]interface{}{"task_id": "T-1", "_meta": map[string]interface{}{"progressToken": 1.0}}, nil},
+		{"null for left out", "baton.tasks.list",
+			map[string]interface{}{"state": nil}, nil},
+		{"params not an object", "baton.tasks.get",
+			[]interface{}{"T-1"},
+			[]FieldError{{Message: "must be an object"}}},
+		{"open protocol params", "tools/call",
+			map[string]interface{}{"name": "baton_tasks_get", "task": map[string]interface{}{}}, nil},
+		{"protocol params", "resources/read",
+			map[string]interface{}{},
+			[]FieldError{{Field: "uri", Message: "is required"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := checkParams(&JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: tt.method, Params: tt.params})
+			if tt.errors == nil {
+				if response != nil {
+					t.Fatalf("Expected valid params, got %s: %v", response.Error.Message, response.Error.Data)
+				}
+				return
+			}
+			if response == nil || response.Error == nil {
+				t.Fatal("Expected invalid params to be refused")
+			}
+			if response.Error.Code != InvalidParams {
+				t.Errorf("Expected code %d, got %d", InvalidParams, response.Error.Code)
+			}
+			data, _ := response.Error.Data.(map[string]interface{})
+			if got := data["errors"]; !reflect.DeepEqual(got, tt.errors) {
+				t.Errorf("Expected errors %v, got %v", tt.errors, got)
+			}
+		})
+	}
+}
+
+func TestCheckParamsMessage(t *testing.T) {
+	response := checkParams(&JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "baton.tasks.list",
+		Params: map[string]interface{}{"limit": -1.0}})
+	if response == nil {
+		t.Fatal("Expected invalid params to be refused")
+	}
+	if want := "Invalid params: limit must be at least 0"; response.Error.Message != want {
+		t.Errorf("Expected message %q, got %q", want, response.Error.Message)
+	}
+}
@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+// Channel types a notification can be delivered on
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeDesktop = "desktop"
+)
+
+// queueSize is how many events an attached notifier holds for delivery;
+// events arriving while it is full are dropped
+const queueSize = 256
+
+// Notifier delivers task events to the watchers subscribed to that task,
+// each on the channel they chose
+type Notifier struct {
+	store  *storage.Store
+	cfg    config.NotificationsConfig
+	client *http.Client
+
+	escalationChannel string // also told about every escalation
+
+	// Events waiting for the delivery goroutine of an attached notifier
+	mu          sync.Mutex
+	queue       chan storage.TaskEvent
+	closed      bool
+	done        chan struct{}
+	unsubscribe func()
+}
+
+// NewNotifier creates a notifier for the channels in cfg
+func NewNotifier(store *storage.Store, cfg config.NotificationsConfig) *Notifier {
+	return &Notifier{
+		store:  store,
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Attach creates a notifier and subscribes it to the store's events. Events
+// are queued and delivered on a goroutine of its own, so a slow channel never
+// holds up the write that caused them; Close delivers what is still queued.
+func Attach(store *storage.Store, cfg *config.Config) *Notifier {
+	n := NewNotifier(store, cfg.Notifications)
+	n.escalationChannel = cfg.ReviewEscalation.Channel
+	n.queue = make(chan storage.TaskEvent, queueSize)
+	n.done = make(chan struct{})
+	go n.run()
+	n.unsubscribe = store.Subscribe(n.enqueue)
+	return n
+}
+
+// Close stops taking events and waits until the queued ones are delivered
+func (n *Notifier) Close() {
+	if n.unsubscribe == nil {
+		return
+	}
+	n.unsubscribe()
+
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	<-n.done
+}
+
+// enqueue queues an event worth a notification, dropping it when the queue
+// is full
+func (n *Notifier) enqueue(event storage.TaskEvent) {
+	if !notifies(event.Kind) {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- event:
+	default:
+		log.Printf("Dropped a notification about task %s: %d are already waiting", event.TaskID, queueSize)
+	}
+}
+
+// run delivers queued events until the queue is closed
+func (n *Notifier) run() {
+	defer close(n.done)
+	for event := range n.queue {
+		n.Handle(event)
+	}
+}
+
+// notifies reports whether events of kind lead to notifications
+func notifies(kind string) bool {
+	switch kind {
+	case storage.EventTransition, storage.EventArtifact, storage.EventCycleFailed, storage.EventEscalated:
+		return true
+	}
+	return false
+}
+
+// Channel resolves a channel name, "" meaning the default channel. The name
+// "desktop" works without being configured.
+func (n *Notifier) Channel(name string) (config.NotificationChannel, error) {
+	if name == "" {
+		name = n.cfg.DefaultChannel
+	}
+	if channel, ok := n.cfg.Channels[name]; ok {
+		return channel, nil
+	}
+	if name == TypeDesktop {
+		return config.NotificationChannel{Type: TypeDesktop}, nil
+	}
+	if name == "" {
+		return config.NotificationChannel{}, fmt.Errorf("no notification channel given and notifications.default_channel is not set")
+	}
+	return config.NotificationChannel{}, fmt.Errorf("unknown notification channel %q", name)
+}
+
+// Handle notifies every watcher of the event's task about transitions, new
+// artifacts and failed cycles; other edits are not worth a notification.
+// Escalations, which watchers hear about as transitions, go to the escalation
+// channel. Delivery failures are logged rather than returned, so a broken
+// channel never fails the write that caused the event.
+func (n *Notifier) Handle(event storage.TaskEvent) {
+	switch event.Kind {
+	case storage.EventTransition, storage.EventArtifact, storage.EventCycleFailed:
+	case storage.EventEscalated:
+		n.escalate(event)
+		return
+	default:
+		return
+	}
+
+	watches, err := n.store.ListTaskWatches(event.TaskID)
+	if err != nil {
+		log.Printf("Failed to list watchers of task %s: %v", event.TaskID, err)
+		return
+	}
+	if len(watches) == 0 {
+		return
+	}
+
+	title := event.TaskID
+	if task, err := n.store.GetTask(event.TaskID); err == nil {
+		title = task.Title
+	}
+	message := Message(event, title)
+
+	for _, watch := range watches {
+		if err := n.deliver(watch, event, message); err != nil {
+			log.Printf("Failed to notify %s about task %s: %v", watch.Watcher, event.TaskID, err)
+		}
+	}
+}
+
+// escalate tells the escalation channel, if one is configured, about an escalated task
+func (n *Notifier) escalate(event storage.TaskEvent) {
+	if n.escalationChannel == "" {
+		return
+	}
+
+	title := event.TaskID
+	if task, err := n.store.GetTask(event.TaskID); err == nil {
+		title = task.Title
+	}
+	watch := &storage.TaskWatch{TaskID: event.TaskID, Watcher: "escalation", Channel: n.escalationChannel}
+	if err := n.deliver(watch, event, Message(event, title)); err != nil {
+		log.Printf("Failed to notify %s about the escalation of task %s: %v", n.escalationChannel, event.TaskID, err)
+	}
+}
+
+// deliver sends one notification to one watcher
+func (n *Notifier) deliver(watch *storage.TaskWatch, event storage.TaskEvent, message string) error {
+	channel, err := n.Channel(watch.Channel)
+	if err != nil {
+		return err
+	}
+
+	switch channel.Type {
+	case TypeWebhook:
+		return n.post(channel.URL, map[string]interface{}{
+			"watcher": watch.Watcher,
+			"message": message,
+			"event":   event,
+		})
+	case TypeSlack:
+		return n.post(channel.URL, map[string]string{"text": message})
+	case TypeDesktop:
+		return desktop("Baton", message)
+	default:
+		return fmt.Errorf("unsupported channel type %q", channel.Type)
+	}
+}
+
+// post sends body as JSON to url
+func (n *Notifier) post(url string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// desktop shows a notification on the local desktop
+func desktop(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", message, title))
+	case "linux":
+		cmd = exec.Command("notify-send", title, message)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	return cmd.Run()
+}
+
+// Message describes an event in one line
+func Message(event storage.TaskEvent, title string) string {
+	var message string
+	switch event.Kind {
+	case storage.EventTransition:
+		message = fmt.Sprintf("%q moved %s -> %s", title, event.PrevState, event.NextState)
+	case storage.EventArtifact:
+		message = fmt.Sprintf("%q has a new artifact: %s v%d", title, event.Artifact, event.Version)
+	case storage.EventCycleFailed:
+		message = fmt.Sprintf("%q cycle ended with %s", title, event.Result)
+	case storage.EventEscalated:
+		message = fmt.Sprintf("%q needs attention: moved %s -> %s", title, event.PrevState, event.NextState)
+	default:
+		message = fmt.Sprintf("%q: %s", title, event.Kind)
+	}
+	if event.Note != "" {
+		message += ": " + event.Note
+	}
+	return message
+}
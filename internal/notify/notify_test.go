@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+func TestAttachDeliversOffTheWritePath(t *testing.T) {
+	release := make(chan struct{})
+	var delivered atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		delivered.Add(1)
+	}))
+	defer hook.Close()
+
+	store, err := storage.NewStore(filepath.Join(t.TempDir(), "notify.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &storage.Task{Title: "Watched", State: storage.ReadyForPlan}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.WatchTask(&storage.TaskWatch{TaskID: task.ID, Watcher: "ops", Channel: "hook"}); err != nil {
+		t.Fatalf("Failed to watch task: %v", err)
+	}
+
+	cfg := &config.Config{Notifications: config.NotificationsConfig{
+		Channels: map[string]config.NotificationChannel{"hook": {Type: TypeWebhook, URL: hook.URL}},
+	}}
+	notifier := Attach(store, cfg)
+
+	// The webhook hangs until released, which must not hold up the write
+	start := time.Now()
+	if err := store.UpdateTaskState(task.ID, storage.Planning, "start"); err != nil {
+		t.Fatalf("Failed to update task state: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Expected the write not to wait for delivery, took %s", elapsed)
+	}
+	if delivered.Load() != 0 {
+		t.Fatal("Expected delivery to still be pending")
+	}
+
+	// Close waits for what is queued
+	close(release)
+	notifier.Close()
+	if got := delivered.Load(); got != 1 {
+		t.Errorf("Expected 1 delivery after Close, got %d", got)
+	}
+
+	// Events after Close are ignored rather than sent on a closed queue
+	if err := store.UpdateTaskState(task.ID, storage.ReadyForImplementation, "planned"); err != nil {
+		t.Fatalf("Failed to update task state after Close: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// UnavailableError reports a plan file agents cannot use
+type UnavailableError struct {
+	Path   string
+	Reason string
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("plan file %s is unavailable: %s", e.Path, e.Reason)
+}
+
+// Status is whether the plan file can be served to agents, for status reports
+type Status struct {
+	Path      string    `json:"path"`
+	Available bool      `json:"available"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Check returns an *UnavailableError when the plan file is missing,
+// unreadable, empty or fails to parse
+func Check(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		reason := err.Error()
+		if os.IsNotExist(err) {
+			reason = "file not found"
+		}
+		return &UnavailableError{Path: path, Reason: reason}
+	}
+	if !utf8.Valid(content) {
+		return &UnavailableError{Path: path, Reason: "not valid UTF-8 text"}
+	}
+	if strings.TrimSpace(string(content)) == "" {
+		return &UnavailableError{Path: path, Reason: "file is empty"}
+	}
+	if _, _, err := NewParser().Parse(path); err != nil {
+		return &UnavailableError{Path: path, Reason: err.Error()}
+	}
+	return nil
+}
+
+// CheckStatus runs Check and reports the result as a Status
+func CheckStatus(path string) *Status {
+	status := &Status{Path: path, Available: true, CheckedAt: time.Now()}
+	if err := Check(path); err != nil {
+		status.Available = false
+		status.Error = err.(*UnavailableError).Reason
+	}
+	return status
+}
@@ -0,0 +1,70 @@
+package plan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// requirementKeyPattern matches requirement keys such as FR-1, FR-P1 or NFR-12
+var requirementKeyPattern = regexp.MustCompile(`\b[A-Z]{2,4}-[A-Z]?\d+\b`)
+
+// CitedKeys returns the distinct requirement keys referenced in content
+func CitedKeys(content string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+
+	for _, key := range requirementKeyPattern.FindAllString(content, -1) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// MissingCitations returns the requirement keys that content does not cite
+func MissingCitations(content string, keys []string) []string {
+	cited := make(map[string]bool)
+	for _, key := range CitedKeys(content) {
+		cited[key] = true
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if !cited[key] {
+			missing = append(missing, key)
+		}
+	}
+
+	return missing
+}
+
+// UncitedSections returns the headings of markdown sections whose body does
+// not cite any requirement key, i.e. work that cannot be traced to the plan
+func UncitedSections(content string) []string {
+	var uncited []string
+	heading := ""
+	var body []string
+
+	flush := func() {
+		if heading != "" && strings.TrimSpace(strings.Join(body, "\n")) != "" &&
+			!requirementKeyPattern.MatchString(heading+"\n"+strings.Join(body, "\n")) {
+			uncited = append(uncited, heading)
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "## ") {
+			flush()
+			heading = strings.TrimSpace(strings.TrimPrefix(trimmed, "## "))
+			body = nil
+			continue
+		}
+		body = append(body, trimmed)
+	}
+	flush()
+
+	return uncited
+}
@@ -0,0 +1,62 @@
+// Package redact masks secrets in text written outside the database, such as
+// LLM transcripts, using the patterns from security.secret_patterns.
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Mask replaces a redacted value
+const Mask = "[REDACTED]"
+
+// Redactor masks the secrets matching a set of patterns. A pattern is either
+// a key prefix such as "sk-", whose tokens are masked after the prefix, or a
+// word such as "password", whose assigned values are masked wherever a key
+// containing it is followed by ':' or '=', quoted or not, as in JSON lines.
+type Redactor struct {
+	prefixes    []*regexp.Regexp
+	assignments []*regexp.Regexp
+}
+
+// New creates a redactor for the patterns; empty patterns are ignored
+func New(patterns []string) *Redactor {
+	r := &Redactor{}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		quoted := regexp.QuoteMeta(pattern)
+		if strings.HasSuffix(pattern, "-") || strings.HasSuffix(pattern, "_") {
+			r.prefixes = append(r.prefixes, regexp.MustCompile(`(?i)\b(`+quoted+`)[A-Za-z0-9_\-]{8,}`))
+			continue
+		}
+		r.assignments = append(r.assignments, regexp.MustCompile(
+			`(?i)([A-Za-z0-9_\-]*`+quoted+`[A-Za-z0-9_\-]*\\?["']?\s*[:=]\s*\\?["']?)[^\s"'\\,;}]+`))
+	}
+	return r
+}
+
+// String returns s with every secret masked
+func (r *Redactor) String(s string) string {
+	for _, re := range r.prefixes {
+		s = re.ReplaceAllString(s, "${1}"+Mask)
+	}
+	for _, re := range r.assignments {
+		s = re.ReplaceAllString(s, "${1}"+Mask)
+	}
+	return s
+}
+
+// Strings returns a copy of lines with every secret masked
+func (r *Redactor) Strings(lines []string) []string {
+	if lines == nil {
+		return nil
+	}
+	masked := make([]string, len(lines))
+	for i, line := range lines {
+		masked[i] = r.String(line)
+	}
+	return masked
+}
@@ -0,0 +1,210 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/llm"
+	"baton/internal/mcp"
+	"baton/internal/storage"
+)
+
+// BundleFormatVersion is bumped whenever the bundle layout changes incompatibly
+const BundleFormatVersion = 1
+
+// Bundle captures everything that went into and came out of one cycle
+type Bundle struct {
+	FormatVersion int            `json:"format_version"`
+	BatonVersion  string         `json:"baton_version"`
+	RecordedAt    time.Time      `json:"recorded_at"`
+	Config        *config.Config `json:"config"`
+	Snapshot      *Snapshot      `json:"snapshot"`
+	Selection     *Selection     `json:"selection,omitempty"`
+	Agent         string         `json:"agent,omitempty"`
+	Prompt        string         `json:"prompt,omitempty"`
+	LLM           *LLMExchange   `json:"llm,omitempty"`
+	MCPCalls      []MCPCall      `json:"mcp_calls"`
+	Outcome       *Outcome       `json:"outcome,omitempty"`
+}
+
+// Snapshot is the workspace state the cycle started from
+type Snapshot struct {
+	Tasks            []*storage.Task        `json:"tasks"`
+	Requirements     []*storage.Requirement `json:"requirements"`
+	TaskRequirements []TaskRequirement      `json:"task_requirements"`
+	Artifacts        []*storage.Artifact    `json:"artifacts"`
+	Notes            []*storage.TaskNote    `json:"notes,omitempty"`
+}
+
+// TaskRequirement is a link between a task and a requirement key
+type TaskRequirement struct {
+	TaskID         string `json:"task_id"`
+	RequirementKey string `json:"requirement_key"`
+}
+
+// Selection is the task the selector picked and why
+type Selection struct {
+	TaskID string `json:"task_id"`
+	Reason string `json:"reason"`
+}
+
+// LLMExchange is the response the LLM returned for the cycle prompt
+type LLMExchange struct {
+	Success   bool                   `json:"success"`
+	Content   string                 `json:"content"`
+	Stream    []string               `json:"stream,omitempty"`
+	Cost      float64                `json:"total_cost_usd"`
+	SessionID string                 `json:"session_id,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// MCPCall is one request the agent made to the MCP server and its response
+type MCPCall struct {
+	Request  *mcp.JSONRPCRequest  `json:"request"`
+	Response *mcp.JSONRPCResponse `json:"response,omitempty"`
+}
+
+// Outcome is the result of the cycle
+type Outcome struct {
+	CycleID          string        `json:"cycle_id,omitempty"`
+	Success          bool          `json:"success"`
+	TaskID           string        `json:"task_id,omitempty"`
+	PrevState        storage.State `json:"prev_state,omitempty"`
+	NextState        storage.State `json:"next_state,omitempty"`
+	ArtifactsCreated []string      `json:"artifacts_created,omitempty"`
+	Error            string        `json:"error,omitempty"`
+}
+
+// response converts the recorded exchange back into an LLM response
+func (e *LLMExchange) response() *llm.Response {
+	response := &llm.Response{
+		Success:   e.Success,
+		Content:   e.Content,
+		Stream:    e.Stream,
+		Cost:      e.Cost,
+		SessionID: e.SessionID,
+		Metadata:  e.Metadata,
+	}
+	if e.Error != "" {
+		response.Error = fmt.Errorf("%s", e.Error)
+	}
+	return response
+}
+
+// Save writes the bundle as indented JSON, creating parent directories
+func (b *Bundle) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Load reads a bundle written by Save
+func Load(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	if bundle.FormatVersion != BundleFormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format version %d (expected %d)", bundle.FormatVersion, BundleFormatVersion)
+	}
+	if bundle.Config == nil || bundle.Snapshot == nil {
+		return nil, fmt.Errorf("bundle is missing its config or workspace snapshot")
+	}
+
+	return &bundle, nil
+}
+
+// TakeSnapshot captures the tasks, requirements, links, artifacts and notes in the store
+func TakeSnapshot(store *storage.Store) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+
+	tasks, err := store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	snapshot.Tasks = tasks
+
+	if snapshot.Requirements, err = store.ListRequirements(""); err != nil {
+		return nil, fmt.Errorf("failed to list requirements: %w", err)
+	}
+
+	for _, task := range tasks {
+		requirements, err := store.ListTaskRequirements(task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list requirements for task %s: %w", task.ID, err)
+		}
+		for _, req := range requirements {
+			snapshot.TaskRequirements = append(snapshot.TaskRequirements, TaskRequirement{TaskID: task.ID, RequirementKey: req.Key})
+		}
+
+		artifacts, err := store.ListArtifacts(task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts for task %s: %w", task.ID, err)
+		}
+		snapshot.Artifacts = append(snapshot.Artifacts, artifacts...)
+
+		notes, err := store.ListTaskNotes(task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list notes for task %s: %w", task.ID, err)
+		}
+		snapshot.Notes = append(snapshot.Notes, notes...)
+	}
+
+	return snapshot, nil
+}
+
+// Restore loads the snapshot into an empty store
+func (sn *Snapshot) Restore(store *storage.Store) error {
+	for _, task := range sn.Tasks {
+		restored := *task
+		if err := store.CreateTask(&restored); err != nil {
+			return fmt.Errorf("failed to restore task %s: %w", task.ID, err)
+		}
+	}
+
+	for _, req := range sn.Requirements {
+		restored := *req
+		if err := store.CreateRequirement(&restored); err != nil {
+			return fmt.Errorf("failed to restore requirement %s: %w", req.Key, err)
+		}
+	}
+
+	for _, link := range sn.TaskRequirements {
+		if err := store.LinkTaskRequirement(link.TaskID, link.RequirementKey); err != nil {
+			return fmt.Errorf("failed to restore requirement link %s -> %s: %w", link.TaskID, link.RequirementKey, err)
+		}
+	}
+
+	for _, artifact := range sn.Artifacts {
+		if err := store.RestoreArtifact(artifact); err != nil {
+			return fmt.Errorf("failed to restore artifact %s v%d: %w", artifact.Name, artifact.Version, err)
+		}
+	}
+
+	for _, note := range sn.Notes {
+		restored := *note
+		if err := store.AddTaskNote(&restored); err != nil {
+			return fmt.Errorf("failed to restore note %s: %w", note.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package replay
+
+import (
+	"sync"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/llm"
+	"baton/internal/mcp"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+	"baton/pkg/version"
+)
+
+// Recorder builds a bundle from the hooks a cycle engine calls while it runs.
+// MCP calls arrive on server goroutines, so all recording is serialized.
+type Recorder struct {
+	mu     sync.Mutex
+	bundle *Bundle
+}
+
+// NewRecorder starts a bundle for a cycle about to run against the snapshot
+func NewRecorder(cfg *config.Config, snapshot *Snapshot) *Recorder {
+	return &Recorder{
+		bundle: &Bundle{
+			FormatVersion: BundleFormatVersion,
+			BatonVersion:  version.Version,
+			RecordedAt:    time.Now(),
+			Config:        cfg,
+			Snapshot:      snapshot,
+			MCPCalls:      []MCPCall{},
+		},
+	}
+}
+
+// RecordSelection records the task chosen for the cycle
+func (r *Recorder) RecordSelection(selection *statemachine.SelectionResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bundle.Selection = &Selection{TaskID: selection.Task.ID, Reason: selection.Reason}
+}
+
+// RecordPrompt records the agent and the prompt sent to the LLM
+func (r *Recorder) RecordPrompt(agent string, prompt string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bundle.Agent = agent
+	r.bundle.Prompt = prompt
+}
+
+// RecordLLMResponse records the LLM response, or the error executing it
+func (r *Recorder) RecordLLMResponse(response *llm.Response, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exchange := &LLMExchange{}
+	if response != nil {
+		exchange.Success = response.Success
+		exchange.Content = response.Content
+		exchange.Stream = response.Stream
+		exchange.Cost = response.Cost
+		exchange.SessionID = response.SessionID
+		exchange.Metadata = response.Metadata
+		if response.Error != nil {
+			exchange.Error = response.Error.Error()
+		}
+	}
+	if err != nil {
+		exchange.Error = err.Error()
+	}
+	r.bundle.LLM = exchange
+}
+
+// RecordMCPCall records one MCP request and its response
+func (r *Recorder) RecordMCPCall(req *mcp.JSONRPCRequest, resp *mcp.JSONRPCResponse) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bundle.MCPCalls = append(r.bundle.MCPCalls, MCPCall{Request: req, Response: resp})
+}
+
+// RecordResult records the cycle result
+func (r *Recorder) RecordResult(result *storage.CycleResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bundle.Outcome = outcomeFromResult(result)
+}
+
+// RecordError records a cycle that failed before producing a result
+func (r *Recorder) RecordError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.bundle.Outcome == nil {
+		r.bundle.Outcome = &Outcome{}
+	}
+	r.bundle.Outcome.Success = false
+	r.bundle.Outcome.Error = err.Error()
+}
+
+// Bundle returns the bundle recorded so far
+func (r *Recorder) Bundle() *Bundle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bundle
+}
+
+// outcomeFromResult converts a cycle result into its recorded form
+func outcomeFromResult(result *storage.CycleResult) *Outcome {
+	outcome := &Outcome{
+		CycleID:          result.CycleID,
+		Success:          result.Success,
+		TaskID:           result.TaskID,
+		PrevState:        result.PrevState,
+		NextState:        result.NextState,
+		ArtifactsCreated: result.ArtifactsCreated,
+	}
+	if result.Error != nil {
+		outcome.Error = result.Error.Error()
+	}
+	return outcome
+}
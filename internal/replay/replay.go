@@ -0,0 +1,211 @@
+package replay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"baton/internal/cycle"
+	"baton/internal/llm"
+	"baton/internal/mcp"
+	"baton/internal/storage"
+)
+
+// Report compares a replayed cycle against its recording
+type Report struct {
+	Recorded   *Outcome `json:"recorded"`
+	Replayed   *Outcome `json:"replayed"`
+	Mismatches []string `json:"mismatches"`
+}
+
+// Matches reports whether the replay reproduced the recording
+func (r *Report) Matches() bool {
+	return len(r.Mismatches) == 0
+}
+
+// Replay re-executes a recorded cycle against a scratch copy of its workspace
+// snapshot, with the mock LLM client returning the recorded response and
+// re-issuing the recorded MCP calls, then diffs the result against the recording.
+func Replay(ctx context.Context, bundle *Bundle) (*Report, error) {
+	if bundle.LLM == nil {
+		return nil, fmt.Errorf("bundle has no recorded LLM response to replay")
+	}
+
+	dir, err := os.MkdirTemp("", "baton-replay-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create replay workspace: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Replay against the recorded configuration, but never wait between handshake retries
+	cfg := *bundle.Config
+	cfg.Database = filepath.Join(dir, "replay.db")
+	cfg.Completion.RetryDelaySeconds = 0
+
+	// The scratch database holds the snapshot's data, so it is encrypted when
+	// the recorded workspace's is, with a key thrown away along with it
+	key := ""
+	if cfg.DatabaseEncryption {
+		if key, err = scratchKey(); err != nil {
+			return nil, fmt.Errorf("failed to create replay database key: %w", err)
+		}
+	}
+	store, err := storage.OpenStore(cfg.Database, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize replay database: %w", err)
+	}
+	defer store.Close()
+
+	if err := bundle.Snapshot.Restore(store); err != nil {
+		return nil, fmt.Errorf("failed to restore workspace snapshot: %w", err)
+	}
+
+	mockClient := llm.NewMockClient(bundle.LLM.response())
+	engine := cycle.NewCycleEngine(store, &cfg, mockClient)
+	engine.DisableMCPTransport()
+	engine.MCPServer().SkipCommands()
+
+	recorder := NewRecorder(&cfg, bundle.Snapshot)
+	engine.SetRecorder(recorder)
+
+	// The agent's MCP traffic happens while the LLM runs, so re-issue it from the mock
+	mockClient.OnExecute = func(ctx context.Context, prompt string, agentID string) error {
+		for _, call := range bundle.MCPCalls {
+			engine.MCPServer().HandleRequest(call.Request)
+		}
+		return nil
+	}
+
+	if _, err := engine.ExecuteCycle(ctx, false); err != nil {
+		recorder.RecordError(err)
+	}
+
+	replayed := recorder.Bundle()
+	return &Report{
+		Recorded:   bundle.Outcome,
+		Replayed:   replayed.Outcome,
+		Mismatches: compare(bundle, replayed),
+	}, nil
+}
+
+// scratchKey returns a random key for a database that lives only as long as
+// the replay
+func scratchKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// compare lists every observable difference between two bundles of the same cycle
+func compare(recorded, replayed *Bundle) []string {
+	mismatches := []string{}
+
+	if recorded.Selection != nil && replayed.Selection != nil {
+		if recorded.Selection.TaskID != replayed.Selection.TaskID {
+			mismatches = append(mismatches, fmt.Sprintf("selected task: recorded %s, replayed %s",
+				recorded.Selection.TaskID, replayed.Selection.TaskID))
+		} else if recorded.Selection.Reason != replayed.Selection.Reason {
+			mismatches = append(mismatches, fmt.Sprintf("selection reason: recorded %q, replayed %q",
+				recorded.Selection.Reason, replayed.Selection.Reason))
+		}
+	} else if (recorded.Selection == nil) != (replayed.Selection == nil) {
+		mismatches = append(mismatches, "selection: only one run selected a task")
+	}
+
+	if recorded.Agent != replayed.Agent {
+		mismatches = append(mismatches, fmt.Sprintf("agent: recorded %q, replayed %q", recorded.Agent, replayed.Agent))
+	}
+
+	if recorded.Prompt != replayed.Prompt {
+		mismatches = append(mismatches, fmt.Sprintf("prompt: %s", firstDifference(recorded.Prompt, replayed.Prompt)))
+	}
+
+	if len(recorded.MCPCalls) != len(replayed.MCPCalls) {
+		mismatches = append(mismatches, fmt.Sprintf("mcp calls: recorded %d, replayed %d",
+			len(recorded.MCPCalls), len(replayed.MCPCalls)))
+	} else {
+		for i := range recorded.MCPCalls {
+			want, got := callOutcome(recorded.MCPCalls[i].Response), callOutcome(replayed.MCPCalls[i].Response)
+			if want != got {
+				mismatches = append(mismatches, fmt.Sprintf("mcp call %d (%s): recorded %s, replayed %s",
+					i+1, recorded.MCPCalls[i].Request.Method, want, got))
+			}
+		}
+	}
+
+	mismatches = append(mismatches, compareOutcomes(recorded.Outcome, replayed.Outcome)...)
+
+	return mismatches
+}
+
+// compareOutcomes diffs the deterministic parts of two cycle outcomes
+func compareOutcomes(recorded, replayed *Outcome) []string {
+	if recorded == nil || replayed == nil {
+		if recorded != replayed {
+			return []string{"outcome: only one run produced an outcome"}
+		}
+		return nil
+	}
+
+	var mismatches []string
+	if recorded.Success != replayed.Success {
+		mismatches = append(mismatches, fmt.Sprintf("success: recorded %v, replayed %v", recorded.Success, replayed.Success))
+	}
+	if recorded.NextState != replayed.NextState {
+		mismatches = append(mismatches, fmt.Sprintf("next state: recorded %s, replayed %s", recorded.NextState, replayed.NextState))
+	}
+	if recorded.Error != replayed.Error {
+		mismatches = append(mismatches, fmt.Sprintf("error: recorded %q, replayed %q", recorded.Error, replayed.Error))
+	}
+
+	want, got := sortedCopy(recorded.ArtifactsCreated), sortedCopy(replayed.ArtifactsCreated)
+	if strings.Join(want, ",") != strings.Join(got, ",") {
+		mismatches = append(mismatches, fmt.Sprintf("artifacts created: recorded %v, replayed %v", want, got))
+	}
+
+	return mismatches
+}
+
+// callOutcome summarizes an MCP response without ids or timestamps, which differ between runs
+func callOutcome(resp *mcp.JSONRPCResponse) string {
+	switch {
+	case resp == nil:
+		return "no response"
+	case resp.Error != nil:
+		return fmt.Sprintf("error %d (%s)", resp.Error.Code, resp.Error.Message)
+	default:
+		return "ok"
+	}
+}
+
+// firstDifference describes the first line at which two texts diverge
+func firstDifference(a, b string) string {
+	aLines, bLines := strings.Split(a, "\n"), strings.Split(b, "\n")
+	for i := 0; i < len(aLines) || i < len(bLines); i++ {
+		var aLine, bLine string
+		if i < len(aLines) {
+			aLine = aLines[i]
+		}
+		if i < len(bLines) {
+			bLine = bLines[i]
+		}
+		if aLine != bLine {
+			return fmt.Sprintf("differs at line %d: recorded %q, replayed %q", i+1, aLine, bLine)
+		}
+	}
+	return "differs"
+}
+
+// sortedCopy returns a sorted copy of a string slice
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
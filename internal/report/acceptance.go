@@ -0,0 +1,224 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"baton/internal/storage"
+)
+
+// AcceptanceTestsArtifact is the artifact a tester attaches to map acceptance
+// tests to requirement keys
+const AcceptanceTestsArtifact = "acceptance_tests"
+
+// acceptanceResultsSection is the section of an acceptance_tests artifact
+// that lists one result per requirement key
+const acceptanceResultsSection = "## Results"
+
+// Acceptance test statuses
+const (
+	TestPass    = "pass"
+	TestFail    = "fail"
+	TestPending = "pending" // written but not yet run
+)
+
+// acceptanceResultPattern matches a results line such as "- FR-3: pass" or
+// "- **FR-3** — failed: times out on login"
+var acceptanceResultPattern = regexp.MustCompile(`^[-*]\s+\**([A-Z]{2,4}-[A-Z]?[1-9][0-9]*)\**\s*(?::|-|—|\|)\s*([A-Za-z]+)\b\s*(?:[:—|-]\s*)?(.*)$`)
+
+// AcceptanceResult is one requirement's result in an acceptance_tests artifact
+type AcceptanceResult struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// AcceptanceTestsError lists every problem in an acceptance_tests artifact
+type AcceptanceTestsError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *AcceptanceTestsError) Error() string {
+	return fmt.Sprintf("artifact '%s' is invalid: %s", AcceptanceTestsArtifact, strings.Join(e.Problems, "; "))
+}
+
+// ParseAcceptanceTests reads the results section of an acceptance_tests
+// artifact. Each "- <KEY>: <status>" line under "## Results" maps a test to a
+// requirement; pass, passed, fail, failed and pending are understood, and any
+// text after the status is kept as a note.
+func ParseAcceptanceTests(content string) ([]AcceptanceResult, error) {
+	var results []AcceptanceResult
+	var problems []string
+	inResults, found := false, false
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			inResults = isResultsHeading(line)
+			found = found || inResults
+			continue
+		}
+		if !inResults || line == "" {
+			continue
+		}
+
+		match := acceptanceResultPattern.FindStringSubmatch(line)
+		if match == nil {
+			if strings.HasPrefix(line, "-") || strings.HasPrefix(line, "*") {
+				problems = append(problems, fmt.Sprintf("can't read result line %q: use \"- <KEY>: pass|fail|pending\"", line))
+			}
+			continue
+		}
+		status, ok := normalizeTestStatus(match[2])
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown status %q for %s: use pass, fail or pending", match[2], match[1]))
+			continue
+		}
+		results = append(results, AcceptanceResult{Key: match[1], Status: status, Note: strings.TrimSpace(match[3])})
+	}
+
+	switch {
+	case !found:
+		problems = append(problems, fmt.Sprintf("missing section %q", acceptanceResultsSection))
+	case len(results) == 0 && len(problems) == 0:
+		problems = append(problems, fmt.Sprintf("section %q lists no requirement results", acceptanceResultsSection))
+	}
+	if len(problems) > 0 {
+		return results, &AcceptanceTestsError{Problems: problems}
+	}
+	return results, nil
+}
+
+// isResultsHeading reports whether a markdown heading opens the results
+// section; "## Results (12)" counts too
+func isResultsHeading(line string) bool {
+	line = strings.ToLower(line)
+	want := strings.ToLower(acceptanceResultsSection)
+	return line == want || strings.HasPrefix(line, want+" ") || strings.HasPrefix(line, want+":")
+}
+
+// normalizeTestStatus maps the spellings testers use onto the test statuses
+func normalizeTestStatus(status string) (string, bool) {
+	switch strings.ToLower(status) {
+	case "pass", "passed", "passing", "ok":
+		return TestPass, true
+	case "fail", "failed", "failing":
+		return TestFail, true
+	case "pending", "todo", "skipped":
+		return TestPending, true
+	}
+	return "", false
+}
+
+// RequirementCoverage is one requirement's acceptance test coverage
+type RequirementCoverage struct {
+	Key        string     `json:"key"`
+	Title      string     `json:"title"`
+	Type       string     `json:"type"`
+	Mandatory  bool       `json:"mandatory"`
+	Tests      int        `json:"tests"`                 // acceptance_tests artifacts listing the requirement
+	LastStatus string     `json:"last_status,omitempty"` // from the most recently updated of them
+	LastNote   string     `json:"last_note,omitempty"`
+	LastTaskID string     `json:"last_task_id,omitempty"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+}
+
+// AcceptanceReport is the acceptance test coverage of a set of requirements
+type AcceptanceReport struct {
+	Milestone    string                 `json:"milestone,omitempty"`
+	Requirements []*RequirementCoverage `json:"requirements"`
+	Untested     []string               `json:"untested"`          // mandatory requirements without tests
+	Failing      []string               `json:"failing,omitempty"` // requirements whose last result is a failure
+}
+
+// Complete reports whether every mandatory requirement has an acceptance test
+func (r *AcceptanceReport) Complete() bool {
+	return len(r.Untested) == 0
+}
+
+// BuildAcceptanceReport maps the latest acceptance_tests artifact of every
+// task onto the active requirements. With a milestone, only requirements
+// linked to the milestone's tasks are reported. Requirements of the mandatory
+// types count as untested until some artifact lists them.
+func BuildAcceptanceReport(store *storage.Store, milestone string, mandatoryTypes []string) (*AcceptanceReport, error) {
+	requirements, err := store.ListRequirements("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list requirements: %w", err)
+	}
+	tasks, err := store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	coverage := make(map[string]*RequirementCoverage)
+	for _, task := range tasks {
+		artifact, err := store.GetArtifact(task.ID, AcceptanceTestsArtifact, 0)
+		if err != nil {
+			continue
+		}
+		// An artifact that no longer parses still counts for the results it has
+		results, _ := ParseAcceptanceTests(artifact.Content)
+		for _, result := range results {
+			c, exists := coverage[result.Key]
+			if !exists {
+				c = &RequirementCoverage{}
+				coverage[result.Key] = c
+			}
+			c.Tests++
+			if c.LastRunAt == nil || artifact.CreatedAt.After(*c.LastRunAt) {
+				runAt := artifact.CreatedAt
+				c.LastStatus, c.LastNote, c.LastTaskID, c.LastRunAt = result.Status, result.Note, task.ID, &runAt
+			}
+		}
+	}
+
+	var inScope map[string]bool
+	if milestone != "" {
+		inScope = make(map[string]bool)
+		for _, task := range tasks {
+			if task.Milestone() != milestone {
+				continue
+			}
+			linked, err := store.ListTaskRequirements(task.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list requirements of task %s: %w", task.ID, err)
+			}
+			for _, req := range linked {
+				inScope[req.ID] = true
+			}
+		}
+	}
+
+	mandatory := make(map[string]bool, len(mandatoryTypes))
+	for _, reqType := range mandatoryTypes {
+		mandatory[reqType] = true
+	}
+
+	report := &AcceptanceReport{Milestone: milestone, Requirements: []*RequirementCoverage{}, Untested: []string{}}
+	for _, req := range requirements {
+		if req.Status == storage.RequirementDeprecated || (inScope != nil && !inScope[req.ID]) {
+			continue
+		}
+
+		row := &RequirementCoverage{}
+		if c, exists := coverage[req.Key]; exists {
+			row = c
+		}
+		row.Key, row.Title, row.Type, row.Mandatory = req.Key, req.Title, req.Type, mandatory[req.Type]
+		report.Requirements = append(report.Requirements, row)
+
+		if row.Mandatory && row.Tests == 0 {
+			report.Untested = append(report.Untested, req.Key)
+		}
+		if row.LastStatus == TestFail {
+			report.Failing = append(report.Failing, req.Key)
+		}
+	}
+	sort.Strings(report.Untested)
+	sort.Strings(report.Failing)
+
+	return report, nil
+}
@@ -0,0 +1,230 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"baton/internal/llm"
+	"baton/internal/storage"
+)
+
+// otherGroup holds completed tasks without tags
+const otherGroup = "Other"
+
+// ChangelogEntry is one completed task in a changelog
+type ChangelogEntry struct {
+	TaskID       string                 `json:"task_id"`
+	Title        string                 `json:"title"`
+	Summary      string                 `json:"summary,omitempty"` // from the commit_summary artifact
+	CompletedAt  time.Time              `json:"completed_at"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// ChangelogGroup is the entries for one milestone or tag
+type ChangelogGroup struct {
+	Name    string            `json:"name"`
+	Entries []*ChangelogEntry `json:"entries"`
+}
+
+// Changelog is the set of tasks completed since a point in time
+type Changelog struct {
+	Since  time.Time         `json:"since"`
+	Until  time.Time         `json:"until"`
+	Groups []*ChangelogGroup `json:"groups"`
+}
+
+// ResolveSince parses a date (2006-01-02 or RFC 3339) or, failing that,
+// looks the value up as a git tag in the workspace and returns its commit time
+func ResolveSince(workspace, value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	out, err := exec.Command("git", "-C", workspace, "log", "-1", "--format=%cI", value+"^{commit}", "--").Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (YYYY-MM-DD) nor a git tag in %s", value, workspace)
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(out)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit time of %s: %w", value, err)
+	}
+	return t, nil
+}
+
+// BuildChangelog collects tasks completed since the given time, grouped by
+// milestone (a "milestone:<name>" tag) or otherwise their first tag
+func BuildChangelog(store *storage.Store, since time.Time) (*Changelog, error) {
+	done := storage.Done
+	tasks, err := store.ListTasks(storage.TaskFilters{State: &done, IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list completed tasks: %w", err)
+	}
+
+	groups := make(map[string]*ChangelogGroup)
+	for _, task := range tasks {
+		completedAt, err := completionTime(store, task)
+		if err != nil {
+			return nil, err
+		}
+		if completedAt.Before(since) {
+			continue
+		}
+
+		entry := &ChangelogEntry{
+			TaskID:      task.ID,
+			Title:       task.Title,
+			CompletedAt: completedAt,
+		}
+		if fields := task.CustomFieldMap(); len(fields) > 0 {
+			entry.CustomFields = fields
+		}
+		if artifact, err := store.GetArtifact(task.ID, "commit_summary", 0); err == nil {
+			entry.Summary = strings.TrimSpace(artifact.Content)
+		}
+
+		name := groupName(task)
+		group, exists := groups[name]
+		if !exists {
+			group = &ChangelogGroup{Name: name}
+			groups[name] = group
+		}
+		group.Entries = append(group.Entries, entry)
+	}
+
+	changelog := &Changelog{Since: since, Until: time.Now(), Groups: make([]*ChangelogGroup, 0, len(groups))}
+	for _, group := range groups {
+		sort.Slice(group.Entries, func(i, j int) bool {
+			return group.Entries[i].CompletedAt.Before(group.Entries[j].CompletedAt)
+		})
+		changelog.Groups = append(changelog.Groups, group)
+	}
+
+	// Named groups alphabetically, untagged work last
+	sort.Slice(changelog.Groups, func(i, j int) bool {
+		a, b := changelog.Groups[i].Name, changelog.Groups[j].Name
+		if (a == otherGroup) != (b == otherGroup) {
+			return b == otherGroup
+		}
+		return a < b
+	})
+
+	return changelog, nil
+}
+
+// completionTime is when the task last moved to DONE according to the audit
+// log, falling back to its last update for tasks completed outside a cycle
+func completionTime(store *storage.Store, task *storage.Task) (time.Time, error) {
+	logs, err := store.GetAuditLogs(task.ID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get audit logs for %s: %w", task.ID, err)
+	}
+
+	// Audit logs are newest first
+	for _, log := range logs {
+		if storage.NormalizeState(log.NextState) == storage.Done {
+			return log.CreatedAt, nil
+		}
+	}
+	return task.UpdatedAt, nil
+}
+
+// groupName returns the changelog group of a task
+func groupName(task *storage.Task) string {
+	if milestone := task.Milestone(); milestone != "" {
+		return milestone
+	}
+	if tags := task.TagList(); len(tags) > 0 && tags[0] != "" {
+		return tags[0]
+	}
+	return otherGroup
+}
+
+// Markdown renders the changelog as a CHANGELOG section with the given heading
+func (c *Changelog) Markdown(heading string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s - %s\n", heading, c.Until.Format("2006-01-02"))
+
+	if len(c.Groups) == 0 {
+		b.WriteString("\nNo tasks were completed in this period.\n")
+		return b.String()
+	}
+
+	for _, group := range c.Groups {
+		fmt.Fprintf(&b, "\n### %s\n\n", group.Name)
+		for _, entry := range group.Entries {
+			fmt.Fprintf(&b, "- %s%s\n", entry.Title, formatFields(entry.CustomFields))
+			if line := firstLine(entry.Summary); line != "" {
+				fmt.Fprintf(&b, "  %s\n", line)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// formatFields renders custom field values as " (name: value, ...)", or ""
+// when there are none
+func formatFields(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %v", name, fields[name])
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// firstLine returns the first line of prose in a markdown document
+func firstLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.TrimSpace(strings.TrimLeft(line, "-* "))
+	}
+	return ""
+}
+
+// Polish asks the LLM to rewrite a rendered changelog section for release notes
+func Polish(llmClient llm.Client, changelog *Changelog, section string) (string, error) {
+	details, err := json.MarshalIndent(changelog, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	prompt := fmt.Sprintf(`Rewrite the following CHANGELOG section so it is ready to paste into release notes.
+
+Keep the "##" heading and the "###" groups exactly as they are. Under each group, write one concise,
+user-facing bullet per task in the past tense, using the commit summaries for detail. Do not invent
+changes that are not listed. Respond with the markdown section only.
+
+## Draft
+
+%s
+## Completed tasks (JSON)
+
+%s`, section, details)
+
+	content, err := llmClient.GenerateText(context.Background(), prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to polish changelog: %w", err)
+	}
+	return strings.TrimSpace(content) + "\n", nil
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"baton/internal/storage"
+)
+
+// CostGroupings are the ways a cost report can group cycles
+var CostGroupings = []string{"day", "agent", "provider", "outcome", "task"}
+
+// CostReport is what the cycles started in a period cost, grouped
+type CostReport struct {
+	Since  time.Time              `json:"since"`
+	Until  time.Time              `json:"until"`
+	By     string                 `json:"by"`
+	Groups []*storage.CycleTotals `json:"groups"` // by key; days oldest first, otherwise costliest first
+	Total  *storage.CycleTotals   `json:"total"`
+}
+
+// BuildCostReport adds up the cycles started in [since, until), grouped by
+// one of CostGroupings; a zero until means up to now
+func BuildCostReport(store *storage.Store, since, until time.Time, by string) (*CostReport, error) {
+	key, err := costGroupKey(by)
+	if err != nil {
+		return nil, err
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	records, err := store.ListCycles(since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cycles: %w", err)
+	}
+
+	report := &CostReport{Since: since, Until: until, By: by, Total: &storage.CycleTotals{}}
+	groups := make(map[string]*storage.CycleTotals)
+	for _, record := range records {
+		k := key(record)
+		group, ok := groups[k]
+		if !ok {
+			group = &storage.CycleTotals{Key: k}
+			groups[k] = group
+			report.Groups = append(report.Groups, group)
+		}
+		group.Add(record)
+		report.Total.Add(record)
+	}
+
+	// Records come oldest first, so days already are in order
+	if by != "day" {
+		sort.SliceStable(report.Groups, func(i, j int) bool {
+			return report.Groups[i].CostUSD > report.Groups[j].CostUSD
+		})
+	}
+	return report, nil
+}
+
+// costGroupKey returns what groups a cycle record under by
+func costGroupKey(by string) (func(*storage.CycleRecord) string, error) {
+	switch by {
+	case "day":
+		return func(r *storage.CycleRecord) string { return r.StartedAt.Format("2006-01-02") }, nil
+	case "agent":
+		return func(r *storage.CycleRecord) string { return r.Agent }, nil
+	case "provider":
+		return func(r *storage.CycleRecord) string {
+			if r.Provider == "" {
+				return "(none)" // the agent never got a response
+			}
+			return r.Provider
+		}, nil
+	case "outcome":
+		return func(r *storage.CycleRecord) string { return r.Outcome }, nil
+	case "task":
+		return func(r *storage.CycleRecord) string { return r.TaskID }, nil
+	}
+	return nil, fmt.Errorf("invalid grouping %q: use one of %v", by, CostGroupings)
+}
@@ -0,0 +1,258 @@
+package report
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// MilestoneSummaryArtifact is the artifact recorded on the task that finishes
+// a milestone, summarizing the milestone for its sign-off
+const MilestoneSummaryArtifact = "milestone_summary"
+
+// MilestoneSummary is what a finished milestone delivered and left open
+type MilestoneSummary struct {
+	Milestone     string            `json:"milestone"`
+	Delivered     []*storage.Task   `json:"delivered"`
+	Acceptance    *AcceptanceReport `json:"acceptance"`
+	Risks         []string          `json:"risks"`
+	OpenQuestions []string          `json:"open_questions,omitempty"`
+}
+
+// BuildMilestoneSummary collects a milestone's delivered scope, the
+// requirements its tasks cover and its outstanding risks: risk requirements
+// in scope, untested or failing requirements and questions agents left open
+func BuildMilestoneSummary(store *storage.Store, milestone string, mandatoryTypes []string) (*MilestoneSummary, error) {
+	tasks, err := milestoneTasks(store, milestone)
+	if err != nil {
+		return nil, err
+	}
+	acceptance, err := BuildAcceptanceReport(store, milestone, mandatoryTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &MilestoneSummary{Milestone: milestone, Delivered: []*storage.Task{}, Acceptance: acceptance, Risks: []string{}}
+	for _, task := range tasks {
+		if task.State == storage.Done {
+			summary.Delivered = append(summary.Delivered, task)
+		}
+
+		assessment, err := store.GetTaskAssessment(task.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get the self-assessment of task %s: %w", task.ID, err)
+		}
+		if assessment != nil {
+			for _, question := range assessment.OpenQuestions {
+				summary.OpenQuestions = append(summary.OpenQuestions, fmt.Sprintf("%s (%s)", question, task.Title))
+			}
+		}
+	}
+
+	for _, row := range acceptance.Requirements {
+		if row.Type == "risk" {
+			summary.Risks = append(summary.Risks, fmt.Sprintf("%s %s", row.Key, row.Title))
+		}
+	}
+	for _, key := range acceptance.Failing {
+		summary.Risks = append(summary.Risks, fmt.Sprintf("%s is failing its last acceptance test", key))
+	}
+	for _, key := range acceptance.Untested {
+		summary.Risks = append(summary.Risks, fmt.Sprintf("%s has no acceptance test", key))
+	}
+
+	return summary, nil
+}
+
+// Markdown renders the summary as the milestone_summary artifact
+func (m *MilestoneSummary) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Milestone %s\n", m.Milestone)
+
+	b.WriteString("\n## Scope Delivered\n")
+	if len(m.Delivered) == 0 {
+		b.WriteString("- None\n")
+	}
+	for _, task := range m.Delivered {
+		fmt.Fprintf(&b, "- %s (%s)\n", task.Title, task.ID[:8])
+	}
+
+	b.WriteString("\n## Requirements Covered\n")
+	if len(m.Acceptance.Requirements) == 0 {
+		b.WriteString("- None\n")
+	}
+	for _, row := range m.Acceptance.Requirements {
+		status := "untested"
+		if row.Tests > 0 {
+			status = row.LastStatus
+		}
+		fmt.Fprintf(&b, "- %s %s: %s\n", row.Key, row.Title, status)
+	}
+
+	b.WriteString("\n## Outstanding Risks\n")
+	if len(m.Risks) == 0 && len(m.OpenQuestions) == 0 {
+		b.WriteString("- None\n")
+	}
+	for _, risk := range m.Risks {
+		fmt.Fprintf(&b, "- %s\n", risk)
+	}
+	for _, question := range m.OpenQuestions {
+		fmt.Fprintf(&b, "- Open question: %s\n", question)
+	}
+
+	return b.String()
+}
+
+// RecordMilestoneSummary records the milestone_summary artifact on a task
+// that just reached DONE when it was the last unfinished task of its
+// milestone. It returns the milestone summarized, or "" when there was none.
+func RecordMilestoneSummary(store *storage.Store, taskID string, mandatoryTypes []string) (string, error) {
+	task, err := store.GetTask(taskID)
+	if err != nil {
+		return "", fmt.Errorf("task %s not found: %w", taskID, err)
+	}
+	milestone := task.Milestone()
+	if task.State != storage.Done || milestone == "" {
+		return "", nil
+	}
+
+	tasks, err := milestoneTasks(store, milestone)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tasks {
+		if !statemachine.IsTerminalState(t.State) {
+			return "", nil
+		}
+	}
+	if existing, err := findMilestoneSummary(store, tasks); err != nil || existing != nil {
+		return "", err
+	}
+
+	if err := recordSummary(store, task, mandatoryTypes); err != nil {
+		return "", err
+	}
+	return milestone, nil
+}
+
+// SignOffMilestone records a finished milestone's sign-off, releasing the
+// next milestone's tasks for selection. The milestone_summary artifact is
+// recorded first if the milestone does not have one yet.
+func SignOffMilestone(store *storage.Store, milestone, signedOffBy, note string, mandatoryTypes []string) (*storage.MilestoneSignoff, error) {
+	tasks, err := milestoneTasks(store, milestone)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("milestone %s not found: milestones are named by %s<name> task tags", milestone, storage.MilestoneTagPrefix)
+	}
+
+	var unfinished []string
+	for _, task := range tasks {
+		if !statemachine.IsTerminalState(task.State) {
+			unfinished = append(unfinished, task.ID[:8])
+		}
+	}
+	if len(unfinished) > 0 {
+		return nil, fmt.Errorf("milestone %s can't be signed off: %d tasks are unfinished: %s",
+			milestone, len(unfinished), strings.Join(unfinished, ", "))
+	}
+
+	existing, err := findMilestoneSummary(store, tasks)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		// The summary goes on the milestone's most recently finished task
+		last := tasks[0]
+		for _, task := range tasks[1:] {
+			if task.UpdatedAt.After(last.UpdatedAt) {
+				last = task
+			}
+		}
+		if err := recordSummary(store, last, mandatoryTypes); err != nil {
+			return nil, err
+		}
+	}
+
+	signoff := &storage.MilestoneSignoff{Milestone: milestone, SignedOffBy: signedOffBy, Note: note}
+	if err := store.SignOffMilestone(signoff); err != nil {
+		return nil, fmt.Errorf("failed to sign off milestone %s: %w", milestone, err)
+	}
+	return signoff, nil
+}
+
+// GetMilestoneSummary returns the milestone_summary artifact of a milestone,
+// or nil if it has none yet
+func GetMilestoneSummary(store *storage.Store, milestone string) (*storage.Artifact, error) {
+	tasks, err := milestoneTasks(store, milestone)
+	if err != nil {
+		return nil, err
+	}
+	return findMilestoneSummary(store, tasks)
+}
+
+// recordSummary builds the milestone's summary and attaches it to task
+func recordSummary(store *storage.Store, task *storage.Task, mandatoryTypes []string) error {
+	milestone := task.Milestone()
+	summary, err := BuildMilestoneSummary(store, milestone, mandatoryTypes)
+	if err != nil {
+		return err
+	}
+
+	meta, _ := json.Marshal(map[string]string{"milestone": milestone})
+	err = store.UpsertArtifact(&storage.Artifact{
+		TaskID:  task.ID,
+		Name:    MilestoneSummaryArtifact,
+		Content: summary.Markdown(),
+		Meta:    meta,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record the summary of milestone %s: %w", milestone, err)
+	}
+	return nil
+}
+
+// findMilestoneSummary returns the latest milestone_summary artifact on any
+// of the tasks, or nil
+func findMilestoneSummary(store *storage.Store, tasks []*storage.Task) (*storage.Artifact, error) {
+	var latest *storage.Artifact
+	for _, task := range tasks {
+		artifact, err := store.GetArtifact(task.ID, MilestoneSummaryArtifact, 0)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get artifacts of task %s: %w", task.ID, err)
+		}
+		if latest == nil || artifact.CreatedAt.After(latest.CreatedAt) {
+			latest = artifact
+		}
+	}
+	return latest, nil
+}
+
+// milestoneTasks returns the tasks of a milestone by title
+func milestoneTasks(store *storage.Store, milestone string) ([]*storage.Task, error) {
+	tasks, err := store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	var inMilestone []*storage.Task
+	for _, task := range tasks {
+		if task.Milestone() == milestone {
+			inMilestone = append(inMilestone, task)
+		}
+	}
+	sort.Slice(inMilestone, func(i, j int) bool {
+		return inMilestone[i].Title < inMilestone[j].Title
+	})
+	return inMilestone, nil
+}
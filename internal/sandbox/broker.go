@@ -0,0 +1,251 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"baton/internal/config"
+)
+
+// KindAgent marks the commands agents run through the broker in the audit
+// entry's command list, which also holds cycle hook results
+const KindAgent = "agent_command"
+
+// DefaultTimeout bounds a command that asks for no timeout of its own
+const DefaultTimeout = 5 * time.Minute
+
+// MaxTimeout bounds the timeout a command may ask for
+const MaxTimeout = 30 * time.Minute
+
+// maxOutput is how much of a command's combined output is kept, from its end
+const maxOutput = 64 * 1024
+
+// Request is a command an agent asks to run
+type Request struct {
+	Command        string   `json:"command"`                   // the binary's name, as listed in security.allowed_commands
+	Args           []string `json:"args,omitempty"`            // passed as they are, without a shell
+	Dir            string   `json:"dir,omitempty"`             // relative to the cycle's working directory
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"` // DefaultTimeout when 0
+}
+
+// Result is what running, or refusing, a command came to
+type Result struct {
+	Kind            string    `json:"kind"` // always KindAgent
+	Command         string    `json:"command"`
+	Args            []string  `json:"args,omitempty"`
+	Dir             string    `json:"dir,omitempty"` // relative to the cycle's working directory
+	Allowed         bool      `json:"allowed"`       // false when the broker refused to run it
+	Success         bool      `json:"success"`
+	ExitCode        int       `json:"exit_code"`
+	Output          string    `json:"output,omitempty"` // tail of the combined stdout and stderr
+	Truncated       bool      `json:"truncated,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	StartedAt       time.Time `json:"started_at"`
+}
+
+// Broker runs the commands agents ask for, as long as security allows them:
+// the binary must be in security.allowed_commands and, with
+// security.workspace_restriction, the working directory and every path
+// among the arguments must lie within the broker's root. Commands run
+// without a shell, so pipes, redirections and globs are not interpreted.
+type Broker struct {
+	allowed  map[string]bool
+	restrict bool
+	root     string
+}
+
+// NewBroker creates a broker that confines commands to root
+func NewBroker(security config.SecurityConfig, root string) *Broker {
+	allowed := make(map[string]bool, len(security.AllowedCommands))
+	for _, name := range security.AllowedCommands {
+		allowed[name] = true
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return &Broker{allowed: allowed, restrict: security.WorkspaceRestriction, root: root}
+}
+
+// Check returns the directory req runs in, or why the broker refuses it
+func (b *Broker) Check(req *Request) (string, error) {
+	if req.Command == "" {
+		return "", fmt.Errorf("command is required")
+	}
+	if !b.allowed[req.Command] {
+		return "", fmt.Errorf("command %q is not in security.allowed_commands", req.Command)
+	}
+	if req.TimeoutSeconds < 0 || time.Duration(req.TimeoutSeconds)*time.Second > MaxTimeout {
+		return "", fmt.Errorf("timeout_seconds must be between 0 and %d", int(MaxTimeout/time.Second))
+	}
+
+	dir := b.root
+	if req.Dir != "" {
+		dir = req.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(b.root, dir)
+		}
+	}
+	if !b.restrict {
+		return dir, nil
+	}
+
+	if !b.within(dir) {
+		return "", fmt.Errorf("directory %s is outside the workspace", req.Dir)
+	}
+	for _, arg := range req.Args {
+		for _, value := range pathValues(arg) {
+			if err := b.checkPath(dir, value); err != nil {
+				return "", fmt.Errorf("argument %q: %w", arg, err)
+			}
+		}
+	}
+	return dir, nil
+}
+
+// pathValues returns the parts of an argument that may name a path: a plain
+// argument itself, the value of a --flag=value or -f=value argument, and the
+// value attached to a short flag, as in -o/etc/passwd
+func pathValues(arg string) []string {
+	if !strings.HasPrefix(arg, "-") {
+		return []string{arg}
+	}
+	var values []string
+	if _, after, found := strings.Cut(arg, "="); found {
+		values = append(values, after)
+	}
+	if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+		values = append(values, arg[2:])
+	}
+	return values
+}
+
+// checkPath refuses an argument naming a path outside the root: an absolute
+// path, one that climbs out with .., one under ~, or one through a symlink
+// leading out, whether or not the path exists yet. Arguments that aren't
+// paths resolve to within dir and pass.
+func (b *Broker) checkPath(dir, value string) error {
+	if value == "" {
+		return nil
+	}
+	if strings.HasPrefix(value, "~") {
+		return fmt.Errorf("home directory paths are outside the workspace")
+	}
+
+	path := value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if !b.within(path) {
+		return fmt.Errorf("path is outside the workspace")
+	}
+	return nil
+}
+
+// within reports whether path, with its symlinks resolved, lies within the root
+func (b *Broker) within(path string) bool {
+	root := resolve(b.root)
+	rel, err := filepath.Rel(root, resolve(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// resolve cleans path and resolves the symlinks of its longest existing
+// prefix, so paths that don't exist yet can be checked too
+func resolve(path string) string {
+	path = filepath.Clean(path)
+	var rest []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...)
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return path
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+	}
+}
+
+// Run runs req if the broker allows it, adding env to its environment, and
+// reports the outcome either way
+func (b *Broker) Run(ctx context.Context, req *Request, env ...string) *Result {
+	result := &Result{Kind: KindAgent, Command: req.Command, Args: req.Args, Dir: req.Dir, StartedAt: time.Now()}
+	dir, err := b.Check(req)
+	if err != nil {
+		result.ExitCode = -1
+		result.Error = err.Error()
+		return result
+	}
+	result.Allowed = true
+
+	timeout := DefaultTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Only the tail is kept as the command writes, so a chatty command can't
+	// run the process out of memory
+	output := &tailWriter{max: maxOutput}
+	cmd := exec.CommandContext(ctx, req.Command, req.Args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err = cmd.Run()
+	result.DurationSeconds = time.Since(result.StartedAt).Seconds()
+
+	result.Output = string(output.buf)
+	result.Truncated = output.truncated
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.Success = true
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = err.Error()
+	default:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// tailWriter keeps the last max bytes written to it. exec.Cmd writes to it
+// from one goroutine at a time when it is both Stdout and Stderr.
+type tailWriter struct {
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) >= w.max {
+		w.truncated = w.truncated || len(w.buf) > 0 || len(p) > w.max
+		w.buf = append(w.buf[:0], p[len(p)-w.max:]...)
+		return n, nil
+	}
+	if over := len(w.buf) + len(p) - w.max; over > 0 {
+		w.buf = append(w.buf[:0], w.buf[over:]...)
+		w.truncated = true
+	}
+	w.buf = append(w.buf, p...)
+	return n, nil
+}
+
+// Refuse reports a command that was not run, for why
+func Refuse(req *Request, why string) *Result {
+	return &Result{Kind: KindAgent, Command: req.Command, Args: req.Args, Dir: req.Dir,
+		ExitCode: -1, Error: why, StartedAt: time.Now()}
+}
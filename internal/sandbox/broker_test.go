@@ -0,0 +1,133 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"baton/internal/config"
+)
+
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatalf("Failed to create src: %v", err)
+	}
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	broker := NewBroker(config.SecurityConfig{
+		AllowedCommands:      []string{"go", "git", "tar"},
+		WorkspaceRestriction: true,
+	}, root)
+
+	tests := []struct {
+		name    string
+		req     Request
+		wantErr string
+	}{
+		{name: "allowed command", req: Request{Command: "go", Args: []string{"test", "./..."}}},
+		{name: "relative path", req: Request{Command: "git", Args: []string{"add", "src/main.go"}}},
+		{name: "subdirectory", req: Request{Command: "go", Args: []string{"build"}, Dir: "src"}},
+		{name: "combined short flags", req: Request{Command: "git", Args: []string{"log", "-np"}}},
+		{name: "attached relative value", req: Request{Command: "go", Args: []string{"build", "-obin/baton"}}},
+		{name: "long flag relative value", req: Request{Command: "go", Args: []string{"test", "--coverprofile=cover.out"}}},
+
+		{name: "missing command", req: Request{}, wantErr: "command is required"},
+		{name: "command not allowed", req: Request{Command: "rm", Args: []string{"-rf", "src"}}, wantErr: "not in security.allowed_commands"},
+		{name: "timeout too long", req: Request{Command: "go", TimeoutSeconds: 3600}, wantErr: "timeout_seconds"},
+		{name: "negative timeout", req: Request{Command: "go", TimeoutSeconds: -1}, wantErr: "timeout_seconds"},
+
+		{name: "absolute path", req: Request{Command: "tar", Args: []string{"-cf", "out.tar", "/etc/passwd"}}, wantErr: "outside the workspace"},
+		{name: "parent path", req: Request{Command: "git", Args: []string{"add", "../secret"}}, wantErr: "outside the workspace"},
+		{name: "nested parent path", req: Request{Command: "git", Args: []string{"add", "src/../../secret"}}, wantErr: "outside the workspace"},
+		{name: "home path", req: Request{Command: "git", Args: []string{"add", "~/.ssh/id_rsa"}}, wantErr: "home directory"},
+		{name: "symlink out", req: Request{Command: "git", Args: []string{"add", "escape/file"}}, wantErr: "outside the workspace"},
+		{name: "directory outside", req: Request{Command: "go", Dir: "../"}, wantErr: "directory"},
+		{name: "absolute directory outside", req: Request{Command: "go", Dir: outside}, wantErr: "directory"},
+
+		{name: "long flag value", req: Request{Command: "go", Args: []string{"build", "--output=/etc/passwd"}}, wantErr: "outside the workspace"},
+		{name: "short flag equals value", req: Request{Command: "go", Args: []string{"build", "-o=/etc/passwd"}}, wantErr: "outside the workspace"},
+		{name: "short flag attached value", req: Request{Command: "go", Args: []string{"build", "-o/etc/passwd"}}, wantErr: "outside the workspace"},
+		{name: "short flag attached parent", req: Request{Command: "git", Args: []string{"-C../other", "status"}}, wantErr: "outside the workspace"},
+		{name: "short flag attached home", req: Request{Command: "go", Args: []string{"build", "-o~/bin/baton"}}, wantErr: "home directory"},
+		{name: "short flag separate value", req: Request{Command: "go", Args: []string{"build", "-o", "/etc/passwd"}}, wantErr: "outside the workspace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			dir, err := broker.Check(&req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected the command to be allowed, got %v", err)
+				}
+				if !strings.HasPrefix(dir, root) {
+					t.Errorf("Expected a directory within %s, got %s", root, dir)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected the command to be refused (%s)", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckUnrestricted(t *testing.T) {
+	broker := NewBroker(config.SecurityConfig{AllowedCommands: []string{"go"}}, t.TempDir())
+	if _, err := broker.Check(&Request{Command: "go", Args: []string{"build", "-o/tmp/baton"}}); err != nil {
+		t.Errorf("Expected paths to go unchecked without workspace_restriction, got %v", err)
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	w := &tailWriter{max: 8}
+
+	w.Write([]byte("abc"))
+	w.Write([]byte("def"))
+	if string(w.buf) != "abcdef" || w.truncated {
+		t.Fatalf("Expected abcdef untruncated, got %q (truncated %v)", w.buf, w.truncated)
+	}
+
+	w.Write([]byte("ghij"))
+	if string(w.buf) != "cdefghij" || !w.truncated {
+		t.Fatalf("Expected the tail cdefghij truncated, got %q (truncated %v)", w.buf, w.truncated)
+	}
+
+	w.Write([]byte("0123456789"))
+	if string(w.buf) != "23456789" {
+		t.Fatalf("Expected the tail 23456789, got %q", w.buf)
+	}
+}
+
+func TestRunCapsOutput(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	broker := NewBroker(config.SecurityConfig{AllowedCommands: []string{"sh"}}, t.TempDir())
+
+	// Four times the cap, ending in a marker that must survive
+	script := "i=0; while [ $i -lt 4096 ]; do printf '%063d\\n' $i; i=$((i+1)); done; echo END"
+	result := broker.Run(context.Background(), &Request{Command: "sh", Args: []string{"-c", script}})
+	if !result.Success {
+		t.Fatalf("Expected the command to succeed, got %s", result.Error)
+	}
+	if !result.Truncated {
+		t.Error("Expected the output to be truncated")
+	}
+	if len(result.Output) != maxOutput {
+		t.Errorf("Expected %d bytes of output, got %d", maxOutput, len(result.Output))
+	}
+	if !bytes.HasSuffix([]byte(result.Output), []byte("END\n")) {
+		t.Error("Expected the output to keep its tail")
+	}
+}
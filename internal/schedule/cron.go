@@ -0,0 +1,197 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression: minute, hour, day of month, month and day
+// of week, matched in time.Local
+type Cron struct {
+	expr    string
+	minutes uint64 // bit n set when minute n matches
+	hours   uint64
+	days    uint64 // days of the month, 1-31
+	months  uint64 // 1-12
+	weekday uint64 // 0-6, Sunday is 0
+
+	// Like cron, when both the day of month and the day of week are
+	// restricted (don't start with *), a day matching either one matches
+	daysRestricted    bool
+	weekdayRestricted bool
+}
+
+// field describes one field of a cron expression
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	dayField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	weekdayField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros are the shorthands cron accepts in place of five fields
+var macros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+}
+
+// ParseCron parses a five-field cron expression such as "0 22 * * 1-5" (at
+// 22:00 on weekdays). Fields take *, numbers, ranges (1-5), steps (*/15,
+// 0-30/10) and comma-separated lists of those; months and days of the week
+// may be given by their first three letters. Sunday is 0 or 7. The macros
+// @hourly, @daily, @midnight, @weekly, @monthly and @yearly are accepted too.
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(fields))
+	}
+
+	c := &Cron{expr: expr}
+	var err error
+	if c.minutes, err = minuteField.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.hours, err = hourField.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.days, err = dayField.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.months, err = monthField.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	if c.weekday, err = weekdayField.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+	// Sunday may be written as 7
+	if c.weekday&(1<<7) != 0 {
+		c.weekday = c.weekday&^(1<<7) | 1
+	}
+	c.daysRestricted = !strings.HasPrefix(fields[2], "*")
+	c.weekdayRestricted = !strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Next returns the first time after t the expression matches, or the zero
+// time if it matches none in the next five years (e.g. "0 0 31 2 *")
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.In(time.Local).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.Local)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.Local)
+		case c.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.Local)
+		case c.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day fields
+func (c *Cron) dayMatches(t time.Time) bool {
+	day := c.days&(1<<uint(t.Day())) != 0
+	weekday := c.weekday&(1<<uint(t.Weekday())) != 0
+	if c.daysRestricted && c.weekdayRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// parse parses one field into a bit set of the values it matches
+func (f field) parse(spec string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(spec, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepSpec)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepSpec, f.name)
+			}
+			step = n
+		}
+
+		var low, high int
+		switch {
+		case rangeSpec == "*":
+			low, high = f.min, f.max
+		case strings.Contains(rangeSpec, "-"):
+			lowSpec, highSpec, _ := strings.Cut(rangeSpec, "-")
+			var err error
+			if low, err = f.value(lowSpec); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(highSpec); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangeSpec, f.name)
+			}
+		default:
+			var err error
+			if low, err = f.value(rangeSpec); err != nil {
+				return 0, err
+			}
+			high = low
+			// A step after a single value runs to the end of the field, e.g. 5/15
+			if hasStep {
+				high = f.max
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or name within the field's bounds
+func (f field) value(spec string) (int, error) {
+	if n, ok := f.names[strings.ToLower(spec)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(spec)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", spec, f.name)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s %d is out of range %d-%d", f.name, n, f.min, f.max)
+	}
+	return n, nil
+}
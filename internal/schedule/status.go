@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"baton/internal/lock"
+)
+
+// maxRuns is how many finished runs the status file keeps
+const maxRuns = 20
+
+// Status is what a running scheduler reports in its status file
+type Status struct {
+	PID       int        `json:"pid"`
+	Hostname  string     `json:"hostname"`
+	StartedAt time.Time  `json:"started_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Running   string     `json:"running,omitempty"` // the schedule whose run is in progress, "" while waiting
+	Next      []NextRun  `json:"next"`
+	Runs      []*RunInfo `json:"runs"` // finished runs, newest first
+}
+
+// NextRun is when a schedule runs next
+type NextRun struct {
+	Schedule string    `json:"schedule"`
+	At       time.Time `json:"at"`
+}
+
+// RunInfo is the outcome of one scheduled run
+type RunInfo struct {
+	Schedule   string    `json:"schedule"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Cycles     int       `json:"cycles"`
+	Succeeded  int       `json:"succeeded"`
+	StopReason string    `json:"stop_reason,omitempty"`
+	Error      string    `json:"error,omitempty"` // why the run could not start or failed
+}
+
+// StatusPath returns the status file location for a workspace
+func StatusPath(workspace string) string {
+	return filepath.Join(workspace, ".baton", "schedule.json")
+}
+
+// AddRun records a finished run, keeping the newest maxRuns
+func (s *Status) AddRun(run *RunInfo) {
+	s.Runs = append([]*RunInfo{run}, s.Runs...)
+	if len(s.Runs) > maxRuns {
+		s.Runs = s.Runs[:maxRuns]
+	}
+}
+
+// Alive reports whether the scheduler that wrote the status is still running.
+// A scheduler on another host is assumed to be.
+func (s *Status) Alive() bool {
+	hostname, _ := os.Hostname()
+	return s.Hostname != hostname || lock.ProcessAlive(s.PID)
+}
+
+// WriteStatus replaces a workspace's status file
+func WriteStatus(workspace string, status *Status) error {
+	status.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := StatusPath(workspace)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create status directory: %w", err)
+	}
+	// Readers never see a half-written file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadStatus reads a workspace's status file, nil when there is none
+func ReadStatus(workspace string) (*Status, error) {
+	data, err := os.ReadFile(StatusPath(workspace))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status file: %w", err)
+	}
+
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("invalid status file %s: %w", StatusPath(workspace), err)
+	}
+	return &status, nil
+}
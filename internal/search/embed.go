@@ -0,0 +1,192 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"baton/internal/config"
+)
+
+// hashDimensions is the vector size of the local embedder
+const hashDimensions = 512
+
+// Embedder turns text into vectors whose cosine similarity reflects relatedness
+type Embedder interface {
+	// Model identifies the embedding space, so cached vectors from another model are not reused
+	Model() string
+	Embed(texts []string) ([][]float64, error)
+}
+
+// NewEmbedder creates the embedder selected by the search configuration
+func NewEmbedder(cfg config.SearchConfig) (Embedder, error) {
+	switch cfg.EmbeddingProvider {
+	case "", "local":
+		return NewHashEmbedder(), nil
+	case "api":
+		apiKey := os.Getenv(cfg.APIKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("search.embedding_provider is api but %s is not set", cfg.APIKeyEnv)
+		}
+		return NewAPIEmbedder(cfg.EmbeddingEndpoint, cfg.EmbeddingModel, apiKey), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.EmbeddingProvider)
+	}
+}
+
+// HashEmbedder is a local, dependency-free embedder using feature hashing of
+// word stems and character trigrams. It captures lexical overlap including
+// partial words rather than true meaning, but needs no network access.
+type HashEmbedder struct{}
+
+// NewHashEmbedder creates a new local embedder
+func NewHashEmbedder() *HashEmbedder {
+	return &HashEmbedder{}
+}
+
+// Model implements Embedder
+func (e *HashEmbedder) Model() string {
+	return fmt.Sprintf("local-hash-%d", hashDimensions)
+}
+
+// Embed implements Embedder
+func (e *HashEmbedder) Embed(texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = hashVector(text)
+	}
+	return vectors, nil
+}
+
+// hashVector builds a normalized feature-hashed vector for text
+func hashVector(text string) []float64 {
+	vector := make([]float64, hashDimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	add := func(feature string, weight float64) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		// The top bit picks the sign so collisions tend to cancel out
+		if sum&(1<<31) != 0 {
+			weight = -weight
+		}
+		vector[sum%hashDimensions] += weight
+	}
+
+	for _, word := range words {
+		add("w:"+word, 1.0)
+		padded := "^" + word + "$"
+		for i := 0; i+3 <= len(padded); i++ {
+			add("g:"+padded[i:i+3], 0.5)
+		}
+	}
+
+	normalize(vector)
+	return vector
+}
+
+// normalize scales a vector to unit length in place
+func normalize(vector []float64) {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+// APIEmbedder calls an OpenAI-compatible embeddings endpoint
+type APIEmbedder struct {
+	endpoint string
+	model    string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewAPIEmbedder creates a new embedder backed by a provider API
+func NewAPIEmbedder(endpoint, model, apiKey string) *APIEmbedder {
+	return &APIEmbedder{
+		endpoint: endpoint,
+		model:    model,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Model implements Embedder
+func (e *APIEmbedder) Model() string {
+	return e.model
+}
+
+// Embed implements Embedder
+func (e *APIEmbedder) Embed(texts []string) ([][]float64, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding response: %w", err)
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response has out of range index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for i, vector := range vectors {
+		if vector == nil {
+			return nil, fmt.Errorf("embedding response is missing input %d", i)
+		}
+	}
+
+	return vectors, nil
+}
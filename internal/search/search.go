@@ -0,0 +1,218 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"baton/internal/storage"
+)
+
+// Modes supported by Search
+const (
+	ModeKeyword  = "keyword"
+	ModeSemantic = "semantic"
+)
+
+// DefaultLimit is the number of hits returned when no limit is given
+const DefaultLimit = 10
+
+// maxEmbedChars bounds how much of each document is embedded
+const maxEmbedChars = 8000
+
+// snippetChars is the length of semantic search snippets
+const snippetChars = 160
+
+// embedBatchSize bounds how many documents are embedded per request
+const embedBatchSize = 64
+
+// Searcher runs keyword and semantic searches over tasks, artifacts,
+// requirements and audit notes
+type Searcher struct {
+	store    *storage.Store
+	embedder Embedder
+}
+
+// NewSearcher creates a new searcher; embedder may be nil when semantic search is unavailable
+func NewSearcher(store *storage.Store, embedder Embedder) *Searcher {
+	return &Searcher{
+		store:    store,
+		embedder: embedder,
+	}
+}
+
+// Search returns the documents matching the query, best matches first
+func (s *Searcher) Search(query, mode string, filters storage.SearchFilters) ([]*storage.SearchHit, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if filters.Kind != "" && !ValidKind(filters.Kind) {
+		return nil, fmt.Errorf("unknown search kind %q: must be one of %s", filters.Kind, strings.Join(storage.SearchKinds, ", "))
+	}
+	if filters.Limit <= 0 {
+		filters.Limit = DefaultLimit
+	}
+
+	switch mode {
+	case "", ModeKeyword:
+		return s.store.Search(query, filters)
+	case ModeSemantic:
+		return s.semantic(query, filters)
+	default:
+		return nil, fmt.Errorf("unknown search mode: %s", mode)
+	}
+}
+
+// ValidKind reports whether kind names a kind of search hit
+func ValidKind(kind string) bool {
+	for _, k := range storage.SearchKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// semantic ranks indexed documents by cosine similarity to the query,
+// embedding documents lazily and caching vectors until their content changes
+func (s *Searcher) semantic(query string, filters storage.SearchFilters) ([]*storage.SearchHit, error) {
+	if s.embedder == nil {
+		return nil, fmt.Errorf("semantic search is not available")
+	}
+
+	limit := filters.Limit
+	filters.Limit = 0
+	docs, err := s.store.ListSearchDocuments(filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	vectors, err := s.documentVectors(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	queryVectors, err := s.embedder.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	hits := make([]*storage.SearchHit, 0, len(docs))
+	for i, doc := range docs {
+		hits = append(hits, &storage.SearchHit{
+			Kind:      doc.Kind,
+			RefID:     doc.RefID,
+			TaskID:    doc.TaskID,
+			TaskTitle: doc.TaskTitle,
+			TaskState: doc.TaskState,
+			Title:     doc.Title,
+			Snippet:   snippet(doc.Body),
+			Score:     cosine(queryVectors[0], vectors[i]),
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
+
+// documentVectors returns an embedding per document, computing and caching missing ones
+func (s *Searcher) documentVectors(docs []*storage.SearchDocument) ([][]float64, error) {
+	model := s.embedder.Model()
+	vectors := make([][]float64, len(docs))
+	hashes := make([]string, len(docs))
+
+	var missing []int
+	for i, doc := range docs {
+		hashes[i] = contentHash(doc)
+		vector, err := s.store.GetSearchEmbedding(doc.RefID, model, hashes[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cached embedding: %w", err)
+		}
+		if vector == nil {
+			missing = append(missing, i)
+		}
+		vectors[i] = vector
+	}
+
+	for start := 0; start < len(missing); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		batch := missing[start:end]
+
+		texts := make([]string, len(batch))
+		for j, i := range batch {
+			texts[j] = documentText(docs[i])
+		}
+
+		embedded, err := s.embedder.Embed(texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed documents: %w", err)
+		}
+
+		for j, i := range batch {
+			vectors[i] = embedded[j]
+			if err := s.store.SaveSearchEmbedding(docs[i].RefID, model, hashes[i], embedded[j]); err != nil {
+				return nil, fmt.Errorf("failed to cache embedding: %w", err)
+			}
+		}
+	}
+
+	return vectors, nil
+}
+
+// documentText is the text embedded for a document
+func documentText(doc *storage.SearchDocument) string {
+	text := doc.Title + "\n\n" + doc.Body
+	if len(text) > maxEmbedChars {
+		text = text[:maxEmbedChars]
+	}
+	return text
+}
+
+// contentHash identifies the embedded content of a document
+func contentHash(doc *storage.SearchDocument) string {
+	sum := sha256.Sum256([]byte(documentText(doc)))
+	return hex.EncodeToString(sum[:])
+}
+
+// snippet returns the start of a document body on a single line
+func snippet(body string) string {
+	text := strings.Join(strings.Fields(body), " ")
+	runes := []rune(text)
+	if len(runes) > snippetChars {
+		return string(runes[:snippetChars]) + "…"
+	}
+	return text
+}
+
+// cosine returns the cosine similarity of two vectors
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,83 @@
+package statemachine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"baton/internal/config"
+)
+
+// ArtifactSchemaError lists every way an artifact fails its configured schema
+type ArtifactSchemaError struct {
+	Artifact string   `json:"artifact"`
+	Problems []string `json:"problems"`
+}
+
+func (e *ArtifactSchemaError) Error() string {
+	return fmt.Sprintf("artifact '%s' does not match its schema: %s", e.Artifact, strings.Join(e.Problems, "; "))
+}
+
+// ValidateArtifact checks artifact content against the schema configured for
+// its name. Artifacts without a schema always pass.
+func ValidateArtifact(schemas map[string]config.ArtifactSchema, name, content string) error {
+	schema, exists := schemas[name]
+	if !exists {
+		return nil
+	}
+
+	var problems []string
+
+	if schema.MinLength > 0 && len(strings.TrimSpace(content)) < schema.MinLength {
+		problems = append(problems, fmt.Sprintf("content is %d characters, at least %d required",
+			len(strings.TrimSpace(content)), schema.MinLength))
+	}
+
+	switch schema.Format {
+	case "json":
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(content), &doc); err != nil {
+			problems = append(problems, fmt.Sprintf("content is not a JSON object: %v", err))
+			break
+		}
+		for _, field := range schema.RequiredFields {
+			if _, ok := doc[field]; !ok {
+				problems = append(problems, fmt.Sprintf("missing field %q", field))
+			}
+		}
+	default:
+		for _, section := range schema.RequiredSections {
+			if !hasSection(content, section) {
+				problems = append(problems, fmt.Sprintf("missing section %q", section))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ArtifactSchemaError{Artifact: name, Problems: problems}
+	}
+	return nil
+}
+
+// hasSection reports whether markdown content has a heading for the section.
+// "## Steps" must match the heading level; "Steps" matches any level. A heading
+// may carry a suffix, so "## Steps (3)" satisfies "## Steps".
+func hasSection(content, section string) bool {
+	want := strings.ToLower(strings.TrimSpace(section))
+	anyLevel := !strings.HasPrefix(want, "#")
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		if anyLevel {
+			line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+		}
+		if line == want || strings.HasPrefix(line, want+" ") || strings.HasPrefix(line, want+":") {
+			return true
+		}
+	}
+
+	return false
+}
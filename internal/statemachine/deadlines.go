@@ -0,0 +1,77 @@
+package statemachine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"baton/internal/storage"
+)
+
+// OverdueTask is an open task past its due date
+type OverdueTask struct {
+	ID           string        `json:"id"`
+	Title        string        `json:"title"`
+	State        storage.State `json:"state"`
+	Owner        string        `json:"owner,omitempty"`
+	DueDate      time.Time     `json:"due_date"`
+	HoursOverdue float64       `json:"hours_overdue"`
+}
+
+// FindOverdueTasks returns the open tasks past their due date at now, most
+// overdue first. Archived tasks are left out.
+func FindOverdueTasks(store *storage.Store, now time.Time) ([]*OverdueTask, error) {
+	tasks, err := store.ListTasks(storage.TaskFilters{DueBefore: &now})
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := []*OverdueTask{}
+	for _, task := range tasks {
+		if !task.IsOverdue(now) {
+			continue
+		}
+		overdue = append(overdue, &OverdueTask{
+			ID:           task.ID,
+			Title:        task.Title,
+			State:        task.State,
+			Owner:        task.Owner,
+			DueDate:      *task.DueDate,
+			HoursOverdue: now.Sub(*task.DueDate).Hours(),
+		})
+	}
+
+	sort.SliceStable(overdue, func(i, j int) bool { return overdue[i].DueDate.Before(overdue[j].DueDate) })
+	return overdue, nil
+}
+
+// earlierDue orders two tasks by due date, tasks without one last. ok is
+// false when neither has a due date or both are due at the same time.
+func earlierDue(a, b *storage.Task) (first bool, ok bool) {
+	switch {
+	case a.DueDate == nil && b.DueDate == nil:
+		return false, false
+	case a.DueDate == nil || b.DueDate == nil:
+		return a.DueDate != nil, true
+	case a.DueDate.Equal(*b.DueDate):
+		return false, false
+	default:
+		return a.DueDate.Before(*b.DueDate), true
+	}
+}
+
+// dueLabel describes a due date relative to now, for selection reasons
+func dueLabel(due, now time.Time) string {
+	if due.Before(now) {
+		return "overdue by " + roughDuration(now.Sub(due))
+	}
+	return "due in " + roughDuration(due.Sub(now))
+}
+
+// roughDuration formats d in whole days, or whole hours below a day
+func roughDuration(d time.Duration) string {
+	if d > 24*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
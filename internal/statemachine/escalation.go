@@ -0,0 +1,55 @@
+package statemachine
+
+import (
+	"fmt"
+	"log"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+// EscalationActor is who escalations are recorded as in the audit log
+const EscalationActor = "baton"
+
+// SetReviewEscalation makes transitions into needs_fixes put a task that keeps
+// failing review on hold, as configured under review_escalation
+func (tv *TransitionValidator) SetReviewEscalation(policy config.ReviewEscalationConfig) {
+	tv.escalation = policy
+}
+
+// Escalate puts a task in needs_fixes on hold once it has failed review as
+// many times as the policy allows, returning the state it was put in, or ""
+// when it was not escalated
+func (tv *TransitionValidator) Escalate(taskID string) (storage.State, error) {
+	if tv.escalation.MaxFailures <= 0 {
+		return "", nil
+	}
+
+	task, err := tv.store.GetTask(taskID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get task %s: %w", taskID, err)
+	}
+	if task.State != storage.NeedsFixes || task.ReviewFailures < tv.escalation.MaxFailures {
+		return "", nil
+	}
+
+	hold := storage.State(tv.escalation.State)
+	if hold == "" {
+		hold = storage.Blocked
+	}
+	if err := tv.store.EscalateTask(taskID, hold, EscalationActor); err != nil {
+		return "", fmt.Errorf("failed to escalate task %s: %w", taskID, err)
+	}
+	return hold, nil
+}
+
+// escalate applies the escalation policy after a transition into newState.
+// The transition stands even when the escalation fails.
+func (tv *TransitionValidator) escalate(taskID string, newState storage.State) {
+	if newState != storage.NeedsFixes {
+		return
+	}
+	if _, err := tv.Escalate(taskID); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
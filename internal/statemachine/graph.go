@@ -0,0 +1,105 @@
+package statemachine
+
+import (
+	"fmt"
+	"strings"
+
+	"baton/internal/storage"
+)
+
+// DependencyGraph is the task dependency graph, with an edge from each task to
+// every task it depends on
+type DependencyGraph struct {
+	dependencies map[string][]string
+	dependents   map[string][]string
+}
+
+// NewDependencyGraph builds the dependency graph of the given tasks
+func NewDependencyGraph(tasks []*storage.Task) *DependencyGraph {
+	g := &DependencyGraph{
+		dependencies: make(map[string][]string),
+		dependents:   make(map[string][]string),
+	}
+	for _, task := range tasks {
+		for _, dep := range task.DependencyList() {
+			g.AddEdge(task.ID, dep)
+		}
+	}
+	return g
+}
+
+// LoadDependencyGraph builds the dependency graph of every task in the store
+func LoadDependencyGraph(store *storage.Store) (*DependencyGraph, error) {
+	tasks, err := store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	return NewDependencyGraph(tasks), nil
+}
+
+// AddEdge records that taskID depends on dependsOn
+func (g *DependencyGraph) AddEdge(taskID, dependsOn string) {
+	for _, existing := range g.dependencies[taskID] {
+		if existing == dependsOn {
+			return
+		}
+	}
+	g.dependencies[taskID] = append(g.dependencies[taskID], dependsOn)
+	g.dependents[dependsOn] = append(g.dependents[dependsOn], taskID)
+}
+
+// Dependencies returns the tasks taskID depends on directly
+func (g *DependencyGraph) Dependencies(taskID string) []string {
+	return g.dependencies[taskID]
+}
+
+// Dependents returns the tasks that depend on taskID directly
+func (g *DependencyGraph) Dependents(taskID string) []string {
+	return g.dependents[taskID]
+}
+
+// Path returns a chain of dependencies leading from one task to another,
+// both included, or nil when from does not depend on to
+func (g *DependencyGraph) Path(from, to string) []string {
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			var path []string
+			for id := to; id != ""; id = previous[id] {
+				path = append([]string{id}, path...)
+			}
+			return path
+		}
+		for _, next := range g.dependencies[current] {
+			if _, seen := previous[next]; !seen {
+				previous[next] = current
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// CycleError reports a dependency that would make tasks wait on each other forever
+type CycleError struct {
+	Cycle []string // task IDs, starting and ending with the same task
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// CheckEdge returns a *CycleError if making taskID depend on dependsOn would
+// create a cycle
+func (g *DependencyGraph) CheckEdge(taskID, dependsOn string) error {
+	if taskID == dependsOn {
+		return &CycleError{Cycle: []string{taskID, taskID}}
+	}
+	if path := g.Path(dependsOn, taskID); path != nil {
+		return &CycleError{Cycle: append([]string{taskID}, path...)}
+	}
+	return nil
+}
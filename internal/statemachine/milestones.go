@@ -0,0 +1,241 @@
+package statemachine
+
+import (
+	"fmt"
+	"sort"
+
+	"baton/internal/storage"
+)
+
+// MilestoneSummary is the progress of one milestone
+type MilestoneSummary struct {
+	Name           string                    `json:"name"`
+	TotalTasks     int                       `json:"total_tasks"`
+	DoneTasks      int                       `json:"done_tasks"`
+	RemainingTasks int                       `json:"remaining_tasks"`
+	BlockedTasks   int                       `json:"blocked_tasks"`
+	PercentDone    float64                   `json:"percent_done"`
+	RemainingHours float64                   `json:"remaining_hours"` // sum of estimates of unfinished tasks
+	Unestimated    int                       `json:"unestimated"`     // unfinished tasks without an estimate
+	ByState        map[string]int            `json:"by_state"`
+	SignedOff      *storage.MilestoneSignoff `json:"signed_off,omitempty"`
+	WaitingFor     string                    `json:"waiting_for,omitempty"` // the earlier milestone whose sign-off holds this one back
+}
+
+// MilestoneTask is an unfinished task within a milestone
+type MilestoneTask struct {
+	ID             string        `json:"id"`
+	Title          string        `json:"title"`
+	State          storage.State `json:"state"`
+	Priority       int           `json:"priority"`
+	Owner          string        `json:"owner,omitempty"`
+	EstimatedHours float64       `json:"estimated_hours,omitempty"`
+	Blocked        bool          `json:"blocked"`
+	BlockedReason  string        `json:"blocked_reason,omitempty"`
+}
+
+// MilestoneProgress is a milestone's summary plus the work that remains in it
+type MilestoneProgress struct {
+	MilestoneSummary
+	Remaining []*MilestoneTask `json:"remaining"`
+}
+
+// ListMilestones summarizes every milestone named by a "milestone:<name>" task tag
+func (ts *TaskSelector) ListMilestones() ([]*MilestoneSummary, error) {
+	progress, err := ts.milestoneProgress("")
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*MilestoneSummary, 0, len(progress))
+	for _, p := range progress {
+		summaries = append(summaries, &p.MilestoneSummary)
+	}
+	return summaries, nil
+}
+
+// GetMilestoneProgress returns one milestone's progress and remaining tasks,
+// or nil if no task belongs to it
+func (ts *TaskSelector) GetMilestoneProgress(name string) (*MilestoneProgress, error) {
+	progress, err := ts.milestoneProgress(name)
+	if err != nil || len(progress) == 0 {
+		return nil, err
+	}
+	return progress[0], nil
+}
+
+// milestoneProgress builds progress for all milestones, or only the named one
+func (ts *TaskSelector) milestoneProgress(only string) ([]*MilestoneProgress, error) {
+	tasks, err := ts.store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+
+	signoffs, err := ts.store.ListMilestoneSignoffs()
+	if err != nil {
+		return nil, err
+	}
+	gates := ts.gatesFor(tasks, signoffs)
+
+	milestones := make(map[string]*MilestoneProgress)
+	for _, task := range tasks {
+		name := task.Milestone()
+		if name == "" || (only != "" && name != only) {
+			continue
+		}
+
+		m, exists := milestones[name]
+		if !exists {
+			m = &MilestoneProgress{
+				MilestoneSummary: MilestoneSummary{
+					Name:       name,
+					ByState:    make(map[string]int),
+					SignedOff:  signoffs[name],
+					WaitingFor: gates[name],
+				},
+				Remaining: []*MilestoneTask{},
+			}
+			milestones[name] = m
+		}
+
+		// Cancelled tasks are left out of the progress, as archived ones are
+		m.ByState[string(task.State)]++
+		if task.State == storage.Cancelled {
+			continue
+		}
+		m.TotalTasks++
+		if IsTerminalState(task.State) {
+			m.DoneTasks++
+			continue
+		}
+
+		blocked, reason := isOnHold(task)
+		if !blocked {
+			blocked, reason = ts.isBlockedByDependencies(task)
+		}
+		if !blocked && ts.isUnassigned(task) {
+			blocked, reason = true, "no agent configured for state "+string(task.State)
+		}
+		if !blocked && gates[name] != "" {
+			blocked, reason = true, milestoneGateReason(gates[name])
+		}
+
+		m.RemainingTasks++
+		m.RemainingHours += task.EstimatedHours
+		if task.EstimatedHours <= 0 {
+			m.Unestimated++
+		}
+		if blocked {
+			m.BlockedTasks++
+		}
+		m.Remaining = append(m.Remaining, &MilestoneTask{
+			ID:             task.ID,
+			Title:          task.Title,
+			State:          task.State,
+			Priority:       task.Priority,
+			Owner:          task.Owner,
+			EstimatedHours: task.EstimatedHours,
+			Blocked:        blocked,
+			BlockedReason:  reason,
+		})
+	}
+
+	names := make([]string, 0, len(milestones))
+	for name := range milestones {
+		names = append(names, name)
+	}
+	result := make([]*MilestoneProgress, 0, len(milestones))
+	for _, name := range OrderMilestones(names, ts.config.MilestoneOrder) {
+		m := milestones[name]
+		m.PercentDone = float64(m.DoneTasks) / float64(m.TotalTasks) * 100
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+// OrderMilestones sorts milestone names into delivery order: the ones in
+// order as listed there, then the rest by name
+func OrderMilestones(names []string, order []string) []string {
+	rank := make(map[string]int, len(order))
+	for i, name := range order {
+		if _, exists := rank[name]; !exists {
+			rank[name] = i
+		}
+	}
+
+	ordered := append([]string(nil), names...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		ri, iListed := rank[ordered[i]]
+		rj, jListed := rank[ordered[j]]
+		switch {
+		case iListed && jListed:
+			return ri < rj
+		case iListed != jListed:
+			return iListed
+		default:
+			return ordered[i] < ordered[j]
+		}
+	})
+	return ordered
+}
+
+// milestoneGates returns, for every milestone held back by an earlier one
+// that is not signed off, the name of that earlier milestone. It is empty
+// unless selection.milestone_signoff is on.
+func (ts *TaskSelector) milestoneGates() (map[string]string, error) {
+	if !ts.config.MilestoneSignoff {
+		return nil, nil
+	}
+
+	tasks, err := ts.store.ListTasks(storage.TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+	signoffs, err := ts.store.ListMilestoneSignoffs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list milestone sign-offs: %w", err)
+	}
+	return ts.gatesFor(tasks, signoffs), nil
+}
+
+// gatesFor computes milestoneGates from the given tasks and sign-offs
+func (ts *TaskSelector) gatesFor(tasks []*storage.Task, signoffs map[string]*storage.MilestoneSignoff) map[string]string {
+	if !ts.config.MilestoneSignoff {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, task := range tasks {
+		if name := task.Milestone(); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	gates := make(map[string]string)
+	pending := ""
+	for _, name := range OrderMilestones(names, ts.config.MilestoneOrder) {
+		if pending != "" {
+			gates[name] = pending
+		} else if signoffs[name] == nil {
+			pending = name
+		}
+	}
+	return gates
+}
+
+// isMilestoneGated reports whether the task's milestone waits for the
+// sign-off of an earlier one
+func (ts *TaskSelector) isMilestoneGated(task *storage.Task, gates map[string]string) (bool, string) {
+	if earlier := gates[task.Milestone()]; earlier != "" {
+		return true, milestoneGateReason(earlier)
+	}
+	return false, ""
+}
+
+// milestoneGateReason explains that a task waits for an earlier milestone
+func milestoneGateReason(earlier string) string {
+	return fmt.Sprintf("milestone %s is not signed off (baton milestones signoff %s)", earlier, earlier)
+}
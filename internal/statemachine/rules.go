@@ -0,0 +1,106 @@
+package statemachine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+// SetTransitionRules makes transitions check the configured conditions on
+// handover artifact metadata
+func (tv *TransitionValidator) SetTransitionRules(rules []config.TransitionRule) {
+	tv.rules = rules
+}
+
+// requiredHandovers returns the built-in handovers of a transition followed
+// by the artifacts its transition rules check
+func (tv *TransitionValidator) requiredHandovers(from, to storage.State) []string {
+	required := getRequiredHandovers(from, to)
+	for _, name := range RuleArtifacts(tv.rules, from, to) {
+		if !containsName(required, name) {
+			required = append(required, name)
+		}
+	}
+	return required
+}
+
+// RuleArtifacts returns the artifacts the transition rules of a transition check
+func RuleArtifacts(rules []config.TransitionRule, from, to storage.State) []string {
+	var names []string
+	for _, rule := range rules {
+		if rule.Matches(string(from), string(to)) && !containsName(names, rule.Artifact) {
+			names = append(names, rule.Artifact)
+		}
+	}
+	return names
+}
+
+// ArtifactGetter reads a task's artifacts, as *storage.Store does
+type ArtifactGetter interface {
+	GetArtifact(taskID, name string, version int) (*storage.Artifact, error)
+}
+
+// CheckTransitionRules checks the transition rules of a task's move to
+// newState. Artifacts that don't exist are left to the required handover check.
+func CheckTransitionRules(store ArtifactGetter, rules []config.TransitionRule, task *storage.Task, newState storage.State) error {
+	if violations := ruleViolations(store, rules, task, newState); len(violations) > 0 {
+		return fmt.Errorf("transition from %s to %s refused: %s", task.State, newState, strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// ruleViolations lists the transition rules a move to newState fails
+func ruleViolations(store ArtifactGetter, rules []config.TransitionRule, task *storage.Task, newState storage.State) []string {
+	var violations []string
+	for _, rule := range rules {
+		if !rule.Matches(string(task.State), string(newState)) {
+			continue
+		}
+		artifact, err := store.GetArtifact(task.ID, rule.Artifact, 0)
+		if err != nil {
+			continue
+		}
+		value, present := metaField(artifact.Meta, rule.Field)
+		if rule.Satisfied(value, present) {
+			continue
+		}
+		violation := fmt.Sprintf("requires %s", rule.Condition())
+		if rule.Message != "" {
+			violation = fmt.Sprintf("%s (%s)", rule.Message, rule.Condition())
+		}
+		violations = append(violations, violation)
+	}
+	return violations
+}
+
+// metaField looks up a field of artifact metadata, with dots separating the
+// keys of nested objects
+func metaField(meta json.RawMessage, field string) (interface{}, bool) {
+	var value interface{}
+	if len(meta) == 0 || json.Unmarshal(meta, &value) != nil {
+		return nil, false
+	}
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// containsName reports whether names holds name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package statemachine
+
+import (
+	"fmt"
+	"time"
+
+	"baton/internal/storage"
+)
+
+// ageHorizon is how long a task must sit untouched to earn the full age score
+const ageHorizon = 7 * 24 * time.Hour
+
+// deadlineHorizon is how far ahead of its due date a task starts to earn a
+// deadline score, which reaches 1 on the due date
+const deadlineHorizon = 7 * 24 * time.Hour
+
+// maxPriority is the top of the task priority scale
+const maxPriority = 10
+
+// TaskScore is a task's weighted selection score. Each factor lies between 0
+// and 1 and Total is their sum weighted by the selection config.
+type TaskScore struct {
+	Priority  float64 `json:"priority"`
+	Age       float64 `json:"age"`
+	Estimate  float64 `json:"estimate"`
+	FanOut    float64 `json:"fanout"`
+	Milestone float64 `json:"milestone"`
+	Deadline  float64 `json:"deadline"`
+	Total     float64 `json:"total"`
+}
+
+// String lists the score and its factors, for selection reasons
+func (s *TaskScore) String() string {
+	return fmt.Sprintf("score %.2f (priority %.2f, age %.2f, estimate %.2f, fan-out %.2f, milestone %.2f, deadline %.2f)",
+		s.Total, s.Priority, s.Age, s.Estimate, s.FanOut, s.Milestone, s.Deadline)
+}
+
+// scoringContext holds the workspace-wide figures scores are computed from
+type scoringContext struct {
+	now        time.Time
+	dependents map[string]int     // unfinished tasks depending on each task
+	milestones map[string]float64 // fraction of each milestone's tasks that are done
+}
+
+// newScoringContext counts dependents and milestone progress over all tasks
+func newScoringContext(tasks []*storage.Task) *scoringContext {
+	ctx := &scoringContext{
+		now:        time.Now(),
+		dependents: make(map[string]int),
+		milestones: make(map[string]float64),
+	}
+
+	totals := make(map[string]int)
+	done := make(map[string]int)
+	for _, task := range tasks {
+		if milestone := task.Milestone(); milestone != "" {
+			totals[milestone]++
+			if IsTerminalState(task.State) {
+				done[milestone]++
+			}
+		}
+		if IsTerminalState(task.State) {
+			continue
+		}
+		for _, depID := range task.DependencyList() {
+			ctx.dependents[depID]++
+		}
+	}
+	for milestone, total := range totals {
+		ctx.milestones[milestone] = float64(done[milestone]) / float64(total)
+	}
+
+	return ctx
+}
+
+// score computes a task's weighted selection score within ctx
+func (ts *TaskSelector) score(task *storage.Task, ctx *scoringContext) *TaskScore {
+	s := &TaskScore{
+		Priority: clamp01(float64(task.Priority) / maxPriority),
+		Age:      clamp01(float64(ctx.now.Sub(task.UpdatedAt)) / float64(ageHorizon)),
+	}
+
+	// Smaller estimated tasks score higher; unestimated tasks get nothing
+	if task.EstimatedHours > 0 {
+		s.Estimate = 1 / (1 + task.EstimatedHours)
+	}
+
+	// 0 for no dependents, approaching 1 as more tasks wait on this one
+	if n := ctx.dependents[task.ID]; n > 0 {
+		s.FanOut = float64(n) / float64(n+1)
+	}
+
+	if milestone := task.Milestone(); milestone != "" {
+		s.Milestone = ctx.milestones[milestone]
+	}
+
+	// 0 without a due date or more than a horizon away, 1 once it is due
+	if task.DueDate != nil {
+		s.Deadline = clamp01(1 - float64(task.DueDate.Sub(ctx.now))/float64(deadlineHorizon))
+	}
+
+	s.Total = ts.config.PriorityWeight*s.Priority +
+		ts.config.AgeWeight*s.Age +
+		ts.config.EstimateWeight*s.Estimate +
+		ts.config.FanOutWeight*s.FanOut +
+		ts.config.MilestoneWeight*s.Milestone +
+		ts.config.DeadlineWeight*s.Deadline
+	return s
+}
+
+// clamp01 limits v to [0, 1]
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
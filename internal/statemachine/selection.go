@@ -14,6 +14,15 @@ import (
 type TaskSelector struct {
 	store  *storage.Store
 	config *config.SelectionConfig
+
+	// hasAgent reports whether some agent handles a state; nil means every state is covered
+	hasAgent func(state storage.State) bool
+
+	// until is the state tasks are worked up to; "" means all the way
+	until storage.State
+
+	// only accepts the states of tasks that may be selected; nil accepts all
+	only func(state storage.State) bool
 }
 
 // NewTaskSelector creates a new task selector
@@ -24,10 +33,47 @@ func NewTaskSelector(store *storage.Store, config *config.SelectionConfig) *Task
 	}
 }
 
+// SetAgentCoverage tells the selector which states have an agent, so that
+// with skip_unassigned_states it passes over tasks nobody can work on
+func (ts *TaskSelector) SetAgentCoverage(hasAgent func(state storage.State) bool) {
+	ts.hasAgent = hasAgent
+}
+
+// AgentCoverage returns a coverage check for SetAgentCoverage backed by the configured agents
+func AgentCoverage(cfg *config.Config) func(state storage.State) bool {
+	return func(state storage.State) bool {
+		_, ok := cfg.AgentForState(string(state))
+		return ok
+	}
+}
+
+// SetUntilState makes the selector pass over tasks that have reached state, or
+// a state after it in the workflow, so that work stops there
+func (ts *TaskSelector) SetUntilState(state storage.State) {
+	ts.until = state
+}
+
+// SetStateFilter makes the selector pass over tasks in states filter does not
+// accept; nil accepts every state again
+func (ts *TaskSelector) SetStateFilter(filter func(state storage.State) bool) {
+	ts.only = filter
+}
+
+// hasReachedUntil reports whether a task is at or past the until state
+func (ts *TaskSelector) hasReachedUntil(task *storage.Task) bool {
+	return ts.until != "" && storage.StateRank(task.State) >= storage.StateRank(ts.until)
+}
+
+// isUnassigned reports whether a task should be skipped because no agent handles its state
+func (ts *TaskSelector) isUnassigned(task *storage.Task) bool {
+	return ts.config.SkipUnassignedStates && ts.hasAgent != nil && !ts.hasAgent(task.State)
+}
+
 // SelectionResult represents the result of task selection
 type SelectionResult struct {
 	Task   *storage.Task `json:"task"`
 	Reason string        `json:"reason"`
+	Score  *TaskScore    `json:"score,omitempty"` // set by the weighted algorithm
 }
 
 // SelectNext selects the next task to work on
@@ -44,14 +90,74 @@ func (ts *TaskSelector) SelectNext() (*SelectionResult, error) {
 
 	// Apply selection algorithm
 	switch ts.config.Algorithm {
-	case "priority_dependency":
+	case "priority_dependency", "weighted":
 		return ts.selectByPriorityAndDependency(tasks)
 	default:
 		return nil, fmt.Errorf("unknown selection algorithm: %s", ts.config.Algorithm)
 	}
 }
 
-// getSelectableTasks returns tasks that are not in terminal states
+// ManualSelectionReason is the selection reason of a task chosen by the user
+const ManualSelectionReason = "manual selection"
+
+// SelectTask selects the given task instead of choosing one, for cycles the
+// user starts on a specific task. It applies the checks SelectNext applies to
+// every candidate: a terminal or hold state, a cycle already working on it,
+// unfinished dependencies, no agent for the state, a locked area, an earlier
+// milestone awaiting sign-off or a full WIP limit on the state it would move
+// into reject the task.
+func (ts *TaskSelector) SelectTask(taskID string) (*SelectionResult, error) {
+	task, err := ts.store.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task %s not found: %w", taskID, err)
+	}
+
+	if IsTerminalState(task.State) {
+		return nil, fmt.Errorf("task %s cannot be started: it is %s", task.ID, task.State)
+	}
+	if storage.IsHeld(task.State) {
+		return nil, fmt.Errorf("task %s cannot be started: it is %s; resume it with 'baton tasks resume %s'", task.ID, task.State, task.ID)
+	}
+	if locked, reason := isTaskLocked(task); locked {
+		return nil, fmt.Errorf("task %s cannot be started: %s", task.ID, reason)
+	}
+	if blocked, reason := ts.isBlockedByDependencies(task); blocked {
+		return nil, fmt.Errorf("task %s cannot be started: %s", task.ID, reason)
+	}
+	// Unlike selection, an explicit request fails whether or not unassigned states are skipped
+	if ts.hasAgent != nil && !ts.hasAgent(task.State) {
+		return nil, fmt.Errorf("task %s cannot be started: no agent configured for state %s", task.ID, task.State)
+	}
+
+	locks, err := ts.areaLocks()
+	if err != nil {
+		return nil, err
+	}
+	if locked, reason := ts.isAreaLocked(task, locks); locked {
+		return nil, fmt.Errorf("task %s cannot be started: %s", task.ID, reason)
+	}
+	gates, err := ts.milestoneGates()
+	if err != nil {
+		return nil, err
+	}
+	if gated, reason := ts.isMilestoneGated(task, gates); gated {
+		return nil, fmt.Errorf("task %s cannot be started: %s", task.ID, reason)
+	}
+	counts, err := ts.wipCounts()
+	if err != nil {
+		return nil, err
+	}
+	if limited, reason := ts.isWIPLimited(task, counts); limited {
+		return nil, fmt.Errorf("task %s cannot be started: %s", task.ID, reason)
+	}
+
+	return &SelectionResult{
+		Task:   task,
+		Reason: fmt.Sprintf("%s (state: %s)", ManualSelectionReason, task.State),
+	}, nil
+}
+
+// getSelectableTasks returns tasks that are neither in terminal states nor on hold
 func (ts *TaskSelector) getSelectableTasks() ([]*storage.Task, error) {
 	allTasks, err := ts.store.ListTasks(storage.TaskFilters{})
 	if err != nil {
@@ -60,7 +166,8 @@ func (ts *TaskSelector) getSelectableTasks() ([]*storage.Task, error) {
 
 	var selectable []*storage.Task
 	for _, task := range allTasks {
-		if !IsTerminalState(task.State) {
+		if !IsTerminalState(task.State) && !storage.IsHeld(task.State) && !ts.hasReachedUntil(task) &&
+			(ts.only == nil || ts.only(task.State)) {
 			selectable = append(selectable, task)
 		}
 	}
@@ -68,8 +175,31 @@ func (ts *TaskSelector) getSelectableTasks() ([]*storage.Task, error) {
 	return selectable, nil
 }
 
-// selectByPriorityAndDependency implements the priority+dependency selection algorithm
+// selectByPriorityAndDependency implements the priority+dependency selection
+// algorithm and, ranking unblocked tasks by score instead, the weighted one
 func (ts *TaskSelector) selectByPriorityAndDependency(tasks []*storage.Task) (*SelectionResult, error) {
+	var scoring *scoringContext
+	if ts.config.Algorithm == "weighted" {
+		allTasks, err := ts.store.ListTasks(storage.TaskFilters{})
+		if err != nil {
+			return nil, err
+		}
+		scoring = newScoringContext(allTasks)
+	}
+
+	locks, err := ts.areaLocks()
+	if err != nil {
+		return nil, err
+	}
+	gates, err := ts.milestoneGates()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := ts.wipCounts()
+	if err != nil {
+		return nil, err
+	}
+
 	// Filter out blocked tasks
 	var candidates []*taskCandidate
 	for _, task := range tasks {
@@ -80,8 +210,23 @@ func (ts *TaskSelector) selectByPriorityAndDependency(tasks []*storage.Task) (*S
 			Priority: task.Priority,
 		}
 
-		// Check if blocked by dependencies
-		if blocked, reason := ts.isBlockedByDependencies(task); blocked {
+		// Check if another cycle is on it or blocked by dependencies
+		if locked, reason := isTaskLocked(task); locked {
+			candidate.Blocked = true
+			candidate.BlockReason = reason
+		} else if blocked, reason := ts.isBlockedByDependencies(task); blocked {
+			candidate.Blocked = true
+			candidate.BlockReason = reason
+		} else if ts.isUnassigned(task) {
+			candidate.Blocked = true
+			candidate.BlockReason = fmt.Sprintf("no agent configured for state %s", task.State)
+		} else if locked, reason := ts.isAreaLocked(task, locks); locked {
+			candidate.Blocked = true
+			candidate.BlockReason = reason
+		} else if gated, reason := ts.isMilestoneGated(task, gates); gated {
+			candidate.Blocked = true
+			candidate.BlockReason = reason
+		} else if limited, reason := ts.isWIPLimited(task, counts); limited {
 			candidate.Blocked = true
 			candidate.BlockReason = reason
 		}
@@ -93,6 +238,10 @@ func (ts *TaskSelector) selectByPriorityAndDependency(tasks []*storage.Task) (*S
 			candidate.IsLeaf = !hasDependent
 		}
 
+		if scoring != nil {
+			candidate.Score = ts.score(task, scoring)
+		}
+
 		candidates = append(candidates, candidate)
 	}
 
@@ -118,6 +267,7 @@ func (ts *TaskSelector) selectByPriorityAndDependency(tasks []*storage.Task) (*S
 	return &SelectionResult{
 		Task:   selected.Task,
 		Reason: reason,
+		Score:  selected.Score,
 	}, nil
 }
 
@@ -128,10 +278,67 @@ type taskCandidate struct {
 	BlockReason string
 	IsLeaf      bool
 	Priority    int
+	Score       *TaskScore // nil unless the weighted algorithm is in use
 }
 
-// isBlockedByDependencies checks if a task is blocked by incomplete dependencies
+// areaLocks returns the held area locks by area, or nil when area locks are off
+func (ts *TaskSelector) areaLocks() (map[string]*storage.AreaLock, error) {
+	if !ts.config.AreaLocks.Enabled {
+		return nil, nil
+	}
+
+	locks, err := ts.store.ListAreaLocks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list area locks: %w", err)
+	}
+
+	byArea := make(map[string]*storage.AreaLock, len(locks))
+	for _, lock := range locks {
+		byArea[lock.Area] = lock
+	}
+	return byArea, nil
+}
+
+// isAreaLocked reports whether a running cycle holds one of the areas a cycle
+// on task would lock. This includes a cycle on the task itself, since
+// selection does not claim tasks.
+func (ts *TaskSelector) isAreaLocked(task *storage.Task, locks map[string]*storage.AreaLock) (bool, string) {
+	for _, area := range ts.config.AreaLocks.AreasFor(task.TagList(), string(task.State)) {
+		if lock, held := locks[area]; held {
+			return true, fmt.Sprintf("area %s is locked by the cycle on task %s", area, lock.TaskID)
+		}
+	}
+	return false, ""
+}
+
+// isTaskLocked reports whether a running cycle holds the task's lock
+func isTaskLocked(task *storage.Task) (bool, string) {
+	if !task.IsLocked(time.Now()) {
+		return false, ""
+	}
+	return true, fmt.Sprintf("locked by %s", task.LockedBy)
+}
+
+// isOnHold reports whether a task is blocked or paused, and why it can't be selected
+func isOnHold(task *storage.Task) (bool, string) {
+	if !storage.IsHeld(task.State) {
+		return false, ""
+	}
+	return true, fmt.Sprintf("on hold (%s) until resumed", task.State)
+}
+
+// isBlockedByDependencies checks if a task is blocked by incomplete
+// dependencies or, whatever selection.dependency_strict says, by subtasks
+// grouped under it that are not done yet
 func (ts *TaskSelector) isBlockedByDependencies(task *storage.Task) (bool, string) {
+	subtask, err := unfinishedSubtask(ts.store, task.ID)
+	if err != nil {
+		return true, fmt.Sprintf("failed to list subtasks: %v", err)
+	}
+	if subtask != nil {
+		return true, fmt.Sprintf("subtask %s (%s) not complete", subtask.ID, subtask.Title)
+	}
+
 	if !ts.config.DependencyStrict {
 		return false, ""
 	}
@@ -157,6 +364,21 @@ func (ts *TaskSelector) isBlockedByDependencies(task *storage.Task) (bool, strin
 	return false, ""
 }
 
+// unfinishedSubtask returns a subtask of the task that is not done yet, or
+// nil; a cancelled subtask doesn't hold up its parent
+func unfinishedSubtask(store *storage.Store, taskID string) (*storage.Task, error) {
+	children, err := store.ListChildTasks(taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if child.State != storage.Done && child.State != storage.Cancelled {
+			return child, nil
+		}
+	}
+	return nil, nil
+}
+
 // hasUnfinishedDependents checks if other unfinished tasks depend on this task
 func (ts *TaskSelector) hasUnfinishedDependents(task *storage.Task) (bool, error) {
 	allTasks, err := ts.store.ListTasks(storage.TaskFilters{})
@@ -165,7 +387,7 @@ func (ts *TaskSelector) hasUnfinishedDependents(task *storage.Task) (bool, error
 	}
 
 	for _, otherTask := range allTasks {
-		if otherTask.ID == task.ID || otherTask.State == storage.Done {
+		if otherTask.ID == task.ID || IsTerminalState(otherTask.State) {
 			continue
 		}
 
@@ -191,38 +413,61 @@ func (ts *TaskSelector) sortCandidates(candidates []*taskCandidate) {
 	sort.Slice(candidates, func(i, j int) bool {
 		a, b := candidates[i], candidates[j]
 
+		// Weighted selection ranks by score and only falls back to the tie breaker
+		if a.Score != nil && b.Score != nil {
+			if a.Score.Total != b.Score.Total {
+				return a.Score.Total > b.Score.Total
+			}
+			return ts.breakTie(a, b)
+		}
+
 		// 1. Priority (higher priority first)
 		if a.Priority != b.Priority {
 			return a.Priority > b.Priority
 		}
 
-		// 2. Leaf preference (if enabled)
+		// 2. Due date (earliest first, tasks without one last)
+		if dueFirst, ok := earlierDue(a.Task, b.Task); ok {
+			return dueFirst
+		}
+
+		// 3. Leaf preference (if enabled)
 		if ts.config.PreferLeafTasks {
 			if a.IsLeaf != b.IsLeaf {
 				return a.IsLeaf // prefer leaf tasks
 			}
 		}
 
-		// 3. Tie breaker
-		switch ts.config.TieBreaker {
-		case "oldest_updated":
-			return a.Task.UpdatedAt.Before(b.Task.UpdatedAt)
-		case "newest_created":
-			return a.Task.CreatedAt.After(b.Task.CreatedAt)
-		case "alphabetical":
-			return a.Task.Title < b.Task.Title
-		default:
-			return a.Task.UpdatedAt.Before(b.Task.UpdatedAt)
-		}
+		// 4. Tie breaker
+		return ts.breakTie(a, b)
 	})
 }
 
+// breakTie orders two otherwise equal candidates by the configured tie breaker
+func (ts *TaskSelector) breakTie(a, b *taskCandidate) bool {
+	switch ts.config.TieBreaker {
+	case "oldest_updated":
+		return a.Task.UpdatedAt.Before(b.Task.UpdatedAt)
+	case "newest_created":
+		return a.Task.CreatedAt.After(b.Task.CreatedAt)
+	case "alphabetical":
+		return a.Task.Title < b.Task.Title
+	default:
+		return a.Task.UpdatedAt.Before(b.Task.UpdatedAt)
+	}
+}
+
 // buildSelectionReason builds a human-readable explanation of why a task was selected
 func (ts *TaskSelector) buildSelectionReason(selected *taskCandidate, totalTasks, availableTasks int) string {
 	reason := fmt.Sprintf("Selected from %d total tasks (%d available)", totalTasks, availableTasks)
 
 	criteria := []string{}
 
+	// Weighted score, with the factors that produced it
+	if selected.Score != nil {
+		criteria = append(criteria, selected.Score.String())
+	}
+
 	// Priority
 	if selected.Priority > 5 {
 		criteria = append(criteria, fmt.Sprintf("high priority (%d)", selected.Priority))
@@ -232,6 +477,11 @@ func (ts *TaskSelector) buildSelectionReason(selected *taskCandidate, totalTasks
 		criteria = append(criteria, fmt.Sprintf("low priority (%d)", selected.Priority))
 	}
 
+	// Deadline
+	if selected.Task.DueDate != nil {
+		criteria = append(criteria, dueLabel(*selected.Task.DueDate, time.Now()))
+	}
+
 	// Leaf status
 	if ts.config.PreferLeafTasks && selected.IsLeaf {
 		criteria = append(criteria, "leaf task (no dependents)")
@@ -286,6 +536,16 @@ func (ts *TaskSelector) GetTaskStatus() (map[string]interface{}, error) {
 		"blocked_tasks":  []map[string]interface{}{},
 		"ready_tasks":    []map[string]interface{}{},
 		"completed_tasks": 0,
+		"cancelled_tasks": 0,
+	}
+
+	locks, err := ts.areaLocks()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := ts.wipCounts()
+	if err != nil {
+		return nil, err
 	}
 
 	var blockedTasks []map[string]interface{}
@@ -301,9 +561,28 @@ func (ts *TaskSelector) GetTaskStatus() (map[string]interface{}, error) {
 			status["completed_tasks"] = status["completed_tasks"].(int) + 1
 		}
 
+		// Cancelled tasks are left out of the total, as archived ones are
+		if task.State == storage.Cancelled {
+			status["cancelled_tasks"] = status["cancelled_tasks"].(int) + 1
+			status["total_tasks"] = status["total_tasks"].(int) - 1
+		}
+
 		// Check if blocked
 		if !IsTerminalState(task.State) {
-			if blocked, reason := ts.isBlockedByDependencies(task); blocked {
+			blocked, reason := isOnHold(task)
+			if !blocked {
+				blocked, reason = ts.isBlockedByDependencies(task)
+			}
+			if !blocked && ts.isUnassigned(task) {
+				blocked, reason = true, fmt.Sprintf("no agent configured for state %s", task.State)
+			}
+			if !blocked {
+				blocked, reason = ts.isAreaLocked(task, locks)
+			}
+			if !blocked {
+				blocked, reason = ts.isWIPLimited(task, counts)
+			}
+			if blocked {
 				blockedTasks = append(blockedTasks, map[string]interface{}{
 					"id":     task.ID,
 					"title":  task.Title,
@@ -323,6 +602,14 @@ func (ts *TaskSelector) GetTaskStatus() (map[string]interface{}, error) {
 
 	status["blocked_tasks"] = blockedTasks
 	status["ready_tasks"] = readyTasks
+	if locks != nil {
+		areaLocks := make([]*storage.AreaLock, 0, len(locks))
+		for _, lock := range locks {
+			areaLocks = append(areaLocks, lock)
+		}
+		sort.Slice(areaLocks, func(i, j int) bool { return areaLocks[i].Area < areaLocks[j].Area })
+		status["area_locks"] = areaLocks
+	}
 
 	return status, nil
 }
\ No newline at end of file
@@ -0,0 +1,63 @@
+package statemachine
+
+import (
+	"sort"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+// StaleTask is a task that has been in a watched work state for longer than
+// the staleness threshold of that state
+type StaleTask struct {
+	ID             string        `json:"id"`
+	Title          string        `json:"title"`
+	State          storage.State `json:"state"`
+	Owner          string        `json:"owner,omitempty"`
+	EnteredAt      time.Time     `json:"entered_at"` // when the task moved into its state
+	HoursInState   float64       `json:"hours_in_state"`
+	ThresholdHours float64       `json:"threshold_hours"` // how long the state may last, from the configuration
+}
+
+// FindStaleTasks returns the tasks stuck in a work state at now, per the
+// staleness configuration, longest stuck first. Archived tasks are left out.
+func FindStaleTasks(store *storage.Store, cfg config.StalenessConfig, now time.Time) ([]*StaleTask, error) {
+	stale := []*StaleTask{}
+	if cfg.AfterHours <= 0 {
+		return stale, nil
+	}
+
+	tasks, err := store.ListTasks(storage.TaskFilters{})
+	if err != nil {
+		return nil, err
+	}
+	entered, err := store.CurrentStateEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		threshold := cfg.Threshold(string(task.State))
+		enteredAt, ok := entered[task.ID]
+		if threshold <= 0 || !ok {
+			continue
+		}
+		inState := now.Sub(enteredAt)
+		if inState <= threshold {
+			continue
+		}
+		stale = append(stale, &StaleTask{
+			ID:             task.ID,
+			Title:          task.Title,
+			State:          task.State,
+			Owner:          task.Owner,
+			EnteredAt:      enteredAt,
+			HoursInState:   inState.Hours(),
+			ThresholdHours: threshold.Hours(),
+		})
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool { return stale[i].EnteredAt.Before(stale[j].EnteredAt) })
+	return stale, nil
+}
@@ -2,6 +2,7 @@ package statemachine
 
 import (
 	"fmt"
+	"strings"
 
 	"baton/internal/storage"
 )
@@ -46,6 +47,30 @@ var ValidTransitions = map[storage.State][]storage.State{
 	storage.Done: {
 		// Terminal state - no transitions
 	},
+	// Won't do: entered from any non-terminal state (see init), with a reason
+	storage.Cancelled: {
+		// Terminal state - no transitions
+	},
+	// Hold states: entered from any non-terminal state (see init), left for
+	// the state they were entered from by resuming
+	storage.Blocked: {
+		storage.Paused,
+	},
+	storage.Paused: {
+		storage.Blocked,
+	},
+}
+
+func init() {
+	for state, targets := range ValidTransitions {
+		if len(targets) == 0 {
+			continue
+		}
+		if !storage.IsHeld(state) {
+			targets = append(targets, storage.Blocked, storage.Paused)
+		}
+		ValidTransitions[state] = append(targets, storage.Cancelled)
+	}
 }
 
 // ValidateTransition validates if a state transition is allowed
@@ -66,9 +91,21 @@ func ValidateTransition(from, to storage.State) error {
 		}
 	}
 
+	if storage.IsHeld(from) {
+		return fmt.Errorf("invalid transition from %s to %s: resume the task with 'baton tasks resume' first", from, to)
+	}
 	return fmt.Errorf("invalid transition from %s to %s. Allowed transitions: %v", from, to, allowedStates)
 }
 
+// ValidateReason checks that a move to cancelled comes with a reason for
+// not doing the task
+func ValidateReason(to storage.State, note string) error {
+	if storage.NormalizeState(string(to)) == storage.Cancelled && strings.TrimSpace(note) == "" {
+		return fmt.Errorf("cancelling a task requires a reason note")
+	}
+	return nil
+}
+
 // GetAllowedTransitions returns the list of allowed transitions from a given state
 func GetAllowedTransitions(from storage.State) ([]storage.State, error) {
 	from = storage.NormalizeState(string(from))
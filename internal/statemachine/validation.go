@@ -2,14 +2,26 @@ package statemachine
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 
+	"baton/internal/config"
+	"baton/internal/hooks"
+	"baton/internal/plan"
 	"baton/internal/storage"
 )
 
 // TransitionValidator handles state transition validation and enforcement
 type TransitionValidator struct {
-	store *storage.Store
+	store   *storage.Store
+	schemas map[string]config.ArtifactSchema
+	hooks   *hooks.Runner
+	rules   []config.TransitionRule
+
+	wipLimits  map[string]int // most tasks a state may hold, by state
+	escalation config.ReviewEscalationConfig
 }
 
 // NewTransitionValidator creates a new transition validator
@@ -19,7 +31,20 @@ func NewTransitionValidator(store *storage.Store) *TransitionValidator {
 	}
 }
 
-// ValidateAndTransition validates a transition and updates the task state
+// SetArtifactSchemas makes transitions check required handovers against their configured schemas
+func (tv *TransitionValidator) SetArtifactSchemas(schemas map[string]config.ArtifactSchema) {
+	tv.schemas = schemas
+}
+
+// SetHooks makes transitions run the configured transition hooks
+func (tv *TransitionValidator) SetHooks(runner *hooks.Runner) {
+	tv.hooks = runner
+}
+
+// ValidateAndTransition validates a transition and updates the task state.
+// Blocking hooks run before the state changes and refuse it if one fails;
+// the others run after. Hook results are recorded in an audit entry. A task
+// moved into needs_fixes too often is then escalated.
 func (tv *TransitionValidator) ValidateAndTransition(taskID string, newState storage.State, note string) error {
 	// Get current task
 	task, err := tv.store.GetTask(taskID)
@@ -27,6 +52,97 @@ func (tv *TransitionValidator) ValidateAndTransition(taskID string, newState sto
 		return fmt.Errorf("failed to get task %s: %w", taskID, err)
 	}
 
+	if err := tv.Validate(task, newState); err != nil {
+		return err
+	}
+	if err := ValidateReason(newState, note); err != nil {
+		return err
+	}
+
+	if tv.hooks == nil {
+		if err := tv.transition(taskID, newState, note); err != nil {
+			return err
+		}
+		tv.escalate(taskID, newState)
+		return nil
+	}
+
+	results := tv.hooks.Run(task, newState, note, true)
+	if failed := hooks.FirstFailure(results); failed != nil {
+		tv.logHooks(task, newState, results, "blocked")
+		return fmt.Errorf("hook %q failed: %s", failed.Hook, failed.Error)
+	}
+
+	if err := tv.transition(taskID, newState, note); err != nil {
+		return err
+	}
+
+	results = append(results, tv.hooks.Run(task, newState, note, false)...)
+	result := "success"
+	if hooks.FirstFailure(results) != nil {
+		result = "hook_failed"
+	}
+	tv.logHooks(task, newState, results, result)
+	tv.escalate(taskID, newState)
+	return nil
+}
+
+// CancelNoteAuthor is who the reason of a cancelled task is recorded as
+const CancelNoteAuthor = "baton"
+
+// transition performs the state change; the reason a task won't be done stays
+// on it as a note
+func (tv *TransitionValidator) transition(taskID string, newState storage.State, note string) error {
+	if err := tv.store.UpdateTaskState(taskID, newState, note); err != nil {
+		return err
+	}
+	if newState != storage.Cancelled {
+		return nil
+	}
+	return RecordCancelReason(tv.store, taskID, note)
+}
+
+// RecordCancelReason keeps the reason a cancelled task won't be done on it as
+// a note
+func RecordCancelReason(store *storage.Store, taskID, reason string) error {
+	note := &storage.TaskNote{TaskID: taskID, Author: CancelNoteAuthor, Body: string(storage.Cancelled) + ": " + strings.TrimSpace(reason)}
+	if err := store.AddTaskNote(note); err != nil {
+		return fmt.Errorf("task %s cancelled, but its reason was not recorded: %w", taskID, err)
+	}
+	return nil
+}
+
+// logHooks records the hooks run for a transition in the audit log. The
+// transition is not undone when the entry can't be written.
+func (tv *TransitionValidator) logHooks(task *storage.Task, newState storage.State, results []*hooks.Result, result string) {
+	if len(results) == 0 {
+		return
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Success {
+			failed++
+		}
+	}
+	commands, _ := json.Marshal(results)
+	entry := &storage.AuditLog{
+		TaskID:    task.ID,
+		CycleID:   "hooks",
+		PrevState: string(task.State),
+		NextState: string(newState),
+		Actor:     "hooks",
+		Commands:  commands,
+		Result:    result,
+		Note:      fmt.Sprintf("%d transition hooks run, %d failed", len(results), failed),
+	}
+	if err := tv.store.CreateAuditLog(entry); err != nil {
+		log.Printf("Failed to record transition hooks of task %s: %v", task.ID, err)
+	}
+}
+
+// Validate checks that a task can move to newState, without moving it
+func (tv *TransitionValidator) Validate(task *storage.Task, newState storage.State) error {
 	// Validate the transition
 	if err := ValidateTransition(task.State, newState); err != nil {
 		return fmt.Errorf("transition validation failed: %w", err)
@@ -42,29 +158,33 @@ func (tv *TransitionValidator) ValidateAndTransition(taskID string, newState sto
 		return fmt.Errorf("handover validation failed: %w", err)
 	}
 
-	// Perform the transition
-	return tv.store.UpdateTaskState(taskID, newState, note)
-}
+	// Check conditions on handover metadata
+	if err := CheckTransitionRules(tv.store, tv.rules, task, newState); err != nil {
+		return fmt.Errorf("transition rule failed: %w", err)
+	}
 
-// validateDependencies ensures all dependencies are satisfied before transition
-func (tv *TransitionValidator) validateDependencies(task *storage.Task, newState storage.State) error {
-	// Only check dependencies for certain states
-	workStates := []storage.State{
-		storage.Planning,
-		storage.Implementing,
-		storage.Reviewing,
-		storage.Committing,
+	if err := tv.CheckWIPLimit(newState, 1); err != nil {
+		return err
 	}
 
-	isWorkState := false
-	for _, state := range workStates {
-		if newState == state {
-			isWorkState = true
-			break
-		}
+	return nil
+}
+
+// isWorkState reports whether moving into state starts work on a task, which
+// needs its dependencies and subtasks done
+func isWorkState(state storage.State) bool {
+	switch state {
+	case storage.Planning, storage.Implementing, storage.Reviewing, storage.Committing:
+		return true
+	default:
+		return false
 	}
+}
 
-	if !isWorkState {
+// validateDependencies ensures all dependencies are satisfied before transition
+func (tv *TransitionValidator) validateDependencies(task *storage.Task, newState storage.State) error {
+	// Only check dependencies for certain states
+	if !isWorkState(newState) {
 		return nil
 	}
 
@@ -88,12 +208,21 @@ func (tv *TransitionValidator) validateDependencies(task *storage.Task, newState
 		}
 	}
 
+	// A parent task waits for the subtasks grouped under it
+	subtask, err := unfinishedSubtask(tv.store, task.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list subtasks: %w", err)
+	}
+	if subtask != nil {
+		return fmt.Errorf("subtask %s (%s) is not complete (current state: %s)", subtask.ID, subtask.Title, subtask.State)
+	}
+
 	return nil
 }
 
 // validateRequiredHandovers checks if required handover artifacts exist
 func (tv *TransitionValidator) validateRequiredHandovers(task *storage.Task, newState storage.State) error {
-	requiredHandovers := getRequiredHandovers(task.State, newState)
+	requiredHandovers := tv.requiredHandovers(task.State, newState)
 
 	for _, handover := range requiredHandovers {
 		artifact, err := tv.store.GetArtifact(task.ID, handover, 0) // Get latest version
@@ -105,51 +234,116 @@ func (tv *TransitionValidator) validateRequiredHandovers(task *storage.Task, new
 		if artifact.Content == "" {
 			return fmt.Errorf("required handover artifact '%s' exists but is empty", handover)
 		}
+
+		if err := ValidateArtifact(tv.schemas, handover, artifact.Content); err != nil {
+			return err
+		}
+
+		if err := tv.validateCitations(task, artifact); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// citationHandovers lists the handover artifacts that must cite the task's linked requirements
+var citationHandovers = map[string]bool{
+	"implementation_plan": true,
+	"change_summary":      true,
+}
+
+// validateCitations checks that a handover artifact cites every requirement linked to the task
+func (tv *TransitionValidator) validateCitations(task *storage.Task, artifact *storage.Artifact) error {
+	missing, err := tv.missingCitations(task, artifact)
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("handover artifact '%s' does not cite linked requirements: %s",
+			artifact.Name, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// missingCitations returns the linked requirement keys an artifact fails to cite
+func (tv *TransitionValidator) missingCitations(task *storage.Task, artifact *storage.Artifact) ([]string, error) {
+	if !citationHandovers[artifact.Name] {
+		return nil, nil
+	}
+
+	requirements, err := tv.store.ListTaskRequirements(task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked requirements: %w", err)
+	}
+
+	keys := make([]string, 0, len(requirements))
+	for _, req := range requirements {
+		keys = append(keys, req.Key)
+	}
+
+	return plan.MissingCitations(artifact.Content, keys), nil
+}
+
+// RequiredHandovers lists the handover artifacts each transition requires, keyed "from->to"
+var RequiredHandovers = map[string][]string{
+	"planning->ready_for_implementation":  {"implementation_plan"},
+	"implementing->ready_for_code_review": {"change_summary"},
+	"reviewing->ready_for_commit":         {"review_findings"},
+	"reviewing->needs_fixes":              {"review_findings"},
+	"fixing->ready_for_code_review":       {"fix_plan"},
+	"committing->DONE":                    {"commit_summary"},
+}
+
 // getRequiredHandovers returns the required handover artifacts for a state transition
 func getRequiredHandovers(from, to storage.State) []string {
 	key := fmt.Sprintf("%s->%s", from, to)
 
-	requiredHandovers := map[string][]string{
-		"planning->ready_for_implementation":       {"implementation_plan"},
-		"implementing->ready_for_code_review":      {"change_summary"},
-		"reviewing->ready_for_commit":              {"review_findings"},
-		"reviewing->needs_fixes":                   {"review_findings"},
-		"fixing->ready_for_code_review":            {"fix_plan"},
-		"committing->DONE":                         {"commit_summary"},
-	}
-
-	if handovers, exists := requiredHandovers[key]; exists {
+	if handovers, exists := RequiredHandovers[key]; exists {
 		return handovers
 	}
 
 	return []string{}
 }
 
-// GetTransitionRequirements returns information about what's needed for a transition
+// TransitionRequirement describes what a transition needs, and what stands in
+// its way, without making it
 type TransitionRequirement struct {
-	DependenciesBlocked []string `json:"dependencies_blocked,omitempty"`
-	MissingHandovers    []string `json:"missing_handovers,omitempty"`
-	IsValid             bool     `json:"is_valid"`
-	Reason              string   `json:"reason,omitempty"`
+	From                storage.State   `json:"from"`
+	To                  storage.State   `json:"to"`
+	Handovers           []string        `json:"handovers,omitempty"` // handovers the move requires
+	DependenciesBlocked []string        `json:"dependencies_blocked,omitempty"`
+	MissingHandovers    []string        `json:"missing_handovers,omitempty"`
+	MissingCitations    []string        `json:"missing_citations,omitempty"`
+	SchemaViolations    []string        `json:"schema_violations,omitempty"`
+	RuleViolations      []string        `json:"rule_violations,omitempty"` // transition rules the handover metadata fails
+	WIPLimit            string          `json:"wip_limit,omitempty"`       // set when the new state is full
+	ReasonRequired      bool            `json:"reason_required,omitempty"` // the move needs a reason note
+	Hooks               []hooks.Planned `json:"hooks,omitempty"`           // hooks the move would run; blocking ones may still refuse it
+	IsValid             bool            `json:"is_valid"`
+	Reason              string          `json:"reason,omitempty"`
 }
 
-// GetTransitionRequirements analyzes what's needed for a specific transition
+// GetTransitionRequirements checks a task's move to newState the way
+// ValidateAndTransition does, collecting every problem instead of stopping at
+// the first, and lists the hooks it would run. Nothing is changed.
 func (tv *TransitionValidator) GetTransitionRequirements(taskID string, newState storage.State) (*TransitionRequirement, error) {
-	req := &TransitionRequirement{
-		IsValid: true,
-	}
-
 	// Get current task
 	task, err := tv.store.GetTask(taskID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task %s: %w", taskID, err)
 	}
 
+	newState = storage.NormalizeState(string(newState))
+	req := &TransitionRequirement{
+		From:           task.State,
+		To:             newState,
+		IsValid:        true,
+		ReasonRequired: ValidateReason(newState, "") != nil,
+	}
+
 	// Check basic transition validity
 	if err := ValidateTransition(task.State, newState); err != nil {
 		req.IsValid = false
@@ -157,37 +351,79 @@ func (tv *TransitionValidator) GetTransitionRequirements(taskID string, newState
 		return req, nil
 	}
 
-	// Check dependencies
-	var dependencies []string
-	if len(task.Dependencies) > 0 {
-		if err := json.Unmarshal(task.Dependencies, &dependencies); err == nil {
-			for _, depID := range dependencies {
-				depTask, err := tv.store.GetTask(depID)
-				if err == nil && depTask.State != storage.Done {
-					req.DependenciesBlocked = append(req.DependenciesBlocked,
-						fmt.Sprintf("%s (%s): %s", depID, depTask.Title, depTask.State))
-				}
+	// Check dependencies and subtasks, for moves into a work state
+	if isWorkState(newState) {
+		for _, depID := range task.DependencyList() {
+			depTask, err := tv.store.GetTask(depID)
+			if err != nil {
+				req.DependenciesBlocked = append(req.DependenciesBlocked, fmt.Sprintf("%s: not found", depID))
+			} else if depTask.State != storage.Done {
+				req.DependenciesBlocked = append(req.DependenciesBlocked,
+					fmt.Sprintf("%s (%s): %s", depID, depTask.Title, depTask.State))
 			}
 		}
+		subtask, err := unfinishedSubtask(tv.store, task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list subtasks: %w", err)
+		}
+		if subtask != nil {
+			req.DependenciesBlocked = append(req.DependenciesBlocked,
+				fmt.Sprintf("subtask %s (%s): %s", subtask.ID, subtask.Title, subtask.State))
+		}
 	}
 
 	// Check handovers
-	requiredHandovers := getRequiredHandovers(task.State, newState)
+	requiredHandovers := tv.requiredHandovers(task.State, newState)
+	req.Handovers = requiredHandovers
 	for _, handover := range requiredHandovers {
-		if _, err := tv.store.GetArtifact(task.ID, handover, 0); err != nil {
+		artifact, err := tv.store.GetArtifact(task.ID, handover, 0)
+		if err != nil || artifact.Content == "" {
 			req.MissingHandovers = append(req.MissingHandovers, handover)
+			continue
+		}
+
+		var schemaErr *ArtifactSchemaError
+		if errors.As(ValidateArtifact(tv.schemas, handover, artifact.Content), &schemaErr) {
+			for _, problem := range schemaErr.Problems {
+				req.SchemaViolations = append(req.SchemaViolations, fmt.Sprintf("%s: %s", handover, problem))
+			}
+		}
+
+		missing, err := tv.missingCitations(task, artifact)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range missing {
+			req.MissingCitations = append(req.MissingCitations, fmt.Sprintf("%s: %s", handover, key))
 		}
 	}
 
+	req.RuleViolations = ruleViolations(tv.store, tv.rules, task, newState)
+
+	if err := tv.CheckWIPLimit(newState, 1); err != nil {
+		req.WIPLimit = err.Error()
+	}
+
+	if tv.hooks != nil {
+		req.Hooks = tv.hooks.Plan(task, newState)
+	}
+
 	// Determine if blocked
-	if len(req.DependenciesBlocked) > 0 || len(req.MissingHandovers) > 0 {
-		req.IsValid = false
-		if len(req.DependenciesBlocked) > 0 {
-			req.Reason = fmt.Sprintf("blocked by %d dependencies", len(req.DependenciesBlocked))
-		} else {
-			req.Reason = fmt.Sprintf("missing %d required handovers", len(req.MissingHandovers))
-		}
+	switch {
+	case len(req.DependenciesBlocked) > 0:
+		req.Reason = fmt.Sprintf("blocked by %d dependencies", len(req.DependenciesBlocked))
+	case len(req.MissingHandovers) > 0:
+		req.Reason = fmt.Sprintf("missing %d required handovers", len(req.MissingHandovers))
+	case len(req.SchemaViolations) > 0:
+		req.Reason = fmt.Sprintf("%d handover schema violations", len(req.SchemaViolations))
+	case len(req.MissingCitations) > 0:
+		req.Reason = fmt.Sprintf("missing %d requirement citations", len(req.MissingCitations))
+	case len(req.RuleViolations) > 0:
+		req.Reason = fmt.Sprintf("%d transition rules not met", len(req.RuleViolations))
+	case req.WIPLimit != "":
+		req.Reason = req.WIPLimit
 	}
+	req.IsValid = req.Reason == ""
 
 	return req, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,71 @@
+package statemachine
+
+import (
+	"fmt"
+
+	"baton/internal/storage"
+)
+
+// ForwardState returns the state working on a task in state moves it into,
+// the first of its allowed transitions, or "" for a terminal state
+func ForwardState(state storage.State) storage.State {
+	targets := ValidTransitions[storage.NormalizeState(string(state))]
+	if len(targets) == 0 {
+		return ""
+	}
+	return targets[0]
+}
+
+// wipCounts returns how many tasks each WIP-limited state holds, or nil when
+// no limits are configured
+func (ts *TaskSelector) wipCounts() (map[storage.State]int, error) {
+	if len(ts.config.WIPLimits) == 0 {
+		return nil, nil
+	}
+
+	tasks, err := ts.store.ListTasks(storage.TaskFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count work in progress: %w", err)
+	}
+	counts := make(map[storage.State]int)
+	for _, task := range tasks {
+		counts[task.State]++
+	}
+	return counts, nil
+}
+
+// isWIPLimited reports whether working on a task would move it into a state
+// already holding as many tasks as its WIP limit allows. Tasks in the full
+// state itself stay selectable, so the work in progress gets finished.
+func (ts *TaskSelector) isWIPLimited(task *storage.Task, counts map[storage.State]int) (bool, string) {
+	next := ForwardState(task.State)
+	limit := ts.config.WIPLimits[string(next)]
+	if limit <= 0 || counts[next] < limit {
+		return false, ""
+	}
+	return true, fmt.Sprintf("WIP limit reached: %s holds %d of %d tasks", next, counts[next], limit)
+}
+
+// SetWIPLimits makes transitions refuse to move a task into a state already
+// holding as many tasks as its limit allows. Leave the limits unset to
+// override them.
+func (tv *TransitionValidator) SetWIPLimits(limits map[string]int) {
+	tv.wipLimits = limits
+}
+
+// CheckWIPLimit checks that adding tasks to state keeps it within its WIP limit
+func (tv *TransitionValidator) CheckWIPLimit(state storage.State, adding int) error {
+	limit := tv.wipLimits[string(state)]
+	if limit <= 0 {
+		return nil
+	}
+
+	count, err := tv.store.GetTaskCount(storage.TaskFilters{State: &state})
+	if err != nil {
+		return fmt.Errorf("failed to count tasks in %s: %w", state, err)
+	}
+	if count+adding > limit {
+		return fmt.Errorf("WIP limit reached: %s holds %d of %d tasks; finish one first or override the limit", state, count, limit)
+	}
+	return nil
+}
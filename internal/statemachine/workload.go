@@ -0,0 +1,123 @@
+package statemachine
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/storage"
+)
+
+// UnassignedOwner groups tasks with no owner whose state no agent handles
+const UnassignedOwner = "unassigned"
+
+// inFlightStates are the states in which an agent is actively working on a task
+var inFlightStates = map[storage.State]bool{
+	storage.Planning:     true,
+	storage.Implementing: true,
+	storage.Reviewing:    true,
+	storage.Committing:   true,
+	storage.Fixing:       true,
+}
+
+// OwnerWorkload summarizes the work assigned to one owner or agent
+type OwnerWorkload struct {
+	Owner       string         `json:"owner"`
+	IsAgent     bool           `json:"is_agent"` // owner is the agent handling unowned tasks in its states
+	OpenTasks   int            `json:"open_tasks"`
+	InFlight    int            `json:"in_flight"`
+	Blocked     int            `json:"blocked"`
+	ByState     map[string]int `json:"by_state"`
+	Transitions int            `json:"transitions"` // state changes within the window
+	Completed   int            `json:"completed"`   // tasks moved to DONE within the window
+	OpenTaskIDs []string       `json:"open_task_ids"`
+}
+
+// WorkloadReport is the per-owner workload view
+type WorkloadReport struct {
+	Since  time.Time        `json:"since"`
+	Owners []*OwnerWorkload `json:"owners"`
+}
+
+// workloadOwner resolves who a task's work belongs to: its owner when set,
+// otherwise the agent handling the given state
+func workloadOwner(cfg *config.Config, owner string, state storage.State) (string, bool) {
+	if owner != "" {
+		return owner, false
+	}
+	if agentID, ok := cfg.AgentIDForState(string(state)); ok {
+		return agentID, true
+	}
+	return UnassignedOwner, false
+}
+
+// GetOwnerWorkload summarizes open tasks, in-flight work and throughput since
+// the given time per owner, busiest owners first
+func (ts *TaskSelector) GetOwnerWorkload(cfg *config.Config, since time.Time) (*WorkloadReport, error) {
+	tasks, err := ts.store.ListTasks(storage.TaskFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	logs, err := ts.store.ListAuditLogsSince(since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	owners := make(map[string]*OwnerWorkload)
+	get := func(owner string, isAgent bool) *OwnerWorkload {
+		w, exists := owners[owner]
+		if !exists {
+			w = &OwnerWorkload{Owner: owner, IsAgent: isAgent, ByState: make(map[string]int), OpenTaskIDs: []string{}}
+			owners[owner] = w
+		}
+		return w
+	}
+
+	taskOwners := make(map[string]string)
+	for _, task := range tasks {
+		taskOwners[task.ID] = task.Owner
+		if IsTerminalState(task.State) {
+			continue
+		}
+
+		w := get(workloadOwner(cfg, task.Owner, task.State))
+		w.OpenTasks++
+		w.ByState[string(task.State)]++
+		w.OpenTaskIDs = append(w.OpenTaskIDs, task.ID)
+		if inFlightStates[task.State] {
+			w.InFlight++
+		}
+		if blocked, _ := ts.isBlockedByDependencies(task); blocked || storage.IsHeld(task.State) || ts.isUnassigned(task) {
+			w.Blocked++
+		}
+	}
+
+	// Throughput is credited to whoever owned the work when it moved on
+	for _, log := range logs {
+		if log.PrevState == log.NextState || log.NextState == "" {
+			continue
+		}
+
+		w := get(workloadOwner(cfg, taskOwners[log.TaskID], storage.NormalizeState(log.PrevState)))
+		w.Transitions++
+		if storage.NormalizeState(log.NextState) == storage.Done {
+			w.Completed++
+		}
+	}
+
+	report := &WorkloadReport{Since: since, Owners: make([]*OwnerWorkload, 0, len(owners))}
+	for _, w := range owners {
+		report.Owners = append(report.Owners, w)
+	}
+	sort.Slice(report.Owners, func(i, j int) bool {
+		a, b := report.Owners[i], report.Owners[j]
+		if a.OpenTasks != b.OpenTasks {
+			return a.OpenTasks > b.OpenTasks
+		}
+		return a.Owner < b.Owner
+	})
+
+	return report, nil
+}
@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveDirName is the directory, next to the database, that holds archived
+// audit payloads
+const archiveDirName = "archive"
+
+// auditPayload is the part of an audit log moved to cold storage
+type auditPayload struct {
+	InputsSummary  string          `json:"inputs_summary"`
+	OutputsSummary string          `json:"outputs_summary"`
+	Commands       json.RawMessage `json:"commands,omitempty"`
+	Note           string          `json:"note"`
+	FollowUps      json.RawMessage `json:"follow_ups,omitempty"`
+}
+
+// ArchiveResult summarizes an ArchiveAuditPayloads run
+type ArchiveResult struct {
+	Archived int   `json:"archived"` // audit logs whose payload moved to disk
+	Bytes    int64 `json:"bytes"`    // payload bytes removed from the database
+}
+
+// ArchiveAuditPayloads moves the payload (summaries, commands, note and
+// follow-ups) of audit logs created before cutoff and at least minBytes in
+// size to gzip files under the archive directory next to the database. Files
+// are named by the SHA-256 of their content, which the row keeps along with
+// the path; reads restore the payload transparently. With dryRun nothing
+// changes and the result reports what would move.
+func (s *Store) ArchiveAuditPayloads(cutoff time.Time, minBytes int, dryRun bool) (*ArchiveResult, error) {
+	if s.key != "" {
+		return nil, fmt.Errorf("audit payloads of an encrypted database can't be archived: the archive files would not be encrypted")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, COALESCE(inputs_summary, ''), COALESCE(outputs_summary, ''), commands,
+			COALESCE(note, ''), follow_ups
+		FROM audit_logs
+		WHERE created_at < ? AND archive_path = ''
+			AND LENGTH(COALESCE(inputs_summary, '')) + LENGTH(COALESCE(outputs_summary, ''))
+				+ LENGTH(COALESCE(commands, '')) + LENGTH(COALESCE(note, ''))
+				+ LENGTH(COALESCE(follow_ups, '')) >= ?
+	`, cutoff.UTC(), minBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	type pending struct {
+		id      string
+		payload auditPayload
+	}
+	var logs []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.payload.InputsSummary, &p.payload.OutputsSummary,
+			(*[]byte)(&p.payload.Commands), &p.payload.Note, (*[]byte)(&p.payload.FollowUps)); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		logs = append(logs, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &ArchiveResult{}
+	for _, p := range logs {
+		size := int64(len(p.payload.InputsSummary) + len(p.payload.OutputsSummary) +
+			len(p.payload.Commands) + len(p.payload.Note) + len(p.payload.FollowUps))
+		if dryRun {
+			result.Archived++
+			result.Bytes += size
+			continue
+		}
+
+		path, sum, err := s.writeArchive(p.payload)
+		if err != nil {
+			return result, fmt.Errorf("failed to archive audit log %s: %w", p.id, err)
+		}
+
+		_, err = s.db.Exec(`
+			UPDATE audit_logs SET inputs_summary = '', outputs_summary = '', commands = NULL,
+				note = '', follow_ups = NULL, archive_path = ?, archive_sha256 = ?
+			WHERE id = ?
+		`, path, sum, p.id)
+		if err != nil {
+			return result, err
+		}
+		result.Archived++
+		result.Bytes += size
+	}
+
+	return result, nil
+}
+
+// Vacuum rebuilds the database file, returning space freed by deletes and
+// archiving to the file system
+func (s *Store) Vacuum() error {
+	_, err := s.db.Exec("VACUUM")
+	return err
+}
+
+// BackupTo writes a consistent snapshot of the database to path, which must
+// not exist yet, using VACUUM INTO. Writers may keep going meanwhile. The
+// snapshot of an encrypted database is encrypted with the same key.
+func (s *Store) BackupTo(path string) error {
+	if s.key != "" {
+		path = encryptedDSN(path, s.key)
+	}
+	_, err := s.db.Exec("VACUUM INTO ?", path)
+	return err
+}
+
+// CheckIntegrity runs SQLite's integrity check on the database file at path
+// without migrating it. key decrypts an encrypted database; a plaintext one
+// is checked as is.
+func CheckIntegrity(path, key string) error {
+	plaintext, err := IsPlaintextDatabase(path)
+	if err != nil {
+		return err
+	}
+	var db *sql.DB
+	if plaintext || key == "" {
+		db, err = sql.Open("sqlite", path)
+	} else {
+		db, err = sql.Open("sqlite3", encryptedDSN(path, key))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("failed to check database integrity: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("database integrity check failed: %s", result)
+	}
+	return nil
+}
+
+// writeArchive compresses a payload to its content-addressed file and returns
+// the file's path, relative to the database directory, and its SHA-256
+func (s *Store) writeArchive(payload auditPayload) (string, string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", "", err
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return "", "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", "", err
+	}
+
+	hash := sha256.Sum256(compressed.Bytes())
+	sum := hex.EncodeToString(hash[:])
+	rel := archiveDirName + "/" + sum[:2] + "/" + sum + ".json.gz"
+
+	path := filepath.Join(filepath.Dir(s.path), filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", "", err
+	}
+	// Identical payloads share a file
+	if _, err := os.Stat(path); err == nil {
+		return rel, sum, nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, compressed.Bytes(), 0644); err != nil {
+		return "", "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", "", err
+	}
+	return rel, sum, nil
+}
+
+// readArchive loads an archived payload, checking it against its SHA-256
+func (s *Store) readArchive(rel, sum string) (*auditPayload, error) {
+	compressed, err := os.ReadFile(filepath.Join(filepath.Dir(s.path), filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(compressed)
+	if hex.EncodeToString(hash[:]) != sum {
+		return nil, fmt.Errorf("%s does not match its checksum", rel)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload auditPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// restoreAuditLog fills in an archived audit log's payload. A payload that
+// cannot be read is reported in the note rather than failing the whole query.
+func (s *Store) restoreAuditLog(log *AuditLog, rel, sum string) {
+	if rel == "" {
+		return
+	}
+	payload, err := s.readArchive(rel, sum)
+	if err != nil {
+		log.Note = fmt.Sprintf("[archived payload unavailable: %v]", err)
+		return
+	}
+	log.InputsSummary = payload.InputsSummary
+	log.OutputsSummary = payload.OutputsSummary
+	log.Commands = payload.Commands
+	log.Note = payload.Note
+	log.FollowUps = payload.FollowUps
+}
+
+// restoreAuditEntry fills in an archived audit entry's payload, as restoreAuditLog
+func (s *Store) restoreAuditEntry(entry *AuditEntry, rel, sum string) {
+	if rel == "" {
+		return
+	}
+	payload, err := s.readArchive(rel, sum)
+	if err != nil {
+		entry.Note = fmt.Sprintf("[archived payload unavailable: %v]", err)
+		return
+	}
+	entry.InputsSummary = payload.InputsSummary
+	entry.OutputsSummary = payload.OutputsSummary
+	entry.Commands = payload.Commands
+	entry.Note = payload.Note
+	entry.FollowUps = payload.FollowUps
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// AreaLock records that a cycle is working in a code area
+type AreaLock struct {
+	Area       string    `json:"area" db:"area"`
+	TaskID     string    `json:"task_id" db:"task_id"`
+	CycleID    string    `json:"cycle_id" db:"cycle_id"`
+	Holder     string    `json:"holder" db:"holder"`
+	AcquiredAt time.Time `json:"acquired_at" db:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// AreaLockedError reports an area held by another cycle
+type AreaLockedError struct {
+	Lock *AreaLock
+}
+
+func (e *AreaLockedError) Error() string {
+	return fmt.Sprintf("area %s is locked by cycle %s on task %s (%s) since %s",
+		e.Lock.Area, e.Lock.CycleID, e.Lock.TaskID, e.Lock.Holder, e.Lock.AcquiredAt.Format(time.RFC3339))
+}
+
+// AcquireAreaLocks locks every area for a cycle, or none of them: if another
+// cycle holds one of the areas an *AreaLockedError is returned. Expired locks
+// are cleared first. Locks the same cycle already holds are renewed.
+func (s *Store) AcquireAreaLocks(areas []string, taskID, cycleID, holder string, ttl time.Duration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.Exec("DELETE FROM area_locks WHERE expires_at < ?", now.UTC()); err != nil {
+		return err
+	}
+
+	for _, area := range areas {
+		lock := &AreaLock{}
+		err := tx.QueryRow(`
+			SELECT area, task_id, cycle_id, holder, acquired_at, expires_at
+			FROM area_locks WHERE area = ?
+		`, area).Scan(&lock.Area, &lock.TaskID, &lock.CycleID, &lock.Holder, local(&lock.AcquiredAt), local(&lock.ExpiresAt))
+		if err == nil && lock.CycleID != cycleID {
+			return &AreaLockedError{Lock: lock}
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO area_locks (area, task_id, cycle_id, holder, acquired_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(area) DO UPDATE SET expires_at = excluded.expires_at
+		`, area, taskID, cycleID, holder, now.UTC(), now.Add(ttl).UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReleaseAreaLocks releases every area a cycle holds
+func (s *Store) ReleaseAreaLocks(cycleID string) error {
+	_, err := s.db.Exec("DELETE FROM area_locks WHERE cycle_id = ?", cycleID)
+	return err
+}
+
+// ListAreaLocks returns the unexpired area locks, by area
+func (s *Store) ListAreaLocks() ([]*AreaLock, error) {
+	rows, err := s.db.Query(`
+		SELECT area, task_id, cycle_id, holder, acquired_at, expires_at
+		FROM area_locks WHERE expires_at >= ? ORDER BY area
+	`, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locks []*AreaLock
+	for rows.Next() {
+		lock := &AreaLock{}
+		if err := rows.Scan(&lock.Area, &lock.TaskID, &lock.CycleID, &lock.Holder, local(&lock.AcquiredAt), local(&lock.ExpiresAt)); err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+
+	return locks, rows.Err()
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TaskAssessment is the self-assessment an agent reported at the end of its
+// latest cycle on a task: how confident it is in the transition it made and
+// what it could not settle
+type TaskAssessment struct {
+	TaskID        string    `json:"task_id" db:"task_id"`
+	CycleID       string    `json:"cycle_id" db:"cycle_id"`
+	Actor         string    `json:"actor" db:"actor"`
+	PrevState     State     `json:"prev_state" db:"prev_state"`
+	NextState     State     `json:"next_state" db:"next_state"`
+	Confidence    *float64  `json:"confidence,omitempty" db:"confidence"` // 0 to 1, nil when the agent gave none
+	OpenQuestions []string  `json:"open_questions,omitempty" db:"open_questions"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// SaveTaskAssessment stores the assessment for a task, replacing the previous one
+func (s *Store) SaveTaskAssessment(assessment *TaskAssessment) error {
+	assessment.CreatedAt = time.Now()
+
+	questions, err := json.Marshal(assessment.OpenQuestions)
+	if err != nil {
+		return err
+	}
+	if assessment.OpenQuestions == nil {
+		questions = []byte("[]")
+	}
+
+	query := `
+		INSERT INTO task_assessments (task_id, cycle_id, actor, prev_state, next_state,
+			confidence, open_questions, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(task_id) DO UPDATE SET cycle_id = excluded.cycle_id, actor = excluded.actor,
+			prev_state = excluded.prev_state, next_state = excluded.next_state,
+			confidence = excluded.confidence, open_questions = excluded.open_questions,
+			created_at = excluded.created_at
+	`
+
+	_, err = s.db.Exec(query, assessment.TaskID, assessment.CycleID, assessment.Actor,
+		assessment.PrevState, assessment.NextState, assessment.Confidence, string(questions),
+		assessment.CreatedAt.UTC())
+	return err
+}
+
+// GetTaskAssessment returns the latest assessment of a task; sql.ErrNoRows
+// when no agent has reported one
+func (s *Store) GetTaskAssessment(taskID string) (*TaskAssessment, error) {
+	row := s.db.QueryRow(`
+		SELECT task_id, cycle_id, actor, prev_state, next_state, confidence, open_questions, created_at
+		FROM task_assessments WHERE task_id = ?
+	`, taskID)
+	return scanTaskAssessment(row.Scan)
+}
+
+// ListLowConfidenceAssessments returns the latest assessments whose confidence
+// is below threshold, least confident first, for human review. Assessments of
+// tasks that have moved on since are left out.
+func (s *Store) ListLowConfidenceAssessments(threshold float64) ([]*TaskAssessment, error) {
+	rows, err := s.db.Query(`
+		SELECT a.task_id, a.cycle_id, a.actor, a.prev_state, a.next_state, a.confidence,
+			a.open_questions, a.created_at
+		FROM task_assessments a
+		JOIN tasks t ON t.id = a.task_id AND t.state = a.next_state
+		WHERE a.confidence IS NOT NULL AND a.confidence < ?
+		ORDER BY a.confidence ASC, a.created_at ASC
+	`, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assessments []*TaskAssessment
+	for rows.Next() {
+		assessment, err := scanTaskAssessment(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		assessments = append(assessments, assessment)
+	}
+
+	return assessments, rows.Err()
+}
+
+// scanTaskAssessment reads an assessment row through scan
+func scanTaskAssessment(scan func(dest ...interface{}) error) (*TaskAssessment, error) {
+	assessment := &TaskAssessment{}
+	var questions string
+	err := scan(&assessment.TaskID, &assessment.CycleID, &assessment.Actor, &assessment.PrevState,
+		&assessment.NextState, &assessment.Confidence, &questions, local(&assessment.CreatedAt))
+	if err != nil {
+		return nil, err
+	}
+	if questions != "" {
+		json.Unmarshal([]byte(questions), &assessment.OpenQuestions)
+	}
+	return assessment, nil
+}
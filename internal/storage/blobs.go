@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// blobDirName is the directory, relative to the database's, that holds the
+// content of artifacts too large to keep in the database
+const blobDirName = ".baton/blobs"
+
+// DefaultBlobThreshold is the artifact size, in bytes, above which content is
+// spilled to the blob store
+const DefaultBlobThreshold = 256 << 10
+
+// SetBlobThreshold sets the artifact size, in bytes, above which content is
+// stored as a blob file instead of in the database; 0 keeps all content in
+// the database. Encrypted stores always keep it in the database.
+func (s *Store) SetBlobThreshold(bytes int) {
+	if s.key != "" {
+		return
+	}
+	s.blobThreshold = bytes
+}
+
+// insertArtifact inserts an artifact as given, spilling its content to the
+// blob store when it is over the threshold. The artifact keeps its content;
+// Size and BlobSHA256 are filled in.
+func (s *Store) insertArtifact(q interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, artifact *Artifact) error {
+	artifact.Size = int64(len(artifact.Content))
+	artifact.BlobSHA256 = ""
+	content := artifact.Content
+	if s.blobThreshold > 0 && len(content) > s.blobThreshold {
+		sum, err := s.writeBlob(content)
+		if err != nil {
+			return fmt.Errorf("failed to store artifact content: %w", err)
+		}
+		artifact.BlobSHA256 = sum
+		content = ""
+	}
+
+	_, err := q.Exec(`
+		INSERT INTO artifacts (id, task_id, project_id, name, version, content, meta, blob_sha256, size, created_at)
+		VALUES (?, ?, COALESCE((SELECT project_id FROM tasks WHERE id = ?), 'default'), ?, ?, ?, ?, ?, ?, ?)
+	`, artifact.ID, artifact.TaskID, artifact.TaskID, artifact.Name, artifact.Version,
+		content, artifact.Meta, artifact.BlobSHA256, artifact.Size, artifact.CreatedAt.UTC())
+	return err
+}
+
+// blobPath is where the blob with the given SHA-256 lives
+func (s *Store) blobPath(sum string) string {
+	return filepath.Join(filepath.Dir(s.path), filepath.FromSlash(blobDirName), sum[:2], sum)
+}
+
+// writeBlob stores content under its SHA-256, which it returns. Identical
+// content shares a file.
+func (s *Store) writeBlob(content string) (string, error) {
+	hash := sha256.Sum256([]byte(content))
+	sum := hex.EncodeToString(hash[:])
+
+	path := s.blobPath(sum)
+	if _, err := os.Stat(path); err == nil {
+		return sum, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return sum, nil
+}
+
+// loadArtifactBlob fills in a spilled artifact's content, checking it against
+// its SHA-256. Content that cannot be read is reported in its place rather
+// than failing the whole query, as with archived audit payloads.
+func (s *Store) loadArtifactBlob(artifact *Artifact) {
+	if artifact.BlobSHA256 == "" {
+		return
+	}
+	data, err := os.ReadFile(s.blobPath(artifact.BlobSHA256))
+	if err == nil {
+		hash := sha256.Sum256(data)
+		if hex.EncodeToString(hash[:]) != artifact.BlobSHA256 {
+			err = fmt.Errorf("blob %s does not match its checksum", artifact.BlobSHA256)
+		}
+	}
+	if err != nil {
+		artifact.Content = fmt.Sprintf("[artifact content unavailable: %v]", err)
+		return
+	}
+	artifact.Content = string(data)
+}
+
+// OpenArtifact returns an artifact (version 0 is the latest) without its
+// content, and a reader over the content that streams spilled content from
+// its blob file. The caller closes the reader.
+func (s *Store) OpenArtifact(taskID, name string, version int) (*Artifact, io.ReadSeekCloser, error) {
+	artifact, err := s.getArtifact(taskID, name, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if artifact.BlobSHA256 == "" {
+		content := artifact.Content
+		artifact.Content = ""
+		return artifact, nopSeekCloser{strings.NewReader(content)}, nil
+	}
+
+	file, err := os.Open(s.blobPath(artifact.BlobSHA256))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open artifact content: %w", err)
+	}
+	return artifact, file, nil
+}
+
+// nopSeekCloser adds a no-op Close to a strings.Reader
+type nopSeekCloser struct {
+	*strings.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Resolutions of a cycle checkpoint
+const (
+	CheckpointFinished   = "finished"    // the cycle ran to its end
+	CheckpointCompleted  = "completed"   // resume finished an interrupted cycle
+	CheckpointRolledBack = "rolled_back" // resume undid an interrupted cycle
+)
+
+// CycleCheckpoint records how far a cycle got. A checkpoint without
+// FinishedAt belongs to a cycle that is running, or whose run died.
+type CycleCheckpoint struct {
+	CycleID        string     `json:"cycle_id" db:"cycle_id"`
+	TaskID         string     `json:"task_id" db:"task_id"`
+	PrevState      State      `json:"prev_state" db:"prev_state"`
+	Agent          string     `json:"agent" db:"agent"`
+	Phase          string     `json:"phase" db:"phase"`
+	Holder         string     `json:"holder" db:"holder"`
+	WorktreePath   string     `json:"worktree_path,omitempty" db:"worktree_path"`
+	WorktreeBranch string     `json:"worktree_branch,omitempty" db:"worktree_branch"`
+	Resolution     string     `json:"resolution,omitempty" db:"resolution"`
+	StartedAt      time.Time  `json:"started_at" db:"started_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// SaveCheckpoint creates or replaces a cycle's checkpoint
+func (s *Store) SaveCheckpoint(cp *CycleCheckpoint) error {
+	now := time.Now()
+	if cp.StartedAt.IsZero() {
+		cp.StartedAt = now
+	}
+	cp.UpdatedAt = now
+
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO cycle_checkpoints (cycle_id, project_id, task_id, prev_state, agent, phase, holder,
+			worktree_path, worktree_branch, resolution, started_at, updated_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+	`, cp.CycleID, s.project, cp.TaskID, cp.PrevState, cp.Agent, cp.Phase, cp.Holder,
+		cp.WorktreePath, cp.WorktreeBranch, cp.Resolution, cp.StartedAt.UTC(), cp.UpdatedAt.UTC())
+	return err
+}
+
+// SetCheckpointPhase records the phase a cycle entered
+func (s *Store) SetCheckpointPhase(cycleID, phase string) error {
+	_, err := s.db.Exec("UPDATE cycle_checkpoints SET phase = ?, updated_at = ? WHERE cycle_id = ?",
+		phase, time.Now().UTC(), cycleID)
+	return err
+}
+
+// FinishCheckpoint marks a cycle's checkpoint as over, with one of the
+// Checkpoint* resolutions
+func (s *Store) FinishCheckpoint(cycleID, resolution string) error {
+	now := time.Now().UTC()
+	_, err := s.db.Exec("UPDATE cycle_checkpoints SET resolution = ?, updated_at = ?, finished_at = ? WHERE cycle_id = ?",
+		resolution, now, now, cycleID)
+	return err
+}
+
+// ListUnfinishedCheckpoints returns the checkpoints of the project's cycles
+// that never finished, oldest first. Unless a run is in progress, these are
+// the cycles of runs that died.
+func (s *Store) ListUnfinishedCheckpoints() ([]*CycleCheckpoint, error) {
+	rows, err := s.db.Query(`
+		SELECT cycle_id, task_id, prev_state, agent, phase, holder, worktree_path, worktree_branch,
+			resolution, started_at, updated_at, finished_at
+		FROM cycle_checkpoints WHERE project_id = ? AND finished_at IS NULL ORDER BY started_at
+	`, s.project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []*CycleCheckpoint
+	for rows.Next() {
+		cp := &CycleCheckpoint{}
+		if err := rows.Scan(&cp.CycleID, &cp.TaskID, &cp.PrevState, &cp.Agent, &cp.Phase, &cp.Holder,
+			&cp.WorktreePath, &cp.WorktreeBranch, &cp.Resolution,
+			local(&cp.StartedAt), local(&cp.UpdatedAt), localOrNil{&cp.FinishedAt}); err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+// CycleAudited reports whether a cycle's audit entry was recorded
+func (s *Store) CycleAudited(cycleID string) (bool, error) {
+	var id string
+	err := s.db.QueryRow("SELECT id FROM audit_logs WHERE cycle_id = ? LIMIT 1", cycleID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// CustomFieldMap returns the task's custom field values, or an empty map when
+// they are missing or malformed
+func (t *Task) CustomFieldMap() map[string]interface{} {
+	fields := make(map[string]interface{})
+	if len(t.CustomFields) > 0 {
+		json.Unmarshal(t.CustomFields, &fields)
+	}
+	return fields
+}
+
+// SetCustomField sets a custom field value on the task; a nil value removes it
+func (t *Task) SetCustomField(name string, value interface{}) {
+	fields := t.CustomFieldMap()
+	if value == nil {
+		delete(fields, name)
+	} else {
+		fields[name] = value
+	}
+	t.CustomFields, _ = json.Marshal(fields)
+}
+
+// customFieldsValue is the column value of a task's custom fields
+func customFieldsValue(fields json.RawMessage) string {
+	if len(fields) == 0 || string(fields) == "null" {
+		return "{}"
+	}
+	return string(fields)
+}
+
+// customFieldConditions adds the filters' custom field conditions to a task query
+func (f TaskFilters) customFieldConditions(query string, args []interface{}) (string, []interface{}) {
+	names := make([]string, 0, len(f.CustomFields))
+	for name := range f.CustomFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := f.CustomFields[name]
+		// json_extract returns JSON booleans as 1 and 0
+		if b, ok := value.(bool); ok {
+			value = 0
+			if b {
+				value = 1
+			}
+		}
+		query += " AND json_extract(custom_fields, ?) = ?"
+		args = append(args, "$."+strconv.Quote(name), value)
+	}
+	return query, args
+}
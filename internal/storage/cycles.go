@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"time"
+)
+
+// Outcomes of a cycle
+const (
+	CycleSuccess = "success"   // the agent moved its task on
+	CycleFailure = "failure"   // the agent ran, but the task did not move
+	CycleTimeout = "timed_out" // the cycle exceeded its timebox and its agent was stopped
+	CycleError   = "error"     // the cycle was aborted by an error
+)
+
+// CycleRecord is what a cycle that started an agent cost and how it ended
+type CycleRecord struct {
+	CycleID          string    `json:"cycle_id" db:"cycle_id"`
+	TaskID           string    `json:"task_id" db:"task_id"`
+	Agent            string    `json:"agent" db:"agent"`
+	Provider         string    `json:"provider,omitempty" db:"provider"`
+	ModelTier        string    `json:"model_tier,omitempty" db:"model_tier"`
+	Outcome          string    `json:"outcome" db:"outcome"`
+	PromptTokens     int       `json:"prompt_tokens" db:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens" db:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd" db:"cost_usd"`
+	DurationSeconds  float64   `json:"duration_seconds" db:"duration_seconds"`
+	StartedAt        time.Time `json:"started_at" db:"started_at"`
+	FinishedAt       time.Time `json:"finished_at" db:"finished_at"`
+}
+
+// CycleTotals adds up cycle records
+type CycleTotals struct {
+	Key              string  `json:"key,omitempty"` // what the records have in common, when grouped
+	Cycles           int     `json:"cycles"`
+	Succeeded        int     `json:"succeeded"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+}
+
+// Add counts a cycle record in the totals
+func (t *CycleTotals) Add(record *CycleRecord) {
+	t.Cycles++
+	if record.Outcome == CycleSuccess {
+		t.Succeeded++
+	}
+	t.PromptTokens += record.PromptTokens
+	t.CompletionTokens += record.CompletionTokens
+	t.CostUSD += record.CostUSD
+	t.DurationSeconds += record.DurationSeconds
+}
+
+// RecordCycle saves what a cycle cost and how it ended
+func (s *Store) RecordCycle(record *CycleRecord) error {
+	if record.FinishedAt.IsZero() {
+		record.FinishedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO cycles (cycle_id, project_id, task_id, agent, provider, model_tier, outcome,
+			prompt_tokens, completion_tokens, cost_usd, duration_seconds, started_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, record.CycleID, s.project, record.TaskID, record.Agent, record.Provider, record.ModelTier, record.Outcome,
+		record.PromptTokens, record.CompletionTokens, record.CostUSD, record.DurationSeconds,
+		record.StartedAt.UTC(), record.FinishedAt.UTC())
+	return err
+}
+
+// ListCycles returns the project's cycles that started in [since, until),
+// oldest first; a zero until means up to now
+func (s *Store) ListCycles(since, until time.Time) ([]*CycleRecord, error) {
+	if until.IsZero() {
+		until = time.Now().Add(time.Minute)
+	}
+	rows, err := s.db.Query(`
+		SELECT cycle_id, task_id, agent, provider, model_tier, outcome, prompt_tokens, completion_tokens,
+			cost_usd, duration_seconds, started_at, finished_at
+		FROM cycles WHERE project_id = ? AND started_at >= ? AND started_at < ? ORDER BY started_at
+	`, s.project, since.UTC(), until.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*CycleRecord
+	for rows.Next() {
+		var record CycleRecord
+		if err := rows.Scan(&record.CycleID, &record.TaskID, &record.Agent, &record.Provider, &record.ModelTier,
+			&record.Outcome, &record.PromptTokens, &record.CompletionTokens, &record.CostUSD, &record.DurationSeconds,
+			local(&record.StartedAt), local(&record.FinishedAt)); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, rows.Err()
+}
+
+// CycleTotalsSince adds up the project's cycles that started at or after since
+func (s *Store) CycleTotalsSince(since time.Time) (*CycleTotals, error) {
+	var totals CycleTotals
+	err := s.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(outcome = ?), 0), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0),
+			COALESCE(SUM(cost_usd), 0), COALESCE(SUM(duration_seconds), 0)
+		FROM cycles WHERE project_id = ? AND started_at >= ?
+	`, CycleSuccess, s.project, since.UTC()).Scan(&totals.Cycles, &totals.Succeeded, &totals.PromptTokens,
+		&totals.CompletionTokens, &totals.CostUSD, &totals.DurationSeconds)
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// RecentCycleCosts sums up the cycles of today and of the last seven days
+// (today and the six days before it), counting days from midnight in time.Local
+type RecentCycleCosts struct {
+	Today    *CycleTotals `json:"today"`
+	LastWeek *CycleTotals `json:"last_7_days"`
+}
+
+// GetRecentCycleCosts adds up the project's cycles of the day of now and of
+// the week up to it
+func (s *Store) GetRecentCycleCosts(now time.Time) (*RecentCycleCosts, error) {
+	now = now.In(time.Local)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	today, err := s.CycleTotalsSince(midnight)
+	if err != nil {
+		return nil, err
+	}
+	lastWeek, err := s.CycleTotalsSince(midnight.AddDate(0, 0, -6))
+	if err != nil {
+		return nil, err
+	}
+	return &RecentCycleCosts{Today: today, LastWeek: lastWeek}, nil
+}
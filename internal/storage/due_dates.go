@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseDueDate parses a due date given as a day (2006-01-02), which means
+// the end of that day in time.Local, or as an RFC 3339 timestamp
+func ParseDueDate(value string) (time.Time, error) {
+	if day, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return day.Add(24*time.Hour - time.Second), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid due date %q (use YYYY-MM-DD or RFC 3339)", value)
+}
+
+// IsOverdue reports whether the task is past its due date at now and still
+// open; tasks without a due date are never overdue
+func (t *Task) IsOverdue(now time.Time) bool {
+	if t.DueDate == nil || t.State == Done || t.State == Cancelled {
+		return false
+	}
+	return t.DueDate.Before(now)
+}
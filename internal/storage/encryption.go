@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/vfs/adiantum"
+)
+
+// Encrypted databases are opened with a second SQLite build, ncruces'
+// (driver "sqlite3"), through its Adiantum VFS, which encrypts every page of
+// the database and of its write-ahead log. modernc's build has no codec.
+
+// plaintextHeader starts every unencrypted SQLite database file
+const plaintextHeader = "SQLite format 3\x00"
+
+// storedTimeLayout is how modernc writes a time.Time; encrypted databases
+// are written the same way so timestamps keep comparing as text
+const storedTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// OpenStore opens the database at dbPath: encrypted with key, or plaintext
+// when key is ""
+func OpenStore(dbPath, key string) (*Store, error) {
+	if key == "" {
+		return NewStore(dbPath)
+	}
+	return NewEncryptedStore(dbPath, key)
+}
+
+// NewEncryptedStore opens the database at dbPath encrypted with key, creating
+// it when it doesn't exist. A plaintext database at dbPath is encrypted in
+// place first. Large artifacts stay in the database instead of going to the
+// blob store, whose files would not be encrypted.
+func NewEncryptedStore(dbPath, key string) (*Store, error) {
+	if key == "" {
+		return nil, fmt.Errorf("no database encryption key")
+	}
+	if err := encryptPlaintext(dbPath, key); err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", dbPath, err)
+	}
+
+	db, err := sql.Open("sqlite3", encryptedDSN(dbPath, key)+
+		"&_pragma=busy_timeout(5000)&_txlock=immediate&_timefmt="+url.QueryEscape(storedTimeLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// A wrong key only shows when the first page is read
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open encrypted database %s (is the key right?): %w", dbPath, err)
+	}
+
+	store, err := newStore(db, dbPath)
+	if err != nil {
+		return nil, err
+	}
+	store.key = key
+	store.blobThreshold = 0
+	return store, nil
+}
+
+// IsPlaintextDatabase reports whether the file at path is an unencrypted
+// SQLite database. A missing or empty file is neither.
+func IsPlaintextDatabase(path string) (bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(plaintextHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(header) == plaintextHeader, nil
+}
+
+// encryptPlaintext replaces a plaintext database at dbPath with an encrypted
+// copy. The copy is written beside it with VACUUM INTO, which reads through
+// the write-ahead log, and renamed over it once complete.
+func encryptPlaintext(dbPath, key string) error {
+	plaintext, err := IsPlaintextDatabase(dbPath)
+	if err != nil || !plaintext {
+		return err
+	}
+
+	encrypted := dbPath + ".encrypting"
+	if err := os.Remove(encrypted); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", fileURI(dbPath))
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec("VACUUM INTO ?", encryptedDSN(encrypted, key)); err != nil {
+		db.Close()
+		os.Remove(encrypted)
+		return err
+	}
+	// Closing the last connection checkpoints and removes the log
+	if err := db.Close(); err != nil {
+		os.Remove(encrypted)
+		return err
+	}
+
+	if err := os.Rename(encrypted, dbPath); err != nil {
+		os.Remove(encrypted)
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// encryptedDSN is the URI of the database at path opened through the
+// Adiantum VFS with key
+func encryptedDSN(path, key string) string {
+	return fileURI(path) + "?vfs=adiantum&textkey=" + url.QueryEscape(key)
+}
+
+// fileURI is the SQLite URI filename of path
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if filepath.VolumeName(path) != "" {
+		path = "/" + path
+	}
+	return "file:" + strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+}
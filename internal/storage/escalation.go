@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// EscalationCycleID is the cycle ID of the audit entry recording an escalation
+const EscalationCycleID = "escalation"
+
+// reviewFailure returns 1 when a move from prev to next is a failed review
+// loop, the task entering needs_fixes, and 0 otherwise
+func reviewFailure(prev, next State) int {
+	if next == NeedsFixes && prev != NeedsFixes {
+		return 1
+	}
+	return 0
+}
+
+// EscalateTask puts a task that keeps failing review on hold in state hold,
+// for a person to look at. Its failure count starts over, so once resumed it
+// gets as many attempts again; the escalation is recorded in the audit log.
+func (s *Store) EscalateTask(id string, hold State, actor string) error {
+	if !IsHeld(hold) {
+		return fmt.Errorf("tasks can only be escalated to blocked or paused, not %s", hold)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var prevState, prevHeldFrom State
+	var failures int
+	err = tx.QueryRow("SELECT state, held_from, review_failures FROM tasks WHERE id = ?", id).Scan(&prevState, &prevHeldFrom, &failures)
+	if err != nil {
+		return err
+	}
+	if IsHeld(prevState) {
+		return fmt.Errorf("task %s is already %s", id, prevState)
+	}
+
+	now := time.Now()
+	_, err = tx.Exec("UPDATE tasks SET state = ?, held_from = ?, review_failures = 0, updated_at = ? WHERE id = ?",
+		hold, heldFrom(prevState, prevHeldFrom, hold), now.UTC(), id)
+	if err != nil {
+		return err
+	}
+	if err := recordStateEntry(tx, id, hold, now); err != nil {
+		return fmt.Errorf("failed to record state history: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	note := fmt.Sprintf("escalated after %d failed review loops", failures)
+	s.emit(TaskEvent{Kind: EventTransition, TaskID: id, PrevState: prevState, NextState: hold, Note: note})
+	s.emit(TaskEvent{Kind: EventEscalated, TaskID: id, PrevState: prevState, NextState: hold, Note: note})
+
+	entry := &AuditLog{
+		TaskID:    id,
+		CycleID:   EscalationCycleID,
+		PrevState: string(prevState),
+		NextState: string(hold),
+		Actor:     actor,
+		Result:    "escalated",
+		Note:      note,
+	}
+	if err := s.CreateAuditLog(entry); err != nil {
+		return fmt.Errorf("task escalated, but the audit entry was not recorded: %w", err)
+	}
+	return nil
+}
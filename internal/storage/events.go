@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Task event kinds reported to the store's subscribers
+const (
+	EventCreated     = "created"      // the task was created
+	EventUpdated     = "updated"      // the task changed without changing state
+	EventTransition  = "transition"   // the task changed state
+	EventDeleted     = "deleted"      // the task was moved to the trash
+	EventRestored    = "restored"     // the task was taken out of the trash
+	EventArtifact    = "artifact"     // a new artifact version was stored for the task
+	EventCycleFailed = "cycle_failed" // a cycle on the task ended without success
+	EventEscalated   = "escalated"    // the task was put on hold after failing review too often
+)
+
+// TaskEvent is something that happened to a task that watchers, the web UI or
+// other integrations may want to hear about
+type TaskEvent struct {
+	Kind      string    `json:"kind"`
+	TaskID    string    `json:"task_id"`
+	PrevState State     `json:"prev_state,omitempty"`
+	NextState State     `json:"next_state,omitempty"`
+	Artifact  string    `json:"artifact,omitempty"` // artifact name, for artifact events
+	Version   int       `json:"version,omitempty"`  // artifact version, for artifact events
+	Result    string    `json:"result,omitempty"`   // audit result, for cycle_failed events
+	Note      string    `json:"note,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// eventBus holds the store's event subscribers
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]func(TaskEvent)
+	next        int
+}
+
+// Subscribe registers fn to be called after every change recorded through this
+// store: tasks created, updated, moved between states, deleted and restored,
+// artifacts stored and unsuccessful cycles. Events are delivered once the
+// change has committed, on the goroutine that made it, so fn should hand slow
+// work off. Changes made by other processes are not seen. The returned
+// function unsubscribes fn.
+func (s *Store) Subscribe(fn func(TaskEvent)) (unsubscribe func()) {
+	bus := &s.events
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	if bus.subscribers == nil {
+		bus.subscribers = make(map[int]func(TaskEvent))
+	}
+	id := bus.next
+	bus.next++
+	bus.subscribers[id] = fn
+
+	return func() {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		delete(bus.subscribers, id)
+	}
+}
+
+// emit passes event to every subscriber, in the order they subscribed
+func (s *Store) emit(event TaskEvent) {
+	bus := &s.events
+	bus.mu.RLock()
+	ids := make([]int, 0, len(bus.subscribers))
+	for id := range bus.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	subscribers := make([]func(TaskEvent), len(ids))
+	for i, id := range ids {
+		subscribers[i] = bus.subscribers[id]
+	}
+	bus.mu.RUnlock()
+
+	event.Time = time.Now()
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
+
+// emitTransition announces a task moved from prevState to state, unless it
+// didn't exist or didn't move
+func (s *Store) emitTransition(id string, prevState, state State, note string) {
+	if prevState != "" && prevState != state {
+		s.emit(TaskEvent{Kind: EventTransition, TaskID: id, PrevState: prevState, NextState: state, Note: note})
+	}
+}
+
+// emitCreated announces newly created tasks
+func (s *Store) emitCreated(tasks []*Task) {
+	for _, task := range tasks {
+		s.emit(TaskEvent{Kind: EventCreated, TaskID: task.ID})
+	}
+}
+
+// emitTaskChange announces an updated task: a transition when its state moved
+// from prevState, an update otherwise
+func (s *Store) emitTaskChange(task *Task, prevState State, note string) {
+	if prevState != task.State {
+		s.emit(TaskEvent{Kind: EventTransition, TaskID: task.ID, PrevState: prevState, NextState: task.State, Note: note})
+	} else {
+		s.emit(TaskEvent{Kind: EventUpdated, TaskID: task.ID, Note: note})
+	}
+}
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ExportFormatVersion is bumped whenever the export layout changes incompatibly
+const ExportFormatVersion = 1
+
+// WorkspaceExport is everything a workspace's database holds about its work,
+// as portable JSON. Records are sorted by ID, key or position so two exports
+// of the same workspace are identical and diff cleanly.
+type WorkspaceExport struct {
+	FormatVersion    int                `json:"format_version"`
+	Projects         []*Project         `json:"projects,omitempty"` // absent from exports made before projects
+	Tasks            []*Task            `json:"tasks"`
+	Requirements     []*Requirement     `json:"requirements"`
+	TaskRequirements []*TaskRequirement `json:"task_requirements"`
+	Artifacts        []*Artifact        `json:"artifacts"` // every version
+	AuditLogs        []*AuditLog        `json:"audit_logs"`
+	Agents           []*Agent           `json:"agents"`
+	Notes            []*TaskNote        `json:"notes"`
+	StateHistory     []*StateEntry      `json:"state_history,omitempty"` // absent from exports made before it was kept
+}
+
+// TaskRequirement links a task to a requirement by ID
+type TaskRequirement struct {
+	TaskID        string `json:"task_id"`
+	RequirementID string `json:"requirement_id"`
+}
+
+// ExportAll collects the workspace's projects, the tasks of every project
+// (archived and deleted ones included), requirements and their links to tasks,
+// every artifact version, audit logs with any archived payloads read back,
+// agents, task notes and state history
+func (s *Store) ExportAll() (*WorkspaceExport, error) {
+	export := &WorkspaceExport{FormatVersion: ExportFormatVersion}
+
+	var err error
+	if export.Projects, err = s.ListProjects(); err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	if export.Tasks, err = s.ListTasks(TaskFilters{IncludeArchived: true, IncludeDeleted: true, AllProjects: true}); err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	sort.Slice(export.Tasks, func(i, j int) bool { return export.Tasks[i].ID < export.Tasks[j].ID })
+
+	if export.Requirements, err = s.listRequirements("", true); err != nil {
+		return nil, fmt.Errorf("failed to list requirements: %w", err)
+	}
+
+	if export.TaskRequirements, err = s.listTaskRequirementLinks(); err != nil {
+		return nil, fmt.Errorf("failed to list requirement links: %w", err)
+	}
+	if export.Artifacts, err = s.listAllArtifacts(); err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	if export.AuditLogs, err = s.ListAuditLogsSince(time.Time{}); err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	if export.Agents, err = s.listAgents(); err != nil {
+		return nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+	if export.Notes, err = s.listAllTaskNotes(); err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	if export.StateHistory, err = s.listAllStateEntries(); err != nil {
+		return nil, fmt.Errorf("failed to list state history: %w", err)
+	}
+
+	return export, nil
+}
+
+// ImportAll loads an export into a store that has no tasks or requirements
+// yet, keeping every record's ID and timestamps. It all happens in one
+// transaction, so a failed import leaves the store empty.
+func (s *Store) ImportAll(export *WorkspaceExport) error {
+	if export.FormatVersion != ExportFormatVersion {
+		return fmt.Errorf("unsupported export format version %d (expected %d)", export.FormatVersion, ExportFormatVersion)
+	}
+
+	var existing int
+	if err := s.db.QueryRow("SELECT (SELECT COUNT(*) FROM tasks) + (SELECT COUNT(*) FROM requirements)").Scan(&existing); err != nil {
+		return err
+	}
+	if existing > 0 {
+		return fmt.Errorf("the database already has tasks or requirements; import into an empty database")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, project := range export.Projects {
+		_, err := tx.Exec("INSERT OR IGNORE INTO projects (id, description, created_at) VALUES (?, ?, ?)",
+			project.ID, project.Description, project.CreatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to import project %s: %w", project.ID, err)
+		}
+	}
+
+	for _, task := range export.Tasks {
+		_, err := tx.Exec(`
+			INSERT INTO tasks (id, project_id, title, description, state, held_from, review_failures, priority, owner, tags, dependencies, blocked_by,
+				estimated_hours, due_date, parent_id, custom_fields, archived, deleted_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, task.ID, projectOrDefault(task.ProjectID), task.Title, task.Description, task.State, task.HeldFrom, task.ReviewFailures, task.Priority, task.Owner, task.Tags,
+			task.Dependencies, task.BlockedBy, task.EstimatedHours, utcOrNil(task.DueDate), task.ParentID, customFieldsValue(task.CustomFields),
+			task.Archived, utcOrNil(task.DeletedAt), task.CreatedAt.UTC(), task.UpdatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to import task %s: %w", task.ID, err)
+		}
+	}
+
+	for _, req := range export.Requirements {
+		status := req.Status
+		if status == "" {
+			status = RequirementActive
+		}
+		_, err := tx.Exec(`
+			INSERT INTO requirements (id, project_id, key, title, text, type, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, req.ID, projectOrDefault(req.ProjectID), req.Key, req.Title, req.Text, req.Type, status, req.CreatedAt.UTC(), req.UpdatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to import requirement %s: %w", req.Key, err)
+		}
+	}
+
+	for _, link := range export.TaskRequirements {
+		_, err := tx.Exec("INSERT INTO task_requirements (task_id, requirement_id) VALUES (?, ?)", link.TaskID, link.RequirementID)
+		if err != nil {
+			return fmt.Errorf("failed to import requirement link %s -> %s: %w", link.TaskID, link.RequirementID, err)
+		}
+	}
+
+	for _, artifact := range export.Artifacts {
+		if err := s.insertArtifact(tx, artifact); err != nil {
+			return fmt.Errorf("failed to import artifact %s v%d: %w", artifact.Name, artifact.Version, err)
+		}
+	}
+
+	for _, log := range export.AuditLogs {
+		openQuestions := string(log.OpenQuestions)
+		if openQuestions == "" {
+			openQuestions = "[]"
+		}
+		_, err := tx.Exec(`
+			INSERT INTO audit_logs (id, task_id, cycle_id, prev_state, next_state, actor,
+				selection_reason, inputs_summary, outputs_summary, commands, result, note, follow_ups,
+				timebox_seconds, duration_seconds, model_tier, provider, prompt_tokens, completion_tokens,
+				cost_usd, handshake, confidence, open_questions, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, log.ID, log.TaskID, log.CycleID, log.PrevState, log.NextState,
+			log.Actor, log.SelectionReason, log.InputsSummary, log.OutputsSummary, log.Commands,
+			log.Result, log.Note, log.FollowUps, log.TimeboxSeconds, log.DurationSeconds, log.ModelTier, log.Provider,
+			log.PromptTokens, log.CompletionTokens, log.CostUSD, log.Handshake, log.Confidence, openQuestions,
+			log.CreatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to import audit log %s: %w", log.ID, err)
+		}
+	}
+
+	for _, agent := range export.Agents {
+		_, err := tx.Exec(`
+			INSERT INTO agents (id, name, role, description, routing_policy, permissions, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, agent.ID, agent.Name, agent.Role, agent.Description, agent.RoutingPolicy, agent.Permissions, agent.CreatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to import agent %s: %w", agent.ID, err)
+		}
+	}
+
+	for _, note := range export.Notes {
+		_, err := tx.Exec(`
+			INSERT INTO task_notes (id, task_id, author, body, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, note.ID, note.TaskID, note.Author, note.Body, note.CreatedAt.UTC(), note.UpdatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to import note %s: %w", note.ID, err)
+		}
+	}
+
+	for _, entry := range export.StateHistory {
+		if err := recordStateEntry(tx, entry.TaskID, entry.State, entry.EnteredAt); err != nil {
+			return fmt.Errorf("failed to import state history of task %s: %w", entry.TaskID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// Tasks from exports made before state history was kept get an entry for their current state
+	return s.backfillStateHistory()
+}
+
+// listTaskRequirementLinks returns every task-requirement link
+func (s *Store) listTaskRequirementLinks() ([]*TaskRequirement, error) {
+	rows, err := s.db.Query("SELECT task_id, requirement_id FROM task_requirements ORDER BY task_id, requirement_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*TaskRequirement
+	for rows.Next() {
+		link := &TaskRequirement{}
+		if err := rows.Scan(&link.TaskID, &link.RequirementID); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// listAllArtifacts returns every version of every artifact
+func (s *Store) listAllArtifacts() ([]*Artifact, error) {
+	return s.queryArtifacts(`
+		SELECT id, task_id, name, version, content, meta, blob_sha256, size, created_at
+		FROM artifacts ORDER BY task_id, name, version
+	`)
+}
+
+// listAgents returns every agent
+func (s *Store) listAgents() ([]*Agent, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, role, COALESCE(description, ''), routing_policy, permissions, created_at
+		FROM agents ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []*Agent
+	for rows.Next() {
+		agent := &Agent{}
+		var routing, permissions sql.NullString
+		err := rows.Scan(&agent.ID, &agent.Name, &agent.Role, &agent.Description, &routing, &permissions, local(&agent.CreatedAt))
+		if err != nil {
+			return nil, err
+		}
+		if routing.Valid {
+			agent.RoutingPolicy = []byte(routing.String)
+		}
+		if permissions.Valid {
+			agent.Permissions = []byte(permissions.String)
+		}
+		agents = append(agents, agent)
+	}
+
+	return agents, rows.Err()
+}
+
+// listAllTaskNotes returns the notes of every task
+func (s *Store) listAllTaskNotes() ([]*TaskNote, error) {
+	rows, err := s.db.Query(`
+		SELECT id, task_id, author, body, created_at, updated_at
+		FROM task_notes ORDER BY task_id, created_at, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*TaskNote
+	for rows.Next() {
+		note := &TaskNote{}
+		if err := rows.Scan(&note.ID, &note.TaskID, &note.Author, &note.Body, local(&note.CreatedAt), local(&note.UpdatedAt)); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
@@ -1,17 +1,36 @@
 package storage
 
 const CreateTablesSQL = `
+-- Projects sharing the database; tasks, requirements and artifacts belong to one
+CREATE TABLE IF NOT EXISTS projects (
+    id TEXT PRIMARY KEY, -- name used by --project
+    description TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT OR IGNORE INTO projects (id, description) VALUES ('default', 'The project of a database created without projects');
+
 -- Tasks table
 CREATE TABLE IF NOT EXISTS tasks (
     id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL DEFAULT 'default',
     title TEXT NOT NULL,
     description TEXT,
     state TEXT NOT NULL DEFAULT 'ready_for_plan',
+    held_from TEXT NOT NULL DEFAULT '', -- state a blocked or paused task resumes to
+    review_failures INTEGER NOT NULL DEFAULT 0, -- moves into needs_fixes since the task was last escalated
     priority INTEGER NOT NULL DEFAULT 5,
     owner TEXT,
     tags TEXT, -- JSON array
     dependencies TEXT, -- JSON array of task IDs
     blocked_by TEXT, -- JSON array of task IDs
+    estimated_hours REAL NOT NULL DEFAULT 0, -- 0 when not estimated
+    due_date DATETIME, -- when the task must be done by; NULL for no deadline
+    locked_by TEXT NOT NULL DEFAULT '', -- cycle working on the task, '' when unlocked
+    locked_at DATETIME, -- when the lock was taken or last renewed
+    parent_id TEXT NOT NULL DEFAULT '', -- task this one was split from
+    custom_fields TEXT NOT NULL DEFAULT '{}', -- JSON object of custom_fields values
+    archived INTEGER NOT NULL DEFAULT 0, -- 1 when archived: left out of task lists unless asked for
+    deleted_at DATETIME, -- set while the task is in the trash
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -19,12 +38,25 @@ CREATE TABLE IF NOT EXISTS tasks (
 -- Requirements table
 CREATE TABLE IF NOT EXISTS requirements (
     id TEXT PRIMARY KEY,
-    key TEXT UNIQUE NOT NULL, -- e.g., "FR-P1"
+    project_id TEXT NOT NULL DEFAULT 'default',
+    key TEXT NOT NULL, -- e.g., "FR-P1"; unique within a project
     title TEXT NOT NULL,
     text TEXT NOT NULL,
     type TEXT NOT NULL, -- functional|nonfunctional|constraint|risk
+    status TEXT NOT NULL DEFAULT 'active', -- active|deprecated
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (project_id, key)
+);
+
+-- Keys given up by renumbering, never issued again
+CREATE TABLE IF NOT EXISTS retired_requirement_keys (
+    project_id TEXT NOT NULL DEFAULT 'default',
+    key TEXT NOT NULL,
+    requirement_id TEXT NOT NULL,
+    replaced_by TEXT NOT NULL, -- the requirement's new key
+    retired_at DATETIME NOT NULL,
+    PRIMARY KEY (project_id, key)
 );
 
 -- Task-Requirement links
@@ -40,10 +72,13 @@ CREATE TABLE IF NOT EXISTS task_requirements (
 CREATE TABLE IF NOT EXISTS artifacts (
     id TEXT PRIMARY KEY,
     task_id TEXT NOT NULL,
+    project_id TEXT NOT NULL DEFAULT 'default', -- the task's project
     name TEXT NOT NULL, -- implementation_plan, change_summary, etc.
     version INTEGER NOT NULL DEFAULT 1,
     content TEXT NOT NULL,
     meta TEXT, -- JSON metadata
+    blob_sha256 TEXT NOT NULL DEFAULT '', -- content is in the blob store when set
+    size INTEGER NOT NULL DEFAULT 0,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
     UNIQUE(task_id, name, version)
@@ -75,10 +110,160 @@ CREATE TABLE IF NOT EXISTS audit_logs (
     result TEXT,
     note TEXT,
     follow_ups TEXT, -- JSON array of follow-up interactions
+    timebox_seconds INTEGER NOT NULL DEFAULT 0, -- cycle timeout the task was given
+    duration_seconds REAL NOT NULL DEFAULT 0, -- how long the cycle actually took
+    model_tier TEXT NOT NULL DEFAULT '', -- LLM model tier that served the cycle
+    provider TEXT NOT NULL DEFAULT '', -- LLM provider that served the cycle, after any fallback
+    prompt_tokens INTEGER NOT NULL DEFAULT 0, -- LLM tokens the cycle sent
+    completion_tokens INTEGER NOT NULL DEFAULT 0, -- LLM tokens the cycle received
+    cost_usd REAL NOT NULL DEFAULT 0, -- what the cycle's LLM calls cost
+    handshake TEXT NOT NULL DEFAULT '', -- how the completion handshake ended: updated, refused, timeout or exhausted
+    confidence REAL, -- the agent's confidence in the transition, 0 to 1; NULL when it gave none
+    open_questions TEXT NOT NULL DEFAULT '[]', -- JSON array of questions the agent left open
+    archive_path TEXT NOT NULL DEFAULT '', -- gzip file holding the archived payload, relative to the database
+    archive_sha256 TEXT NOT NULL DEFAULT '', -- checksum of that file
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+-- Cached LLM task briefings, keyed by a hash of the task's current version
+CREATE TABLE IF NOT EXISTS task_briefings (
+    task_id TEXT PRIMARY KEY,
+    version TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+-- Per-task subscriptions: who wants to hear about a task, and on which channel
+CREATE TABLE IF NOT EXISTS task_watches (
+    task_id TEXT NOT NULL,
+    watcher TEXT NOT NULL,
+    channel TEXT NOT NULL DEFAULT '', -- notifications.channels key, '' for the default channel
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, watcher),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+-- Title and description revisions of a task; revision 1 is the text before the first change
+CREATE TABLE IF NOT EXISTS task_revisions (
+    task_id TEXT NOT NULL,
+    revision INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    actor TEXT NOT NULL DEFAULT '', -- who made the change: an agent, llm, cli, import, web
+    reverted_from INTEGER NOT NULL DEFAULT 0, -- the revision this one restored, if it was a revert
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, revision),
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+-- The self-assessment each task's agent reported at the end of its latest cycle
+CREATE TABLE IF NOT EXISTS task_assessments (
+    task_id TEXT PRIMARY KEY,
+    cycle_id TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    prev_state TEXT NOT NULL DEFAULT '',
+    next_state TEXT NOT NULL DEFAULT '', -- the transition the assessment is about
+    confidence REAL, -- 0 to 1; NULL when the agent gave none
+    open_questions TEXT NOT NULL DEFAULT '[]', -- JSON array
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+-- Approvals of finished milestones; a milestone's tasks wait for every earlier one
+CREATE TABLE IF NOT EXISTS milestone_signoffs (
+    milestone TEXT PRIMARY KEY, -- name from the milestone:<name> task tag
+    signed_off_by TEXT NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Areas held by running cycles, so concurrent workers stay out of each other's code
+CREATE TABLE IF NOT EXISTS area_locks (
+    area TEXT PRIMARY KEY,
+    task_id TEXT NOT NULL,
+    cycle_id TEXT NOT NULL,
+    holder TEXT NOT NULL DEFAULT '', -- host and pid of the worker running the cycle
+    acquired_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL -- locks of crashed workers lapse after this
+);
+
+-- How far each cycle got, so 'baton resume' can finish or undo the cycles of a run that died
+CREATE TABLE IF NOT EXISTS cycle_checkpoints (
+    cycle_id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL DEFAULT 'default',
+    task_id TEXT NOT NULL,
+    prev_state TEXT NOT NULL, -- the task's state when the cycle claimed it
+    agent TEXT NOT NULL DEFAULT '',
+    phase TEXT NOT NULL, -- claimed, executing, handshake, review, merging or recording
+    holder TEXT NOT NULL DEFAULT '', -- the task lock the cycle holds
+    worktree_path TEXT NOT NULL DEFAULT '', -- set when the cycle is isolated in a worktree
+    worktree_branch TEXT NOT NULL DEFAULT '',
+    resolution TEXT NOT NULL DEFAULT '', -- finished, or what resume did: completed or rolled_back
+    started_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    finished_at DATETIME, -- NULL while the cycle runs, or after it was interrupted
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+-- What each cycle that started an agent cost and how it ended, for cost reporting.
+-- Rows outlive their task, since what was spent stays spent.
+CREATE TABLE IF NOT EXISTS cycles (
+    cycle_id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL DEFAULT 'default',
+    task_id TEXT NOT NULL,
+    agent TEXT NOT NULL DEFAULT '',
+    provider TEXT NOT NULL DEFAULT '', -- LLM provider that served the cycle, after any fallback
+    model_tier TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL, -- success, failure, timeout or error
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    duration_seconds REAL NOT NULL DEFAULT 0,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME NOT NULL
+);
+
+-- Each time a task moved into a state, for time-in-state and stuck tasks
+CREATE TABLE IF NOT EXISTS state_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id TEXT NOT NULL,
+    state TEXT NOT NULL,
+    entered_at DATETIME NOT NULL,
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+-- Comments on a task by people and agents
+CREATE TABLE IF NOT EXISTS task_notes (
+    id TEXT PRIMARY KEY,
+    task_id TEXT NOT NULL,
+    author TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
 );
 
+-- Full-text index over task titles/descriptions, the latest version of each
+-- artifact, requirements and audit notes
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+    kind UNINDEXED, -- task|artifact|requirement|audit
+    ref_id UNINDEXED, -- task, artifact, requirement or audit log ID
+    task_id UNINDEXED, -- empty for requirements
+    title, -- task title, artifact name, requirement key and title, or audit transition
+    body, -- task description, artifact content, requirement text or audit note
+    tokenize = 'porter unicode61'
+);
+
+-- Cached embeddings for semantic search, invalidated by content hash
+CREATE TABLE IF NOT EXISTS search_embeddings (
+    ref_id TEXT PRIMARY KEY,
+    model TEXT NOT NULL,
+    content_hash TEXT NOT NULL,
+    vector TEXT NOT NULL -- JSON array of floats
+);
+
 -- Indexes for performance
 CREATE INDEX IF NOT EXISTS idx_tasks_state ON tasks(state);
 CREATE INDEX IF NOT EXISTS idx_tasks_priority ON tasks(priority);
@@ -90,19 +275,135 @@ CREATE INDEX IF NOT EXISTS idx_artifacts_name ON artifacts(name);
 CREATE INDEX IF NOT EXISTS idx_audit_logs_task_id ON audit_logs(task_id);
 CREATE INDEX IF NOT EXISTS idx_audit_logs_cycle_id ON audit_logs(cycle_id);
 CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_task_notes_task_id ON task_notes(task_id);
+CREATE INDEX IF NOT EXISTS idx_state_history_task_id ON state_history(task_id, entered_at);
+CREATE INDEX IF NOT EXISTS idx_cycles_started_at ON cycles(project_id, started_at);
 
--- Triggers to update updated_at timestamps
-CREATE TRIGGER IF NOT EXISTS update_tasks_updated_at
+-- Triggers to update updated_at timestamps (only when the writer did not set
+-- it); archiving, deleting and locking are not updates
+DROP TRIGGER IF EXISTS update_tasks_updated_at;
+CREATE TRIGGER update_tasks_updated_at
     AFTER UPDATE ON tasks
     FOR EACH ROW
+    WHEN NEW.updated_at = OLD.updated_at AND NEW.archived = OLD.archived AND NEW.deleted_at IS OLD.deleted_at
+        AND NEW.locked_at IS OLD.locked_at
     BEGIN
         UPDATE tasks SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
     END;
 
+CREATE TRIGGER IF NOT EXISTS search_index_task_insert
+    AFTER INSERT ON tasks
+    BEGIN
+        INSERT INTO search_index (kind, ref_id, task_id, title, body)
+        VALUES ('task', NEW.id, NEW.id, NEW.title, COALESCE(NEW.description, ''));
+    END;
+
+CREATE TRIGGER IF NOT EXISTS search_index_task_update
+    AFTER UPDATE OF title, description ON tasks
+    BEGIN
+        DELETE FROM search_index WHERE kind = 'task' AND ref_id = OLD.id;
+        INSERT INTO search_index (kind, ref_id, task_id, title, body)
+        VALUES ('task', NEW.id, NEW.id, NEW.title, COALESCE(NEW.description, ''));
+    END;
+
+CREATE TRIGGER IF NOT EXISTS search_index_task_delete
+    AFTER DELETE ON tasks
+    BEGIN
+        DELETE FROM search_index WHERE task_id = OLD.id;
+    END;
+
+CREATE TRIGGER IF NOT EXISTS search_index_artifact_insert
+    AFTER INSERT ON artifacts
+    BEGIN
+        DELETE FROM search_index WHERE kind = 'artifact' AND task_id = NEW.task_id AND title = NEW.name;
+        INSERT INTO search_index (kind, ref_id, task_id, title, body)
+        VALUES ('artifact', NEW.id, NEW.task_id, NEW.name, NEW.content);
+    END;
+
+CREATE TRIGGER IF NOT EXISTS search_index_requirement_insert
+    AFTER INSERT ON requirements
+    BEGIN
+        INSERT INTO search_index (kind, ref_id, task_id, title, body)
+        VALUES ('requirement', NEW.id, '', NEW.key || ' ' || NEW.title, NEW.text);
+    END;
+
+CREATE TRIGGER IF NOT EXISTS search_index_requirement_update
+    AFTER UPDATE OF key, title, text ON requirements
+    BEGIN
+        DELETE FROM search_index WHERE kind = 'requirement' AND ref_id = OLD.id;
+        INSERT INTO search_index (kind, ref_id, task_id, title, body)
+        VALUES ('requirement', NEW.id, '', NEW.key || ' ' || NEW.title, NEW.text);
+    END;
+
+CREATE TRIGGER IF NOT EXISTS search_index_requirement_delete
+    AFTER DELETE ON requirements
+    BEGIN
+        DELETE FROM search_index WHERE kind = 'requirement' AND ref_id = OLD.id;
+    END;
+
+-- Audit notes are indexed while they are in the database; archiving drops them
+CREATE TRIGGER IF NOT EXISTS search_index_audit_insert
+    AFTER INSERT ON audit_logs
+    WHEN COALESCE(NEW.note, '') != ''
+    BEGIN
+        INSERT INTO search_index (kind, ref_id, task_id, title, body)
+        VALUES ('audit', NEW.id, NEW.task_id,
+            COALESCE(NEW.prev_state, '') || ' → ' || COALESCE(NEW.next_state, '') || ' (' || COALESCE(NEW.actor, '') || ')',
+            NEW.note);
+    END;
+
+CREATE TRIGGER IF NOT EXISTS search_index_audit_update
+    AFTER UPDATE OF note ON audit_logs
+    BEGIN
+        DELETE FROM search_index WHERE kind = 'audit' AND ref_id = OLD.id;
+        INSERT INTO search_index (kind, ref_id, task_id, title, body)
+        SELECT 'audit', NEW.id, NEW.task_id,
+            COALESCE(NEW.prev_state, '') || ' → ' || COALESCE(NEW.next_state, '') || ' (' || COALESCE(NEW.actor, '') || ')',
+            NEW.note
+        WHERE COALESCE(NEW.note, '') != '';
+    END;
+
 CREATE TRIGGER IF NOT EXISTS update_requirements_updated_at
     AFTER UPDATE ON requirements
     FOR EACH ROW
     BEGIN
         UPDATE requirements SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
     END;
-`
\ No newline at end of file
+`
+
+// AddedColumns lists columns added after their table was first released, so
+// databases created before then can be upgraded in place
+var AddedColumns = []struct {
+	Table      string
+	Column     string
+	Definition string
+}{
+	{"tasks", "estimated_hours", "REAL NOT NULL DEFAULT 0"},
+	{"tasks", "parent_id", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "custom_fields", "TEXT NOT NULL DEFAULT '{}'"},
+	{"tasks", "archived", "INTEGER NOT NULL DEFAULT 0"},
+	{"tasks", "deleted_at", "DATETIME"},
+	{"tasks", "project_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"tasks", "held_from", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "review_failures", "INTEGER NOT NULL DEFAULT 0"},
+	{"tasks", "due_date", "DATETIME"},
+	{"tasks", "locked_by", "TEXT NOT NULL DEFAULT ''"},
+	{"tasks", "locked_at", "DATETIME"},
+	{"requirements", "status", "TEXT NOT NULL DEFAULT 'active'"},
+	{"requirements", "project_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"artifacts", "blob_sha256", "TEXT NOT NULL DEFAULT ''"},
+	{"artifacts", "size", "INTEGER NOT NULL DEFAULT 0"},
+	{"artifacts", "project_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"audit_logs", "timebox_seconds", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_logs", "duration_seconds", "REAL NOT NULL DEFAULT 0"},
+	{"audit_logs", "model_tier", "TEXT NOT NULL DEFAULT ''"},
+	{"audit_logs", "provider", "TEXT NOT NULL DEFAULT ''"},
+	{"audit_logs", "prompt_tokens", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_logs", "completion_tokens", "INTEGER NOT NULL DEFAULT 0"},
+	{"audit_logs", "cost_usd", "REAL NOT NULL DEFAULT 0"},
+	{"audit_logs", "handshake", "TEXT NOT NULL DEFAULT ''"},
+	{"audit_logs", "confidence", "REAL"},
+	{"audit_logs", "open_questions", "TEXT NOT NULL DEFAULT '[]'"},
+	{"audit_logs", "archive_path", "TEXT NOT NULL DEFAULT ''"},
+	{"audit_logs", "archive_sha256", "TEXT NOT NULL DEFAULT ''"},
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"time"
+)
+
+// MilestoneSignoff records that someone approved a finished milestone,
+// releasing the next milestone's tasks for selection
+type MilestoneSignoff struct {
+	Milestone   string    `json:"milestone" db:"milestone"`
+	SignedOffBy string    `json:"signed_off_by" db:"signed_off_by"`
+	Note        string    `json:"note,omitempty" db:"note"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// SignOffMilestone records a milestone's sign-off, replacing an earlier one
+func (s *Store) SignOffMilestone(signoff *MilestoneSignoff) error {
+	signoff.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO milestone_signoffs (milestone, signed_off_by, note, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(milestone) DO UPDATE SET signed_off_by = excluded.signed_off_by,
+			note = excluded.note, created_at = excluded.created_at
+	`
+
+	_, err := s.db.Exec(query, signoff.Milestone, signoff.SignedOffBy, signoff.Note, signoff.CreatedAt.UTC())
+	return err
+}
+
+// ListMilestoneSignoffs returns every sign-off by milestone name
+func (s *Store) ListMilestoneSignoffs() (map[string]*MilestoneSignoff, error) {
+	rows, err := s.db.Query("SELECT milestone, signed_off_by, note, created_at FROM milestone_signoffs")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	signoffs := make(map[string]*MilestoneSignoff)
+	for rows.Next() {
+		signoff := &MilestoneSignoff{}
+		if err := rows.Scan(&signoff.Milestone, &signoff.SignedOffBy, &signoff.Note, local(&signoff.CreatedAt)); err != nil {
+			return nil, err
+		}
+		signoffs[signoff.Milestone] = signoff
+	}
+
+	return signoffs, rows.Err()
+}
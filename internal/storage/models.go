@@ -2,6 +2,7 @@ package storage
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -20,6 +21,9 @@ const (
 	Committing             State = "committing"
 	Fixing                 State = "fixing"
 	Done                   State = "DONE"
+	Blocked                State = "blocked" // on hold, waiting on something outside the task
+	Paused                 State = "paused"  // on hold by choice
+	Cancelled              State = "cancelled" // terminal: the task won't be done
 )
 
 // StateAliases maps common typos to correct states
@@ -28,6 +32,8 @@ var StateAliases = map[string]State{
 	"ready_for_code_revie":    ReadyForCodeReview,
 	"need_fixes":              NeedsFixes,
 	"commiting":               Committing,
+	"canceled":                Cancelled,
+	"wont_do":                 Cancelled,
 }
 
 // NormalizeState normalizes input state aliases to canonical states
@@ -41,38 +47,84 @@ func NormalizeState(input string) State {
 // Task represents a unit of work
 type Task struct {
 	ID           string          `json:"id" db:"id"`
+	ProjectID    string          `json:"project_id,omitempty" db:"project_id"` // "" creates it in the store's project
 	Title        string          `json:"title" db:"title"`
 	Description  string          `json:"description" db:"description"`
 	State        State           `json:"state" db:"state"`
+	HeldFrom     State           `json:"held_from,omitempty" db:"held_from"` // state a blocked or paused task resumes to
+	ReviewFailures int           `json:"review_failures,omitempty" db:"review_failures"` // moves into needs_fixes since the task was last escalated
 	Priority     int             `json:"priority" db:"priority"`
 	Owner        string          `json:"owner" db:"owner"`
 	Tags         json.RawMessage `json:"tags" db:"tags"`         // JSON array
 	Dependencies json.RawMessage `json:"dependencies" db:"dependencies"` // JSON array of task IDs
 	BlockedBy    json.RawMessage `json:"blocked_by" db:"blocked_by"`    // JSON array of task IDs
+	EstimatedHours float64       `json:"estimated_hours" db:"estimated_hours"` // 0 when not estimated
+	DueDate      *time.Time      `json:"due_date,omitempty" db:"due_date"`     // when the task must be done by; nil for no deadline
+	LockedBy     string          `json:"locked_by,omitempty" db:"locked_by"`   // cycle working on the task; see LockTask
+	LockedAt     *time.Time      `json:"locked_at,omitempty" db:"locked_at"`   // when the lock was taken or last renewed
+	ParentID     string          `json:"parent_id,omitempty" db:"parent_id"`   // epic this one is grouped under, or task it was split from
+	CustomFields json.RawMessage `json:"custom_fields,omitempty" db:"custom_fields"` // JSON object of custom field values
+	Archived     bool            `json:"archived,omitempty" db:"archived"` // left out of task lists unless asked for
+	DeletedAt    *time.Time      `json:"deleted_at,omitempty" db:"deleted_at"` // set while the task is in the trash
 	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// MilestoneTagPrefix marks the tag naming a task's milestone, e.g. "milestone:MVP-2"
+const MilestoneTagPrefix = "milestone:"
+
+// TagList returns the task's tags, or nil when they are missing or malformed
+func (t *Task) TagList() []string {
+	var tags []string
+	if len(t.Tags) > 0 {
+		json.Unmarshal(t.Tags, &tags)
+	}
+	return tags
+}
+
+// DependencyList returns the IDs of the tasks this one depends on, or nil when they are missing or malformed
+func (t *Task) DependencyList() []string {
+	var deps []string
+	if len(t.Dependencies) > 0 {
+		json.Unmarshal(t.Dependencies, &deps)
+	}
+	return deps
+}
+
+// Milestone returns the milestone named by the task's tags, or "" when it has none
+func (t *Task) Milestone() string {
+	for _, tag := range t.TagList() {
+		if strings.HasPrefix(tag, MilestoneTagPrefix) {
+			return strings.TrimPrefix(tag, MilestoneTagPrefix)
+		}
+	}
+	return ""
+}
+
 // Requirement represents a functional or non-functional requirement
 type Requirement struct {
 	ID        string    `json:"id" db:"id"`
+	ProjectID string    `json:"project_id,omitempty" db:"project_id"` // "" creates it in the store's project
 	Key       string    `json:"key" db:"key"` // e.g., "FR-P1"
 	Title     string    `json:"title" db:"title"`
 	Text      string    `json:"text" db:"text"`
 	Type      string    `json:"type" db:"type"` // functional|nonfunctional|constraint|risk
+	Status    string    `json:"status" db:"status"` // active|deprecated; a deprecated key is never reused
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // Artifact represents task-scoped documents (implementation plans, etc.)
 type Artifact struct {
-	ID        string          `json:"id" db:"id"`
-	TaskID    string          `json:"task_id" db:"task_id"`
-	Name      string          `json:"name" db:"name"` // implementation_plan, change_summary, etc.
-	Version   int             `json:"version" db:"version"`
-	Content   string          `json:"content" db:"content"`
-	Meta      json.RawMessage `json:"meta" db:"meta"` // JSON metadata
-	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	ID         string          `json:"id" db:"id"`
+	TaskID     string          `json:"task_id" db:"task_id"`
+	Name       string          `json:"name" db:"name"` // implementation_plan, change_summary, etc.
+	Version    int             `json:"version" db:"version"`
+	Content    string          `json:"content" db:"content"`
+	Meta       json.RawMessage `json:"meta" db:"meta"`                         // JSON metadata
+	BlobSHA256 string          `json:"blob_sha256,omitempty" db:"blob_sha256"` // set when the content lives in the blob store
+	Size       int64           `json:"size" db:"size"`                         // content length in bytes
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
 }
 
 // Agent represents a role configuration
@@ -101,24 +153,63 @@ type AuditLog struct {
 	Result          string          `json:"result" db:"result"`
 	Note            string          `json:"note" db:"note"`
 	FollowUps       json.RawMessage `json:"follow_ups" db:"follow_ups"` // JSON array of follow-up interactions
+	TimeboxSeconds  int             `json:"timebox_seconds" db:"timebox_seconds"`   // cycle timeout the task was given
+	DurationSeconds float64         `json:"duration_seconds" db:"duration_seconds"` // how long the cycle actually took
+	ModelTier       string          `json:"model_tier,omitempty" db:"model_tier"`   // LLM model tier that served the cycle
+	Provider        string          `json:"provider,omitempty" db:"provider"`       // LLM provider that served the cycle, after any fallback
+	PromptTokens     int            `json:"prompt_tokens,omitempty" db:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens,omitempty" db:"completion_tokens"`
+	CostUSD          float64        `json:"cost_usd,omitempty" db:"cost_usd"` // what the cycle's LLM calls cost
+	Handshake        string         `json:"handshake,omitempty" db:"handshake"` // how the completion handshake ended, if one ran
+	Confidence       *float64       `json:"confidence,omitempty" db:"confidence"` // the agent's confidence in the transition, 0 to 1
+	OpenQuestions    json.RawMessage `json:"open_questions,omitempty" db:"open_questions"` // JSON array of questions the agent left open
 	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
 }
 
+// TaskBriefing is a cached LLM-generated status briefing for a task
+type TaskBriefing struct {
+	TaskID    string    `json:"task_id" db:"task_id"`
+	Version   string    `json:"version" db:"version"` // hash of the task state the briefing describes
+	Content   string    `json:"content" db:"content"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
 // TaskFilters represents filters for task queries
 type TaskFilters struct {
 	State    *State  `json:"state,omitempty"`
 	Priority *int    `json:"priority,omitempty"`
 	Owner    *string `json:"owner,omitempty"`
-	Tags     []string `json:"tags,omitempty"`
+	Tags     []string `json:"tags,omitempty"` // tasks must have every tag
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"` // field name -> value the task must have
+	ParentID        *string `json:"parent_id,omitempty"` // "" matches top-level tasks
+	DueBefore       *time.Time `json:"due_before,omitempty"` // tasks due before this time; tasks without a due date never match
+	IncludeArchived bool `json:"include_archived,omitempty"` // archived tasks are left out unless set
+	ArchivedOnly    bool `json:"archived_only,omitempty"`
+	IncludeDeleted  bool `json:"include_deleted,omitempty"` // deleted tasks are left out unless set
+	DeletedOnly     bool `json:"deleted_only,omitempty"`
+	AllProjects     bool `json:"all_projects,omitempty"` // tasks of other projects than the store's are left out unless set
+	Sort       string `json:"sort,omitempty"`       // one of TaskSorts; "" sorts by priority
+	Reverse    bool   `json:"reverse,omitempty"`    // flips the sort's order
+	Limit      int    `json:"limit,omitempty"`      // 0 lists every task
+	Offset     int    `json:"offset,omitempty"`
+	Cursor     string `json:"cursor,omitempty"` // TaskPage.NextCursor of the previous page
 }
 
 // CycleResult represents the outcome of a cycle execution
 type CycleResult struct {
+	CycleID         string        `json:"cycle_id"`
 	Success         bool          `json:"success"`
 	TaskID          string        `json:"task_id"`
 	PrevState       State         `json:"prev_state"`
 	NextState       State         `json:"next_state"`
 	ArtifactsCreated []string      `json:"artifacts_created"`
 	Duration        time.Duration `json:"duration"`
+	ModelTier       string        `json:"model_tier,omitempty"`
+	Provider        string        `json:"provider,omitempty"`
+	PromptTokens     int          `json:"prompt_tokens,omitempty"`
+	CompletionTokens int          `json:"completion_tokens,omitempty"`
+	CostUSD          float64      `json:"cost_usd,omitempty"`
+	Outcome          string       `json:"outcome,omitempty"` // the audit entry's result: success, failure, error or timed_out
+	AutoRetries      int          `json:"auto_retries,omitempty"` // cycles run again after a failed handshake, which the result covers
 	Error           error         `json:"error,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskNote is a comment on a task by a person or an agent
+type TaskNote struct {
+	ID        string    `json:"id" db:"id"`
+	TaskID    string    `json:"task_id" db:"task_id"`
+	Author    string    `json:"author" db:"author"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AddTaskNote records a note on a task
+func (s *Store) AddTaskNote(note *TaskNote) error {
+	if note.ID == "" {
+		note.ID = uuid.New().String()
+	}
+	note.CreatedAt = time.Now()
+	note.UpdatedAt = note.CreatedAt
+
+	query := `
+		INSERT INTO task_notes (id, task_id, author, body, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.Exec(query, note.ID, note.TaskID, note.Author, note.Body, note.CreatedAt.UTC(), note.UpdatedAt.UTC())
+	return err
+}
+
+// GetTaskNote returns a note by ID
+func (s *Store) GetTaskNote(id string) (*TaskNote, error) {
+	note := &TaskNote{}
+	err := s.db.QueryRow("SELECT id, task_id, author, body, created_at, updated_at FROM task_notes WHERE id = ?", id).Scan(
+		&note.ID, &note.TaskID, &note.Author, &note.Body, local(&note.CreatedAt), local(&note.UpdatedAt))
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// ListTaskNotes returns a task's notes, oldest first
+func (s *Store) ListTaskNotes(taskID string) ([]*TaskNote, error) {
+	rows, err := s.db.Query(`
+		SELECT id, task_id, author, body, created_at, updated_at
+		FROM task_notes WHERE task_id = ?
+		ORDER BY created_at, rowid
+	`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*TaskNote
+	for rows.Next() {
+		note := &TaskNote{}
+		if err := rows.Scan(&note.ID, &note.TaskID, &note.Author, &note.Body, local(&note.CreatedAt), local(&note.UpdatedAt)); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// UpdateTaskNote replaces the body of a note
+func (s *Store) UpdateTaskNote(note *TaskNote) error {
+	note.UpdatedAt = time.Now()
+
+	result, err := s.db.Exec("UPDATE task_notes SET body = ?, updated_at = ? WHERE id = ?", note.Body, note.UpdatedAt.UTC(), note.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// DeleteTaskNote removes a note, returning sql.ErrNoRows when there is none
+func (s *Store) DeleteTaskNote(id string) error {
+	result, err := s.db.Exec("DELETE FROM task_notes WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultProject is the project a store works in until SetProject picks
+// another. Tasks and requirements created before projects existed belong to it.
+const DefaultProject = "default"
+
+// ErrProjectNotFound is returned for a project that was never created
+var ErrProjectNotFound = errors.New("project not found")
+
+// validProjectID matches the project names CreateProject accepts
+var validProjectID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Project groups tasks, requirements and artifacts that share the database
+// with other projects
+type Project struct {
+	ID          string    `json:"id" db:"id"`
+	Description string    `json:"description,omitempty" db:"description"`
+	Tasks       int       `json:"tasks"` // tasks in the project, deleted ones excluded
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateProject adds a project
+func (s *Store) CreateProject(project *Project) error {
+	if !validProjectID.MatchString(project.ID) {
+		return fmt.Errorf("invalid project name %q: use letters, digits, '-' and '_'", project.ID)
+	}
+	if _, err := s.GetProject(project.ID); err == nil {
+		return fmt.Errorf("project %s already exists", project.ID)
+	}
+
+	project.CreatedAt = time.Now()
+	_, err := s.db.Exec("INSERT INTO projects (id, description, created_at) VALUES (?, ?, ?)",
+		project.ID, project.Description, project.CreatedAt.UTC())
+	return err
+}
+
+// GetProject returns a project with its task count, or ErrProjectNotFound
+func (s *Store) GetProject(id string) (*Project, error) {
+	projects, err := s.listProjects("WHERE p.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) == 0 {
+		return nil, ErrProjectNotFound
+	}
+	return projects[0], nil
+}
+
+// ListProjects returns every project with its task count, by name
+func (s *Store) ListProjects() ([]*Project, error) {
+	return s.listProjects("")
+}
+
+// listProjects returns the projects matching where
+func (s *Store) listProjects(where string, args ...interface{}) ([]*Project, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, p.description, p.created_at,
+			(SELECT COUNT(*) FROM tasks t WHERE t.project_id = p.id AND t.deleted_at IS NULL)
+		FROM projects p `+where+`
+		ORDER BY p.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*Project
+	for rows.Next() {
+		project := &Project{}
+		if err := rows.Scan(&project.ID, &project.Description, local(&project.CreatedAt), &project.Tasks); err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// SetProject scopes the store to a project: tasks and requirements are
+// created in it, and only its tasks and requirements are read back. A web UI
+// or MCP server built on the store serves that project alone.
+func (s *Store) SetProject(id string) error {
+	if id == "" {
+		id = DefaultProject
+	}
+	if _, err := s.GetProject(id); err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return fmt.Errorf("unknown project %q; create it with 'baton projects create %s'", id, id)
+		}
+		return err
+	}
+	s.project = id
+	return nil
+}
+
+// Project returns the project the store is scoped to
+func (s *Store) Project() string {
+	return s.project
+}
+
+// projectOrDefault is project, or DefaultProject for records exported before
+// projects existed
+func projectOrDefault(project string) string {
+	if project == "" {
+		return DefaultProject
+	}
+	return project
+}
+
+// projectCondition adds the store's project to a task query, unless the
+// filters ask for every project
+func (s *Store) projectCondition(f TaskFilters, query string, args []interface{}) (string, []interface{}) {
+	if f.AllProjects {
+		return query, args
+	}
+	return query + " AND project_id = ?", append(args, s.project)
+}
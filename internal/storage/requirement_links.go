@@ -0,0 +1,150 @@
+package storage
+
+import "fmt"
+
+// CreateTasksWithRequirements creates tasks and links each to the requirements
+// keyed under its ID in links, in one transaction. An unknown requirement key
+// fails the whole batch, so no task is left without the links it was given.
+func (s *Store) CreateTasksWithRequirements(tasks []*Task, links map[string][]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, task := range tasks {
+		if err := s.insertTask(tx, task); err != nil {
+			return fmt.Errorf("failed to create task %d (%q): %w", i+1, task.Title, err)
+		}
+		for _, key := range links[task.ID] {
+			var reqID string
+			if err := tx.QueryRow("SELECT id FROM requirements WHERE key = ? AND project_id = ?", key, s.project).Scan(&reqID); err != nil {
+				return fmt.Errorf("requirement %s not found: %w", key, err)
+			}
+			if _, err := tx.Exec("INSERT OR IGNORE INTO task_requirements (task_id, requirement_id) VALUES (?, ?)", task.ID, reqID); err != nil {
+				return fmt.Errorf("failed to link task %q to %s: %w", task.Title, key, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.emitCreated(tasks)
+	return nil
+}
+
+// ListRequirementTasks returns the tasks linked to a requirement, leaving out
+// deleted tasks
+func (s *Store) ListRequirementTasks(requirementKey string) ([]*Task, error) {
+	req, err := s.GetRequirement(requirementKey)
+	if err != nil {
+		return nil, fmt.Errorf("requirement %s not found: %w", requirementKey, err)
+	}
+
+	rows, err := s.db.Query("SELECT task_id FROM task_requirements WHERE requirement_id = ? ORDER BY task_id", req.ID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	linked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		linked[id] = true
+	}
+	tasks, err := s.ListTasks(TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+	var linkedTasks []*Task
+	for _, task := range tasks {
+		if linked[task.ID] {
+			linkedTasks = append(linkedTasks, task)
+		}
+	}
+	return linkedTasks, nil
+}
+
+// RequirementLinks is an active requirement with the tasks linked to it
+type RequirementLinks struct {
+	Requirement *Requirement `json:"requirement"`
+	Tasks       []*Task      `json:"tasks"`
+}
+
+// LinkCoverage shows how requirements and tasks cover each other
+type LinkCoverage struct {
+	Requirements  []*RequirementLinks `json:"requirements"`   // active requirements by key
+	UnlinkedTasks []*Task             `json:"unlinked_tasks"` // tasks linked to no requirement
+}
+
+// Uncovered returns the active requirements no task is linked to
+func (c *LinkCoverage) Uncovered() []*Requirement {
+	var uncovered []*Requirement
+	for _, links := range c.Requirements {
+		if len(links.Tasks) == 0 {
+			uncovered = append(uncovered, links.Requirement)
+		}
+	}
+	return uncovered
+}
+
+// GetLinkCoverage links active requirements to their tasks and finds the tasks
+// linked to none. Archived tasks count; deleted tasks and deprecated
+// requirements don't.
+func (s *Store) GetLinkCoverage() (*LinkCoverage, error) {
+	requirements, err := s.ListRequirements("")
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.ListTasks(TaskFilters{IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.listTaskRequirementLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	tasksByID := make(map[string]*Task, len(tasks))
+	for _, task := range tasks {
+		tasksByID[task.ID] = task
+	}
+	linkedTasks := make(map[string][]*Task)
+	linked := make(map[string]bool)
+	for _, link := range links {
+		if task, ok := tasksByID[link.TaskID]; ok {
+			linkedTasks[link.RequirementID] = append(linkedTasks[link.RequirementID], task)
+			linked[task.ID] = true
+		}
+	}
+
+	coverage := &LinkCoverage{Requirements: []*RequirementLinks{}, UnlinkedTasks: []*Task{}}
+	for _, req := range requirements {
+		if req.Status == RequirementDeprecated {
+			continue
+		}
+		linkedToReq := linkedTasks[req.ID]
+		if linkedToReq == nil {
+			linkedToReq = []*Task{}
+		}
+		coverage.Requirements = append(coverage.Requirements, &RequirementLinks{Requirement: req, Tasks: linkedToReq})
+	}
+	for _, task := range tasks {
+		if !linked[task.ID] {
+			coverage.UnlinkedTasks = append(coverage.UnlinkedTasks, task)
+		}
+	}
+	return coverage, nil
+}
@@ -0,0 +1,509 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Requirement statuses
+const (
+	RequirementActive     = "active"
+	RequirementDeprecated = "deprecated"
+)
+
+// requirementKeyPattern splits a key such as FR-12 or FR-P3 into its series
+// (FR-, FR-P) and number. Zero-padded numbers don't match, so such keys are
+// never allocated or renumbered.
+var requirementKeyPattern = regexp.MustCompile(`^([A-Z]{2,4}-[A-Z]?)([1-9][0-9]*)$`)
+
+// requirementSeriesPattern matches a key series as passed to the allocator
+var requirementSeriesPattern = regexp.MustCompile(`^[A-Z]{2,4}-[A-Z]?$`)
+
+// requirementTypePrefixes is the series new requirements of each type get
+var requirementTypePrefixes = map[string]string{
+	"functional":    "FR-",
+	"nonfunctional": "NFR-",
+	"constraint":    "CR-",
+	"risk":          "RR-",
+	"acceptance":    "AC-",
+}
+
+// RequirementKeyError reports a key that can't be used for a new requirement
+type RequirementKeyError struct {
+	Key    string
+	Reason string
+}
+
+func (e *RequirementKeyError) Error() string {
+	return fmt.Sprintf("requirement key %s can't be reused: %s", e.Key, e.Reason)
+}
+
+// RequirementRename is one key change made by renumbering
+type RequirementRename struct {
+	RequirementID string `json:"requirement_id"`
+	OldKey        string `json:"old_key"`
+	NewKey        string `json:"new_key"`
+	Title         string `json:"title"`
+	TaskLinks     int    `json:"task_links"` // links follow the requirement, not its key
+}
+
+// RequirementKeyPrefix returns the key series for a requirement type, FR- for
+// unknown types
+func RequirementKeyPrefix(reqType string) string {
+	if prefix, ok := requirementTypePrefixes[reqType]; ok {
+		return prefix
+	}
+	return "FR-"
+}
+
+// NormalizeRequirementPrefix turns FR or fr- into the series FR-
+func NormalizeRequirementPrefix(prefix string) (string, error) {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if !strings.Contains(prefix, "-") {
+		prefix += "-"
+	}
+	if !requirementSeriesPattern.MatchString(prefix) {
+		return "", fmt.Errorf("invalid requirement key prefix %q: use 2-4 letters, e.g. FR or NFR-", prefix)
+	}
+	return prefix, nil
+}
+
+// parseRequirementKey returns a key's series and number
+func parseRequirementKey(key string) (string, int, bool) {
+	matches := requirementKeyPattern.FindStringSubmatch(key)
+	if matches == nil {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return matches[1], n, true
+}
+
+// requirementKeyOrder returns the ORDER BY terms that sort requirements by
+// the key in column: by series, then by number, so FR-2 comes before FR-10
+func requirementKeyOrder(column string) string {
+	series := "rtrim(" + column + ", '0123456789')"
+	return series + ", CAST(substr(" + column + ", length(" + series + ") + 1) AS INTEGER), " + column
+}
+
+// checkRequirementKey rejects keys of deprecated or renumbered requirements
+// in a project
+func (s *Store) checkRequirementKey(project, key string) error {
+	var status string
+	err := s.db.QueryRow("SELECT status FROM requirements WHERE key = ? AND project_id = ?", key, project).Scan(&status)
+	if err == nil && status == RequirementDeprecated {
+		return &RequirementKeyError{Key: key, Reason: "the requirement was deprecated"}
+	}
+
+	var replacedBy string
+	err = s.db.QueryRow("SELECT replaced_by FROM retired_requirement_keys WHERE key = ? AND project_id = ?", key, project).Scan(&replacedBy)
+	if err == nil {
+		return &RequirementKeyError{Key: key, Reason: "it was renumbered to " + replacedBy}
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// scopeRequirementKeys rebuilds the requirements and retired keys tables of a
+// database from when keys were unique across projects, so that each project
+// has its own key series. Retired keys go to their requirement's project.
+func (s *Store) scopeRequirementKeys() error {
+	var scoped int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('retired_requirement_keys') WHERE name = 'project_id'").Scan(&scoped)
+	if err != nil {
+		return err
+	}
+	if scoped > 0 {
+		return nil
+	}
+
+	// Dropping the old requirements table would cascade to the task links,
+	// so the rebuild runs on one connection with foreign keys off
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+
+	if err := rebuildRequirementTables(ctx, conn); err != nil {
+		conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+		return err
+	}
+	_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	return err
+}
+
+// rebuildRequirementTables copies requirements and retired keys into tables
+// keyed by project, in one transaction
+func rebuildRequirementTables(ctx context.Context, conn *sql.Conn) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`CREATE TABLE requirements_scoped (
+			id TEXT PRIMARY KEY,
+			project_id TEXT NOT NULL DEFAULT 'default',
+			key TEXT NOT NULL,
+			title TEXT NOT NULL,
+			text TEXT NOT NULL,
+			type TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'active',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (project_id, key)
+		)`,
+		`INSERT INTO requirements_scoped (id, project_id, key, title, text, type, status, created_at, updated_at)
+			SELECT id, project_id, key, title, text, type, status, created_at, updated_at FROM requirements`,
+		`CREATE TABLE retired_requirement_keys_scoped (
+			project_id TEXT NOT NULL DEFAULT 'default',
+			key TEXT NOT NULL,
+			requirement_id TEXT NOT NULL,
+			replaced_by TEXT NOT NULL,
+			retired_at DATETIME NOT NULL,
+			PRIMARY KEY (project_id, key)
+		)`,
+		`INSERT INTO retired_requirement_keys_scoped (project_id, key, requirement_id, replaced_by, retired_at)
+			SELECT COALESCE((SELECT project_id FROM requirements r WHERE r.id = k.requirement_id), 'default'),
+				k.key, k.requirement_id, k.replaced_by, k.retired_at
+			FROM retired_requirement_keys k`,
+		"DROP TABLE requirements",
+		"DROP TABLE retired_requirement_keys",
+		"ALTER TABLE requirements_scoped RENAME TO requirements",
+		"ALTER TABLE retired_requirement_keys_scoped RENAME TO retired_requirement_keys",
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	// The dropped table took its indexes and triggers with it
+	if _, err := tx.ExecContext(ctx, CreateTablesSQL); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// usedRequirementKeys returns every key in a project held by a requirement,
+// whatever its status, or retired by renumbering
+func usedRequirementKeys(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, project string) ([]string, error) {
+	rows, err := q.Query(`
+		SELECT key FROM requirements WHERE project_id = ?
+		UNION SELECT key FROM retired_requirement_keys WHERE project_id = ?
+	`, project, project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// nextKey returns the key after the highest one used in the series
+func nextKey(prefix string, used []string) string {
+	highest := 0
+	for _, key := range used {
+		if series, n, ok := parseRequirementKey(key); ok && series == prefix && n > highest {
+			highest = n
+		}
+	}
+	return fmt.Sprintf("%s%d", prefix, highest+1)
+}
+
+// NextRequirementKey returns the key the allocator would issue next in a
+// series of the store's project, e.g. FR-13 after FR-12. Deprecated and
+// retired keys count as used.
+func (s *Store) NextRequirementKey(prefix string) (string, error) {
+	prefix, err := NormalizeRequirementPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	used, err := usedRequirementKeys(s.db, s.project)
+	if err != nil {
+		return "", err
+	}
+	return nextKey(prefix, used), nil
+}
+
+// AllocateRequirement creates a requirement under the next free key in the
+// series, or the series for its type when prefix is "", and sets req.Key
+func (s *Store) AllocateRequirement(req *Requirement, prefix string) error {
+	if prefix == "" {
+		prefix = RequirementKeyPrefix(req.Type)
+	}
+	prefix, err := NormalizeRequirementPrefix(prefix)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	used, err := usedRequirementKeys(tx, s.project)
+	if err != nil {
+		return err
+	}
+
+	if req.ID == "" {
+		req.ID = uuid.New().String()
+	}
+	req.Key = nextKey(prefix, used)
+	req.ProjectID = s.project
+	req.Status = RequirementActive
+	req.CreatedAt = time.Now()
+	req.UpdatedAt = req.CreatedAt
+
+	_, err = tx.Exec(`
+		INSERT INTO requirements (id, project_id, key, title, text, type, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, req.ID, req.ProjectID, req.Key, req.Title, req.Text, req.Type, req.Status, req.CreatedAt.UTC(), req.UpdatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeprecateRequirement marks a requirement deprecated. It keeps its key and
+// task links, and the key is never issued again.
+func (s *Store) DeprecateRequirement(key string) error {
+	result, err := s.db.Exec("UPDATE requirements SET status = ? WHERE key = ? AND project_id = ?",
+		RequirementDeprecated, key, s.project)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("requirement %s not found", key)
+	}
+	return nil
+}
+
+// PlanRequirementRenumber works out how to close the gaps in each key series,
+// or only in prefix's series when it isn't "". Active requirements, in key
+// order, move down to the lowest numbers no requirement has ever held; keys
+// of deprecated requirements and keys already retired are never reused.
+func (s *Store) PlanRequirementRenumber(prefix string) ([]*RequirementRename, error) {
+	if prefix != "" {
+		var err error
+		if prefix, err = NormalizeRequirementPrefix(prefix); err != nil {
+			return nil, err
+		}
+	}
+
+	requirements, err := s.ListRequirements("")
+	if err != nil {
+		return nil, err
+	}
+	used, err := usedRequirementKeys(s.db, s.project)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]map[int]bool)
+	for _, key := range used {
+		if series, n, ok := parseRequirementKey(key); ok {
+			if taken[series] == nil {
+				taken[series] = make(map[int]bool)
+			}
+			taken[series][n] = true
+		}
+	}
+
+	type numbered struct {
+		req *Requirement
+		n   int
+	}
+	bySeries := make(map[string][]numbered)
+	for _, req := range requirements {
+		series, n, ok := parseRequirementKey(req.Key)
+		if !ok || req.Status == RequirementDeprecated || (prefix != "" && series != prefix) {
+			continue
+		}
+		bySeries[series] = append(bySeries[series], numbered{req: req, n: n})
+	}
+
+	seriesNames := make([]string, 0, len(bySeries))
+	for series := range bySeries {
+		seriesNames = append(seriesNames, series)
+	}
+	sort.Strings(seriesNames)
+
+	var renames []*RequirementRename
+	for _, series := range seriesNames {
+		reqs := bySeries[series]
+		sort.Slice(reqs, func(i, j int) bool { return reqs[i].n < reqs[j].n })
+
+		free := 1
+		for _, r := range reqs {
+			for taken[series][free] {
+				free++
+			}
+			if free >= r.n {
+				continue
+			}
+			// The vacated number stays taken: it is retired, not freed
+			taken[series][free] = true
+			renames = append(renames, &RequirementRename{
+				RequirementID: r.req.ID,
+				OldKey:        r.req.Key,
+				NewKey:        fmt.Sprintf("%s%d", series, free),
+				Title:         r.req.Title,
+			})
+		}
+	}
+
+	for _, rename := range renames {
+		err := s.db.QueryRow("SELECT COUNT(*) FROM task_requirements WHERE requirement_id = ?",
+			rename.RequirementID).Scan(&rename.TaskLinks)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return renames, nil
+}
+
+// RenumberRequirements applies renames from PlanRequirementRenumber in one
+// transaction: keys change, old keys are retired, and mentions of old keys in
+// the project's requirements and in its task titles and descriptions are
+// rewritten. It returns how many tasks were rewritten. Task links are by
+// requirement ID, so they carry over as is.
+func (s *Store) RenumberRequirements(renames []*RequirementRename) (int, error) {
+	if len(renames) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	keys := make(map[string]string, len(renames))
+	for _, rename := range renames {
+		result, err := tx.Exec("UPDATE requirements SET key = ? WHERE id = ? AND key = ?",
+			rename.NewKey, rename.RequirementID, rename.OldKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to renumber %s to %s: %w", rename.OldKey, rename.NewKey, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return 0, fmt.Errorf("requirement %s changed since the renumbering was planned", rename.OldKey)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO retired_requirement_keys (project_id, key, requirement_id, replaced_by, retired_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, s.project, rename.OldKey, rename.RequirementID, rename.NewKey, now.UTC())
+		if err != nil {
+			return 0, err
+		}
+		keys[rename.OldKey] = rename.NewKey
+	}
+
+	// Requirements can mention each other, so their text is rewritten too
+	if _, err := replaceKeysInTable(tx, "requirements", "text", s.project, keys); err != nil {
+		return 0, err
+	}
+	rewritten, err := replaceKeysInTable(tx, "tasks", "description", s.project, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	return rewritten, tx.Commit()
+}
+
+// replaceKeysInTable rewrites key mentions in the title and the given text
+// column of a project's rows in a table, returning how many rows changed.
+// Rewritten tasks get a revision attributed to the renumbering.
+func replaceKeysInTable(tx *sql.Tx, table, column, project string, keys map[string]string) (int, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT id, title, COALESCE(%s, ''), updated_at FROM %s WHERE project_id = ?", column, table), project)
+	if err != nil {
+		return 0, err
+	}
+	type rowText struct {
+		id, title, text string
+		prev            *TaskRevision
+	}
+	var rewritten []rowText
+	for rows.Next() {
+		var r rowText
+		var updatedAt time.Time
+		if err := rows.Scan(&r.id, &r.title, &r.text, local(&updatedAt)); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		title, text := ReplaceRequirementKeys(r.title, keys), ReplaceRequirementKeys(r.text, keys)
+		if title != r.title || text != r.text {
+			prev := &TaskRevision{Title: r.title, Description: r.text, CreatedAt: updatedAt}
+			rewritten = append(rewritten, rowText{r.id, title, text, prev})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	for _, r := range rewritten {
+		query := fmt.Sprintf("UPDATE %s SET title = ?, %s = ?, updated_at = ? WHERE id = ?", table, column)
+		if _, err := tx.Exec(query, r.title, r.text, now.UTC(), r.id); err != nil {
+			return 0, err
+		}
+		if table == "tasks" {
+			next := &TaskRevision{TaskID: r.id, Title: r.title, Description: r.text, Actor: "renumber", CreatedAt: now}
+			if err := recordRevision(tx, r.prev, next); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(rewritten), nil
+}
+
+// ReplaceRequirementKeys rewrites whole-word mentions of the old keys in
+// text, all at once so chained renames don't apply twice
+func ReplaceRequirementKeys(text string, keys map[string]string) string {
+	if len(keys) == 0 || text == "" {
+		return text
+	}
+	old := make([]string, 0, len(keys))
+	for key := range keys {
+		old = append(old, regexp.QuoteMeta(key))
+	}
+	// Longest first, so FR-1 doesn't win over FR-12
+	sort.Slice(old, func(i, j int) bool { return len(old[i]) > len(old[j]) })
+	pattern := regexp.MustCompile(`\b(` + strings.Join(old, "|") + `)\b`)
+	return pattern.ReplaceAllStringFunc(text, func(key string) string {
+		return keys[key]
+	})
+}
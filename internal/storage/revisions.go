@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TaskRevision is one version of a task's title and description
+type TaskRevision struct {
+	TaskID       string    `json:"task_id" db:"task_id"`
+	Revision     int       `json:"revision" db:"revision"`
+	Title        string    `json:"title" db:"title"`
+	Description  string    `json:"description" db:"description"`
+	Actor        string    `json:"actor,omitempty" db:"actor"`                 // "" for the text before the first recorded change
+	RevertedFrom int       `json:"reverted_from,omitempty" db:"reverted_from"` // the revision a revert restored
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// recordRevision stores next as the task's newest revision. A task's first
+// change also stores prev, the text it had until then, as revision 1.
+func recordRevision(tx *sql.Tx, prev, next *TaskRevision) error {
+	var latest int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(revision), 0) FROM task_revisions WHERE task_id = ?", next.TaskID).Scan(&latest); err != nil {
+		return err
+	}
+
+	insert := `
+		INSERT INTO task_revisions (task_id, revision, title, description, actor, reverted_from, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	if latest == 0 {
+		latest++
+		if _, err := tx.Exec(insert, next.TaskID, latest, prev.Title, prev.Description, "", 0, prev.CreatedAt.UTC()); err != nil {
+			return err
+		}
+	}
+
+	next.Revision = latest + 1
+	_, err := tx.Exec(insert, next.TaskID, next.Revision, next.Title, next.Description, next.Actor, next.RevertedFrom, next.CreatedAt.UTC())
+	return err
+}
+
+// ListTaskRevisions returns a task's revisions, oldest first. A task whose
+// title and description never changed has none.
+func (s *Store) ListTaskRevisions(taskID string) ([]*TaskRevision, error) {
+	rows, err := s.db.Query(`
+		SELECT task_id, revision, title, description, actor, reverted_from, created_at
+		FROM task_revisions WHERE task_id = ? ORDER BY revision
+	`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*TaskRevision
+	for rows.Next() {
+		rev := &TaskRevision{}
+		if err := rows.Scan(&rev.TaskID, &rev.Revision, &rev.Title, &rev.Description, &rev.Actor, &rev.RevertedFrom, local(&rev.CreatedAt)); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// RevertTask restores the title and description of one of a task's revisions,
+// recording the restore as a new revision attributed to actor
+func (s *Store) RevertTask(taskID string, revision int, actor string) (*Task, error) {
+	rev := &TaskRevision{}
+	err := s.db.QueryRow(`
+		SELECT task_id, revision, title, description FROM task_revisions WHERE task_id = ? AND revision = ?
+	`, taskID, revision).Scan(&rev.TaskID, &rev.Revision, &rev.Title, &rev.Description)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("task %s has no revision %d", taskID, revision)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Title == rev.Title && task.Description == rev.Description {
+		return nil, fmt.Errorf("task %s already matches revision %d", taskID, revision)
+	}
+
+	task.Title, task.Description = rev.Title, rev.Description
+	if err := s.updateTask(task, actor, revision); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// DiffText compares two texts line by line, prefixing removed lines with
+// "- ", added lines with "+ " and unchanged lines with "  "
+func DiffText(before, after string) string {
+	a, b := strings.Split(before, "\n"), strings.Split(after, "\n")
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RollbackCycleID is the cycle ID of the compensating audit entry a rollback
+// records
+const RollbackCycleID = "rollback"
+
+// Rollback undoes a task's last recorded transition: the task returns to the
+// state the transition left, and artifacts changed since the transition's
+// work began get their earlier content back
+type Rollback struct {
+	TaskID    string             `json:"task_id"`
+	From      State              `json:"from"`
+	To        State              `json:"to"`
+	Undone    *AuditLog          `json:"undone"` // the audit entry of the transition rolled back
+	Artifacts []*ArtifactRestore `json:"artifacts,omitempty"`
+	Kept      []string           `json:"kept,omitempty"` // artifacts first created by the transition, which have nothing to return to
+
+	undoneIDs []string // Undone and any other entries recording the same transition, such as its hooks'
+}
+
+// ArtifactRestore is an artifact a rollback returns to an earlier version.
+// Versions are never removed: the earlier content is saved as a new version.
+type ArtifactRestore struct {
+	Name        string `json:"name"`
+	FromVersion int    `json:"from_version"` // the version current before the rollback
+	ToVersion   int    `json:"to_version"`   // the version whose content is restored
+	Version     int    `json:"version"`      // the new version holding it, once applied
+}
+
+// rollbackInputs is the inputs summary of a compensating audit entry
+type rollbackInputs struct {
+	RolledBack []string `json:"rolled_back"` // IDs of the audit entries undone
+}
+
+// isTransitionEntry reports whether an audit entry records a state change
+// that took place
+func isTransitionEntry(entry *AuditLog) bool {
+	return entry.PrevState != "" && entry.NextState != "" && entry.PrevState != entry.NextState &&
+		entry.Result != "blocked" && entry.Result != "error"
+}
+
+// PlanRollback works out what rolling back a task's last recorded transition
+// would do, without doing it. Transitions already rolled back are skipped, so
+// repeated rollbacks walk further back through the audit history.
+func (s *Store) PlanRollback(taskID string) (*Rollback, error) {
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := s.GetAuditLogs(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit history: %w", err)
+	}
+
+	// Newest first, so a rollback is seen before the entries it undid
+	undone := make(map[string]bool)
+	for i, entry := range logs {
+		if entry.CycleID == RollbackCycleID {
+			var inputs rollbackInputs
+			if json.Unmarshal([]byte(entry.InputsSummary), &inputs) == nil {
+				for _, id := range inputs.RolledBack {
+					undone[id] = true
+				}
+			}
+			continue
+		}
+		if undone[entry.ID] || !isTransitionEntry(entry) {
+			continue
+		}
+
+		if NormalizeState(entry.NextState) != task.State {
+			return nil, fmt.Errorf("task %s is %s, but its last recorded transition was %s → %s; only that transition can be rolled back",
+				taskID, task.State, entry.PrevState, entry.NextState)
+		}
+		rollback := &Rollback{TaskID: taskID, From: task.State, To: NormalizeState(entry.PrevState), Undone: entry}
+		start := entry.CreatedAt.Add(-time.Duration(entry.DurationSeconds * float64(time.Second)))
+
+		// A cycle's transition may also be recorded by the hooks it ran
+		rollback.undoneIDs = []string{entry.ID}
+		for _, other := range logs[i+1:] {
+			if other.CreatedAt.Before(start) {
+				break
+			}
+			if other.PrevState == entry.PrevState && other.NextState == entry.NextState {
+				rollback.undoneIDs = append(rollback.undoneIDs, other.ID)
+			}
+		}
+		if err := s.planArtifactRestores(rollback, start); err != nil {
+			return nil, err
+		}
+		return rollback, nil
+	}
+	return nil, fmt.Errorf("task %s has no recorded transition to roll back", taskID)
+}
+
+// planArtifactRestores finds the artifacts changed since start and the
+// version each had then
+func (s *Store) planArtifactRestores(rollback *Rollback, start time.Time) error {
+	artifacts, err := s.ListArtifacts(rollback.TaskID)
+	if err != nil {
+		return fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	// Grouped by name, newest version first
+	var latest *Artifact
+	settled := make(map[string]bool) // names whose outcome is known
+	for _, artifact := range artifacts {
+		if latest == nil || latest.Name != artifact.Name {
+			latest = artifact
+			if latest.CreatedAt.Before(start) {
+				settled[artifact.Name] = true // unchanged since
+				continue
+			}
+		}
+		if settled[artifact.Name] || !artifact.CreatedAt.Before(start) {
+			continue
+		}
+		settled[artifact.Name] = true
+		rollback.Artifacts = append(rollback.Artifacts, &ArtifactRestore{
+			Name:        artifact.Name,
+			FromVersion: latest.Version,
+			ToVersion:   artifact.Version,
+		})
+	}
+
+	for _, artifact := range artifacts {
+		if !settled[artifact.Name] {
+			settled[artifact.Name] = true
+			rollback.Kept = append(rollback.Kept, artifact.Name)
+		}
+	}
+	return nil
+}
+
+// RollbackTask rolls back a task's last recorded transition, as planned by
+// PlanRollback, and records a compensating audit entry attributed to actor.
+// The state change, the restored artifacts and the audit entry are written
+// in one transaction: a rollback that fails halfway leaves nothing behind.
+func (s *Store) RollbackTask(taskID, actor, reason string) (*Rollback, error) {
+	rollback, err := s.PlanRollback(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	note := fmt.Sprintf("rolled back %s → %s", rollback.Undone.PrevState, rollback.Undone.NextState)
+	if reason != "" {
+		note += ": " + reason
+	}
+
+	// Earlier content is read up front; blobs are not read through the transaction
+	restored := make([]*Artifact, len(rollback.Artifacts))
+	for i, restore := range rollback.Artifacts {
+		earlier, err := s.GetArtifact(taskID, restore.Name, restore.ToVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get artifact %s version %d: %w", restore.Name, restore.ToVersion, err)
+		}
+		restored[i] = &Artifact{TaskID: taskID, Name: restore.Name, Content: earlier.Content, Meta: earlier.Meta}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	prevState, err := updateTaskStateTx(tx, taskID, rollback.To)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, restore := range rollback.Artifacts {
+		if err := s.upsertArtifact(tx, restored[i]); err != nil {
+			return nil, fmt.Errorf("failed to restore artifact %s: %w", restore.Name, err)
+		}
+		restore.Version = restored[i].Version
+	}
+
+	inputs, _ := json.Marshal(rollbackInputs{RolledBack: rollback.undoneIDs})
+	outputs, _ := json.Marshal(map[string]interface{}{"restored_artifacts": rollback.Artifacts, "kept_artifacts": rollback.Kept})
+	entry := &AuditLog{
+		TaskID:         taskID,
+		CycleID:        RollbackCycleID,
+		PrevState:      string(rollback.From),
+		NextState:      string(rollback.To),
+		Actor:          actor,
+		InputsSummary:  string(inputs),
+		OutputsSummary: string(outputs),
+		Result:         "rolled_back",
+		Note:           note,
+	}
+	if err := insertAuditLog(tx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record the rollback: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.emitTransition(taskID, prevState, rollback.To, note)
+	for _, artifact := range restored {
+		s.emit(TaskEvent{Kind: EventArtifact, TaskID: taskID, Artifact: artifact.Name, Version: artifact.Version})
+	}
+	return rollback, nil
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// Kinds of search hits
+const (
+	SearchKindTask        = "task"
+	SearchKindArtifact    = "artifact"
+	SearchKindRequirement = "requirement"
+	SearchKindAudit       = "audit"
+)
+
+// SearchKinds lists every kind of search hit
+var SearchKinds = []string{SearchKindTask, SearchKindArtifact, SearchKindRequirement, SearchKindAudit}
+
+// SearchHit is one task, artifact, requirement or audit note matching a search
+type SearchHit struct {
+	Kind      string  `json:"kind"` // task|artifact|requirement|audit
+	RefID     string  `json:"ref_id"`
+	TaskID    string  `json:"task_id,omitempty"` // empty for requirements
+	TaskTitle string  `json:"task_title,omitempty"`
+	TaskState State   `json:"task_state,omitempty"`
+	Title     string  `json:"title"` // task title, artifact name, requirement key and title, or audit transition
+	Snippet   string  `json:"snippet"`
+	Score     float64 `json:"score"` // higher is more relevant
+}
+
+// SearchDocument is an indexed document, used for semantic search
+type SearchDocument struct {
+	Kind      string
+	RefID     string
+	TaskID    string
+	TaskTitle string
+	TaskState State
+	Title     string
+	Body      string
+}
+
+// SearchFilters narrows search results
+type SearchFilters struct {
+	State *State `json:"state,omitempty"`
+	Owner string `json:"owner,omitempty"`
+	Kind  string `json:"kind,omitempty"` // task|artifact|requirement|audit
+	Limit int    `json:"limit,omitempty"`
+}
+
+// ftsQuery turns free text into an FTS5 query matching any of its words, so
+// punctuation in natural-language questions cannot cause syntax errors
+func ftsQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"`)
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// filterClause builds the WHERE conditions shared by keyword and semantic
+// search over the documents of one project
+func filterClause(filters SearchFilters, project string) (string, []interface{}) {
+	// Deleted tasks' documents are left out; requirements have no task
+	conditions := []string{
+		"t.deleted_at IS NULL",
+		"(t.project_id = ? OR si.ref_id IN (SELECT id FROM requirements WHERE project_id = ?))",
+	}
+	args := []interface{}{project, project}
+
+	if filters.State != nil {
+		conditions = append(conditions, "t.state = ?")
+		args = append(args, *filters.State)
+	}
+	if filters.Owner != "" {
+		conditions = append(conditions, "t.owner = ?")
+		args = append(args, filters.Owner)
+	}
+	if filters.Kind != "" {
+		conditions = append(conditions, "si.kind = ?")
+		args = append(args, filters.Kind)
+	}
+
+	return " AND " + strings.Join(conditions, " AND "), args
+}
+
+// Search runs a keyword search over tasks, artifacts, requirements and audit
+// notes, best matches first. Requirements belong to no task, so state and
+// owner filters leave them out.
+func (s *Store) Search(text string, filters SearchFilters) ([]*SearchHit, error) {
+	match := ftsQuery(text)
+	if match == "" {
+		return nil, nil
+	}
+
+	where, args := filterClause(filters, s.project)
+	query := `
+		SELECT si.kind, si.ref_id, si.task_id, COALESCE(t.title, ''), COALESCE(t.state, ''), si.title,
+			snippet(search_index, 4, '[', ']', '…', 16), bm25(search_index)
+		FROM search_index si LEFT JOIN tasks t ON t.id = si.task_id
+		WHERE search_index MATCH ?` + where + `
+		ORDER BY bm25(search_index)`
+
+	queryArgs := append([]interface{}{match}, args...)
+	if filters.Limit > 0 {
+		query += " LIMIT ?"
+		queryArgs = append(queryArgs, filters.Limit)
+	}
+
+	rows, err := s.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []*SearchHit
+	for rows.Next() {
+		hit := &SearchHit{}
+		var rank float64
+		if err := rows.Scan(&hit.Kind, &hit.RefID, &hit.TaskID, &hit.TaskTitle, &hit.TaskState,
+			&hit.Title, &hit.Snippet, &rank); err != nil {
+			return nil, err
+		}
+		// bm25 is lower-is-better; flip it so all search scores sort descending
+		hit.Score = -rank
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+// ListSearchDocuments returns every indexed document matching the filters
+func (s *Store) ListSearchDocuments(filters SearchFilters) ([]*SearchDocument, error) {
+	where, args := filterClause(filters, s.project)
+	query := `
+		SELECT si.kind, si.ref_id, si.task_id, COALESCE(t.title, ''), COALESCE(t.state, ''), si.title, si.body
+		FROM search_index si LEFT JOIN tasks t ON t.id = si.task_id
+		WHERE 1 = 1` + where
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []*SearchDocument
+	for rows.Next() {
+		doc := &SearchDocument{}
+		if err := rows.Scan(&doc.Kind, &doc.RefID, &doc.TaskID, &doc.TaskTitle, &doc.TaskState,
+			&doc.Title, &doc.Body); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, rows.Err()
+}
+
+// GetSearchEmbedding returns the cached embedding for a document if it was
+// computed by the same model from the same content
+func (s *Store) GetSearchEmbedding(refID, model, contentHash string) ([]float64, error) {
+	var data string
+	err := s.db.QueryRow("SELECT vector FROM search_embeddings WHERE ref_id = ? AND model = ? AND content_hash = ?",
+		refID, model, contentHash).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var vector []float64
+	if err := json.Unmarshal([]byte(data), &vector); err != nil {
+		return nil, err
+	}
+	return vector, nil
+}
+
+// SaveSearchEmbedding caches the embedding for a document
+func (s *Store) SaveSearchEmbedding(refID, model, contentHash string, vector []float64) error {
+	data, err := json.Marshal(vector)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO search_embeddings (ref_id, model, content_hash, vector) VALUES (?, ?, ?, ?)
+		ON CONFLICT(ref_id) DO UPDATE SET model = excluded.model, content_hash = excluded.content_hash,
+			vector = excluded.vector
+	`, refID, model, contentHash, string(data))
+	return err
+}
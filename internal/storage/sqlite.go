@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -11,16 +12,28 @@ import (
 
 // Store represents the SQLite database storage
 type Store struct {
-	db *sql.DB
+	db            *sql.DB
+	path          string // database file; archived payloads and blobs live beside it
+	events        eventBus
+	project       string // project tasks and requirements are created in and read from
+	blobThreshold int // artifact size above which content goes to the blob store
+	key           string // encryption key of an encrypted database, "" for plaintext
 }
 
 // NewStore creates a new SQLite store
 func NewStore(dbPath string) (*Store, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	// Every pooled connection waits out the write locks of concurrent writers,
+	// such as parallel cycles, instead of failing with SQLITE_BUSY. Transactions
+	// take the write lock up front: one that read first could not wait for it.
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	return newStore(db, dbPath)
+}
 
+// newStore sets up and migrates an opened database
+func newStore(db *sql.DB, dbPath string) (*Store, error) {
 	// Enable foreign keys and WAL mode for better concurrency
 	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
@@ -30,7 +43,7 @@ func NewStore(dbPath string) (*Store, error) {
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	store := &Store{db: db}
+	store := &Store{db: db, path: dbPath, project: DefaultProject, blobThreshold: DefaultBlobThreshold}
 
 	// Run migrations
 	if err := store.migrate(); err != nil {
@@ -42,8 +55,89 @@ func NewStore(dbPath string) (*Store, error) {
 
 // migrate runs the database migrations
 func (s *Store) migrate() error {
-	_, err := s.db.Exec(CreateTablesSQL)
-	return err
+	if _, err := s.db.Exec(CreateTablesSQL); err != nil {
+		return err
+	}
+	if err := s.addMissingColumns(); err != nil {
+		return err
+	}
+	if err := s.scopeRequirementKeys(); err != nil {
+		return fmt.Errorf("failed to make requirement keys unique per project: %w", err)
+	}
+	if err := s.normalizeTimestamps(); err != nil {
+		return fmt.Errorf("failed to convert timestamps to UTC: %w", err)
+	}
+	if err := s.backfillStateHistory(); err != nil {
+		return fmt.Errorf("failed to backfill state history: %w", err)
+	}
+	return s.backfillSearchIndex()
+}
+
+// addMissingColumns adds columns introduced after a table was created
+func (s *Store) addMissingColumns() error {
+	for _, added := range AddedColumns {
+		var count int
+		err := s.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", added.Table, added.Column).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.Table, added.Column, added.Definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", added.Table, added.Column, err)
+		}
+	}
+	return nil
+}
+
+// backfillSearchIndex populates the search index for databases created before
+// it, or before one of its kinds, existed; afterwards triggers keep it current
+func (s *Store) backfillSearchIndex() error {
+	backfills := []struct {
+		kind   string
+		source string // table whose rows the kind indexes
+		insert string
+	}{
+		{"task", "tasks", `
+			INSERT INTO search_index (kind, ref_id, task_id, title, body)
+			SELECT 'task', id, id, title, COALESCE(description, '') FROM tasks`},
+		{"artifact", "artifacts", `
+			INSERT INTO search_index (kind, ref_id, task_id, title, body)
+			SELECT 'artifact', a.id, a.task_id, a.name, a.content FROM artifacts a
+			WHERE a.version = (SELECT MAX(version) FROM artifacts WHERE task_id = a.task_id AND name = a.name)`},
+		{"requirement", "requirements", `
+			INSERT INTO search_index (kind, ref_id, task_id, title, body)
+			SELECT 'requirement', id, '', key || ' ' || title, text FROM requirements`},
+		{"audit", "audit_logs", `
+			INSERT INTO search_index (kind, ref_id, task_id, title, body)
+			SELECT 'audit', id, task_id,
+				COALESCE(prev_state, '') || ' → ' || COALESCE(next_state, '') || ' (' || COALESCE(actor, '') || ')', note
+			FROM audit_logs WHERE COALESCE(note, '') != ''`},
+	}
+
+	for _, b := range backfills {
+		var indexed, rows int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM search_index WHERE kind = ?", b.kind).Scan(&indexed); err != nil {
+			return err
+		}
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM " + b.source).Scan(&rows); err != nil {
+			return err
+		}
+		if indexed > 0 || rows == 0 {
+			continue
+		}
+		if _, err := s.db.Exec(b.insert); err != nil {
+			return fmt.Errorf("failed to index %s rows: %w", b.kind, err)
+		}
+	}
+	return nil
+}
+
+// Ping checks that the database is reachable
+func (s *Store) Ping() error {
+	return s.db.Ping()
 }
 
 // Close closes the database connection
@@ -53,35 +147,57 @@ func (s *Store) Close() error {
 
 // Task operations
 func (s *Store) CreateTask(task *Task) error {
+	if err := s.insertTask(s.db, task); err != nil {
+		return err
+	}
+	s.emit(TaskEvent{Kind: EventCreated, TaskID: task.ID})
+	return nil
+}
+
+// insertTask gives a new task its ID and project, unless it has them, and
+// timestamps, and inserts it
+func (s *Store) insertTask(q interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, task *Task) error {
 	if task.ID == "" {
 		task.ID = uuid.New().String()
 	}
+	if task.ProjectID == "" {
+		task.ProjectID = s.project
+	}
 
 	task.CreatedAt = time.Now()
 	task.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO tasks (id, title, description, state, priority, owner, tags, dependencies, blocked_by, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO tasks (id, project_id, title, description, state, priority, owner, tags, dependencies, blocked_by,
+			estimated_hours, due_date, parent_id, custom_fields, archived, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.Exec(query, task.ID, task.Title, task.Description, task.State, task.Priority,
-		task.Owner, task.Tags, task.Dependencies, task.BlockedBy, task.CreatedAt, task.UpdatedAt)
+	_, err := q.Exec(query, task.ID, task.ProjectID, task.Title, task.Description, task.State, task.Priority,
+		task.Owner, task.Tags, task.Dependencies, task.BlockedBy, task.EstimatedHours, utcOrNil(task.DueDate), task.ParentID,
+		customFieldsValue(task.CustomFields), task.Archived, task.CreatedAt.UTC(), task.UpdatedAt.UTC())
+	if err != nil {
+		return err
+	}
 
-	return err
+	return recordStateEntry(q, task.ID, task.State, task.CreatedAt)
 }
 
 func (s *Store) GetTask(id string) (*Task, error) {
 	query := `
-		SELECT id, title, description, state, priority, owner, tags, dependencies, blocked_by, created_at, updated_at
-		FROM tasks WHERE id = ?
+		SELECT id, project_id, title, description, state, held_from, review_failures, priority, owner, tags, dependencies, blocked_by,
+			estimated_hours, due_date, locked_by, locked_at, parent_id, custom_fields, archived, deleted_at, created_at, updated_at
+		FROM tasks WHERE id = ? AND project_id = ? AND deleted_at IS NULL
 	`
 
 	task := &Task{}
-	err := s.db.QueryRow(query, id).Scan(
-		&task.ID, &task.Title, &task.Description, &task.State, &task.Priority,
-		&task.Owner, &task.Tags, &task.Dependencies, &task.BlockedBy,
-		&task.CreatedAt, &task.UpdatedAt,
+	err := s.db.QueryRow(query, id, s.project).Scan(
+		&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.State, &task.HeldFrom, &task.ReviewFailures, &task.Priority,
+		&task.Owner, (*[]byte)(&task.Tags), (*[]byte)(&task.Dependencies), (*[]byte)(&task.BlockedBy),
+		&task.EstimatedHours, localOrNil{&task.DueDate}, &task.LockedBy, localOrNil{&task.LockedAt}, &task.ParentID, (*[]byte)(&task.CustomFields), &task.Archived,
+		localOrNil{&task.DeletedAt}, local(&task.CreatedAt), local(&task.UpdatedAt),
 	)
 
 	if err != nil {
@@ -98,17 +214,70 @@ func (s *Store) UpdateTaskState(id string, state State, note string) error {
 	}
 	defer tx.Rollback()
 
-	// Update task state
-	_, err = tx.Exec("UPDATE tasks SET state = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", state, id)
+	prevState, err := updateTaskStateTx(tx, id, state)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.emitTransition(id, prevState, state, note)
+	return nil
+}
+
+// UpdateTaskStateWithArtifacts saves artifacts, each as the next version of
+// its name, and moves the task to state in one transaction, so that neither
+// is written without the other
+func (s *Store) UpdateTaskStateWithArtifacts(id string, state State, note string, artifacts []*Artifact) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, artifact := range artifacts {
+		if err := s.upsertArtifact(tx, artifact); err != nil {
+			return fmt.Errorf("failed to save artifact %s: %w", artifact.Name, err)
+		}
+	}
+	prevState, err := updateTaskStateTx(tx, id, state)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, artifact := range artifacts {
+		s.emit(TaskEvent{Kind: EventArtifact, TaskID: artifact.TaskID, Artifact: artifact.Name, Version: artifact.Version})
+	}
+	s.emitTransition(id, prevState, state, note)
+	return nil
+}
+
+// updateTaskStateTx moves a task to state within tx and returns the state it
+// left, "" when the task doesn't exist
+func updateTaskStateTx(tx *sql.Tx, id string, state State) (State, error) {
+	var prevState, prevHeldFrom State
+	tx.QueryRow("SELECT state, held_from FROM tasks WHERE id = ?", id).Scan(&prevState, &prevHeldFrom)
+
+	now := time.Now()
+	_, err := tx.Exec("UPDATE tasks SET state = ?, held_from = ?, review_failures = review_failures + ?, updated_at = ? WHERE id = ?",
+		state, heldFrom(prevState, prevHeldFrom, state), reviewFailure(prevState, state), now.UTC(), id)
+	if err != nil {
+		return "", err
+	}
+	if prevState != state {
+		if err := recordStateEntry(tx, id, state, now); err != nil {
+			return "", fmt.Errorf("failed to record state history: %w", err)
+		}
+	}
+	return prevState, nil
 }
 
 func (s *Store) ListTasks(filters TaskFilters) ([]*Task, error) {
-	query := "SELECT id, title, description, state, priority, owner, tags, dependencies, blocked_by, created_at, updated_at FROM tasks WHERE 1=1"
+	query := "SELECT id, project_id, title, description, state, held_from, review_failures, priority, owner, tags, dependencies, blocked_by, estimated_hours, due_date, locked_by, locked_at, parent_id, custom_fields, archived, deleted_at, created_at, updated_at FROM tasks WHERE 1=1"
 	args := []interface{}{}
 
 	if filters.State != nil {
@@ -126,7 +295,26 @@ func (s *Store) ListTasks(filters TaskFilters) ([]*Task, error) {
 		args = append(args, *filters.Owner)
 	}
 
-	query += " ORDER BY priority DESC, updated_at ASC"
+	if filters.ParentID != nil {
+		query += " AND parent_id = ?"
+		args = append(args, *filters.ParentID)
+	}
+
+	if filters.DueBefore != nil {
+		query += " AND due_date < ?"
+		args = append(args, filters.DueBefore.UTC())
+	}
+
+	query, args = filters.tagConditions(query, args)
+	query, args = filters.customFieldConditions(query, args)
+	query = filters.archivedCondition(query)
+	query = filters.deletedCondition(query)
+	query, args = s.projectCondition(filters, query, args)
+
+	query, args, err := filters.orderAndPage(query, args)
+	if err != nil {
+		return nil, err
+	}
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
@@ -138,9 +326,10 @@ func (s *Store) ListTasks(filters TaskFilters) ([]*Task, error) {
 	for rows.Next() {
 		task := &Task{}
 		err := rows.Scan(
-			&task.ID, &task.Title, &task.Description, &task.State, &task.Priority,
-			&task.Owner, &task.Tags, &task.Dependencies, &task.BlockedBy,
-			&task.CreatedAt, &task.UpdatedAt,
+			&task.ID, &task.ProjectID, &task.Title, &task.Description, &task.State, &task.HeldFrom, &task.ReviewFailures, &task.Priority,
+			&task.Owner, (*[]byte)(&task.Tags), (*[]byte)(&task.Dependencies), (*[]byte)(&task.BlockedBy),
+			&task.EstimatedHours, localOrNil{&task.DueDate}, &task.LockedBy, localOrNil{&task.LockedAt}, &task.ParentID, (*[]byte)(&task.CustomFields), &task.Archived,
+			localOrNil{&task.DeletedAt}, local(&task.CreatedAt), local(&task.UpdatedAt),
 		)
 		if err != nil {
 			return nil, err
@@ -151,6 +340,75 @@ func (s *Store) ListTasks(filters TaskFilters) ([]*Task, error) {
 	return tasks, rows.Err()
 }
 
+// SplitTask creates children as subtasks of parent and makes parent depend on
+// them, so it is not selected again until they are done. It all happens in one
+// transaction; the parent's updated dependencies are written back to parent.
+func (s *Store) SplitTask(parent *Task, children []*Task) error {
+	if len(children) == 0 {
+		return fmt.Errorf("task %s cannot be split into zero subtasks", parent.ID)
+	}
+
+	var dependencies []string
+	if len(parent.Dependencies) > 0 {
+		if err := json.Unmarshal(parent.Dependencies, &dependencies); err != nil {
+			return fmt.Errorf("invalid dependencies on task %s: %w", parent.ID, err)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, child := range children {
+		if child.ID == "" {
+			child.ID = uuid.New().String()
+		}
+		child.ParentID = parent.ID
+		child.ProjectID = parent.ProjectID
+		if child.ProjectID == "" {
+			child.ProjectID = s.project
+		}
+		child.CreatedAt = now
+		child.UpdatedAt = now
+
+		_, err := tx.Exec(`
+			INSERT INTO tasks (id, project_id, title, description, state, priority, owner, tags, dependencies, blocked_by,
+				estimated_hours, parent_id, custom_fields, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, child.ID, child.ProjectID, child.Title, child.Description, child.State, child.Priority,
+			child.Owner, child.Tags, child.Dependencies, child.BlockedBy, child.EstimatedHours, child.ParentID,
+			customFieldsValue(child.CustomFields), child.CreatedAt.UTC(), child.UpdatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to create subtask %q: %w", child.Title, err)
+		}
+		if err := recordStateEntry(tx, child.ID, child.State, child.CreatedAt); err != nil {
+			return fmt.Errorf("failed to create subtask %q: %w", child.Title, err)
+		}
+		dependencies = append(dependencies, child.ID)
+	}
+
+	deps, err := json.Marshal(dependencies)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE tasks SET dependencies = ?, updated_at = ? WHERE id = ?", deps, now.UTC(), parent.ID); err != nil {
+		return fmt.Errorf("failed to update task %s: %w", parent.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	parent.Dependencies = deps
+	parent.UpdatedAt = now
+
+	s.emitCreated(children)
+	s.emit(TaskEvent{Kind: EventUpdated, TaskID: parent.ID})
+	return nil
+}
+
 // Requirement operations
 func (s *Store) CreateRequirement(req *Requirement) error {
 	if req.ID == "" {
@@ -160,39 +418,62 @@ func (s *Store) CreateRequirement(req *Requirement) error {
 	req.CreatedAt = time.Now()
 	req.UpdatedAt = time.Now()
 
+	if req.Status == "" {
+		req.Status = RequirementActive
+	}
+
+	if req.ProjectID == "" {
+		req.ProjectID = s.project
+	}
+
+	// Keys of deprecated and renumbered requirements are never handed out again
+	if err := s.checkRequirementKey(req.ProjectID, req.Key); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO requirements (id, key, title, text, type, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO requirements (id, project_id, key, title, text, type, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.Exec(query, req.ID, req.Key, req.Title, req.Text, req.Type, req.CreatedAt, req.UpdatedAt)
+	_, err := s.db.Exec(query, req.ID, req.ProjectID, req.Key, req.Title, req.Text, req.Type, req.Status, req.CreatedAt.UTC(), req.UpdatedAt.UTC())
 	return err
 }
 
 func (s *Store) GetRequirement(key string) (*Requirement, error) {
 	query := `
-		SELECT id, key, title, text, type, created_at, updated_at
-		FROM requirements WHERE key = ?
+		SELECT id, project_id, key, title, text, type, status, created_at, updated_at
+		FROM requirements WHERE key = ? AND project_id = ?
 	`
 
 	req := &Requirement{}
-	err := s.db.QueryRow(query, key).Scan(
-		&req.ID, &req.Key, &req.Title, &req.Text, &req.Type, &req.CreatedAt, &req.UpdatedAt,
+	err := s.db.QueryRow(query, key, s.project).Scan(
+		&req.ID, &req.ProjectID, &req.Key, &req.Title, &req.Text, &req.Type, &req.Status, local(&req.CreatedAt), local(&req.UpdatedAt),
 	)
 
 	return req, err
 }
 
 func (s *Store) ListRequirements(reqType string) ([]*Requirement, error) {
-	query := "SELECT id, key, title, text, type, created_at, updated_at FROM requirements"
+	return s.listRequirements(reqType, false)
+}
+
+// listRequirements lists the requirements of the store's project, or of every
+// project when allProjects is set
+func (s *Store) listRequirements(reqType string, allProjects bool) ([]*Requirement, error) {
+	query := "SELECT id, project_id, key, title, text, type, status, created_at, updated_at FROM requirements WHERE 1=1"
 	args := []interface{}{}
 
 	if reqType != "" {
-		query += " WHERE type = ?"
+		query += " AND type = ?"
 		args = append(args, reqType)
 	}
+	if !allProjects {
+		query += " AND project_id = ?"
+		args = append(args, s.project)
+	}
 
-	query += " ORDER BY key"
+	query += " ORDER BY " + requirementKeyOrder("key")
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
@@ -203,7 +484,7 @@ func (s *Store) ListRequirements(reqType string) ([]*Requirement, error) {
 	var requirements []*Requirement
 	for rows.Next() {
 		req := &Requirement{}
-		err := rows.Scan(&req.ID, &req.Key, &req.Title, &req.Text, &req.Type, &req.CreatedAt, &req.UpdatedAt)
+		err := rows.Scan(&req.ID, &req.ProjectID, &req.Key, &req.Title, &req.Text, &req.Type, &req.Status, local(&req.CreatedAt), local(&req.UpdatedAt))
 		if err != nil {
 			return nil, err
 		}
@@ -217,15 +498,69 @@ func (s *Store) UpdateRequirement(req *Requirement) error {
 	query := `
 		UPDATE requirements
 		SET title = ?, text = ?, type = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE key = ?
+		WHERE key = ? AND project_id = ?
 	`
 
-	_, err := s.db.Exec(query, req.Title, req.Text, req.Type, req.Key)
+	_, err := s.db.Exec(query, req.Title, req.Text, req.Type, req.Key, s.project)
 	return err
 }
 
+// LinkTaskRequirement links a task to a requirement by requirement key
+func (s *Store) LinkTaskRequirement(taskID, requirementKey string) error {
+	req, err := s.GetRequirement(requirementKey)
+	if err != nil {
+		return fmt.Errorf("requirement %s not found: %w", requirementKey, err)
+	}
+
+	_, err = s.db.Exec("INSERT OR IGNORE INTO task_requirements (task_id, requirement_id) VALUES (?, ?)",
+		taskID, req.ID)
+	return err
+}
+
+// ListTaskRequirements returns the requirements linked to a task
+func (s *Store) ListTaskRequirements(taskID string) ([]*Requirement, error) {
+	query := `
+		SELECT r.id, r.project_id, r.key, r.title, r.text, r.type, r.status, r.created_at, r.updated_at
+		FROM requirements r
+		JOIN task_requirements tr ON tr.requirement_id = r.id
+		WHERE tr.task_id = ?
+		ORDER BY ` + requirementKeyOrder("r.key")
+
+	rows, err := s.db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requirements []*Requirement
+	for rows.Next() {
+		req := &Requirement{}
+		err := rows.Scan(&req.ID, &req.ProjectID, &req.Key, &req.Title, &req.Text, &req.Type, &req.Status, local(&req.CreatedAt), local(&req.UpdatedAt))
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, req)
+	}
+
+	return requirements, rows.Err()
+}
+
 // Artifact operations
 func (s *Store) UpsertArtifact(artifact *Artifact) error {
+	if err := s.upsertArtifact(s.db, artifact); err != nil {
+		return err
+	}
+
+	s.emit(TaskEvent{Kind: EventArtifact, TaskID: artifact.TaskID, Artifact: artifact.Name, Version: artifact.Version})
+	return nil
+}
+
+// upsertArtifact stores artifact as the next version of its name through q,
+// the database or a transaction, without announcing it
+func (s *Store) upsertArtifact(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, artifact *Artifact) error {
 	if artifact.ID == "" {
 		artifact.ID = uuid.New().String()
 	}
@@ -233,35 +568,42 @@ func (s *Store) UpsertArtifact(artifact *Artifact) error {
 
 	// Get the next version number for this task/name combination
 	var maxVersion int
-	err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM artifacts WHERE task_id = ? AND name = ?",
+	err := q.QueryRow("SELECT COALESCE(MAX(version), 0) FROM artifacts WHERE task_id = ? AND name = ?",
 		artifact.TaskID, artifact.Name).Scan(&maxVersion)
 	if err != nil {
 		return err
 	}
 
 	artifact.Version = maxVersion + 1
+	return s.insertArtifact(q, artifact)
+}
 
-	query := `
-		INSERT INTO artifacts (id, task_id, name, version, content, meta, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
-
-	_, err = s.db.Exec(query, artifact.ID, artifact.TaskID, artifact.Name, artifact.Version,
-		artifact.Content, artifact.Meta, artifact.CreatedAt)
-
-	return err
+// RestoreArtifact inserts an artifact exactly as given, keeping its id,
+// version and creation time, e.g. when rebuilding a recorded workspace
+func (s *Store) RestoreArtifact(artifact *Artifact) error {
+	return s.insertArtifact(s.db, artifact)
 }
 
 func (s *Store) GetArtifact(taskID, name string, version int) (*Artifact, error) {
+	artifact, err := s.getArtifact(taskID, name, version)
+	if err != nil {
+		return nil, err
+	}
+	s.loadArtifactBlob(artifact)
+	return artifact, nil
+}
+
+// getArtifact returns an artifact without reading spilled content from its blob
+func (s *Store) getArtifact(taskID, name string, version int) (*Artifact, error) {
 	query := `
-		SELECT id, task_id, name, version, content, meta, created_at
+		SELECT id, task_id, name, version, content, meta, blob_sha256, size, created_at
 		FROM artifacts WHERE task_id = ? AND name = ? AND version = ?
 	`
 
 	if version == 0 {
 		// Get latest version
 		query = `
-			SELECT id, task_id, name, version, content, meta, created_at
+			SELECT id, task_id, name, version, content, meta, blob_sha256, size, created_at
 			FROM artifacts WHERE task_id = ? AND name = ?
 			ORDER BY version DESC LIMIT 1
 		`
@@ -272,26 +614,39 @@ func (s *Store) GetArtifact(taskID, name string, version int) (*Artifact, error)
 
 	if version == 0 {
 		err = s.db.QueryRow(query, taskID, name).Scan(
-			&artifact.ID, &artifact.TaskID, &artifact.Name, &artifact.Version,
-			&artifact.Content, &artifact.Meta, &artifact.CreatedAt,
+			&artifact.ID, &artifact.TaskID, &artifact.Name, &artifact.Version, &artifact.Content,
+			(*[]byte)(&artifact.Meta), &artifact.BlobSHA256, &artifact.Size, local(&artifact.CreatedAt),
 		)
 	} else {
 		err = s.db.QueryRow(query, taskID, name, version).Scan(
-			&artifact.ID, &artifact.TaskID, &artifact.Name, &artifact.Version,
-			&artifact.Content, &artifact.Meta, &artifact.CreatedAt,
+			&artifact.ID, &artifact.TaskID, &artifact.Name, &artifact.Version, &artifact.Content,
+			(*[]byte)(&artifact.Meta), &artifact.BlobSHA256, &artifact.Size, local(&artifact.CreatedAt),
 		)
 	}
+	if err != nil {
+		return nil, err
+	}
+	if artifact.BlobSHA256 == "" {
+		artifact.Size = int64(len(artifact.Content))
+	}
 
-	return artifact, err
+	return artifact, nil
 }
 
 func (s *Store) ListArtifacts(taskID string) ([]*Artifact, error) {
 	query := `
-		SELECT id, task_id, name, version, content, meta, created_at
+		SELECT id, task_id, name, version, content, meta, blob_sha256, size, created_at
 		FROM artifacts WHERE task_id = ? ORDER BY name, version DESC
 	`
 
-	rows, err := s.db.Query(query, taskID)
+	return s.queryArtifacts(query, taskID)
+}
+
+// queryArtifacts runs an artifact query selecting id, task_id, name, version,
+// content, meta, blob_sha256, size and created_at, reading spilled content
+// back from the blob store
+func (s *Store) queryArtifacts(query string, args ...interface{}) ([]*Artifact, error) {
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -300,19 +655,44 @@ func (s *Store) ListArtifacts(taskID string) ([]*Artifact, error) {
 	var artifacts []*Artifact
 	for rows.Next() {
 		artifact := &Artifact{}
-		err := rows.Scan(&artifact.ID, &artifact.TaskID, &artifact.Name, &artifact.Version,
-			&artifact.Content, &artifact.Meta, &artifact.CreatedAt)
+		err := rows.Scan(&artifact.ID, &artifact.TaskID, &artifact.Name, &artifact.Version, &artifact.Content,
+			(*[]byte)(&artifact.Meta), &artifact.BlobSHA256, &artifact.Size, local(&artifact.CreatedAt))
 		if err != nil {
 			return nil, err
 		}
+		if artifact.BlobSHA256 == "" {
+			artifact.Size = int64(len(artifact.Content))
+		}
 		artifacts = append(artifacts, artifact)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return artifacts, rows.Err()
+	for _, artifact := range artifacts {
+		s.loadArtifactBlob(artifact)
+	}
+	return artifacts, nil
 }
 
 // Audit operations
 func (s *Store) CreateAuditLog(log *AuditLog) error {
+	if err := insertAuditLog(s.db, log); err != nil {
+		return err
+	}
+
+	switch log.Result {
+	case "failure", "error", CycleTimeout:
+		s.emit(TaskEvent{Kind: EventCycleFailed, TaskID: log.TaskID, Result: log.Result, Note: log.Note})
+	}
+	return nil
+}
+
+// insertAuditLog records log through q, the database or a transaction,
+// without announcing it
+func insertAuditLog(q interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, log *AuditLog) error {
 	if log.ID == "" {
 		log.ID = uuid.New().String()
 	}
@@ -320,21 +700,31 @@ func (s *Store) CreateAuditLog(log *AuditLog) error {
 
 	query := `
 		INSERT INTO audit_logs (id, task_id, cycle_id, prev_state, next_state, actor,
-			selection_reason, inputs_summary, outputs_summary, commands, result, note, follow_ups, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			selection_reason, inputs_summary, outputs_summary, commands, result, note, follow_ups,
+			timebox_seconds, duration_seconds, model_tier, provider, prompt_tokens, completion_tokens,
+			cost_usd, handshake, confidence, open_questions, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.Exec(query, log.ID, log.TaskID, log.CycleID, log.PrevState, log.NextState,
-		log.Actor, log.SelectionReason, log.InputsSummary, log.OutputsSummary, log.Commands,
-		log.Result, log.Note, log.FollowUps, log.CreatedAt)
+	openQuestions := string(log.OpenQuestions)
+	if openQuestions == "" {
+		openQuestions = "[]"
+	}
 
+	_, err := q.Exec(query, log.ID, log.TaskID, log.CycleID, log.PrevState, log.NextState,
+		log.Actor, log.SelectionReason, log.InputsSummary, log.OutputsSummary, log.Commands,
+		log.Result, log.Note, log.FollowUps, log.TimeboxSeconds, log.DurationSeconds, log.ModelTier, log.Provider,
+		log.PromptTokens, log.CompletionTokens, log.CostUSD, log.Handshake, log.Confidence, openQuestions,
+		log.CreatedAt.UTC())
 	return err
 }
 
 func (s *Store) GetAuditLogs(taskID string) ([]*AuditLog, error) {
 	query := `
 		SELECT id, task_id, cycle_id, prev_state, next_state, actor, selection_reason,
-			inputs_summary, outputs_summary, commands, result, note, follow_ups, created_at
+			inputs_summary, outputs_summary, commands, result, note, follow_ups,
+			timebox_seconds, duration_seconds, model_tier, provider, prompt_tokens, completion_tokens,
+			cost_usd, handshake, confidence, open_questions, created_at, archive_path, archive_sha256
 		FROM audit_logs WHERE task_id = ? ORDER BY created_at DESC
 	`
 
@@ -347,14 +737,60 @@ func (s *Store) GetAuditLogs(taskID string) ([]*AuditLog, error) {
 	var logs []*AuditLog
 	for rows.Next() {
 		log := &AuditLog{}
+		var archivePath, archiveSum string
 		err := rows.Scan(&log.ID, &log.TaskID, &log.CycleID, &log.PrevState, &log.NextState,
-			&log.Actor, &log.SelectionReason, &log.InputsSummary, &log.OutputsSummary, &log.Commands,
-			&log.Result, &log.Note, &log.FollowUps, &log.CreatedAt)
+			&log.Actor, &log.SelectionReason, &log.InputsSummary, &log.OutputsSummary, (*[]byte)(&log.Commands),
+			&log.Result, &log.Note, (*[]byte)(&log.FollowUps), &log.TimeboxSeconds, &log.DurationSeconds,
+			&log.ModelTier, &log.Provider, &log.PromptTokens, &log.CompletionTokens, &log.CostUSD,
+			&log.Handshake, &log.Confidence, (*[]byte)(&log.OpenQuestions), local(&log.CreatedAt), &archivePath, &archiveSum)
 		if err != nil {
 			return nil, err
 		}
+		s.restoreAuditLog(log, archivePath, archiveSum)
 		logs = append(logs, log)
 	}
 
 	return logs, rows.Err()
-}
\ No newline at end of file
+}
+
+// ListAuditLogsSince returns audit logs across all tasks created at or after since, oldest first
+func (s *Store) ListAuditLogsSince(since time.Time) ([]*AuditLog, error) {
+	query := `
+		SELECT id, task_id, cycle_id, prev_state, next_state, actor, selection_reason,
+			inputs_summary, outputs_summary, commands, result, note, follow_ups,
+			timebox_seconds, duration_seconds, model_tier, provider, prompt_tokens, completion_tokens,
+			cost_usd, handshake, confidence, open_questions, created_at, archive_path, archive_sha256
+		FROM audit_logs WHERE created_at >= ? ORDER BY created_at ASC
+	`
+
+	rows, err := s.db.Query(query, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*AuditLog
+	for rows.Next() {
+		log := &AuditLog{}
+		var archivePath, archiveSum string
+		err := rows.Scan(&log.ID, &log.TaskID, &log.CycleID, &log.PrevState, &log.NextState,
+			&log.Actor, &log.SelectionReason, &log.InputsSummary, &log.OutputsSummary, (*[]byte)(&log.Commands),
+			&log.Result, &log.Note, (*[]byte)(&log.FollowUps), &log.TimeboxSeconds, &log.DurationSeconds,
+			&log.ModelTier, &log.Provider, &log.PromptTokens, &log.CompletionTokens, &log.CostUSD,
+			&log.Handshake, &log.Confidence, (*[]byte)(&log.OpenQuestions), local(&log.CreatedAt), &archivePath, &archiveSum)
+		if err != nil {
+			return nil, err
+		}
+		s.restoreAuditLog(log, archivePath, archiveSum)
+		logs = append(logs, log)
+	}
+
+	return logs, rows.Err()
+}
+
+// LLMSpendSince returns what cycles logged at or after since spent on LLM calls, in USD
+func (s *Store) LLMSpendSince(since time.Time) (float64, error) {
+	var spent float64
+	err := s.db.QueryRow("SELECT COALESCE(SUM(cost_usd), 0) FROM audit_logs WHERE created_at >= ?", since.UTC()).Scan(&spent)
+	return spent, err
+}
@@ -1,7 +1,14 @@
 package storage
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -279,4 +286,2571 @@ func TestArtifactOperations(t *testing.T) {
 	if len(artifacts) != 2 {
 		t.Errorf("Expected 2 artifacts, got %d", len(artifacts))
 	}
-}
\ No newline at end of file
+}
+func TestTaskRequirementLinks(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_requirements.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Linked Task", State: Planning}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	for _, key := range []string{"FR-2", "FR-1"} {
+		req := &Requirement{Key: key, Title: "Requirement " + key, Text: "Text", Type: "functional"}
+		if err := store.CreateRequirement(req); err != nil {
+			t.Fatalf("Failed to create requirement: %v", err)
+		}
+		if err := store.LinkTaskRequirement(task.ID, key); err != nil {
+			t.Fatalf("Failed to link requirement: %v", err)
+		}
+	}
+
+	// Linking twice is a no-op
+	if err := store.LinkTaskRequirement(task.ID, "FR-1"); err != nil {
+		t.Fatalf("Failed to re-link requirement: %v", err)
+	}
+
+	if err := store.LinkTaskRequirement(task.ID, "FR-404"); err == nil {
+		t.Error("Expected error linking unknown requirement")
+	}
+
+	linked, err := store.ListTaskRequirements(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to list linked requirements: %v", err)
+	}
+
+	if len(linked) != 2 {
+		t.Fatalf("Expected 2 linked requirements, got %d", len(linked))
+	}
+
+	if linked[0].Key != "FR-1" || linked[1].Key != "FR-2" {
+		t.Errorf("Expected linked requirements ordered by key, got %s, %s", linked[0].Key, linked[1].Key)
+	}
+}
+
+func TestRequirementKeyOrder(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_requirement_key_order.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Linked Task", State: Planning}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	for _, key := range []string{"FR-10", "NFR-12", "FR-2", "FR-100", "NFR-1", "FR-1"} {
+		req := &Requirement{Key: key, Title: "Requirement " + key, Text: "Text", Type: "functional"}
+		if err := store.CreateRequirement(req); err != nil {
+			t.Fatalf("Failed to create requirement %s: %v", key, err)
+		}
+		if err := store.LinkTaskRequirement(task.ID, key); err != nil {
+			t.Fatalf("Failed to link requirement %s: %v", key, err)
+		}
+	}
+
+	want := []string{"FR-1", "FR-2", "FR-10", "FR-100", "NFR-1", "NFR-12"}
+	keys := func(requirements []*Requirement) []string {
+		var keys []string
+		for _, req := range requirements {
+			keys = append(keys, req.Key)
+		}
+		return keys
+	}
+
+	linked, err := store.ListTaskRequirements(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to list linked requirements: %v", err)
+	}
+	if got := keys(linked); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected linked requirements in key order %v, got %v", want, got)
+	}
+
+	all, err := store.ListRequirements("")
+	if err != nil {
+		t.Fatalf("Failed to list requirements: %v", err)
+	}
+	if got := keys(all); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected requirements in key order %v, got %v", want, got)
+	}
+}
+
+func TestSearchFTS(t *testing.T) {
+	dbFile := "test_search.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{
+		Title:       "Add rate limiting",
+		Description: "Protect the public API from bursts",
+		State:       Planning,
+	}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	for _, content := range []string{"Use a token bucket", "Use a sliding window"} {
+		if err := store.UpsertArtifact(&Artifact{TaskID: task.ID, Name: "implementation_plan", Content: content}); err != nil {
+			t.Fatalf("Failed to create artifact: %v", err)
+		}
+	}
+
+	// Punctuation in the query must not break the FTS syntax
+	hits, err := store.Search("rate-limiting?", SearchFilters{})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Kind != "task" || hits[0].TaskID != task.ID {
+		t.Errorf("Expected the task to match, got %+v", hits)
+	}
+
+	// Only the latest artifact version is indexed
+	hits, err = store.Search("bucket", SearchFilters{})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Errorf("Expected superseded artifact version not to match, got %d hits", len(hits))
+	}
+
+	hits, err = store.Search("sliding", SearchFilters{Kind: "artifact"})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Title != "implementation_plan" {
+		t.Errorf("Expected the latest artifact to match, got %+v", hits)
+	}
+
+	// Title changes are reindexed
+	task.Title = "Add request throttling"
+	if err := store.UpdateTask(task); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	hits, err = store.Search("throttling", SearchFilters{Kind: "task"})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(hits) != 1 {
+		t.Errorf("Expected renamed task to match, got %d hits", len(hits))
+	}
+
+	// Requirements and audit notes are indexed too
+	req := &Requirement{Key: "NFR-1", Title: "Burst protection", Text: "Survive traffic spikes", Type: "nonfunctional"}
+	if err := store.CreateRequirement(req); err != nil {
+		t.Fatalf("Failed to create requirement: %v", err)
+	}
+	if err := store.CreateAuditLog(&AuditLog{TaskID: task.ID, CycleID: "c1", PrevState: "planning",
+		NextState: "ready_for_implementation", Actor: "architect", Note: "Spikes come from the nightly batch job"}); err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+	hits, err = store.Search("spikes", SearchFilters{})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	kinds := make(map[string]*SearchHit)
+	for _, hit := range hits {
+		kinds[hit.Kind] = hit
+	}
+	if len(hits) != 2 || kinds[SearchKindRequirement] == nil || kinds[SearchKindAudit] == nil {
+		t.Fatalf("Expected a requirement and an audit hit, got %+v", hits)
+	}
+	if hit := kinds[SearchKindRequirement]; hit.Title != "NFR-1 Burst protection" || hit.TaskID != "" {
+		t.Errorf("Unexpected requirement hit: %+v", hit)
+	}
+	if hit := kinds[SearchKindAudit]; hit.TaskID != task.ID || hit.TaskTitle != task.Title {
+		t.Errorf("Unexpected audit hit: %+v", hit)
+	}
+
+	// Task filters leave out requirements, which belong to no task
+	hits, err = store.Search("spikes", SearchFilters{State: &task.State})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Kind != SearchKindAudit {
+		t.Errorf("Expected only the audit note to match the task filters, got %+v", hits)
+	}
+}
+
+func TestSplitTask(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_split.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	parent := &Task{
+		Title:        "Large Task",
+		State:        Implementing,
+		Priority:     5,
+		Dependencies: []byte(`["existing-dep"]`),
+	}
+	if err := store.CreateTask(parent); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	children := []*Task{
+		{Title: "Part 1", State: ReadyForPlan, Priority: 5},
+		{Title: "Part 2", State: ReadyForPlan, Priority: 5},
+	}
+	if err := store.SplitTask(parent, children); err != nil {
+		t.Fatalf("Failed to split task: %v", err)
+	}
+
+	for _, child := range children {
+		retrieved, err := store.GetTask(child.ID)
+		if err != nil {
+			t.Fatalf("Failed to get subtask: %v", err)
+		}
+		if retrieved.ParentID != parent.ID {
+			t.Errorf("Expected parent %s, got %s", parent.ID, retrieved.ParentID)
+		}
+	}
+
+	// The parent should now wait for its subtasks as well as its old dependencies
+	retrievedParent, err := store.GetTask(parent.ID)
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	expected := `["existing-dep","` + children[0].ID + `","` + children[1].ID + `"]`
+	if string(retrievedParent.Dependencies) != expected {
+		t.Errorf("Expected dependencies %s, got %s", expected, retrievedParent.Dependencies)
+	}
+
+	if err := store.SplitTask(parent, nil); err == nil {
+		t.Error("Expected an error splitting a task into zero subtasks")
+	}
+}
+
+func TestTaskWatchesAndEvents(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_watches.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	var events []TaskEvent
+	store.Subscribe(func(event TaskEvent) {
+		events = append(events, event)
+	})
+
+	task := &Task{Title: "Watched Task", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	if err := store.WatchTask(&TaskWatch{TaskID: task.ID, Watcher: "alice"}); err != nil {
+		t.Fatalf("Failed to watch task: %v", err)
+	}
+	if err := store.WatchTask(&TaskWatch{TaskID: task.ID, Watcher: "alice", Channel: "slack"}); err != nil {
+		t.Fatalf("Failed to re-watch task: %v", err)
+	}
+	watches, err := store.ListTaskWatches(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to list watches: %v", err)
+	}
+	if len(watches) != 1 || watches[0].Channel != "slack" {
+		t.Errorf("Expected one watch on channel slack, got %+v", watches)
+	}
+
+	// Creating the task, a transition, an artifact and a failed cycle each
+	// report one event; watching and a successful audit entry do not
+	store.UpdateTaskState(task.ID, Planning, "start")
+	store.UpsertArtifact(&Artifact{TaskID: task.ID, Name: "implementation_plan", Content: "plan"})
+	store.CreateAuditLog(&AuditLog{TaskID: task.ID, CycleID: "c1", Result: "success"})
+	store.CreateAuditLog(&AuditLog{TaskID: task.ID, CycleID: "c2", Result: CycleTimeout})
+
+	kinds := []string{EventCreated, EventTransition, EventArtifact, EventCycleFailed}
+	if len(events) != len(kinds) {
+		t.Fatalf("Expected %d events, got %+v", len(kinds), events)
+	}
+	for i, kind := range kinds {
+		if events[i].Kind != kind {
+			t.Errorf("Event %d: expected %s, got %s", i, kind, events[i].Kind)
+		}
+	}
+	if events[1].PrevState != ReadyForPlan || events[1].NextState != Planning {
+		t.Errorf("Unexpected transition %s -> %s", events[1].PrevState, events[1].NextState)
+	}
+
+	removed, err := store.UnwatchTask(task.ID, "alice")
+	if err != nil || !removed {
+		t.Errorf("Expected watch to be removed, got %v, %v", removed, err)
+	}
+	if removed, _ := store.UnwatchTask(task.ID, "alice"); removed {
+		t.Error("Expected nothing to remove the second time")
+	}
+}
+
+func TestAreaLocks(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_area_locks.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.AcquireAreaLocks([]string{"backend", "db-schema"}, "t1", "c1", "host:1", time.Hour); err != nil {
+		t.Fatalf("Failed to acquire area locks: %v", err)
+	}
+
+	// A second cycle sharing one area gets none of its areas
+	err = store.AcquireAreaLocks([]string{"frontend", "backend"}, "t2", "c2", "host:2", time.Hour)
+	var locked *AreaLockedError
+	if !errors.As(err, &locked) || locked.Lock.CycleID != "c1" {
+		t.Fatalf("Expected backend to be locked by c1, got %v", err)
+	}
+
+	locks, err := store.ListAreaLocks()
+	if err != nil {
+		t.Fatalf("Failed to list area locks: %v", err)
+	}
+	if len(locks) != 2 || locks[0].Area != "backend" || locks[1].Area != "db-schema" {
+		t.Errorf("Expected backend and db-schema locks, got %+v", locks)
+	}
+
+	// Released and expired locks free their areas
+	if err := store.ReleaseAreaLocks("c1"); err != nil {
+		t.Fatalf("Failed to release area locks: %v", err)
+	}
+	if err := store.AcquireAreaLocks([]string{"frontend"}, "t3", "c3", "host:3", -time.Minute); err != nil {
+		t.Fatalf("Failed to acquire area locks: %v", err)
+	}
+	if err := store.AcquireAreaLocks([]string{"frontend", "backend"}, "t2", "c2", "host:2", time.Hour); err != nil {
+		t.Fatalf("Expected released and expired areas to be free, got %v", err)
+	}
+}
+
+func TestArchiveAuditPayloads(t *testing.T) {
+	// Create temporary database
+	dir := t.TempDir()
+	store, err := NewStore(filepath.Join(dir, "baton.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Archived Task", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	large := strings.Repeat("x", 4096)
+	store.CreateAuditLog(&AuditLog{TaskID: task.ID, CycleID: "c1", Result: "success", Note: large,
+		Commands: json.RawMessage(`["go test ./..."]`)})
+	store.CreateAuditLog(&AuditLog{TaskID: task.ID, CycleID: "c2", Result: "success", Note: "small"})
+
+	result, err := store.ArchiveAuditPayloads(time.Now().Add(time.Minute), 1024, false)
+	if err != nil {
+		t.Fatalf("Failed to archive: %v", err)
+	}
+	if result.Archived != 1 {
+		t.Fatalf("Expected 1 archived payload, got %+v", result)
+	}
+
+	var note, archivePath string
+	store.db.QueryRow("SELECT note, archive_path FROM audit_logs WHERE cycle_id = 'c1'").Scan(&note, &archivePath)
+	if note != "" || archivePath == "" {
+		t.Errorf("Expected the payload to leave the database, got note %d bytes, path %q", len(note), archivePath)
+	}
+	if _, err := os.Stat(filepath.Join(dir, archivePath)); err != nil {
+		t.Errorf("Expected archive file: %v", err)
+	}
+
+	// Reads restore the payload
+	logs, err := store.GetAuditLogs(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get audit logs: %v", err)
+	}
+	for _, log := range logs {
+		if log.CycleID == "c1" && (log.Note != large || string(log.Commands) != `["go test ./..."]`) {
+			t.Errorf("Expected archived payload to be restored, got note %d bytes, commands %s", len(log.Note), log.Commands)
+		}
+	}
+	history, err := store.GetAuditHistory(task.ID)
+	if err != nil || len(history) != 2 || history[0].Note != large {
+		t.Errorf("Expected audit history to restore the payload, got %v", err)
+	}
+
+	// Archived rows are not archived again
+	if result, _ := store.ArchiveAuditPayloads(time.Now().Add(time.Minute), 1024, false); result.Archived != 0 {
+		t.Errorf("Expected nothing left to archive, got %+v", result)
+	}
+}
+
+func TestLLMSpendSince(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_spend.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Costly Task", State: Implementing, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	store.CreateAuditLog(&AuditLog{TaskID: task.ID, CycleID: "c1", Result: "success", PromptTokens: 1200, CostUSD: 0.25})
+	store.CreateAuditLog(&AuditLog{TaskID: task.ID, CycleID: "c2", Result: "failure", CostUSD: 0.5})
+
+	spent, err := store.LLMSpendSince(time.Now().Add(-time.Hour))
+	if err != nil || spent != 0.75 {
+		t.Errorf("Expected $0.75 spent, got %v, %v", spent, err)
+	}
+	if spent, _ := store.LLMSpendSince(time.Now().Add(time.Hour)); spent != 0 {
+		t.Errorf("Expected nothing spent after now, got %v", spent)
+	}
+
+	logs, _ := store.GetAuditLogs(task.ID)
+	for _, log := range logs {
+		if log.CycleID == "c1" && (log.PromptTokens != 1200 || log.CostUSD != 0.25) {
+			t.Errorf("Expected usage to round-trip, got %+v", log)
+		}
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_custom_fields.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	backend := &Task{Title: "Backend Task", State: ReadyForPlan, Priority: 5}
+	backend.SetCustomField("component", "backend")
+	backend.SetCustomField("story_points", 3.0)
+	backend.SetCustomField("billable", true)
+	plain := &Task{Title: "Plain Task", State: ReadyForPlan, Priority: 5}
+	for _, task := range []*Task{backend, plain} {
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+
+	for _, filter := range []map[string]interface{}{
+		{"component": "backend"},
+		{"story_points": 3.0},
+		{"billable": true, "component": "backend"},
+	} {
+		tasks, err := store.ListTasks(TaskFilters{CustomFields: filter})
+		if err != nil {
+			t.Fatalf("Failed to list tasks: %v", err)
+		}
+		if len(tasks) != 1 || tasks[0].ID != backend.ID {
+			t.Errorf("Expected only the backend task for %v, got %d tasks", filter, len(tasks))
+		}
+	}
+	if count, _ := store.GetTaskCount(TaskFilters{CustomFields: map[string]interface{}{"billable": false}}); count != 0 {
+		t.Errorf("Expected no task with billable false, got %d", count)
+	}
+
+	// Clearing a field removes it
+	backend.SetCustomField("story_points", nil)
+	if err := store.UpdateTask(backend); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	got, _ := store.GetTask(backend.ID)
+	if _, ok := got.CustomFieldMap()["story_points"]; ok || got.CustomFieldMap()["component"] != "backend" {
+		t.Errorf("Expected story_points cleared and component kept, got %s", got.CustomFields)
+	}
+	if got, _ := store.GetTask(plain.ID); string(got.CustomFields) != "{}" {
+		t.Errorf("Expected an empty object for a task without fields, got %q", got.CustomFields)
+	}
+}
+
+func TestRequirementKeys(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_requirement_keys.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	for _, key := range []string{"FR-1", "FR-4", "FR-7"} {
+		if err := store.CreateRequirement(&Requirement{Key: key, Title: key, Text: "see FR-4", Type: "functional"}); err != nil {
+			t.Fatalf("Failed to create requirement: %v", err)
+		}
+	}
+	if err := store.DeprecateRequirement("FR-1"); err != nil {
+		t.Fatalf("Failed to deprecate requirement: %v", err)
+	}
+
+	// The allocator continues after the highest key ever used
+	added := &Requirement{Title: "Added", Text: "Added", Type: "functional"}
+	if err := store.AllocateRequirement(added, ""); err != nil {
+		t.Fatalf("Failed to allocate requirement: %v", err)
+	}
+	if added.Key != "FR-8" {
+		t.Errorf("Expected FR-8, got %s", added.Key)
+	}
+
+	task := &Task{Title: "Implement FR-7", Description: "Covers FR-4 and FR-70", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.LinkTaskRequirement(task.ID, "FR-7"); err != nil {
+		t.Fatalf("Failed to link requirement: %v", err)
+	}
+
+	// The deprecated FR-1 is skipped and each vacated key is retired
+	renames, err := store.PlanRequirementRenumber("FR")
+	if err != nil {
+		t.Fatalf("Failed to plan renumbering: %v", err)
+	}
+	var moves []string
+	for _, rename := range renames {
+		moves = append(moves, rename.OldKey+">"+rename.NewKey)
+	}
+	if strings.Join(moves, " ") != "FR-4>FR-2 FR-7>FR-3 FR-8>FR-5" {
+		t.Errorf("Unexpected renames: %v", moves)
+	}
+	if rewritten, err := store.RenumberRequirements(renames); err != nil || rewritten != 1 {
+		t.Fatalf("Expected 1 task rewritten, got %d (%v)", rewritten, err)
+	}
+
+	got, _ := store.GetTask(task.ID)
+	if got.Title != "Implement FR-3" || got.Description != "Covers FR-2 and FR-70" {
+		t.Errorf("Unexpected task text after renumbering: %q, %q", got.Title, got.Description)
+	}
+	linked, _ := store.ListTaskRequirements(task.ID)
+	if len(linked) != 1 || linked[0].Key != "FR-3" {
+		t.Errorf("Expected the task linked to FR-3, got %v", linked)
+	}
+
+	// Deprecated and retired keys are rejected for new requirements
+	for _, key := range []string{"FR-1", "FR-4"} {
+		var keyErr *RequirementKeyError
+		err := store.CreateRequirement(&Requirement{Key: key, Title: key, Text: key, Type: "functional"})
+		if !errors.As(err, &keyErr) {
+			t.Errorf("Expected a RequirementKeyError for %s, got %v", key, err)
+		}
+	}
+	if next, _ := store.NextRequirementKey("FR"); next != "FR-9" {
+		t.Errorf("Expected FR-9 next, got %s", next)
+	}
+}
+
+func TestRequirementKeysPerProject(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_requirement_keys_per_project.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	for _, key := range []string{"FR-1", "FR-2", "FR-3"} {
+		if err := store.CreateRequirement(&Requirement{Key: key, Title: key, Text: key, Type: "functional"}); err != nil {
+			t.Fatalf("Failed to create requirement: %v", err)
+		}
+	}
+	if err := store.DeprecateRequirement("FR-1"); err != nil {
+		t.Fatalf("Failed to deprecate requirement: %v", err)
+	}
+	renames := []*RequirementRename{{RequirementID: mustRequirement(t, store, "FR-3").ID, OldKey: "FR-3", NewKey: "FR-4"}}
+	if _, err := store.RenumberRequirements(renames); err != nil {
+		t.Fatalf("Failed to renumber: %v", err)
+	}
+
+	if err := store.CreateProject(&Project{ID: "other"}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	if err := store.SetProject("other"); err != nil {
+		t.Fatalf("Failed to switch project: %v", err)
+	}
+
+	// The other project's keys, deprecated and retired ones too, are free here
+	if _, err := store.GetRequirement("FR-1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected FR-1 not to be found in other, got %v", err)
+	}
+	for _, key := range []string{"FR-1", "FR-3"} {
+		if err := store.CreateRequirement(&Requirement{Key: key, Title: "Other " + key, Text: key, Type: "functional"}); err != nil {
+			t.Errorf("Failed to create %s in a second project: %v", key, err)
+		}
+	}
+	if next, _ := store.NextRequirementKey("FR"); next != "FR-4" {
+		t.Errorf("Expected FR-4 next in other, got %s", next)
+	}
+	added := &Requirement{Title: "Added", Text: "Added", Type: "functional"}
+	if err := store.AllocateRequirement(added, ""); err != nil || added.Key != "FR-4" {
+		t.Errorf("Expected FR-4 allocated in other, got %s (%v)", added.Key, err)
+	}
+	if err := store.DeprecateRequirement("FR-2"); err == nil {
+		t.Error("Expected deprecating another project's requirement to fail")
+	}
+
+	if err := store.SetProject(DefaultProject); err != nil {
+		t.Fatalf("Failed to switch project: %v", err)
+	}
+	if got := mustRequirement(t, store, "FR-1"); got.Title != "FR-1" || got.Status != RequirementDeprecated {
+		t.Errorf("Expected the default project's FR-1 untouched, got %+v", got)
+	}
+	if next, _ := store.NextRequirementKey("FR"); next != "FR-5" {
+		t.Errorf("Expected FR-5 next in default, got %s", next)
+	}
+}
+
+func TestScopeRequirementKeysMigration(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_scope_requirement_keys.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	task := &Task{Title: "Linked", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	store.Close()
+
+	// Put the tables back the way they were when keys were unique everywhere
+	db, err := sql.Open("sqlite", dbFile)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+		DROP TABLE requirements;
+		DROP TABLE retired_requirement_keys;
+		CREATE TABLE requirements (
+			id TEXT PRIMARY KEY,
+			project_id TEXT NOT NULL DEFAULT 'default',
+			key TEXT UNIQUE NOT NULL,
+			title TEXT NOT NULL,
+			text TEXT NOT NULL,
+			type TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'active',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE retired_requirement_keys (
+			key TEXT PRIMARY KEY,
+			requirement_id TEXT NOT NULL,
+			replaced_by TEXT NOT NULL,
+			retired_at DATETIME NOT NULL
+		);
+		INSERT INTO requirements (id, key, title, text, type) VALUES ('r1', 'FR-2', 'Login', 'Login', 'functional');
+		INSERT INTO retired_requirement_keys (key, requirement_id, replaced_by, retired_at) VALUES ('FR-1', 'r1', 'FR-2', '2026-01-01 00:00:00');
+	`)
+	if err == nil {
+		_, err = db.Exec("INSERT INTO task_requirements (task_id, requirement_id) VALUES (?, 'r1')", task.ID)
+	}
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to set up the old schema: %v", err)
+	}
+
+	store, err = NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+
+	// Links and retired keys survive the rebuild
+	if linked, _ := store.ListTaskRequirements(task.ID); len(linked) != 1 || linked[0].Key != "FR-2" {
+		t.Errorf("Expected the task still linked to FR-2, got %v", linked)
+	}
+	var keyErr *RequirementKeyError
+	if err := store.CreateRequirement(&Requirement{Key: "FR-1", Title: "FR-1", Text: "FR-1", Type: "functional"}); !errors.As(err, &keyErr) {
+		t.Errorf("Expected the retired FR-1 to stay retired, got %v", err)
+	}
+	if err := store.UpdateRequirement(&Requirement{Key: "FR-2", Title: "Sign in", Text: "Sign in", Type: "functional"}); err != nil {
+		t.Fatalf("Failed to update requirement: %v", err)
+	}
+	if hits, _ := store.Search("Sign", SearchFilters{}); len(hits) != 1 {
+		t.Errorf("Expected the search index triggers to be back, got %d hits", len(hits))
+	}
+
+	if err := store.CreateProject(&Project{ID: "other"}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	if err := store.SetProject("other"); err != nil {
+		t.Fatalf("Failed to switch project: %v", err)
+	}
+	if err := store.CreateRequirement(&Requirement{Key: "FR-2", Title: "Other", Text: "Other", Type: "functional"}); err != nil {
+		t.Errorf("Failed to reuse FR-2 in another project after the upgrade: %v", err)
+	}
+}
+
+// mustRequirement looks up a requirement of the store's project by key
+func mustRequirement(t *testing.T, store *Store, key string) *Requirement {
+	t.Helper()
+	req, err := store.GetRequirement(key)
+	if err != nil {
+		t.Fatalf("Failed to get requirement %s: %v", key, err)
+	}
+	return req
+}
+
+func TestTaskRevisions(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_revisions.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Login", Description: "Add a form\nCheck the password", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	// Changes that leave the text alone record nothing
+	task.Priority = 7
+	if err := store.UpdateTaskBy(task, "web"); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	if revisions, _ := store.ListTaskRevisions(task.ID); len(revisions) != 0 {
+		t.Errorf("Expected no revisions, got %d", len(revisions))
+	}
+
+	// The first text change records the original text as well
+	task.Description = "Add a form\nCheck the password hash"
+	if err := store.UpdateTaskBy(task, "llm"); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	revisions, err := store.ListTaskRevisions(task.ID)
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("Expected 2 revisions, got %d (%v)", len(revisions), err)
+	}
+	if revisions[0].Actor != "" || revisions[1].Actor != "llm" || revisions[0].Description != "Add a form\nCheck the password" {
+		t.Errorf("Unexpected revisions: %+v, %+v", revisions[0], revisions[1])
+	}
+
+	reverted, err := store.RevertTask(task.ID, 1, "cli")
+	if err != nil {
+		t.Fatalf("Failed to revert task: %v", err)
+	}
+	if reverted.Description != "Add a form\nCheck the password" {
+		t.Errorf("Unexpected description after revert: %q", reverted.Description)
+	}
+	revisions, _ = store.ListTaskRevisions(task.ID)
+	if len(revisions) != 3 || revisions[2].RevertedFrom != 1 || revisions[2].Actor != "cli" {
+		t.Errorf("Expected revision 3 reverting to 1, got %+v", revisions[len(revisions)-1])
+	}
+	if _, err := store.RevertTask(task.ID, 1, "cli"); err == nil {
+		t.Error("Expected reverting to the current text to fail")
+	}
+
+	diff := DiffText("Add a form\nCheck the password", "Add a form\nCheck the password hash")
+	if diff != "  Add a form\n- Check the password\n+ Check the password hash\n" {
+		t.Errorf("Unexpected diff: %q", diff)
+	}
+}
+
+func TestTimestampsUTC(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_timestamps.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// Display in a zone other than UTC
+	defer func(previous *time.Location) { time.Local = previous }(time.Local)
+	time.Local = time.FixedZone("UTC+2", 2*60*60)
+
+	task := &Task{Title: "Stamped", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	var stored string
+	store.db.QueryRow("SELECT CAST(created_at AS TEXT) FROM tasks WHERE id = ?", task.ID).Scan(&stored)
+	if !strings.HasSuffix(stored, "+0000 UTC") {
+		t.Errorf("Expected created_at stored in UTC, got %q", stored)
+	}
+	got, _ := store.GetTask(task.ID)
+	if got.CreatedAt.Location() != time.Local || !got.CreatedAt.Equal(task.CreatedAt) {
+		t.Errorf("Expected created_at read back in the display zone, got %v", got.CreatedAt)
+	}
+
+	// Timestamps written in local time before UTC storage are converted once
+	store.db.Exec("UPDATE tasks SET created_at = '2026-03-01 14:30:00.5 +0200 EET', updated_at = '2026-03-01 14:30:00.5 +0200 EET' WHERE id = ?", task.ID)
+	store.db.Exec("PRAGMA user_version = 0")
+	if err := store.normalizeTimestamps(); err != nil {
+		t.Fatalf("Failed to normalize timestamps: %v", err)
+	}
+	var created, updated string
+	store.db.QueryRow("SELECT CAST(created_at AS TEXT), CAST(updated_at AS TEXT) FROM tasks WHERE id = ?", task.ID).Scan(&created, &updated)
+	if created != "2026-03-01 12:30:00.5 +0000 UTC" || updated != created {
+		t.Errorf("Unexpected normalized timestamps: %q, %q", created, updated)
+	}
+}
+
+func TestTaskAssessments(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_assessments.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	sure, unsure := 0.9, 0.3
+	var tasks []*Task
+	for i, confidence := range []*float64{&sure, &unsure, nil} {
+		task := &Task{Title: fmt.Sprintf("Task %d", i), State: ReadyForImplementation, Priority: 5}
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+		tasks = append(tasks, task)
+
+		err := store.SaveTaskAssessment(&TaskAssessment{
+			TaskID: task.ID, CycleID: fmt.Sprintf("c%d", i), Actor: "planner",
+			PrevState: Planning, NextState: ReadyForImplementation, Confidence: confidence,
+			OpenQuestions: []string{"Which login provider?"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to save assessment: %v", err)
+		}
+	}
+
+	if _, err := store.GetTaskAssessment("missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a task without an assessment, got %v", err)
+	}
+	assessment, err := store.GetTaskAssessment(tasks[1].ID)
+	if err != nil {
+		t.Fatalf("Failed to get assessment: %v", err)
+	}
+	if assessment.Confidence == nil || *assessment.Confidence != unsure || len(assessment.OpenQuestions) != 1 {
+		t.Errorf("Unexpected assessment: %+v", assessment)
+	}
+
+	// Only the rated transition below the threshold needs review
+	review, err := store.ListLowConfidenceAssessments(0.5)
+	if err != nil {
+		t.Fatalf("Failed to list assessments: %v", err)
+	}
+	if len(review) != 1 || review[0].TaskID != tasks[1].ID {
+		t.Fatalf("Expected only task 1 to need review, got %+v", review)
+	}
+
+	// A task that has moved on since is no longer waiting for review
+	if err := store.UpdateTaskState(tasks[1].ID, Implementing, "picked up"); err != nil {
+		t.Fatalf("Failed to update state: %v", err)
+	}
+	if review, _ := store.ListLowConfidenceAssessments(0.5); len(review) != 0 {
+		t.Errorf("Expected no tasks to need review, got %d", len(review))
+	}
+
+	// The audit entry keeps the assessment of its cycle
+	err = store.CreateAuditLog(&AuditLog{TaskID: tasks[0].ID, CycleID: "c0", Result: "success",
+		Confidence: &sure, OpenQuestions: json.RawMessage(`["Which login provider?"]`)})
+	if err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+	logs, err := store.GetAuditLogs(tasks[0].ID)
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("Failed to get audit logs: %v", err)
+	}
+	if logs[0].Confidence == nil || *logs[0].Confidence != sure || string(logs[0].OpenQuestions) != `["Which login provider?"]` {
+		t.Errorf("Unexpected audit assessment: %v %s", logs[0].Confidence, logs[0].OpenQuestions)
+	}
+}
+
+func TestMilestoneSignoffs(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_milestone_signoffs.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if signoffs, err := store.ListMilestoneSignoffs(); err != nil || len(signoffs) != 0 {
+		t.Fatalf("Expected no sign-offs, got %v (%v)", signoffs, err)
+	}
+
+	if err := store.SignOffMilestone(&MilestoneSignoff{Milestone: "MVP-1", SignedOffBy: "alice"}); err != nil {
+		t.Fatalf("Failed to sign off milestone: %v", err)
+	}
+	// Signing off again replaces the earlier sign-off
+	if err := store.SignOffMilestone(&MilestoneSignoff{Milestone: "MVP-1", SignedOffBy: "bob", Note: "risks accepted"}); err != nil {
+		t.Fatalf("Failed to sign off milestone again: %v", err)
+	}
+
+	signoffs, err := store.ListMilestoneSignoffs()
+	if err != nil {
+		t.Fatalf("Failed to list sign-offs: %v", err)
+	}
+	if len(signoffs) != 1 {
+		t.Fatalf("Expected 1 sign-off, got %d", len(signoffs))
+	}
+	if got := signoffs["MVP-1"]; got == nil || got.SignedOffBy != "bob" || got.Note != "risks accepted" || got.CreatedAt.IsZero() {
+		t.Errorf("Unexpected sign-off: %+v", got)
+	}
+}
+
+func TestArchiveTasks(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_archive_tasks.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	done := &Task{Title: "Finished Task", State: Done, Priority: 5}
+	open := &Task{Title: "Open Task", State: Implementing, Priority: 5}
+	for _, task := range []*Task{done, open} {
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+
+	if archived, err := store.ArchiveTasks(Done, done.UpdatedAt.Add(-time.Hour), false); err != nil || len(archived) != 0 {
+		t.Fatalf("Expected nothing updated before the cutoff, got %v (%v)", archived, err)
+	}
+
+	cutoff := time.Now().Add(time.Minute)
+	archived, err := store.ArchiveTasks(Done, cutoff, true)
+	if err != nil || len(archived) != 1 || archived[0].ID != done.ID {
+		t.Fatalf("Expected the dry run to list the finished task, got %v (%v)", archived, err)
+	}
+	if tasks, _ := store.ListTasks(TaskFilters{}); len(tasks) != 2 {
+		t.Fatalf("Expected the dry run to archive nothing, got %d listed tasks", len(tasks))
+	}
+
+	before, err := store.GetTask(done.ID)
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	if archived, err = store.ArchiveTasks(Done, cutoff, false); err != nil || len(archived) != 1 {
+		t.Fatalf("Failed to archive tasks: %v (%v)", archived, err)
+	}
+
+	tasks, err := store.ListTasks(TaskFilters{})
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != open.ID {
+		t.Errorf("Expected archived tasks to be left out, got %d tasks", len(tasks))
+	}
+	if count, _ := store.GetTaskCount(TaskFilters{}); count != 1 {
+		t.Errorf("Expected a count of 1 without archived tasks, got %d", count)
+	}
+	if tasks, _ := store.ListTasks(TaskFilters{IncludeArchived: true}); len(tasks) != 2 {
+		t.Errorf("Expected 2 tasks including archived ones, got %d", len(tasks))
+	}
+
+	retrieved, err := store.GetTask(done.ID)
+	if err != nil {
+		t.Fatalf("Failed to get archived task: %v", err)
+	}
+	if !retrieved.Archived {
+		t.Error("Expected the task to be archived")
+	}
+	if !retrieved.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Errorf("Expected archiving to keep updated_at %v, got %v", before.UpdatedAt, retrieved.UpdatedAt)
+	}
+
+	if n, err := store.UnarchiveTasks([]string{done.ID, open.ID}); err != nil || n != 1 {
+		t.Fatalf("Expected 1 task unarchived, got %d (%v)", n, err)
+	}
+	if tasks, _ := store.ListTasks(TaskFilters{ArchivedOnly: true}); len(tasks) != 0 {
+		t.Errorf("Expected no archived tasks after unarchiving, got %d", len(tasks))
+	}
+}
+
+func TestSubtaskHierarchy(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_subtasks.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	epic := &Task{Title: "Epic", State: ReadyForPlan, Priority: 5}
+	story := &Task{Title: "Story", State: ReadyForPlan, Priority: 5}
+	subtask := &Task{Title: "Subtask", State: ReadyForPlan, Priority: 5}
+	for _, task := range []*Task{epic, story, subtask} {
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+
+	if err := store.SetTaskParent(story.ID, epic.ID); err != nil {
+		t.Fatalf("Failed to set parent: %v", err)
+	}
+	if err := store.SetTaskParent(subtask.ID, story.ID); err != nil {
+		t.Fatalf("Failed to set parent: %v", err)
+	}
+	if err := store.SetTaskParent(epic.ID, subtask.ID); err == nil {
+		t.Error("Expected grouping a task under its own subtask to fail")
+	}
+	if err := store.SetTaskParent(epic.ID, epic.ID); err == nil {
+		t.Error("Expected grouping a task under itself to fail")
+	}
+	if err := store.SetTaskParent(story.ID, "missing"); err == nil {
+		t.Error("Expected an unknown parent to fail")
+	}
+
+	children, err := store.ListChildTasks(epic.ID)
+	if err != nil {
+		t.Fatalf("Failed to list subtasks: %v", err)
+	}
+	if len(children) != 1 || children[0].ID != story.ID {
+		t.Fatalf("Expected the story under the epic, got %d subtasks", len(children))
+	}
+
+	tasks, err := store.ListTasks(TaskFilters{})
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	tree := BuildTaskTree(tasks)
+	if len(tree) != 1 || tree[0].ID != epic.ID {
+		t.Fatalf("Expected the epic as the only root, got %d roots", len(tree))
+	}
+	if len(tree[0].Children) != 1 || len(tree[0].Children[0].Children) != 1 || tree[0].Children[0].Children[0].ID != subtask.ID {
+		t.Errorf("Expected epic > story > subtask, got %+v", tree[0])
+	}
+
+	if err := store.SetTaskParent(subtask.ID, ""); err != nil {
+		t.Fatalf("Failed to unset parent: %v", err)
+	}
+	if children, _ := store.ListChildTasks(story.ID); len(children) != 0 {
+		t.Errorf("Expected no subtasks after unsetting the parent, got %d", len(children))
+	}
+}
+
+func TestTaskNotes(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_notes.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Noted Task", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	first := &TaskNote{TaskID: task.ID, Author: "agent", Body: "Found a flaky test"}
+	second := &TaskNote{TaskID: task.ID, Author: "alice", Body: "Known issue"}
+	for _, note := range []*TaskNote{first, second} {
+		if err := store.AddTaskNote(note); err != nil {
+			t.Fatalf("Failed to add note: %v", err)
+		}
+	}
+
+	notes, err := store.ListTaskNotes(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to list notes: %v", err)
+	}
+	if len(notes) != 2 || notes[0].ID != first.ID || notes[1].Author != "alice" {
+		t.Fatalf("Expected both notes oldest first, got %+v", notes)
+	}
+
+	first.Body = "Found a flaky test in the parser"
+	if err := store.UpdateTaskNote(first); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	retrieved, err := store.GetTaskNote(first.ID)
+	if err != nil {
+		t.Fatalf("Failed to get note: %v", err)
+	}
+	if retrieved.Body != first.Body || retrieved.Author != "agent" {
+		t.Errorf("Unexpected note after update: %+v", retrieved)
+	}
+
+	if err := store.DeleteTaskNote(second.ID); err != nil {
+		t.Fatalf("Failed to delete note: %v", err)
+	}
+	if err := store.DeleteTaskNote(second.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows deleting a missing note, got %v", err)
+	}
+	if notes, _ := store.ListTaskNotes(task.ID); len(notes) != 1 {
+		t.Errorf("Expected 1 note after deleting, got %d", len(notes))
+	}
+}
+
+func TestBackupTo(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_backup_source.db"
+	backupFile := "test_backup_copy.db"
+	defer os.Remove(dbFile)
+	defer os.Remove(backupFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Backed Up Task", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	if err := store.BackupTo(backupFile); err != nil {
+		t.Fatalf("Failed to back up database: %v", err)
+	}
+	if err := CheckIntegrity(backupFile, ""); err != nil {
+		t.Fatalf("Expected the backup to pass the integrity check: %v", err)
+	}
+
+	copied, err := NewStore(backupFile)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer copied.Close()
+	if retrieved, err := copied.GetTask(task.ID); err != nil || retrieved.Title != task.Title {
+		t.Errorf("Expected the task in the backup, got %v (%v)", retrieved, err)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_encrypted.db"
+	backupFile := "test_encrypted_copy.db"
+	defer os.Remove(dbFile)
+	defer os.Remove(backupFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	task := &Task{Title: "Proprietary login flow", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.CreateRequirement(&Requirement{Key: "FR-1", Title: "Secret sauce", Text: "Secret sauce", Type: "functional"}); err != nil {
+		t.Fatalf("Failed to create requirement: %v", err)
+	}
+	store.Close()
+
+	// Opening the plaintext database with a key encrypts it in place
+	store, err = NewEncryptedStore(dbFile, "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to open encrypted store: %v", err)
+	}
+	if got, err := store.GetTask(task.ID); err != nil || got.Title != task.Title {
+		t.Errorf("Expected the task to survive encryption, got %v (%v)", got, err)
+	}
+	if got, err := store.GetRequirement("FR-1"); err != nil || got.Title != "Secret sauce" {
+		t.Errorf("Expected the requirement to survive encryption, got %v (%v)", got, err)
+	}
+	added := &Task{Title: "Added after encryption", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(added); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.BackupTo(backupFile); err != nil {
+		t.Fatalf("Failed to back up database: %v", err)
+	}
+	if _, err := store.ArchiveAuditPayloads(time.Now(), 0, true); err == nil {
+		t.Error("Expected archiving an encrypted database's audit payloads to be refused")
+	}
+	store.Close()
+
+	for _, path := range []string{dbFile, backupFile} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if strings.HasPrefix(string(data), plaintextHeader) || strings.Contains(string(data), "Secret sauce") {
+			t.Errorf("Expected %s to be encrypted", path)
+		}
+	}
+	if err := CheckIntegrity(backupFile, "correct horse"); err != nil {
+		t.Errorf("Expected the encrypted backup to pass the integrity check: %v", err)
+	}
+
+	if _, err := NewEncryptedStore(dbFile, "wrong"); err == nil {
+		t.Error("Expected a wrong key to be refused")
+	}
+	if _, err := NewStore(dbFile); err == nil {
+		t.Error("Expected the encrypted database not to open without a key")
+	}
+
+	store, err = NewEncryptedStore(dbFile, "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to reopen encrypted store: %v", err)
+	}
+	defer store.Close()
+	if tasks, _ := store.ListTasks(TaskFilters{}); len(tasks) != 2 {
+		t.Errorf("Expected 2 tasks after reopening, got %d", len(tasks))
+	}
+}
+
+func TestEncryptedStoreRefusesOtherKeys(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_encrypted_keys.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewEncryptedStore(dbFile, "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to create encrypted store: %v", err)
+	}
+	task := &Task{Title: "Proprietary login flow", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	store.Close()
+
+	before, err := os.ReadFile(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+
+	if store, err := NewStore(dbFile); err == nil {
+		store.Close()
+		t.Error("Expected NewStore to refuse an encrypted database")
+	}
+	if store, err := OpenStore(dbFile, ""); err == nil {
+		store.Close()
+		t.Error("Expected OpenStore without a key to refuse an encrypted database")
+	}
+	for _, key := range []string{"wrong", "correct horse "} {
+		if store, err := OpenStore(dbFile, key); err == nil {
+			store.Close()
+			t.Errorf("Expected the key %q to be refused", key)
+		}
+	}
+
+	// Failed opens leave the database as it was
+	after, err := os.ReadFile(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+	if string(after) != string(before) {
+		t.Error("Expected refused opens not to change the database file")
+	}
+	store, err = OpenStore(dbFile, "correct horse")
+	if err != nil {
+		t.Fatalf("Failed to reopen encrypted store: %v", err)
+	}
+	defer store.Close()
+	if got, err := store.GetTask(task.ID); err != nil || got.Title != task.Title {
+		t.Errorf("Expected the task after refused opens, got %v (%v)", got, err)
+	}
+}
+
+func TestExportImportAll(t *testing.T) {
+	// Create temporary databases
+	dbFile := "test_export_source.db"
+	importFile := "test_export_target.db"
+	defer os.Remove(dbFile)
+	defer os.Remove(importFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	parent := &Task{Title: "Exported Parent", State: Done, Priority: 5}
+	if err := store.CreateTask(parent); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	child := &Task{Title: "Exported Child", State: ReadyForPlan, Priority: 3, ParentID: parent.ID}
+	if err := store.CreateTask(child); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.CreateRequirement(&Requirement{Key: "REQ-1", Title: "Exported", Text: "Must export", Type: "functional"}); err != nil {
+		t.Fatalf("Failed to create requirement: %v", err)
+	}
+	if err := store.LinkTaskRequirement(child.ID, "REQ-1"); err != nil {
+		t.Fatalf("Failed to link requirement: %v", err)
+	}
+	for _, content := range []string{"v1", "v2"} {
+		if err := store.UpsertArtifact(&Artifact{TaskID: child.ID, Name: "plan", Content: content}); err != nil {
+			t.Fatalf("Failed to create artifact: %v", err)
+		}
+	}
+	if err := store.AddTaskNote(&TaskNote{TaskID: child.ID, Author: "alice", Body: "Exported note"}); err != nil {
+		t.Fatalf("Failed to add note: %v", err)
+	}
+
+	export, err := store.ExportAll()
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if len(export.Tasks) != 2 || len(export.Requirements) != 1 || len(export.TaskRequirements) != 1 ||
+		len(export.Artifacts) != 2 || len(export.Notes) != 1 {
+		t.Fatalf("Unexpected export contents: %+v", export)
+	}
+	if err := store.ImportAll(export); err == nil {
+		t.Error("Expected importing into a database with tasks to fail")
+	}
+
+	target, err := NewStore(importFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer target.Close()
+	if err := target.ImportAll(export); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	reexported, err := target.ExportAll()
+	if err != nil {
+		t.Fatalf("Failed to export imported database: %v", err)
+	}
+	before, _ := json.Marshal(export)
+	after, _ := json.Marshal(reexported)
+	if string(before) != string(after) {
+		t.Errorf("Expected the import to round-trip:\n%s\n%s", before, after)
+	}
+}
+
+func TestArtifactBlobs(t *testing.T) {
+	// Create temporary database
+	dir := t.TempDir()
+	store, err := NewStore(filepath.Join(dir, "baton.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	store.SetBlobThreshold(16)
+
+	task := &Task{Title: "Large Output", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	small := &Artifact{TaskID: task.ID, Name: "notes", Content: "short"}
+	large := &Artifact{TaskID: task.ID, Name: "diff", Content: strings.Repeat("+ added line\n", 10)}
+	for _, artifact := range []*Artifact{small, large} {
+		if err := store.UpsertArtifact(artifact); err != nil {
+			t.Fatalf("Failed to upsert artifact: %v", err)
+		}
+	}
+	if small.BlobSHA256 != "" || large.BlobSHA256 == "" {
+		t.Fatalf("Expected only the large artifact to spill, got %q and %q", small.BlobSHA256, large.BlobSHA256)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".baton", "blobs", large.BlobSHA256[:2], large.BlobSHA256)); err != nil {
+		t.Fatalf("Expected the blob file: %v", err)
+	}
+
+	retrieved, err := store.GetArtifact(task.ID, "diff", 0)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	if retrieved.Content != large.Content || retrieved.Size != int64(len(large.Content)) {
+		t.Errorf("Expected the spilled content back, got %d bytes (size %d)", len(retrieved.Content), retrieved.Size)
+	}
+
+	artifact, content, err := store.OpenArtifact(task.ID, "diff", 0)
+	if err != nil {
+		t.Fatalf("Failed to open artifact: %v", err)
+	}
+	defer content.Close()
+	if _, err := content.Seek(2, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek: %v", err)
+	}
+	data, err := io.ReadAll(content)
+	if err != nil || string(data) != large.Content[2:] || artifact.Content != "" {
+		t.Errorf("Expected to stream the content from offset 2, got %q (%v)", data, err)
+	}
+
+	if _, _, err := store.OpenArtifact(task.ID, "missing", 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows opening a missing artifact, got %v", err)
+	}
+}
+
+func TestListTaskPage(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_pages.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	for i := 0; i < 7; i++ {
+		task := &Task{Title: fmt.Sprintf("Task %d", i), State: ReadyForPlan, Priority: i % 3}
+		if i%2 == 0 {
+			task.State = Implementing
+		}
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+
+	for _, sort := range TaskSorts {
+		all, err := store.ListTasks(TaskFilters{Sort: sort})
+		if err != nil {
+			t.Fatalf("Failed to list tasks by %s: %v", sort, err)
+		}
+
+		var paged []*Task
+		filters := TaskFilters{Sort: sort, Limit: 3}
+		for {
+			page, err := store.ListTaskPage(filters)
+			if err != nil {
+				t.Fatalf("Failed to list a page by %s: %v", sort, err)
+			}
+			if page.Total != 7 {
+				t.Errorf("Expected a total of 7, got %d", page.Total)
+			}
+			paged = append(paged, page.Tasks...)
+			if page.NextCursor == "" {
+				break
+			}
+			filters.Cursor = page.NextCursor
+		}
+
+		if len(paged) != len(all) {
+			t.Fatalf("Expected %d tasks across pages sorted by %s, got %d", len(all), sort, len(paged))
+		}
+		for i := range all {
+			if paged[i].ID != all[i].ID {
+				t.Errorf("Pages sorted by %s differ from the full list at %d", sort, i)
+			}
+		}
+	}
+
+	byState, _ := store.ListTasks(TaskFilters{Sort: SortByState})
+	if byState[0].State != ReadyForPlan || byState[len(byState)-1].State != Implementing {
+		t.Errorf("Expected tasks in workflow order, got %s first and %s last", byState[0].State, byState[len(byState)-1].State)
+	}
+
+	offset, err := store.ListTasks(TaskFilters{Limit: 2, Offset: 6})
+	if err != nil || len(offset) != 1 {
+		t.Errorf("Expected 1 task past offset 6, got %d (%v)", len(offset), err)
+	}
+
+	page, _ := store.ListTaskPage(TaskFilters{Limit: 2})
+	if _, err := store.ListTaskPage(TaskFilters{Sort: SortByState, Limit: 2, Cursor: page.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for a cursor of another sort, got %v", err)
+	}
+	if _, err := store.ListTasks(TaskFilters{Sort: "title"}); err == nil {
+		t.Error("Expected an unknown sort to fail")
+	}
+}
+
+func TestTagFilters(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_tag_filters.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	tasks := []*Task{
+		{Title: "Backend API", Tags: json.RawMessage(`["backend","api"]`)},
+		{Title: "Backend DB", Tags: json.RawMessage(`["backend","db-schema"]`)},
+		{Title: "Frontend", Tags: json.RawMessage(`["frontend"]`)},
+		{Title: "Untagged"},
+	}
+	for _, task := range tasks {
+		task.State = ReadyForPlan
+		task.Priority = 5
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+
+	backend, err := store.ListTasks(TaskFilters{Tags: []string{"backend"}})
+	if err != nil {
+		t.Fatalf("Failed to list tasks by tag: %v", err)
+	}
+	if len(backend) != 2 {
+		t.Errorf("Expected 2 backend tasks, got %d", len(backend))
+	}
+
+	both, _ := store.ListTasks(TaskFilters{Tags: []string{"backend", "api"}})
+	if len(both) != 1 || both[0].Title != "Backend API" {
+		t.Errorf("Expected only the task with both tags, got %d", len(both))
+	}
+
+	if count, err := store.GetTaskCount(TaskFilters{Tags: []string{"backend"}}); err != nil || count != 2 {
+		t.Errorf("Expected a count of 2 backend tasks, got %d (%v)", count, err)
+	}
+	if none, _ := store.ListTasks(TaskFilters{Tags: []string{"back"}}); len(none) != 0 {
+		t.Errorf("Expected tags to match whole, got %d tasks", len(none))
+	}
+}
+
+func TestTaskBatches(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_batches.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	tasks := []*Task{
+		{Title: "First", State: ReadyForPlan, Priority: 5},
+		{Title: "Second", State: ReadyForPlan, Priority: 5},
+	}
+	if err := store.CreateTasksBatch(tasks); err != nil {
+		t.Fatalf("Failed to create tasks: %v", err)
+	}
+	if count, _ := store.GetTaskCount(TaskFilters{}); count != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", count)
+	}
+
+	// A failing task rolls back the whole batch
+	duplicate := []*Task{{Title: "Third", State: ReadyForPlan}, {ID: tasks[0].ID, Title: "Clash", State: ReadyForPlan}}
+	if err := store.CreateTasksBatch(duplicate); err == nil {
+		t.Error("Expected a duplicate ID to fail the batch")
+	}
+	if count, _ := store.GetTaskCount(TaskFilters{}); count != 2 {
+		t.Errorf("Expected the failed batch to create nothing, got %d tasks", count)
+	}
+
+	var events []TaskEvent
+	store.Subscribe(func(event TaskEvent) {
+		events = append(events, event)
+	})
+
+	tasks[0].State = Planning
+	tasks[1].Title = "Second, renamed"
+	if err := store.UpdateTasksBatch(tasks, "tester", "bulk"); err != nil {
+		t.Fatalf("Failed to update tasks: %v", err)
+	}
+	if first, _ := store.GetTask(tasks[0].ID); first.State != Planning {
+		t.Errorf("Expected the first task to be planning, got %s", first.State)
+	}
+	if revisions, _ := store.ListTaskRevisions(tasks[1].ID); len(revisions) != 2 || revisions[1].Actor != "tester" {
+		t.Errorf("Expected the rename to be recorded as a revision by tester, got %+v", revisions)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected a transition and an update event, got %+v", events)
+	}
+	if events[0].Kind != EventTransition || events[0].TaskID != tasks[0].ID || events[0].NextState != Planning || events[0].Note != "bulk" {
+		t.Errorf("Unexpected transition event: %+v", events[0])
+	}
+	if events[1].Kind != EventUpdated || events[1].TaskID != tasks[1].ID {
+		t.Errorf("Unexpected update event: %+v", events[1])
+	}
+
+	// An unknown task rolls back the whole batch
+	tasks[0].State = ReadyForImplementation
+	missing := &Task{ID: "missing", Title: "Missing", State: ReadyForPlan}
+	if err := store.UpdateTasksBatch([]*Task{tasks[0], missing}, "tester", ""); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+	if first, _ := store.GetTask(tasks[0].ID); first.State != Planning {
+		t.Errorf("Expected the failed batch to leave the first task planning, got %s", first.State)
+	}
+}
+
+func TestTaskTrash(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_trash.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	parent := &Task{Title: "Epic", State: ReadyForPlan, Priority: 5}
+	child := &Task{Title: "Story", State: ReadyForPlan, Priority: 5}
+	for _, task := range []*Task{parent, child} {
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+	if err := store.SetTaskParent(child.ID, parent.ID); err != nil {
+		t.Fatalf("Failed to set parent: %v", err)
+	}
+	artifact := &Artifact{TaskID: child.ID, Name: "implementation_plan", Content: "searchable plan"}
+	if err := store.UpsertArtifact(artifact); err != nil {
+		t.Fatalf("Failed to create artifact: %v", err)
+	}
+
+	if err := store.DeleteTask(parent.ID); err == nil {
+		t.Error("Expected deleting a task with subtasks to fail")
+	}
+	if err := store.DeleteTask(child.ID); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if err := store.DeleteTask(child.ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected deleting a deleted task to give ErrTaskNotFound, got %v", err)
+	}
+
+	if _, err := store.GetTask(child.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected a deleted task to be hidden, got %v", err)
+	}
+	if count, _ := store.GetTaskCount(TaskFilters{}); count != 1 {
+		t.Errorf("Expected 1 task outside the trash, got %d", count)
+	}
+	if hits, _ := store.Search("searchable", SearchFilters{}); len(hits) != 0 {
+		t.Errorf("Expected a deleted task's artifacts to drop out of search, got %d hits", len(hits))
+	}
+	trash, err := store.ListDeletedTasks()
+	if err != nil || len(trash) != 1 || trash[0].ID != child.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("Expected the task in the trash, got %v (%v)", trash, err)
+	}
+
+	if err := store.RestoreTask(child.ID); err != nil {
+		t.Fatalf("Failed to restore task: %v", err)
+	}
+	restored, err := store.GetTask(child.ID)
+	if err != nil {
+		t.Fatalf("Failed to get restored task: %v", err)
+	}
+	if restored.DeletedAt != nil || !restored.UpdatedAt.Equal(trash[0].UpdatedAt) {
+		t.Errorf("Expected deleting and restoring to leave updated_at alone")
+	}
+
+	// Purging only removes tasks in the trash
+	if n, err := store.PurgeTasks([]string{child.ID}); err != nil || n != 0 {
+		t.Errorf("Expected a task outside the trash not to be purged, got %d (%v)", n, err)
+	}
+	store.DeleteTask(child.ID)
+	if n, err := store.PurgeTasks([]string{child.ID}); err != nil || n != 1 {
+		t.Fatalf("Expected 1 task purged, got %d (%v)", n, err)
+	}
+	if artifacts, _ := store.ListArtifacts(child.ID); len(artifacts) != 0 {
+		t.Errorf("Expected the purged task's artifacts to be removed, got %d", len(artifacts))
+	}
+	if trash, _ := store.ListDeletedTasks(); len(trash) != 0 {
+		t.Errorf("Expected an empty trash, got %d tasks", len(trash))
+	}
+}
+
+func TestTaskTrashPerProject(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_trash_per_project.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.CreateProject(&Project{ID: "other"}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	if err := store.SetProject("other"); err != nil {
+		t.Fatalf("Failed to switch project: %v", err)
+	}
+	live := &Task{Title: "Live Task", State: ReadyForPlan, Priority: 5}
+	trashed := &Task{Title: "Trashed Task", State: ReadyForPlan, Priority: 5}
+	done := &Task{Title: "Finished Task", State: Done, Priority: 5}
+	for _, task := range []*Task{live, trashed, done} {
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+	if err := store.DeleteTask(trashed.ID); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if archived, err := store.ArchiveTasks(Done, time.Now().Add(time.Minute), false); err != nil || len(archived) != 1 {
+		t.Fatalf("Expected the finished task archived, got %v (%v)", archived, err)
+	}
+
+	// None of the other project's tasks can be reached from the default one
+	if err := store.SetProject(DefaultProject); err != nil {
+		t.Fatalf("Failed to switch project: %v", err)
+	}
+	if err := store.DeleteTask(live.ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected deleting another project's task to give ErrTaskNotFound, got %v", err)
+	}
+	if err := store.RestoreTask(trashed.ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected restoring another project's task to give ErrTaskNotFound, got %v", err)
+	}
+	if n, err := store.PurgeTasks([]string{trashed.ID}); err != nil || n != 0 {
+		t.Errorf("Expected another project's task not to be purged, got %d (%v)", n, err)
+	}
+	if n, err := store.UnarchiveTasks([]string{done.ID}); err != nil || n != 0 {
+		t.Errorf("Expected another project's task not to be unarchived, got %d (%v)", n, err)
+	}
+
+	if err := store.SetProject("other"); err != nil {
+		t.Fatalf("Failed to switch project: %v", err)
+	}
+	if _, err := store.GetTask(live.ID); err != nil {
+		t.Errorf("Expected the live task untouched: %v", err)
+	}
+	if trash, _ := store.ListDeletedTasks(); len(trash) != 1 || trash[0].ID != trashed.ID {
+		t.Errorf("Expected the trashed task still in the trash, got %v", trash)
+	}
+	if archived, _ := store.ListTasks(TaskFilters{ArchivedOnly: true}); len(archived) != 1 || archived[0].ID != done.ID {
+		t.Errorf("Expected the finished task still archived, got %v", archived)
+	}
+}
+
+func TestRequirementLinks(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_requirement_links.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	for _, key := range []string{"FR-1", "FR-2", "FR-3"} {
+		if err := store.CreateRequirement(&Requirement{Key: key, Title: key, Text: key, Type: "functional"}); err != nil {
+			t.Fatalf("Failed to create requirement: %v", err)
+		}
+	}
+
+	login := &Task{ID: "task-login", Title: "Login", State: ReadyForPlan, Priority: 5}
+	logout := &Task{ID: "task-logout", Title: "Logout", State: ReadyForPlan, Priority: 5}
+	chore := &Task{ID: "task-chore", Title: "Chore", State: ReadyForPlan, Priority: 5}
+
+	// An unknown key fails the whole batch
+	bad := &Task{ID: "task-bad", Title: "Bad", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTasksWithRequirements([]*Task{bad}, map[string][]string{bad.ID: {"FR-9"}}); err == nil {
+		t.Error("Expected linking an unknown requirement to fail")
+	}
+	if _, err := store.GetTask(bad.ID); err == nil {
+		t.Error("Expected no task to be created when a link fails")
+	}
+
+	links := map[string][]string{login.ID: {"FR-1", "FR-2"}, logout.ID: {"FR-1"}}
+	if err := store.CreateTasksWithRequirements([]*Task{login, logout, chore}, links); err != nil {
+		t.Fatalf("Failed to create tasks: %v", err)
+	}
+
+	if reqs, err := store.ListTaskRequirements(login.ID); err != nil || len(reqs) != 2 {
+		t.Errorf("Expected 2 requirements linked to the task, got %d (%v)", len(reqs), err)
+	}
+	tasks, err := store.ListRequirementTasks("FR-1")
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("Expected 2 tasks linked to FR-1, got %d (%v)", len(tasks), err)
+	}
+
+	// Deleted tasks and deprecated requirements drop out of coverage
+	if err := store.DeleteTask(login.ID); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if err := store.DeprecateRequirement("FR-3"); err != nil {
+		t.Fatalf("Failed to deprecate requirement: %v", err)
+	}
+	coverage, err := store.GetLinkCoverage()
+	if err != nil {
+		t.Fatalf("Failed to get coverage: %v", err)
+	}
+	if len(coverage.Requirements) != 2 {
+		t.Fatalf("Expected 2 active requirements, got %d", len(coverage.Requirements))
+	}
+	if n := len(coverage.Requirements[0].Tasks); n != 1 {
+		t.Errorf("Expected 1 task linked to FR-1, got %d", n)
+	}
+	if uncovered := coverage.Uncovered(); len(uncovered) != 1 || uncovered[0].Key != "FR-2" {
+		t.Errorf("Expected FR-2 to be uncovered, got %v", uncovered)
+	}
+	if len(coverage.UnlinkedTasks) != 1 || coverage.UnlinkedTasks[0].ID != chore.ID {
+		t.Errorf("Expected the chore to be unlinked, got %v", coverage.UnlinkedTasks)
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_event_bus.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	var first, second []string
+	store.Subscribe(func(event TaskEvent) { first = append(first, event.Kind) })
+	unsubscribe := store.Subscribe(func(event TaskEvent) { second = append(second, event.Kind) })
+
+	task := &Task{Title: "Bus", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	task.Priority = 8
+	if err := store.UpdateTask(task); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	task.State = Planning
+	if err := store.UpdateTask(task); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+
+	unsubscribe()
+	if err := store.DeleteTask(task.ID); err != nil {
+		t.Fatalf("Failed to delete task: %v", err)
+	}
+	if err := store.RestoreTask(task.ID); err != nil {
+		t.Fatalf("Failed to restore task: %v", err)
+	}
+
+	want := []string{EventCreated, EventUpdated, EventTransition, EventDeleted, EventRestored}
+	if strings.Join(first, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected events %v, got %v", want, first)
+	}
+	if strings.Join(second, ",") != strings.Join(want[:3], ",") {
+		t.Errorf("Expected the unsubscribed listener to stop after %v, got %v", want[:3], second)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_projects.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if store.Project() != DefaultProject {
+		t.Errorf("Expected a new store to work in %s, got %s", DefaultProject, store.Project())
+	}
+	if err := store.CreateProject(&Project{ID: "bad name"}); err == nil {
+		t.Error("Expected an invalid project name to be refused")
+	}
+	if err := store.SetProject("web"); err == nil {
+		t.Error("Expected switching to an unknown project to fail")
+	}
+
+	home := &Task{Title: "Default task", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(home); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.CreateRequirement(&Requirement{Key: "FR-1", Title: "Default", Text: "Default", Type: "functional"}); err != nil {
+		t.Fatalf("Failed to create requirement: %v", err)
+	}
+
+	if err := store.CreateProject(&Project{ID: "web", Description: "Web app"}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	if err := store.SetProject("web"); err != nil {
+		t.Fatalf("Failed to switch project: %v", err)
+	}
+	away := &Task{Title: "Web task", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(away); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if away.ProjectID != "web" {
+		t.Errorf("Expected the task to be created in web, got %q", away.ProjectID)
+	}
+
+	// Each project sees only its own tasks and requirements
+	if _, err := store.GetTask(home.ID); err == nil {
+		t.Error("Expected another project's task to be hidden")
+	}
+	if tasks, _ := store.ListTasks(TaskFilters{}); len(tasks) != 1 || tasks[0].ID != away.ID {
+		t.Errorf("Expected only the web task, got %v", tasks)
+	}
+	if count, _ := store.GetTaskCount(TaskFilters{}); count != 1 {
+		t.Errorf("Expected a count of 1, got %d", count)
+	}
+	if reqs, _ := store.ListRequirements(""); len(reqs) != 0 {
+		t.Errorf("Expected no requirements in web, got %d", len(reqs))
+	}
+	if hits, _ := store.Search("task", SearchFilters{}); len(hits) != 1 || hits[0].TaskID != away.ID {
+		t.Errorf("Expected search to find only the web task, got %v", hits)
+	}
+	if tasks, _ := store.ListTasks(TaskFilters{AllProjects: true}); len(tasks) != 2 {
+		t.Errorf("Expected 2 tasks across projects, got %d", len(tasks))
+	}
+
+	projects, err := store.ListProjects()
+	if err != nil || len(projects) != 2 {
+		t.Fatalf("Expected 2 projects, got %d (%v)", len(projects), err)
+	}
+	if projects[0].ID != DefaultProject || projects[0].Tasks != 1 || projects[1].ID != "web" || projects[1].Tasks != 1 {
+		t.Errorf("Unexpected projects: %+v, %+v", projects[0], projects[1])
+	}
+
+	// An export covers every project
+	export, err := store.ExportAll()
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if len(export.Projects) != 2 || len(export.Tasks) != 2 || len(export.Requirements) != 1 {
+		t.Errorf("Expected the export to cover both projects, got %d projects, %d tasks, %d requirements",
+			len(export.Projects), len(export.Tasks), len(export.Requirements))
+	}
+}
+
+func TestTaskHolds(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_holds.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Waiting task", State: Implementing, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if _, err := store.ResumeTask(task.ID, ""); err == nil {
+		t.Error("Expected resuming a task that is not held to fail")
+	}
+
+	// Going on hold remembers the state left, moving between holds keeps it
+	if err := store.UpdateTaskState(task.ID, Blocked, "waiting on keys"); err != nil {
+		t.Fatalf("Failed to block task: %v", err)
+	}
+	if got, _ := store.GetTask(task.ID); got.HeldFrom != Implementing {
+		t.Errorf("Expected the task to be held from %s, got %q", Implementing, got.HeldFrom)
+	}
+	got, _ := store.GetTask(task.ID)
+	got.State = Paused
+	if err := store.UpdateTask(got); err != nil {
+		t.Fatalf("Failed to pause task: %v", err)
+	}
+	if got, _ := store.GetTask(task.ID); got.State != Paused || got.HeldFrom != Implementing {
+		t.Errorf("Expected a paused task held from %s, got %s held from %q", Implementing, got.State, got.HeldFrom)
+	}
+
+	// Resuming returns the task to that state and forgets it
+	state, err := store.ResumeTask(task.ID, "keys arrived")
+	if err != nil {
+		t.Fatalf("Failed to resume task: %v", err)
+	}
+	if state != Implementing {
+		t.Errorf("Expected to resume to %s, got %s", Implementing, state)
+	}
+	if got, _ := store.GetTask(task.ID); got.State != Implementing || got.HeldFrom != "" {
+		t.Errorf("Expected an implementing task held from nothing, got %s held from %q", got.State, got.HeldFrom)
+	}
+}
+
+func TestStateHistory(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_state_history.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Tracked task", State: ReadyForPlan, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.UpdateTaskState(task.ID, Planning, ""); err != nil {
+		t.Fatalf("Failed to update state: %v", err)
+	}
+	// An update that keeps the state adds no entry
+	got, _ := store.GetTask(task.ID)
+	got.Title = "Tracked task, renamed"
+	if err := store.UpdateTask(got); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	got.State = ReadyForImplementation
+	if err := store.UpdateTask(got); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+
+	entries, err := store.GetStateHistory(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get state history: %v", err)
+	}
+	var states []State
+	for _, entry := range entries {
+		states = append(states, entry.State)
+	}
+	if len(states) != 3 || states[0] != ReadyForPlan || states[1] != Planning || states[2] != ReadyForImplementation {
+		t.Fatalf("Expected ready_for_plan, planning, ready_for_implementation, got %v", states)
+	}
+
+	// The current state counts up to now; earlier ones end when the next began
+	now := entries[2].EnteredAt.Add(2 * time.Hour)
+	timeInState, err := store.GetTimeInState(task.ID, now)
+	if err != nil {
+		t.Fatalf("Failed to get time in state: %v", err)
+	}
+	current := timeInState.Current()
+	if current == nil || current.State != ReadyForImplementation || current.LeftAt != nil || current.Seconds != 7200 {
+		t.Errorf("Expected the current span to be 2h of ready_for_implementation, got %+v", current)
+	}
+	if first := timeInState.Spans[0]; first.LeftAt == nil || !first.LeftAt.Equal(entries[1].EnteredAt) {
+		t.Errorf("Expected the first span to end when planning began, got %+v", first)
+	}
+
+	entered, err := store.CurrentStateEntries()
+	if err != nil {
+		t.Fatalf("Failed to get current state entries: %v", err)
+	}
+	if !entered[task.ID].Equal(entries[2].EnteredAt) {
+		t.Errorf("Expected the task to have entered its state at %v, got %v", entries[2].EnteredAt, entered[task.ID])
+	}
+
+	// History travels with an export
+	export, err := store.ExportAll()
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if len(export.StateHistory) != 3 {
+		t.Errorf("Expected 3 state entries in the export, got %d", len(export.StateHistory))
+	}
+}
+
+func TestCancelledTasks(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_cancelled.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if state := NormalizeState("canceled"); state != Cancelled {
+		t.Errorf("Expected canceled to normalize to %s, got %s", Cancelled, state)
+	}
+
+	// Cancelling a held task ends the hold for good
+	task := &Task{Title: "Unwanted task", State: Planning, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.UpdateTaskState(task.ID, Blocked, "waiting"); err != nil {
+		t.Fatalf("Failed to block task: %v", err)
+	}
+	if err := store.UpdateTaskState(task.ID, Cancelled, "no longer needed"); err != nil {
+		t.Fatalf("Failed to cancel task: %v", err)
+	}
+	if got, _ := store.GetTask(task.ID); got.State != Cancelled || got.HeldFrom != "" {
+		t.Errorf("Expected a cancelled task held from nothing, got %s held from %q", got.State, got.HeldFrom)
+	}
+	if _, err := store.ResumeTask(task.ID, ""); err == nil {
+		t.Error("Expected resuming a cancelled task to fail")
+	}
+
+	state := Cancelled
+	if count, err := store.GetTaskCount(TaskFilters{State: &state}); err != nil || count != 1 {
+		t.Errorf("Expected 1 cancelled task, got %d (%v)", count, err)
+	}
+}
+
+func TestRollbackTask(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_rollback.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Reviewed task", State: Reviewing, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if _, err := store.PlanRollback(task.ID); err == nil {
+		t.Error("Expected a task without recorded transitions to have nothing to roll back")
+	}
+	if err := store.UpsertArtifact(&Artifact{TaskID: task.ID, Name: "review_notes", Content: "looks risky"}); err != nil {
+		t.Fatalf("Failed to create artifact: %v", err)
+	}
+
+	// A cycle wrongly approves the task, rewriting one artifact and adding another
+	time.Sleep(10 * time.Millisecond)
+	cycleStart := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	for _, artifact := range []*Artifact{
+		{TaskID: task.ID, Name: "review_notes", Content: "all good"},
+		{TaskID: task.ID, Name: "change_summary", Content: "ship it"},
+	} {
+		if err := store.UpsertArtifact(artifact); err != nil {
+			t.Fatalf("Failed to create artifact: %v", err)
+		}
+	}
+	if err := store.UpdateTaskState(task.ID, ReadyForCommit, ""); err != nil {
+		t.Fatalf("Failed to update task state: %v", err)
+	}
+	cycle := &AuditLog{TaskID: task.ID, CycleID: "cycle-1", PrevState: string(Reviewing), NextState: string(ReadyForCommit),
+		Actor: "reviewer", Result: "success", DurationSeconds: time.Since(cycleStart).Seconds()}
+	if err := store.CreateAuditLog(cycle); err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+
+	rollback, err := store.RollbackTask(task.ID, "tester", "approved by mistake")
+	if err != nil {
+		t.Fatalf("Failed to roll back task: %v", err)
+	}
+	if rollback.To != Reviewing || rollback.Undone.ID != cycle.ID {
+		t.Errorf("Expected to undo cycle %s back to %s, got %s back to %s", cycle.ID, Reviewing, rollback.Undone.ID, rollback.To)
+	}
+	if got, _ := store.GetTask(task.ID); got.State != Reviewing {
+		t.Errorf("Expected the task to be %s again, got %s", Reviewing, got.State)
+	}
+
+	// The earlier content comes back as a new version; a new artifact is kept
+	notes, err := store.GetArtifact(task.ID, "review_notes", 0)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	if notes.Content != "looks risky" || notes.Version != 3 {
+		t.Errorf("Expected review_notes v3 with the v1 content, got v%d %q", notes.Version, notes.Content)
+	}
+	if len(rollback.Kept) != 1 || rollback.Kept[0] != "change_summary" {
+		t.Errorf("Expected change_summary to be kept, got %v", rollback.Kept)
+	}
+
+	logs, err := store.GetAuditLogs(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get audit logs: %v", err)
+	}
+	if len(logs) != 2 || logs[0].CycleID != RollbackCycleID || logs[0].NextState != string(Reviewing) || logs[0].Actor != "tester" {
+		t.Errorf("Expected a compensating rollback entry, got %+v", logs[0])
+	}
+
+	// The undone transition is not rolled back twice
+	if _, err := store.PlanRollback(task.ID); err == nil {
+		t.Error("Expected no further transition to roll back")
+	}
+}
+
+func TestRollbackTaskIsAtomic(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_rollback_atomic.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Reviewed task", State: Reviewing, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if err := store.UpsertArtifact(&Artifact{TaskID: task.ID, Name: "review_notes", Content: "looks risky"}); err != nil {
+		t.Fatalf("Failed to create artifact: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	cycleStart := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	if err := store.UpsertArtifact(&Artifact{TaskID: task.ID, Name: "review_notes", Content: "all good"}); err != nil {
+		t.Fatalf("Failed to create artifact: %v", err)
+	}
+	if err := store.UpdateTaskState(task.ID, ReadyForCommit, ""); err != nil {
+		t.Fatalf("Failed to update task state: %v", err)
+	}
+	cycle := &AuditLog{TaskID: task.ID, CycleID: "cycle-1", PrevState: string(Reviewing), NextState: string(ReadyForCommit),
+		Actor: "reviewer", Result: "success", DurationSeconds: time.Since(cycleStart).Seconds()}
+	if err := store.CreateAuditLog(cycle); err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+
+	var events []TaskEvent
+	store.Subscribe(func(event TaskEvent) { events = append(events, event) })
+
+	// The audit entry, written last, fails after the state and artifact changes
+	if _, err := store.db.Exec(`CREATE TRIGGER fail_rollback BEFORE INSERT ON audit_logs
+		WHEN NEW.cycle_id = 'rollback' BEGIN SELECT RAISE(ABORT, 'forced failure'); END`); err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+	if _, err := store.RollbackTask(task.ID, "tester", ""); err == nil {
+		t.Fatal("Expected the rollback to fail")
+	}
+
+	if got, _ := store.GetTask(task.ID); got.State != ReadyForCommit {
+		t.Errorf("Expected the task to stay %s, got %s", ReadyForCommit, got.State)
+	}
+	notes, err := store.GetArtifact(task.ID, "review_notes", 0)
+	if err != nil {
+		t.Fatalf("Failed to get artifact: %v", err)
+	}
+	if notes.Version != 2 || notes.Content != "all good" {
+		t.Errorf("Expected review_notes to stay at v2, got v%d %q", notes.Version, notes.Content)
+	}
+	var last State
+	if err := store.db.QueryRow("SELECT state FROM state_history WHERE task_id = ? ORDER BY rowid DESC LIMIT 1", task.ID).Scan(&last); err != nil {
+		t.Fatalf("Failed to get state history: %v", err)
+	}
+	if last != ReadyForCommit {
+		t.Errorf("Expected no state history entry for the failed rollback, got %s", last)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no events for a failed rollback, got %d", len(events))
+	}
+
+	// Once the failure is gone the same rollback goes through
+	if _, err := store.db.Exec("DROP TRIGGER fail_rollback"); err != nil {
+		t.Fatalf("Failed to drop trigger: %v", err)
+	}
+	if _, err := store.RollbackTask(task.ID, "tester", ""); err != nil {
+		t.Fatalf("Failed to roll back task: %v", err)
+	}
+	if got, _ := store.GetTask(task.ID); got.State != Reviewing {
+		t.Errorf("Expected the task to be %s, got %s", Reviewing, got.State)
+	}
+}
+
+func TestEscalateTask(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_escalate.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	var escalations []TaskEvent
+	store.Subscribe(func(event TaskEvent) {
+		if event.Kind == EventEscalated {
+			escalations = append(escalations, event)
+		}
+	})
+
+	// Every move into needs_fixes counts as a failed review loop
+	task := &Task{Title: "Flaky task", State: Reviewing, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	for _, state := range []State{NeedsFixes, Fixing, NeedsFixes, Fixing, ReadyForCodeReview, Reviewing, NeedsFixes} {
+		if err := store.UpdateTaskState(task.ID, state, ""); err != nil {
+			t.Fatalf("Failed to move task to %s: %v", state, err)
+		}
+	}
+	if got, _ := store.GetTask(task.ID); got.ReviewFailures != 3 {
+		t.Errorf("Expected 3 review failures, got %d", got.ReviewFailures)
+	}
+
+	if err := store.EscalateTask(task.ID, Done, "baton"); err == nil {
+		t.Error("Expected escalating to a state other than blocked or paused to fail")
+	}
+	if err := store.EscalateTask(task.ID, Blocked, "baton"); err != nil {
+		t.Fatalf("Failed to escalate task: %v", err)
+	}
+
+	got, _ := store.GetTask(task.ID)
+	if got.State != Blocked || got.HeldFrom != NeedsFixes || got.ReviewFailures != 0 {
+		t.Errorf("Expected a blocked task held from %s with no failures, got %s held from %q with %d",
+			NeedsFixes, got.State, got.HeldFrom, got.ReviewFailures)
+	}
+	if len(escalations) != 1 || escalations[0].NextState != Blocked {
+		t.Errorf("Expected one escalation event, got %+v", escalations)
+	}
+
+	logs, err := store.GetAuditLogs(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get audit logs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].CycleID != EscalationCycleID || logs[0].Note != "escalated after 3 failed review loops" {
+		t.Errorf("Expected an escalation audit entry, got %+v", logs)
+	}
+
+	if err := store.EscalateTask(task.ID, Paused, "baton"); err == nil {
+		t.Error("Expected escalating a held task to fail")
+	}
+}
+
+func TestTaskDueDates(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_due_dates.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	due, err := ParseDueDate("2026-03-01")
+	if err != nil {
+		t.Fatalf("Failed to parse due date: %v", err)
+	}
+	if due.Day() != 1 || due.Hour() != 23 {
+		t.Errorf("Expected a day to mean its end, got %v", due)
+	}
+	if _, err := ParseDueDate("next week"); err == nil {
+		t.Error("Expected an invalid due date to fail")
+	}
+
+	late := &Task{Title: "Late task", State: Implementing, Priority: 5, DueDate: &due}
+	undated := &Task{Title: "Undated task", State: Implementing, Priority: 5}
+	for _, task := range []*Task{late, undated} {
+		if err := store.CreateTask(task); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+
+	got, err := store.GetTask(late.ID)
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	if got.DueDate == nil || !got.DueDate.Equal(due) {
+		t.Errorf("Expected due date %v, got %v", due, got.DueDate)
+	}
+	now := due.Add(time.Hour)
+	if !got.IsOverdue(now) || got.IsOverdue(due.Add(-time.Hour)) {
+		t.Error("Expected the task to be overdue only after its due date")
+	}
+
+	tasks, err := store.ListTasks(TaskFilters{DueBefore: &now})
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != late.ID {
+		t.Errorf("Expected only the late task to be due before %v, got %d tasks", now, len(tasks))
+	}
+	if count, _ := store.GetTaskCount(TaskFilters{DueBefore: &now}); count != 1 {
+		t.Errorf("Expected a count of 1, got %d", count)
+	}
+
+	// Clearing the due date
+	got.DueDate = nil
+	if err := store.UpdateTask(got); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	if got, _ := store.GetTask(late.ID); got.DueDate != nil {
+		t.Errorf("Expected the due date to be cleared, got %v", got.DueDate)
+	}
+}
+
+func TestTaskLocks(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_task_locks.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Locked task", State: Implementing, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	before, _ := store.GetTask(task.ID)
+
+	if err := store.LockTask(task.ID, "worker-1"); err != nil {
+		t.Fatalf("Failed to lock task: %v", err)
+	}
+	got, err := store.GetTask(task.ID)
+	if err != nil {
+		t.Fatalf("Failed to get task: %v", err)
+	}
+	if got.LockedBy != "worker-1" || !got.IsLocked(time.Now()) {
+		t.Errorf("Expected the task to be locked by worker-1, got %q", got.LockedBy)
+	}
+	if !got.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Error("Expected locking not to count as an update")
+	}
+
+	// Another holder is refused; the holder itself renews
+	var lockErr *TaskLockedError
+	if err := store.LockTask(task.ID, "worker-2"); !errors.As(err, &lockErr) || lockErr.LockedBy != "worker-1" {
+		t.Errorf("Expected a TaskLockedError naming worker-1, got %v", err)
+	}
+	if err := store.LockTask(task.ID, "worker-1"); err != nil {
+		t.Errorf("Expected the holder to renew its lock, got %v", err)
+	}
+	if err := store.LockTask("missing", "worker-1"); err == nil || errors.As(err, &lockErr) {
+		t.Errorf("Expected locking a missing task to fail as not found, got %v", err)
+	}
+
+	// Only the holder unlocks
+	if err := store.UnlockTask(task.ID, "worker-2"); err != nil {
+		t.Fatalf("Failed to unlock task: %v", err)
+	}
+	if got, _ := store.GetTask(task.ID); got.LockedBy != "worker-1" {
+		t.Error("Expected another holder's unlock to leave the lock alone")
+	}
+	if err := store.UnlockTask(task.ID, "worker-1"); err != nil {
+		t.Fatalf("Failed to unlock task: %v", err)
+	}
+	if got, _ := store.GetTask(task.ID); got.LockedBy != "" || got.IsLocked(time.Now()) {
+		t.Error("Expected the task to be unlocked")
+	}
+
+	// Expired locks are taken over
+	if err := store.LockTask(task.ID, "worker-1"); err != nil {
+		t.Fatalf("Failed to lock task: %v", err)
+	}
+	if _, err := store.db.Exec("UPDATE tasks SET locked_at = ? WHERE id = ?", time.Now().Add(-2*TaskLockTTL).UTC(), task.ID); err != nil {
+		t.Fatalf("Failed to age lock: %v", err)
+	}
+	if got, _ := store.GetTask(task.ID); got.IsLocked(time.Now()) {
+		t.Error("Expected an expired lock not to count")
+	}
+	if err := store.LockTask(task.ID, "worker-2"); err != nil {
+		t.Errorf("Expected an expired lock to be taken over, got %v", err)
+	}
+}
+
+func TestCycleCheckpoints(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_cycle_checkpoints.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	task := &Task{Title: "Checkpointed task", State: Implementing, Priority: 5}
+	if err := store.CreateTask(task); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	for _, cycleID := range []string{"cycle-1", "cycle-2"} {
+		cp := &CycleCheckpoint{CycleID: cycleID, TaskID: task.ID, PrevState: Implementing, Agent: "developer", Phase: "claimed", Holder: "worker"}
+		if err := store.SaveCheckpoint(cp); err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+	}
+	if err := store.SetCheckpointPhase("cycle-1", "handshake"); err != nil {
+		t.Fatalf("Failed to set phase: %v", err)
+	}
+	if err := store.FinishCheckpoint("cycle-2", CheckpointFinished); err != nil {
+		t.Fatalf("Failed to finish checkpoint: %v", err)
+	}
+
+	// Only the cycle that never finished is left
+	unfinished, err := store.ListUnfinishedCheckpoints()
+	if err != nil {
+		t.Fatalf("Failed to list checkpoints: %v", err)
+	}
+	if len(unfinished) != 1 || unfinished[0].CycleID != "cycle-1" {
+		t.Fatalf("Expected only cycle-1 to be unfinished, got %+v", unfinished)
+	}
+	if cp := unfinished[0]; cp.Phase != "handshake" || cp.PrevState != Implementing || cp.Agent != "developer" || cp.FinishedAt != nil {
+		t.Errorf("Unexpected checkpoint: %+v", cp)
+	}
+
+	audited, err := store.CycleAudited("cycle-1")
+	if err != nil || audited {
+		t.Errorf("Expected cycle-1 not to be audited, got %v, %v", audited, err)
+	}
+	if err := store.CreateAuditLog(&AuditLog{TaskID: task.ID, CycleID: "cycle-1", PrevState: "implementing", NextState: "implementing", Result: "failure"}); err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+	if audited, err := store.CycleAudited("cycle-1"); err != nil || !audited {
+		t.Errorf("Expected cycle-1 to be audited, got %v, %v", audited, err)
+	}
+}
+
+func TestCycleRecords(t *testing.T) {
+	// Create temporary database
+	dbFile := "test_cycle_records.db"
+	defer os.Remove(dbFile)
+
+	store, err := NewStore(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	records := []*CycleRecord{
+		{CycleID: "old", TaskID: "t1", Agent: "developer", Outcome: CycleSuccess, CostUSD: 5, StartedAt: now.AddDate(0, 0, -10)},
+		{CycleID: "c1", TaskID: "t1", Agent: "developer", Provider: "claude", Outcome: CycleSuccess,
+			PromptTokens: 1000, CompletionTokens: 200, CostUSD: 0.25, DurationSeconds: 60, StartedAt: now.Add(-2 * time.Minute)},
+		{CycleID: "c2", TaskID: "t2", Agent: "reviewer", Outcome: CycleError, CostUSD: 0.5, DurationSeconds: 30, StartedAt: now.Add(-time.Minute)},
+	}
+	for _, record := range records {
+		if err := store.RecordCycle(record); err != nil {
+			t.Fatalf("Failed to record cycle: %v", err)
+		}
+	}
+
+	listed, err := store.ListCycles(now.Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to list cycles: %v", err)
+	}
+	if len(listed) != 2 || listed[0].CycleID != "c1" || listed[0].PromptTokens != 1000 || listed[0].Provider != "claude" {
+		t.Errorf("Expected c1 and c2 oldest first, got %+v", listed)
+	}
+
+	totals, err := store.CycleTotalsSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to add up cycles: %v", err)
+	}
+	if totals.Cycles != 2 || totals.Succeeded != 1 || totals.CostUSD != 0.75 || totals.DurationSeconds != 90 {
+		t.Errorf("Unexpected totals %+v", totals)
+	}
+
+	var summed CycleTotals
+	for _, record := range listed {
+		summed.Add(record)
+	}
+	if summed != *totals {
+		t.Errorf("Expected Add to match the query's totals, got %+v and %+v", summed, *totals)
+	}
+
+	recent, err := store.GetRecentCycleCosts(now)
+	if err != nil {
+		t.Fatalf("Failed to get recent cycle costs: %v", err)
+	}
+	if recent.LastWeek.Cycles < recent.Today.Cycles || recent.LastWeek.CostUSD >= 5 {
+		t.Errorf("Expected the 10-day-old cycle left out of the last week, got %+v", recent.LastWeek)
+	}
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StateEntry records a task moving into a state
+type StateEntry struct {
+	TaskID    string    `json:"task_id" db:"task_id"`
+	State     State     `json:"state" db:"state"`
+	EnteredAt time.Time `json:"entered_at" db:"entered_at"`
+}
+
+// StateSpan is a stretch of time a task spent in one state
+type StateSpan struct {
+	State     State      `json:"state"`
+	EnteredAt time.Time  `json:"entered_at"`
+	LeftAt    *time.Time `json:"left_at,omitempty"` // nil while the task is still in the state
+	Seconds   float64    `json:"seconds"`           // up to now for the current state
+}
+
+// TimeInState is how long a task has spent in each of its states
+type TimeInState struct {
+	TaskID string            `json:"task_id"`
+	Spans  []*StateSpan      `json:"spans"`  // oldest first
+	Totals map[State]float64 `json:"totals"` // seconds per state, over every span in it
+}
+
+// Current returns the span of the state the task is in now, or nil for a task
+// with no history
+func (t *TimeInState) Current() *StateSpan {
+	if len(t.Spans) == 0 {
+		return nil
+	}
+	return t.Spans[len(t.Spans)-1]
+}
+
+// recordStateEntry notes that a task moved into state at the given time
+func recordStateEntry(q interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, taskID string, state State, at time.Time) error {
+	_, err := q.Exec("INSERT INTO state_history (task_id, state, entered_at) VALUES (?, ?, ?)", taskID, state, at.UTC())
+	return err
+}
+
+// backfillStateHistory gives tasks created before state history was kept an
+// entry for their current state. When they entered it isn't known, so their
+// last update stands in for it.
+func (s *Store) backfillStateHistory() error {
+	_, err := s.db.Exec(`
+		INSERT INTO state_history (task_id, state, entered_at)
+		SELECT id, state, updated_at FROM tasks
+		WHERE id NOT IN (SELECT task_id FROM state_history)
+	`)
+	return err
+}
+
+// GetStateHistory returns the states a task has moved into, oldest first
+func (s *Store) GetStateHistory(taskID string) ([]*StateEntry, error) {
+	return s.queryStateEntries("SELECT task_id, state, entered_at FROM state_history WHERE task_id = ? ORDER BY entered_at, id", taskID)
+}
+
+// listAllStateEntries returns the state history of every task
+func (s *Store) listAllStateEntries() ([]*StateEntry, error) {
+	return s.queryStateEntries("SELECT task_id, state, entered_at FROM state_history ORDER BY task_id, entered_at, id")
+}
+
+func (s *Store) queryStateEntries(query string, args ...interface{}) ([]*StateEntry, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*StateEntry
+	for rows.Next() {
+		entry := &StateEntry{}
+		if err := rows.Scan(&entry.TaskID, &entry.State, local(&entry.EnteredAt)); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetTimeInState works out how long a task spent in each state it has been
+// in, counting the current one up to now
+func (s *Store) GetTimeInState(taskID string, now time.Time) (*TimeInState, error) {
+	if _, err := s.GetTask(taskID); err != nil {
+		return nil, err
+	}
+	entries, err := s.GetStateHistory(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state history: %w", err)
+	}
+
+	result := &TimeInState{TaskID: taskID, Spans: []*StateSpan{}, Totals: make(map[State]float64)}
+	for i, entry := range entries {
+		span := &StateSpan{State: entry.State, EnteredAt: entry.EnteredAt}
+		end := now
+		if i+1 < len(entries) {
+			leftAt := entries[i+1].EnteredAt
+			span.LeftAt = &leftAt
+			end = leftAt
+		}
+		span.Seconds = end.Sub(entry.EnteredAt).Seconds()
+		result.Spans = append(result.Spans, span)
+		result.Totals[entry.State] += span.Seconds
+	}
+	return result, nil
+}
+
+// CurrentStateEntries returns when each task of the store's project entered
+// the state it is in now, leaving out deleted tasks
+func (s *Store) CurrentStateEntries() (map[string]time.Time, error) {
+	rows, err := s.db.Query(`
+		SELECT h.task_id, h.entered_at
+		FROM state_history h JOIN tasks t ON t.id = h.task_id
+		WHERE t.project_id = ? AND t.deleted_at IS NULL
+			AND h.id = (SELECT MAX(id) FROM state_history WHERE task_id = h.task_id)
+	`, s.project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entered := make(map[string]time.Time)
+	for rows.Next() {
+		var taskID string
+		var at time.Time
+		if err := rows.Scan(&taskID, local(&at)); err != nil {
+			return nil, err
+		}
+		entered[taskID] = at
+	}
+	return entered, rows.Err()
+}
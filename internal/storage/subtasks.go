@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"fmt"
+)
+
+// ListChildTasks returns the tasks grouped directly under a parent task,
+// archived ones included
+func (s *Store) ListChildTasks(parentID string) ([]*Task, error) {
+	return s.ListTasks(TaskFilters{ParentID: &parentID, IncludeArchived: true})
+}
+
+// SetTaskParent groups a task under parentID, or makes it a top-level task
+// when parentID is "". A task can't be grouped under itself or under one of
+// its own subtasks.
+func (s *Store) SetTaskParent(taskID, parentID string) error {
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		return err
+	}
+
+	if parentID == taskID {
+		return fmt.Errorf("task %s can't be grouped under itself", taskID)
+	}
+	// Walk up from the new parent; meeting the task would make a cycle
+	for ancestor := parentID; ancestor != ""; {
+		if ancestor == taskID {
+			return fmt.Errorf("task %s can't be grouped under %s: %s is one of its subtasks", taskID, parentID, parentID)
+		}
+		parent, err := s.GetTask(ancestor)
+		if err != nil {
+			return fmt.Errorf("parent task %s not found: %w", ancestor, err)
+		}
+		ancestor = parent.ParentID
+	}
+
+	task.ParentID = parentID
+	return s.UpdateTask(task)
+}
+
+// TaskNode is a task with the subtasks grouped under it
+type TaskNode struct {
+	*Task
+	Children []*TaskNode `json:"children,omitempty"`
+}
+
+// BuildTaskTree groups tasks under their parents, keeping their order. Tasks
+// whose parent is not among them are roots.
+func BuildTaskTree(tasks []*Task) []*TaskNode {
+	nodes := make(map[string]*TaskNode, len(tasks))
+	for _, task := range tasks {
+		nodes[task.ID] = &TaskNode{Task: task}
+	}
+
+	var roots []*TaskNode
+	for _, task := range tasks {
+		node := nodes[task.ID]
+		if parent, ok := nodes[task.ParentID]; ok && task.ParentID != task.ID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,11 @@
+package storage
+
+// tagConditions adds the filters' tag conditions to a task query: a task must
+// have every tag listed. Tasks whose tags are not a valid JSON array have none.
+func (f TaskFilters) tagConditions(query string, args []interface{}) (string, []interface{}) {
+	for _, tag := range f.Tags {
+		query += " AND EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(tags) THEN tags ELSE '[]' END) WHERE value = ?)"
+		args = append(args, tag)
+	}
+	return query, args
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"strings"
+	"time"
+)
+
+// archivedCondition adds the filters' archived condition to a task query.
+// Archived tasks are left out unless IncludeArchived or ArchivedOnly is set.
+func (f TaskFilters) archivedCondition(query string) string {
+	switch {
+	case f.ArchivedOnly:
+		return query + " AND archived = 1"
+	case f.IncludeArchived:
+		return query
+	default:
+		return query + " AND archived = 0"
+	}
+}
+
+// ArchiveTasks archives the unarchived tasks in state last updated before
+// cutoff, so task lists, selection and the board leave them out. Archiving
+// keeps the tasks' updated_at. With dryRun nothing changes and the tasks
+// that would be archived are returned.
+func (s *Store) ArchiveTasks(state State, cutoff time.Time, dryRun bool) ([]*Task, error) {
+	tasks, err := s.ListTasks(TaskFilters{State: &state})
+	if err != nil {
+		return nil, err
+	}
+
+	var archived []*Task
+	for _, task := range tasks {
+		if task.UpdatedAt.Before(cutoff) {
+			archived = append(archived, task)
+		}
+	}
+	if dryRun || len(archived) == 0 {
+		return archived, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, task := range archived {
+		if _, err := tx.Exec("UPDATE tasks SET archived = 1 WHERE id = ? AND project_id = ?", task.ID, s.project); err != nil {
+			return nil, err
+		}
+		task.Archived = true
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// UnarchiveTasks returns archived tasks of the store's project to the task
+// lists. It returns how many of the tasks were archived.
+func (s *Store) UnarchiveTasks(ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	args := []interface{}{s.project}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	result, err := s.db.Exec("UPDATE tasks SET archived = 0 WHERE project_id = ? AND archived = 1 AND id IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,54 @@
+package storage
+
+import "fmt"
+
+// CreateTasksBatch creates tasks in one transaction: either every task is
+// created or, when one fails, none is. Like CreateTask, tasks without an ID
+// are given one.
+func (s *Store) CreateTasksBatch(tasks []*Task) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, task := range tasks {
+		if err := s.insertTask(tx, task); err != nil {
+			return fmt.Errorf("failed to create task %d (%q): %w", i+1, task.Title, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.emitCreated(tasks)
+	return nil
+}
+
+// UpdateTasksBatch updates tasks in one transaction, recording title and
+// description changes as revisions attributed to actor as UpdateTaskBy does.
+// Either every task is updated or, when one fails, none is. Transitions are
+// announced with note once the transaction commits.
+func (s *Store) UpdateTasksBatch(tasks []*Task, actor, note string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	prevStates := make([]State, len(tasks))
+	for i, task := range tasks {
+		if prevStates[i], err = updateTaskTx(tx, task, actor, 0); err != nil {
+			return fmt.Errorf("failed to update task %s: %w", task.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for i, task := range tasks {
+		s.emitTaskChange(task, prevStates[i], note)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import "fmt"
+
+// IsHeld reports whether state puts a task on hold: a blocked or paused task
+// keeps its place in the workflow but is not selected until it is resumed
+func IsHeld(state State) bool {
+	return state == Blocked || state == Paused
+}
+
+// heldFrom returns the state a task moving from prev to next resumes to once
+// the move is made: the state it leaves when it goes on hold, the one it was
+// already held from when it moves between blocked and paused, and none once it
+// is no longer held.
+func heldFrom(prev, prevHeldFrom, next State) State {
+	switch {
+	case !IsHeld(next):
+		return ""
+	case IsHeld(prev):
+		return prevHeldFrom
+	default:
+		return prev
+	}
+}
+
+// ResumeTask moves a blocked or paused task back to the state it was held
+// from, returning that state
+func (s *Store) ResumeTask(id, note string) (State, error) {
+	task, err := s.GetTask(id)
+	if err != nil {
+		return "", err
+	}
+	if !IsHeld(task.State) {
+		return "", fmt.Errorf("task %s is %s, not blocked or paused", id, task.State)
+	}
+	if task.HeldFrom == "" {
+		return "", fmt.Errorf("task %s has no state to resume to; move it with 'baton tasks update'", id)
+	}
+
+	resumeTo := task.HeldFrom
+	if err := s.UpdateTaskState(id, resumeTo, note); err != nil {
+		return "", err
+	}
+	return resumeTo, nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// TaskLockTTL is how long a task lock holds unless its holder renews it, so
+// that the locks of a run that died are taken over
+const TaskLockTTL = 10 * time.Minute
+
+// TaskLockedError reports a task another cycle is working on
+type TaskLockedError struct {
+	TaskID   string
+	LockedBy string
+	LockedAt time.Time
+}
+
+func (e *TaskLockedError) Error() string {
+	return fmt.Sprintf("task %s is locked by %s since %s", e.TaskID, e.LockedBy, e.LockedAt.Format(time.RFC3339))
+}
+
+// IsLocked reports whether a cycle holds the task's lock at now
+func (t *Task) IsLocked(now time.Time) bool {
+	return t.LockedBy != "" && t.LockedAt != nil && now.Sub(*t.LockedAt) < TaskLockTTL
+}
+
+// LockTask locks a task for holder, unless someone else holds an unexpired
+// lock on it, in which case a *TaskLockedError is returned. Locking a task the
+// holder already holds renews the lock. Locks are advisory: they keep cycles
+// from selecting the same task, not anyone from updating it.
+func (s *Store) LockTask(taskID, holder string) error {
+	now := time.Now()
+	res, err := s.db.Exec(`
+		UPDATE tasks SET locked_by = ?, locked_at = ?
+		WHERE id = ? AND project_id = ? AND (locked_by = '' OR locked_by = ? OR locked_at IS NULL OR locked_at < ?)
+	`, holder, now.UTC(), taskID, s.project, holder, now.Add(-TaskLockTTL).UTC())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("task %s not found: %w", taskID, err)
+	}
+	lockErr := &TaskLockedError{TaskID: task.ID, LockedBy: task.LockedBy}
+	if task.LockedAt != nil {
+		lockErr.LockedAt = *task.LockedAt
+	}
+	return lockErr
+}
+
+// UnlockTask releases holder's lock on a task; locks held by others are left alone
+func (s *Store) UnlockTask(taskID, holder string) error {
+	_, err := s.db.Exec("UPDATE tasks SET locked_by = '', locked_at = NULL WHERE id = ? AND locked_by = ?", taskID, holder)
+	return err
+}
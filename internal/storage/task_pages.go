@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not made by a listing
+// with the same sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Task sort keys for TaskFilters.Sort
+const (
+	SortByPriority  = "priority"   // highest priority first, then least recently updated
+	SortByUpdatedAt = "updated_at" // most recently updated first
+	SortByCreatedAt = "created_at" // newest first
+	SortByState     = "state"      // in workflow order, then highest priority first
+	SortByDueDate   = "due_date"   // earliest due first, tasks without a due date last
+)
+
+// TaskSorts lists the valid task sort keys
+var TaskSorts = []string{SortByPriority, SortByUpdatedAt, SortByCreatedAt, SortByState, SortByDueDate}
+
+// stateOrder is the workflow order SortByState sorts in
+var stateOrder = []State{
+	ReadyForPlan, Planning, ReadyForImplementation, Implementing, ReadyForCodeReview,
+	Reviewing, NeedsFixes, Fixing, ReadyForCommit, Committing, Done,
+}
+
+// StateRank is a state's place in the workflow order, after every workflow
+// state for states outside it, such as the held states
+func StateRank(state State) int {
+	for i, s := range stateOrder {
+		if s == state {
+			return i
+		}
+	}
+	return len(stateOrder)
+}
+
+// sortColumn is one ORDER BY term. expr is SQL over the tasks table whose
+// value can be compared with a cursor's.
+type sortColumn struct {
+	expr string
+	desc bool
+}
+
+// taskSortColumns returns the ORDER BY terms of a sort key. Task ID breaks
+// ties, so the order is total and cursors are stable.
+func taskSortColumns(sort string) ([]sortColumn, error) {
+	var columns []sortColumn
+	switch sort {
+	case "", SortByPriority:
+		columns = []sortColumn{{"priority", true}, {"updated_at || ''", false}}
+	case SortByUpdatedAt:
+		columns = []sortColumn{{"updated_at || ''", true}}
+	case SortByCreatedAt:
+		columns = []sortColumn{{"created_at || ''", true}}
+	case SortByState:
+		rank := "CASE state"
+		for i, state := range stateOrder {
+			rank += fmt.Sprintf(" WHEN '%s' THEN %d", state, i)
+		}
+		rank += fmt.Sprintf(" ELSE %d END", len(stateOrder))
+		columns = []sortColumn{{rank, false}, {"priority", true}}
+	case SortByDueDate:
+		columns = []sortColumn{{"COALESCE(due_date, '9999') || ''", false}, {"priority", true}}
+	default:
+		return nil, fmt.Errorf("unknown sort %q (valid: %s)", sort, strings.Join(TaskSorts, ", "))
+	}
+	return append(columns, sortColumn{"id", false}), nil
+}
+
+// CheckTaskSort returns an error unless sort is "" or one of TaskSorts
+func CheckTaskSort(sort string) error {
+	_, err := taskSortColumns(sort)
+	return err
+}
+
+// TaskPage is one page of tasks
+type TaskPage struct {
+	Tasks      []*Task `json:"tasks"`
+	Total      int     `json:"total"`                 // tasks matching the filters, across all pages
+	NextCursor string  `json:"next_cursor,omitempty"` // "" on the last page
+}
+
+// taskCursor is the position after the last task of a page: the values of
+// its sort columns, for the sort and order it was made with
+type taskCursor struct {
+	Sort    string        `json:"sort"`
+	Reverse bool          `json:"reverse"`
+	Values  []interface{} `json:"values"`
+}
+
+// encodeCursor makes an opaque cursor
+func encodeCursor(cursor taskCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor reads a cursor made by encodeCursor
+func decodeCursor(s string) (*taskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &taskCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// orderAndPage appends the cursor condition, ORDER BY, LIMIT and OFFSET for
+// the filters' sort and paging to a task query
+func (f TaskFilters) orderAndPage(query string, args []interface{}) (string, []interface{}, error) {
+	columns, err := taskSortColumns(f.Sort)
+	if err != nil {
+		return "", nil, err
+	}
+	// Reverse flips every column, ID included
+	if f.Reverse {
+		for i := range columns {
+			columns[i].desc = !columns[i].desc
+		}
+	}
+
+	if f.Cursor != "" {
+		cursor, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		if cursor.Sort != f.Sort || cursor.Reverse != f.Reverse || len(cursor.Values) != len(columns) {
+			return "", nil, fmt.Errorf("%w: it was made for a different sort", ErrInvalidCursor)
+		}
+		// Rows after the cursor: (a, b, c) > (x, y, z) in each column's direction
+		var terms []string
+		for i, column := range columns {
+			var term []string
+			for j, prev := range columns[:i] {
+				term = append(term, prev.expr+" = ?")
+				args = append(args, cursor.Values[j])
+			}
+			op := " > ?"
+			if column.desc {
+				op = " < ?"
+			}
+			term = append(term, column.expr+op)
+			args = append(args, cursor.Values[i])
+			terms = append(terms, "("+strings.Join(term, " AND ")+")")
+		}
+		query += " AND (" + strings.Join(terms, " OR ") + ")"
+	}
+
+	var order []string
+	for _, column := range columns {
+		if column.desc {
+			order = append(order, column.expr+" DESC")
+		} else {
+			order = append(order, column.expr+" ASC")
+		}
+	}
+	query += " ORDER BY " + strings.Join(order, ", ")
+
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+		if f.Offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, f.Offset)
+		}
+	} else if f.Offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, f.Offset)
+	}
+
+	return query, args, nil
+}
+
+// ListTaskPage returns one page of the tasks matching filters, in the order
+// of filters.Sort, with the total across pages. A page is filters.Limit
+// tasks from filters.Cursor, or from filters.Offset when there is no cursor;
+// NextCursor continues after it.
+func (s *Store) ListTaskPage(filters TaskFilters) (*TaskPage, error) {
+	if filters.Cursor != "" && filters.Offset > 0 {
+		return nil, fmt.Errorf("%w: a page starts at a cursor or an offset, not both", ErrInvalidCursor)
+	}
+
+	tasks, err := s.ListTasks(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	countFilters := filters
+	countFilters.Cursor = ""
+	total, err := s.GetTaskCount(countFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TaskPage{Tasks: tasks, Total: total}
+	if filters.Limit > 0 && len(tasks) == filters.Limit {
+		if page.NextCursor, err = s.nextTaskCursor(filters, tasks[len(tasks)-1].ID); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// nextTaskCursor makes the cursor positioned after the task with the given ID
+func (s *Store) nextTaskCursor(filters TaskFilters, lastID string) (string, error) {
+	columns, err := taskSortColumns(filters.Sort)
+	if err != nil {
+		return "", err
+	}
+
+	exprs := make([]string, len(columns))
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i, column := range columns {
+		exprs[i] = column.expr
+		pointers[i] = &values[i]
+	}
+	query := "SELECT " + strings.Join(exprs, ", ") + " FROM tasks WHERE id = ?"
+	if err := s.db.QueryRow(query, lastID).Scan(pointers...); err != nil {
+		return "", err
+	}
+	for i, value := range values {
+		// Text comes back as bytes, which would not survive the JSON round trip
+		if b, ok := value.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+
+	return encodeCursor(taskCursor{Sort: filters.Sort, Reverse: filters.Reverse, Values: values})
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// deletedCondition adds the filters' deleted condition to a task query.
+// Deleted tasks are left out unless IncludeDeleted or DeletedOnly is set.
+func (f TaskFilters) deletedCondition(query string) string {
+	switch {
+	case f.DeletedOnly:
+		return query + " AND deleted_at IS NOT NULL"
+	case f.IncludeDeleted:
+		return query
+	default:
+		return query + " AND deleted_at IS NULL"
+	}
+}
+
+// utcOrNil is a nullable timestamp as written to the database
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// DeleteTask moves a task of the store's project to the trash. A deleted task
+// drops out of GetTask, task lists, counts and search until RestoreTask
+// brings it back, but keeps its artifacts and history until it is purged. A
+// task with subtasks that are not deleted can't be deleted.
+func (s *Store) DeleteTask(id string) error {
+	var subtasks int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE parent_id = ? AND project_id = ? AND deleted_at IS NULL", id, s.project).Scan(&subtasks); err != nil {
+		return err
+	}
+	if subtasks > 0 {
+		return fmt.Errorf("task %s has %d subtasks; delete or regroup them first", id, subtasks)
+	}
+
+	result, err := s.db.Exec("UPDATE tasks SET deleted_at = ? WHERE id = ? AND project_id = ? AND deleted_at IS NULL", time.Now().UTC(), id, s.project)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTaskNotFound
+	}
+	s.emit(TaskEvent{Kind: EventDeleted, TaskID: id})
+	return nil
+}
+
+// RestoreTask takes a task of the store's project out of the trash
+func (s *Store) RestoreTask(id string) error {
+	result, err := s.db.Exec("UPDATE tasks SET deleted_at = NULL WHERE id = ? AND project_id = ? AND deleted_at IS NOT NULL", id, s.project)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTaskNotFound
+	}
+	s.emit(TaskEvent{Kind: EventRestored, TaskID: id})
+	return nil
+}
+
+// ListDeletedTasks returns the tasks in the trash, most recently deleted first
+func (s *Store) ListDeletedTasks() ([]*Task, error) {
+	tasks, err := s.ListTasks(TaskFilters{DeletedOnly: true, IncludeArchived: true})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].DeletedAt.After(*tasks[j].DeletedAt) })
+	return tasks, nil
+}
+
+// taskTables lists the tables holding rows that belong to a task, which
+// purging removes along with it
+var taskTables = []string{
+	"task_requirements", "artifacts", "audit_logs", "task_briefings", "task_watches",
+	"task_revisions", "task_assessments", "area_locks", "task_notes", "state_history", "cycle_checkpoints",
+}
+
+// PurgeTasks permanently removes deleted tasks and everything that belongs to
+// them, in one transaction. Tasks that are not in the trash, or belong to
+// another project, are left alone.
+// It returns how many tasks were purged. Archived audit payloads and artifact
+// blobs stay on disk.
+func (s *Store) PurgeTasks(ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	args := []interface{}{s.project}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	deleted := "SELECT id FROM tasks WHERE project_id = ? AND deleted_at IS NOT NULL AND id IN (" + placeholders + ")"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM search_embeddings WHERE ref_id IN (SELECT ref_id FROM search_index WHERE task_id IN ("+deleted+"))", args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge search embeddings: %w", err)
+	}
+	for _, table := range taskTables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE task_id IN ("+deleted+")", args...); err != nil {
+			return 0, fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+	}
+	result, err := tx.Exec("DELETE FROM tasks WHERE project_id = ? AND deleted_at IS NOT NULL AND id IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge tasks: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), tx.Commit()
+}
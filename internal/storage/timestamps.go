@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Timestamps are written in UTC so they sort and compare as text, and are
+// read back in time.Local, which baton sets to the configured display timezone.
+
+// TimestampColumns lists every timestamp column, for normalizeTimestamps
+var TimestampColumns = []struct {
+	Table  string
+	Column string
+}{
+	{"projects", "created_at"},
+	{"tasks", "created_at"},
+	{"tasks", "updated_at"},
+	{"tasks", "deleted_at"},
+	{"tasks", "due_date"},
+	{"tasks", "locked_at"},
+	{"requirements", "created_at"},
+	{"requirements", "updated_at"},
+	{"retired_requirement_keys", "retired_at"},
+	{"artifacts", "created_at"},
+	{"agents", "created_at"},
+	{"audit_logs", "created_at"},
+	{"task_briefings", "created_at"},
+	{"task_watches", "created_at"},
+	{"task_revisions", "created_at"},
+	{"task_assessments", "created_at"},
+	{"milestone_signoffs", "created_at"},
+	{"task_notes", "created_at"},
+	{"task_notes", "updated_at"},
+	{"state_history", "entered_at"},
+	{"area_locks", "acquired_at"},
+	{"area_locks", "expires_at"},
+	{"cycle_checkpoints", "started_at"},
+	{"cycle_checkpoints", "updated_at"},
+	{"cycle_checkpoints", "finished_at"},
+	{"cycles", "started_at"},
+	{"cycles", "finished_at"},
+}
+
+// localTime scans a timestamp column into a time.Time in time.Local
+type localTime struct {
+	t *time.Time
+}
+
+// local wraps a time.Time scan destination so the value is read in time.Local
+func local(t *time.Time) sql.Scanner {
+	return localTime{t: t}
+}
+
+// storedTimeLayouts are the text forms timestamps are stored in: Go's, as
+// baton writes them, and SQLite's CURRENT_TIMESTAMP. modernc's driver turns
+// both into a time.Time; the encrypted database's driver only the first.
+var storedTimeLayouts = []string{storedTimeLayout, "2006-01-02 15:04:05"}
+
+// Scan implements sql.Scanner
+func (l localTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		*l.t = v.Local()
+	case nil:
+		*l.t = time.Time{}
+	case string:
+		for _, layout := range storedTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				*l.t = t.Local()
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot scan %q into a timestamp", v)
+	default:
+		return fmt.Errorf("cannot scan %T into a timestamp", value)
+	}
+	return nil
+}
+
+// localOrNil scans a nullable timestamp column into a *time.Time in
+// time.Local, nil for NULL
+type localOrNil struct {
+	t **time.Time
+}
+
+// Scan implements sql.Scanner
+func (l localOrNil) Scan(value interface{}) error {
+	if value == nil {
+		*l.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := local(&t).Scan(value); err != nil {
+		return err
+	}
+	*l.t = &t
+	return nil
+}
+
+// utcTimestampsVersion is the user_version of databases whose timestamps are all UTC
+const utcTimestampsVersion = 1
+
+// normalizeTimestamps rewrites timestamps written in a zone other than UTC,
+// as versions before timezone support did, once per database. The updated_at
+// triggers are dropped while rows are rewritten and restored afterwards.
+func (s *Store) normalizeTimestamps() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version >= utcTimestampsVersion {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	dropped := false
+	for _, column := range TimestampColumns {
+		// Go writes "2006-01-02 15:04:05.999999999 -0700 MST"; SQLite's own
+		// CURRENT_TIMESTAMP is UTC without a zone
+		query := fmt.Sprintf(`
+			SELECT rowid, %[2]s FROM %[1]s
+			WHERE %[2]s GLOB '* [+-][0-9][0-9][0-9][0-9] *' AND %[2]s NOT GLOB '* +0000 UTC*'
+		`, column.Table, column.Column)
+		rows, err := tx.Query(query)
+		if err != nil {
+			return err
+		}
+
+		type stale struct {
+			rowid int64
+			at    time.Time
+		}
+		var pending []stale
+		for rows.Next() {
+			var row stale
+			if err := rows.Scan(&row.rowid, &row.at); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to read %s.%s: %w", column.Table, column.Column, err)
+			}
+			pending = append(pending, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		if !dropped {
+			if _, err := tx.Exec("DROP TRIGGER IF EXISTS update_tasks_updated_at; DROP TRIGGER IF EXISTS update_requirements_updated_at"); err != nil {
+				return err
+			}
+			dropped = true
+		}
+		update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", column.Table, column.Column)
+		for _, row := range pending {
+			if _, err := tx.Exec(update, row.at.UTC(), row.rowid); err != nil {
+				return err
+			}
+		}
+	}
+
+	if dropped {
+		// Recreate the triggers
+		if _, err := tx.Exec(CreateTablesSQL); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", utcTimestampsVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"time"
+)
+
+// TaskWatch subscribes a watcher to notifications about one task
+type TaskWatch struct {
+	TaskID    string    `json:"task_id" db:"task_id"`
+	Watcher   string    `json:"watcher" db:"watcher"`
+	Channel   string    `json:"channel" db:"channel"` // "" for the default channel
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// taskState returns the current state of a task, or "" when it cannot be read
+func (s *Store) taskState(id string) State {
+	var state State
+	s.db.QueryRow("SELECT state FROM tasks WHERE id = ?", id).Scan(&state)
+	return state
+}
+
+// WatchTask subscribes watcher to a task, replacing the channel of an existing watch
+func (s *Store) WatchTask(watch *TaskWatch) error {
+	watch.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO task_watches (task_id, watcher, channel, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(task_id, watcher) DO UPDATE SET channel = excluded.channel
+	`
+
+	_, err := s.db.Exec(query, watch.TaskID, watch.Watcher, watch.Channel, watch.CreatedAt.UTC())
+	return err
+}
+
+// UnwatchTask removes a watcher's subscription, reporting whether there was one
+func (s *Store) UnwatchTask(taskID, watcher string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM task_watches WHERE task_id = ? AND watcher = ?", taskID, watcher)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+// ListTaskWatches returns the watches on a task, or every watch when taskID is ""
+func (s *Store) ListTaskWatches(taskID string) ([]*TaskWatch, error) {
+	query := "SELECT task_id, watcher, channel, created_at FROM task_watches"
+	args := []interface{}{}
+	if taskID != "" {
+		query += " WHERE task_id = ?"
+		args = append(args, taskID)
+	}
+	query += " ORDER BY task_id, created_at"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watches []*TaskWatch
+	for rows.Next() {
+		watch := &TaskWatch{}
+		if err := rows.Scan(&watch.TaskID, &watch.Watcher, &watch.Channel, local(&watch.CreatedAt)); err != nil {
+			return nil, err
+		}
+		watches = append(watches, watch)
+	}
+
+	return watches, rows.Err()
+}
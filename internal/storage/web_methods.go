@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -28,6 +29,22 @@ func (s *Store) GetTaskCount(filters TaskFilters) (int, error) {
 		args = append(args, *filters.Owner)
 	}
 
+	if filters.ParentID != nil {
+		query += " AND parent_id = ?"
+		args = append(args, *filters.ParentID)
+	}
+
+	if filters.DueBefore != nil {
+		query += " AND due_date < ?"
+		args = append(args, filters.DueBefore.UTC())
+	}
+
+	query, args = filters.tagConditions(query, args)
+	query, args = filters.customFieldConditions(query, args)
+	query = filters.archivedCondition(query)
+	query = filters.deletedCondition(query)
+	query, args = s.projectCondition(filters, query, args)
+
 	var count int
 	err := s.db.QueryRow(query, args...).Scan(&count)
 	return count, err
@@ -47,6 +64,8 @@ type AuditEntry struct {
 	InputsSummary  string         `json:"inputs_summary" db:"inputs_summary"`
 	OutputsSummary string         `json:"outputs_summary" db:"outputs_summary"`
 	Result         string         `json:"result" db:"result"`
+	Confidence     *float64       `json:"confidence,omitempty" db:"confidence"`
+	OpenQuestions  json.RawMessage `json:"open_questions,omitempty" db:"open_questions"` // JSON array
 	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
 }
 
@@ -56,12 +75,13 @@ func (s *Store) GetRecentAuditEntries(limit int) ([]AuditEntry, error) {
 		SELECT a.id, a.task_id, t.title as task_title, a.prev_state, a.next_state,
 		       a.actor, a.created_at
 		FROM audit_logs a
-		LEFT JOIN tasks t ON a.task_id = t.id
+		JOIN tasks t ON a.task_id = t.id
+		WHERE t.project_id = ?
 		ORDER BY a.created_at DESC
 		LIMIT ?
 	`
 
-	rows, err := s.db.Query(query, limit)
+	rows, err := s.db.Query(query, s.project, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query recent audit entries: %w", err)
 	}
@@ -79,7 +99,7 @@ func (s *Store) GetRecentAuditEntries(limit int) ([]AuditEntry, error) {
 			&entry.PrevState,
 			&entry.NextState,
 			&entry.Actor,
-			&entry.CreatedAt,
+			local(&entry.CreatedAt),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
@@ -101,7 +121,7 @@ func (s *Store) GetAuditHistory(taskID string) ([]AuditEntry, error) {
 	query := `
 		SELECT id, task_id, prev_state, next_state, actor, selection_reason,
 		       note, commands, follow_ups, inputs_summary, outputs_summary,
-		       result, created_at
+		       result, confidence, open_questions, created_at, archive_path, archive_sha256
 		FROM audit_logs
 		WHERE task_id = ?
 		ORDER BY created_at ASC
@@ -116,6 +136,7 @@ func (s *Store) GetAuditHistory(taskID string) ([]AuditEntry, error) {
 	var entries []AuditEntry
 	for rows.Next() {
 		var entry AuditEntry
+		var archivePath, archiveSum string
 
 		err := rows.Scan(
 			&entry.ID,
@@ -130,11 +151,16 @@ func (s *Store) GetAuditHistory(taskID string) ([]AuditEntry, error) {
 			&entry.InputsSummary,
 			&entry.OutputsSummary,
 			&entry.Result,
-			&entry.CreatedAt,
+			&entry.Confidence,
+			(*[]byte)(&entry.OpenQuestions),
+			local(&entry.CreatedAt),
+			&archivePath,
+			&archiveSum,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
 		}
+		s.restoreAuditEntry(&entry, archivePath, archiveSum)
 
 		entries = append(entries, entry)
 	}
@@ -144,33 +170,121 @@ func (s *Store) GetAuditHistory(taskID string) ([]AuditEntry, error) {
 
 // UpdateTask updates an existing task
 func (s *Store) UpdateTask(task *Task) error {
+	return s.UpdateTaskBy(task, "")
+}
+
+// UpdateTaskBy updates an existing task, recording a revision attributed to
+// actor when its title or description changes
+func (s *Store) UpdateTaskBy(task *Task, actor string) error {
+	return s.updateTask(task, actor, 0)
+}
+
+// updateTask updates a task and records any title or description change as a
+// revision; revertedFrom names the revision a revert restores
+func (s *Store) updateTask(task *Task, actor string, revertedFrom int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	prevState, err := updateTaskTx(tx, task, actor, revertedFrom)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.emitTaskChange(task, prevState, "")
+	return nil
+}
+
+// updateTaskTx does updateTask's work inside tx and returns the task's state
+// before the update, so the caller can emit a transition once tx commits
+func updateTaskTx(tx *sql.Tx, task *Task, actor string, revertedFrom int) (State, error) {
 	task.UpdatedAt = time.Now()
 
+	var prevState, prevHeldFrom State
+	var prev TaskRevision
+	err := tx.QueryRow("SELECT state, held_from, title, COALESCE(description, ''), updated_at FROM tasks WHERE id = ?", task.ID).Scan(
+		&prevState, &prevHeldFrom, &prev.Title, &prev.Description, local(&prev.CreatedAt))
+	if err == sql.ErrNoRows {
+		return "", ErrTaskNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get task: %w", err)
+	}
+	task.HeldFrom = heldFrom(prevState, prevHeldFrom, task.State)
+
 	query := `
 		UPDATE tasks
-		SET title = ?, description = ?, state = ?, priority = ?, owner = ?,
-		    tags = ?, dependencies = ?, blocked_by = ?, updated_at = ?
+		SET title = ?, description = ?, state = ?, held_from = ?, review_failures = review_failures + ?, priority = ?, owner = ?,
+		    tags = ?, dependencies = ?, blocked_by = ?, estimated_hours = ?, due_date = ?, parent_id = ?, custom_fields = ?, updated_at = ?
 		WHERE id = ?
 	`
 
-	result, err := s.db.Exec(query,
-		task.Title, task.Description, task.State, task.Priority, task.Owner,
-		task.Tags, task.Dependencies, task.BlockedBy, task.UpdatedAt, task.ID)
+	result, err := tx.Exec(query,
+		task.Title, task.Description, task.State, task.HeldFrom, reviewFailure(prevState, task.State), task.Priority, task.Owner,
+		task.Tags, task.Dependencies, task.BlockedBy, task.EstimatedHours, utcOrNil(task.DueDate), task.ParentID,
+		customFieldsValue(task.CustomFields), task.UpdatedAt.UTC(), task.ID)
 
 	if err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+		return "", fmt.Errorf("failed to update task: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return "", fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return ErrTaskNotFound
+		return "", ErrTaskNotFound
 	}
 
-	return nil
+	if prevState != task.State {
+		if err := recordStateEntry(tx, task.ID, task.State, task.UpdatedAt); err != nil {
+			return "", fmt.Errorf("failed to record state history: %w", err)
+		}
+	}
+
+	if prev.Title != task.Title || prev.Description != task.Description {
+		next := &TaskRevision{TaskID: task.ID, Title: task.Title, Description: task.Description,
+			Actor: actor, RevertedFrom: revertedFrom, CreatedAt: task.UpdatedAt}
+		if err := recordRevision(tx, &prev, next); err != nil {
+			return "", fmt.Errorf("failed to record task revision: %w", err)
+		}
+	}
+
+	return prevState, nil
+}
+
+// GetTaskBriefing returns the cached briefing for a task
+func (s *Store) GetTaskBriefing(taskID string) (*TaskBriefing, error) {
+	briefing := &TaskBriefing{}
+	err := s.db.QueryRow("SELECT task_id, version, content, created_at FROM task_briefings WHERE task_id = ?", taskID).Scan(
+		&briefing.TaskID, &briefing.Version, &briefing.Content, local(&briefing.CreatedAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return briefing, nil
+}
+
+// SaveTaskBriefing stores the briefing for a task, replacing any previous one
+func (s *Store) SaveTaskBriefing(briefing *TaskBriefing) error {
+	briefing.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO task_briefings (task_id, version, content, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(task_id) DO UPDATE SET version = excluded.version,
+			content = excluded.content, created_at = excluded.created_at
+	`
+
+	_, err := s.db.Exec(query, briefing.TaskID, briefing.Version, briefing.Content, briefing.CreatedAt.UTC())
+	return err
 }
 
 // Error definitions
@@ -0,0 +1,594 @@
+// Package taskcsv exports tasks to CSV and imports them back, so backlogs can
+// be edited in a spreadsheet or moved between workspaces. Tasks exported as
+// JSON import by the same rules.
+//
+// Columns, matched by header name in any order and case:
+//
+//	id               short ID of an existing task to update; any other value is
+//	                 a label other rows can depend on, and a new task is created
+//	title            required for new tasks
+//	description
+//	state            defaults to ready_for_plan; changes must be valid transitions
+//	priority         1-10, defaults to 5
+//	owner
+//	milestone        stored as the milestone:<name> tag
+//	tags             separated by ";"
+//	dependencies     short IDs or labels, separated by ";"
+//	estimated_hours
+//	due_date         YYYY-MM-DD (the end of that day) or RFC 3339
+//
+// followed by one column per configured custom field, named after the field.
+// Empty cells leave an existing task's field unchanged.
+package taskcsv
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"baton/internal/artifactfs"
+	"baton/internal/config"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// Columns are the built-in columns, in the order Export writes them
+var Columns = []string{
+	"id", "title", "description", "state", "priority", "owner",
+	"milestone", "tags", "dependencies", "estimated_hours", "due_date",
+}
+
+// listSeparator separates the values of the tags and dependencies columns
+const listSeparator = ";"
+
+// defaultPriority is the priority of new tasks without one
+const defaultPriority = 5
+
+// Export writes tasks as CSV, with dependencies as short IDs and a column per
+// custom field
+func Export(w io.Writer, tasks []*storage.Task, fields config.CustomFields) error {
+	fieldNames := fields.Names()
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, Columns...), fieldNames...)); err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if err := writer.Write(taskRecord(task, fieldNames)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// taskRecord is a task's cells, in the order of Columns and then fieldNames
+func taskRecord(task *storage.Task, fieldNames []string) []string {
+	var tags []string
+	for _, tag := range task.TagList() {
+		if !strings.HasPrefix(tag, storage.MilestoneTagPrefix) {
+			tags = append(tags, tag)
+		}
+	}
+	var deps []string
+	for _, dep := range task.DependencyList() {
+		deps = append(deps, artifactfs.ShortID(dep))
+	}
+	hours := ""
+	if task.EstimatedHours > 0 {
+		hours = strconv.FormatFloat(task.EstimatedHours, 'f', -1, 64)
+	}
+	due := ""
+	if task.DueDate != nil {
+		due = task.DueDate.Format(time.RFC3339)
+	}
+
+	record := []string{
+		artifactfs.ShortID(task.ID), task.Title, task.Description, string(task.State),
+		strconv.Itoa(task.Priority), task.Owner, task.Milestone(),
+		strings.Join(tags, listSeparator), strings.Join(deps, listSeparator), hours, due,
+	}
+	values := task.CustomFieldMap()
+	for _, name := range fieldNames {
+		record = append(record, formatValue(values[name]))
+	}
+	return record
+}
+
+// Change is a task an import creates or updates
+type Change struct {
+	Line    int           `json:"line"`
+	Create  bool          `json:"create"`
+	Task    *storage.Task `json:"task"`
+	Changed []string      `json:"changed,omitempty"` // columns that differ from the existing task
+}
+
+// RowError is a problem with one row of an import
+type RowError struct {
+	Line    int
+	Message string
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ImportError lists every invalid row of an import
+type ImportError struct {
+	Rows []RowError
+}
+
+func (e *ImportError) Error() string {
+	lines := make([]string, len(e.Rows))
+	for i, row := range e.Rows {
+		lines[i] = row.Error()
+	}
+	return fmt.Sprintf("%d invalid rows:\n  %s", len(e.Rows), strings.Join(lines, "\n  "))
+}
+
+// row is a parsed CSV record
+type row struct {
+	line   int
+	cells  map[string]string
+	change *Change
+}
+
+// Plan reads CSV and works out the changes it makes to existing tasks. It
+// returns an *ImportError listing every invalid row, in which case nothing
+// should be imported.
+func Plan(r io.Reader, existing []*storage.Task, fields config.CustomFields) ([]*Change, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty CSV")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns, err := parseHeader(header, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		cells := make(map[string]string, len(columns))
+		for i, column := range columns {
+			cells[column] = strings.TrimSpace(record[i])
+		}
+		rows = append(rows, &row{line: line, cells: cells})
+	}
+
+	return plan(rows, existing, fields)
+}
+
+// PlanJSON works out the changes made by tasks in the JSON array written by
+// 'baton tasks export --format json'. Each task is read as the CSV row Export
+// would write for it, so the same rules apply: a task whose ID matches an
+// existing task updates it, empty fields leave it unchanged, and any other
+// task is created. A RowError's line is the task's position in the array,
+// from 1.
+func PlanJSON(r io.Reader, existing []*storage.Task, fields config.CustomFields) ([]*Change, error) {
+	var tasks []*storage.Task
+	if err := json.NewDecoder(r).Decode(&tasks); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	fieldNames := fields.Names()
+	columns := append(append([]string{}, Columns...), fieldNames...)
+	rows := make([]*row, len(tasks))
+	for i, task := range tasks {
+		values := taskRecord(task, fieldNames)
+		// Missing IDs and priorities are empty cells, not zero values
+		if task.ID == "" {
+			values[0] = ""
+		}
+		if task.Priority == 0 {
+			values[4] = ""
+		}
+		cells := make(map[string]string, len(columns))
+		for j, column := range columns {
+			cells[column] = strings.TrimSpace(values[j])
+		}
+		rows[i] = &row{line: i + 1, cells: cells}
+	}
+
+	return plan(rows, existing, fields)
+}
+
+// plan applies parsed rows to the existing tasks
+func plan(rows []*row, existing []*storage.Task, fields config.CustomFields) ([]*Change, error) {
+	p := &planner{existing: existing, fields: fields, labels: make(map[string]string)}
+	p.resolveIDs(rows)
+	for _, r := range rows {
+		if r.change != nil {
+			p.apply(r)
+		}
+	}
+	p.checkCycles(rows)
+
+	if len(p.errors) > 0 {
+		sort.SliceStable(p.errors, func(i, j int) bool { return p.errors[i].Line < p.errors[j].Line })
+		return nil, &ImportError{Rows: p.errors}
+	}
+
+	changes := make([]*Change, 0, len(rows))
+	for _, r := range rows {
+		changes = append(changes, r.change)
+	}
+	return changes, nil
+}
+
+// parseHeader maps the header to known column names
+func parseHeader(header []string, fields config.CustomFields) ([]string, error) {
+	known := make(map[string]bool, len(Columns)+len(fields))
+	for _, column := range Columns {
+		known[column] = true
+	}
+	for name := range fields {
+		known[name] = true
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown column %q (columns: %s)", name, strings.Join(append(append([]string{}, Columns...), fields.Names()...), ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	if !seen["id"] && !seen["title"] {
+		return nil, fmt.Errorf("the CSV needs an id or a title column")
+	}
+	return columns, nil
+}
+
+// planner turns rows into changes, collecting row errors
+type planner struct {
+	existing []*storage.Task
+	fields   config.CustomFields
+	labels   map[string]string // id cell of a new task -> its generated ID
+	errors   []RowError
+}
+
+func (p *planner) fail(line int, format string, args ...interface{}) {
+	p.errors = append(p.errors, RowError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// resolveIDs matches each row to the task it updates, or gives it a new ID,
+// before any row is applied so dependencies can point at later rows
+func (p *planner) resolveIDs(rows []*row) {
+	claimed := make(map[string]int) // task ID -> line that updates it
+	for _, r := range rows {
+		id := r.cells["id"]
+		if id == "" {
+			r.change = &Change{Line: r.line, Create: true, Task: &storage.Task{ID: uuid.New().String()}}
+			continue
+		}
+
+		task, err := p.lookup(id)
+		if err != nil {
+			p.fail(r.line, "%v", err)
+			continue
+		}
+		if task == nil {
+			if _, ok := p.labels[id]; ok {
+				p.fail(r.line, "id %q is used by another row", id)
+				continue
+			}
+			p.labels[id] = uuid.New().String()
+			r.change = &Change{Line: r.line, Create: true, Task: &storage.Task{ID: p.labels[id]}}
+			continue
+		}
+		if line, ok := claimed[task.ID]; ok {
+			p.fail(r.line, "task %s is already updated on line %d", id, line)
+			continue
+		}
+		claimed[task.ID] = r.line
+		copied := *task
+		r.change = &Change{Line: r.line, Task: &copied}
+	}
+}
+
+// lookup finds the existing task whose ID starts with id, or nil when there
+// is none
+func (p *planner) lookup(id string) (*storage.Task, error) {
+	var match *storage.Task
+	for _, task := range p.existing {
+		if task.ID == id {
+			return task, nil
+		}
+		if strings.HasPrefix(task.ID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("id %q matches more than one task", id)
+			}
+			match = task
+		}
+	}
+	return match, nil
+}
+
+// resolveDependency returns the task ID a dependencies cell entry refers to
+func (p *planner) resolveDependency(ref string) (string, error) {
+	if id, ok := p.labels[ref]; ok {
+		return id, nil
+	}
+	task, err := p.lookup(ref)
+	if err != nil {
+		return "", err
+	}
+	if task == nil {
+		return "", fmt.Errorf("dependency %q matches no task", ref)
+	}
+	return task.ID, nil
+}
+
+// apply sets the row's cells on its task, recording what changed
+func (p *planner) apply(r *row) {
+	change := r.change
+	task := change.Task
+	cells := r.cells
+	valid := true
+	invalid := func(format string, args ...interface{}) {
+		p.fail(r.line, format, args...)
+		valid = false
+	}
+	set := func(column string, differs bool) {
+		if differs && !change.Create {
+			change.Changed = append(change.Changed, column)
+		}
+	}
+
+	if title := cells["title"]; title != "" {
+		set("title", title != task.Title)
+		task.Title = title
+	} else if change.Create {
+		invalid("title is required for a new task")
+	}
+
+	if description := cells["description"]; description != "" {
+		set("description", description != task.Description)
+		task.Description = description
+	}
+
+	if value := cells["state"]; value != "" {
+		state := storage.NormalizeState(value)
+		if _, ok := statemachine.ValidTransitions[state]; !ok {
+			invalid("invalid state %q", value)
+		} else if !change.Create && state != task.State {
+			if err := statemachine.ValidateTransition(task.State, state); err != nil {
+				invalid("%v", err)
+			}
+		}
+		set("state", state != task.State)
+		task.State = state
+	} else if change.Create {
+		task.State = storage.ReadyForPlan
+	}
+
+	if value := cells["priority"]; value != "" {
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 1 || priority > 10 {
+			invalid("priority must be a number from 1 to 10, got %q", value)
+		}
+		set("priority", priority != task.Priority)
+		task.Priority = priority
+	} else if change.Create {
+		task.Priority = defaultPriority
+	}
+
+	if owner := cells["owner"]; owner != "" {
+		set("owner", owner != task.Owner)
+		task.Owner = owner
+	}
+
+	if value := cells["estimated_hours"]; value != "" {
+		hours, err := strconv.ParseFloat(value, 64)
+		if err != nil || hours < 0 {
+			invalid("estimated_hours must be a non-negative number, got %q", value)
+		}
+		set("estimated_hours", hours != task.EstimatedHours)
+		task.EstimatedHours = hours
+	}
+
+	if value := cells["due_date"]; value != "" {
+		due, err := storage.ParseDueDate(value)
+		if err != nil {
+			invalid("due_date must be YYYY-MM-DD or RFC 3339, got %q", value)
+		}
+		set("due_date", task.DueDate == nil || !due.Equal(*task.DueDate))
+		task.DueDate = &due
+	}
+
+	tags := task.TagList()
+	milestone := task.Milestone()
+	tagsChanged := false
+	if value := cells["tags"]; value != "" {
+		list := splitList(value)
+		set("tags", !sameStrings(list, withoutMilestone(tags)))
+		tags = list
+		tagsChanged = true
+	} else {
+		tags = withoutMilestone(tags)
+	}
+	if value := cells["milestone"]; value != "" {
+		set("milestone", value != milestone)
+		milestone = value
+		tagsChanged = true
+	}
+	if milestone != "" {
+		tags = append(tags, storage.MilestoneTagPrefix+milestone)
+	}
+	if tagsChanged || change.Create {
+		task.Tags = marshalList(tags)
+	}
+
+	if value := cells["dependencies"]; value != "" {
+		var deps []string
+		for _, ref := range splitList(value) {
+			dep, err := p.resolveDependency(ref)
+			if err != nil {
+				invalid("%v", err)
+				continue
+			}
+			if dep == task.ID {
+				invalid("task depends on itself")
+				continue
+			}
+			deps = append(deps, dep)
+		}
+		set("dependencies", !sameStrings(deps, task.DependencyList()))
+		task.Dependencies = marshalList(deps)
+	} else if change.Create {
+		task.Dependencies = marshalList(nil)
+	}
+
+	values := task.CustomFieldMap()
+	for _, name := range p.fields.Names() {
+		text := cells[name]
+		if text == "" {
+			continue
+		}
+		value, err := p.fields[name].ParseValue(text)
+		if err != nil {
+			invalid("%s: %v", name, err)
+			continue
+		}
+		set(name, formatValue(value) != formatValue(values[name]))
+		task.SetCustomField(name, value)
+	}
+
+	if !valid {
+		r.change = nil
+	}
+}
+
+// checkCycles reports rows whose dependencies would make tasks wait on each
+// other forever
+func (p *planner) checkCycles(rows []*row) {
+	planned := make(map[string]bool)
+	for _, r := range rows {
+		if r.change != nil {
+			planned[r.change.Task.ID] = true
+		}
+	}
+
+	var unchanged []*storage.Task
+	for _, task := range p.existing {
+		if !planned[task.ID] {
+			unchanged = append(unchanged, task)
+		}
+	}
+	graph := statemachine.NewDependencyGraph(unchanged)
+	for _, r := range rows {
+		if r.change == nil {
+			continue
+		}
+		task := r.change.Task
+		for _, dep := range task.DependencyList() {
+			if err := graph.CheckEdge(task.ID, dep); err != nil {
+				p.fail(r.line, "%v", err)
+				continue
+			}
+			graph.AddEdge(task.ID, dep)
+		}
+	}
+}
+
+// Apply saves planned changes: new tasks are created in one transaction,
+// then updated tasks are saved in another, so updates can depend on the new
+// tasks and neither half is left partly applied.
+func Apply(store *storage.Store, changes []*Change) error {
+	var created, updated []*storage.Task
+	for _, change := range changes {
+		switch {
+		case change.Create:
+			created = append(created, change.Task)
+		case len(change.Changed) > 0:
+			updated = append(updated, change.Task)
+		}
+	}
+
+	if err := store.CreateTasksBatch(created); err != nil {
+		return err
+	}
+	return store.UpdateTasksBatch(updated, "import", "")
+}
+
+// formatValue writes a custom field value as a cell
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// splitList splits a tags or dependencies cell
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// withoutMilestone returns tags without the milestone tag
+func withoutMilestone(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, storage.MilestoneTagPrefix) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// marshalList encodes a list as a JSON array, never null
+func marshalList(list []string) json.RawMessage {
+	if list == nil {
+		list = []string{}
+	}
+	data, _ := json.Marshal(list)
+	return data
+}
+
+// sameStrings reports whether two lists hold the same values in order
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,336 @@
+package tenant
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"baton/internal/config"
+	"baton/internal/cycle"
+	"baton/internal/llm"
+	"baton/internal/lock"
+	"baton/internal/mcp"
+	"baton/internal/notify"
+	"baton/internal/storage"
+	"baton/internal/web"
+)
+
+// tokenCookie carries a project token set from a ?token= query, so browsers
+// keep access after the first request
+const tokenCookie = "baton_token"
+
+// Options controls how a Host opens its projects
+type Options struct {
+	ReadOnly  bool // serve every project read-only, whatever its config says
+	ForceLock bool // take over workspace locks held by other processes
+}
+
+// Project is one project hosted by a Host. Each project has its own
+// configuration, workspace and database, so no query can reach another
+// project's data.
+type Project struct {
+	Name   string
+	Config *config.Config
+
+	store    *storage.Store
+	lock     *lock.Lock
+	notifier *notify.Notifier
+	web      *web.Server
+	mcp      *mcp.Server
+	token    string
+	webUI    http.Handler
+	mcpHTTP  http.Handler
+}
+
+// Host serves several projects from one HTTP listener. Each project lives
+// under /p/{name}/: its REST API and web UI at the root of that prefix and its
+// MCP server at /p/{name}/mcp. Every request must carry the project's token.
+type Host struct {
+	projects map[string]*Project
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+// NewHost opens every project listed in cfg.Projects. Project config paths
+// are relative to the directory of cfg's own config file.
+func NewHost(cfg *config.Config, opts Options) (*Host, error) {
+	if len(cfg.Projects) == 0 {
+		return nil, fmt.Errorf("no projects configured")
+	}
+
+	baseDir := "."
+	if cfg.ConfigFile != "" {
+		baseDir = filepath.Dir(cfg.ConfigFile)
+	}
+
+	h := &Host{projects: make(map[string]*Project)}
+	workspaces := make(map[string]string)
+	databases := make(map[string]string)
+
+	for _, name := range sortedNames(cfg.Projects) {
+		projectCfg := cfg.Projects[name]
+		path := projectCfg.Config
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		pcfg, err := config.LoadProject(cfg.ConfigFile, path)
+		if err != nil {
+			h.Close()
+			return nil, fmt.Errorf("project %s: %w", name, err)
+		}
+
+		// Isolation rests on each project owning its workspace and database
+		workspace, _ := filepath.Abs(pcfg.Workspace)
+		database, _ := filepath.Abs(pcfg.Database)
+		if other, ok := workspaces[workspace]; ok {
+			h.Close()
+			return nil, fmt.Errorf("projects %s and %s share workspace %s", other, name, pcfg.Workspace)
+		}
+		if other, ok := databases[database]; ok {
+			h.Close()
+			return nil, fmt.Errorf("projects %s and %s share database %s", other, name, pcfg.Database)
+		}
+		workspaces[workspace] = name
+		databases[database] = name
+
+		token := os.Getenv(projectCfg.TokenEnv)
+		if token == "" {
+			h.Close()
+			return nil, fmt.Errorf("project %s: token not set: export %s", name, projectCfg.TokenEnv)
+		}
+
+		project, err := openProject(name, pcfg, token, opts)
+		if err != nil {
+			h.Close()
+			return nil, fmt.Errorf("project %s: %w", name, err)
+		}
+		h.projects[name] = project
+	}
+
+	return h, nil
+}
+
+// openProject locks the project's workspace, opens its database and builds
+// its web and MCP handlers
+func openProject(name string, cfg *config.Config, token string, opts Options) (*Project, error) {
+	workspaceLock, err := lock.Acquire(cfg.Workspace, "serve", opts.ForceLock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire workspace lock: %w", err)
+	}
+
+	key, err := cfg.DatabaseKey()
+	if err != nil {
+		workspaceLock.Release()
+		return nil, err
+	}
+	store, err := storage.OpenStore(cfg.Database, key)
+	if err != nil {
+		workspaceLock.Release()
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	notifier := notify.Attach(store, cfg)
+
+	readOnly := opts.ReadOnly || cfg.Web.ReadOnly
+
+	// The web UI runs without an LLM when the project's client is unavailable
+	var llmClient llm.Client
+	if !readOnly {
+		if client, err := llm.NewClientForMCP(cfg.LLM, cfg.MCPPort); err == nil {
+			llmClient = client
+		} else {
+			log.Printf("Project %s: %v; prompt-based features are disabled", name, err)
+		}
+	}
+
+	webServer := web.NewServer(store, cfg, llmClient)
+	webServer.SetReadOnly(readOnly)
+
+	mcpServer := mcp.NewServer(store, cfg)
+	cycle.ServePrompts(mcpServer, store, cfg)
+
+	return &Project{
+		Name:     name,
+		Config:   cfg,
+		store:    store,
+		lock:     workspaceLock,
+		notifier: notifier,
+		web:      webServer,
+		mcp:      mcpServer,
+		token:    token,
+		webUI:    webServer.Handler(),
+		mcpHTTP:  mcpServer.Mount(prefix(name) + "/mcp"),
+	}, nil
+}
+
+// Projects returns the hosted project names in order
+func (h *Host) Projects() []string {
+	return sortedNames(h.projects)
+}
+
+// ServeHTTP routes /p/{name}/... to the named project after checking its token
+func (h *Host) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "projects": len(h.projects)})
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/p/")
+	if !ok {
+		http.Error(w, "Not found: project paths are /p/{project}/...", http.StatusNotFound)
+		return
+	}
+	name, path, _ := strings.Cut(rest, "/")
+	project, exists := h.projects[name]
+	if !exists {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	path = "/" + path
+	handler := project.webUI
+	isMCP := path == "/mcp" || strings.HasPrefix(path, "/mcp/")
+	if isMCP {
+		path = strings.TrimPrefix(path, "/mcp")
+		if path == "" {
+			path = "/"
+		}
+		handler = project.mcpHTTP
+	}
+
+	if !project.authorize(w, r, isMCP) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="baton"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Hand the project a request as if it were served on its own
+	inner := r.Clone(r.Context())
+	inner.URL.Path = path
+	inner.URL.RawPath = ""
+	handler.ServeHTTP(w, inner)
+}
+
+// authorize accepts the project token as a bearer token. Requests for the
+// web UI may also carry it as a ?token= query, which sets a cookie so a
+// browser need not repeat it, or as that cookie. MCP requests only get in with
+// the bearer token, as on a project's own MCP server, so MCP clients never
+// put the token in a URL.
+func (p *Project) authorize(w http.ResponseWriter, r *http.Request, isMCP bool) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return p.tokenMatches(token)
+	}
+	if isMCP {
+		return false
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		if !p.tokenMatches(token) {
+			return false
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     tokenCookie,
+			Value:    token,
+			Path:     prefix(p.Name),
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		return true
+	}
+	if cookie, err := r.Cookie(tokenCookie); err == nil {
+		return p.tokenMatches(cookie.Value)
+	}
+	return false
+}
+
+// tokenMatches compares in constant time so timing does not leak the token
+func (p *Project) tokenMatches(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(p.token)) == 1
+}
+
+// Start serves every project on port until Stop is called
+func (h *Host) Start(port int) error {
+	h.mu.Lock()
+	if h.server != nil {
+		h.mu.Unlock()
+		return fmt.Errorf("host is already running")
+	}
+	h.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: h,
+	}
+	server := h.server
+	h.mu.Unlock()
+
+	log.Printf("Serving projects %s on port %d", strings.Join(h.Projects(), ", "), port)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Stop shuts the listener down and closes every project
+func (h *Host) Stop() error {
+	h.mu.Lock()
+	server := h.server
+	h.mu.Unlock()
+
+	var err error
+	if server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = server.Shutdown(ctx)
+	}
+
+	h.Close()
+	return err
+}
+
+// Close stops every project's MCP server, closes its database and releases
+// its workspace lock
+func (h *Host) Close() {
+	for _, name := range sortedNames(h.projects) {
+		project := h.projects[name]
+		if err := project.mcp.Stop(); err != nil {
+			log.Printf("Project %s: error stopping MCP server: %v", name, err)
+		}
+		if err := project.web.Stop(); err != nil {
+			log.Printf("Project %s: error stopping web server: %v", name, err)
+		}
+		project.notifier.Close()
+		project.store.Close()
+		project.lock.Release()
+	}
+	h.projects = make(map[string]*Project)
+}
+
+// prefix returns the path a project is served under
+func prefix(name string) string {
+	return "/p/" + name
+}
+
+// sortedNames returns the keys of m in order
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,65 @@
+package tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHTTPTokens(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	host := &Host{projects: map[string]*Project{
+		"payments": {Name: "payments", token: "secret", webUI: ok, mcpHTTP: ok},
+	}}
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		cookie string
+		want   int
+	}{
+		{name: "web bearer", path: "/p/payments/", header: "Bearer secret", want: http.StatusOK},
+		{name: "web query", path: "/p/payments/?token=secret", want: http.StatusOK},
+		{name: "web cookie", path: "/p/payments/", cookie: "secret", want: http.StatusOK},
+		{name: "web wrong token", path: "/p/payments/?token=wrong", want: http.StatusUnauthorized},
+		{name: "web no token", path: "/p/payments/", want: http.StatusUnauthorized},
+		{name: "mcp bearer", path: "/p/payments/mcp", header: "Bearer secret", want: http.StatusOK},
+		{name: "mcp wrong bearer", path: "/p/payments/mcp", header: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "mcp query", path: "/p/payments/mcp?token=secret", want: http.StatusUnauthorized},
+		{name: "mcp sse query", path: "/p/payments/mcp/sse?token=secret", want: http.StatusUnauthorized},
+		{name: "mcp cookie", path: "/p/payments/mcp", cookie: "secret", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: tokenCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			host.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestQueryTokenSetsCookie(t *testing.T) {
+	host := &Host{projects: map[string]*Project{
+		"payments": {Name: "payments", token: "secret", webUI: http.NotFoundHandler()},
+	}}
+
+	rec := httptest.NewRecorder()
+	host.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/p/payments/?token=secret", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != tokenCookie || cookies[0].Path != "/p/payments" {
+		t.Errorf("Expected a %s cookie for /p/payments, got %v", tokenCookie, cookies)
+	}
+}
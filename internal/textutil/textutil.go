@@ -0,0 +1,21 @@
+// Package textutil holds string helpers shared by the packages that build
+// prompts and briefings.
+package textutil
+
+import "unicode/utf8"
+
+// Truncate returns the longest prefix of s that is at most max bytes and ends
+// on a rune boundary, so a multi-byte character is dropped rather than split
+func Truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	if max < 0 {
+		max = 0
+	}
+	i := max
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i]
+}
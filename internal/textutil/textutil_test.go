@@ -0,0 +1,33 @@
+package textutil
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 3, "hel"},
+		{"hello", 0, ""},
+		{"héllo", 2, "h"}, // é is two bytes
+		{"héllo", 3, "hé"},
+		{"日本語", 4, "日"}, // each rune is three bytes
+		{"日本語", 2, ""},
+		{"a→b", 3, "a"},
+	}
+	for _, tt := range tests {
+		got := Truncate(tt.s, tt.max)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("Truncate(%q, %d) = %q is not valid UTF-8", tt.s, tt.max, got)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// handleTaskArtifacts handles GET /api/tasks/{id}/artifacts/{name}/content,
+// which streams an artifact's raw content, the latest version unless
+// ?version= names one. Range requests are supported, so large artifacts
+// can be fetched in parts.
+func (s *Server) handleTaskArtifacts(w http.ResponseWriter, r *http.Request, taskID string, rest []string) {
+	if len(rest) != 2 || rest[0] == "" || rest[1] != "content" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 0 {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+	}
+
+	artifact, content, err := s.store.OpenArtifact(taskID, rest[0], version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Artifact not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to read artifact: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("X-Artifact-Version", strconv.Itoa(artifact.Version))
+	http.ServeContent(w, r, artifact.Name, artifact.CreatedAt, content)
+}
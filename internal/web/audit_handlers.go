@@ -49,7 +49,7 @@ func (s *Server) handleAuditHistory(w http.ResponseWriter, r *http.Request) {
 
 		// Parse commands if available
 		if entry.Commands != nil {
-			json.Unmarshal(entry.Commands, &historyEntry.Commands)
+			historyEntry.Commands = commandSummaries(entry.Commands)
 		}
 
 		// Parse follow-ups if available
@@ -78,4 +78,53 @@ type AuditHistoryEntry struct {
 	InputsSummary  string    `json:"inputs_summary"`
 	OutputsSummary string    `json:"outputs_summary"`
 	CreatedAt      time.Time `json:"created_at"`
-}
\ No newline at end of file
+}
+
+// commandSummaries summarizes the commands of an audit entry, cycle hook
+// results and the commands agents ran, one line each
+func commandSummaries(raw json.RawMessage) []string {
+	var commands []json.RawMessage
+	if err := json.Unmarshal(raw, &commands); err != nil {
+		return nil
+	}
+
+	summaries := make([]string, 0, len(commands))
+	for _, command := range commands {
+		var summary string
+		if json.Unmarshal(command, &summary) == nil {
+			summaries = append(summaries, summary)
+			continue
+		}
+		var result struct {
+			Kind     string   `json:"kind"`
+			Hook     string   `json:"hook"`
+			Command  string   `json:"command"`
+			Args     []string `json:"args"`
+			Allowed  *bool    `json:"allowed"`
+			Success  bool     `json:"success"`
+			ExitCode int      `json:"exit_code"`
+			Error    string   `json:"error"`
+		}
+		if json.Unmarshal(command, &result) != nil {
+			continue
+		}
+		switch {
+		case result.Hook != "":
+			summary = fmt.Sprintf("%s hook %s", result.Kind, result.Hook)
+		default:
+			summary = strings.TrimSpace(result.Command + " " + strings.Join(result.Args, " "))
+		}
+		switch {
+		case result.Allowed != nil && !*result.Allowed:
+			summary += fmt.Sprintf(" (refused: %s)", result.Error)
+		case result.Success:
+			summary += " (ok)"
+		case result.Error != "":
+			summary += fmt.Sprintf(" (failed: %s)", result.Error)
+		default:
+			summary += fmt.Sprintf(" (exit %d)", result.ExitCode)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
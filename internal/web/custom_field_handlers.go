@@ -0,0 +1,107 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"baton/internal/config"
+)
+
+// customFieldParamPrefix marks task list query parameters that filter on a
+// custom field, e.g. field.customer=acme
+const customFieldParamPrefix = "field."
+
+// CustomFieldsRequest sets custom field values on a task; fields not named
+// keep their value and a null value clears the field
+type CustomFieldsRequest struct {
+	Fields map[string]interface{} `json:"fields"`
+}
+
+// handleCustomFields handles GET /api/custom-fields, the configured field definitions
+func (s *Server) handleCustomFields(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fields := s.config.CustomFields
+	if fields == nil {
+		fields = config.CustomFields{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fields)
+}
+
+// handleTaskFields handles PUT /api/tasks/{id}/fields
+func (s *Server) handleTaskFields(w http.ResponseWriter, r *http.Request, taskID string) {
+	if r.Method != "PUT" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CustomFieldsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	values := make(map[string]interface{}, len(req.Fields))
+	for name, value := range req.Fields {
+		checked, err := s.config.CustomFields.Value(name, value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		values[name] = checked
+	}
+
+	// Edits are read-modify-write on the task, so serialize them
+	s.fieldsMux.Lock()
+	defer s.fieldsMux.Unlock()
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	for name, value := range values {
+		task.SetCustomField(name, value)
+	}
+	if err := s.store.UpdateTask(task); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// customFieldFilters reads field.<name> query parameters into task filters
+func (s *Server) customFieldFilters(query url.Values) (map[string]interface{}, error) {
+	var filters map[string]interface{}
+	for param, values := range query {
+		if !strings.HasPrefix(param, customFieldParamPrefix) || len(values) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(param, customFieldParamPrefix)
+		value, err := s.config.CustomFields.Value(name, values[0])
+		if err != nil {
+			return nil, err
+		}
+		if filters == nil {
+			filters = make(map[string]interface{})
+		}
+		filters[name] = value
+	}
+	return filters, nil
+}
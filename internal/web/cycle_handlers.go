@@ -0,0 +1,100 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"baton/internal/cycle"
+)
+
+// CycleReporter reports the cycle a worker in this process is executing
+type CycleReporter interface {
+	CurrentCycle() (*cycle.LiveCycle, bool)
+}
+
+// CycleStarter starts cycles on request, through a worker in this process
+type CycleStarter interface {
+	// StartCycle queues a cycle on taskID, or on the next selected task when
+	// taskID is empty; it fails when the task cannot be started
+	StartCycle(taskID string) error
+}
+
+// StartCycleRequest is the body of POST /api/cycles/start
+type StartCycleRequest struct {
+	TaskID string `json:"task_id,omitempty"`
+}
+
+// CurrentCycleResponse is the body of GET /api/cycles/current
+type CurrentCycleResponse struct {
+	Active  bool             `json:"active"`
+	Tracked bool             `json:"tracked"` // false when no cycle worker runs in this process
+	Cycle   *cycle.LiveCycle `json:"cycle,omitempty"`
+}
+
+// SetCycleReporter lets the server report the in-flight cycle of a worker it runs alongside
+func (s *Server) SetCycleReporter(reporter CycleReporter) {
+	s.cycleReporter = reporter
+}
+
+// SetCycleStarter lets clients trigger cycles on the worker the server runs alongside
+func (s *Server) SetCycleStarter(starter CycleStarter) {
+	s.cycleStarter = starter
+}
+
+// BroadcastCycleOutput pushes agent output streamed by a worker's cycle to
+// connected clients
+func (s *Server) BroadcastCycleOutput(chunk *cycle.OutputChunk) {
+	s.broadcastMessage(WSMessage{
+		Type:      WSMessageTypeCycleOutput,
+		Timestamp: time.Now().Unix(),
+		Data:      chunk,
+	})
+}
+
+// handleCurrentCycle handles GET /api/cycles/current
+func (s *Server) handleCurrentCycle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var response CurrentCycleResponse
+	if s.cycleReporter != nil {
+		response.Tracked = true
+		response.Cycle, response.Active = s.cycleReporter.CurrentCycle()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleStartCycle handles POST /api/cycles/start
+func (s *Server) handleStartCycle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.cycleStarter == nil {
+		http.Error(w, "No cycle worker runs in this process", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req StartCycleRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.cycleStarter.StartCycle(req.TaskID); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"queued": true, "task_id": req.TaskID})
+}
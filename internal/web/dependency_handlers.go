@@ -0,0 +1,167 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"baton/internal/statemachine"
+)
+
+// WSMessageTypeGraphChanged tells the dependency view to refresh its edges
+const WSMessageTypeGraphChanged = "graph_changed"
+
+// DependencyRequest names the task a dependency points at
+type DependencyRequest struct {
+	DependsOn string `json:"depends_on"`
+}
+
+// GraphChangedEvent describes one edge added to or removed from the dependency graph
+type GraphChangedEvent struct {
+	Action       string   `json:"action"` // added or removed
+	TaskID       string   `json:"task_id"`
+	DependsOn    string   `json:"depends_on"`
+	Dependencies []string `json:"dependencies"` // the task's dependencies after the change
+}
+
+// handleTaskDependencies handles POST/DELETE /api/tasks/{id}/dependencies
+func (s *Server) handleTaskDependencies(w http.ResponseWriter, r *http.Request, taskID string) {
+	var req DependencyRequest
+	switch r.Method {
+	case "POST":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	case "DELETE":
+		// DELETE bodies are optional, so the target may come from the query instead
+		req.DependsOn = r.URL.Query().Get("depends_on")
+		if req.DependsOn == "" {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "depends_on is required", http.StatusBadRequest)
+				return
+			}
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.DependsOn == "" {
+		http.Error(w, "depends_on is required", http.StatusBadRequest)
+		return
+	}
+
+	// Edits are read-modify-write on the task, so serialize them
+	s.dependenciesMux.Lock()
+	defer s.dependenciesMux.Unlock()
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	dependencies := task.DependencyList()
+	action := "added"
+	if r.Method == "POST" {
+		if status, err := s.validateDependency(taskID, req.DependsOn); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if containsID(dependencies, req.DependsOn) {
+			http.Error(w, fmt.Sprintf("Task %s already depends on %s", taskID, req.DependsOn), http.StatusConflict)
+			return
+		}
+		dependencies = append(dependencies, req.DependsOn)
+	} else {
+		if !containsID(dependencies, req.DependsOn) {
+			http.Error(w, fmt.Sprintf("Task %s does not depend on %s", taskID, req.DependsOn), http.StatusNotFound)
+			return
+		}
+		dependencies = removeID(dependencies, req.DependsOn)
+		action = "removed"
+	}
+
+	// blocked_by is kept in step with dependencies
+	var blockedBy []string
+	if len(task.BlockedBy) > 0 {
+		json.Unmarshal(task.BlockedBy, &blockedBy)
+	}
+	if action == "added" {
+		if !containsID(blockedBy, req.DependsOn) {
+			blockedBy = append(blockedBy, req.DependsOn)
+		}
+	} else {
+		blockedBy = removeID(blockedBy, req.DependsOn)
+	}
+
+	task.Dependencies, _ = json.Marshal(dependencies)
+	task.BlockedBy, _ = json.Marshal(blockedBy)
+	if err := s.store.UpdateTask(task); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.broadcastMessage(WSMessage{
+		Type:      WSMessageTypeGraphChanged,
+		Timestamp: time.Now().Unix(),
+		Data: GraphChangedEvent{
+			Action:       action,
+			TaskID:       taskID,
+			DependsOn:    req.DependsOn,
+			Dependencies: dependencies,
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// validateDependency checks that the target exists and that depending on it
+// would not create a cycle, returning the HTTP status to report otherwise
+func (s *Server) validateDependency(taskID, dependsOn string) (int, error) {
+	if _, err := s.store.GetTask(dependsOn); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return http.StatusBadRequest, fmt.Errorf("dependency task %s not found", dependsOn)
+		}
+		return http.StatusInternalServerError, fmt.Errorf("failed to get dependency task: %v", err)
+	}
+
+	graph, err := statemachine.LoadDependencyGraph(s.store)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err := graph.CheckEdge(taskID, dependsOn); err != nil {
+		return http.StatusConflict, err
+	}
+	return http.StatusOK, nil
+}
+
+// containsID reports whether ids includes id
+func containsID(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeID returns ids without id, never nil
+func removeID(ids []string, id string) []string {
+	result := []string{}
+	for _, existing := range ids {
+		if existing != id {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// handleTaskResume handles POST /api/tasks/{id}/resume, which returns a
+// blocked or paused task to the state it was held from and answers with it
+func (s *Server) handleTaskResume(w http.ResponseWriter, r *http.Request, taskID string) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := s.store.ResumeTask(taskID, "Resumed via web UI"); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to resume task: %v", err), http.StatusConflict)
+		}
+		return
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
@@ -1,13 +1,14 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
+	"baton/internal/llm"
 	"baton/internal/storage"
 	"baton/internal/statemachine"
 )
@@ -29,6 +30,7 @@ Please provide a JSON response with the following structure:
   "tags": ["tag1", "tag2"],
   "dependencies": [],
   "estimated_complexity": "low|medium|high",
+  "estimated_hours": 4,
   "acceptance_criteria": [
     "Specific, testable criteria"
   ]
@@ -40,6 +42,7 @@ Guidelines:
 - Tags should be relevant technology or domain keywords
 - Dependencies should reference existing task IDs if mentioned
 - Acceptance criteria should be specific and testable
+- Estimated hours is the expected hours of work (1-40)
 
 Respond with ONLY the JSON object, no additional text.`
 
@@ -93,6 +96,7 @@ type TaskCreationResponse struct {
 	Tags               []string `json:"tags"`
 	Dependencies       []string `json:"dependencies"`
 	EstimatedComplexity string   `json:"estimated_complexity"`
+	EstimatedHours     float64  `json:"estimated_hours"`
 	AcceptanceCriteria []string `json:"acceptance_criteria"`
 }
 
@@ -107,8 +111,41 @@ type TaskUpdateResponse struct {
 	UpdateReason string   `json:"update_reason"`
 }
 
+// taskCreationSchema is the JSON schema of TaskCreationResponse
+var taskCreationSchema = llm.Schema{
+	"type":     "object",
+	"required": []string{"title", "description"},
+	"properties": map[string]interface{}{
+		"title":                llm.Schema{"type": "string"},
+		"description":          llm.Schema{"type": "string"},
+		"priority":             llm.Schema{"type": "integer"},
+		"state":                llm.Schema{"type": "string"},
+		"owner":                llm.Schema{"type": "string"},
+		"tags":                 llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+		"dependencies":         llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+		"estimated_complexity": llm.Schema{"type": "string", "enum": []string{"low", "medium", "high"}},
+		"estimated_hours":      llm.Schema{"type": "number"},
+		"acceptance_criteria":  llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+	},
+}
+
+// taskUpdateSchema is the JSON schema of TaskUpdateResponse
+var taskUpdateSchema = llm.Schema{
+	"type":     "object",
+	"required": []string{"update_reason"},
+	"properties": map[string]interface{}{
+		"title":         llm.Schema{"type": []string{"string", "null"}},
+		"description":   llm.Schema{"type": []string{"string", "null"}},
+		"priority":      llm.Schema{"type": []string{"integer", "null"}},
+		"state":         llm.Schema{"type": []string{"string", "null"}},
+		"tags":          llm.Schema{"type": []string{"array", "null"}, "items": llm.Schema{"type": "string"}},
+		"dependencies":  llm.Schema{"type": []string{"array", "null"}, "items": llm.Schema{"type": "string"}},
+		"update_reason": llm.Schema{"type": "string"},
+	},
+}
+
 // createTaskFromPrompt uses LLM to create a task from a natural language prompt
-func (s *Server) createTaskFromPrompt(prompt string, owner string) (*storage.Task, error) {
+func (s *Server) createTaskFromPrompt(ctx context.Context, prompt string, owner string) (*storage.Task, error) {
 	if owner == "" {
 		owner = "system"
 	}
@@ -117,25 +154,9 @@ func (s *Server) createTaskFromPrompt(prompt string, owner string) (*storage.Tas
 	llmPrompt := fmt.Sprintf(taskCreationPrompt, prompt, owner)
 
 	// Call the LLM
-	response, err := s.llmClient.GenerateText(llmPrompt)
-	if err != nil {
-		return nil, fmt.Errorf("LLM call failed: %w", err)
-	}
-
-	// Parse the JSON response
 	var taskResp TaskCreationResponse
-	if err := json.Unmarshal([]byte(response), &taskResp); err != nil {
-		// Try to extract JSON from the response if it's wrapped in other text
-		jsonStart := strings.Index(response, "{")
-		jsonEnd := strings.LastIndex(response, "}") + 1
-		if jsonStart >= 0 && jsonEnd > jsonStart {
-			jsonStr := response[jsonStart:jsonEnd]
-			if err := json.Unmarshal([]byte(jsonStr), &taskResp); err != nil {
-				return nil, fmt.Errorf("failed to parse LLM response: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("failed to parse LLM response: %w", err)
-		}
+	if err := llm.GenerateJSON(ctx, s.llmClient, llmPrompt, taskCreationSchema, &taskResp); err != nil {
+		return nil, fmt.Errorf("LLM call failed: %w", err)
 	}
 
 	// Validate and normalize the response
@@ -166,6 +187,7 @@ func (s *Server) createTaskFromPrompt(prompt string, owner string) (*storage.Tas
 		Owner:        taskResp.Owner,
 		Tags:         tags,
 		Dependencies: deps,
+		EstimatedHours: taskResp.EstimatedHours,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -190,7 +212,7 @@ func (s *Server) createTaskFromPrompt(prompt string, owner string) (*storage.Tas
 }
 
 // updateTaskFromPrompt uses LLM to update a task based on a natural language prompt
-func (s *Server) updateTaskFromPrompt(task *storage.Task, prompt string) (*storage.Task, error) {
+func (s *Server) updateTaskFromPrompt(ctx context.Context, task *storage.Task, prompt string) (*storage.Task, error) {
 	// Parse current tags and dependencies for the prompt
 	var tags []string
 	var deps []string
@@ -217,25 +239,9 @@ func (s *Server) updateTaskFromPrompt(task *storage.Task, prompt string) (*stora
 	)
 
 	// Call the LLM
-	response, err := s.llmClient.GenerateText(llmPrompt)
-	if err != nil {
-		return nil, fmt.Errorf("LLM call failed: %w", err)
-	}
-
-	// Parse the JSON response
 	var updateResp TaskUpdateResponse
-	if err := json.Unmarshal([]byte(response), &updateResp); err != nil {
-		// Try to extract JSON from the response if it's wrapped in other text
-		jsonStart := strings.Index(response, "{")
-		jsonEnd := strings.LastIndex(response, "}") + 1
-		if jsonStart >= 0 && jsonEnd > jsonStart {
-			jsonStr := response[jsonStart:jsonEnd]
-			if err := json.Unmarshal([]byte(jsonStr), &updateResp); err != nil {
-				return nil, fmt.Errorf("failed to parse LLM response: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("failed to parse LLM response: %w", err)
-		}
+	if err := llm.GenerateJSON(ctx, s.llmClient, llmPrompt, taskUpdateSchema, &updateResp); err != nil {
+		return nil, fmt.Errorf("LLM call failed: %w", err)
 	}
 
 	// Create updated task
@@ -258,8 +264,7 @@ func (s *Server) updateTaskFromPrompt(task *storage.Task, prompt string) (*stora
 		newState := storage.NormalizeState(*updateResp.State)
 		if newState != "" {
 			// Validate state transition
-			validator := statemachine.NewTransitionValidator(s.store)
-			if validator.IsValidTransition(task.State, newState) {
+			if statemachine.ValidateTransition(task.State, newState) == nil {
 				updatedTask.State = newState
 			} else {
 				return nil, fmt.Errorf("invalid state transition from %s to %s", task.State, newState)
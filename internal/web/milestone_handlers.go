@@ -0,0 +1,99 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"baton/internal/report"
+	"baton/internal/statemachine"
+	"baton/internal/storage"
+)
+
+// DefaultWebSignoff is who signs off a milestone when a web request names no one
+const DefaultWebSignoff = "web"
+
+// MilestoneSignoffRequest is the body of POST /api/milestones/{name}/signoff
+type MilestoneSignoffRequest struct {
+	SignedOffBy string `json:"signed_off_by"`
+	Note        string `json:"note"`
+}
+
+// MilestoneResponse is the body of GET /api/milestones/{name}
+type MilestoneResponse struct {
+	*statemachine.MilestoneProgress
+	Summary *storage.Artifact `json:"summary,omitempty"` // the milestone_summary artifact, once finished
+}
+
+// handleMilestones handles GET /api/milestones
+func (s *Server) handleMilestones(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	milestones, err := statemachine.NewTaskSelector(s.store, &s.config.Selection).ListMilestones()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list milestones: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(milestones)
+}
+
+// handleMilestoneByName handles GET /api/milestones/{name} and
+// POST /api/milestones/{name}/signoff
+func (s *Server) handleMilestoneByName(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/milestones/")
+	name, action, _ := strings.Cut(path, "/")
+	if name == "" {
+		http.Error(w, "Milestone name required", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == "GET":
+	case action == "signoff" && r.Method == "POST":
+		var req MilestoneSignoffRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		if req.SignedOffBy == "" {
+			req.SignedOffBy = DefaultWebSignoff
+		}
+		if _, err := report.SignOffMilestone(s.store, name, req.SignedOffBy, req.Note, s.config.Acceptance.MandatoryTypes); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	case action == "" || action == "signoff":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	// Both methods answer with the milestone as it now is
+	progress, err := statemachine.NewTaskSelector(s.store, &s.config.Selection).GetMilestoneProgress(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get milestone progress: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if progress == nil {
+		http.Error(w, "Milestone not found", http.StatusNotFound)
+		return
+	}
+	summary, err := report.GetMilestoneSummary(s.store, name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get milestone summary: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MilestoneResponse{MilestoneProgress: progress, Summary: summary})
+}
@@ -0,0 +1,134 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"baton/internal/storage"
+)
+
+// DefaultWebNoteAuthor is who wrote a note when a web request names no one
+const DefaultWebNoteAuthor = "web"
+
+// TaskNoteRequest is the body of POST /api/tasks/{id}/notes and
+// PUT /api/tasks/{id}/notes/{noteId}
+type TaskNoteRequest struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+}
+
+// handleTaskNotes handles GET/POST /api/tasks/{id}/notes and
+// PUT/DELETE /api/tasks/{id}/notes/{noteId}
+func (s *Server) handleTaskNotes(w http.ResponseWriter, r *http.Request, taskID string, rest []string) {
+	if _, err := s.store.GetTask(taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == "GET":
+		s.listTaskNotes(w, taskID)
+	case len(rest) == 0 && r.Method == "POST":
+		s.addTaskNote(w, r, taskID)
+	case len(rest) == 1 && (r.Method == "PUT" || r.Method == "DELETE"):
+		s.changeTaskNote(w, r, taskID, rest[0])
+	case len(rest) <= 1:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// listTaskNotes answers with a task's notes, oldest first
+func (s *Server) listTaskNotes(w http.ResponseWriter, taskID string) {
+	notes, err := s.store.ListTaskNotes(taskID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list notes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if notes == nil {
+		notes = []*storage.TaskNote{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+// addTaskNote records a note on a task
+func (s *Server) addTaskNote(w http.ResponseWriter, r *http.Request, taskID string) {
+	var req TaskNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		http.Error(w, "body is required", http.StatusBadRequest)
+		return
+	}
+	if req.Author == "" {
+		req.Author = DefaultWebNoteAuthor
+	}
+
+	note := &storage.TaskNote{TaskID: taskID, Author: req.Author, Body: req.Body}
+	if err := s.store.AddTaskNote(note); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to add note: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+// changeTaskNote edits or deletes one of a task's notes
+func (s *Server) changeTaskNote(w http.ResponseWriter, r *http.Request, taskID, noteID string) {
+	note, err := s.store.GetTaskNote(noteID)
+	if err == nil && note.TaskID != taskID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Note not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get note: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if r.Method == "DELETE" {
+		if err := s.store.DeleteTaskNote(noteID); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to delete note: %v", err), http.StatusInternalServerError)
+			return
+		}
+		// Answer with the notes left, as unwatching does with watchers
+		s.listTaskNotes(w, taskID)
+		return
+	}
+
+	var req TaskNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		http.Error(w, "body is required", http.StatusBadRequest)
+		return
+	}
+
+	note.Body = req.Body
+	if err := s.store.UpdateTaskNote(note); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update note: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
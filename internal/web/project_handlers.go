@@ -0,0 +1,33 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"baton/internal/storage"
+)
+
+// ProjectsResponse lists the database's projects and the one this server serves
+type ProjectsResponse struct {
+	Current  string             `json:"current"`
+	Projects []*storage.Project `json:"projects"`
+}
+
+// handleProjects handles GET /api/projects. A server serves only the project
+// it was started in, but every project is listed.
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projects, err := s.store.ListProjects()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list projects: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ProjectsResponse{Current: s.store.Project(), Projects: projects})
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"baton/internal/storage"
+)
+
+// DefaultWebActor is who a revert made through the web API is attributed to
+// when the request names no one
+const DefaultWebActor = "web"
+
+// TaskRevisionEntry is a task revision with the change from the one before it
+type TaskRevisionEntry struct {
+	*storage.TaskRevision
+	DescriptionDiff string `json:"description_diff,omitempty"` // line diff of the description against the previous revision
+}
+
+// RevertRequest names who is reverting a task
+type RevertRequest struct {
+	Actor string `json:"actor"`
+}
+
+// handleTaskRevisions handles GET /api/tasks/{id}/revisions and
+// POST /api/tasks/{id}/revisions/{revision}/revert
+func (s *Server) handleTaskRevisions(w http.ResponseWriter, r *http.Request, taskID string, rest []string) {
+	if _, err := s.store.GetTask(taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == "GET":
+		s.listTaskRevisions(w, taskID)
+	case len(rest) == 2 && rest[1] == "revert" && r.Method == "POST":
+		revision, err := strconv.Atoi(rest[0])
+		if err != nil {
+			http.Error(w, "Invalid revision", http.StatusBadRequest)
+			return
+		}
+		s.revertTask(w, r, taskID, revision)
+	case len(rest) == 0 || (len(rest) == 2 && rest[1] == "revert"):
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// listTaskRevisions answers with a task's revisions, oldest first
+func (s *Server) listTaskRevisions(w http.ResponseWriter, taskID string) {
+	revisions, err := s.store.ListTaskRevisions(taskID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list revisions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]TaskRevisionEntry, len(revisions))
+	for i, rev := range revisions {
+		entries[i].TaskRevision = rev
+		if i > 0 && revisions[i-1].Description != rev.Description {
+			entries[i].DescriptionDiff = storage.DiffText(revisions[i-1].Description, rev.Description)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// revertTask restores a revision's title and description
+func (s *Server) revertTask(w http.ResponseWriter, r *http.Request, taskID string, revision int) {
+	var req RevertRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Actor == "" {
+		req.Actor = DefaultWebActor
+	}
+
+	task, err := s.store.RevertTask(taskID, revision, req.Actor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
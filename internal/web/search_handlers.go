@@ -0,0 +1,66 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"baton/internal/search"
+	"baton/internal/storage"
+)
+
+// handleSearch handles GET /api/search?q=...&kind=&state=&owner=&mode=&limit=
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := params.Get("q")
+	if query == "" {
+		http.Error(w, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+
+	filters := storage.SearchFilters{Kind: params.Get("kind"), Owner: params.Get("owner")}
+	if stateStr := params.Get("state"); stateStr != "" {
+		state := storage.NormalizeState(stateStr)
+		filters.State = &state
+	}
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filters.Limit = limit
+	}
+
+	mode := params.Get("mode")
+	var embedder search.Embedder
+	if mode == search.ModeSemantic {
+		// Embedding the query may call an API, which observers must not trigger
+		if s.readOnly {
+			http.Error(w, "Semantic search is not available in read-only mode", http.StatusForbidden)
+			return
+		}
+		var err error
+		if embedder, err = search.NewEmbedder(s.config.Search); err != nil {
+			http.Error(w, "Semantic search is not available: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	hits, err := search.NewSearcher(s.store, embedder).Search(query, mode, filters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if hits == nil {
+		hits = []*storage.SearchHit{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hits)
+}
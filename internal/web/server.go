@@ -2,10 +2,13 @@ package web
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,8 +17,12 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/rs/cors"
 
+	"baton/internal/briefing"
 	"baton/internal/config"
+	"baton/internal/hooks"
 	"baton/internal/llm"
+	"baton/internal/plan"
+	"baton/internal/report"
 	"baton/internal/storage"
 	"baton/internal/statemachine"
 )
@@ -31,11 +38,21 @@ type Server struct {
 	wsClientsMux  sync.RWMutex
 	running       bool
 	runningMux    sync.RWMutex
+	routes        map[string]http.Handler
+	cycleReporter CycleReporter
+	cycleStarter  CycleStarter
+	readOnly      bool
+
+	// dependenciesMux serializes dependency edits, which read and rewrite the task
+	dependenciesMux sync.Mutex
+	// fieldsMux serializes custom field edits in the same way
+	fieldsMux sync.Mutex
 }
 
-// NewServer creates a new web server
+// NewServer creates a new web server. It subscribes to the store's events to
+// push task changes to WebSocket clients.
 func NewServer(store *storage.Store, config *config.Config, llmClient llm.Client) *Server {
-	return &Server{
+	s := &Server{
 		store:     store,
 		config:    config,
 		llmClient: llmClient,
@@ -45,18 +62,42 @@ func NewServer(store *storage.Store, config *config.Config, llmClient llm.Client
 			},
 		},
 		wsClients: make(map[*websocket.Conn]bool),
+		routes:    make(map[string]http.Handler),
 	}
+	store.Subscribe(s.handleStoreEvent)
+	return s
 }
 
-// Start starts the web server
-func (s *Server) Start(port int) error {
-	s.runningMux.Lock()
-	defer s.runningMux.Unlock()
+// Handle registers an additional route, e.g. health checks; call before Start
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.routes[pattern] = handler
+}
 
-	if s.running {
-		return fmt.Errorf("web server is already running")
-	}
+// SetReadOnly puts the server in observer mode: mutating endpoints are
+// rejected and no LLM calls are made, while reads and WebSocket updates work
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.readOnly = readOnly
+}
+
+// readOnlyGuard rejects requests that could change state when the server is read-only
+func (s *Server) readOnlyGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.readOnly {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				http.Error(w, "Server is in read-only mode", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
+// Handler returns the server's routes wrapped in CORS and the read-only
+// guard. Start serves it on its own port; a multi-project host mounts it
+// under a project prefix instead.
+func (s *Server) Handler() http.Handler {
 	// Create CORS handler
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://127.0.0.1:3000"},
@@ -74,14 +115,38 @@ func (s *Server) Start(port int) error {
 	mux.HandleFunc("/api/tasks/create", s.handleCreateTask)
 	mux.HandleFunc("/api/tasks/update", s.handleUpdateTask)
 	mux.HandleFunc("/api/audit/", s.handleAuditHistory)
+	mux.HandleFunc("/api/cycles/current", s.handleCurrentCycle)
+	mux.HandleFunc("/api/cycles/start", s.handleStartCycle)
 	mux.HandleFunc("/api/ws", s.handleWebSocket)
 	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/status/owners", s.handleOwnerStatus)
+	mux.HandleFunc("/api/custom-fields", s.handleCustomFields)
+	mux.HandleFunc("/api/milestones", s.handleMilestones)
+	mux.HandleFunc("/api/milestones/", s.handleMilestoneByName)
+	mux.HandleFunc("/api/search", s.handleSearch)
+	mux.HandleFunc("/api/projects", s.handleProjects)
+
+	for pattern, handler := range s.routes {
+		mux.Handle(pattern, handler)
+	}
 
 	// Static file serving for the Next.js app
 	fs := http.FileServer(http.Dir("./web/dist"))
 	mux.Handle("/", fs)
 
-	handler := c.Handler(mux)
+	return c.Handler(s.readOnlyGuard(mux))
+}
+
+// Start starts the web server
+func (s *Server) Start(port int) error {
+	s.runningMux.Lock()
+
+	if s.running {
+		s.runningMux.Unlock()
+		return fmt.Errorf("web server is already running")
+	}
+
+	handler := s.Handler()
 
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
@@ -89,9 +154,16 @@ func (s *Server) Start(port int) error {
 	}
 
 	s.running = true
+	server := s.server
+
+	// Release the lock while serving so Stop can shut the server down
+	s.runningMux.Unlock()
 
 	log.Printf("Web server starting on port %d", port)
-	return s.server.ListenAndServe()
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
 
 // Stop stops the web server
@@ -132,9 +204,12 @@ type TaskResponse struct {
 	Owner        string                 `json:"owner"`
 	Tags         []string               `json:"tags"`
 	Dependencies []string               `json:"dependencies"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+	ParentID     string                 `json:"parent_id,omitempty"`
+	Archived     bool                   `json:"archived,omitempty"`
 	CreatedAt    time.Time              `json:"created_at"`
 	UpdatedAt    time.Time              `json:"updated_at"`
-	Artifacts    []storage.Artifact     `json:"artifacts,omitempty"`
+	Artifacts    []*storage.Artifact    `json:"artifacts,omitempty"`
 }
 
 // handleTasks handles GET /api/tasks
@@ -160,12 +235,47 @@ func (s *Server) getTasks(w http.ResponseWriter, r *http.Request) {
 			filters.Priority = &p
 		}
 	}
+	filters.Tags = r.URL.Query()["tag"]
+	customFields, err := s.customFieldFilters(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.CustomFields = customFields
+	// Archived tasks are listed only when asked for, by themselves
+	filters.ArchivedOnly = r.URL.Query().Get("archived") == "true"
+	if err := pageFilters(r.URL.Query(), &filters); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	tasks, err := s.store.ListTasks(filters)
+	page, err := s.store.ListTaskPage(filters)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get tasks: %v", err), http.StatusInternalServerError)
 		return
 	}
+	tasks := page.Tasks
+
+	// The body stays a plain list; paging details travel in headers
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+
+	// With tree=true subtasks are nested under their parents
+	if r.URL.Query().Get("tree") == "true" {
+		tree := storage.BuildTaskTree(tasks)
+		if tree == nil {
+			tree = []*storage.TaskNode{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tree)
+		return
+	}
 
 	// Convert to response format
 	var response []TaskResponse
@@ -177,6 +287,9 @@ func (s *Server) getTasks(w http.ResponseWriter, r *http.Request) {
 			State:        string(task.State),
 			Priority:     task.Priority,
 			Owner:        task.Owner,
+			CustomFields: task.CustomFieldMap(),
+			ParentID:     task.ParentID,
+			Archived:     task.Archived,
 			CreatedAt:    task.CreatedAt,
 			UpdatedAt:    task.UpdatedAt,
 		}
@@ -196,32 +309,160 @@ func (s *Server) getTasks(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleTaskByID handles GET/PUT/DELETE /api/tasks/{id}
+// pageFilters reads the sort, reverse, limit, offset and cursor query
+// parameters of a task listing into filters
+func pageFilters(params url.Values, filters *storage.TaskFilters) error {
+	filters.Sort = params.Get("sort")
+	if err := storage.CheckTaskSort(filters.Sort); err != nil {
+		return err
+	}
+	filters.Reverse = params.Get("reverse") == "true"
+	filters.Cursor = params.Get("cursor")
+	for name, dst := range map[string]*int{"limit": &filters.Limit, "offset": &filters.Offset} {
+		if value := params.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s", name)
+			}
+			*dst = n
+		}
+	}
+	return nil
+}
+
+// handleTaskByID handles GET/PUT/DELETE /api/tasks/{id} and its sub-resources
 func (s *Server) handleTaskByID(w http.ResponseWriter, r *http.Request) {
 	// Extract task ID from path
 	path := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
-	taskID := strings.Split(path, "/")[0]
+	parts := strings.Split(path, "/")
+	taskID := parts[0]
 
 	if taskID == "" {
 		http.Error(w, "Task ID is required", http.StatusBadRequest)
 		return
 	}
 
+	if len(parts) > 1 && parts[1] == "explain" {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.explainTask(w, r, taskID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "dependencies" {
+		s.handleTaskDependencies(w, r, taskID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "watchers" {
+		s.handleTaskWatchers(w, r, taskID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "fields" {
+		s.handleTaskFields(w, r, taskID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "children" {
+		s.handleTaskChildren(w, r, taskID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "parent" {
+		s.handleTaskParent(w, r, taskID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "notes" {
+		s.handleTaskNotes(w, r, taskID, parts[2:])
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "artifacts" {
+		s.handleTaskArtifacts(w, r, taskID, parts[2:])
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "time-in-state" {
+		s.handleTaskTimeInState(w, r, taskID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "revisions" {
+		s.handleTaskRevisions(w, r, taskID, parts[2:])
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "restore" {
+		s.handleTaskRestore(w, r, taskID)
+		return
+	}
+
+	if len(parts) > 1 && parts[1] == "resume" {
+		s.handleTaskResume(w, r, taskID)
+		return
+	}
+
 	switch r.Method {
 	case "GET":
 		s.getTask(w, taskID)
 	case "PUT":
 		s.updateTaskState(w, r, taskID)
+	case "DELETE":
+		s.deleteTask(w, taskID)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// explainTask handles GET /api/tasks/{id}/explain and returns an LLM briefing for the task
+func (s *Server) explainTask(w http.ResponseWriter, r *http.Request, taskID string) {
+	if _, err := s.store.GetTask(taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	briefer := briefing.NewBriefer(s.store, s.llmClient)
+
+	// Observers only see briefings someone else has already paid for
+	if s.readOnly {
+		result, err := briefer.Cached(taskID)
+		if errors.Is(err, briefing.ErrNotCached) {
+			http.Error(w, "No current briefing for this task; read-only mode does not generate briefings", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to explain task: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+	result, err := briefer.Explain(taskID, refresh)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to explain task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // getTask returns a single task with artifacts
 func (s *Server) getTask(w http.ResponseWriter, taskID string) {
 	task, err := s.store.GetTask(taskID)
 	if err != nil {
-		if err == storage.ErrTaskNotFound {
+		if errors.Is(err, sql.ErrNoRows) {
 			http.Error(w, "Task not found", http.StatusNotFound)
 		} else {
 			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
@@ -233,19 +474,22 @@ func (s *Server) getTask(w http.ResponseWriter, taskID string) {
 	artifacts, err := s.store.ListArtifacts(taskID)
 	if err != nil {
 		log.Printf("Failed to get artifacts for task %s: %v", taskID, err)
-		artifacts = []storage.Artifact{} // Continue without artifacts
+		artifacts = []*storage.Artifact{} // Continue without artifacts
 	}
 
 	taskResp := TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		State:       string(task.State),
-		Priority:    task.Priority,
-		Owner:       task.Owner,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
-		Artifacts:   artifacts,
+		ID:           task.ID,
+		Title:        task.Title,
+		Description:  task.Description,
+		State:        string(task.State),
+		Priority:     task.Priority,
+		Owner:        task.Owner,
+		CustomFields: task.CustomFieldMap(),
+		ParentID:     task.ParentID,
+		Archived:     task.Archived,
+		CreatedAt:    task.CreatedAt,
+		UpdatedAt:    task.UpdatedAt,
+		Artifacts:    artifacts,
 	}
 
 	// Parse JSON fields
@@ -260,6 +504,52 @@ func (s *Server) getTask(w http.ResponseWriter, taskID string) {
 	json.NewEncoder(w).Encode(taskResp)
 }
 
+// UpdateTaskStateRequest represents a direct state change request from the board
+type UpdateTaskStateRequest struct {
+	State       string `json:"state"`
+	Note        string `json:"note,omitempty"`
+	OverrideWIP bool   `json:"override_wip,omitempty"` // move the task even if its new state is at its WIP limit
+}
+
+// updateTaskState handles PUT /api/tasks/{id}
+func (s *Server) updateTaskState(w http.ResponseWriter, r *http.Request, taskID string) {
+	var req UpdateTaskStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.State == "" {
+		http.Error(w, "State is required", http.StatusBadRequest)
+		return
+	}
+
+	validator := statemachine.NewTransitionValidator(s.store)
+	validator.SetArtifactSchemas(s.config.ArtifactSchemas)
+	validator.SetTransitionRules(s.config.TransitionRules)
+	validator.SetHooks(hooks.NewRunner(s.config.Hooks, s.config.Workspace))
+	if !req.OverrideWIP {
+		validator.SetWIPLimits(s.config.Selection.WIPLimits)
+	}
+	validator.SetReviewEscalation(s.config.ReviewEscalation)
+	if err := validator.ValidateAndTransition(taskID, storage.NormalizeState(req.State), req.Note); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update task state: %v", err), http.StatusBadRequest)
+		return
+	}
+	if _, err := report.RecordMilestoneSummary(s.store, taskID, s.config.Acceptance.MandatoryTypes); err != nil {
+		log.Printf("Failed to summarize the milestone of task %s: %v", taskID, err)
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
 // CreateTaskRequest represents a request to create a new task via LLM prompt
 type CreateTaskRequest struct {
 	Prompt string `json:"prompt"`
@@ -285,7 +575,7 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Use LLM to analyze the prompt and create task details
-	task, err := s.createTaskFromPrompt(req.Prompt, req.Owner)
+	task, err := s.createTaskFromPrompt(r.Context(), req.Prompt, req.Owner)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to create task: %v", err), http.StatusInternalServerError)
 		return
@@ -297,9 +587,6 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Broadcast update via WebSocket
-	s.broadcastTaskUpdate("created", task)
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(task)
@@ -341,30 +628,36 @@ func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Use LLM to analyze the prompt and update task
-	updatedTask, err := s.updateTaskFromPrompt(task, req.Prompt)
+	updatedTask, err := s.updateTaskFromPrompt(r.Context(), task, req.Prompt)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update task: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Save the updated task
-	if err := s.store.UpdateTask(updatedTask); err != nil {
+	if err := s.store.UpdateTaskBy(updatedTask, "llm"); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to save task: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Broadcast update via WebSocket
-	s.broadcastTaskUpdate("updated", updatedTask)
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedTask)
 }
 
 // StatusResponse represents the current system status
 type StatusResponse struct {
-	TasksByState   map[string]int `json:"tasks_by_state"`
-	TotalTasks     int            `json:"total_tasks"`
-	RecentActivity []AuditEntry   `json:"recent_activity"`
+	TasksByState   map[string]int            `json:"tasks_by_state"`
+	TotalTasks     int                       `json:"total_tasks"`
+	RecentActivity []AuditEntry              `json:"recent_activity"`
+	ReadOnly       bool                      `json:"read_only"` // lets the UI hide editing controls
+	AreaLocks      []*storage.AreaLock       `json:"area_locks,omitempty"`
+	Plan           *plan.Status              `json:"plan,omitempty"`       // unavailable means agents work degraded
+	Timezone       string                    `json:"timezone"`             // the zone timestamps are given in, from the configuration
+	Project        string                    `json:"project"`              // the project the server serves
+	StaleTasks     []*statemachine.StaleTask `json:"stale_tasks"`          // stuck in a work state, longest first
+	OverdueTasks   []*statemachine.OverdueTask `json:"overdue_tasks"`      // open past their due date, most overdue first
+	WIPLimits      map[string]int            `json:"wip_limits,omitempty"` // most tasks a state may hold, by state
+	CycleCosts     *storage.RecentCycleCosts `json:"cycle_costs,omitempty"` // what today's and the last week's cycles cost
 }
 
 type AuditEntry struct {
@@ -400,6 +693,9 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		storage.NeedsFixes,
 		storage.Fixing,
 		storage.Done,
+		storage.Blocked,
+		storage.Paused,
+		storage.Cancelled,
 	} {
 		count, err := s.store.GetTaskCount(storage.TaskFilters{State: &state})
 		if err != nil {
@@ -407,26 +703,89 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		tasksByState[string(state)] = count
-		totalTasks += count
+		if state != storage.Cancelled { // left out of the total, as archived tasks are
+			totalTasks += count
+		}
 	}
 
 	// Get recent audit entries (last 10)
-	recentActivity, err := s.store.GetRecentAuditEntries(10)
+	recentActivity := []AuditEntry{}
+	entries, err := s.store.GetRecentAuditEntries(10)
 	if err != nil {
 		log.Printf("Failed to get recent audit entries: %v", err)
-		recentActivity = []AuditEntry{}
+	}
+	for _, entry := range entries {
+		recentActivity = append(recentActivity, AuditEntry{
+			ID:        entry.ID,
+			TaskID:    entry.TaskID,
+			TaskTitle: entry.SelectionReason, // GetRecentAuditEntries carries the task title here
+			PrevState: entry.PrevState,
+			NextState: entry.NextState,
+			Actor:     entry.Actor,
+			CreatedAt: entry.CreatedAt,
+		})
 	}
 
 	response := StatusResponse{
 		TasksByState:   tasksByState,
 		TotalTasks:     totalTasks,
 		RecentActivity: recentActivity,
+		ReadOnly:       s.readOnly,
+		Timezone:       s.config.Timezone,
+		Project:        s.store.Project(),
+		WIPLimits:      s.config.Selection.WIPLimits,
+	}
+	if s.config.Selection.AreaLocks.Enabled {
+		if response.AreaLocks, err = s.store.ListAreaLocks(); err != nil {
+			log.Printf("Failed to list area locks: %v", err)
+		}
+	}
+	if s.config.PlanFile != "" {
+		response.Plan = plan.CheckStatus(s.config.PlanFile)
+	}
+	if response.StaleTasks, err = statemachine.FindStaleTasks(s.store, s.config.Staleness, time.Now()); err != nil {
+		log.Printf("Failed to find stale tasks: %v", err)
+	}
+	if response.OverdueTasks, err = statemachine.FindOverdueTasks(s.store, time.Now()); err != nil {
+		log.Printf("Failed to find overdue tasks: %v", err)
+	}
+	if response.CycleCosts, err = s.store.GetRecentCycleCosts(time.Now()); err != nil {
+		log.Printf("Failed to get cycle costs: %v", err)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleOwnerStatus handles GET /api/status/owners?window=168h
+func (s *Server) handleOwnerStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	window := 7 * 24 * time.Hour
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		parsed, err := time.ParseDuration(windowStr)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid window duration", http.StatusBadRequest)
+			return
+		}
+		window = parsed
+	}
+
+	selector := statemachine.NewTaskSelector(s.store, &s.config.Selection)
+	selector.SetAgentCoverage(statemachine.AgentCoverage(s.config))
+	report, err := selector.GetOwnerWorkload(s.config, time.Now().Add(-window))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get owner workload: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 // IsRunning returns whether the server is currently running
 func (s *Server) IsRunning() bool {
 	s.runningMux.RLock()
@@ -0,0 +1,32 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleTaskTimeInState handles GET /api/tasks/{id}/time-in-state, which
+// answers with how long the task spent in each state it has been in
+func (s *Server) handleTaskTimeInState(w http.ResponseWriter, r *http.Request, taskID string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	timeInState, err := s.store.GetTimeInState(taskID, time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get state history: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timeInState)
+}
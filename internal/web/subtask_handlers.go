@@ -0,0 +1,83 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"baton/internal/storage"
+)
+
+// ParentRequest is the body of PUT /api/tasks/{id}/parent; an empty
+// parent_id makes the task a top-level task
+type ParentRequest struct {
+	ParentID string `json:"parent_id"`
+}
+
+// handleTaskChildren handles GET /api/tasks/{id}/children
+func (s *Server) handleTaskChildren(w http.ResponseWriter, r *http.Request, taskID string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := s.store.GetTask(taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	children, err := s.store.ListChildTasks(taskID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list subtasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if children == nil {
+		children = []*storage.Task{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(children)
+}
+
+// handleTaskParent handles PUT /api/tasks/{id}/parent
+func (s *Server) handleTaskParent(w http.ResponseWriter, r *http.Request, taskID string) {
+	if r.Method != "PUT" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ParentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.store.GetTask(taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	// Unknown parents and cycles are the caller's mistake
+	if err := s.store.SetTaskParent(taskID, req.ParentID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"baton/internal/storage"
+)
+
+// deleteTask handles DELETE /api/tasks/{id}, which moves the task to the
+// trash and answers with it
+func (s *Server) deleteTask(w http.ResponseWriter, taskID string) {
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := s.store.DeleteTask(taskID); err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to delete task: %v", err), http.StatusConflict)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// handleTaskRestore handles POST /api/tasks/{id}/restore, which takes a
+// deleted task out of the trash and answers with it
+func (s *Server) handleTaskRestore(w http.ResponseWriter, r *http.Request, taskID string) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.store.RestoreTask(taskID); err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			http.Error(w, "Task not found in the trash", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to restore task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	task, err := s.store.GetTask(taskID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"baton/internal/notify"
+	"baton/internal/storage"
+)
+
+// DefaultWebWatcher is the watcher name used when a web request names none
+const DefaultWebWatcher = "web"
+
+// WatchRequest subscribes a watcher to a task
+type WatchRequest struct {
+	Watcher string `json:"watcher"`
+	Channel string `json:"channel"` // "" for notifications.default_channel
+}
+
+// handleTaskWatchers handles GET/POST/DELETE /api/tasks/{id}/watchers
+func (s *Server) handleTaskWatchers(w http.ResponseWriter, r *http.Request, taskID string) {
+	if _, err := s.store.GetTask(taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Task not found", http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("Failed to get task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var req WatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Watcher == "" {
+			req.Watcher = DefaultWebWatcher
+		}
+		if _, err := notify.NewNotifier(s.store, s.config.Notifications).Channel(req.Channel); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		watch := &storage.TaskWatch{TaskID: taskID, Watcher: req.Watcher, Channel: req.Channel}
+		if err := s.store.WatchTask(watch); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to watch task: %v", err), http.StatusInternalServerError)
+			return
+		}
+	case "DELETE":
+		watcher := r.URL.Query().Get("watcher")
+		if watcher == "" {
+			watcher = DefaultWebWatcher
+		}
+		removed, err := s.store.UnwatchTask(taskID, watcher)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to unwatch task: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !removed {
+			http.Error(w, fmt.Sprintf("%s is not watching task %s", watcher, taskID), http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Every method answers with the task's watches as they now are
+	watches, err := s.store.ListTaskWatches(taskID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list watches: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if watches == nil {
+		watches = []*storage.TaskWatch{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(watches)
+}
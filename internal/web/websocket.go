@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -18,6 +17,7 @@ const (
 	WSMessageTypeTaskUpdated = "task_updated"
 	WSMessageTypeTaskDeleted = "task_deleted"
 	WSMessageTypeStatusUpdate = "status_update"
+	WSMessageTypeCycleOutput = "cycle_output"
 )
 
 // WSMessage represents a WebSocket message
@@ -107,6 +107,37 @@ func (s *Server) broadcastTaskUpdate(action string, task *storage.Task) {
 	s.broadcastMessage(message)
 }
 
+// handleStoreEvent pushes task changes recorded through the store to connected
+// clients, whether they came from the web API, MCP agents or a cycle worker.
+// State changes, new and deleted tasks also refresh the status counts.
+func (s *Server) handleStoreEvent(event storage.TaskEvent) {
+	var action string
+	switch event.Kind {
+	case storage.EventCreated, storage.EventRestored:
+		action = "created"
+	case storage.EventUpdated, storage.EventTransition:
+		action = "updated"
+	case storage.EventDeleted:
+		action = "deleted"
+	default:
+		return
+	}
+
+	task := &storage.Task{ID: event.TaskID, UpdatedAt: event.Time}
+	if action != "deleted" {
+		var err error
+		if task, err = s.store.GetTask(event.TaskID); err != nil {
+			log.Printf("Failed to get task %s for WebSocket update: %v", event.TaskID, err)
+			return
+		}
+	}
+
+	s.broadcastTaskUpdate(action, task)
+	if event.Kind != storage.EventUpdated {
+		s.broadcastStatusUpdate()
+	}
+}
+
 // broadcastStatusUpdate broadcasts a status update to all connected clients
 func (s *Server) broadcastStatusUpdate() {
 	// Get current status
@@ -125,6 +156,9 @@ func (s *Server) broadcastStatusUpdate() {
 		storage.NeedsFixes,
 		storage.Fixing,
 		storage.Done,
+		storage.Blocked,
+		storage.Paused,
+		storage.Cancelled,
 	} {
 		count, err := s.store.GetTaskCount(storage.TaskFilters{State: &state})
 		if err != nil {
@@ -132,7 +166,9 @@ func (s *Server) broadcastStatusUpdate() {
 			continue
 		}
 		tasksByState[string(state)] = count
-		totalTasks += count
+		if state != storage.Cancelled { // left out of the total, as archived tasks are
+			totalTasks += count
+		}
 	}
 
 	status := map[string]interface{}{
@@ -167,6 +203,9 @@ func (s *Server) sendStatusUpdate(conn *websocket.Conn) {
 		storage.NeedsFixes,
 		storage.Fixing,
 		storage.Done,
+		storage.Blocked,
+		storage.Paused,
+		storage.Cancelled,
 	} {
 		count, err := s.store.GetTaskCount(storage.TaskFilters{State: &state})
 		if err != nil {
@@ -174,7 +213,9 @@ func (s *Server) sendStatusUpdate(conn *websocket.Conn) {
 			continue
 		}
 		tasksByState[string(state)] = count
-		totalTasks += count
+		if state != storage.Cancelled { // left out of the total, as archived tasks are
+			totalTasks += count
+		}
 	}
 
 	status := map[string]interface{}{
@@ -2,6 +2,7 @@ package wizard
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -83,6 +84,7 @@ type Task struct {
 	Tags         []string
 	Dependencies []string
 	Requirements []string
+	EstimatedHours int
 }
 
 // New creates a new wizard instance
@@ -142,7 +144,7 @@ Please provide a JSON response with:
 
 Focus on being specific and actionable.`, info.Name, fullDescription.String())
 
-	response, err := w.llmClient.GenerateText(visionPrompt)
+	response, err := w.llmClient.GenerateText(context.Background(), visionPrompt)
 	if err != nil {
 		// Fallback to user input
 		info.Vision = strings.TrimSpace(fullDescription.String())
@@ -200,6 +202,76 @@ Focus on being specific and actionable.`, info.Name, fullDescription.String())
 	return info, nil
 }
 
+// requirementsSchema is the JSON schema of the generated Requirements
+var requirementsSchema = llm.Schema{
+	"type":     "object",
+	"required": []string{"functional"},
+	"properties": map[string]interface{}{
+		"functional":     llm.Schema{"type": "array", "items": requirementSchema},
+		"non_functional": llm.Schema{"type": "array", "items": requirementSchema},
+		"constraints":    llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+		"risks":          llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+	},
+}
+
+// requirementSchema is the JSON schema of one Requirement
+var requirementSchema = llm.Schema{
+	"type":     "object",
+	"required": []string{"id", "title"},
+	"properties": map[string]interface{}{
+		"id":          llm.Schema{"type": "string"},
+		"title":       llm.Schema{"type": "string"},
+		"description": llm.Schema{"type": "string"},
+		"priority":    llm.Schema{"type": "string"},
+		"category":    llm.Schema{"type": "string"},
+	},
+}
+
+// architectureSchema is the JSON schema of the generated Architecture
+var architectureSchema = llm.Schema{
+	"type":     "object",
+	"required": []string{"overview", "tech_stack"},
+	"properties": map[string]interface{}{
+		"overview":   llm.Schema{"type": "string"},
+		"tech_stack": llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+		"components": llm.Schema{"type": "array", "items": llm.Schema{
+			"type":     "object",
+			"required": []string{"name"},
+			"properties": map[string]interface{}{
+				"name":         llm.Schema{"type": "string"},
+				"description":  llm.Schema{"type": "string"},
+				"technologies": llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+				"dependencies": llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+			},
+		}},
+		"integrations":   llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+		"deployment":     llm.Schema{"type": "string"},
+		"considerations": llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+	},
+}
+
+// tasksSchema is the JSON schema of the generated task breakdown
+var tasksSchema = llm.Schema{
+	"type":     "object",
+	"required": []string{"tasks"},
+	"properties": map[string]interface{}{
+		"tasks": llm.Schema{"type": "array", "items": llm.Schema{
+			"type":     "object",
+			"required": []string{"title", "description"},
+			"properties": map[string]interface{}{
+				"title":           llm.Schema{"type": "string"},
+				"description":     llm.Schema{"type": "string"},
+				"mvp":             llm.Schema{"type": "string"},
+				"priority":        llm.Schema{"type": "integer"},
+				"tags":            llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+				"requirements":    llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+				"estimated_hours": llm.Schema{"type": "integer"},
+				"dependencies":    llm.Schema{"type": "array", "items": llm.Schema{"type": "string"}},
+			},
+		}},
+	},
+}
+
 // CollectRequirements gathers detailed requirements
 func (w *Wizard) CollectRequirements(projectInfo *ProjectInfo) (*Requirements, error) {
 	reqs := &Requirements{}
@@ -246,26 +318,10 @@ Include modern best practices for the type of application.`,
 		strings.Join(projectInfo.Goals, ", "),
 		strings.Join(projectInfo.Constraints, ", "))
 
-	response, err := w.llmClient.GenerateText(reqPrompt)
-	if err != nil {
+	if err := llm.GenerateJSON(context.Background(), w.llmClient, reqPrompt, requirementsSchema, reqs); err != nil {
 		return nil, fmt.Errorf("failed to generate requirements: %w", err)
 	}
 
-	// Parse requirements
-	if err := json.Unmarshal([]byte(response), &reqs); err != nil {
-		// Try to extract JSON from response
-		jsonStart := strings.Index(response, "{")
-		jsonEnd := strings.LastIndex(response, "}") + 1
-		if jsonStart >= 0 && jsonEnd > jsonStart {
-			jsonStr := response[jsonStart:jsonEnd]
-			if err := json.Unmarshal([]byte(jsonStr), &reqs); err != nil {
-				return nil, fmt.Errorf("failed to parse requirements: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("failed to parse requirements: %w", err)
-		}
-	}
-
 	// Display generated requirements
 	fmt.Println("\n📋 Functional Requirements:")
 	fmt.Println("──────────────────────────")
@@ -347,8 +403,7 @@ Focus on:
 		len(requirements.NonFunctional),
 		userStack)
 
-	response, err := w.llmClient.GenerateText(archPrompt)
-	if err != nil {
+	if err := llm.GenerateJSON(context.Background(), w.llmClient, archPrompt, architectureSchema, arch); err != nil {
 		// Fallback to basic architecture
 		arch.Overview = "Modular architecture with clear separation of concerns"
 		arch.TechStack = strings.Split(userStack, ",")
@@ -356,17 +411,6 @@ Focus on:
 			arch.TechStack = []string{"Go", "React", "PostgreSQL"}
 		}
 		arch.Deployment = "Container-based deployment"
-	} else {
-		// Parse architecture
-		if err := json.Unmarshal([]byte(response), &arch); err != nil {
-			// Try to extract JSON
-			jsonStart := strings.Index(response, "{")
-			jsonEnd := strings.LastIndex(response, "}") + 1
-			if jsonStart >= 0 && jsonEnd > jsonStart {
-				jsonStr := response[jsonStart:jsonEnd]
-				json.Unmarshal([]byte(jsonStr), &arch)
-			}
-		}
 	}
 
 	// Display architecture
@@ -390,6 +434,9 @@ Focus on:
 
 	fmt.Print("\n✅ Use this architecture? (Y/n) ")
 	confirm, _ := w.reader.ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(confirm)) == "n" {
+		fmt.Println("\n📝 You can edit the architecture section of plan.md after initialization.")
+	}
 
 	return arch, nil
 }
@@ -452,7 +499,7 @@ Include a footer indicating generation by Baton AI Wizard with timestamp.`,
 		architecture.Deployment)
 
 	// Generate complete plan using LLM
-	content, err := w.llmClient.GenerateText(prompt)
+	content, err := w.llmClient.GenerateText(context.Background(), prompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate plan: %w", err)
 	}
@@ -547,12 +594,6 @@ TASK REQUIREMENTS:
 Create a COMPLETE waterfall breakdown - don't limit task count artificially.`,
 		plan.Content[:min(3000, len(plan.Content))])
 
-	response, err := w.llmClient.GenerateText(taskPrompt)
-	if err != nil {
-		// Generate default tasks
-		return w.generateDefaultTasks(), nil
-	}
-
 	// Parse tasks
 	var taskData struct {
 		Tasks []struct {
@@ -567,18 +608,9 @@ Create a COMPLETE waterfall breakdown - don't limit task count artificially.`,
 		} `json:"tasks"`
 	}
 
-	if err := json.Unmarshal([]byte(response), &taskData); err != nil {
-		// Try to extract JSON
-		jsonStart := strings.Index(response, "{")
-		jsonEnd := strings.LastIndex(response, "}") + 1
-		if jsonStart >= 0 && jsonEnd > jsonStart {
-			jsonStr := response[jsonStart:jsonEnd]
-			if err := json.Unmarshal([]byte(jsonStr), &taskData); err != nil {
-				return w.generateDefaultTasks(), nil
-			}
-		} else {
-			return w.generateDefaultTasks(), nil
-		}
+	if err := llm.GenerateJSON(context.Background(), w.llmClient, taskPrompt, tasksSchema, &taskData); err != nil {
+		// Generate default tasks
+		return w.generateDefaultTasks(), nil
 	}
 
 	// Convert to Task objects
@@ -595,6 +627,7 @@ Create a COMPLETE waterfall breakdown - don't limit task count artificially.`,
 			Tags:         td.Tags,
 			Dependencies: td.Dependencies,
 			Requirements: td.Requirements,
+			EstimatedHours: td.EstimatedHours,
 		}
 		tasks = append(tasks, task)
 	}
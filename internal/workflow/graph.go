@@ -0,0 +1,98 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Graph formats
+const (
+	FormatDot     = "dot"
+	FormatMermaid = "mermaid"
+)
+
+// Graph renders the definition's state machine as a Graphviz dot or Mermaid
+// state diagram. Edges are labelled with the handover artifacts they require;
+// states nothing moves into are drawn as start states and states with no
+// transitions as terminal ones.
+func (d *Definition) Graph(format string) (string, error) {
+	switch format {
+	case FormatDot:
+		return d.dot(), nil
+	case FormatMermaid:
+		return d.mermaid(), nil
+	default:
+		return "", fmt.Errorf("unknown graph format %q (use %s or %s)", format, FormatDot, FormatMermaid)
+	}
+}
+
+// edgeLabel lists the handover artifacts a transition requires
+func (d *Definition) edgeLabel(from, to string) string {
+	return strings.Join(d.Handovers[from+"->"+to], ", ")
+}
+
+// startStates returns the states no transition moves into
+func (d *Definition) startStates() []string {
+	entered := make(map[string]bool)
+	for _, targets := range d.States {
+		for _, to := range targets {
+			entered[to] = true
+		}
+	}
+	var starts []string
+	for _, state := range sortedKeys(d.States) {
+		if !entered[state] {
+			starts = append(starts, state)
+		}
+	}
+	return starts
+}
+
+func (d *Definition) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph baton {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+	for _, state := range d.startStates() {
+		fmt.Fprintf(&b, "  %q [style=\"rounded,bold\"];\n", state)
+	}
+	for _, state := range sortedKeys(d.States) {
+		if len(d.States[state]) == 0 {
+			fmt.Fprintf(&b, "  %q [peripheries=2];\n", state)
+		}
+	}
+	for _, from := range sortedKeys(d.States) {
+		for _, to := range d.States[from] {
+			if label := d.edgeLabel(from, to); label != "" {
+				fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", from, to, label)
+			} else {
+				fmt.Fprintf(&b, "  %q -> %q;\n", from, to)
+			}
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (d *Definition) mermaid() string {
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+	for _, state := range d.startStates() {
+		fmt.Fprintf(&b, "  [*] --> %s\n", state)
+	}
+	for _, from := range sortedKeys(d.States) {
+		for _, to := range d.States[from] {
+			if label := d.edgeLabel(from, to); label != "" {
+				fmt.Fprintf(&b, "  %s --> %s: %s\n", from, to, label)
+			} else {
+				fmt.Fprintf(&b, "  %s --> %s\n", from, to)
+			}
+		}
+	}
+	for _, state := range sortedKeys(d.States) {
+		if len(d.States[state]) == 0 {
+			fmt.Fprintf(&b, "  %s --> [*]\n", state)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,343 @@
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"baton/internal/config"
+	"baton/internal/statemachine"
+)
+
+// FormatVersion is the version of the workflow file format written by Export
+const FormatVersion = 1
+
+// Definition is a shareable description of a workflow: the state machine, the
+// handovers its transitions require, artifact schemas and agent permissions
+type Definition struct {
+	Version         int                              `yaml:"version"`
+	States          map[string][]string              `yaml:"states"`    // state -> states it may move to
+	Handovers       map[string][]string              `yaml:"handovers"` // "from->to" -> required artifacts
+	ArtifactSchemas map[string]config.ArtifactSchema `yaml:"artifact_schemas,omitempty"`
+	Agents          map[string]AgentDefinition       `yaml:"agents"`
+}
+
+// AgentDefinition is the part of an agent's configuration a workflow owns
+type AgentDefinition struct {
+	Name          string                  `yaml:"name,omitempty"`
+	Role          string                  `yaml:"role,omitempty"`
+	AllowedStates []string                `yaml:"allowed_states"`
+	Permissions   config.AgentPermissions `yaml:"permissions"`
+}
+
+// Active returns the workflow currently in effect
+func Active(cfg *config.Config) *Definition {
+	def := &Definition{
+		Version:         FormatVersion,
+		States:          make(map[string][]string),
+		Handovers:       make(map[string][]string),
+		ArtifactSchemas: cfg.ArtifactSchemas,
+		Agents:          make(map[string]AgentDefinition),
+	}
+
+	for from, targets := range statemachine.ValidTransitions {
+		next := []string{}
+		for _, to := range targets {
+			next = append(next, string(to))
+		}
+		def.States[string(from)] = next
+	}
+	for transition, artifacts := range statemachine.RequiredHandovers {
+		def.Handovers[transition] = append([]string(nil), artifacts...)
+	}
+	for id, agent := range cfg.Agents {
+		def.Agents[id] = AgentDefinition{
+			Name:          agent.Name,
+			Role:          agent.Role,
+			AllowedStates: agent.AllowedStates,
+			Permissions:   agent.Permissions,
+		}
+	}
+
+	return def
+}
+
+// Marshal renders the definition as YAML
+func (d *Definition) Marshal() ([]byte, error) {
+	return encode(d)
+}
+
+// encode renders YAML with the two-space indent used by baton.yaml
+func encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Parse reads a workflow definition from YAML
+func Parse(data []byte) (*Definition, error) {
+	var def Definition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	return &def, nil
+}
+
+// Validate returns every problem with the definition; none means it is valid
+func (d *Definition) Validate() []string {
+	var problems []string
+
+	if d.Version != FormatVersion {
+		problems = append(problems, fmt.Sprintf("unsupported workflow version %d (expected %d)", d.Version, FormatVersion))
+	}
+	if len(d.States) == 0 {
+		problems = append(problems, "no states defined")
+	}
+
+	terminal := 0
+	for _, from := range sortedKeys(d.States) {
+		targets := d.States[from]
+		if len(targets) == 0 {
+			terminal++
+		}
+		for _, to := range targets {
+			if _, exists := d.States[to]; !exists {
+				problems = append(problems, fmt.Sprintf("state %s moves to undefined state %s", from, to))
+			}
+		}
+	}
+	if len(d.States) > 0 && terminal == 0 {
+		problems = append(problems, "no terminal state (a state with no transitions)")
+	}
+
+	for _, transition := range sortedKeys(d.Handovers) {
+		from, to, ok := strings.Cut(transition, "->")
+		if !ok || !contains(d.States[from], to) {
+			problems = append(problems, fmt.Sprintf("handover %s is not a defined transition", transition))
+		}
+	}
+
+	for _, name := range sortedKeys(d.ArtifactSchemas) {
+		switch d.ArtifactSchemas[name].Format {
+		case "", "markdown", "json":
+		default:
+			problems = append(problems, fmt.Sprintf("artifact schema %s has invalid format %q", name, d.ArtifactSchemas[name].Format))
+		}
+	}
+
+	for _, id := range sortedKeys(d.Agents) {
+		agent := d.Agents[id]
+		for _, state := range agent.AllowedStates {
+			if _, exists := d.States[state]; !exists {
+				problems = append(problems, fmt.Sprintf("agent %s allows undefined state %s", id, state))
+			}
+		}
+		for _, state := range agent.Permissions.CanTransitionTo {
+			if _, exists := d.States[state]; !exists {
+				problems = append(problems, fmt.Sprintf("agent %s may transition to undefined state %s", id, state))
+			}
+		}
+	}
+
+	return problems
+}
+
+// Diff lists the changes importing other would make to d, one per line:
+// "+" added, "-" removed, "~" changed
+func (d *Definition) Diff(other *Definition) []string {
+	var changes []string
+	changes = append(changes, diffSection("state", d.States, other.States)...)
+	changes = append(changes, diffSection("handover", d.Handovers, other.Handovers)...)
+	changes = append(changes, diffSection("artifact schema", d.ArtifactSchemas, other.ArtifactSchemas)...)
+	changes = append(changes, diffSection("agent", d.Agents, other.Agents)...)
+	return changes
+}
+
+// SameStateMachine reports whether other has the same states, transitions and handovers as d
+func (d *Definition) SameStateMachine(other *Definition) bool {
+	return len(diffSection("", d.States, other.States)) == 0 &&
+		len(diffSection("", d.Handovers, other.Handovers)) == 0
+}
+
+// diffSection compares two maps of one section of a definition
+func diffSection[V any](section string, current, incoming map[string]V) []string {
+	keys := make(map[string]bool)
+	for key := range current {
+		keys[key] = true
+	}
+	for key := range incoming {
+		keys[key] = true
+	}
+
+	var changes []string
+	for _, key := range sortedKeys(keys) {
+		old, inCurrent := current[key]
+		updated, inIncoming := incoming[key]
+		switch {
+		case !inCurrent:
+			changes = append(changes, fmt.Sprintf("+ %s %s: %s", section, key, describe(updated)))
+		case !inIncoming:
+			changes = append(changes, fmt.Sprintf("- %s %s", section, key))
+		case !reflect.DeepEqual(normalize(old), normalize(updated)):
+			changes = append(changes, fmt.Sprintf("~ %s %s: %s -> %s", section, key, describe(old), describe(updated)))
+		}
+	}
+	return changes
+}
+
+// normalize makes nil and empty slices compare equal
+func normalize(value interface{}) interface{} {
+	data, _ := yaml.Marshal(value)
+	var out interface{}
+	yaml.Unmarshal(data, &out)
+	return out
+}
+
+// describe renders a value on one line for the diff
+func describe(value interface{}) string {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	setFlowStyle(&node)
+	data, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// setFlowStyle makes a node and its children render inline
+func setFlowStyle(node *yaml.Node) {
+	node.Style |= yaml.FlowStyle
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
+}
+
+// ErrStateMachineChange is returned when an import would change the built-in
+// state machine; only agent permissions and artifact schemas can be imported
+var ErrStateMachineChange = fmt.Errorf("states, transitions and handovers are built in and cannot be imported yet; only agents and artifact schemas can change")
+
+// Apply writes the imported agent definitions and artifact schemas into the
+// YAML config file at path, leaving the rest of the file as it is
+func Apply(path string, active, incoming *Definition) error {
+	if !active.SameStateMachine(incoming) {
+		return ErrStateMachineChange
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+
+	agents := mappingValue(root, "agents")
+	for _, id := range sortedKeys(incoming.Agents) {
+		def := incoming.Agents[id]
+		agent := mappingValue(agents, id)
+		if def.Name != "" {
+			if err := setValue(agent, "name", def.Name); err != nil {
+				return err
+			}
+		}
+		if def.Role != "" {
+			if err := setValue(agent, "role", def.Role); err != nil {
+				return err
+			}
+		}
+		if err := setValue(agent, "allowed_states", def.AllowedStates); err != nil {
+			return err
+		}
+		if err := setValue(agent, "permissions", def.Permissions); err != nil {
+			return err
+		}
+	}
+
+	if len(incoming.ArtifactSchemas) > 0 {
+		if err := setValue(root, "artifact_schemas", incoming.ArtifactSchemas); err != nil {
+			return err
+		}
+	}
+
+	out, err := encode(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to render config file: %w", err)
+	}
+	return os.WriteFile(path, out, 0644)
+}
+
+// mappingValue returns the mapping stored under key, creating it if needed
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			if value.Kind != yaml.MappingNode {
+				*value = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			return value
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+// setValue stores value under key in the mapping, replacing any existing value
+func setValue(mapping *yaml.Node, key string, value interface{}) error {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			old := mapping.Content[i+1]
+			node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			mapping.Content[i+1] = &node
+			return nil
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &node)
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
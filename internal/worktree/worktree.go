@@ -0,0 +1,175 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Worktree is a git worktree on a branch of its own, created for one cycle
+type Worktree struct {
+	Repo   string // top level of the repository's main working tree
+	Path   string // top level of the worktree
+	Dir    string // the workspace's counterpart in the worktree, where the agent works
+	Branch string
+}
+
+// mergeMu makes merges into a main working tree, which parallel cycles share,
+// happen one at a time
+var mergeMu sync.Mutex
+
+// Check reports why worktrees can't be created for workspace, or nil
+func Check(workspace string) error {
+	if _, err := git(workspace, "rev-parse", "--show-toplevel"); err != nil {
+		return fmt.Errorf("workspace %s is not in a git repository: %w", workspace, err)
+	}
+	return nil
+}
+
+// Create adds a worktree on a new branch starting at the current HEAD of the
+// repository holding workspace. Worktrees are created in dir, or when dir is
+// "" in the repository's .git directory, out of sight of git status.
+func Create(workspace, dir, branch string) (*Worktree, error) {
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, err
+	}
+	repo, rel, err := locate(workspace)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		common, err := git(repo, "rev-parse", "--git-common-dir")
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(repo, common)
+		}
+		dir = filepath.Join(common, "baton-worktrees")
+	} else if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workspace, dir)
+	}
+
+	path := filepath.Join(dir, strings.ReplaceAll(branch, "/", "-"))
+	if _, err := git(repo, "worktree", "add", "-b", branch, path, "HEAD"); err != nil {
+		return nil, fmt.Errorf("failed to create worktree for branch %s: %w", branch, err)
+	}
+
+	tree := &Worktree{Repo: repo, Path: path, Dir: filepath.Join(path, rel), Branch: branch}
+	// An untracked workspace directory has no counterpart yet
+	if err := os.MkdirAll(tree.Dir, 0755); err != nil {
+		tree.Discard(false)
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Reopen returns the worktree at path on branch, created by an earlier
+// process for the repository holding workspace. Its Dir is the workspace's
+// counterpart in it, as Create set it.
+func Reopen(workspace, path, branch string) (*Worktree, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("worktree %s is gone: %w", path, err)
+	}
+	repo, rel, err := locate(workspace)
+	if err != nil {
+		return nil, err
+	}
+	return &Worktree{Repo: repo, Path: path, Dir: filepath.Join(path, rel), Branch: branch}, nil
+}
+
+// locate returns the top level of the repository holding workspace and where
+// the workspace is within it, "." at the top level. Git works the latter out
+// itself, so symlinks in the workspace's path don't throw it off.
+func locate(workspace string) (repo, rel string, err error) {
+	repo, err = git(workspace, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", "", fmt.Errorf("workspace %s is not in a git repository: %w", workspace, err)
+	}
+	prefix, err := git(workspace, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", "", err
+	}
+	if prefix == "" {
+		return repo, ".", nil
+	}
+	return repo, filepath.FromSlash(strings.TrimSuffix(prefix, "/")), nil
+}
+
+// Merge commits whatever was left uncommitted in the worktree and merges its
+// branch into the branch checked out in the main working tree, then removes
+// the worktree and the branch. When the merge fails it is aborted and the
+// branch is kept, so the changes can be merged by hand; when committing fails
+// the worktree is left in place.
+func (w *Worktree) Merge(message string) error {
+	if _, err := git(w.Path, "add", "-A"); err != nil {
+		return fmt.Errorf("changes left in worktree %s: %w", w.Path, err)
+	}
+	if status, err := git(w.Path, "status", "--porcelain"); err != nil {
+		return fmt.Errorf("changes left in worktree %s: %w", w.Path, err)
+	} else if status != "" {
+		if _, err := git(w.Path, "commit", "-m", message); err != nil {
+			return fmt.Errorf("changes left in worktree %s: %w", w.Path, err)
+		}
+	}
+
+	mergeMu.Lock()
+	defer mergeMu.Unlock()
+	if _, err := git(w.Repo, "merge", "--no-edit", "-m", message, w.Branch); err != nil {
+		git(w.Repo, "merge", "--abort")
+		return w.keep(err)
+	}
+	return w.Discard(false)
+}
+
+// keep removes the worktree but keeps its branch after a failed merge
+func (w *Worktree) keep(cause error) error {
+	if err := w.Discard(true); err != nil {
+		return fmt.Errorf("%v; %w", cause, err)
+	}
+	return fmt.Errorf("changes kept on branch %s: %w", w.Branch, cause)
+}
+
+// Discard removes the worktree and, unless keepBranch is set, its branch
+// along with every change made on it
+func (w *Worktree) Discard(keepBranch bool) error {
+	if _, err := git(w.Repo, "worktree", "remove", "--force", w.Path); err != nil {
+		return err
+	}
+	if keepBranch {
+		return nil
+	}
+	_, err := git(w.Repo, "branch", "-D", w.Branch)
+	return err
+}
+
+// Diff returns the changes under dir against HEAD of the repository holding
+// it: the status of each changed or untracked file, then the diff of the
+// tracked ones. There are none when it returns "".
+func Diff(dir string) (string, error) {
+	status, err := git(dir, "status", "--short", "--", ".")
+	if err != nil {
+		return "", err
+	}
+	if status == "" {
+		return "", nil
+	}
+	diff, err := git(dir, "diff", "HEAD", "--", ".")
+	if err != nil || diff == "" {
+		return status, err
+	}
+	return status + "\n\n" + diff, nil
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,81 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newRepo creates a git repository with one commit holding a file in the
+// app subdirectory and returns its top level
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "app"), 0755); err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "app", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		if _, err := git(repo, args...); err != nil {
+			t.Fatalf("Failed to set up repository: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestReopenKeepsWorkspaceOffset(t *testing.T) {
+	repo := newRepo(t)
+	workspace := filepath.Join(repo, "app")
+
+	created, err := Create(workspace, "", "baton/T-1")
+	if err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	defer created.Discard(false)
+
+	if want := filepath.Join(created.Path, "app"); created.Dir != want {
+		t.Errorf("Expected Create to set Dir to %s, got %s", want, created.Dir)
+	}
+	if _, err := os.Stat(filepath.Join(created.Dir, "main.go")); err != nil {
+		t.Errorf("Expected the workspace's files in Dir: %v", err)
+	}
+
+	reopened, err := Reopen(workspace, created.Path, created.Branch)
+	if err != nil {
+		t.Fatalf("Failed to reopen worktree: %v", err)
+	}
+	if reopened.Dir != created.Dir {
+		t.Errorf("Expected Reopen to set Dir to %s, got %s", created.Dir, reopened.Dir)
+	}
+	if reopened.Repo != created.Repo {
+		t.Errorf("Expected Reopen to set Repo to %s, got %s", created.Repo, reopened.Repo)
+	}
+}
+
+func TestReopenAtTopLevel(t *testing.T) {
+	repo := newRepo(t)
+
+	created, err := Create(repo, "", "baton/T-2")
+	if err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	defer created.Discard(false)
+
+	reopened, err := Reopen(repo, created.Path, created.Branch)
+	if err != nil {
+		t.Fatalf("Failed to reopen worktree: %v", err)
+	}
+	if reopened.Dir != created.Path {
+		t.Errorf("Expected Dir to be the worktree's top level %s, got %s", created.Path, reopened.Dir)
+	}
+}
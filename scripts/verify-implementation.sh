@@ -195,6 +195,6 @@ echo "  ./baton ingest plan.md"
 echo "  ./baton start --dry-run"
 echo ""
 echo "Requirements:"
-echo "  • Go 1.21+ for building"
+echo "  • Go 1.23+ for building"
 echo "  • Claude Code CLI for LLM integration"
 echo ""
\ No newline at end of file
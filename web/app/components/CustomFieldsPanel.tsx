@@ -0,0 +1,114 @@
+'use client'
+
+import { useEffect, useState } from 'react'
+import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query'
+import { SlidersHorizontal, Loader2 } from 'lucide-react'
+
+import { Task, CustomField, CustomFieldValue } from '../types'
+import { apiClient } from '../lib/api'
+
+interface CustomFieldsPanelProps {
+  task: Task
+}
+
+// Inputs hold text; the server converts it to the field's type
+type Drafts = Record<string, string>
+
+function toDrafts(values: Record<string, CustomFieldValue> | undefined): Drafts {
+  const drafts: Drafts = {}
+  for (const [name, value] of Object.entries(values ?? {})) {
+    drafts[name] = String(value)
+  }
+  return drafts
+}
+
+export function CustomFieldsPanel({ task }: CustomFieldsPanelProps) {
+  const queryClient = useQueryClient()
+  const [drafts, setDrafts] = useState<Drafts>(toDrafts(task.custom_fields))
+
+  useEffect(() => {
+    setDrafts(toDrafts(task.custom_fields))
+  }, [task.custom_fields])
+
+  const { data: definitions } = useQuery({
+    queryKey: ['custom-fields'],
+    queryFn: () => apiClient.getCustomFields(),
+  })
+
+  const saveMutation = useMutation({
+    mutationFn: () => {
+      const fields: Record<string, CustomFieldValue | null> = {}
+      for (const name of Object.keys(definitions ?? {})) {
+        const draft = drafts[name] ?? ''
+        fields[name] = draft === '' ? null : draft
+      }
+      return apiClient.setTaskFields(task.id, fields)
+    },
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ['tasks'] })
+      queryClient.invalidateQueries({ queryKey: ['task', task.id] })
+    },
+  })
+
+  const names = Object.keys(definitions ?? {}).sort()
+  if (names.length === 0) return null
+
+  const renderInput = (name: string, field: CustomField) => {
+    const value = drafts[name] ?? ''
+    const onChange = (next: string) => setDrafts({ ...drafts, [name]: next })
+
+    if (field.type === 'enum' || field.type === 'boolean') {
+      const options = field.type === 'enum' ? field.values ?? [] : ['true', 'false']
+      return (
+        <select className="input-tech w-full" value={value} onChange={(e) => onChange(e.target.value)}>
+          <option value="">—</option>
+          {options.map((option) => (
+            <option key={option} value={option}>
+              {option}
+            </option>
+          ))}
+        </select>
+      )
+    }
+    return (
+      <input
+        className="input-tech w-full"
+        type={field.type === 'number' ? 'number' : 'text'}
+        value={value}
+        onChange={(e) => onChange(e.target.value)}
+      />
+    )
+  }
+
+  return (
+    <div>
+      <h3 className="text-lg font-semibold mb-3 flex items-center">
+        <SlidersHorizontal className="w-5 h-5 mr-2" />
+        Custom Fields
+      </h3>
+      <div className="grid grid-cols-2 gap-4">
+        {names.map((name) => (
+          <label key={name} className="block text-sm">
+            <span className="text-muted-foreground" title={definitions![name].description}>
+              {name}
+            </span>
+            {renderInput(name, definitions![name])}
+          </label>
+        ))}
+      </div>
+      <div className="flex items-center justify-end mt-3 space-x-3">
+        {saveMutation.isError && (
+          <span className="text-sm text-red-400">{(saveMutation.error as Error).message}</span>
+        )}
+        <button
+          onClick={() => saveMutation.mutate()}
+          disabled={saveMutation.isPending}
+          className="btn-tech-secondary"
+        >
+          {saveMutation.isPending && <Loader2 className="w-4 h-4 mr-2 animate-spin" />}
+          Save fields
+        </button>
+      </div>
+    </div>
+  )
+}
@@ -4,9 +4,9 @@ import { useState, useEffect } from 'react'
 import { useQuery, useQueryClient } from '@tanstack/react-query'
 import { DragDropContext, Droppable, Draggable, DropResult } from 'react-beautiful-dnd'
 import { motion, AnimatePresence } from 'framer-motion'
-import { Plus, RefreshCw, AlertCircle, Wifi, WifiOff } from 'lucide-react'
+import { Plus, RefreshCw, AlertCircle, Wifi, WifiOff, PauseCircle } from 'lucide-react'
 
-import { Task, TaskState, STATE_CONFIG } from '../types'
+import { Task, TaskState, STATE_CONFIG, HOLD_STATES } from '../types'
 import { apiClient } from '../lib/api'
 import { useWebSocket } from '../hooks/useWebSocket'
 import { TaskCard } from './TaskCard'
@@ -23,11 +23,13 @@ const COLUMN_ORDER: TaskState[] = [
   'committing',
   'needs_fixes',
   'fixing',
-  'DONE'
+  'DONE',
+  'cancelled'
 ]
 
 export function KanbanBoard() {
   const [isCreateDialogOpen, setIsCreateDialogOpen] = useState(false)
+  const [showHeld, setShowHeld] = useState(true)
   const queryClient = useQueryClient()
   const { isConnected, lastMessage } = useWebSocket()
 
@@ -37,6 +39,12 @@ export function KanbanBoard() {
     refetchInterval: 30000, // Refetch every 30 seconds as fallback
   })
 
+  const { data: status } = useQuery({
+    queryKey: ['status'],
+    queryFn: () => apiClient.getStatus(),
+    refetchInterval: 30000,
+  })
+
   // Handle real-time updates via WebSocket
   useEffect(() => {
     if (lastMessage) {
@@ -50,6 +58,9 @@ export function KanbanBoard() {
         case 'status_update':
           // Could update a status indicator here
           break
+        case 'cycle_output':
+          // Streamed agent output; the board only shows task changes
+          break
       }
     }
   }, [lastMessage, queryClient])
@@ -63,6 +74,10 @@ export function KanbanBoard() {
     return acc
   }, {} as Record<TaskState, Task[]>)
 
+  // Held tasks get their own columns after the workflow, which can be hidden
+  const heldCount = HOLD_STATES.reduce((n, state) => n + (tasksByState[state]?.length || 0), 0)
+  const columns = showHeld ? [...COLUMN_ORDER, ...HOLD_STATES] : COLUMN_ORDER
+
   const handleDragEnd = async (result: DropResult) => {
     const { destination, source, draggableId } = result
 
@@ -75,9 +90,33 @@ export function KanbanBoard() {
 
     const newState = destination.droppableId as TaskState
     const taskId = draggableId
+    const task = tasks.find((t) => t.id === taskId)
 
     try {
-      await apiClient.updateTaskState(taskId, newState, 'Moved via kanban drag & drop')
+      // Dropping a held task back on the column it was held from resumes it
+      if (task?.held_from && HOLD_STATES.includes(task.state) && newState === task.held_from) {
+        await apiClient.resumeTask(taskId)
+        return
+      }
+      // Cancelling needs a reason
+      let note = 'Moved via kanban drag & drop'
+      if (newState === 'cancelled') {
+        const reason = window.prompt("Why won't this task be done?")
+        if (!reason?.trim()) {
+          return
+        }
+        note = reason.trim()
+      }
+      // A column at its WIP limit takes more only when the user insists
+      const limit = status?.wip_limits?.[newState]
+      let overrideWip = false
+      if (limit && (tasksByState[newState]?.length || 0) >= limit) {
+        if (!window.confirm(`${STATE_CONFIG[newState].label} is at its WIP limit of ${limit}. Move the task anyway?`)) {
+          return
+        }
+        overrideWip = true
+      }
+      await apiClient.updateTaskState(taskId, newState, note, overrideWip)
       // The WebSocket will handle the real-time update
     } catch (error) {
       console.error('Failed to update task state:', error)
@@ -134,6 +173,14 @@ export function KanbanBoard() {
           </div>
         </div>
         <div className="flex items-center space-x-2">
+          <button
+            onClick={() => setShowHeld(!showHeld)}
+            className="btn-tech-ghost"
+            title="Blocked and paused tasks"
+          >
+            <PauseCircle className="w-4 h-4 mr-2" />
+            {showHeld ? 'Hide' : 'Show'} held ({heldCount})
+          </button>
           <button
             onClick={() => refetch()}
             className="btn-tech-ghost"
@@ -152,11 +199,49 @@ export function KanbanBoard() {
         </div>
       </div>
 
+      {/* Degraded mode: agents can't read the plan */}
+      {status?.plan && !status.plan.available && (
+        <div className="flex items-center space-x-2 px-4 py-2 border-b border-border bg-yellow-500/10 text-yellow-400 text-sm">
+          <AlertCircle className="w-4 h-4" />
+          <span>
+            Plan file {status.plan.path} is unavailable ({status.plan.error}). Agents are working without the plan.
+          </span>
+        </div>
+      )}
+
+      {/* Tasks stuck in a work state */}
+      {status?.stale_tasks && status.stale_tasks.length > 0 && (
+        <div className="flex items-center space-x-2 px-4 py-2 border-b border-border bg-orange-500/10 text-orange-400 text-sm">
+          <AlertCircle className="w-4 h-4" />
+          <span>
+            {status.stale_tasks.length} stale {status.stale_tasks.length === 1 ? 'task' : 'tasks'}:{' '}
+            {status.stale_tasks.slice(0, 3).map((t) =>
+              `${t.title} (${STATE_CONFIG[t.state].label}, ${Math.round(t.hours_in_state)}h)`
+            ).join(', ')}
+            {status.stale_tasks.length > 3 && ` and ${status.stale_tasks.length - 3} more`}
+          </span>
+        </div>
+      )}
+
+      {/* Open tasks past their due date */}
+      {status?.overdue_tasks && status.overdue_tasks.length > 0 && (
+        <div className="flex items-center space-x-2 px-4 py-2 border-b border-border bg-red-500/10 text-red-400 text-sm">
+          <AlertCircle className="w-4 h-4" />
+          <span>
+            {status.overdue_tasks.length} overdue {status.overdue_tasks.length === 1 ? 'task' : 'tasks'}:{' '}
+            {status.overdue_tasks.slice(0, 3).map((t) =>
+              `${t.title} (${STATE_CONFIG[t.state].label}, ${Math.round(t.hours_overdue)}h late)`
+            ).join(', ')}
+            {status.overdue_tasks.length > 3 && ` and ${status.overdue_tasks.length - 3} more`}
+          </span>
+        </div>
+      )}
+
       {/* Kanban Board */}
       <div className="flex-1 overflow-x-auto">
         <DragDropContext onDragEnd={handleDragEnd}>
           <div className="flex space-x-4 p-4 min-w-max">
-            {COLUMN_ORDER.map((state) => {
+            {columns.map((state) => {
               const stateTasks = tasksByState[state] || []
               const config = STATE_CONFIG[state]
 
@@ -174,6 +259,7 @@ export function KanbanBoard() {
                         </div>
                         <span className="bg-muted text-muted-foreground px-2 py-1 rounded-full text-xs">
                           {stateTasks.length}
+                          {status?.wip_limits?.[state] && ` / ${status.wip_limits[state]}`}
                         </span>
                       </div>
                       <p className="text-xs text-muted-foreground mt-1">
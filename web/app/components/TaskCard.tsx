@@ -118,6 +118,7 @@ export function TaskCard({ task, isDragging }: TaskCardProps) {
           <div className="flex items-center justify-between text-xs">
             <span className="text-muted-foreground">
               {stateConfig.label}
+              {task.held_from && ` · resumes to ${STATE_CONFIG[task.held_from].label}`}
             </span>
             <div className="flex items-center space-x-1">
               <span className="text-muted-foreground">
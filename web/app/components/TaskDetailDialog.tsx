@@ -1,7 +1,7 @@
 'use client'
 
 import { useState } from 'react'
-import { useQuery } from '@tanstack/react-query'
+import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query'
 import { motion, AnimatePresence } from 'framer-motion'
 import { formatDistanceToNow, format } from 'date-fns'
 import {
@@ -17,11 +17,14 @@ import {
   RefreshCw,
   ChevronDown,
   ChevronRight,
+  Eye,
+  EyeOff,
 } from 'lucide-react'
 
 import { Task, PRIORITY_CONFIG, STATE_CONFIG } from '../types'
 import { apiClient } from '../lib/api'
 import { UpdateTaskDialog } from './UpdateTaskDialog'
+import { CustomFieldsPanel } from './CustomFieldsPanel'
 
 interface TaskDetailDialogProps {
   task: Task
@@ -46,6 +49,24 @@ export function TaskDetailDialog({ task, open, onOpenChange }: TaskDetailDialogP
     enabled: open,
   })
 
+  const queryClient = useQueryClient()
+
+  const { data: watchers } = useQuery({
+    queryKey: ['watchers', task.id],
+    queryFn: () => apiClient.getTaskWatchers(task.id),
+    enabled: open,
+  })
+
+  // The web UI watches as the "web" watcher on the default channel
+  const isWatching = watchers?.some((watch) => watch.watcher === 'web') ?? false
+
+  const watchMutation = useMutation({
+    mutationFn: () => (isWatching ? apiClient.unwatchTask(task.id) : apiClient.watchTask(task.id)),
+    onSuccess: (updated) => {
+      queryClient.setQueryData(['watchers', task.id], updated)
+    },
+  })
+
   const priorityConfig = PRIORITY_CONFIG[task.priority as keyof typeof PRIORITY_CONFIG]
   const stateConfig = STATE_CONFIG[task.state]
 
@@ -110,6 +131,15 @@ export function TaskDetailDialog({ task, open, onOpenChange }: TaskDetailDialogP
             </div>
           </div>
           <div className="flex items-center space-x-2">
+            <button
+              onClick={() => watchMutation.mutate()}
+              disabled={watchMutation.isPending}
+              className="btn-tech-secondary"
+              title={isWatching ? 'Stop notifications for this task' : 'Get notified when this task changes'}
+            >
+              {isWatching ? <EyeOff className="w-4 h-4 mr-2" /> : <Eye className="w-4 h-4 mr-2" />}
+              {isWatching ? 'Unwatch' : 'Watch'}
+            </button>
             <button
               onClick={() => setIsUpdateDialogOpen(true)}
               className="btn-tech-secondary"
@@ -195,6 +225,9 @@ export function TaskDetailDialog({ task, open, onOpenChange }: TaskDetailDialogP
                   </div>
                 )}
 
+                {/* Custom fields */}
+                <CustomFieldsPanel task={taskDetail ?? task} />
+
                 {/* Dependencies */}
                 {task.dependencies.length > 0 && (
                   <div>
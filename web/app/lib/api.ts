@@ -1,4 +1,4 @@
-import { Task, TaskState, Status, AuditEntry, CreateTaskRequest, UpdateTaskRequest } from '../types'
+import { Task, TaskNode, TaskPage, TaskPageRequest, TaskState, Status, AuditEntry, TaskWatch, TaskRevision, TaskNote, TimeInState, CurrentCycle, CreateTaskRequest, UpdateTaskRequest, CustomField, CustomFieldValue, Milestone, MilestoneSummary, SearchHit, SearchKind } from '../types'
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:3001/api'
 
@@ -33,7 +33,7 @@ class ApiClient {
   }
 
   // Task operations
-  async getTasks(filters?: { state?: TaskState; priority?: number }): Promise<Task[]> {
+  async getTasks(filters?: { state?: TaskState; priority?: number; tags?: string[]; archived?: boolean }): Promise<Task[]> {
     const params = new URLSearchParams()
 
     if (filters?.state) {
@@ -42,6 +42,12 @@ class ApiClient {
     if (filters?.priority) {
       params.append('priority', filters.priority.toString())
     }
+    for (const tag of filters?.tags ?? []) {
+      params.append('tag', tag)
+    }
+    if (filters?.archived) {
+      params.append('archived', 'true')
+    }
 
     const query = params.toString()
     const endpoint = query ? `/tasks?${query}` : '/tasks'
@@ -49,18 +55,81 @@ class ApiClient {
     return this.request<Task[]>(endpoint)
   }
 
+  // One page of tasks; the server sends the total and next cursor as headers
+  async getTaskPage(
+    filters: { state?: TaskState; priority?: number; tags?: string[]; archived?: boolean } & TaskPageRequest
+  ): Promise<TaskPage> {
+    const { tags, ...rest } = filters
+    const params = new URLSearchParams()
+    for (const tag of tags ?? []) {
+      params.append('tag', tag)
+    }
+    for (const [key, value] of Object.entries(rest)) {
+      if (value !== undefined && value !== '' && value !== false) {
+        params.append(key, String(value))
+      }
+    }
+
+    const response = await fetch(`${this.baseUrl}/tasks?${params.toString()}`)
+    if (!response.ok) {
+      throw new Error(`API request failed: ${response.status} ${response.statusText}`)
+    }
+
+    return {
+      tasks: await response.json(),
+      total: Number(response.headers.get('X-Total-Count') ?? 0),
+      next_cursor: response.headers.get('X-Next-Cursor') ?? undefined,
+    }
+  }
+
   async getTask(id: string): Promise<Task> {
     return this.request<Task>(`/tasks/${id}`)
   }
 
+  // Moves the task to the trash; restoreTask brings it back
+  async deleteTask(id: string): Promise<Task> {
+    return this.request<Task>(`/tasks/${id}`, {
+      method: 'DELETE',
+    })
+  }
+
+  async restoreTask(id: string): Promise<Task> {
+    return this.request<Task>(`/tasks/${id}/restore`, {
+      method: 'POST',
+    })
+  }
+
+  // Returns a blocked or paused task to the state it was held from
+  async resumeTask(id: string): Promise<Task> {
+    return this.request<Task>(`/tasks/${id}/resume`, {
+      method: 'POST',
+    })
+  }
+
+  async getTaskTree(): Promise<TaskNode[]> {
+    return this.request<TaskNode[]>('/tasks?tree=true')
+  }
+
+  async getTaskChildren(id: string): Promise<Task[]> {
+    return this.request<Task[]>(`/tasks/${id}/children`)
+  }
+
+  async setTaskParent(id: string, parentId: string): Promise<Task> {
+    return this.request<Task>(`/tasks/${id}/parent`, {
+      method: 'PUT',
+      body: JSON.stringify({ parent_id: parentId }),
+    })
+  }
+
   async updateTaskState(
     id: string,
     state: TaskState,
-    note?: string
+    note?: string,
+    overrideWip?: boolean
   ): Promise<Task> {
     return this.request<Task>(`/tasks/${id}`, {
       method: 'PUT',
-      body: JSON.stringify({ state, note }),
+      body: JSON.stringify({ state, note, override_wip: overrideWip }),
     })
   }
 
@@ -78,11 +147,128 @@ class ApiClient {
     })
   }
 
+  // Custom fields
+  async getCustomFields(): Promise<Record<string, CustomField>> {
+    return this.request<Record<string, CustomField>>('/custom-fields')
+  }
+
+  // Fields not named keep their value; null clears a field
+  async setTaskFields(id: string, fields: Record<string, CustomFieldValue | null>): Promise<Task> {
+    return this.request<Task>(`/tasks/${id}/fields`, {
+      method: 'PUT',
+      body: JSON.stringify({ fields }),
+    })
+  }
+
+  // Watch operations
+  async getTaskWatchers(id: string): Promise<TaskWatch[]> {
+    return this.request<TaskWatch[]>(`/tasks/${id}/watchers`)
+  }
+
+  async watchTask(id: string, watcher?: string, channel?: string): Promise<TaskWatch[]> {
+    return this.request<TaskWatch[]>(`/tasks/${id}/watchers`, {
+      method: 'POST',
+      body: JSON.stringify({ watcher, channel }),
+    })
+  }
+
+  async unwatchTask(id: string, watcher: string = 'web'): Promise<TaskWatch[]> {
+    return this.request<TaskWatch[]>(`/tasks/${id}/watchers?watcher=${encodeURIComponent(watcher)}`, {
+      method: 'DELETE',
+    })
+  }
+
+  // Revision operations
+  async getTaskRevisions(id: string): Promise<TaskRevision[]> {
+    return this.request<TaskRevision[]>(`/tasks/${id}/revisions`)
+  }
+
+  async getTaskTimeInState(id: string): Promise<TimeInState> {
+    return this.request<TimeInState>(`/tasks/${id}/time-in-state`)
+  }
+
+  async revertTask(id: string, revision: number, actor?: string): Promise<Task> {
+    return this.request<Task>(`/tasks/${id}/revisions/${revision}/revert`, {
+      method: 'POST',
+      body: JSON.stringify({ actor }),
+    })
+  }
+
+  async getTaskNotes(id: string): Promise<TaskNote[]> {
+    return this.request<TaskNote[]>(`/tasks/${id}/notes`)
+  }
+
+  async addTaskNote(id: string, body: string, author?: string): Promise<TaskNote> {
+    return this.request<TaskNote>(`/tasks/${id}/notes`, {
+      method: 'POST',
+      body: JSON.stringify({ body, author }),
+    })
+  }
+
+  async updateTaskNote(id: string, noteId: string, body: string): Promise<TaskNote> {
+    return this.request<TaskNote>(`/tasks/${id}/notes/${noteId}`, {
+      method: 'PUT',
+      body: JSON.stringify({ body }),
+    })
+  }
+
+  async deleteTaskNote(id: string, noteId: string): Promise<TaskNote[]> {
+    return this.request<TaskNote[]>(`/tasks/${id}/notes/${noteId}`, {
+      method: 'DELETE',
+    })
+  }
+
+  // URL of an artifact's raw content, streamed by the server; supports Range requests
+  artifactContentUrl(id: string, name: string, version?: number): string {
+    const query = version ? `?version=${version}` : ''
+    return `${this.baseUrl}/tasks/${id}/artifacts/${encodeURIComponent(name)}/content${query}`
+  }
+
   // Status and monitoring
   async getStatus(): Promise<Status> {
     return this.request<Status>('/status')
   }
 
+  async getCurrentCycle(): Promise<CurrentCycle> {
+    return this.request<CurrentCycle>('/cycles/current')
+  }
+
+  async startCycle(taskId?: string): Promise<{ queued: boolean; task_id?: string }> {
+    return this.request<{ queued: boolean; task_id?: string }>('/cycles/start', {
+      method: 'POST',
+      body: JSON.stringify({ task_id: taskId }),
+    })
+  }
+
+  async search(
+    query: string,
+    filters?: { kind?: SearchKind; state?: TaskState; owner?: string; mode?: 'keyword' | 'semantic'; limit?: number }
+  ): Promise<SearchHit[]> {
+    const params = new URLSearchParams({ q: query })
+    if (filters?.kind) params.append('kind', filters.kind)
+    if (filters?.state) params.append('state', filters.state)
+    if (filters?.owner) params.append('owner', filters.owner)
+    if (filters?.mode) params.append('mode', filters.mode)
+    if (filters?.limit) params.append('limit', filters.limit.toString())
+
+    return this.request<SearchHit[]>(`/search?${params.toString()}`)
+  }
+
+  async getMilestones(): Promise<MilestoneSummary[]> {
+    return this.request<MilestoneSummary[]>('/milestones')
+  }
+
+  async getMilestone(name: string): Promise<Milestone> {
+    return this.request<Milestone>(`/milestones/${encodeURIComponent(name)}`)
+  }
+
+  async signOffMilestone(name: string, signedOffBy?: string, note?: string): Promise<Milestone> {
+    return this.request<Milestone>(`/milestones/${encodeURIComponent(name)}/signoff`, {
+      method: 'POST',
+      body: JSON.stringify({ signed_off_by: signedOffBy, note }),
+    })
+  }
+
   async getAuditHistory(taskId: string): Promise<AuditEntry[]> {
     return this.request<AuditEntry[]>(`/audit/${taskId}`)
   }
@@ -3,15 +3,53 @@ export interface Task {
   title: string
   description: string
   state: TaskState
+  held_from?: TaskState // state a blocked or paused task resumes to
+  review_failures?: number // moves into needs_fixes since the task was last escalated
   priority: number
   owner: string
   tags: string[]
   dependencies: string[]
+  due_date?: string // when the task must be done by
+  locked_by?: string // cycle working on the task
+  locked_at?: string
+  custom_fields?: Record<string, CustomFieldValue>
+  parent_id?: string
+  archived?: boolean
+  deleted_at?: string // set while the task is in the trash
+  project_id?: string
   created_at: string
   updated_at: string
   artifacts?: Artifact[]
 }
 
+export interface TaskNode extends Task {
+  children?: TaskNode[]
+}
+
+export type TaskSort = 'priority' | 'updated_at' | 'created_at' | 'state'
+
+export interface TaskPageRequest {
+  sort?: TaskSort
+  reverse?: boolean
+  limit?: number
+  offset?: number
+  cursor?: string
+}
+
+export interface TaskPage {
+  tasks: Task[]
+  total: number
+  next_cursor?: string
+}
+
+export type CustomFieldValue = string | number | boolean
+
+export interface CustomField {
+  type: 'string' | 'number' | 'boolean' | 'enum'
+  values?: string[]
+  description?: string
+}
+
 export type TaskState =
   | 'ready_for_plan'
   | 'planning'
@@ -24,6 +62,9 @@ export type TaskState =
   | 'needs_fixes'
   | 'fixing'
   | 'DONE'
+  | 'blocked'
+  | 'paused'
+  | 'cancelled'
 
 export interface Artifact {
   id: string
@@ -32,9 +73,65 @@ export interface Artifact {
   version: number
   content: string
   metadata: Record<string, any>
+  blob_sha256?: string
+  size: number
+  created_at: string
+}
+
+export interface TaskWatch {
+  task_id: string
+  watcher: string
+  channel: string
   created_at: string
 }
 
+export interface TaskNote {
+  id: string
+  task_id: string
+  author: string
+  body: string
+  created_at: string
+  updated_at: string
+}
+
+export interface TaskRevision {
+  task_id: string
+  revision: number
+  title: string
+  description: string
+  actor?: string
+  reverted_from?: number
+  created_at: string
+  description_diff?: string
+}
+
+export interface LiveCycle {
+  cycle_id: string
+  task_id: string
+  task_title: string
+  task_state: TaskState
+  agent: string
+  phase: 'executing' | 'handshake' | 'hooks' | 'review' | 'merging' | 'recording'
+  model_tier?: string
+  started_at: string
+  elapsed_seconds: number
+  timebox_seconds?: number
+  last_llm_event?: {
+    type: 'request' | 'response' | 'error' | 'fallback' | 'escalation' | 'budget'
+    tier?: string
+    detail?: string
+    at: string
+  }
+  handshake_attempts: number
+  dry_run: boolean
+}
+
+export interface CurrentCycle {
+  active: boolean
+  tracked: boolean
+  cycle?: LiveCycle
+}
+
 export interface AuditEntry {
   id: string
   task_id: string
@@ -48,21 +145,154 @@ export interface AuditEntry {
   follow_ups?: string[]
   inputs_summary: string
   outputs_summary: string
+  confidence?: number // the agent's self-assessed confidence, 0 to 1
+  open_questions?: string[]
   created_at: string
 }
 
+export type SearchKind = 'task' | 'artifact' | 'requirement' | 'audit'
+
+export interface SearchHit {
+  kind: SearchKind
+  ref_id: string
+  task_id?: string // empty for requirements
+  task_title?: string
+  task_state?: TaskState
+  title: string
+  snippet: string // matched words marked with [brackets]
+  score: number
+}
+
+export interface MilestoneSignoff {
+  milestone: string
+  signed_off_by: string
+  note?: string
+  created_at: string
+}
+
+export interface MilestoneSummary {
+  name: string
+  total_tasks: number
+  done_tasks: number
+  remaining_tasks: number
+  blocked_tasks: number
+  percent_done: number
+  remaining_hours: number
+  unestimated: number
+  by_state: Record<string, number>
+  signed_off?: MilestoneSignoff
+  waiting_for?: string // the earlier milestone whose sign-off holds this one back
+}
+
+export interface MilestoneTask {
+  id: string
+  title: string
+  state: TaskState
+  priority: number
+  owner?: string
+  estimated_hours?: number
+  blocked: boolean
+  blocked_reason?: string
+}
+
+export interface Milestone extends MilestoneSummary {
+  remaining: MilestoneTask[]
+  summary?: Artifact // the milestone_summary artifact, once finished
+}
+
+export interface AreaLock {
+  area: string
+  task_id: string
+  cycle_id: string
+  holder: string
+  acquired_at: string
+  expires_at: string
+}
+
+export interface PlanStatus {
+  path: string
+  available: boolean
+  error?: string
+  checked_at: string
+}
+
 export interface Status {
   tasks_by_state: Record<TaskState, number>
   total_tasks: number
   recent_activity: AuditEntry[]
+  area_locks?: AreaLock[]
+  plan?: PlanStatus
+  timezone: string
+  project: string
+  stale_tasks: StaleTask[] // stuck in a work state, longest first
+  overdue_tasks: OverdueTask[] // open past their due date, most overdue first
+  wip_limits?: Partial<Record<TaskState, number>> // most tasks a state may hold
+  cycle_costs?: CycleCosts
+}
+
+// What cycles cost, added up
+export interface CycleTotals {
+  key?: string // what the cycles have in common, when grouped
+  cycles: number
+  succeeded: number
+  prompt_tokens: number
+  completion_tokens: number
+  cost_usd: number
+  duration_seconds: number
+}
+
+// What today's cycles and the last seven days' cycles cost
+export interface CycleCosts {
+  today: CycleTotals
+  last_7_days: CycleTotals
+}
+
+// A task in a work state for longer than the configured staleness threshold
+export interface StaleTask {
+  id: string
+  title: string
+  state: TaskState
+  owner?: string
+  entered_at: string
+  hours_in_state: number
+  threshold_hours: number
+}
+
+// An open task past its due date
+export interface OverdueTask {
+  id: string
+  title: string
+  state: TaskState
+  owner?: string
+  due_date: string
+  hours_overdue: number
+}
+
+// How long a task spent in each state it has been in
+export interface TimeInState {
+  task_id: string
+  spans: {
+    state: TaskState
+    entered_at: string
+    left_at?: string // absent while the task is still in the state
+    seconds: number
+  }[]
+  totals: Partial<Record<TaskState, number>> // seconds per state
 }
 
 export interface WSMessage {
-  type: 'task_created' | 'task_updated' | 'task_deleted' | 'status_update'
+  type: 'task_created' | 'task_updated' | 'task_deleted' | 'status_update' | 'cycle_output'
   timestamp: number
   data: any
 }
 
+// Data of a cycle_output message: agent output streamed by the worker's cycle
+export interface CycleOutputChunk {
+  cycle_id: string
+  task_id: string
+  content: string
+}
+
 export interface CreateTaskRequest {
   prompt: string
   owner?: string
@@ -145,9 +375,30 @@ export const STATE_CONFIG: Record<TaskState, {
     color: 'state-done',
     description: 'Task is complete and committed',
     icon: '🎉'
+  },
+  blocked: {
+    label: 'Blocked',
+    color: 'state-blocked',
+    description: 'Waiting on something outside the task; resume to continue',
+    icon: '⛔'
+  },
+  paused: {
+    label: 'Paused',
+    color: 'state-paused',
+    description: 'Set aside for now; resume to continue',
+    icon: '⏸️'
+  },
+  cancelled: {
+    label: 'Cancelled',
+    color: 'state-cancelled',
+    description: "Won't be done; left out of the completion rate",
+    icon: '🚫'
   }
 }
 
+// Hold states keep a task out of selection until it is resumed
+export const HOLD_STATES: TaskState[] = ['blocked', 'paused']
+
 export const PRIORITY_CONFIG = {
   1: { label: 'Lowest', color: 'badge-priority-low' },
   2: { label: 'Very Low', color: 'badge-priority-low' },
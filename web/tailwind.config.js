@@ -53,6 +53,9 @@ module.exports = {
         'state-needs-fixes': '#ef4444',       // red
         'state-fixing': '#f59e0b',            // amber
         'state-done': '#10b981',              // emerald
+        'state-blocked': '#dc2626',           // dark red
+        'state-paused': '#64748b',            // slate
+        'state-cancelled': '#71717a',         // zinc
       },
       fontFamily: {
         mono: ['var(--font-mono)', 'ui-monospace', 'SFMono-Regular', 'Consolas', 'Liberation Mono', 'Menlo', 'monospace'],